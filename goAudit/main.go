@@ -2,11 +2,13 @@ package main
 
 import (
 	// Standard Library
-
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"image/color"
 	"log"
 	"os"
-	"path/filepath"
 
 	// Fyne Imports
 	"fyne.io/fyne/v2"
@@ -16,15 +18,16 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
-	// External Imports
-	"github.com/joho/godotenv"
-
 	// Internal Imports
 
 	myAuth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
 	crud "github.com/j4m1n-t/goAudit/internal/databases"
 	myFunctions "github.com/j4m1n-t/goAudit/internal/functions"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
 	myLayout "github.com/j4m1n-t/goAudit/internal/layouts"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
 	state "github.com/j4m1n-t/goAudit/internal/status"
 )
 
@@ -71,38 +74,47 @@ func toggleTheme(a fyne.App) {
 }
 
 var (
-	tabs           *container.AppTabs
-	configPath     string
-	adminTab       fyne.CanvasObject
-	auditTab       fyne.CanvasObject
-	credentialsTab fyne.CanvasObject
-	crmTab         fyne.CanvasObject
-	notesTab       fyne.CanvasObject
-	tasksTab       fyne.CanvasObject
-	appState       *state.AppState
+	tabs             *container.AppTabs
+	adminTab         fyne.CanvasObject
+	auditTab         fyne.CanvasObject
+	credentialsTab   fyne.CanvasObject
+	crmTab           fyne.CanvasObject
+	notesTab         fyne.CanvasObject
+	tasksTab         fyne.CanvasObject
+	appState         *state.AppState
+	stopLiveUpdate   = func() {}
+	stopSessionWatch = func() {}
 )
 
 func main() {
-	//Load environment variables
-	env := godotenv.Load()
-	if env != nil {
-		log.Fatalf("Error loading.env file.")
-	}
 	// Set logging and configuration
 	myFunctions.SetLog()
-	configDir, err := os.UserConfigDir()
+	_, configErr := config.Init(os.Args[1:])
+	if errors.Is(configErr, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if configErr != nil {
+		log.Printf("Configuration problems: %v", configErr)
+	}
+	// Settings used to live in a .env next to the binary; bring them over once
+	imported, err := config.ImportEnvFile(".env")
 	if err != nil {
-		log.Printf("Failed to get user config dir: %v", err)
-		fyne.LogError("Failed to get user config dir.", err)
+		log.Printf("Failed to import .env: %v", err)
+	} else if imported {
+		log.Printf("Imported settings from .env into %s; .env is no longer read", config.UserPath())
+	}
+	log.Printf("Config file: %s", config.UserPath())
+	err = config.Watch(context.Background())
+	if err != nil {
+		log.Printf("Configuration will not reload automatically: %v", err)
+	}
+	err = secrets.UnlockFromEnvironment()
+	if err != nil {
+		log.Printf("Failed to unlock the secrets vault from %s: %v", secrets.EnvVaultPassphrase, err)
 	}
-	log.Printf("Config directory: %s", configDir)
-	configPath = filepath.Join(configDir, "goAudit", "config.json")
-	// Initialize connection to db server(s)
-	myFunctions.InitDBs()
 	// Initialize authentication
 	dbInstance := &crud.DatabaseWrapper{}
 	ldapInstance := &myAuth.LDAPWrapper{}
-	authInstance := myAuth.NewAuth(dbInstance, ldapInstance)
 	// Set the default app layout
 	myApp := app.New()
 	myWindow := myApp.NewWindow("goAudit")
@@ -111,14 +123,41 @@ func main() {
 	myWindow.SetPadded(true)
 	// Assign the window to the AppState
 	state.GlobalState.SetWindow(myWindow)
-	// Icons and mutible items
-
-	// Menu Items
-	// Check if ldap is configured
-	if !myFunctions.LDAPConfigured() {
+	// Tell the user now rather than failing on every screen after login
+	if configErr != nil {
+		dialog.ShowError(fmt.Errorf("Some settings could not be loaded and were ignored:\n\n%v", configErr), myWindow)
+	}
+	// Shown on the login form when an OpenID Connect provider is configured
+	ssoButton := widget.NewButton("Sign In With SSO", nil)
+	ssoButton.Hide()
+	// Someone has to be able to log in: through LDAP, an existing local
+	// account, single sign-on, or a first administrator created now
+	checkLoginSources := func() {
+		if myFunctions.OIDCLoginAvailable() {
+			ssoButton.Show()
+		}
+		if myFunctions.LDAPConfigured() {
+			return
+		}
+		if myFunctions.NeedsFirstAccount() {
+			myLayout.ShowFirstAccountDialog(myWindow, myFunctions.CreateFirstAccount, func(created bool) {
+				if !created {
+					myFunctions.ShowLDAPDialog(myWindow)
+				}
+			})
+			return
+		}
+		if myFunctions.OIDCLoginAvailable() {
+			log.Println("LDAP is not configured; users sign in through OpenID Connect")
+			return
+		}
+		if myFunctions.LocalLoginAvailable() {
+			log.Println("LDAP is not configured; only local accounts can log in")
+			return
+		}
 		myFunctions.ShowLDAPDialog(myWindow)
 		if !myFunctions.LDAPConfigured() {
-			errorMessage := widget.NewLabel("The LDAP must be configured to use the application.")
+			errorMessage := widget.NewLabel("The LDAP must be configured to use the application, unless local accounts are enabled in the auth chain.")
 			errorMessage.Wrapping = fyne.TextWrapWord
 
 			content := container.NewVBox(
@@ -130,22 +169,84 @@ func main() {
 			customDialog.Show()
 		}
 	}
+	// Initialize connection to db server(s)
+	connectedSQL := config.Current().SQL
+	startDatabase := func() {
+		// Older versions saved passwords in plain text
+		moved, err := secrets.MigrateUserConfig()
+		if err != nil {
+			log.Printf("Passwords left in %s: %v", config.UserPath(), err)
+		} else if moved > 0 {
+			log.Printf("Moved %d passwords from %s to the secret store", moved, config.UserPath())
+		}
+		connectedSQL = config.Current().SQL
+		dbErr := myFunctions.InitDBs()
+		if dbErr != nil {
+			dialog.ShowError(fmt.Errorf("Could not connect to the database. Check the SQL settings.\n\n%v", dbErr), myWindow)
+		}
+		checkLoginSources()
+	}
+	// LDAP settings apply on the next login; the pool is only built at startup
+	config.OnChange(func(cfg *config.Config) {
+		if cfg.SQL != connectedSQL {
+			log.Println("Database settings changed; restart goAudit to reconnect")
+		}
+	})
+	// Passwords kept in the vault cannot be read until it is unlocked
+	if secrets.VaultNeeded(config.Current()) && secrets.DefaultVault().Locked() {
+		myLayout.ShowUnlockVaultDialog(myWindow, func(bool) { startDatabase() })
+	} else {
+		startDatabase()
+	}
+	// Icons and mutible items
+
+	// Menu Items
 	Menu := fyne.NewMainMenu()
 	FileMenu := fyne.NewMenu("File")
 	QuitItem := fyne.NewMenuItem("Quit", func() {
 		// Graceful shutdown handling
+		myFunctions.StopBackground()
 		if state.GlobalState.LDAPConn != nil && state.GlobalState.LDAPConn.Conn != nil {
 			state.GlobalState.LDAPConn.Conn.Close()
 		}
 		os.Exit(0)
 	})
 	LogoutItem := fyne.NewMenuItem("Logout", func() {
-		ldapInstance.LogoutUser(state.GlobalState.LDAPConn)
+		logout(myWindow, ldapInstance, "")
+	})
+	TwoFactorItem := fyne.NewMenuItem("Two-Factor Login", func() {
+		if state.GlobalState.Session == nil {
+			dialog.ShowInformation("Two-Factor Login", "Log in to set up two-factor login.", myWindow)
+			return
+		}
+		myLayout.ShowTwoFactorSettings(myWindow, state.GlobalState.Username, myFunctions.TwoFactor())
+	})
+	LinkSSOItem := fyne.NewMenuItem("Link SSO Sign-In", func() {
+		if state.GlobalState.Session == nil || !myFunctions.OIDCLoginAvailable() {
+			dialog.ShowInformation("Link SSO Sign-In", "Log in, with SSO configured, to link your identity provider account.", myWindow)
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		waiting := dialog.NewCustom("Link SSO Sign-In", "Cancel",
+			widget.NewLabel("Sign in to your identity provider account in your browser."), myWindow)
+		waiting.SetOnClosed(cancel)
+		waiting.Show()
+		go func() {
+			err := myFunctions.LinkOIDC(ctx)
+			waiting.Hide()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					dialog.ShowError(err, myWindow)
+				}
+				return
+			}
+			dialog.ShowInformation("Link SSO Sign-In", "You can now sign in with SSO.", myWindow)
+		}()
 	})
 	SettingsMenu := fyne.NewMenu("Settings")
 	ThemeItem := fyne.NewMenuItem("Toggle Theme", func() { toggleTheme(myApp) })
 	SettingsMenu.Items = append(SettingsMenu.Items, ThemeItem)
-	FileMenu.Items = append(FileMenu.Items, LogoutItem, QuitItem)
+	FileMenu.Items = append(FileMenu.Items, TwoFactorItem, LinkSSOItem, LogoutItem, QuitItem)
 	Menu.Items = append(Menu.Items, FileMenu)
 	Menu.Items = append(Menu.Items, SettingsMenu)
 	myWindow.SetMainMenu(Menu)
@@ -160,29 +261,50 @@ func main() {
 			{Text: "Password: ", Widget: password},
 		},
 		OnSubmit: func() {
-			var err error
-			state.GlobalState.LDAPConn, err = authInstance.LDAP.ConnectToAdServer(username.Text, password.Text)
+			login, err := myFunctions.Login(username.Text, password.Text)
 			if err != nil {
 				dialog.ShowError(err, myWindow)
-				fyne.LogError("Error connecting to LDAP server.", err)
+				fyne.LogError("Error logging in.", err)
 				return
 			}
-			state.GlobalState.Username = username.Text
-			err = state.GlobalState.FetchAll()
-			if err != nil {
-				dialog.ShowError(err, myWindow)
-				fyne.LogError("Error fetching information from database(s).", err)
+			current := password.Text
+			password.SetText("")
+			if !login.MustChangePassword {
+				finishLogin(login, myWindow, ldapInstance, dbInstance)
 				return
 			}
-			log.Printf("Notes fetched for user: %s: %+v", state.GlobalState.Username, state.GlobalState.Notes)
-			dbInstance.Create(state.GlobalState.Username)
-			isAdmin := myFunctions.CheckIfAdmin(state.GlobalState.LDAPConn, username.Text)
-			myFunctions.UpdateTabsForUser(isAdmin, myWindow, appState)
-			myFunctions.UpdateMenuForUser(isAdmin, myWindow)
-			tabs.SelectIndex(1)
+			myLayout.ShowChangePasswordDialog(myWindow, "Your password was set by an administrator. Choose a new one to continue.",
+				func(replacement string) error {
+					return myFunctions.ChangePassword(login.Username, current, replacement)
+				},
+				func(changed bool) {
+					if changed {
+						finishLogin(login, myWindow, ldapInstance, dbInstance)
+					}
+				})
 		},
 	}
 
+	ssoButton.OnTapped = func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		waiting := dialog.NewCustom("Sign In With SSO", "Cancel",
+			widget.NewLabel("Finish signing in in your browser."), myWindow)
+		waiting.SetOnClosed(cancel)
+		waiting.Show()
+		go func() {
+			login, err := myFunctions.LoginWithOIDC(ctx)
+			waiting.Hide()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					dialog.ShowError(err, myWindow)
+					fyne.LogError("Error signing in with SSO.", err)
+				}
+				return
+			}
+			finishLogin(login, myWindow, ldapInstance, dbInstance)
+		}()
+	}
+
 	adminTab = myLayout.CreatePlaceholderAdminTab()
 	auditTab = myLayout.CreatePlaceholderAuditsTab()
 	credentialsTab = myLayout.CreatePlaceholderCredentialsTab()
@@ -190,7 +312,7 @@ func main() {
 	notesTab = myLayout.CreatePlaceholderNotesTab()
 	tasksTab = myLayout.CreatePlaceholderTaskTab()
 	tabs = container.NewAppTabs(
-		container.NewTabItem("Login", loginForm),
+		container.NewTabItem("Login", container.NewVBox(loginForm, ssoButton)),
 		container.NewTabItem("Audits", auditTab),
 		container.NewTabItem("CRM", crmTab),
 		container.NewTabItem("Credentials", credentialsTab),
@@ -204,7 +326,99 @@ func main() {
 	// Show and run the application
 	myWindow.ShowAndRun()
 	// Graceful shutdown
+	myFunctions.StopBackground()
 	myWindow.SetOnClosed(func() { os.Exit(0) })
 	myApp.Quit()
 	os.Exit(0)
 }
+
+// finishLogin checks the second factor of a successful login, then starts
+// the session and shows the tabs the user's roles allow
+func finishLogin(login *interfaces.Login, window fyne.Window, ldapInstance *myAuth.LDAPWrapper, dbInstance *crud.DatabaseWrapper) {
+	abandon := func(err error) {
+		ldapInstance.LogoutUser(login.LDAP)
+		state.GlobalState.LDAPConn = nil
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+	session, err := myFunctions.NewSession(login)
+	if err != nil {
+		abandon(err)
+		fyne.LogError("Error looking up roles.", err)
+		return
+	}
+	begin := func() {
+		state.GlobalState.LDAPConn = login.LDAP
+		myFunctions.StartSession(session, login)
+		showWorkspace(session, window, ldapInstance, dbInstance)
+	}
+
+	twoFactor := myFunctions.TwoFactor()
+	enrolled, err := twoFactor.Enrolled(login.Username)
+	if err != nil {
+		abandon(fmt.Errorf("Could not check two-factor login: %v", err))
+		return
+	}
+	switch {
+	case enrolled:
+		myLayout.ShowTwoFactorPrompt(window, login.Username, twoFactor, func(verified bool) {
+			if !verified {
+				abandon(nil)
+				return
+			}
+			begin()
+		})
+	case myAuth.TwoFactorRequired(session.Roles()):
+		myLayout.ShowTwoFactorEnrolment(window, login.Username, twoFactor,
+			"Your role requires two-factor login. Set it up to continue.", func(enrolled bool) {
+				if !enrolled {
+					abandon(nil)
+					return
+				}
+				begin()
+			})
+	default:
+		begin()
+	}
+}
+
+// showWorkspace replaces the login form with the user's tabs
+func showWorkspace(session *rbac.Session, window fyne.Window, ldapInstance *myAuth.LDAPWrapper, dbInstance *crud.DatabaseWrapper) {
+	state.GlobalState.Username = session.Username
+	err := state.GlobalState.FetchAll()
+	if err != nil {
+		dialog.ShowError(err, window)
+		fyne.LogError("Error fetching information from database(s).", err)
+		return
+	}
+	log.Printf("Notes fetched for user: %s: %+v", state.GlobalState.Username, state.GlobalState.Notes)
+	dbInstance.Create(state.GlobalState.Username)
+	isAdmin := session.Can(rbac.SettingsManage)
+	myFunctions.UpdateTabsForUser(isAdmin, window, appState)
+	myFunctions.UpdateMenuForUser(isAdmin, window)
+	stopLiveUpdate()
+	stopLiveUpdate = myLayout.StartLiveUpdates(window)
+	stopSessionWatch()
+	stopSessionWatch = myFunctions.WatchSession(window, func(err error) {
+		logout(window, ldapInstance, fmt.Sprintf("You have been logged out (%v). Log in again to continue.", err))
+	})
+	tabs.SelectIndex(1)
+}
+
+// logout ends the session and puts the login form back. message says why
+// when the user did not ask to log out.
+func logout(window fyne.Window, ldapInstance *myAuth.LDAPWrapper, message string) {
+	stopSessionWatch()
+	stopSessionWatch = func() {}
+	stopLiveUpdate()
+	stopLiveUpdate = func() {}
+	ldapInstance.LogoutUser(state.GlobalState.LDAPConn)
+	myFunctions.EndSession()
+	myFunctions.UpdateMenuForUser(false, window)
+	window.SetContent(tabs)
+	tabs.SelectIndex(0)
+	if message != "" {
+		dialog.ShowInformation("Logged Out", message, window)
+	}
+}
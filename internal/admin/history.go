@@ -0,0 +1,23 @@
+package admin
+
+import (
+	// Standard Library
+	"log"
+	"os"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// addHistory records an administrative change in the change history. A
+// failure is logged rather than returned, as the change has already been made.
+func addHistory(db interfaces.DatabaseOperations, action, subject, detail string) {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	entry := interfaces.ChangeHistory{Host: host, Action: action, Subject: subject, Detail: detail}
+	if _, err := db.AddChangeHistory(entry); err != nil {
+		log.Printf("Failed to add %s for %s to the change history: %v", action, subject, err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package admin is the user and record administration goAudit does on top of
+// the store. Who the user is and what they may do stays in auth and rbac.
+package admin
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Change history actions recorded by the record browser
+const (
+	ActionRecordsDeleted    = "records_deleted"
+	ActionRecordsReassigned = "records_reassigned"
+	ActionRecordsCompleted  = "records_completed"
+)
+
+// DeleteRecords deletes the chosen records of kind
+func DeleteRecords(db interfaces.DatabaseOperations, kind string, ids []int) (int, error) {
+	deleted, err := db.DeleteRecords(kind, ids)
+	if err != nil {
+		return 0, err
+	}
+	addHistory(db, ActionRecordsDeleted, kind, fmt.Sprintf("%d deleted: %s", deleted, recordIDs(ids)))
+	log.Printf("Deleted %d %s", deleted, kind)
+	return deleted, nil
+}
+
+// ReassignRecords gives the chosen records of kind to another user: as
+// assignee for tasks and audits, as owner for notes and CRM entries
+func ReassignRecords(db interfaces.DatabaseOperations, kind string, ids []int, to string) (int, error) {
+	changed, err := db.ReassignRecords(kind, ids, to)
+	if err != nil {
+		return 0, err
+	}
+	addHistory(db, ActionRecordsReassigned, kind, fmt.Sprintf("%d reassigned to %s: %s", changed, to, recordIDs(ids)))
+	log.Printf("Reassigned %d %s to %s", changed, kind, to)
+	return changed, nil
+}
+
+// CompleteRecords marks the chosen tasks or audits complete
+func CompleteRecords(db interfaces.DatabaseOperations, kind string, ids []int) (int, error) {
+	completed, err := db.CompleteRecords(kind, ids)
+	if err != nil {
+		return 0, err
+	}
+	addHistory(db, ActionRecordsCompleted, kind, fmt.Sprintf("%d completed: %s", completed, recordIDs(ids)))
+	log.Printf("Completed %d %s", completed, kind)
+	return completed, nil
+}
+
+// recordIDs lists ids for the change history, as "#3, #7"
+func recordIDs(ids []int) string {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = "#" + strconv.Itoa(id)
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	// Standard Library
+	"fmt"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestRecordAdministration(t *testing.T) {
+	db := fakes.NewDatabase()
+	db.GetOrCreateUser("alice")
+	db.GetOrCreateUser("bob")
+	task, _ := db.CreateTask(interfaces.Tasks{Title: "Review", Username: "alice"})
+
+	if n, err := ReassignRecords(db, interfaces.RecordTasks, []int{task.ID}, "bob"); err != nil || n != 1 {
+		t.Fatalf("ReassignRecords = %d, %v", n, err)
+	}
+	if n, err := CompleteRecords(db, interfaces.RecordTasks, []int{task.ID}); err != nil || n != 1 {
+		t.Fatalf("CompleteRecords = %d, %v", n, err)
+	}
+	if _, err := CompleteRecords(db, interfaces.RecordCRM, []int{task.ID}); err == nil {
+		t.Error("CompleteRecords of CRM entries returned no error")
+	}
+	if n, err := DeleteRecords(db, interfaces.RecordTasks, []int{task.ID}); err != nil || n != 1 {
+		t.Fatalf("DeleteRecords = %d, %v", n, err)
+	}
+
+	history, _ := db.GetChangeHistory(10)
+	if len(history) != 3 || history[0].Action != ActionRecordsDeleted || history[2].Detail != fmt.Sprintf("1 reassigned to bob: #%d", task.ID) {
+		t.Errorf("change history = %+v", history)
+	}
+}
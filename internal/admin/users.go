@@ -0,0 +1,63 @@
+package admin
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Change history actions recorded by user administration
+const (
+	ActionUserActivated   = "user_activated"
+	ActionUserDeactivated = "user_deactivated"
+	ActionUserTransferred = "user_records_transferred"
+	ActionUserDeleted     = "user_deleted"
+)
+
+// SetUserStatus activates or deactivates user. Inactive users cannot log in
+// or be given work; the directory sync sets the status of directory users
+// again from their account in the directory.
+func SetUserStatus(db interfaces.DatabaseOperations, user interfaces.Users, status string) (interfaces.Users, error) {
+	if status != interfaces.UserActive && status != interfaces.UserInactive {
+		return interfaces.Users{}, fmt.Errorf("unknown user status %q", status)
+	}
+	previous := user.Status
+	user.Status = status
+	updated, err := db.Update(user)
+	if err != nil {
+		return interfaces.Users{}, fmt.Errorf("failed to update %s: %v", user.Username, err)
+	}
+	action := ActionUserActivated
+	if status == interfaces.UserInactive {
+		action = ActionUserDeactivated
+	}
+	addHistory(db, action, user.Username, fmt.Sprintf("status %s -> %s", previous, status))
+	log.Printf("Set the status of %s to %s", user.Username, status)
+	return updated, nil
+}
+
+// TransferUserRecords hands everything from owns or is assigned, apart from
+// their credentials, to another user
+func TransferUserRecords(db interfaces.DatabaseOperations, from, to string) (interfaces.UserRecords, error) {
+	moved, err := db.TransferUserRecords(from, to)
+	if err != nil {
+		return interfaces.UserRecords{}, err
+	}
+	addHistory(db, ActionUserTransferred, from, fmt.Sprintf("%s transferred to %s", moved, to))
+	log.Printf("Transferred %s from %s to %s", moved, from, to)
+	return moved, nil
+}
+
+// DeleteUser removes a user with no records left. Anything they still own or
+// are assigned has to be transferred first, so nothing is orphaned.
+func DeleteUser(db interfaces.DatabaseOperations, user interfaces.Users) error {
+	if err := db.Delete(user); err != nil {
+		return err
+	}
+	addHistory(db, ActionUserDeleted, user.Username, fmt.Sprintf("user %d (%s) deleted", user.ID, user.UserID))
+	log.Printf("Deleted user %s", user.Username)
+	return nil
+}
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestUserAdministration(t *testing.T) {
+	db := fakes.NewDatabase()
+	alice, _ := db.GetOrCreateUser("alice")
+	db.GetOrCreateUser("bob")
+	if _, err := db.CreateTask(interfaces.Tasks{Title: "Review", Username: "bob", Assignee: "alice"}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	alice, err := SetUserStatus(db, alice, interfaces.UserInactive)
+	if err != nil || alice.Status != interfaces.UserInactive {
+		t.Fatalf("SetUserStatus = %+v, %v", alice, err)
+	}
+	if _, err := SetUserStatus(db, alice, "Away"); err == nil {
+		t.Error("SetUserStatus accepted an unknown status")
+	}
+	if err := DeleteUser(db, alice); !errors.Is(err, interfaces.ErrUserInUse) {
+		t.Errorf("DeleteUser with an assigned task = %v, want ErrUserInUse", err)
+	}
+	if moved, err := TransferUserRecords(db, "alice", "bob"); err != nil || moved.AssignedTasks != 1 {
+		t.Fatalf("TransferUserRecords = %+v, %v", moved, err)
+	}
+	if err := DeleteUser(db, alice); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	history, _ := db.GetChangeHistory(10)
+	var actions []string
+	for _, entry := range history {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{ActionUserDeleted, ActionUserTransferred, ActionUserDeactivated}
+	if len(actions) != len(want) {
+		t.Fatalf("change history = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("change history = %v, want %v", actions, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package audits
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/catalog"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Change history actions for the controls catalog
+const (
+	ActionControlsImported = "controls_imported"
+	ActionFrameworkDeleted = "framework_deleted"
+)
+
+// ImportControls reads a YAML, JSON or CSV catalog and adds its controls to
+// the catalog, updating the ones already there
+func ImportControls(db interfaces.DatabaseOperations, filename string, data []byte) (string, interfaces.ControlImport, error) {
+	framework, err := catalog.Parse(filename, data)
+	if err != nil {
+		return "", interfaces.ControlImport{}, err
+	}
+	result, err := db.ImportControls(framework.Name, framework.Controls)
+	if err != nil {
+		return framework.Name, interfaces.ControlImport{}, err
+	}
+	addHistory(db, ActionControlsImported, framework.Name,
+		fmt.Sprintf("%s: %d added, %d updated, %d unchanged", filename, result.Added, result.Updated, result.Unchanged))
+	log.Printf("Imported %s from %s: %d added, %d updated, %d unchanged", framework.Name, filename, result.Added,
+		result.Updated, result.Unchanged)
+	return framework.Name, result, nil
+}
+
+// DeleteFramework removes a framework's controls and every mapping to them
+func DeleteFramework(db interfaces.DatabaseOperations, framework string) error {
+	if err := db.DeleteFramework(framework); err != nil {
+		return err
+	}
+	addHistory(db, ActionFrameworkDeleted, framework, "")
+	log.Printf("Deleted framework %s", framework)
+	return nil
+}
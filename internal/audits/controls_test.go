@@ -0,0 +1,38 @@
+package audits
+
+import (
+	// Standard Library
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+)
+
+func TestImportControls(t *testing.T) {
+	db := fakes.NewDatabase()
+	catalog := "framework,id,title\nSOC 2,CC6.1,Logical access\nSOC 2,CC7.2,Monitoring\n"
+	framework, result, err := ImportControls(db, "soc2.csv", []byte(catalog))
+	if err != nil || framework != "SOC 2" || result.Added != 2 {
+		t.Fatalf("ImportControls = %s, %+v, %v, want 2 SOC 2 controls added", framework, result, err)
+	}
+
+	catalog = "framework,id,title\nSOC 2,CC6.1,Logical access security\nSOC 2,CC7.2,Monitoring\nSOC 2,CC8.1,Change management\n"
+	_, result, err = ImportControls(db, "soc2.csv", []byte(catalog))
+	if err != nil || result.Added != 1 || result.Updated != 1 || result.Unchanged != 1 {
+		t.Errorf("reimport = %+v, %v, want one of each", result, err)
+	}
+	history, _ := db.GetChangeHistory(1)
+	if len(history) != 1 || history[0].Action != ActionControlsImported || history[0].Subject != "SOC 2" {
+		t.Errorf("latest history entry = %+v, want the import", history)
+	}
+
+	if _, _, err := ImportControls(db, "soc2.csv", []byte("id,title\nCC6.1,\n")); err == nil {
+		t.Error("import of a control without a title returned no error")
+	}
+	if err := DeleteFramework(db, "SOC 2"); err != nil {
+		t.Fatalf("DeleteFramework: %v", err)
+	}
+	if controls, _ := db.GetControls(""); len(controls) != 0 {
+		t.Errorf("controls after deleting the framework = %+v", controls)
+	}
+}
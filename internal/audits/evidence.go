@@ -0,0 +1,137 @@
+package audits
+
+import (
+	// Standard Library
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Change history actions for evidence
+const (
+	ActionEvidenceAdded    = "evidence_added"
+	ActionEvidenceTampered = "evidence_tampered"
+)
+
+// ErrEvidenceTooLarge is returned for uploads over evidence.maxSizeMB
+var ErrEvidenceTooLarge = errors.New("evidence is too large")
+
+// UploadEvidence records the hash and size of content and stores it, in the
+// configured evidence directory or else in the database. Set
+// evidence.Replaces to upload a new version of earlier evidence.
+func UploadEvidence(db interfaces.DatabaseOperations, evidence interfaces.Evidence, content []byte) (interfaces.Evidence, error) {
+	settings := config.Current().Evidence
+	if limit := settings.MaxSize(); int64(len(content)) > limit {
+		return interfaces.Evidence{}, fmt.Errorf("%s is over the %d MB limit: %w", evidence.Name, limit>>20, ErrEvidenceTooLarge)
+	}
+	evidence.SHA256 = interfaces.EvidenceHash(content)
+	evidence.Size = int64(len(content))
+	evidence.Storage = interfaces.EvidenceInDatabase
+	stored := content
+	if settings.Directory != "" {
+		if err := writeEvidenceFile(settings.Directory, evidence.SHA256, content); err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("failed to store %s: %w", evidence.Name, err)
+		}
+		evidence.Storage, stored = interfaces.EvidenceInDirectory, nil
+	}
+
+	added, err := db.AddEvidence(evidence, stored)
+	if err != nil {
+		return interfaces.Evidence{}, err
+	}
+	addHistory(db, ActionEvidenceAdded, fmt.Sprintf("audit #%d", added.AuditID),
+		fmt.Sprintf("%s version %d, %d bytes, SHA-256 %s", added.Name, added.Version, added.Size, added.SHA256))
+	log.Printf("Added evidence %s version %d to audit %d", added.Name, added.Version, added.AuditID)
+	return added, nil
+}
+
+// DownloadEvidence reads evidence back and checks it still matches what was
+// uploaded. A mismatch is recorded in the change history and returned as
+// interfaces.ErrEvidenceTampered.
+func DownloadEvidence(db interfaces.DatabaseOperations, id int) (interfaces.Evidence, []byte, error) {
+	evidence, err := db.GetEvidence(id)
+	if err != nil {
+		return interfaces.Evidence{}, nil, err
+	}
+	var content []byte
+	if evidence.Storage == interfaces.EvidenceInDirectory {
+		content, err = readEvidenceFile(config.Current().Evidence.Directory, evidence.SHA256)
+	} else {
+		content, err = db.ReadEvidence(id)
+	}
+	if err != nil {
+		return evidence, nil, fmt.Errorf("failed to read %s: %v", evidence.Name, err)
+	}
+	if !evidence.Matches(content) {
+		addHistory(db, ActionEvidenceTampered, fmt.Sprintf("audit #%d", evidence.AuditID),
+			fmt.Sprintf("%s version %d no longer matches SHA-256 %s", evidence.Name, evidence.Version, evidence.SHA256))
+		log.Printf("Evidence %d (%s) failed its integrity check", evidence.ID, evidence.Name)
+		return evidence, nil, fmt.Errorf("%s version %d: %w", evidence.Name, evidence.Version, interfaces.ErrEvidenceTampered)
+	}
+	return evidence, content, nil
+}
+
+// evidencePath is where content with hash is kept in dir, fanned out by the
+// first two characters of the hash
+func evidencePath(dir, hash string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("evidence.directory is not set")
+	}
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+		return "", fmt.Errorf("invalid SHA-256 %q", hash)
+	}
+	return filepath.Join(dir, hash[:2], hash), nil
+}
+
+// writeEvidenceFile stores content under its hash. Files are written once
+// and made read only; uploading the same content again reuses the file.
+func writeEvidenceFile(dir, hash string, content []byte) error {
+	path, err := evidencePath(dir, hash)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(path); err == nil {
+		if interfaces.EvidenceHash(existing) != hash {
+			return fmt.Errorf("%s: %w", path, interfaces.ErrEvidenceTampered)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0o440); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func readEvidenceFile(dir, hash string) ([]byte, error) {
+	path, err := evidencePath(dir, hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,94 @@
+package audits
+
+import (
+	// Standard Library
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestEvidenceInDatabase(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Evidence.MaxSizeMB = 1
+	config.Use(cfg)
+
+	db := fakes.NewDatabase()
+	audit, _ := db.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice"})
+	first, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "export.csv", Uploader: "alice"}, []byte("a,b\n"))
+	if err != nil {
+		t.Fatalf("UploadEvidence: %v", err)
+	}
+	if first.Storage != interfaces.EvidenceInDatabase || first.Size != 4 || first.SHA256 != interfaces.EvidenceHash([]byte("a,b\n")) {
+		t.Errorf("uploaded evidence = %+v", first)
+	}
+	if _, content, err := DownloadEvidence(db, first.ID); err != nil || string(content) != "a,b\n" {
+		t.Errorf("DownloadEvidence = %q, %v", content, err)
+	}
+
+	second, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "export.csv", Uploader: "alice", Replaces: first.ID},
+		[]byte("a,b\n1,2\n"))
+	if err != nil || second.Version != 2 {
+		t.Fatalf("replacement = %+v, %v, want version 2", second, err)
+	}
+	if _, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "export.csv", Uploader: "bob", Replaces: first.ID},
+		[]byte("other")); !errors.Is(err, interfaces.ErrConflict) {
+		t.Errorf("replacing an old version = %v, want ErrConflict", err)
+	}
+	if _, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "huge.bin", Uploader: "alice"},
+		make([]byte, 2<<20)); !errors.Is(err, ErrEvidenceTooLarge) {
+		t.Errorf("upload over the limit = %v, want ErrEvidenceTooLarge", err)
+	}
+
+	db.Corrupt(first.ID, []byte("a,c\n"))
+	if _, _, err := DownloadEvidence(db, first.ID); !errors.Is(err, interfaces.ErrEvidenceTampered) {
+		t.Errorf("download of altered evidence = %v, want ErrEvidenceTampered", err)
+	}
+	history, _ := db.GetChangeHistory(10)
+	if len(history) == 0 || history[0].Action != ActionEvidenceTampered {
+		t.Errorf("latest history entry = %+v, want %s", history, ActionEvidenceTampered)
+	}
+}
+
+func TestEvidenceInDirectory(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Evidence.Directory = t.TempDir()
+	config.Use(cfg)
+
+	db := fakes.NewDatabase()
+	audit, _ := db.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice"})
+	content := []byte("signed policy")
+	evidence, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "policy.pdf", Uploader: "alice"}, content)
+	if err != nil || evidence.Storage != interfaces.EvidenceInDirectory {
+		t.Fatalf("UploadEvidence = %+v, %v", evidence, err)
+	}
+	if _, err := db.ReadEvidence(evidence.ID); err == nil {
+		t.Error("evidence kept in a directory is also in the database")
+	}
+	if _, got, err := DownloadEvidence(db, evidence.ID); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("DownloadEvidence = %q, %v", got, err)
+	}
+
+	path := filepath.Join(cfg.Evidence.Directory, evidence.SHA256[:2], evidence.SHA256)
+	os.Chmod(path, 0o600)
+	if err := os.WriteFile(path, []byte("unsigned policy"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DownloadEvidence(db, evidence.ID); !errors.Is(err, interfaces.ErrEvidenceTampered) {
+		t.Errorf("download of an altered file = %v, want ErrEvidenceTampered", err)
+	}
+	if _, err := UploadEvidence(db, interfaces.Evidence{AuditID: audit.ID, Name: "copy.pdf", Uploader: "alice"},
+		content); !errors.Is(err, interfaces.ErrEvidenceTampered) {
+		t.Errorf("upload over an altered file = %v, want ErrEvidenceTampered", err)
+	}
+}
@@ -0,0 +1,288 @@
+package audits
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+// How often schedules are checked when the configuration does not say
+const defaultScheduleInterval = time.Hour
+
+// Change history action for an audit created by a schedule
+const ActionScheduledAuditCreated = "scheduled_audit_created"
+
+// today is the calendar day of now, at midnight UTC like the dates of a rule
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ScheduledAudit is the audit schedule creates for date. It is owned by
+// whoever created the schedule and starts in the workflow's first state.
+func ScheduledAudit(schedule interfaces.AuditSchedule, date time.Time) interfaces.Audits {
+	return interfaces.Audits{
+		Action:       fmt.Sprintf("%s %s", schedule.Name, date.Format("2006-01-02")),
+		AuditType:    schedule.AuditType,
+		AuditArea:    schedule.AuditArea,
+		Firm:         schedule.Firm,
+		TemplateID:   schedule.TemplateID,
+		AssignedUser: schedule.Assignee,
+		Username:     schedule.CreatedBy,
+		Status:       Workflow().Initial(),
+		ScheduleID:   schedule.ID,
+		ScheduledFor: date,
+	}
+}
+
+// dueDates lists the dates schedule has yet to create audits for on day:
+// those from day, or after the last date already created, up to its lead
+// days ahead. through is the end of that window.
+func dueDates(schedule interfaces.AuditSchedule, day time.Time) (dates []time.Time, through time.Time, err error) {
+	rule, err := schedule.Rule()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	from := day
+	if !schedule.CreatedThrough.Before(from) {
+		from = schedule.CreatedThrough.AddDate(0, 0, 1)
+	}
+	through = day.AddDate(0, 0, schedule.LeadDays)
+	if from.After(through) {
+		return nil, through, nil
+	}
+	return rule.Between(schedule.StartDate, from, through), through, nil
+}
+
+// errCreatorRefused is why a schedule whose creator may no longer create
+// audits is skipped
+var errCreatorRefused = errors.New("its creator may no longer create audits")
+
+// creatorMayCreate checks that username, who created a schedule, is still an
+// active user holding the permissions it needs. The scheduler runs without
+// anyone logged in, so their roles are read afresh; the schedules of users
+// whose roles are only known while they are logged in are refused.
+func creatorMayCreate(users map[string]interfaces.Users, username string) error {
+	user, ok := users[strings.ToLower(username)]
+	if !ok {
+		return fmt.Errorf("%w: %q no longer exists", errCreatorRefused, username)
+	}
+	if user.Status == interfaces.UserInactive {
+		return fmt.Errorf("%w: %s is inactive", errCreatorRefused, username)
+	}
+	roles, err := auth.LookupRoles(user)
+	if errors.Is(err, interfaces.ErrUnknownUser) || errors.Is(err, auth.ErrRolesUnknown) {
+		return fmt.Errorf("%w: %v", errCreatorRefused, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up the roles of %s: %v", username, err)
+	}
+	for _, p := range []rbac.Permission{rbac.SchedulesManage, rbac.AuditsWrite} {
+		if !anyRoleHas(roles, p) {
+			return fmt.Errorf("%w: %s no longer has %s", errCreatorRefused, username, p)
+		}
+	}
+	return nil
+}
+
+// anyRoleHas reports whether any of roles has p
+func anyRoleHas(roles []rbac.Role, p rbac.Permission) bool {
+	for _, role := range roles {
+		if role.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateScheduledAudits creates the audits that are due, as of now, for every
+// schedule that is not paused. Dates another goAudit has already created are
+// skipped. Schedules whose creator no longer exists, or may no longer create
+// audits, are logged and skipped. Audits are left unassigned when the
+// schedule's assignee has since been deactivated. A schedule whose audit
+// cannot be created stops at that date and tries it again next time; the
+// others carry on.
+func CreateScheduledAudits(db interfaces.DatabaseOperations, now time.Time) ([]interfaces.Audits, error) {
+	schedules, err := db.GetSchedules()
+	if err != nil {
+		return nil, err
+	}
+	all, err := db.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %v", err)
+	}
+	users := make(map[string]interfaces.Users, len(all))
+	for _, user := range all {
+		users[strings.ToLower(user.Username)] = user
+	}
+	// Each creator is checked once a run, however many schedules they have
+	creators := make(map[string]error)
+	day := today(now)
+	var created []interfaces.Audits
+	var problems []error
+	for _, schedule := range schedules {
+		if schedule.Paused {
+			continue
+		}
+		dates, through, err := dueDates(schedule, day)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("schedule %q: %v", schedule.Name, err))
+			continue
+		}
+		if len(dates) > 0 {
+			checked, seen := creators[schedule.CreatedBy]
+			if !seen {
+				checked = creatorMayCreate(users, schedule.CreatedBy)
+				creators[schedule.CreatedBy] = checked
+			}
+			if errors.Is(checked, errCreatorRefused) {
+				log.Printf("Skipping schedule %q: %v", schedule.Name, checked)
+				continue
+			}
+			if checked != nil {
+				problems = append(problems, fmt.Errorf("schedule %q: %v", schedule.Name, checked))
+				continue
+			}
+			if schedule.Assignee != "" {
+				if assignee, ok := users[strings.ToLower(schedule.Assignee)]; ok && assignee.Status == interfaces.UserInactive {
+					log.Printf("Schedule %q leaves its audits unassigned: %s is inactive", schedule.Name, schedule.Assignee)
+					schedule.Assignee = ""
+				}
+			}
+		}
+		for _, date := range dates {
+			audit, err := db.CreateAudit(ScheduledAudit(schedule, date))
+			if errors.Is(err, interfaces.ErrAlreadyScheduled) {
+				continue
+			}
+			if err != nil {
+				problems = append(problems, fmt.Errorf("schedule %q, %s: %v", schedule.Name, date.Format("2006-01-02"), err))
+				through = date.AddDate(0, 0, -1)
+				break
+			}
+			created = append(created, audit)
+			assigned := "unassigned"
+			if audit.AssignedUser != "" {
+				assigned = "assigned to " + audit.AssignedUser
+			}
+			addHistory(db, ActionScheduledAuditCreated, audit.Action,
+				fmt.Sprintf("schedule %q, due %s, %s", schedule.Name, date.Format("2006-01-02"), assigned))
+			log.Printf("Schedule %q created audit %d for %s", schedule.Name, audit.ID, date.Format("2006-01-02"))
+		}
+		if through.After(schedule.CreatedThrough) {
+			if err := db.SetScheduleCreatedThrough(schedule.ID, through); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	return created, errors.Join(problems...)
+}
+
+// StartScheduler creates scheduled audits now and then on the configured
+// interval until ctx is cancelled. Runs are skipped while the scheduler is
+// disabled in the configuration.
+func StartScheduler(ctx context.Context, db interfaces.DatabaseOperations) {
+	go func() {
+		for {
+			cfg := config.Current()
+			interval := time.Duration(cfg.Schedules.Interval)
+			if interval <= 0 {
+				interval = defaultScheduleInterval
+			}
+			if !cfg.Schedules.Disabled {
+				created, err := CreateScheduledAudits(db, time.Now())
+				if err != nil {
+					log.Printf("Scheduled audits failed: %v", err)
+				}
+				if len(created) > 0 {
+					log.Printf("Scheduled audits: %d created", len(created))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// CalendarEntry is an audit on the calendar: one a schedule has created, or
+// one it will create. Audit is nil until it has been created, and Schedule
+// is empty for an audit whose schedule has since been deleted.
+type CalendarEntry struct {
+	Date     time.Time
+	Schedule interfaces.AuditSchedule
+	Audit    *interfaces.Audits
+}
+
+// AuditCalendar lists the scheduled audits dated from from to to, along with
+// those schedules that are not paused will create from today on, by date
+func AuditCalendar(db interfaces.DatabaseOperations, from, to, now time.Time) ([]CalendarEntry, error) {
+	from, to = today(from), today(to)
+	schedules, err := db.GetSchedules()
+	if err != nil {
+		return nil, err
+	}
+	audits, err := db.GetScheduledAudits(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]interfaces.AuditSchedule, len(schedules))
+	for _, schedule := range schedules {
+		byID[schedule.ID] = schedule
+	}
+	type key struct {
+		schedule int
+		date     time.Time
+	}
+	exists := make(map[key]bool, len(audits))
+	var entries []CalendarEntry
+	for i := range audits {
+		audit := &audits[i]
+		exists[key{audit.ScheduleID, audit.ScheduledFor}] = true
+		entries = append(entries, CalendarEntry{Date: audit.ScheduledFor, Schedule: byID[audit.ScheduleID], Audit: audit})
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Paused {
+			continue
+		}
+		rule, err := schedule.Rule()
+		if err != nil {
+			continue
+		}
+		start := from
+		if day := today(now); start.Before(day) {
+			start = day
+		}
+		if !schedule.CreatedThrough.Before(start) {
+			start = schedule.CreatedThrough.AddDate(0, 0, 1)
+		}
+		for _, date := range rule.Between(schedule.StartDate, start, to) {
+			if !exists[key{schedule.ID, date}] {
+				entries = append(entries, CalendarEntry{Date: date, Schedule: schedule})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].Schedule.Name < entries[j].Schedule.Name
+	})
+	return entries, nil
+}
@@ -0,0 +1,192 @@
+package audits
+
+import (
+	// Standard Library
+	"strings"
+	"testing"
+	"time"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func date(text string) time.Time {
+	d, err := time.Parse("2006-01-02", text)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestCreateScheduledAudits(t *testing.T) {
+	db := fakes.NewDatabase()
+	if _, err := auth.SetLocalAccount(db, interfaces.Users{Username: "alice", Role: "manager"}, "local-password", false); err != nil {
+		t.Fatalf("SetLocalAccount: %v", err)
+	}
+	quarterly, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "Acme access review", Recurrence: "FREQ=MONTHLY;INTERVAL=3",
+		StartDate: date("2026-01-15"), LeadDays: 30, Firm: "Acme", AuditType: "Access", Assignee: "bob", CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	if _, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "Paused", Recurrence: "FREQ=DAILY",
+		StartDate: date("2026-01-01"), CreatedBy: "alice", Paused: true}); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+
+	created, err := CreateScheduledAudits(db, date("2026-03-20"))
+	if err != nil || len(created) != 1 {
+		t.Fatalf("CreateScheduledAudits = %+v, %v, want the April audit", created, err)
+	}
+	audit := created[0]
+	if !audit.ScheduledFor.Equal(date("2026-04-15")) || audit.ScheduleID != quarterly.ID || audit.Username != "alice" ||
+		audit.AssignedUser != "bob" || audit.Firm != "Acme" || audit.Status != Workflow().Initial() ||
+		audit.Action != "Acme access review 2026-04-15" {
+		t.Errorf("scheduled audit = %+v", audit)
+	}
+	if history, _ := db.GetChangeHistory(1); len(history) != 1 || history[0].Action != ActionScheduledAuditCreated {
+		t.Errorf("history = %+v, want the scheduled audit", history)
+	}
+
+	// Running again, or after the audit was deleted, creates nothing
+	if created, err := CreateScheduledAudits(db, date("2026-03-21")); err != nil || len(created) != 0 {
+		t.Errorf("second run = %+v, %v, want nothing", created, err)
+	}
+	if err := db.DeleteAudit(audit.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if created, err := CreateScheduledAudits(db, date("2026-04-01")); err != nil || len(created) != 0 {
+		t.Errorf("run after deleting = %+v, %v, want nothing", created, err)
+	}
+
+	// A date another goAudit created first is skipped
+	if _, err := db.CreateAudit(ScheduledAudit(quarterly, date("2026-07-15"))); err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	if created, err := CreateScheduledAudits(db, date("2026-06-20")); err != nil || len(created) != 0 {
+		t.Errorf("run with the audit already there = %+v, %v, want nothing", created, err)
+	}
+
+	entries, err := AuditCalendar(db, date("2026-01-01"), date("2027-01-31"), date("2026-06-20"))
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("AuditCalendar = %+v, %v, want July and the two to come", entries, err)
+	}
+	if !entries[0].Date.Equal(date("2026-07-15")) || entries[0].Audit == nil {
+		t.Errorf("first entry = %+v, want the created July audit", entries[0])
+	}
+	for i, want := range []string{"2026-10-15", "2027-01-15"} {
+		if entry := entries[i+1]; !entry.Date.Equal(date(want)) || entry.Audit != nil || entry.Schedule.ID != quarterly.ID {
+			t.Errorf("entry %d = %+v, want %s still to be created", i+1, entry, want)
+		}
+	}
+
+	// A schedule whose audit cannot be created stops at that date
+	broken, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "No template", Recurrence: "FREQ=WEEKLY",
+		StartDate: date("2026-06-22"), LeadDays: 14, TemplateID: 999, CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	if _, err := CreateScheduledAudits(db, date("2026-06-20")); err == nil {
+		t.Error("run with an audit that cannot be created returned no error")
+	}
+	if stored, _ := db.GetSchedule(broken.ID); !stored.CreatedThrough.Equal(date("2026-06-21")) {
+		t.Errorf("created through = %v, want the day before the failed date", stored.CreatedThrough)
+	}
+}
+
+func TestScheduledAuditsCheckTheCreator(t *testing.T) {
+	db := fakes.NewDatabase()
+	for username, role := range map[string]string{"alice": "manager", "carol": "auditor", "dave": "manager"} {
+		if _, err := auth.SetLocalAccount(db, interfaces.Users{Username: username, Role: role}, "local-password", false); err != nil {
+			t.Fatalf("auth.SetLocalAccount(%s): %v", username, err)
+		}
+	}
+	dave, _, _ := db.GetUsers("dave")
+	dave[0].Status = interfaces.UserInactive
+	if _, err := db.Update(dave[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// Roles from an identity provider are not known without a login
+	if _, err := db.GetOrCreateUser("erin"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+
+	var refused []interfaces.AuditSchedule
+	for _, creator := range []string{"ghost", "carol", "dave", "erin"} {
+		schedule, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "By " + creator, Recurrence: "FREQ=WEEKLY",
+			StartDate: date("2026-06-22"), LeadDays: 7, CreatedBy: creator})
+		if err != nil {
+			t.Fatalf("CreateSchedule: %v", err)
+		}
+		refused = append(refused, schedule)
+	}
+	if _, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "By alice", Recurrence: "FREQ=WEEKLY",
+		StartDate: date("2026-06-22"), LeadDays: 7, CreatedBy: "alice"}); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+
+	created, err := CreateScheduledAudits(db, date("2026-06-20"))
+	if err != nil || len(created) != 1 || created[0].Username != "alice" {
+		t.Fatalf("CreateScheduledAudits = %+v, %v, want only alice's audit", created, err)
+	}
+	for _, schedule := range refused {
+		if stored, _ := db.GetSchedule(schedule.ID); !stored.CreatedThrough.IsZero() {
+			t.Errorf("%s created through %v, want it skipped", schedule.Name, stored.CreatedThrough)
+		}
+	}
+	if _, _, err := db.GetUsers("ghost"); err == nil {
+		t.Error("the schedule of a missing creator added them back")
+	}
+
+	// Given the role back, carol's schedule picks up from the day it is run
+	if _, err := auth.SetLocalAccount(db, interfaces.Users{Username: "carol", Role: "manager"}, "local-password", false); err != nil {
+		t.Fatalf("SetLocalAccount: %v", err)
+	}
+	created, err = CreateScheduledAudits(db, date("2026-06-21"))
+	if err != nil || len(created) != 1 || created[0].Username != "carol" {
+		t.Errorf("run after promoting carol = %+v, %v, want her audit", created, err)
+	}
+}
+
+func TestScheduledAuditsWithAnInactiveAssignee(t *testing.T) {
+	db := fakes.NewDatabase()
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := auth.SetLocalAccount(db, interfaces.Users{Username: username, Role: "manager"}, "local-password", false); err != nil {
+			t.Fatalf("auth.SetLocalAccount(%s): %v", username, err)
+		}
+	}
+	for _, assignee := range []string{"bob", "carol"} {
+		if _, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "For " + assignee, Recurrence: "FREQ=WEEKLY",
+			StartDate: date("2026-06-22"), LeadDays: 7, Assignee: assignee, CreatedBy: "alice"}); err != nil {
+			t.Fatalf("CreateSchedule: %v", err)
+		}
+	}
+	bob, _, _ := db.GetUsers("bob")
+	bob[0].Status = interfaces.UserInactive
+	if _, err := db.Update(bob[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	created, err := CreateScheduledAudits(db, date("2026-06-20"))
+	if err != nil || len(created) != 2 {
+		t.Fatalf("CreateScheduledAudits = %+v, %v, want both audits", created, err)
+	}
+	// carol has no account yet, so her audit is hers as for any assignee
+	want := map[string]string{"For bob 2026-06-22": "", "For carol 2026-06-22": "carol"}
+	for _, audit := range created {
+		if assignee, ok := want[audit.Action]; !ok || audit.AssignedUser != assignee || audit.Username != "alice" {
+			t.Errorf("audit %q = %+v, want it alice's and assigned to %q", audit.Action, audit, assignee)
+		}
+	}
+	history, _ := db.GetChangeHistory(10)
+	unassigned := 0
+	for _, entry := range history {
+		if entry.Action == ActionScheduledAuditCreated && strings.HasSuffix(entry.Detail, ", unassigned") {
+			unassigned++
+		}
+	}
+	if unassigned != 1 {
+		t.Errorf("history = %+v, want bob's audit recorded as unassigned", history)
+	}
+}
@@ -0,0 +1,28 @@
+// Package audits is the audit work goAudit does on top of the store. Who the
+// user is and what they may do stays in auth and rbac.
+package audits
+
+import (
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+// Workflow is the audit life cycle from the config files, or the built-in
+// one when none is configured
+func Workflow() rbac.Workflow {
+	configured := config.Current().AuditWorkflow
+	if !configured.Configured() {
+		return rbac.DefaultWorkflow
+	}
+	workflow := rbac.Workflow{States: append([]string(nil), configured.States...)}
+	for _, transition := range configured.Transitions {
+		workflow.Transitions = append(workflow.Transitions, rbac.Transition{
+			From:    transition.From,
+			To:      transition.To,
+			By:      append([]string(nil), transition.By...),
+			Comment: transition.Comment,
+		})
+	}
+	return workflow
+}
@@ -0,0 +1,32 @@
+package audits
+
+import (
+	// Standard Library
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+func TestWorkflow(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	config.Use(config.Defaults())
+
+	if got := Workflow(); got.Initial() != rbac.DefaultWorkflow.Initial() || len(got.Transitions) != len(rbac.DefaultWorkflow.Transitions) {
+		t.Errorf("Workflow without configuration = %+v, want the default", got)
+	}
+
+	cfg := config.Defaults()
+	cfg.AuditWorkflow = config.AuditWorkflow{
+		States:      []string{"Open", "Signed Off"},
+		Transitions: []config.AuditTransition{{From: "Open", To: "Signed Off", By: []string{"reviewer"}, Comment: true}},
+	}
+	config.Use(cfg)
+	got := Workflow()
+	if got.Initial() != "Open" || got.Final() != "Signed Off" || len(got.Transitions) != 1 ||
+		!got.Transitions[0].Comment || got.Transitions[0].By[0] != rbac.PartyReviewer {
+		t.Errorf("Workflow = %+v", got)
+	}
+}
@@ -5,14 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	// Fyne Imports
 
 	// External Imports
 	"github.com/go-ldap/ldap/v3"
-	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
 
 	// Internal Imports
@@ -34,82 +32,116 @@ func NewAuth(db interfaces.DatabaseOperations, ldap interfaces.LDAPOperations) *
 	}
 }
 
-// Connect to the LDAP server using the provided username and password
-func (lw *LDAPWrapper) ConnectToAdServer(username, password string) (*interfaces.LDAPConnection, error) {
-	err := godotenv.Load(".env")
-	if err != nil {
-		return nil, fmt.Errorf("error loading .env file: %w", err)
-	}
+var _ interfaces.Authenticator = (*LDAPWrapper)(nil)
 
-	server := os.Getenv("LDAP_SERVER")
-	domain := os.Getenv("LDAP_DOMAIN")
-	ou := os.Getenv("LDAP_OU")
-	readonlyPassword := os.Getenv("LDAP_READONLY_PASSWORD")
+func (lw *LDAPWrapper) Name() string {
+	return SourceLDAP
+}
 
-	if server == "" || domain == "" || ou == "" || readonlyPassword == "" {
-		return nil, fmt.Errorf("one or more required environment variables are not set")
+// Authenticate logs in to the directory for the auth chain
+func (lw *LDAPWrapper) Authenticate(username, password string) (*interfaces.Login, error) {
+	conn, err := lw.ConnectToAdServer(username, password)
+	if err != nil {
+		return nil, err
 	}
+	return &interfaces.Login{Username: conn.Username, Source: SourceLDAP, LDAP: conn}, nil
+}
 
-	ldapServer := fmt.Sprintf("ldaps://%s.%s:636", server, domain)
-	log.Println("Connecting to:", ldapServer)
-
-	l, err := ldap.DialURL(ldapServer)
+// Connect to the LDAP server using the provided username and password.
+// Errors match interfaces.ErrAuthUnavailable when the directory cannot be
+// used, ErrUnknownUser when it has no such user and ErrInvalidCredentials
+// when it rejects the password.
+func (lw *LDAPWrapper) ConnectToAdServer(username, password string) (*interfaces.LDAPConnection, error) {
+	settings, err := LoadLDAPSettings()
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial LDAP server: %w", err)
+		return nil, fmt.Errorf("%w: %v", interfaces.ErrAuthUnavailable, err)
+	}
+	if settings.Validate() != nil {
+		return nil, fmt.Errorf("%w: LDAP is not configured; ask an administrator to complete the LDAP settings", interfaces.ErrAuthUnavailable)
+	}
+	// An empty password would be an anonymous bind, which always succeeds
+	if password == "" {
+		return nil, fmt.Errorf("password is required")
 	}
 
-	// Construct the bindDN
-	domainParts := strings.Split(domain, ".")
-	dcString := strings.Join(domainParts, ",DC=")
-	bindDN := fmt.Sprintf("CN=readonly,CN=Users,DC=%s", strings.Replace(domain, ".", ",DC=", -1))
-
-	log.Printf("Binding as read-only user: %s", bindDN)
-	err = l.Bind(bindDN, readonlyPassword)
+	address, _ := settings.Address()
+	log.Println("Connecting to:", address)
+	log.Printf("Binding as read-only user: %s", settings.ReadOnlyBindDN())
+	l, err := dialReadOnly(settings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to bind to LDAP server as read-only: %w", err)
-	}
-
-	// Search for user
-	searchFilter := fmt.Sprintf("(&(objectClass=user)(sAMAccountName=%s))", ldap.EscapeFilter(username))
-	searchBase := fmt.Sprintf("OU=%s,DC=%s", ou, dcString)
-	searchRequest := ldap.NewSearchRequest(
-		searchBase,
-		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
-		searchFilter,
-		[]string{"distinguishedName"},
-		nil,
-	)
-	log.Printf("Searching for user with filter: %s in base: %s", searchFilter, searchBase)
-	sr, err := l.Search(searchRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search for user: %w", err)
-	}
-	if len(sr.Entries) != 1 {
-		return nil, fmt.Errorf("user not found or too many entries returned")
+		return nil, fmt.Errorf("%w: %v", interfaces.ErrAuthUnavailable, err)
 	}
 
-	userDN := sr.Entries[0].DN
+	entry, err := findUser(l, settings, username)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	userDN := entry.DN
 	log.Printf("Found user: %s", userDN)
 
 	// Now bind as the user
 	log.Printf("Attempting to bind as user: %s", userDN)
 	err = l.Bind(userDN, password)
 	if err != nil {
+		l.Close()
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("failed to bind as user: %w", interfaces.ErrInvalidCredentials)
+		}
 		return nil, fmt.Errorf("failed to bind as user: %w", err)
 	}
 
 	log.Println("Successfully authenticated user")
 	return &interfaces.LDAPConnection{
-		Conn:     l,
-		Username: username,
-		Password: password,
-		Server:   server,
-		Domain:   domain,
+		Conn:        l,
+		Username:    username,
+		Server:      settings.Server,
+		Domain:      settings.Domain,
+		DN:          userDN,
+		DisplayName: entry.GetAttributeValue(settings.DisplayNameAttr()),
+		Email:       entry.GetAttributeValue(settings.EmailAttr()),
+		Groups:      entry.GetAttributeValues(settings.MemberOfAttr()),
 	}, nil
 }
 
-// Logout as current user
+// findUser searches each user search base in turn for exactly one account
+// matching the user filter
+func findUser(l *ldap.Conn, settings LDAPSettings, username string) (*ldap.Entry, error) {
+	searchFilter := settings.UserSearchFilter(username)
+	attributes := []string{settings.UsernameAttr(), settings.DisplayNameAttr(), settings.EmailAttr(), settings.MemberOfAttr()}
+
+	var found []*ldap.Entry
+	for _, searchBase := range settings.UserSearchBases() {
+		searchRequest := ldap.NewSearchRequest(
+			searchBase,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapDialTimeout.Seconds()), false,
+			searchFilter,
+			attributes,
+			nil,
+		)
+		log.Printf("Searching for user with filter: %s in base: %s", searchFilter, searchBase)
+		sr, err := l.Search(searchRequest)
+		if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, fmt.Errorf("%w: failed to search for user in %s: %w", interfaces.ErrAuthUnavailable, searchBase, err)
+		}
+		if sr != nil {
+			found = append(found, sr.Entries...)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: %s is not in the directory", interfaces.ErrUnknownUser, username)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("more than one directory entry matches %s", username)
+	}
+	return found[0], nil
+}
+
+// Logout as current user. Local account logins have no connection to close.
 func (lw *LDAPWrapper) LogoutUser(c *interfaces.LDAPConnection) error {
+	if c == nil || c.Conn == nil {
+		return nil
+	}
 	log.Println("Logging out as:", c.Username)
 	return c.Conn.Close()
 }
@@ -191,7 +223,7 @@ func AuthenticateUser(db interfaces.DatabaseOperations, username, password strin
 		return nil, err
 	}
 	if len(users) == 0 {
-		return nil, errors.New("user not found")
+		return nil, fmt.Errorf("%w: user not found", interfaces.ErrUnknownUser)
 	}
 
 	user := users[0]
@@ -204,7 +236,7 @@ func AuthenticateUser(db interfaces.DatabaseOperations, username, password strin
 
 	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid password")
+		return nil, fmt.Errorf("%w: invalid password", interfaces.ErrInvalidCredentials)
 	}
 
 	return &user, nil
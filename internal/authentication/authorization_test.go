@@ -0,0 +1,85 @@
+package auth
+
+import (
+	// Standard Library
+	"testing"
+
+	// External Imports
+	"golang.org/x/crypto/bcrypt"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+)
+
+// The fake directory must agree with the real one on everything that does not
+// need a server, or tests written against it prove nothing.
+func TestFakeLDAPMatchesHelpers(t *testing.T) {
+	real := &LDAPWrapper{}
+	fake := fakes.NewLDAP("ldap.example.com", "example.com", nil)
+
+	for _, domain := range []string{"example.com", "Corp.Example.com", "example", "a.b.c.d"} {
+		if got, want := fake.DomaintoOU(domain), real.DomaintoOU(domain); got != want {
+			t.Errorf("DomaintoOU(%q) = %q, want %q", domain, got, want)
+		}
+		if got, want := fake.IsProperDomain(domain), real.IsProperDomain(domain); got != want {
+			t.Errorf("IsProperDomain(%q) = %v, want %v", domain, got, want)
+		}
+		if got, want := fake.OUwithDomain("Users", domain), real.OUwithDomain("Users", domain); got != want {
+			t.Errorf("OUwithDomain(Users, %q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestFakeLDAPConnect(t *testing.T) {
+	directory := fakes.NewLDAP("ldap.example.com", "example.com", nil)
+	directory.AddUser("alice", "secret")
+
+	if _, err := directory.ConnectToAdServer("alice", "wrong"); err == nil {
+		t.Error("ConnectToAdServer accepted a wrong password")
+	}
+	if _, err := directory.ConnectToAdServer("bob", "secret"); err == nil {
+		t.Error("ConnectToAdServer accepted an unknown user")
+	}
+
+	conn, err := directory.ConnectToAdServer("alice", "secret")
+	if err != nil {
+		t.Fatalf("ConnectToAdServer: %v", err)
+	}
+	if !directory.LoggedIn("alice") {
+		t.Error("user is not logged in after connecting")
+	}
+	if err := directory.LogoutUser(conn); err != nil {
+		t.Fatalf("LogoutUser: %v", err)
+	}
+	if directory.LoggedIn("alice") {
+		t.Error("user is still logged in after LogoutUser")
+	}
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	db := fakes.NewDatabase()
+	if _, err := db.GetOrCreateUser("alice"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	if _, err := db.CreateCredUser("alice", string(hash), ""); err != nil {
+		t.Fatalf("CreateCredUser: %v", err)
+	}
+
+	user, err := AuthenticateUser(db, "alice", "secret")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("AuthenticateUser returned %+v", user)
+	}
+	if _, err := AuthenticateUser(db, "alice", "wrong"); err == nil {
+		t.Error("AuthenticateUser accepted a wrong password")
+	}
+	if _, err := AuthenticateUser(db, "bob", "secret"); err == nil {
+		t.Error("AuthenticateUser accepted an unknown user")
+	}
+}
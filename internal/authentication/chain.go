@@ -0,0 +1,76 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"log"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Names of the login sources in the auth chain configuration
+const (
+	SourceLDAP  = "ldap"
+	SourceLocal = "local"
+	SourceOIDC  = "oidc"
+)
+
+// Chain tries its authenticators in order. The next one is asked only when
+// the previous one is unavailable or does not know the user; a wrong password
+// ends the login.
+type Chain []interfaces.Authenticator
+
+// NewChain orders the available authenticators by sources. Sources without
+// an authenticator are skipped.
+func NewChain(sources []string, available ...interfaces.Authenticator) Chain {
+	var chain Chain
+	for _, source := range sources {
+		for _, a := range available {
+			if a != nil && a.Name() == source {
+				chain = append(chain, a)
+				break
+			}
+		}
+	}
+	return chain
+}
+
+func (c Chain) Authenticate(username, password string) (*interfaces.Login, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+	if len(c) == 0 {
+		return nil, fmt.Errorf("%w: no login sources are configured", interfaces.ErrAuthUnavailable)
+	}
+
+	var unavailable []error
+	for _, a := range c {
+		login, err := a.Authenticate(username, password)
+		switch {
+		case err == nil:
+			log.Printf("%s logged in through %s", username, a.Name())
+			return login, nil
+		case errors.Is(err, interfaces.ErrAuthUnavailable):
+			log.Printf("Login source %s is unavailable: %v", a.Name(), err)
+			unavailable = append(unavailable, fmt.Errorf("%s: %w", a.Name(), err))
+		case errors.Is(err, interfaces.ErrUnknownUser):
+			log.Printf("Login source %s: %v", a.Name(), err)
+		case errors.Is(err, interfaces.ErrInvalidCredentials):
+			// Which source rejected the password is only logged
+			log.Printf("Login source %s: %v", a.Name(), err)
+			return nil, interfaces.ErrInvalidCredentials
+		default:
+			return nil, err
+		}
+	}
+
+	if len(unavailable) == len(c) {
+		return nil, fmt.Errorf("no login source is available:\n%w", errors.Join(unavailable...))
+	}
+	if len(unavailable) > 0 {
+		return nil, fmt.Errorf("%w\n\nSome login sources could not be reached:\n%v", interfaces.ErrInvalidCredentials, errors.Join(unavailable...))
+	}
+	return nil, interfaces.ErrInvalidCredentials
+}
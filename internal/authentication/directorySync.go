@@ -0,0 +1,162 @@
+package auth
+
+import (
+	// Standard Library
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	// External Imports
+	"github.com/go-ldap/ldap/v3"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// How often the directory is synced when the configuration does not say
+const defaultSyncInterval = time.Hour
+
+// Active Directory sets this userAccountControl bit on disabled accounts
+const accountDisabled = 0x2
+
+// syncPageSize is how many entries each paged search request returns
+const syncPageSize = 500
+
+// SyncDirectory reads every user the sync filter selects and upserts them
+// into the users table. Users missing from the directory are marked inactive.
+func SyncDirectory(db interfaces.DatabaseOperations) (interfaces.SyncResult, error) {
+	settings, err := LoadLDAPSettings()
+	if err != nil {
+		return interfaces.SyncResult{}, err
+	}
+	l, err := dialReadOnly(settings)
+	if err != nil {
+		return interfaces.SyncResult{}, err
+	}
+	defer l.Close()
+
+	users, err := readDirectoryUsers(l, settings)
+	if err != nil {
+		return interfaces.SyncResult{}, err
+	}
+	result, err := db.SyncDirectoryUsers(users, true)
+	if err != nil {
+		return interfaces.SyncResult{}, fmt.Errorf("failed to store directory users: %v", err)
+	}
+	return result, nil
+}
+
+// StartDirectorySync syncs now and then on the configured interval until ctx
+// is cancelled. Runs are skipped while the directory is not configured or
+// the sync is disabled.
+func StartDirectorySync(ctx context.Context, db interfaces.DatabaseOperations) {
+	go func() {
+		for {
+			cfg := config.Current()
+			interval := time.Duration(cfg.LDAP.SyncInterval)
+			if interval <= 0 {
+				interval = defaultSyncInterval
+			}
+			if cfg.LDAPConfigured() && !cfg.LDAP.SyncDisabled {
+				result, err := SyncDirectory(db)
+				if err != nil {
+					log.Printf("Directory sync failed: %v", err)
+				} else {
+					log.Printf("Directory sync: %d created, %d updated, %d deactivated",
+						result.Created, result.Updated, result.Deactivated)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// syncAttributes are read for every user the sync or reporting tree sees
+func syncAttributes(settings LDAPSettings) []string {
+	attributes := []string{
+		settings.UsernameAttr(), settings.DisplayNameAttr(), settings.EmailAttr(),
+		settings.ManagerAttr(), settings.DepartmentAttr(),
+		"userAccountControl", "objectGUID", "entryUUID",
+	}
+	if settings.IDAttribute != "" {
+		attributes = append(attributes, settings.IDAttribute)
+	}
+	return attributes
+}
+
+// readDirectoryUsers pages through every search base. Users found under more
+// than one base are returned once.
+func readDirectoryUsers(l *ldap.Conn, settings LDAPSettings) ([]interfaces.LDAPUser, error) {
+	seen := make(map[string]bool)
+	var users []interfaces.LDAPUser
+	for _, base := range settings.UserSearchBases() {
+		searchRequest := ldap.NewSearchRequest(
+			base,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			settings.SyncSearchFilter(),
+			syncAttributes(settings),
+			nil,
+		)
+		sr, err := l.SearchWithPaging(searchRequest, syncPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for users in %s: %w", base, err)
+		}
+		for _, entry := range sr.Entries {
+			user := entryToUser(entry, settings)
+			if user.Username == "" || user.DirectoryID == "" || seen[user.DirectoryID] {
+				continue
+			}
+			seen[user.DirectoryID] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// entryToUser reads the sync attributes of one directory entry
+func entryToUser(entry *ldap.Entry, settings LDAPSettings) interfaces.LDAPUser {
+	user := interfaces.LDAPUser{
+		DN:          entry.DN,
+		Username:    entry.GetAttributeValue(settings.UsernameAttr()),
+		DisplayName: entry.GetAttributeValue(settings.DisplayNameAttr()),
+		Email:       entry.GetAttributeValue(settings.EmailAttr()),
+		ManagerDN:   entry.GetAttributeValue(settings.ManagerAttr()),
+		Department:  entry.GetAttributeValue(settings.DepartmentAttr()),
+		DirectoryID: directoryID(entry, settings),
+	}
+	if uac, err := strconv.ParseInt(entry.GetAttributeValue("userAccountControl"), 10, 64); err == nil {
+		user.Disabled = uac&accountDisabled != 0
+	}
+	return user
+}
+
+// directoryID is the configured ID attribute, or else objectGUID or
+// entryUUID, whichever the directory has
+func directoryID(entry *ldap.Entry, settings LDAPSettings) string {
+	if settings.IDAttribute != "" && settings.IDAttribute != "objectGUID" {
+		return entry.GetAttributeValue(settings.IDAttribute)
+	}
+	if guid := entry.GetRawAttributeValue("objectGUID"); len(guid) == 16 {
+		return formatGUID(guid)
+	}
+	if settings.IDAttribute == "" {
+		return entry.GetAttributeValue("entryUUID")
+	}
+	return ""
+}
+
+// formatGUID writes a binary objectGUID the way Active Directory displays it,
+// with the first three groups little-endian
+func formatGUID(b []byte) string {
+	return fmt.Sprintf("%02x%02x%02x%02x-%02x%02x-%02x%02x-%02x%02x-%02x%02x%02x%02x%02x%02x",
+		b[3], b[2], b[1], b[0], b[5], b[4], b[7], b[6],
+		b[8], b[9], b[10], b[11], b[12], b[13], b[14], b[15])
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	// Standard Library
+	"testing"
+
+	// External Imports
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestEntryToUser(t *testing.T) {
+	// objectGUID as Active Directory stores it, with the first three groups
+	// little-endian
+	guid := []byte{0x78, 0x56, 0x34, 0x12, 0xbc, 0x9a, 0xf0, 0xde, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	entry := &ldap.Entry{
+		DN: "CN=Bob,OU=Staff,DC=example,DC=com",
+		Attributes: []*ldap.EntryAttribute{
+			{Name: "sAMAccountName", Values: []string{"bob"}},
+			{Name: "department", Values: []string{"Audit"}},
+			{Name: "manager", Values: []string{"CN=Alice,OU=Staff,DC=example,DC=com"}},
+			{Name: "userAccountControl", Values: []string{"514"}},
+			{Name: "objectGUID", Values: []string{string(guid)}, ByteValues: [][]byte{guid}},
+		},
+	}
+
+	user := entryToUser(entry, LDAPSettings{})
+	if user.Username != "bob" || user.Department != "Audit" || user.ManagerDN != "CN=Alice,OU=Staff,DC=example,DC=com" {
+		t.Errorf("entryToUser = %+v", user)
+	}
+	if want := "12345678-9abc-def0-0102-030405060708"; user.DirectoryID != want {
+		t.Errorf("DirectoryID = %q, want %q", user.DirectoryID, want)
+	}
+	if !user.Disabled {
+		t.Error("userAccountControl 514 should mark the user disabled")
+	}
+
+	entry.Attributes = []*ldap.EntryAttribute{
+		{Name: "uid", Values: []string{"carol"}},
+		{Name: "entryUUID", Values: []string{"6f1b3c1e-0000-4000-8000-000000000001"}},
+	}
+	user = entryToUser(entry, LDAPSettings{UsernameAttribute: "uid"})
+	if user.Username != "carol" || user.DirectoryID != "6f1b3c1e-0000-4000-8000-000000000001" || user.Disabled {
+		t.Errorf("OpenLDAP entry = %+v, want carol identified by entryUUID", user)
+	}
+}
+
+func TestSyncSearchFilter(t *testing.T) {
+	if got := (LDAPSettings{}).SyncSearchFilter(); got != "(&(objectClass=user)(sAMAccountName=*))" {
+		t.Errorf("default SyncSearchFilter = %q", got)
+	}
+	settings := LDAPSettings{UserFilter: "(uid={username})", SyncFilter: "(objectClass=person)"}
+	if got := settings.SyncSearchFilter(); got != "(objectClass=person)" {
+		t.Errorf("SyncSearchFilter = %q, want the sync filter", got)
+	}
+}
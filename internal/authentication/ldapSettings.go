@@ -0,0 +1,383 @@
+package auth
+
+import (
+	// Standard Library
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// External Imports
+	"github.com/go-ldap/ldap/v3"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
+)
+
+// How long to wait for the directory before giving up
+const ldapDialTimeout = 10 * time.Second
+
+// Active Directory names, used when the configuration does not say otherwise
+const (
+	defaultUserFilter             = "(&(objectClass=user)(sAMAccountName={username}))"
+	defaultUsernameAttribute      = "sAMAccountName"
+	defaultDisplayNameAttribute   = "displayName"
+	defaultEmailAttribute         = "mail"
+	defaultMemberOfAttribute      = "memberOf"
+	defaultManagerAttribute       = "manager"
+	defaultDirectReportsAttribute = "directReports"
+	defaultDepartmentAttribute    = "department"
+)
+
+// LDAPSettings describes the directory. Server, Domain and OU are the Active
+// Directory shorthand; every other field overrides what is derived from them.
+type LDAPSettings struct {
+	Server           string
+	Domain           string
+	OU               string
+	ReadOnlyPassword string
+
+	URL         string
+	Port        int
+	Security    string
+	CACert      string
+	BindDN      string
+	SearchBases []string
+	UserFilter  string
+
+	UsernameAttribute      string
+	DisplayNameAttribute   string
+	EmailAttribute         string
+	MemberOfAttribute      string
+	ManagerAttribute       string
+	DirectReportsAttribute string
+	DepartmentAttribute    string
+	IDAttribute            string
+
+	SyncInterval time.Duration
+	SyncFilter   string
+	SyncDisabled bool
+}
+
+// LoadLDAPSettings returns the LDAP settings from the active configuration,
+// with the read-only password fetched from the secret store
+func LoadLDAPSettings() (LDAPSettings, error) {
+	return LDAPSettingsFromConfig(config.Current().LDAP)
+}
+
+// LDAPSettingsFromConfig converts c, fetching the read-only password from the
+// secret store when it holds a reference
+func LDAPSettingsFromConfig(c config.LDAP) (LDAPSettings, error) {
+	settings := LDAPSettings{
+		Server:                 c.Server,
+		Domain:                 c.Domain,
+		OU:                     c.OU,
+		URL:                    c.URL,
+		Port:                   c.Port,
+		Security:               c.Security,
+		CACert:                 c.CACert,
+		BindDN:                 c.BindDN,
+		SearchBases:            append([]string(nil), c.SearchBases...),
+		UserFilter:             c.UserFilter,
+		UsernameAttribute:      c.Attributes.Username,
+		DisplayNameAttribute:   c.Attributes.DisplayName,
+		EmailAttribute:         c.Attributes.Email,
+		MemberOfAttribute:      c.Attributes.MemberOf,
+		ManagerAttribute:       c.Attributes.Manager,
+		DirectReportsAttribute: c.Attributes.DirectReports,
+		DepartmentAttribute:    c.Attributes.Department,
+		IDAttribute:            c.Attributes.ID,
+		SyncInterval:           time.Duration(c.SyncInterval),
+		SyncFilter:             c.SyncFilter,
+		SyncDisabled:           c.SyncDisabled,
+	}
+	password, err := secrets.Resolve(c.ReadOnlyPassword)
+	if err != nil {
+		return settings, fmt.Errorf("LDAP read-only password: %v", err)
+	}
+	settings.ReadOnlyPassword = password
+	return settings, nil
+}
+
+// Config converts the settings for saving. A new password is moved into the
+// secret store and only its reference goes in the config; existing is what is
+// configured now.
+func (s LDAPSettings) Config(existing config.LDAP) (config.LDAP, error) {
+	ref, err := secrets.Update(existing.ReadOnlyPassword, secrets.Name("LDAP_READONLY_PASSWORD"), s.ReadOnlyPassword)
+	if err != nil {
+		return config.LDAP{}, err
+	}
+	return config.LDAP{
+		Server:           s.Server,
+		Domain:           s.Domain,
+		OU:               s.OU,
+		ReadOnlyPassword: ref,
+		URL:              s.URL,
+		Port:             s.Port,
+		Security:         s.Security,
+		CACert:           s.CACert,
+		BindDN:           s.BindDN,
+		SearchBases:      append([]string(nil), s.SearchBases...),
+		UserFilter:       s.UserFilter,
+		Attributes: config.LDAPAttributes{
+			Username:      s.UsernameAttribute,
+			DisplayName:   s.DisplayNameAttribute,
+			Email:         s.EmailAttribute,
+			MemberOf:      s.MemberOfAttribute,
+			Manager:       s.ManagerAttribute,
+			DirectReports: s.DirectReportsAttribute,
+			Department:    s.DepartmentAttribute,
+			ID:            s.IDAttribute,
+		},
+		SyncInterval: config.Duration(s.SyncInterval),
+		SyncFilter:   s.SyncFilter,
+		SyncDisabled: s.SyncDisabled,
+	}, nil
+}
+
+func (s LDAPSettings) Validate() error {
+	switch {
+	case s.URL == "" && (s.Server == "" || s.Domain == ""):
+		return fmt.Errorf("LDAP URL, or server and domain, are required")
+	case s.BindDN == "" && s.Domain == "":
+		return fmt.Errorf("LDAP bind DN, or a domain to derive it from, is required")
+	case len(s.SearchBases) == 0 && s.Domain == "":
+		return fmt.Errorf("LDAP search base, or a domain to derive it from, is required")
+	case s.ReadOnlyPassword == "":
+		return fmt.Errorf("LDAP read-only password is required")
+	}
+	return nil
+}
+
+// SecurityMode is ldaps, starttls or none. It defaults to LDAPS for the
+// Active Directory shorthand and ldaps:// URLs, and StartTLS for ldap:// URLs.
+func (s LDAPSettings) SecurityMode() string {
+	if s.Security != "" {
+		return s.Security
+	}
+	if s.URL != "" && strings.HasPrefix(s.URL, "ldap://") {
+		return "starttls"
+	}
+	return "ldaps"
+}
+
+// Address returns the URL to dial, with the scheme matching the security
+// mode and the port filled in
+func (s LDAPSettings) Address() (string, error) {
+	scheme := "ldap"
+	port := 389
+	if s.SecurityMode() == "ldaps" {
+		scheme, port = "ldaps", 636
+	}
+	if s.Port != 0 {
+		port = s.Port
+	}
+
+	host := fmt.Sprintf("%s.%s", s.Server, s.Domain)
+	if s.URL != "" {
+		parsed, err := url.Parse(s.URL)
+		if err != nil || parsed.Hostname() == "" {
+			return "", fmt.Errorf("invalid LDAP URL %q", s.URL)
+		}
+		host = parsed.Hostname()
+		if parsed.Port() != "" && s.Port == 0 {
+			port, _ = strconv.Atoi(parsed.Port())
+		}
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port))), nil
+}
+
+// domainDN turns example.com into DC=example,DC=com
+func (s LDAPSettings) domainDN() string {
+	return "DC=" + strings.Join(strings.Split(s.Domain, "."), ",DC=")
+}
+
+// ReadOnlyBindDN is the account used to look users up
+func (s LDAPSettings) ReadOnlyBindDN() string {
+	if s.BindDN != "" {
+		return s.BindDN
+	}
+	return "CN=readonly,CN=Users," + s.domainDN()
+}
+
+// UserSearchBases are searched in order for user accounts
+func (s LDAPSettings) UserSearchBases() []string {
+	if len(s.SearchBases) > 0 {
+		return s.SearchBases
+	}
+	if s.OU == "" {
+		return []string{s.domainDN()}
+	}
+	return []string{fmt.Sprintf("OU=%s,%s", s.OU, s.domainDN())}
+}
+
+// SearchBase is the first user search base
+func (s LDAPSettings) SearchBase() string {
+	return s.UserSearchBases()[0]
+}
+
+// UserSearchFilter fills the filter template with the escaped login name
+func (s LDAPSettings) UserSearchFilter(username string) string {
+	filter := s.UserFilter
+	if filter == "" {
+		filter = defaultUserFilter
+	}
+	return strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+}
+
+// SyncSearchFilter selects every user the directory sync reads: the sync
+// filter if set, otherwise the login filter matching any name
+func (s LDAPSettings) SyncSearchFilter() string {
+	if s.SyncFilter != "" {
+		return s.SyncFilter
+	}
+	filter := s.UserFilter
+	if filter == "" {
+		filter = defaultUserFilter
+	}
+	return strings.ReplaceAll(filter, "{username}", "*")
+}
+
+func (s LDAPSettings) UsernameAttr() string {
+	return firstNonEmpty(s.UsernameAttribute, defaultUsernameAttribute)
+}
+
+func (s LDAPSettings) DisplayNameAttr() string {
+	return firstNonEmpty(s.DisplayNameAttribute, defaultDisplayNameAttribute)
+}
+
+func (s LDAPSettings) EmailAttr() string {
+	return firstNonEmpty(s.EmailAttribute, defaultEmailAttribute)
+}
+
+func (s LDAPSettings) MemberOfAttr() string {
+	return firstNonEmpty(s.MemberOfAttribute, defaultMemberOfAttribute)
+}
+
+func (s LDAPSettings) ManagerAttr() string {
+	return firstNonEmpty(s.ManagerAttribute, defaultManagerAttribute)
+}
+
+func (s LDAPSettings) DirectReportsAttr() string {
+	return firstNonEmpty(s.DirectReportsAttribute, defaultDirectReportsAttribute)
+}
+
+func (s LDAPSettings) DepartmentAttr() string {
+	return firstNonEmpty(s.DepartmentAttribute, defaultDepartmentAttribute)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// TLSConfig verifies the directory against the system roots plus CACert
+func (s LDAPSettings) TLSConfig(host string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if s.CACert == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(s.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read LDAP CA bundle: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in LDAP CA bundle %s", s.CACert)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// dial connects to the directory, protected as the security mode says
+func (s LDAPSettings) dial() (*ldap.Conn, error) {
+	address, err := s.Address()
+	if err != nil {
+		return nil, err
+	}
+	parsed, _ := url.Parse(address)
+	tlsConfig, err := s.TLSConfig(parsed.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	options := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: ldapDialTimeout})}
+	if s.SecurityMode() == "ldaps" {
+		options = append(options, ldap.DialWithTLSConfig(tlsConfig))
+	}
+	l, err := ldap.DialURL(address, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial LDAP server: %w", err)
+	}
+	l.SetTimeout(ldapDialTimeout)
+
+	switch s.SecurityMode() {
+	case "starttls":
+		if err := l.StartTLS(tlsConfig); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to start TLS with LDAP server: %w", err)
+		}
+	case "none":
+		log.Printf("Warning: LDAP security is none; passwords are sent to %s unencrypted", address)
+	}
+	return l, nil
+}
+
+// dialReadOnly connects to the directory and binds as the read-only account
+func dialReadOnly(s LDAPSettings) (*ldap.Conn, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	l, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.Bind(s.ReadOnlyBindDN(), s.ReadOnlyPassword)
+	if err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to bind to LDAP server as read-only: %w", err)
+	}
+	return l, nil
+}
+
+// CheckLDAPConnection dials, binds as the read-only account and confirms the
+// search bases exist, without logging anyone in.
+func CheckLDAPConnection(s LDAPSettings) error {
+	l, err := dialReadOnly(s)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for _, base := range s.UserSearchBases() {
+		searchRequest := ldap.NewSearchRequest(
+			base,
+			ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapDialTimeout.Seconds()), false,
+			"(objectClass=*)",
+			[]string{"1.1"},
+			nil,
+		)
+		_, err = l.Search(searchRequest)
+		if err != nil {
+			return fmt.Errorf("bound successfully but could not read %s: %w", base, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	// Standard Library
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+)
+
+func TestLDAPAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings LDAPSettings
+		want     string
+	}{
+		{"active directory shorthand", LDAPSettings{Server: "dc01", Domain: "example.com"}, "ldaps://dc01.example.com:636"},
+		{"ldap url defaults to starttls", LDAPSettings{URL: "ldap://ldap.example.org"}, "ldap://ldap.example.org:389"},
+		{"ldaps url", LDAPSettings{URL: "ldaps://ldap.example.org"}, "ldaps://ldap.example.org:636"},
+		{"port in url", LDAPSettings{URL: "ldap://localhost:3893", Security: "none"}, "ldap://localhost:3893"},
+		{"port setting wins", LDAPSettings{URL: "ldaps://ldap:1636", Port: 2636}, "ldaps://ldap:2636"},
+		{"ldaps security on ldap url", LDAPSettings{URL: "ldap://ldap.example.org", Security: "ldaps"}, "ldaps://ldap.example.org:636"},
+		{"ipv6", LDAPSettings{URL: "ldap://[::1]:389", Security: "none"}, "ldap://[::1]:389"},
+	}
+	for _, tc := range tests {
+		got, err := tc.settings.Address()
+		if err != nil || got != tc.want {
+			t.Errorf("%s: Address() = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestLDAPDerivedNames(t *testing.T) {
+	ad := LDAPSettings{Server: "dc01", Domain: "corp.example.com", OU: "Staff"}
+	if got := ad.ReadOnlyBindDN(); got != "CN=readonly,CN=Users,DC=corp,DC=example,DC=com" {
+		t.Errorf("ReadOnlyBindDN = %q", got)
+	}
+	if got := ad.UserSearchBases(); len(got) != 1 || got[0] != "OU=Staff,DC=corp,DC=example,DC=com" {
+		t.Errorf("UserSearchBases = %q", got)
+	}
+	if got := ad.UserSearchFilter("a*)(uid=*"); got != `(&(objectClass=user)(sAMAccountName=a\2a\29\28uid=\2a))` {
+		t.Errorf("UserSearchFilter did not escape the username: %q", got)
+	}
+	if ad.MemberOfAttr() != "memberOf" || ad.UsernameAttr() != "sAMAccountName" {
+		t.Errorf("default attributes = %q, %q", ad.MemberOfAttr(), ad.UsernameAttr())
+	}
+
+	openLDAP := LDAPSettings{
+		URL:               "ldap://ldap.example.org",
+		BindDN:            "cn=reader,dc=example,dc=org",
+		SearchBases:       []string{"ou=people,dc=example,dc=org", "ou=contractors,dc=example,dc=org"},
+		UserFilter:        "(&(objectClass=inetOrgPerson)(uid={username}))",
+		UsernameAttribute: "uid",
+		ReadOnlyPassword:  "secret",
+	}
+	if err := openLDAP.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if got := openLDAP.ReadOnlyBindDN(); got != "cn=reader,dc=example,dc=org" {
+		t.Errorf("ReadOnlyBindDN = %q", got)
+	}
+	if got := openLDAP.UserSearchBases(); len(got) != 2 {
+		t.Errorf("UserSearchBases = %q", got)
+	}
+	if got := openLDAP.UserSearchFilter("alice"); got != "(&(objectClass=inetOrgPerson)(uid=alice))" {
+		t.Errorf("UserSearchFilter = %q", got)
+	}
+	if openLDAP.UsernameAttr() != "uid" {
+		t.Errorf("UsernameAttr = %q", openLDAP.UsernameAttr())
+	}
+}
+
+func TestLDAPTLSConfig(t *testing.T) {
+	settings := LDAPSettings{CACert: filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := settings.TLSConfig("ldap"); err == nil {
+		t.Error("TLSConfig accepted a missing CA bundle")
+	}
+
+	settings.CACert = filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(settings.CACert, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := settings.TLSConfig("ldap"); err == nil || !strings.Contains(err.Error(), "no certificates") {
+		t.Errorf("TLSConfig with no certificates = %v", err)
+	}
+
+	tlsConfig, err := LDAPSettings{}.TLSConfig("ldap.example.org")
+	if err != nil || tlsConfig.ServerName != "ldap.example.org" || tlsConfig.InsecureSkipVerify {
+		t.Errorf("TLSConfig = %+v, %v", tlsConfig, err)
+	}
+}
+
+func TestLDAPSettingsRoundTrip(t *testing.T) {
+	want := config.LDAP{
+		URL:              "ldap://ldap.example.org",
+		Security:         "starttls",
+		BindDN:           "cn=reader,dc=example,dc=org",
+		SearchBases:      []string{"ou=people,dc=example,dc=org"},
+		ReadOnlyPassword: "file:/run/secrets/ldap",
+		Attributes:       config.LDAPAttributes{Username: "uid", MemberOf: "isMemberOf"},
+	}
+	settings := LDAPSettings{
+		URL:               want.URL,
+		Security:          want.Security,
+		BindDN:            want.BindDN,
+		SearchBases:       want.SearchBases,
+		ReadOnlyPassword:  want.ReadOnlyPassword,
+		UsernameAttribute: "uid",
+		MemberOfAttribute: "isMemberOf",
+	}
+	got, err := settings.Config(want)
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	if got.URL != want.URL || got.BindDN != want.BindDN || got.ReadOnlyPassword != want.ReadOnlyPassword || got.Attributes != want.Attributes {
+		t.Errorf("Config() = %+v, want %+v", got, want)
+	}
+}
+
+// TestLDAPDirectory logs in against a real directory, such as a local
+// OpenLDAP or glauth, when GOAUDIT_TEST_LDAP_URL is set. The other variables
+// name the read-only account, the search base and a user to log in as.
+func TestLDAPDirectory(t *testing.T) {
+	url := os.Getenv("GOAUDIT_TEST_LDAP_URL")
+	if url == "" {
+		t.Skip("GOAUDIT_TEST_LDAP_URL is not set")
+	}
+
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.LDAP = config.LDAP{
+		URL:              url,
+		Security:         os.Getenv("GOAUDIT_TEST_LDAP_SECURITY"),
+		CACert:           os.Getenv("GOAUDIT_TEST_LDAP_CA_CERT"),
+		BindDN:           os.Getenv("GOAUDIT_TEST_LDAP_BIND_DN"),
+		ReadOnlyPassword: os.Getenv("GOAUDIT_TEST_LDAP_BIND_PASSWORD"),
+		SearchBases:      strings.Split(os.Getenv("GOAUDIT_TEST_LDAP_SEARCH_BASES"), ";"),
+		UserFilter:       os.Getenv("GOAUDIT_TEST_LDAP_USER_FILTER"),
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("test directory settings: %v", err)
+	}
+	config.Use(cfg)
+
+	settings, err := LoadLDAPSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckLDAPConnection(settings); err != nil {
+		t.Fatalf("CheckLDAPConnection: %v", err)
+	}
+
+	username := os.Getenv("GOAUDIT_TEST_LDAP_USER")
+	password := os.Getenv("GOAUDIT_TEST_LDAP_USER_PASSWORD")
+	wrapper := &LDAPWrapper{}
+	if _, err := wrapper.ConnectToAdServer(username, password+"-wrong"); err == nil {
+		t.Error("ConnectToAdServer accepted a wrong password")
+	}
+	conn, err := wrapper.ConnectToAdServer(username, password)
+	if err != nil {
+		t.Fatalf("ConnectToAdServer(%s): %v", username, err)
+	}
+	defer wrapper.LogoutUser(conn)
+	if conn.DN == "" {
+		t.Error("connection does not record the user's DN")
+	}
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+var _ interfaces.Authenticator = (*LocalAccounts)(nil)
+
+// LocalAccounts checks logins against the password hashes in the users table.
+// DB must not check permissions: nobody is logged in yet.
+type LocalAccounts struct {
+	DB interfaces.DatabaseOperations
+}
+
+// Checked against when the user has no local password, so a login for an
+// unknown name takes as long as one with a wrong password
+var (
+	decoyHash     string
+	decoyHashOnce sync.Once
+)
+
+func (la *LocalAccounts) Name() string {
+	return SourceLocal
+}
+
+func (la *LocalAccounts) Authenticate(username, password string) (*interfaces.Login, error) {
+	if la.DB == nil {
+		return nil, fmt.Errorf("%w: the database is not connected", interfaces.ErrAuthUnavailable)
+	}
+	user, hash, err := la.account(username)
+	if err != nil || hash == "" {
+		decoyHashOnce.Do(func() { decoyHash, _ = HashAccountPassword("decoy") })
+		CheckAccountPassword(decoyHash, password)
+		if err != nil {
+			log.Printf("No local account for %s: %v", username, err)
+		}
+		return nil, fmt.Errorf("%w: %s has no local account", interfaces.ErrUnknownUser, username)
+	}
+	if !CheckAccountPassword(hash, password) {
+		return nil, fmt.Errorf("%w: wrong password for local account %s", interfaces.ErrInvalidCredentials, username)
+	}
+	if user.Status == interfaces.UserInactive {
+		return nil, fmt.Errorf("the account %s is disabled", username)
+	}
+
+	// Passwords hashed with bcrypt or weaker parameters are upgraded now
+	// that the plain text is at hand
+	if NeedsRehash(hash) {
+		if rehashed, err := HashAccountPassword(password); err == nil {
+			if err := la.DB.SetPassword(username, rehashed, user.MustChangePassword); err != nil {
+				log.Printf("Failed to upgrade the password hash of %s: %v", username, err)
+			}
+		}
+	}
+	return &interfaces.Login{
+		Username:           user.Username,
+		Source:             SourceLocal,
+		MustChangePassword: user.MustChangePassword,
+	}, nil
+}
+
+// ChangePassword replaces a user's own password after checking the current
+// one, and clears the forced change
+func (la *LocalAccounts) ChangePassword(username, current, replacement string) error {
+	if _, err := la.Authenticate(username, current); err != nil {
+		return err
+	}
+	if replacement == current {
+		return fmt.Errorf("the new password must be different")
+	}
+	if err := ValidateNewPassword(username, replacement, config.Current().Auth.PasswordLength()); err != nil {
+		return err
+	}
+	hash, err := HashAccountPassword(replacement)
+	if err != nil {
+		return err
+	}
+	return la.DB.SetPassword(username, hash, false)
+}
+
+// RoleResolver returns the role stored on a local account. It is read again
+// whenever the session's role cache expires, so a demoted or disabled account
+// loses access without logging out.
+func (la *LocalAccounts) RoleResolver(username string) rbac.Resolver {
+	return func() ([]rbac.Role, error) {
+		user, _, err := la.account(username)
+		if err != nil {
+			return nil, err
+		}
+		if !user.Local || user.Status == interfaces.UserInactive {
+			return nil, fmt.Errorf("the local account %s is no longer active", username)
+		}
+		return localRoles(user)
+	}
+}
+
+// localRoles is the role stored on the local account user, if it has one
+func localRoles(user interfaces.Users) ([]rbac.Role, error) {
+	if user.Role == "" {
+		return nil, nil
+	}
+	role, err := rbac.ParseRole(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return []rbac.Role{role}, nil
+}
+
+func (la *LocalAccounts) account(username string) (interfaces.Users, string, error) {
+	users, _, err := la.DB.GetUsers(username)
+	if err != nil {
+		return interfaces.Users{}, "", err
+	}
+	if len(users) == 0 {
+		return interfaces.Users{}, "", fmt.Errorf("user not found")
+	}
+	hash, err := la.DB.GetPasswordHash(username)
+	if err != nil {
+		return interfaces.Users{}, "", err
+	}
+	return users[0], hash, nil
+}
+
+// SetLocalAccount creates or updates a local account. With mustChange the
+// password is temporary and has to be changed at the next login. db checks
+// that the caller may manage users.
+func SetLocalAccount(db interfaces.DatabaseOperations, account interfaces.Users, password string, mustChange bool) (interfaces.Users, error) {
+	if strings.TrimSpace(account.Username) == "" {
+		return interfaces.Users{}, fmt.Errorf("a username is required")
+	}
+	if _, err := rbac.ParseRole(account.Role); err != nil {
+		return interfaces.Users{}, err
+	}
+	if err := ValidateNewPassword(account.Username, password, config.Current().Auth.PasswordLength()); err != nil {
+		return interfaces.Users{}, err
+	}
+	hash, err := HashAccountPassword(password)
+	if err != nil {
+		return interfaces.Users{}, err
+	}
+
+	user, err := db.GetOrCreateUser(account.Username)
+	if err != nil {
+		return interfaces.Users{}, fmt.Errorf("failed to create %s: %v", account.Username, err)
+	}
+	user.Role = account.Role
+	if account.DisplayName != "" {
+		user.DisplayName = account.DisplayName
+	}
+	if account.Email != "" {
+		user.Email = account.Email
+	}
+	user.Status = interfaces.UserActive
+	user, err = db.Update(user)
+	if err != nil {
+		return interfaces.Users{}, fmt.Errorf("failed to save %s: %v", account.Username, err)
+	}
+	if err := db.SetPassword(user.Username, hash, mustChange); err != nil {
+		return interfaces.Users{}, err
+	}
+	user.Local = true
+	user.MustChangePassword = mustChange
+	return user, nil
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"strings"
+	"testing"
+
+	// External Imports
+	"golang.org/x/crypto/bcrypt"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+func TestAccountPasswords(t *testing.T) {
+	hash, err := HashAccountPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashAccountPassword: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$") || NeedsRehash(hash) {
+		t.Errorf("hash %q is not a current Argon2id hash", hash)
+	}
+	if !CheckAccountPassword(hash, "correct horse battery") || CheckAccountPassword(hash, "wrong") {
+		t.Error("Argon2id hash does not check passwords correctly")
+	}
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if !CheckAccountPassword(string(legacy), "correct horse battery") || !NeedsRehash(string(legacy)) {
+		t.Error("bcrypt hashes should be accepted and upgraded")
+	}
+	for _, bad := range []string{"", "plain text", "$argon2id$v=19$m=1,t=1,p=0$c2FsdA$a2V5"} {
+		if CheckAccountPassword(bad, "") {
+			t.Errorf("CheckAccountPassword(%q) accepted a password", bad)
+		}
+	}
+
+	if err := ValidateNewPassword("alice", "short", 12); err == nil {
+		t.Error("ValidateNewPassword accepted a short password")
+	}
+	if err := ValidateNewPassword("alice-example", "Alice-Example", 8); err == nil {
+		t.Error("ValidateNewPassword accepted the username")
+	}
+}
+
+func TestLocalAccounts(t *testing.T) {
+	db := fakes.NewDatabase()
+	local := &LocalAccounts{DB: db}
+	if _, err := SetLocalAccount(db, interfaces.Users{Username: "alice", Role: "owner"}, "temporary-password", true); err == nil {
+		t.Error("SetLocalAccount accepted an unknown role")
+	}
+	if _, err := SetLocalAccount(db, interfaces.Users{Username: "alice", Role: "auditor"}, "temporary-password", true); err != nil {
+		t.Fatalf("SetLocalAccount: %v", err)
+	}
+
+	if _, err := local.Authenticate("bob", "temporary-password"); !errors.Is(err, interfaces.ErrUnknownUser) {
+		t.Errorf("unknown user: err = %v, want ErrUnknownUser", err)
+	}
+	if _, err := local.Authenticate("alice", "wrong"); !errors.Is(err, interfaces.ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	login, err := local.Authenticate("alice", "temporary-password")
+	if err != nil || !login.MustChangePassword || login.Source != SourceLocal || login.LDAP != nil {
+		t.Fatalf("Authenticate = %+v, %v, want a local login that must change its password", login, err)
+	}
+
+	if err := local.ChangePassword("alice", "wrong", "a-new-long-password"); err == nil {
+		t.Error("ChangePassword accepted a wrong current password")
+	}
+	if err := local.ChangePassword("alice", "temporary-password", "short"); err == nil {
+		t.Error("ChangePassword accepted a short password")
+	}
+	if err := local.ChangePassword("alice", "temporary-password", "a-new-long-password"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	login, err = local.Authenticate("alice", "a-new-long-password")
+	if err != nil || login.MustChangePassword {
+		t.Errorf("Authenticate after the change = %+v, %v", login, err)
+	}
+
+	roles, err := local.RoleResolver("alice")()
+	if err != nil || len(roles) != 1 || roles[0] != rbac.RoleAuditor {
+		t.Errorf("roles = %v, %v, want auditor", roles, err)
+	}
+
+	// Disabled accounts cannot log in and lose their roles
+	users, _, _ := db.GetUsers("alice")
+	users[0].Status = interfaces.UserInactive
+	if _, err := db.Update(users[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := local.Authenticate("alice", "a-new-long-password"); err == nil {
+		t.Error("Authenticate accepted an inactive account")
+	}
+	if _, err := local.RoleResolver("alice")(); err == nil {
+		t.Error("RoleResolver returned roles for an inactive account")
+	}
+}
+
+func TestLocalAccountsUpgradeBcrypt(t *testing.T) {
+	db := fakes.NewDatabase()
+	if _, err := db.GetOrCreateUser("alice"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("old-style-password"), bcrypt.MinCost)
+	if err := db.SetPassword("alice", string(legacy), false); err != nil {
+		t.Fatalf("SetPassword: %v", err)
+	}
+	if _, err := (&LocalAccounts{DB: db}).Authenticate("alice", "old-style-password"); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if hash, _ := db.GetPasswordHash("alice"); !strings.HasPrefix(hash, "$argon2id$") {
+		t.Errorf("hash after login = %q, want it upgraded to Argon2id", hash)
+	}
+}
+
+func TestChain(t *testing.T) {
+	db := fakes.NewDatabase()
+	if _, err := SetLocalAccount(db, interfaces.Users{Username: "alice", Role: "admin"}, "local-password", false); err != nil {
+		t.Fatalf("SetLocalAccount: %v", err)
+	}
+	directory := fakes.NewLDAP("ldap.example.com", "example.com", nil)
+	directory.AddUser("alice", "directory-password")
+	directory.AddUser("bob", "directory-password")
+	chain := NewChain([]string{SourceLDAP, SourceLocal}, &LocalAccounts{DB: db}, directory)
+
+	tests := []struct {
+		name     string
+		down     bool
+		username string
+		password string
+		source   string
+		err      error
+	}{
+		{"directory first", false, "alice", "directory-password", SourceLDAP, nil},
+		{"directory rejects the password", false, "alice", "local-password", "", interfaces.ErrInvalidCredentials},
+		{"unknown to the directory", false, "carol", "anything", "", interfaces.ErrInvalidCredentials},
+		{"local while the directory is down", true, "alice", "local-password", SourceLocal, nil},
+		{"no local account while the directory is down", true, "bob", "directory-password", "", interfaces.ErrInvalidCredentials},
+	}
+	for _, tc := range tests {
+		directory.SetUnavailable(tc.down)
+		login, err := chain.Authenticate(tc.username, tc.password)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || login.Source != tc.source {
+			t.Errorf("%s: login = %+v, %v, want one through %s", tc.name, login, err, tc.source)
+		}
+	}
+
+	directory.SetUnavailable(true)
+	if _, err := NewChain([]string{SourceLDAP}, directory).Authenticate("alice", "directory-password"); !errors.Is(err, interfaces.ErrAuthUnavailable) {
+		t.Errorf("only source down: err = %v, want ErrAuthUnavailable", err)
+	}
+	if chain := NewChain([]string{SourceLocal}, directory, nil); len(chain) != 0 {
+		t.Errorf("NewChain kept sources that were not asked for: %v", chain)
+	}
+}
@@ -0,0 +1,392 @@
+package auth
+
+import (
+	// Standard Library
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	// External Imports
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
+)
+
+// How long the user has to finish signing in at the provider
+const oidcLoginTimeout = 5 * time.Minute
+
+// Path of the loopback redirect URI
+const oidcCallbackPath = "/callback"
+
+// OIDCIdentity is what an ID token says about the user who signed in
+type OIDCIdentity struct {
+	// Subject is the issuer and sub claim, unique for the user at any provider
+	Subject     string
+	Username    string
+	Email       string
+	DisplayName string
+	Groups      []string
+}
+
+// OIDCProvider signs users in through an OpenID Connect provider with the
+// authorization code flow and PKCE. OpenURL shows the provider's login page,
+// normally in the system browser; the provider then redirects to a listener
+// on the loopback interface.
+type OIDCProvider struct {
+	Settings   config.OIDC
+	OpenURL    func(*url.URL) error
+	HTTPClient *http.Client
+}
+
+// Login waits for the user to sign in at the provider and returns the
+// verified identity from the ID token. Cancelling ctx abandons the login.
+func (p *OIDCProvider) Login(ctx context.Context) (*OIDCIdentity, error) {
+	settings := p.Settings
+	if !settings.Configured() {
+		return nil, fmt.Errorf("%w: OpenID Connect is not configured", interfaces.ErrAuthUnavailable)
+	}
+	if p.OpenURL == nil {
+		return nil, fmt.Errorf("no browser to sign in with")
+	}
+	clientSecret, err := secrets.Resolve(settings.ClientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the OpenID Connect client secret: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, oidcLoginTimeout)
+	defer cancel()
+	if p.HTTPClient != nil {
+		ctx = oidc.ClientContext(ctx, p.HTTPClient)
+	}
+	provider, err := oidc.NewProvider(ctx, settings.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot reach the identity provider: %v", interfaces.ErrAuthUnavailable, err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", settings.RedirectPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the sign-in redirect: %v", err)
+	}
+	defer listener.Close()
+
+	oauthConfig := oauth2.Config{
+		ClientID:     settings.ClientID,
+		ClientSecret: clientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  fmt.Sprintf("http://%s%s", listener.Addr(), oidcCallbackPath),
+		Scopes:       oidcScopes(settings.Scopes),
+	}
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	server := &http.Server{
+		Handler:           oidcCallback(state, codes, failures),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL, err := url.Parse(oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.OpenURL(authURL); err != nil {
+		return nil, fmt.Errorf("failed to open the browser: %v", err)
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-failures:
+		return nil, err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("signing in took too long; try again")
+		}
+		return nil, ctx.Err()
+	}
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("the identity provider refused the sign-in: %v", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("the identity provider did not return an ID token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: settings.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("invalid ID token: the nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %v", err)
+	}
+	return identityFromClaims(idToken.Issuer, idToken.Subject, claims, settings)
+}
+
+// oidcCallback handles the provider's redirect back to the loopback listener
+func oidcCallback(state string, codes chan<- string, failures chan<- error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "This sign-in link is not the one goAudit is waiting for.", http.StatusBadRequest)
+			return
+		}
+		if problem := query.Get("error"); problem != "" {
+			message := problem
+			if description := query.Get("error_description"); description != "" {
+				message = description
+			}
+			writeCallbackPage(w, "Sign-in failed: "+message)
+			select {
+			case failures <- fmt.Errorf("the identity provider refused the sign-in: %s", message):
+			default:
+			}
+			return
+		}
+		select {
+		case codes <- query.Get("code"):
+			writeCallbackPage(w, "You are signed in to goAudit. You can close this window.")
+		default:
+			writeCallbackPage(w, "goAudit has already received this sign-in.")
+		}
+	})
+	return mux
+}
+
+func writeCallbackPage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>goAudit</title></head><body><p>%s</p></body></html>", html.EscapeString(message))
+}
+
+// oidcScopes always asks for the identity scopes goAudit needs
+func oidcScopes(extra []string) []string {
+	scopes := []string{oidc.ScopeOpenID, "profile", "email"}
+	for _, scope := range extra {
+		if scope != "" && !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// identityFromClaims reads the configured username and groups claims. The
+// username falls back to the email address. An email the provider has not
+// verified is not used at all, since anyone could claim it.
+func identityFromClaims(issuer, subject string, claims map[string]interface{}, settings config.OIDC) (*OIDCIdentity, error) {
+	usernameClaim, groupsClaim := settings.Claims()
+	identity := &OIDCIdentity{
+		Subject:     issuer + "|" + subject,
+		Username:    claimString(claims, usernameClaim),
+		DisplayName: claimString(claims, "name"),
+		Groups:      claimStrings(claims, groupsClaim),
+	}
+	if claimTrue(claims, "email_verified") {
+		identity.Email = claimString(claims, "email")
+	} else if usernameClaim == "email" {
+		identity.Username = ""
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("the ID token has no %s or verified email claim to name the user", usernameClaim)
+	}
+	return identity, nil
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// claimTrue accepts true or "true", as some providers send booleans as strings
+func claimTrue(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+// claimStrings accepts a list or a single value, as providers differ
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// OIDCRoles maps groups from the ID token to roles by exact name. With no
+// groups mapped at all everyone can only read, since anyone the provider
+// knows could sign in.
+func OIDCRoles(groups []string, settings config.OIDC) []rbac.Role {
+	mapping := []struct {
+		role   rbac.Role
+		groups []string
+	}{
+		{rbac.RoleAdmin, settings.AdminGroups},
+		{rbac.RoleManager, settings.ManagerGroups},
+		{rbac.RoleAuditor, settings.AuditorGroups},
+		{rbac.RoleReadOnly, settings.ReadOnlyGroups},
+	}
+	configured := false
+	var granted []rbac.Role
+	for _, m := range mapping {
+		configured = configured || len(m.groups) > 0
+		for _, group := range m.groups {
+			if containsString(groups, group) {
+				granted = append(granted, m.role)
+				break
+			}
+		}
+	}
+	if !configured {
+		log.Printf("Warning: no OpenID Connect groups are mapped to roles; everyone gets the read-only role. Set the OIDC role groups in the configuration.")
+		return []rbac.Role{rbac.RoleReadOnly}
+	}
+	return granted
+}
+
+// OIDCRoleResolver returns the roles from the groups in the ID token. They
+// cannot be looked up again without signing in, so they last the session.
+func OIDCRoleResolver(groups []string) rbac.Resolver {
+	return func() ([]rbac.Role, error) {
+		return OIDCRoles(groups, config.Current().OIDC), nil
+	}
+}
+
+// ErrOIDCNotLinked is returned when an identity names a user who already has
+// a goAudit account that has not been linked to it
+var ErrOIDCNotLinked = errors.New("the account is not linked to the identity provider")
+
+// LinkOIDCUser finds the goAudit user linked to identity's subject, or adds
+// one for a username goAudit does not know yet. A user linked to the subject
+// keeps their username even if the provider's changes. An existing user is
+// never taken over by a matching name: they have to log in another way and
+// link the provider with LinkOIDCAccount first.
+func LinkOIDCUser(db interfaces.DatabaseOperations, identity *OIDCIdentity) (interfaces.Users, error) {
+	linked, err := db.GetUserByOIDCSubject(identity.Subject)
+	if err != nil {
+		return interfaces.Users{}, err
+	}
+	var user interfaces.Users
+	if len(linked) == 1 {
+		user = linked[0]
+	} else {
+		if existing, _, err := db.GetUsers(identity.Username); err == nil && len(existing) > 0 {
+			return interfaces.Users{}, fmt.Errorf("%w: %s already has a goAudit account; log in with it and link your identity provider sign-in from the File menu",
+				ErrOIDCNotLinked, identity.Username)
+		}
+		user, err = db.GetOrCreateUser(identity.Username)
+		if err != nil {
+			return interfaces.Users{}, fmt.Errorf("failed to create %s: %v", identity.Username, err)
+		}
+		if err := db.SetOIDCSubject(user.Username, identity.Subject); err != nil {
+			return interfaces.Users{}, err
+		}
+		log.Printf("Added %s for %s", user.Username, identity.Subject)
+	}
+	if user.Status == interfaces.UserInactive {
+		return interfaces.Users{}, fmt.Errorf("the account %s is disabled", user.Username)
+	}
+	return updateFromIdentity(db, user, identity)
+}
+
+// LinkOIDCAccount links identity to username, who is logged in and has just
+// signed in at the provider too, so they have shown they are both. An
+// identity linked to someone else stays theirs.
+func LinkOIDCAccount(db interfaces.DatabaseOperations, username string, identity *OIDCIdentity) (interfaces.Users, error) {
+	linked, err := db.GetUserByOIDCSubject(identity.Subject)
+	if err != nil {
+		return interfaces.Users{}, err
+	}
+	if len(linked) == 1 && linked[0].Username != username {
+		return interfaces.Users{}, fmt.Errorf("this identity provider account is already linked to %s", linked[0].Username)
+	}
+	users, _, err := db.GetUsers(username)
+	if err != nil || len(users) == 0 {
+		return interfaces.Users{}, fmt.Errorf("user not found: %s", username)
+	}
+	if err := db.SetOIDCSubject(username, identity.Subject); err != nil {
+		return interfaces.Users{}, err
+	}
+	log.Printf("Linked %s to %s", username, identity.Subject)
+	return updateFromIdentity(db, users[0], identity)
+}
+
+// updateFromIdentity copies the email and name the provider has for user
+func updateFromIdentity(db interfaces.DatabaseOperations, user interfaces.Users, identity *OIDCIdentity) (interfaces.Users, error) {
+	changed := false
+	if identity.Email != "" && identity.Email != user.Email {
+		user.Email, changed = identity.Email, true
+	}
+	if identity.DisplayName != "" && identity.DisplayName != user.DisplayName {
+		user.DisplayName, changed = identity.DisplayName, true
+	}
+	if !changed {
+		return user, nil
+	}
+	updated, err := db.Update(user)
+	if err != nil {
+		return interfaces.Users{}, fmt.Errorf("failed to update %s: %v", user.Username, err)
+	}
+	return updated, nil
+}
+
+func randomToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	// Standard Library
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// External Imports
+	"github.com/go-jose/go-jose/v4"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+// mockIssuer is a minimal OpenID Connect provider. Its authorize endpoint
+// signs in straight away and redirects back with a code.
+type mockIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{}
+	// deny answers the authorize request with this error
+	deny string
+	// wrongNonce signs the ID token with a nonce other than the one asked for
+	wrongNonce bool
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T, claims map[string]interface{}) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	m := &mockIssuer{key: key, claims: claims}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &m.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) { m.token(t, w, r) })
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	m.challenge, m.nonce = query.Get("code_challenge"), query.Get("nonce")
+	m.mu.Unlock()
+
+	values := url.Values{"state": {query.Get("state")}}
+	if m.deny != "" {
+		values.Set("error", m.deny)
+	} else {
+		values.Set("code", "mock-code")
+	}
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockIssuer) token(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	challenge, nonce := m.challenge, m.nonce
+	m.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if r.FormValue("code") != "mock-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	if m.wrongNonce {
+		nonce = "something else"
+	}
+
+	claims := map[string]interface{}{
+		"iss":   m.URL,
+		"sub":   "248289761001",
+		"aud":   "goaudit",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+	}
+	for name, value := range m.claims {
+		claims[name] = value
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Errorf("NewSigner: %v", err)
+		return
+	}
+	payload, _ := json.Marshal(claims)
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		t.Errorf("Sign: %v", err)
+		return
+	}
+	idToken, _ := signed.CompactSerialize()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// browse follows the authorize URL the way a browser would
+func browse(t *testing.T) func(*url.URL) error {
+	return func(u *url.URL) error {
+		go func() {
+			resp, err := http.Get(u.String())
+			if err != nil {
+				t.Errorf("browsing to %s: %v", u, err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newMockIssuer(t, map[string]interface{}{
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"name":               "Alice Example",
+		"groups":             []string{"staff", "goaudit-managers"},
+	})
+	provider := &OIDCProvider{
+		Settings: config.OIDC{Issuer: issuer.URL, ClientID: "goaudit"},
+		OpenURL:  browse(t),
+	}
+
+	identity, err := provider.Login(context.Background())
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.DisplayName != "Alice Example" ||
+		identity.Subject != issuer.URL+"|248289761001" || len(identity.Groups) != 2 {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	issuer.wrongNonce = true
+	if _, err := provider.Login(context.Background()); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Login with a replayed ID token = %v, want a nonce error", err)
+	}
+	issuer.wrongNonce = false
+
+	issuer.deny = "access_denied"
+	if _, err := provider.Login(context.Background()); err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("Login refused by the provider = %v", err)
+	}
+}
+
+func TestOIDCClaims(t *testing.T) {
+	settings := config.OIDC{UsernameClaim: "upn", GroupsClaim: "roles"}
+	identity, err := identityFromClaims("https://login.example.com", "1", map[string]interface{}{
+		"upn": "bob@example.com", "roles": "goaudit-admins",
+	}, settings)
+	if err != nil || identity.Username != "bob@example.com" || len(identity.Groups) != 1 || identity.Groups[0] != "goaudit-admins" {
+		t.Errorf("identityFromClaims = %+v, %v", identity, err)
+	}
+	identity, err = identityFromClaims("https://login.example.com", "2", map[string]interface{}{
+		"email": "carol@example.com", "email_verified": "true",
+	}, config.OIDC{})
+	if err != nil || identity.Username != "carol@example.com" {
+		t.Errorf("username should fall back to the email: %+v, %v", identity, err)
+	}
+	if _, err := identityFromClaims("https://login.example.com", "2", map[string]interface{}{"email": "carol@example.com"}, config.OIDC{}); err == nil {
+		t.Error("identityFromClaims named the user after an unverified email")
+	}
+	if _, err := identityFromClaims("https://login.example.com", "2", map[string]interface{}{
+		"email": "carol@example.com", "email_verified": false,
+	}, config.OIDC{UsernameClaim: "email"}); err == nil {
+		t.Error("identityFromClaims used an unverified email as the username claim")
+	}
+	identity, err = identityFromClaims("https://login.example.com", "4", map[string]interface{}{
+		"preferred_username": "dave", "email": "ceo@example.com",
+	}, config.OIDC{})
+	if err != nil || identity.Username != "dave" || identity.Email != "" {
+		t.Errorf("unverified email kept: %+v, %v", identity, err)
+	}
+	if _, err := identityFromClaims("https://login.example.com", "3", map[string]interface{}{}, config.OIDC{}); err == nil {
+		t.Error("identityFromClaims accepted a token without a username")
+	}
+
+	settings = config.OIDC{AdminGroups: []string{"goaudit-admins"}, ManagerGroups: []string{"goaudit-managers"}}
+	if roles := OIDCRoles([]string{"staff", "goaudit-managers"}, settings); len(roles) != 1 || roles[0] != rbac.RoleManager {
+		t.Errorf("OIDCRoles = %v, want manager", roles)
+	}
+	if roles := OIDCRoles([]string{"staff"}, settings); len(roles) != 0 {
+		t.Errorf("OIDCRoles for unmapped groups = %v, want none", roles)
+	}
+	if roles := OIDCRoles(nil, config.OIDC{}); len(roles) != 1 || roles[0] != rbac.RoleReadOnly {
+		t.Errorf("OIDCRoles without a mapping = %v, want read-only", roles)
+	}
+}
+
+func TestLinkOIDCUser(t *testing.T) {
+	db := fakes.NewDatabase()
+	identity := &OIDCIdentity{Subject: "https://login.example.com|1", Username: "alice", Email: "alice@example.com"}
+	user, err := LinkOIDCUser(db, identity)
+	if err != nil || user.Username != "alice" || user.Email != "alice@example.com" {
+		t.Fatalf("LinkOIDCUser = %+v, %v", user, err)
+	}
+
+	// A renamed user keeps their goAudit account
+	renamed := &OIDCIdentity{Subject: identity.Subject, Username: "alice.smith"}
+	if user, err := LinkOIDCUser(db, renamed); err != nil || user.Username != "alice" {
+		t.Errorf("LinkOIDCUser after a rename = %+v, %v, want alice", user, err)
+	}
+
+	// Nobody else can take over an account by claiming its username
+	if _, err := LinkOIDCUser(db, &OIDCIdentity{Subject: "https://login.example.com|2", Username: "alice"}); !errors.Is(err, ErrOIDCNotLinked) {
+		t.Errorf("a second subject signing in as alice = %v, want ErrOIDCNotLinked", err)
+	}
+	if _, err := SetLocalAccount(db, interfaces.Users{Username: "bob", Role: "admin"}, "local-password", false); err != nil {
+		t.Fatalf("SetLocalAccount: %v", err)
+	}
+	if _, err := LinkOIDCUser(db, &OIDCIdentity{Subject: "https://login.example.com|3", Username: "bob"}); !errors.Is(err, ErrOIDCNotLinked) {
+		t.Errorf("signing in as a local account = %v, want ErrOIDCNotLinked", err)
+	}
+	if _, err := db.GetOrCreateUser("carol"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	carol := &OIDCIdentity{Subject: "https://login.example.com|4", Username: "carol", Email: "carol@example.com"}
+	if _, err := LinkOIDCUser(db, carol); !errors.Is(err, ErrOIDCNotLinked) {
+		t.Errorf("signing in as a directory user = %v, want ErrOIDCNotLinked", err)
+	}
+
+	// Once logged in, users link the provider to their own account
+	if _, err := LinkOIDCAccount(db, "carol", identity); err == nil {
+		t.Error("alice's identity was linked to carol")
+	}
+	if user, err := LinkOIDCAccount(db, "carol", carol); err != nil || user.Email != "carol@example.com" {
+		t.Fatalf("LinkOIDCAccount = %+v, %v", user, err)
+	}
+	if user, err := LinkOIDCUser(db, carol); err != nil || user.Username != "carol" {
+		t.Errorf("signing in after linking = %+v, %v, want carol", user, err)
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	// Standard Library
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	// External Imports
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2id parameters for new local account passwords, following the OWASP
+// recommendation. Stored hashes record their own parameters, so these can be
+// raised without breaking existing passwords.
+const (
+	accountKeyTime    = 3
+	accountKeyMemory  = 64 * 1024
+	accountKeyThreads = 2
+	accountKeyLength  = 32
+	accountSaltLength = 16
+)
+
+// HashAccountPassword hashes a local account password with Argon2id, in the
+// PHC string format
+func HashAccountPassword(password string) (string, error) {
+	salt := make([]byte, accountSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate a salt: %v", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, accountKeyTime, accountKeyMemory, accountKeyThreads, accountKeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, accountKeyMemory, accountKeyTime, accountKeyThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckAccountPassword reports whether password matches hash, which may be
+// Argon2id or bcrypt
+func CheckAccountPassword(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return checkArgon2id(hash, password)
+	}
+	if strings.HasPrefix(hash, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	return false
+}
+
+// NeedsRehash reports whether hash should be replaced by a fresh
+// HashAccountPassword the next time the password is known
+func NeedsRehash(hash string) bool {
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	_, err := fmt.Sscanf(hash, "$argon2id$v=%d$m=%d,t=%d,p=%d$", &version, &memory, &iterations, &threads)
+	return err != nil || version != argon2.Version ||
+		memory < accountKeyMemory || iterations < accountKeyTime || threads < accountKeyThreads
+}
+
+func checkArgon2id(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil || threads == 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// ValidateNewPassword applies the password policy to a new local account
+// password
+func ValidateNewPassword(username, password string, minLength int) error {
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Errorf("passwords must be at least %d characters", minLength)
+	}
+	if strings.EqualFold(password, username) {
+		return fmt.Errorf("the password cannot be the username")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+	"strings"
+
+	// External Imports
+	"github.com/go-ldap/ldap/v3"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+// Deeper reporting lines than this are assumed to be a loop in the directory
+const maxReportingDepth = 20
+
+// ReportsResolver looks up the reporting tree of a logged in user
+func ReportsResolver(conn *interfaces.LDAPConnection) rbac.ReportsResolver {
+	return func() ([]interfaces.LDAPUser, error) {
+		if conn == nil || conn.DN == "" {
+			return nil, nil
+		}
+		return ReportingTree(conn.DN)
+	}
+}
+
+// ReportingTree returns everyone who reports to managerDN, directly or
+// through other managers, as the read-only account sees them
+func ReportingTree(managerDN string) ([]interfaces.LDAPUser, error) {
+	settings, err := LoadLDAPSettings()
+	if err != nil {
+		return nil, err
+	}
+	l, err := dialReadOnly(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	return walkReports(managerDN, func(dn string) ([]interfaces.LDAPUser, error) {
+		return directReports(l, settings, dn)
+	})
+}
+
+// walkReports gathers the tree under root breadth first, so direct reports
+// come before theirs. Each person appears once even if the directory loops.
+func walkReports(root string, direct func(managerDN string) ([]interfaces.LDAPUser, error)) ([]interfaces.LDAPUser, error) {
+	seen := map[string]bool{normalizeDN(root): true}
+	var tree []interfaces.LDAPUser
+	level := []string{root}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth == maxReportingDepth {
+			log.Printf("Reporting tree under %s is more than %d levels deep; ignoring the rest", root, maxReportingDepth)
+			break
+		}
+		var next []string
+		for _, managerDN := range level {
+			reports, err := direct(managerDN)
+			if err != nil {
+				return nil, err
+			}
+			for _, report := range reports {
+				key := normalizeDN(report.DN)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				tree = append(tree, report)
+				next = append(next, report.DN)
+			}
+		}
+		level = next
+	}
+	return tree, nil
+}
+
+// normalizeDN compares DNs regardless of case and spacing
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	var rdns []string
+	for _, rdn := range parsed.RDNs {
+		var parts []string
+		for _, attr := range rdn.Attributes {
+			parts = append(parts, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+		}
+		rdns = append(rdns, strings.Join(parts, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+// directReports finds the users whose manager attribute names managerDN. If
+// the directory records reporting lines only on the manager, the manager's
+// directReports values are read instead.
+func directReports(l *ldap.Conn, settings LDAPSettings, managerDN string) ([]interfaces.LDAPUser, error) {
+	attributes := syncAttributes(settings)
+	filter := fmt.Sprintf("(%s=%s)", settings.ManagerAttr(), ldap.EscapeFilter(managerDN))
+
+	var reports []interfaces.LDAPUser
+	for _, base := range settings.UserSearchBases() {
+		searchRequest := ldap.NewSearchRequest(
+			base,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapDialTimeout.Seconds()), false,
+			filter,
+			attributes,
+			nil,
+		)
+		sr, err := l.SearchWithPaging(searchRequest, 500)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for reports of %s in %s: %w", managerDN, base, err)
+		}
+		for _, entry := range sr.Entries {
+			reports = appendReport(reports, settings, entry)
+		}
+	}
+	if len(reports) > 0 {
+		return reports, nil
+	}
+
+	manager, err := readEntry(l, managerDN, []string{settings.DirectReportsAttr()})
+	if err != nil || manager == nil {
+		return nil, err
+	}
+	for _, dn := range manager.GetAttributeValues(settings.DirectReportsAttr()) {
+		entry, err := readEntry(l, dn, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			reports = appendReport(reports, settings, entry)
+		}
+	}
+	return reports, nil
+}
+
+func appendReport(reports []interfaces.LDAPUser, settings LDAPSettings, entry *ldap.Entry) []interfaces.LDAPUser {
+	report := entryToUser(entry, settings)
+	if report.Username == "" {
+		// Contacts and other non-login entries can have a manager too
+		return reports
+	}
+	return append(reports, report)
+}
+
+// readEntry reads one entry by DN, returning nil if it does not exist
+func readEntry(l *ldap.Conn, dn string, attributes []string) (*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		dn,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapDialTimeout.Seconds()), false,
+		"(objectClass=*)",
+		attributes,
+		nil,
+	)
+	sr, err := l.Search(searchRequest)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dn, err)
+	}
+	if len(sr.Entries) == 0 {
+		return nil, nil
+	}
+	return sr.Entries[0], nil
+}
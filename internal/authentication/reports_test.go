@@ -0,0 +1,47 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"reflect"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestWalkReports(t *testing.T) {
+	directory := map[string][]string{
+		"CN=Mia,DC=example,DC=com":   {"CN=Bob,DC=example,DC=com", "CN=Carol,DC=example,DC=com"},
+		"CN=Bob,DC=example,DC=com":   {"CN=Dave,DC=example,DC=com"},
+		"CN=Carol,DC=example,DC=com": {"cn=bob, dc=example, dc=com"},
+		// A loop back to the top must not repeat anyone
+		"CN=Dave,DC=example,DC=com": {"CN=Mia,DC=example,DC=com"},
+	}
+	direct := func(managerDN string) ([]interfaces.LDAPUser, error) {
+		var reports []interfaces.LDAPUser
+		for _, dn := range directory[managerDN] {
+			reports = append(reports, interfaces.LDAPUser{DN: dn, ManagerDN: managerDN})
+		}
+		return reports, nil
+	}
+
+	tree, err := walkReports("CN=Mia,DC=example,DC=com", direct)
+	if err != nil {
+		t.Fatalf("walkReports: %v", err)
+	}
+	var got []string
+	for _, report := range tree {
+		got = append(got, report.DN)
+	}
+	want := []string{"CN=Bob,DC=example,DC=com", "CN=Carol,DC=example,DC=com", "CN=Dave,DC=example,DC=com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walkReports = %v, want %v", got, want)
+	}
+
+	failure := errors.New("server down")
+	_, err = walkReports("CN=Mia,DC=example,DC=com", func(string) ([]interfaces.LDAPUser, error) { return nil, failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("walkReports error = %v, want %v", err, failure)
+	}
+}
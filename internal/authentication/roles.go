@@ -0,0 +1,210 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"log"
+
+	// External Imports
+	"github.com/go-ldap/ldap/v3"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+// Active Directory's LDAP_MATCHING_RULE_IN_CHAIN: memberOf matches groups the
+// user belongs to through any depth of nesting
+const matchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// RoleResolver looks up the roles for a logged in user. The first lookup uses
+// the groups read at login; later ones, made when the session's role cache
+// expires, read the user's groups again as the read-only account so a removed
+// membership takes effect without logging out.
+func RoleResolver(conn *interfaces.LDAPConnection) rbac.Resolver {
+	first := true
+	return func() ([]rbac.Role, error) {
+		roles := config.Current().Roles
+		if first {
+			first = false
+			return ResolveRoles(conn, roles)
+		}
+		return lookupRoles(conn, roles)
+	}
+}
+
+// ResolveRoles maps the user's groups to roles. Groups match by exact DN,
+// ignoring case and spacing. With nested groups enabled and an open
+// connection, groups the user is only indirectly a member of match too.
+func ResolveRoles(conn *interfaces.LDAPConnection, roles config.Roles) ([]rbac.Role, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("not logged in")
+	}
+	if !roles.Configured() {
+		log.Printf("Warning: no groups are mapped to roles; %s gets the read-only role. Set roles in the configuration.", conn.Username)
+		return []rbac.Role{rbac.RoleReadOnly}, nil
+	}
+
+	var nested func(groupDN string) (bool, error)
+	if roles.NestedGroups && conn.Conn != nil && conn.DN != "" {
+		settings, err := LoadLDAPSettings()
+		if err != nil {
+			return nil, err
+		}
+		nested = func(groupDN string) (bool, error) {
+			return isNestedMember(conn.Conn, settings.MemberOfAttr(), conn.DN, groupDN)
+		}
+	}
+	return matchRoles(conn.Groups, roles, nested)
+}
+
+// ErrRolesUnknown is returned by LookupRoles for users whose roles come from
+// an identity provider, which are only known while they are logged in
+var ErrRolesUnknown = errors.New("the roles of users from an identity provider cannot be checked while they are logged out")
+
+// LookupRoles reads the roles of user, who need not be logged in: from the
+// account for local users and from the directory for everyone else
+func LookupRoles(user interfaces.Users) ([]rbac.Role, error) {
+	switch {
+	case user.Local:
+		return localRoles(user)
+	case config.Current().LDAPConfigured():
+		return directoryRoles(user.Username)
+	}
+	return nil, fmt.Errorf("%s: %w", user.Username, ErrRolesUnknown)
+}
+
+// directoryRoles reads the groups of username, who need not be logged in,
+// over a read-only connection and maps them to roles
+func directoryRoles(username string) ([]rbac.Role, error) {
+	settings, err := LoadLDAPSettings()
+	if err != nil {
+		return nil, err
+	}
+	l, err := dialReadOnly(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	entry, err := findUser(l, settings, username)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveRoles(&interfaces.LDAPConnection{
+		Conn:     l,
+		Username: username,
+		DN:       entry.DN,
+		Groups:   entry.GetAttributeValues(settings.MemberOfAttr()),
+	}, config.Current().Roles)
+}
+
+// lookupRoles reads the user's groups afresh over a read-only connection
+func lookupRoles(conn *interfaces.LDAPConnection, roles config.Roles) ([]rbac.Role, error) {
+	if conn == nil || conn.DN == "" {
+		return ResolveRoles(conn, roles)
+	}
+	settings, err := LoadLDAPSettings()
+	if err != nil {
+		return nil, err
+	}
+	l, err := dialReadOnly(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	searchRequest := ldap.NewSearchRequest(
+		conn.DN,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapDialTimeout.Seconds()), false,
+		"(objectClass=*)",
+		[]string{settings.MemberOfAttr()},
+		nil,
+	)
+	sr, err := l.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read groups for %s: %w", conn.DN, err)
+	}
+	if len(sr.Entries) != 1 {
+		return nil, fmt.Errorf("user %s no longer exists", conn.DN)
+	}
+
+	refreshed := *conn
+	refreshed.Conn = l
+	refreshed.Groups = sr.Entries[0].GetAttributeValues(settings.MemberOfAttr())
+	return ResolveRoles(&refreshed, roles)
+}
+
+// matchRoles returns every role with a group among groups. nested, when not
+// nil, is asked about role groups that are not direct memberships.
+func matchRoles(groups []string, roles config.Roles, nested func(groupDN string) (bool, error)) ([]rbac.Role, error) {
+	var member []*ldap.DN
+	for _, group := range groups {
+		dn, err := ldap.ParseDN(group)
+		if err != nil {
+			log.Printf("Ignoring unparseable group DN %q: %v", group, err)
+			continue
+		}
+		member = append(member, dn)
+	}
+
+	mapping := []struct {
+		role   rbac.Role
+		groups []string
+	}{
+		{rbac.RoleAdmin, roles.Admin},
+		{rbac.RoleManager, roles.Manager},
+		{rbac.RoleAuditor, roles.Auditor},
+		{rbac.RoleReadOnly, roles.ReadOnly},
+	}
+
+	var granted []rbac.Role
+	for _, m := range mapping {
+		for _, group := range m.groups {
+			ok, err := hasGroup(member, group, nested)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				granted = append(granted, m.role)
+				break
+			}
+		}
+	}
+	return granted, nil
+}
+
+func hasGroup(member []*ldap.DN, group string, nested func(groupDN string) (bool, error)) (bool, error) {
+	want, err := ldap.ParseDN(group)
+	if err != nil {
+		return false, fmt.Errorf("invalid role group DN %q: %v", group, err)
+	}
+	for _, dn := range member {
+		if dn.EqualFold(want) {
+			return true, nil
+		}
+	}
+	if nested == nil {
+		return false, nil
+	}
+	return nested(group)
+}
+
+// isNestedMember asks the directory whether userDN is a member of groupDN
+// through any chain of nested groups
+func isNestedMember(l *ldap.Conn, memberOfAttr, userDN, groupDN string) (bool, error) {
+	searchRequest := ldap.NewSearchRequest(
+		userDN,
+		ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapDialTimeout.Seconds()), false,
+		fmt.Sprintf("(%s:%s:=%s)", memberOfAttr, matchingRuleInChain, ldap.EscapeFilter(groupDN)),
+		[]string{"1.1"},
+		nil,
+	)
+	sr, err := l.Search(searchRequest)
+	if err != nil {
+		return false, fmt.Errorf("failed to check nested membership of %s: %w", groupDN, err)
+	}
+	return len(sr.Entries) == 1, nil
+}
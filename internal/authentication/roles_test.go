@@ -0,0 +1,80 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"reflect"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+func TestResolveRoles(t *testing.T) {
+	roles := config.Roles{
+		Admin:    []string{"CN=goAudit Admins,OU=Groups,DC=example,DC=com"},
+		Manager:  []string{"CN=Audit Managers,OU=Groups,DC=example,DC=com"},
+		Auditor:  []string{"CN=Auditors,OU=Groups,DC=example,DC=com", "CN=Contract Auditors,OU=Groups,DC=example,DC=com"},
+		ReadOnly: []string{"CN=Staff,OU=Groups,DC=example,DC=com"},
+	}
+	tests := []struct {
+		name   string
+		groups []string
+		want   []rbac.Role
+	}{
+		{"no groups", nil, nil},
+		{"substring of admin is not admin", []string{"CN=NotAdmins,OU=Groups,DC=example,DC=com", "CN=goAudit Admins Old,OU=Groups,DC=example,DC=com"}, nil},
+		{"same name elsewhere in the tree", []string{"CN=goAudit Admins,OU=Disabled,DC=example,DC=com"}, nil},
+		{"case and spacing ignored", []string{"cn=GOAUDIT ADMINS, ou=groups, dc=Example, dc=com"}, []rbac.Role{rbac.RoleAdmin}},
+		{"second group of a role", []string{"CN=Contract Auditors,OU=Groups,DC=example,DC=com"}, []rbac.Role{rbac.RoleAuditor}},
+		{"several roles", []string{"CN=Staff,OU=Groups,DC=example,DC=com", "CN=Audit Managers,OU=Groups,DC=example,DC=com"}, []rbac.Role{rbac.RoleManager, rbac.RoleReadOnly}},
+		{"unparseable groups skipped", []string{"not a dn", "CN=Staff,OU=Groups,DC=example,DC=com"}, []rbac.Role{rbac.RoleReadOnly}},
+	}
+	for _, tc := range tests {
+		got, err := ResolveRoles(&interfaces.LDAPConnection{Username: "alice", Groups: tc.groups}, roles)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: ResolveRoles = %v, %v; want %v", tc.name, got, err, tc.want)
+		}
+	}
+
+	got, err := ResolveRoles(&interfaces.LDAPConnection{Username: "alice"}, config.Roles{})
+	if err != nil || !reflect.DeepEqual(got, []rbac.Role{rbac.RoleReadOnly}) {
+		t.Errorf("with no role groups configured got %v, %v; want read-only", got, err)
+	}
+}
+
+func TestMatchRolesNested(t *testing.T) {
+	roles := config.Roles{
+		Admin:   []string{"CN=goAudit Admins,DC=example,DC=com"},
+		Auditor: []string{"CN=Auditors,DC=example,DC=com"},
+	}
+	var asked []string
+	nested := func(groupDN string) (bool, error) {
+		asked = append(asked, groupDN)
+		return groupDN == "CN=goAudit Admins,DC=example,DC=com", nil
+	}
+	got, err := matchRoles([]string{"CN=Auditors,DC=example,DC=com"}, roles, nested)
+	if err != nil || !reflect.DeepEqual(got, []rbac.Role{rbac.RoleAdmin, rbac.RoleAuditor}) {
+		t.Errorf("matchRoles = %v, %v", got, err)
+	}
+	if !reflect.DeepEqual(asked, []string{"CN=goAudit Admins,DC=example,DC=com"}) {
+		t.Errorf("direct memberships should not query the directory; asked about %v", asked)
+	}
+}
+
+func TestLookupRoles(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	config.Use(config.Defaults())
+
+	roles, err := LookupRoles(interfaces.Users{Username: "alice", Local: true, Role: "manager"})
+	if err != nil || !reflect.DeepEqual(roles, []rbac.Role{rbac.RoleManager}) {
+		t.Errorf("LookupRoles(local manager) = %v, %v", roles, err)
+	}
+	// Without a directory, only a login tells what an SSO user may do
+	if roles, err := LookupRoles(interfaces.Users{Username: "erin"}); !errors.Is(err, ErrRolesUnknown) {
+		t.Errorf("LookupRoles(SSO user) = %v, %v, want ErrRolesUnknown", roles, err)
+	}
+}
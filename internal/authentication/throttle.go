@@ -0,0 +1,215 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// A host is shared by everyone who logs in from it, so it may fail this many
+// times more than a single username before it is locked
+const hostFailureFactor = 3
+
+// Change history actions recorded by the throttle
+const (
+	ActionLoginFailed   = "login_failed"
+	ActionLoginLocked   = "login_locked"
+	ActionLoginUnlocked = "login_unlocked"
+)
+
+// Throttle counts failed logins by username and by host. Each failure doubles
+// the wait before the next try, and the limit in the auth config locks the
+// username for the lockout duration. Scope separates application logins from
+// credential store logins. DB is used as is; logins before the session
+// starts need one without permission checks.
+type Throttle struct {
+	DB    interfaces.DatabaseOperations
+	Scope string
+	Host  string
+	now   func() time.Time
+}
+
+func (t *Throttle) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+func (t *Throttle) host() string {
+	if t.Host != "" {
+		return t.Host
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown"
+	}
+	return host
+}
+
+// ErrThrottleUnavailable is returned instead of trying a login whose failed
+// attempts cannot be counted
+var ErrThrottleUnavailable = errors.New("failed logins cannot be checked")
+
+// Attempt runs login unless username or this host has to wait. Wrong
+// passwords and unknown usernames count as failures; a successful login
+// clears the username's count, while the host's runs out on its own so one
+// good account cannot reset it. Without a database that can be read, no
+// login is tried.
+func (t *Throttle) Attempt(username string, login func() error) error {
+	if t == nil || t.DB == nil {
+		return fmt.Errorf("%w: the database is not connected", ErrThrottleUnavailable)
+	}
+	if err := t.check(username); err != nil {
+		return err
+	}
+	err := login()
+	switch {
+	case err == nil:
+		t.succeeded(username)
+	case errors.Is(err, interfaces.ErrInvalidCredentials), errors.Is(err, interfaces.ErrUnknownUser):
+		t.failed(username)
+	}
+	return err
+}
+
+// check returns ErrLockedOut while username or the host has to wait, and
+// ErrThrottleUnavailable when their failures cannot be read
+func (t *Throttle) check(username string) error {
+	auth := config.Current().Auth
+	for _, key := range t.keys(username) {
+		attempts, err := t.DB.GetLoginAttempts(key.scope, key.name)
+		if err != nil {
+			log.Printf("Failed to read the failed logins of %s: %v", key.name, err)
+			return fmt.Errorf("%w: %v", ErrThrottleUnavailable, err)
+		}
+		wait := loginDelay(attempts, key.limit, auth.LockoutDuration(), t.clock())
+		if wait <= 0 {
+			continue
+		}
+		if attempts.Failures >= key.limit {
+			return fmt.Errorf("%w: %s is locked; try again in %s or ask an administrator to unlock it",
+				interfaces.ErrLockedOut, key.describe(), wait.Round(time.Second))
+		}
+		return fmt.Errorf("%w: wait %s before trying again", interfaces.ErrLockedOut, wait.Round(time.Second))
+	}
+	return nil
+}
+
+func (t *Throttle) failed(username string) {
+	auth := config.Current().Auth
+	now := t.clock()
+	since := now.Add(-auth.LockoutDuration())
+	for _, key := range t.keys(username) {
+		attempts, err := t.DB.RecordLoginFailure(key.scope, key.name, now, since)
+		if err != nil {
+			log.Printf("Failed to record a failed login: %v", err)
+			continue
+		}
+		if key.scope == interfaces.AttemptsHost {
+			if attempts.Failures == key.limit {
+				t.record(ActionLoginLocked, username, fmt.Sprintf("%s locked after %d failed logins", key.describe(), attempts.Failures))
+			}
+			continue
+		}
+		log.Printf("Failed %s login for %s from %s (%d in a row)", t.Scope, username, t.host(), attempts.Failures)
+		t.record(ActionLoginFailed, username, fmt.Sprintf("%s login failure %d", t.Scope, attempts.Failures))
+		if attempts.Failures == key.limit {
+			t.record(ActionLoginLocked, username, fmt.Sprintf("%s locked for %s", key.describe(), auth.LockoutDuration()))
+		}
+	}
+}
+
+func (t *Throttle) succeeded(username string) {
+	for _, key := range t.keys(username) {
+		if key.scope == interfaces.AttemptsHost {
+			continue
+		}
+		if err := t.DB.ClearLoginFailures(key.scope, key.name); err != nil {
+			log.Printf("Failed to clear the failed logins of %s: %v", key.name, err)
+		}
+	}
+}
+
+func (t *Throttle) record(action, subject, detail string) {
+	entry := interfaces.ChangeHistory{At: t.clock(), Host: t.host(), Action: action, Subject: subject, Detail: detail}
+	if _, err := t.DB.AddChangeHistory(entry); err != nil {
+		log.Printf("Failed to add %s for %s to the change history: %v", action, subject, err)
+	}
+}
+
+type attemptKey struct {
+	scope string
+	name  string
+	limit int
+}
+
+func (k attemptKey) describe() string {
+	if k.scope == interfaces.AttemptsHost {
+		return "this computer"
+	}
+	return k.name
+}
+
+func (t *Throttle) keys(username string) []attemptKey {
+	limit := config.Current().Auth.LoginFailureLimit()
+	scope := t.Scope
+	if scope == "" {
+		scope = interfaces.AttemptsUser
+	}
+	return []attemptKey{
+		{scope, username, limit},
+		{interfaces.AttemptsHost, t.host(), limit * hostFailureFactor},
+	}
+}
+
+// loginDelay is how long after now the next login must wait. It is one
+// second after the first failure, doubling with each one after, and the
+// lockout once limit failures are reached.
+func loginDelay(attempts interfaces.LoginAttempts, limit int, lockout time.Duration, now time.Time) time.Duration {
+	if attempts.Failures <= 0 {
+		return 0
+	}
+	delay := lockout
+	if attempts.Failures < limit && attempts.Failures <= 30 {
+		delay = min(time.Second<<(attempts.Failures-1), lockout)
+	}
+	return attempts.LastFailure.Add(delay).Sub(now)
+}
+
+// LockedOut reports whether attempts have reached the lockout and it has
+// not expired yet
+func LockedOut(attempts interfaces.LoginAttempts) bool {
+	auth := config.Current().Auth
+	limit := auth.LoginFailureLimit()
+	if attempts.Scope == interfaces.AttemptsHost {
+		limit *= hostFailureFactor
+	}
+	return attempts.Failures >= limit && loginDelay(attempts, limit, auth.LockoutDuration(), time.Now()) > 0
+}
+
+// UnlockLogin clears the failed logins of a username or host and notes it in
+// the change history. db checks that the caller may manage users.
+func UnlockLogin(db interfaces.DatabaseOperations, attempts interfaces.LoginAttempts) error {
+	if err := db.ClearLoginFailures(attempts.Scope, attempts.Name); err != nil {
+		return err
+	}
+	entry := interfaces.ChangeHistory{
+		Host:    (&Throttle{}).host(),
+		Action:  ActionLoginUnlocked,
+		Subject: attempts.Name,
+		Detail:  fmt.Sprintf("cleared %d failed %s logins", attempts.Failures, attempts.Scope),
+	}
+	if _, err := db.AddChangeHistory(entry); err != nil {
+		log.Printf("Failed to add the unlock of %s to the change history: %v", attempts.Name, err)
+	}
+	log.Printf("Unlocked %s %s", attempts.Scope, attempts.Name)
+	return nil
+}
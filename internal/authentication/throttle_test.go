@@ -0,0 +1,197 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestLoginDelay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 15 * time.Minute},
+		{9, 15 * time.Minute},
+	}
+	for _, tc := range tests {
+		attempts := interfaces.LoginAttempts{Failures: tc.failures, LastFailure: now}
+		if got := loginDelay(attempts, 5, 15*time.Minute, now); got != tc.want {
+			t.Errorf("loginDelay(%d failures) = %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Auth.MaxLoginFailures = 3
+	cfg.Auth.LockoutMinutes = 10
+	config.Use(cfg)
+
+	db := fakes.NewDatabase()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := &Throttle{DB: db, Scope: interfaces.AttemptsUser, Host: "pc-1", now: func() time.Time { return now }}
+	wrong := func() error { return interfaces.ErrInvalidCredentials }
+	right := func() error { return nil }
+
+	if err := throttle.Attempt("alice", wrong); !errors.Is(err, interfaces.ErrInvalidCredentials) {
+		t.Fatalf("first failure = %v", err)
+	}
+	if err := throttle.Attempt("alice", right); !errors.Is(err, interfaces.ErrLockedOut) {
+		t.Errorf("immediate retry = %v, want a wait", err)
+	}
+	if err := throttle.Attempt("alice", func() error { return interfaces.ErrAuthUnavailable }); !errors.Is(err, interfaces.ErrLockedOut) {
+		t.Errorf("the wait should hold whatever the outcome: %v", err)
+	}
+
+	// Unavailable sources are not the user's fault and do not count
+	now = now.Add(time.Second)
+	throttle.Attempt("alice", func() error { return interfaces.ErrAuthUnavailable })
+	if attempts, _ := db.GetLoginAttempts(interfaces.AttemptsUser, "alice"); attempts.Failures != 1 {
+		t.Errorf("failures = %d, want 1", attempts.Failures)
+	}
+
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Minute)
+		throttle.Attempt("alice", wrong)
+	}
+	now = now.Add(5 * time.Minute)
+	if err := throttle.Attempt("alice", right); !errors.Is(err, interfaces.ErrLockedOut) {
+		t.Errorf("login during the lockout = %v, want ErrLockedOut", err)
+	}
+	if err := throttle.Attempt("bob", right); err != nil {
+		t.Errorf("other users are not locked out: %v", err)
+	}
+
+	history, _ := db.GetChangeHistory(10)
+	failed, locked := 0, 0
+	for _, entry := range history {
+		switch entry.Action {
+		case ActionLoginFailed:
+			failed++
+		case ActionLoginLocked:
+			locked++
+		}
+		if entry.Subject != "alice" || entry.Host != "pc-1" {
+			t.Errorf("history entry %+v does not name alice on pc-1", entry)
+		}
+	}
+	if failed != 3 || locked != 1 {
+		t.Errorf("history has %d failures and %d lockouts, want 3 and 1", failed, locked)
+	}
+
+	attempts, _ := db.GetLoginAttempts(interfaces.AttemptsUser, "alice")
+	if !LockedOut(interfaces.LoginAttempts{Scope: attempts.Scope, Failures: attempts.Failures, LastFailure: time.Now()}) {
+		t.Error("LockedOut = false for a fresh lockout")
+	}
+	if err := UnlockLogin(db, attempts); err != nil {
+		t.Fatalf("UnlockLogin: %v", err)
+	}
+	if err := throttle.Attempt("alice", right); err != nil {
+		t.Errorf("login after unlocking: %v", err)
+	}
+
+	// A good login clears the count
+	now = now.Add(time.Hour)
+	throttle.Attempt("carol", wrong)
+	now = now.Add(time.Minute)
+	throttle.Attempt("carol", right)
+	if attempts, _ := db.GetLoginAttempts(interfaces.AttemptsUser, "carol"); attempts.Failures != 0 {
+		t.Errorf("failures after a good login = %d", attempts.Failures)
+	}
+}
+
+func TestThrottleHost(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Auth.MaxLoginFailures = 2
+	config.Use(cfg)
+
+	db := fakes.NewDatabase()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := &Throttle{DB: db, Host: "pc-1", now: func() time.Time { return now }}
+	// One failure each for many usernames locks the host
+	for _, username := range []string{"a", "b", "c", "d", "e", "f"} {
+		now = now.Add(time.Minute)
+		throttle.Attempt(username, func() error { return interfaces.ErrUnknownUser })
+	}
+	now = now.Add(time.Minute)
+	if err := throttle.Attempt("g", func() error { return nil }); !errors.Is(err, interfaces.ErrLockedOut) {
+		t.Errorf("login from a locked host = %v, want ErrLockedOut", err)
+	}
+}
+
+func TestThrottleHostOutlastsGoodLogins(t *testing.T) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Auth.MaxLoginFailures = 2
+	cfg.Auth.LockoutMinutes = 10
+	config.Use(cfg)
+
+	db := fakes.NewDatabase()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := &Throttle{DB: db, Host: "pc-1", now: func() time.Time { return now }}
+	for _, username := range []string{"a", "b", "c", "d", "e"} {
+		now = now.Add(time.Minute)
+		throttle.Attempt(username, func() error { return interfaces.ErrInvalidCredentials })
+	}
+	// A login to an account the sprayer controls only clears that account
+	now = now.Add(time.Minute)
+	if err := throttle.Attempt("mallory", func() error { return nil }); err != nil {
+		t.Fatalf("good login before the host is locked: %v", err)
+	}
+	if attempts, _ := db.GetLoginAttempts(interfaces.AttemptsHost, "pc-1"); attempts.Failures != 5 {
+		t.Errorf("host failures after a good login = %d, want 5", attempts.Failures)
+	}
+	now = now.Add(time.Minute)
+	throttle.Attempt("f", func() error { return interfaces.ErrInvalidCredentials })
+	now = now.Add(time.Minute)
+	if err := throttle.Attempt("mallory", func() error { return nil }); !errors.Is(err, interfaces.ErrLockedOut) {
+		t.Errorf("login from a locked host = %v, want ErrLockedOut", err)
+	}
+}
+
+// unreadableAttempts cannot read the failed login counts
+type unreadableAttempts struct {
+	*fakes.Database
+}
+
+func (unreadableAttempts) GetLoginAttempts(scope, name string) (interfaces.LoginAttempts, error) {
+	return interfaces.LoginAttempts{}, errors.New("connection refused")
+}
+
+func TestThrottleFailsClosed(t *testing.T) {
+	tried := false
+	login := func() error {
+		tried = true
+		return nil
+	}
+	for name, throttle := range map[string]*Throttle{
+		"no throttle":       nil,
+		"no database":       {Host: "pc-1"},
+		"unreadable counts": {DB: unreadableAttempts{fakes.NewDatabase()}, Host: "pc-1"},
+	} {
+		tried = false
+		if err := throttle.Attempt("alice", login); !errors.Is(err, ErrThrottleUnavailable) {
+			t.Errorf("%s: Attempt = %v, want ErrThrottleUnavailable", name, err)
+		}
+		if tried {
+			t.Errorf("%s: the login was tried", name)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package auth
+
+import (
+	// Standard Library
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	// External Imports
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
+)
+
+// TOTP parameters, the defaults every authenticator app understands
+const (
+	totpIssuer = "goAudit"
+	totpPeriod = 30
+	// Codes from one step either side are accepted for clock drift
+	totpSkew = 1
+)
+
+// Backup codes are shown once at enrolment and each works once. The
+// alphabet is lower case base32; 32 letters divide a random byte evenly.
+const (
+	backupCodeCount    = 10
+	backupCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	backupCodeLength   = 10
+)
+
+// Prefix of secrets encrypted with the TOTP key, so the format can change
+const totpSecretVersion = "v1:"
+
+var totpOptions = hotp.ValidateOpts{Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// TwoFactor verifies and manages TOTP enrolments. DB is used as is: pass the
+// permission checking store once the user is logged in. Wrong codes count
+// against Throttle, when set, like wrong passwords.
+type TwoFactor struct {
+	DB       interfaces.DatabaseOperations
+	Throttle *Throttle
+	now      func() time.Time
+}
+
+func (tf *TwoFactor) clock() time.Time {
+	if tf.now != nil {
+		return tf.now()
+	}
+	return time.Now()
+}
+
+func (tf *TwoFactor) db() (interfaces.DatabaseOperations, error) {
+	if tf == nil || tf.DB == nil {
+		return nil, fmt.Errorf("the database is not connected")
+	}
+	return tf.DB, nil
+}
+
+// TwoFactorRequired reports whether the policy makes any of roles log in
+// with a second factor
+func TwoFactorRequired(roles []rbac.Role) bool {
+	required := config.Current().Auth.RequireTwoFactor
+	for _, role := range roles {
+		for _, name := range required {
+			if string(role) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Enrolled reports whether username has set up two-factor login
+func (tf *TwoFactor) Enrolled(username string) (bool, error) {
+	db, err := tf.db()
+	if err != nil {
+		return false, err
+	}
+	enrolment, err := db.GetTwoFactor(username)
+	if err != nil {
+		return false, err
+	}
+	return enrolment.Secret != "", nil
+}
+
+// Verify accepts a code from the user's authenticator app or one of their
+// backup codes. Each code works once.
+func (tf *TwoFactor) Verify(username, code string) error {
+	if tf.Throttle == nil {
+		return tf.verify(username, code)
+	}
+	return tf.Throttle.Attempt(username, func() error { return tf.verify(username, code) })
+}
+
+func (tf *TwoFactor) verify(username, code string) error {
+	db, err := tf.db()
+	if err != nil {
+		return err
+	}
+	enrolment, err := db.GetTwoFactor(username)
+	if err != nil {
+		return err
+	}
+	if enrolment.Secret == "" {
+		return fmt.Errorf("%s has not set up two-factor login", username)
+	}
+
+	code = strings.Join(strings.Fields(code), "")
+	if len(code) == int(otp.DigitsSix) && strings.Trim(code, "0123456789") == "" {
+		secret, err := decryptTOTPSecret(enrolment.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := matchTOTP(secret, code, tf.clock())
+		if !ok {
+			return fmt.Errorf("%w: wrong two-factor code", interfaces.ErrInvalidCredentials)
+		}
+		fresh, err := db.UseTOTPStep(username, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return fmt.Errorf("that code was already used; wait for the next one")
+		}
+		return nil
+	}
+
+	used, err := db.UseBackupCode(username, hashBackupCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return fmt.Errorf("%w: wrong two-factor code", interfaces.ErrInvalidCredentials)
+	}
+	log.Printf("%s logged in with a backup code", username)
+	return nil
+}
+
+// Enrolment is a new TOTP secret. It is only saved once the user proves their
+// authenticator app has it.
+type Enrolment struct {
+	Username string
+	key      *otp.Key
+}
+
+// Secret is the base32 secret, for typing into an app that cannot scan
+func (e *Enrolment) Secret() string {
+	return e.key.Secret()
+}
+
+// QRCode renders the otpauth:// URL for authenticator apps to scan
+func (e *Enrolment) QRCode(size int) (image.Image, error) {
+	return e.key.Image(size, size)
+}
+
+// Begin generates a secret for username. It fails early when no TOTP key is
+// configured, since the secret could not be stored.
+func (tf *TwoFactor) Begin(username string) (*Enrolment, error) {
+	if _, err := tf.db(); err != nil {
+		return nil, err
+	}
+	if _, err := totpCipher(); err != nil {
+		return nil, err
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: username, Period: totpPeriod})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a two-factor secret: %v", err)
+	}
+	return &Enrolment{Username: username, key: key}, nil
+}
+
+// Confirm checks a code made from the new secret, then saves the secret with
+// fresh backup codes, replacing any earlier enrolment. The backup codes are
+// returned to be shown to the user; only their hashes are kept.
+func (tf *TwoFactor) Confirm(e *Enrolment, code string) ([]string, error) {
+	db, err := tf.db()
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(e.key.Secret(), strings.Join(strings.Fields(code), ""), tf.clock())
+	if !ok {
+		return nil, fmt.Errorf("the code does not match; check the time on the device and try again")
+	}
+	encrypted, err := encryptTOTPSecret(e.key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SetTwoFactor(e.Username, interfaces.TwoFactor{Secret: encrypted, BackupCodes: hashes}); err != nil {
+		return nil, err
+	}
+	if _, err := db.UseTOTPStep(e.Username, step); err != nil {
+		log.Printf("Failed to record the enrolment code of %s as used: %v", e.Username, err)
+	}
+	log.Printf("%s set up two-factor login", e.Username)
+	return codes, nil
+}
+
+// Disable removes the enrolment and backup codes of username
+func (tf *TwoFactor) Disable(username string) error {
+	db, err := tf.db()
+	if err != nil {
+		return err
+	}
+	return db.SetTwoFactor(username, interfaces.TwoFactor{})
+}
+
+// matchTOTP returns the time step code belongs to, within the allowed skew
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want, err := hotp.GenerateCodeCustom(secret, uint64(step), totpOptions)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCipher derives the AES key for TOTP secrets from AUTH_TOTP_KEY
+func totpCipher() (cipher.AEAD, error) {
+	value, err := secrets.Resolve(config.Current().Auth.TOTPKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the two-factor key: %v", err)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("two-factor login needs an encryption key; an administrator must set the Two-Factor Key in the LDAP settings")
+	}
+	if len(value) < 32 {
+		return nil, fmt.Errorf("the two-factor key must be at least 32 characters")
+	}
+	key := sha256.Sum256([]byte(value))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptTOTPSecret(secret string) (string, error) {
+	gcm, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return totpSecretVersion + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptTOTPSecret(stored string) (string, error) {
+	gcm, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, totpSecretVersion))
+	if err != nil || !strings.HasPrefix(stored, totpSecretVersion) || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("the stored two-factor secret is damaged")
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot read the two-factor secret; this installation's two-factor key differs from the one it was saved with")
+	}
+	return string(secret), nil
+}
+
+// newBackupCodes returns codes formatted for reading, such as "abcde-23456",
+// and the hashes to store
+func newBackupCodes() ([]string, []string, error) {
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	random := make([]byte, backupCodeLength)
+	for i := range codes {
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		var code strings.Builder
+		for j, b := range random {
+			if j == backupCodeLength/2 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(backupCodeAlphabet[int(b)%len(backupCodeAlphabet)])
+		}
+		codes[i] = code.String()
+		hashes[i] = hashBackupCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashBackupCode ignores case, spaces and dashes so codes can be typed loosely.
+// The codes are random enough that a plain hash is safe.
+func hashBackupCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
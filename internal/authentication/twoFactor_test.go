@@ -0,0 +1,128 @@
+package auth
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+
+	// External Imports
+	"github.com/pquerna/otp/hotp"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+)
+
+func useTwoFactorConfig(t *testing.T, key string, required ...string) {
+	previous := config.Current()
+	t.Cleanup(func() { config.Use(previous) })
+	cfg := config.Defaults()
+	cfg.Auth.TOTPKey = key
+	cfg.Auth.RequireTwoFactor = required
+	config.Use(cfg)
+}
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	code, err := hotp.GenerateCodeCustom(secret, uint64(at.Unix()/totpPeriod), totpOptions)
+	if err != nil {
+		t.Fatalf("GenerateCodeCustom: %v", err)
+	}
+	return code
+}
+
+func TestTwoFactor(t *testing.T) {
+	useTwoFactorConfig(t, "a-test-key-that-is-at-least-32-chars")
+	db := fakes.NewDatabase()
+	if _, err := db.GetOrCreateUser("alice"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tf := &TwoFactor{DB: db, now: func() time.Time { return now }}
+
+	enrolment, err := tf.Begin("alice")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if enrolled, _ := tf.Enrolled("alice"); enrolled {
+		t.Error("alice is enrolled before confirming")
+	}
+	if _, err := tf.Confirm(enrolment, "000000"); err == nil {
+		t.Error("Confirm accepted a wrong code")
+	}
+	codes, err := tf.Confirm(enrolment, totpCode(t, enrolment.Secret(), now))
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(codes) != backupCodeCount {
+		t.Errorf("got %d backup codes, want %d", len(codes), backupCodeCount)
+	}
+	stored, _ := db.GetTwoFactor("alice")
+	if stored.Secret == enrolment.Secret() || stored.Secret == "" {
+		t.Errorf("stored secret %q is not encrypted", stored.Secret)
+	}
+	if users, _, _ := db.GetUsers("alice"); !users[0].TwoFactor {
+		t.Error("the user record does not show two-factor login")
+	}
+
+	// The enrolment code cannot be used again to log in
+	if err := tf.Verify("alice", totpCode(t, enrolment.Secret(), now)); err == nil {
+		t.Error("Verify accepted the code used for enrolment")
+	}
+	now = now.Add(time.Minute)
+	code := totpCode(t, enrolment.Secret(), now)
+	if err := tf.Verify("alice", code); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := tf.Verify("alice", code); err == nil {
+		t.Error("Verify accepted a replayed code")
+	}
+	if err := tf.Verify("alice", "000000"); !errors.Is(err, interfaces.ErrInvalidCredentials) {
+		t.Errorf("wrong code: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	// Backup codes are typed loosely and work once
+	if err := tf.Verify("alice", " "+codes[0][:5]+codes[0][6:]+" "); err != nil {
+		t.Errorf("Verify with a backup code: %v", err)
+	}
+	if err := tf.Verify("alice", codes[0]); err == nil {
+		t.Error("Verify accepted a used backup code")
+	}
+
+	// A different key cannot read the secret
+	useTwoFactorConfig(t, "another-test-key-at-least-32-chars")
+	now = now.Add(time.Minute)
+	if err := tf.Verify("alice", totpCode(t, enrolment.Secret(), now)); err == nil {
+		t.Error("Verify decrypted the secret with the wrong key")
+	}
+
+	if err := tf.Disable("alice"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if enrolled, _ := tf.Enrolled("alice"); enrolled {
+		t.Error("alice is still enrolled after Disable")
+	}
+}
+
+func TestTwoFactorNeedsKey(t *testing.T) {
+	useTwoFactorConfig(t, "")
+	if _, err := (&TwoFactor{DB: fakes.NewDatabase()}).Begin("alice"); err == nil {
+		t.Error("Begin succeeded without a two-factor key")
+	}
+	useTwoFactorConfig(t, "too-short")
+	if _, err := (&TwoFactor{DB: fakes.NewDatabase()}).Begin("alice"); err == nil {
+		t.Error("Begin accepted a short two-factor key")
+	}
+}
+
+func TestTwoFactorRequired(t *testing.T) {
+	useTwoFactorConfig(t, "", "admin", "manager")
+	if !TwoFactorRequired([]rbac.Role{rbac.RoleAuditor, rbac.RoleManager}) {
+		t.Error("manager should require two-factor login")
+	}
+	if TwoFactorRequired([]rbac.Role{rbac.RoleAuditor}) || TwoFactorRequired(nil) {
+		t.Error("auditor should not require two-factor login")
+	}
+}
@@ -0,0 +1,177 @@
+// Package catalog reads compliance framework catalogs, such as SOC 2 or ISO
+// 27001, and works out how well audits cover their controls.
+package catalog
+
+import (
+	// Standard Library
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	// External Imports
+	"gopkg.in/yaml.v3"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Framework is a catalog as read from a file
+type Framework struct {
+	Name     string
+	Controls []interfaces.Control
+}
+
+// document is the YAML and JSON layout of a catalog:
+//
+//	framework: SOC 2
+//	controls:
+//	  - id: CC6.1
+//	    title: Logical access security
+//	    description: ...
+//	    family: Logical and Physical Access Controls
+type document struct {
+	Framework string `json:"framework" yaml:"framework"`
+	Controls  []struct {
+		ID          string `json:"id" yaml:"id"`
+		Title       string `json:"title" yaml:"title"`
+		Description string `json:"description" yaml:"description"`
+		Family      string `json:"family" yaml:"family"`
+	} `json:"controls" yaml:"controls"`
+}
+
+// Parse reads a catalog in the format given by the extension of filename:
+// .yaml, .yml, .json or .csv. The framework is named in the file, or else
+// after it.
+func Parse(filename string, data []byte) (Framework, error) {
+	var framework Framework
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		framework, err = parseDocument(data, yaml.Unmarshal)
+	case ".json":
+		framework, err = parseDocument(data, json.Unmarshal)
+	case ".csv":
+		framework, err = parseCSV(data)
+	default:
+		return Framework{}, fmt.Errorf("%w: %s is not a YAML, JSON or CSV file", interfaces.ErrInvalidControl, filename)
+	}
+	if err != nil {
+		return Framework{}, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	if framework.Name == "" {
+		framework.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if len(framework.Controls) == 0 {
+		return Framework{}, fmt.Errorf("%w: %s has no controls", interfaces.ErrInvalidControl, filename)
+	}
+	seen := make(map[string]bool, len(framework.Controls))
+	for i := range framework.Controls {
+		control := &framework.Controls[i]
+		control.Framework = framework.Name
+		if err := control.Validate(); err != nil {
+			return Framework{}, fmt.Errorf("%s: control %d: %w", filename, i+1, err)
+		}
+		if seen[control.ControlID] {
+			return Framework{}, fmt.Errorf("%w: %s lists control %s twice", interfaces.ErrInvalidControl, filename, control.ControlID)
+		}
+		seen[control.ControlID] = true
+	}
+	return framework, nil
+}
+
+func parseDocument(data []byte, unmarshal func([]byte, interface{}) error) (Framework, error) {
+	var doc document
+	if err := unmarshal(data, &doc); err != nil {
+		return Framework{}, fmt.Errorf("%w: %v", interfaces.ErrInvalidControl, err)
+	}
+	framework := Framework{Name: strings.TrimSpace(doc.Framework)}
+	for _, c := range doc.Controls {
+		framework.Controls = append(framework.Controls, interfaces.Control{
+			ControlID:   strings.TrimSpace(c.ID),
+			Title:       strings.TrimSpace(c.Title),
+			Description: strings.TrimSpace(c.Description),
+			Family:      strings.TrimSpace(c.Family),
+		})
+	}
+	return framework, nil
+}
+
+// csvColumns maps the header names a CSV catalog may use, lower case and
+// without spaces, dashes or underscores, to the field they fill
+var csvColumns = map[string]string{
+	"id": "id", "controlid": "id", "control": "id", "ref": "id", "reference": "id",
+	"title": "title", "name": "title", "description": "description",
+	"family": "family", "category": "family", "domain": "family", "framework": "framework",
+}
+
+func csvHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return csvColumns[strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name)]
+}
+
+// parseCSV reads a catalog with a header row naming at least the id and
+// title columns. An optional framework column must be the same throughout.
+func parseCSV(data []byte) (Framework, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Framework{}, fmt.Errorf("%w: no header row: %v", interfaces.ErrInvalidControl, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if field := csvHeader(name); field != "" {
+			if _, ok := columns[field]; !ok {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["id"]; !ok {
+		return Framework{}, fmt.Errorf("%w: no control ID column in %q", interfaces.ErrInvalidControl, strings.Join(header, ","))
+	}
+	if _, ok := columns["title"]; !ok {
+		return Framework{}, fmt.Errorf("%w: no title column in %q", interfaces.ErrInvalidControl, strings.Join(header, ","))
+	}
+
+	var framework Framework
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Framework{}, fmt.Errorf("%w: %v", interfaces.ErrInvalidControl, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if name := field("framework"); name != "" {
+			if framework.Name != "" && framework.Name != name {
+				return Framework{}, fmt.Errorf("%w: line %d is for %s, not %s", interfaces.ErrInvalidControl, line, name, framework.Name)
+			}
+			framework.Name = name
+		}
+		framework.Controls = append(framework.Controls, interfaces.Control{
+			ControlID:   field("id"),
+			Title:       field("title"),
+			Description: field("description"),
+			Family:      field("family"),
+		})
+	}
+	return framework, nil
+}
@@ -0,0 +1,117 @@
+package catalog
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		filename, data string
+		framework      string
+	}{
+		{"soc2.yaml", `
+framework: SOC 2
+controls:
+  - id: CC6.1
+    title: Logical access security
+    family: Logical and Physical Access
+  - id: CC7.2
+    title: System monitoring
+    description: Anomalies are detected
+`, "SOC 2"},
+		{"iso.json", `{"framework": "ISO 27001", "controls": [
+			{"id": "A.5.1", "title": "Policies for information security", "family": "Organizational"},
+			{"id": "A.8.13", "title": "Information backup"}]}`, "ISO 27001"},
+		{"Internal Policies.csv", "\ufeffControl ID,Title,Description,Category\n" +
+			"POL-1, Acceptable use,\"Staff sign, yearly\",HR\n\n" +
+			"POL-2,Clean desk,,Facilities\n", "Internal Policies"},
+	}
+	for _, tc := range tests {
+		framework, err := Parse(tc.filename, []byte(tc.data))
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tc.filename, err)
+			continue
+		}
+		if framework.Name != tc.framework || len(framework.Controls) != 2 {
+			t.Errorf("Parse(%s) = %+v, want 2 controls of %s", tc.filename, framework, tc.framework)
+			continue
+		}
+		for _, control := range framework.Controls {
+			if control.Framework != tc.framework || control.ControlID == "" || control.Title == "" {
+				t.Errorf("Parse(%s) control = %+v", tc.filename, control)
+			}
+		}
+	}
+
+	csv, _ := Parse("policies.csv", []byte("ref,name,description,family\nPOL-1,Acceptable use,\"Staff sign, yearly\",HR\n"))
+	if got := csv.Controls[0]; got.ControlID != "POL-1" || got.Description != "Staff sign, yearly" || got.Family != "HR" {
+		t.Errorf("CSV control = %+v", got)
+	}
+
+	for name, data := range map[string]string{
+		"controls.txt":   "id,title\nA,B\n",
+		"untitled.csv":   "id,description\nA,B\n",
+		"duplicate.yaml": "controls:\n  - {id: A, title: One}\n  - {id: A, title: Two}\n",
+		"empty.json":     `{"framework": "X", "controls": []}`,
+		"no-title.json":  `{"controls": [{"id": "A"}]}`,
+		"mixed.csv":      "framework,id,title\nSOC 2,A,One\nISO,B,Two\n",
+		"broken.yaml":    "controls: [",
+	} {
+		if _, err := Parse(name, []byte(data)); !errors.Is(err, interfaces.ErrInvalidControl) {
+			t.Errorf("Parse(%s) = %v, want ErrInvalidControl", name, err)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	controls := []interfaces.Control{{ID: 1, ControlID: "A"}, {ID: 2, ControlID: "B"}, {ID: 3, ControlID: "C"}, {ID: 4, ControlID: "D"}}
+	links := []interfaces.ControlLink{
+		// Acme: A is mapped by an audit and two of its items, one failed
+		{ControlID: 1, AuditID: 10, Firm: "Acme"},
+		{ControlID: 1, AuditID: 10, Firm: "Acme", ItemID: 100, Result: interfaces.ChecklistPass},
+		{ControlID: 1, AuditID: 10, Firm: "Acme", ItemID: 101, Result: interfaces.ChecklistFail},
+		// B passed for Acme, but has open findings mapped to it for Globex
+		{ControlID: 2, AuditID: 10, Firm: "Acme", ItemID: 102, Result: interfaces.ChecklistPass},
+		{ControlID: 2, AuditID: 20, Firm: "Globex", OpenFindings: 2},
+		{ControlID: 2, AuditID: 20, Firm: "Globex", ItemID: 200, OpenFindings: 2},
+		// C is in a completed audit without items, and in scope of another
+		{ControlID: 3, AuditID: 11, Firm: "Acme", AuditCompleted: true},
+		{ControlID: 3, AuditID: 21, Firm: "Globex"},
+	}
+	m := Coverage(controls, links)
+	if len(m.Firms) != 2 || m.Firms[0] != "Acme" || m.Firms[1] != "Globex" {
+		t.Fatalf("Firms = %v", m.Firms)
+	}
+	tests := []struct {
+		control int
+		firm    string
+		want    string
+	}{
+		{1, "Acme", StatusFailed},
+		{2, "Acme", StatusPassed},
+		{2, "Globex", StatusOpenFindings},
+		{3, "Acme", StatusTested},
+		{3, "Globex", StatusInScope},
+		{4, "Acme", StatusNotCovered},
+		{1, "Globex", StatusNotCovered},
+	}
+	for _, tc := range tests {
+		if got := m.Cell(tc.control, tc.firm).Status(); got != tc.want {
+			t.Errorf("control %d for %s = %s, want %s", tc.control, tc.firm, got, tc.want)
+		}
+	}
+	if cell := m.Cell(1, "Acme"); cell.Audits != 1 || cell.Passed != 1 || cell.Failed != 1 {
+		t.Errorf("cell = %+v, want one audit with a pass and a fail", cell)
+	}
+	if cell := m.Cell(2, "Globex"); cell.Audits != 1 || cell.OpenFindings != 2 || cell.Pending != 1 {
+		t.Errorf("cell = %+v, want the audit's findings counted once", cell)
+	}
+	if counts := m.Counts("Acme"); counts[StatusNotCovered] != 1 || counts[StatusPassed] != 1 || counts[StatusFailed] != 1 {
+		t.Errorf("Counts(Acme) = %v", counts)
+	}
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	// Standard Library
+	"sort"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Coverage statuses of a control for a firm, worst first
+const (
+	StatusOpenFindings = "Open Findings"
+	StatusFailed       = "Failed"
+	StatusPassed       = "Passed"
+	StatusTested       = "Tested"
+	StatusInScope      = "In Scope"
+	StatusNotCovered   = "Not Covered"
+)
+
+// Statuses lists the coverage statuses, worst first
+var Statuses = []string{StatusOpenFindings, StatusFailed, StatusPassed, StatusTested, StatusInScope, StatusNotCovered}
+
+// Cell is how the audits of one firm cover one control. Audits counts the
+// audits mapped to it, directly or through their checklist items or
+// findings; the results count the mapped checklist items and OpenFindings
+// the mapped findings that are not closed.
+type Cell struct {
+	Audits          int
+	CompletedAudits int
+	Passed          int
+	Failed          int
+	NotApplicable   int
+	Pending         int
+	OpenFindings    int
+}
+
+// Tested reports whether a mapped item has been checked or a mapped audit completed
+func (c Cell) Tested() bool {
+	return c.Passed+c.Failed > 0 || c.CompletedAudits > 0
+}
+
+// Status sums the cell up. Open findings mapped to the control outrank a
+// failed item, which outranks a pass.
+func (c Cell) Status() string {
+	switch {
+	case c.Audits == 0:
+		return StatusNotCovered
+	case c.OpenFindings > 0:
+		return StatusOpenFindings
+	case c.Failed > 0:
+		return StatusFailed
+	case c.Passed > 0:
+		return StatusPassed
+	case c.Tested():
+		return StatusTested
+	}
+	return StatusInScope
+}
+
+// Matrix is the coverage of a framework's controls by firm. Audits without a
+// firm are under "".
+type Matrix struct {
+	Controls []interfaces.Control
+	Firms    []string
+	cells    map[int]map[string]Cell
+}
+
+// Cell returns the coverage of a control, by its row ID, for firm
+func (m Matrix) Cell(controlID int, firm string) Cell {
+	return m.cells[controlID][firm]
+}
+
+// Counts tallies the statuses of every control for firm
+func (m Matrix) Counts(firm string) map[string]int {
+	counts := make(map[string]int, len(Statuses))
+	for _, control := range m.Controls {
+		counts[m.Cell(control.ID, firm).Status()]++
+	}
+	return counts
+}
+
+// Coverage builds the matrix of controls from the audits, checklist items
+// and findings linked to them. Each audit counts once per control, however
+// many of its items or findings are mapped.
+func Coverage(controls []interfaces.Control, links []interfaces.ControlLink) Matrix {
+	m := Matrix{Controls: controls, cells: make(map[int]map[string]Cell)}
+	type key struct{ control, audit int }
+	counted := make(map[key]bool)
+	firms := make(map[string]bool)
+	for _, link := range links {
+		if m.cells[link.ControlID] == nil {
+			m.cells[link.ControlID] = make(map[string]Cell)
+		}
+		cell := m.cells[link.ControlID][link.Firm]
+		if k := (key{link.ControlID, link.AuditID}); !counted[k] {
+			counted[k] = true
+			cell.Audits++
+			cell.OpenFindings += link.OpenFindings
+			if link.AuditCompleted {
+				cell.CompletedAudits++
+			}
+		}
+		if link.ItemID != 0 {
+			switch link.Result {
+			case interfaces.ChecklistPass:
+				cell.Passed++
+			case interfaces.ChecklistFail:
+				cell.Failed++
+			case interfaces.ChecklistNotApplicable:
+				cell.NotApplicable++
+			default:
+				cell.Pending++
+			}
+		}
+		m.cells[link.ControlID][link.Firm] = cell
+		firms[link.Firm] = true
+	}
+	for firm := range firms {
+		m.Firms = append(m.Firms, firm)
+	}
+	sort.Strings(m.Firms)
+	return m
+}
@@ -0,0 +1,635 @@
+// Package config loads goAudit's settings from, in increasing priority:
+// built-in defaults, a system-wide file, the per-user config.json, environment
+// variables and command-line flags. Files are watched and reloaded on change.
+package config
+
+import (
+	// Standard Library
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SSLModes are the libpq sslmode values pgx understands
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// SecretStores are where the setup dialogs may write passwords; empty tries
+// the keyring and falls back to the vault
+var SecretStores = []string{"keyring", "vault"}
+
+type Config struct {
+	IconPath string   `json:"iconPath,omitempty"`
+	LDAP     LDAP     `json:"ldap"`
+	SQL      SQL      `json:"sql"`
+	Secrets  Secrets  `json:"secrets"`
+	Roles    Roles    `json:"roles"`
+	Auth     Auth     `json:"auth"`
+	OIDC     OIDC     `json:"oidc"`
+	Evidence Evidence `json:"evidence"`
+
+	AuditWorkflow AuditWorkflow `json:"auditWorkflow"`
+	Schedules     Schedules     `json:"schedules"`
+
+	// Written by older versions of goAudit and ignored
+	LegacyConfigPath string `json:"config,omitempty"`
+}
+
+// LDAPSecurityModes are how the directory connection is protected. Empty
+// means LDAPS for ldaps:// URLs and StartTLS otherwise.
+var LDAPSecurityModes = []string{"ldaps", "starttls", "none"}
+
+// LDAP describes the directory. Server, Domain and OU are the Active Directory
+// shorthand; the remaining fields override what is derived from them so any
+// directory can be used.
+type LDAP struct {
+	Server           string `json:"server,omitempty"`
+	Domain           string `json:"domain,omitempty"`
+	OU               string `json:"ou,omitempty"`
+	ReadOnlyPassword string `json:"readOnlyPassword,omitempty"`
+
+	URL         string   `json:"url,omitempty"`
+	Port        int      `json:"port,omitempty"`
+	Security    string   `json:"security,omitempty"`
+	CACert      string   `json:"caCert,omitempty"`
+	BindDN      string   `json:"bindDN,omitempty"`
+	SearchBases []string `json:"searchBases,omitempty"`
+	UserFilter  string   `json:"userFilter,omitempty"`
+
+	Attributes LDAPAttributes `json:"attributes"`
+
+	// The users table is refreshed from the directory every SyncInterval
+	// (default one hour) using SyncFilter, which defaults to the user filter
+	// matching every login name
+	SyncInterval Duration `json:"syncInterval,omitempty"`
+	SyncFilter   string   `json:"syncFilter,omitempty"`
+	SyncDisabled bool     `json:"syncDisabled,omitempty"`
+}
+
+// LDAPAttributes names the directory attributes goAudit reads. Empty uses
+// the Active Directory names.
+type LDAPAttributes struct {
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	MemberOf    string `json:"memberOf,omitempty"`
+
+	Manager       string `json:"manager,omitempty"`
+	DirectReports string `json:"directReports,omitempty"`
+	Department    string `json:"department,omitempty"`
+	// Unique, never reused ID; empty tries objectGUID then entryUUID
+	ID string `json:"id,omitempty"`
+}
+
+// Secrets chooses where new passwords are stored. Password settings may hold
+// the password itself or a reference such as "keyring:sql-password",
+// "vault:sql-password" or "file:/run/secrets/db_password".
+type Secrets struct {
+	Store     string `json:"store,omitempty"`
+	VaultPath string `json:"vaultPath,omitempty"`
+}
+
+// Roles maps directory groups, by exact DN, to goAudit roles. A user gets
+// every role whose groups they belong to. With NestedGroups, membership
+// through other groups counts too (Active Directory only). With no groups
+// mapped at all, everyone logging in from the directory is read-only. Roles
+// are looked up again after CacheTTL; zero keeps them until logout.
+type Roles struct {
+	Admin    []string `json:"admin,omitempty"`
+	Manager  []string `json:"manager,omitempty"`
+	Auditor  []string `json:"auditor,omitempty"`
+	ReadOnly []string `json:"readOnly,omitempty"`
+
+	NestedGroups bool     `json:"nestedGroups,omitempty"`
+	CacheTTL     Duration `json:"cacheTTL,omitempty"`
+}
+
+// Configured reports whether any group is mapped to a role
+func (r Roles) Configured() bool {
+	return len(r.Admin)+len(r.Manager)+len(r.Auditor)+len(r.ReadOnly) > 0
+}
+
+// AuthSources are where the auth chain can check a login. OIDC logins go
+// through the browser rather than the chain; listing it shows the button.
+var AuthSources = []string{"ldap", "local", "oidc"}
+
+// Default minimum length of local account passwords
+const defaultMinPasswordLength = 12
+
+// Default login throttling: five failures lock a username for 15 minutes
+const (
+	defaultMaxLoginFailures = 5
+	defaultLockoutMinutes   = 15
+	defaultIdleMinutes      = 30
+	defaultSessionHours     = 12
+	defaultReauthMinutes    = 5
+)
+
+// RoleNames are the goAudit roles, as rbac.Roles names them
+var RoleNames = []string{"admin", "manager", "auditor", "read-only"}
+
+// Auth orders the login sources. A source is only asked when those before it
+// are unavailable or do not know the user, so a directory that rejects a
+// password is never second-guessed by a local account. Empty tries LDAP and
+// then local accounts, and offers OIDC sign-in when it is configured.
+//
+// TOTPKey encrypts two-factor secrets in the database; every installation
+// sharing the database needs the same one. Users with a role listed in
+// RequireTwoFactor must enrol before they can log in.
+//
+// After MaxLoginFailures failed logins a username is locked for
+// LockoutMinutes; each failure before that doubles the wait for the next try.
+//
+// A session ends after IdleMinutes without input, or SessionHours after the
+// login whatever the activity. Sensitive actions ask for the password again
+// unless the user entered it in the last ReauthMinutes.
+type Auth struct {
+	Chain             []string `json:"chain,omitempty"`
+	MinPasswordLength int      `json:"minPasswordLength,omitempty"`
+	TOTPKey           string   `json:"totpKey,omitempty"`
+	RequireTwoFactor  []string `json:"requireTwoFactor,omitempty"`
+	MaxLoginFailures  int      `json:"maxLoginFailures,omitempty"`
+	LockoutMinutes    int      `json:"lockoutMinutes,omitempty"`
+	IdleMinutes       int      `json:"idleMinutes,omitempty"`
+	SessionHours      int      `json:"sessionHours,omitempty"`
+	ReauthMinutes     int      `json:"reauthMinutes,omitempty"`
+}
+
+// Sources returns the chain, or the default one
+func (a Auth) Sources() []string {
+	if len(a.Chain) == 0 {
+		return append([]string(nil), AuthSources...)
+	}
+	return append([]string(nil), a.Chain...)
+}
+
+// Allows reports whether source is part of the chain
+func (a Auth) Allows(source string) bool {
+	return contains(a.Sources(), source)
+}
+
+// PasswordLength is the minimum length of a local account password
+func (a Auth) PasswordLength() int {
+	if a.MinPasswordLength <= 0 {
+		return defaultMinPasswordLength
+	}
+	return a.MinPasswordLength
+}
+
+// LoginFailureLimit is how many failed logins lock a username
+func (a Auth) LoginFailureLimit() int {
+	if a.MaxLoginFailures <= 0 {
+		return defaultMaxLoginFailures
+	}
+	return a.MaxLoginFailures
+}
+
+// LockoutDuration is how long a locked username stays locked
+func (a Auth) LockoutDuration() time.Duration {
+	if a.LockoutMinutes <= 0 {
+		return defaultLockoutMinutes * time.Minute
+	}
+	return time.Duration(a.LockoutMinutes) * time.Minute
+}
+
+// IdleTimeout is how long a session lasts without input
+func (a Auth) IdleTimeout() time.Duration {
+	if a.IdleMinutes <= 0 {
+		return defaultIdleMinutes * time.Minute
+	}
+	return time.Duration(a.IdleMinutes) * time.Minute
+}
+
+// SessionLifetime is how long a session lasts after the login
+func (a Auth) SessionLifetime() time.Duration {
+	if a.SessionHours <= 0 {
+		return defaultSessionHours * time.Hour
+	}
+	return time.Duration(a.SessionHours) * time.Hour
+}
+
+// ReauthWindow is how long entering the password again covers sensitive
+// actions
+func (a Auth) ReauthWindow() time.Duration {
+	if a.ReauthMinutes <= 0 {
+		return defaultReauthMinutes * time.Minute
+	}
+	return time.Duration(a.ReauthMinutes) * time.Minute
+}
+
+// OIDC signs users in through an OpenID Connect identity provider, using the
+// authorization code flow with PKCE in the system browser. The provider must
+// allow http://127.0.0.1:RedirectPort/callback as a redirect URI; zero picks
+// a free port, for providers that ignore the loopback port. ClientSecret is
+// only needed by providers that do not treat desktop apps as public clients.
+//
+// UsernameClaim (default preferred_username, falling back to a verified
+// email) names the goAudit user, and the values of GroupsClaim (default
+// groups) map to roles by exact name. Without any role groups, everyone
+// signing in this way is read-only.
+type OIDC struct {
+	Issuer        string   `json:"issuer,omitempty"`
+	ClientID      string   `json:"clientId,omitempty"`
+	ClientSecret  string   `json:"clientSecret,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	RedirectPort  int      `json:"redirectPort,omitempty"`
+	UsernameClaim string   `json:"usernameClaim,omitempty"`
+	GroupsClaim   string   `json:"groupsClaim,omitempty"`
+
+	AdminGroups    []string `json:"adminGroups,omitempty"`
+	ManagerGroups  []string `json:"managerGroups,omitempty"`
+	AuditorGroups  []string `json:"auditorGroups,omitempty"`
+	ReadOnlyGroups []string `json:"readOnlyGroups,omitempty"`
+}
+
+// Configured reports whether there is a provider to sign in with
+func (o OIDC) Configured() bool {
+	return o.Issuer != "" && o.ClientID != ""
+}
+
+// Claims returns the username and groups claim names, or the defaults
+func (o OIDC) Claims() (username, groups string) {
+	username, groups = o.UsernameClaim, o.GroupsClaim
+	if username == "" {
+		username = "preferred_username"
+	}
+	if groups == "" {
+		groups = "groups"
+	}
+	return username, groups
+}
+
+// Default largest evidence upload
+const defaultEvidenceMaxSizeMB = 50
+
+// Evidence chooses where evidence files are kept. With Directory, their
+// contents are written there, named by SHA-256, and the directory must be
+// shared by every installation using the database; otherwise they are stored
+// in the database as large objects. Uploads over MaxSizeMB are refused.
+type Evidence struct {
+	Directory string `json:"directory,omitempty"`
+	MaxSizeMB int    `json:"maxSizeMB,omitempty"`
+}
+
+// MaxSize is the largest evidence upload in bytes
+func (e Evidence) MaxSize() int64 {
+	if e.MaxSizeMB <= 0 {
+		return defaultEvidenceMaxSizeMB << 20
+	}
+	return int64(e.MaxSizeMB) << 20
+}
+
+// Schedules controls the scheduler that creates the audits of recurring
+// schedules ahead of time. It runs every Interval (default one hour) in each
+// goAudit connected to the database, unless Disabled there.
+type Schedules struct {
+	Interval Duration `json:"interval,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// AuditParties are who a workflow transition can name besides roles
+var AuditParties = []string{"owner", "assignee", "reviewer"}
+
+// AuditWorkflow replaces the built-in audit life cycle of Planned, Fieldwork,
+// Review and Closed. States are in order: new audits start in the first and
+// the last one completes them. It is only read from the config files.
+type AuditWorkflow struct {
+	States      []string          `json:"states,omitempty"`
+	Transitions []AuditTransition `json:"transitions,omitempty"`
+}
+
+// AuditTransition allows moving an audit From one state To another. By
+// names who may: the audit's owner, assignee or reviewer, or a role; empty
+// lets anyone who may edit audits. With Comment, they must say why.
+type AuditTransition struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	By      []string `json:"by,omitempty"`
+	Comment bool     `json:"comment,omitempty"`
+}
+
+// Configured reports whether the built-in workflow is replaced
+func (w AuditWorkflow) Configured() bool {
+	return len(w.States) > 0
+}
+
+// SQL mirrors databases.SQLSettings; see there for how the fields are used
+type SQL struct {
+	DSN      string `json:"dsn,omitempty"`
+	Server   string `json:"server,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Database string `json:"database,omitempty"`
+
+	SSLMode     string `json:"sslMode,omitempty"`
+	SSLRootCert string `json:"sslRootCert,omitempty"`
+	SSLCert     string `json:"sslCert,omitempty"`
+	SSLKey      string `json:"sslKey,omitempty"`
+
+	ApplicationName  string   `json:"applicationName,omitempty"`
+	StatementTimeout Duration `json:"statementTimeout,omitempty"`
+
+	MaxConns        int32    `json:"maxConns,omitempty"`
+	MinConns        int32    `json:"minConns,omitempty"`
+	MaxConnIdleTime Duration `json:"maxConnIdleTime,omitempty"`
+	MaxConnLifetime Duration `json:"maxConnLifetime,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "1h30m"
+type Duration time.Duration
+
+func (d Duration) String() string {
+	if d == 0 {
+		return ""
+	}
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("durations must be strings such as \"30s\", got %s", data)
+	}
+	parsed, err := ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ParseDuration accepts time.ParseDuration syntax; empty means zero
+func ParseDuration(text string) (Duration, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected a value such as \"30s\" or \"5m\"", text)
+	}
+	return Duration(parsed), nil
+}
+
+// Defaults is the lowest configuration layer
+func Defaults() *Config {
+	cfg := &Config{
+		SQL: SQL{
+			Port:            5432,
+			ApplicationName: "goAudit",
+		},
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		cfg.IconPath = filepath.Join(dir, "goAudit", "goAudit.ico")
+	}
+	return cfg
+}
+
+// Clone returns a deep copy safe to modify
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.LDAP.SearchBases = append([]string(nil), c.LDAP.SearchBases...)
+	clone.Roles.Admin = append([]string(nil), c.Roles.Admin...)
+	clone.Roles.Manager = append([]string(nil), c.Roles.Manager...)
+	clone.Roles.Auditor = append([]string(nil), c.Roles.Auditor...)
+	clone.Roles.ReadOnly = append([]string(nil), c.Roles.ReadOnly...)
+	clone.Auth.Chain = append([]string(nil), c.Auth.Chain...)
+	clone.Auth.RequireTwoFactor = append([]string(nil), c.Auth.RequireTwoFactor...)
+	clone.OIDC.Scopes = append([]string(nil), c.OIDC.Scopes...)
+	clone.OIDC.AdminGroups = append([]string(nil), c.OIDC.AdminGroups...)
+	clone.OIDC.ManagerGroups = append([]string(nil), c.OIDC.ManagerGroups...)
+	clone.OIDC.AuditorGroups = append([]string(nil), c.OIDC.AuditorGroups...)
+	clone.OIDC.ReadOnlyGroups = append([]string(nil), c.OIDC.ReadOnlyGroups...)
+	clone.AuditWorkflow.States = append([]string(nil), c.AuditWorkflow.States...)
+	clone.AuditWorkflow.Transitions = nil
+	for _, transition := range c.AuditWorkflow.Transitions {
+		transition.By = append([]string(nil), transition.By...)
+		clone.AuditWorkflow.Transitions = append(clone.AuditWorkflow.Transitions, transition)
+	}
+	return &clone
+}
+
+// LDAPConfigured reports whether every setting needed to log in is present:
+// where the directory is, who to bind as and where to look for users
+func (c *Config) LDAPConfigured() bool {
+	ldap := c.LDAP
+	located := ldap.URL != "" || (ldap.Server != "" && ldap.Domain != "")
+	bindable := ldap.BindDN != "" || ldap.Domain != ""
+	searchable := len(ldap.SearchBases) > 0 || ldap.Domain != ""
+	return located && bindable && searchable && ldap.ReadOnlyPassword != ""
+}
+
+// Validate checks values that are present. Missing settings are not an error
+// here; the setup dialogs ask for them.
+func (c *Config) Validate() error {
+	var problems []error
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.LDAP.Server != "" && strings.Contains(c.LDAP.Server, "://") {
+		add("ldap.server", "expected a host name such as \"dc01\", got %q", c.LDAP.Server)
+	}
+	if c.LDAP.Domain != "" {
+		parts := strings.Split(c.LDAP.Domain, ".")
+		if len(parts) < 2 || len(parts) > 3 {
+			add("ldap.domain", "expected a domain such as \"example.com\", got %q", c.LDAP.Domain)
+		}
+	}
+
+	if c.LDAP.URL != "" {
+		if !strings.HasPrefix(c.LDAP.URL, "ldap://") && !strings.HasPrefix(c.LDAP.URL, "ldaps://") {
+			add("ldap.url", "expected an ldap:// or ldaps:// URL, got %q", c.LDAP.URL)
+		}
+	}
+	if c.LDAP.Port < 0 || c.LDAP.Port > 65535 {
+		add("ldap.port", "must be between 1 and 65535, got %d", c.LDAP.Port)
+	}
+	if c.LDAP.Security != "" && !contains(LDAPSecurityModes, c.LDAP.Security) {
+		add("ldap.security", "must be one of %s, got %q", strings.Join(LDAPSecurityModes, ", "), c.LDAP.Security)
+	}
+	if c.LDAP.Security == "starttls" && strings.HasPrefix(c.LDAP.URL, "ldaps://") {
+		add("ldap.security", "StartTLS cannot be used with an ldaps:// URL")
+	}
+	for _, base := range c.LDAP.SearchBases {
+		if !strings.Contains(base, "=") {
+			add("ldap.searchBases", "expected a DN such as \"OU=Users,DC=example,DC=com\", got %q", base)
+		}
+	}
+	if filter := c.LDAP.UserFilter; filter != "" {
+		if !strings.Contains(filter, "{username}") {
+			add("ldap.userFilter", "must contain {username}, got %q", filter)
+		} else if !strings.HasPrefix(filter, "(") || !strings.HasSuffix(filter, ")") {
+			add("ldap.userFilter", "must be wrapped in parentheses, got %q", filter)
+		}
+	}
+
+	if c.LDAP.SyncInterval < 0 {
+		add("ldap.syncInterval", "cannot be negative")
+	}
+	if filter := c.LDAP.SyncFilter; filter != "" && (!strings.HasPrefix(filter, "(") || !strings.HasSuffix(filter, ")")) {
+		add("ldap.syncFilter", "must be wrapped in parentheses, got %q", filter)
+	}
+
+	if c.Secrets.Store != "" && !contains(SecretStores, c.Secrets.Store) {
+		add("secrets.store", "must be one of %s, got %q", strings.Join(SecretStores, ", "), c.Secrets.Store)
+	}
+
+	roleGroups := map[string][]string{
+		"roles.admin":    c.Roles.Admin,
+		"roles.manager":  c.Roles.Manager,
+		"roles.auditor":  c.Roles.Auditor,
+		"roles.readOnly": c.Roles.ReadOnly,
+	}
+	for _, field := range []string{"roles.admin", "roles.manager", "roles.auditor", "roles.readOnly"} {
+		for _, group := range roleGroups[field] {
+			if !strings.Contains(group, "=") {
+				add(field, "expected a group DN such as \"CN=goAudit Admins,OU=Groups,DC=example,DC=com\", got %q", group)
+			}
+		}
+	}
+	if c.Roles.CacheTTL < 0 {
+		add("roles.cacheTTL", "cannot be negative")
+	}
+
+	seen := make(map[string]bool)
+	for _, source := range c.Auth.Chain {
+		if !contains(AuthSources, source) {
+			add("auth.chain", "sources must be %s, got %q", strings.Join(AuthSources, " or "), source)
+		} else if seen[source] {
+			add("auth.chain", "%q is listed twice", source)
+		}
+		seen[source] = true
+	}
+	if c.Auth.MinPasswordLength < 0 {
+		add("auth.minPasswordLength", "cannot be negative")
+	}
+	if c.Auth.MaxLoginFailures < 0 {
+		add("auth.maxLoginFailures", "cannot be negative")
+	}
+	if c.Auth.LockoutMinutes < 0 {
+		add("auth.lockoutMinutes", "cannot be negative")
+	}
+	if c.Auth.IdleMinutes < 0 {
+		add("auth.idleMinutes", "cannot be negative")
+	}
+	if c.Auth.SessionHours < 0 {
+		add("auth.sessionHours", "cannot be negative")
+	}
+	if c.Auth.ReauthMinutes < 0 {
+		add("auth.reauthMinutes", "cannot be negative")
+	}
+	if c.Auth.IdleMinutes > 0 && c.Auth.SessionHours > 0 && time.Duration(c.Auth.IdleMinutes)*time.Minute > time.Duration(c.Auth.SessionHours)*time.Hour {
+		add("auth.idleMinutes", "is longer than the session lifetime")
+	}
+	for _, role := range c.Auth.RequireTwoFactor {
+		if !contains(RoleNames, role) {
+			add("auth.requireTwoFactor", "roles must be %s, got %q", strings.Join(RoleNames, ", "), role)
+		}
+	}
+	if len(c.Auth.RequireTwoFactor) > 0 && c.Auth.TOTPKey == "" {
+		add("auth.totpKey", "is needed when two-factor login is required")
+	}
+
+	if issuer := c.OIDC.Issuer; issuer != "" {
+		if u, err := url.Parse(issuer); err != nil || u.Host == "" || (u.Scheme != "https" && !isLoopback(u.Hostname())) {
+			add("oidc.issuer", "expected an https:// URL, got %q", issuer)
+		}
+		if c.OIDC.ClientID == "" {
+			add("oidc.clientId", "is needed with an issuer")
+		}
+	}
+	if c.OIDC.RedirectPort < 0 || c.OIDC.RedirectPort > 65535 {
+		add("oidc.redirectPort", "must be between 1 and 65535, or 0 for any free port, got %d", c.OIDC.RedirectPort)
+	}
+
+	if dir := c.Evidence.Directory; dir != "" && !filepath.IsAbs(dir) {
+		add("evidence.directory", "must be an absolute path, got %q", dir)
+	}
+	if c.Evidence.MaxSizeMB < 0 {
+		add("evidence.maxSizeMB", "cannot be negative")
+	}
+	if c.Schedules.Interval < 0 {
+		add("schedules.interval", "cannot be negative")
+	}
+
+	workflow := c.AuditWorkflow
+	if len(workflow.Transitions) > 0 && !workflow.Configured() {
+		add("auditWorkflow.states", "are needed with transitions")
+	}
+	states := make(map[string]bool)
+	for _, state := range workflow.States {
+		if strings.TrimSpace(state) == "" {
+			add("auditWorkflow.states", "cannot be empty")
+		} else if states[state] {
+			add("auditWorkflow.states", "%q is listed twice", state)
+		}
+		states[state] = true
+	}
+	for _, transition := range workflow.Transitions {
+		if !states[transition.From] || !states[transition.To] {
+			add("auditWorkflow.transitions", "%q to %q uses a state that is not listed", transition.From, transition.To)
+		}
+		for _, by := range transition.By {
+			if !contains(AuditParties, by) && !contains(RoleNames, by) {
+				add("auditWorkflow.transitions", "by must be %s or a role, got %q", strings.Join(AuditParties, ", "), by)
+			}
+		}
+	}
+
+	sql := c.SQL
+	if sql.Port < 0 || sql.Port > 65535 {
+		add("sql.port", "must be between 1 and 65535, got %d", sql.Port)
+	}
+	if sql.SSLMode != "" && !contains(SSLModes, sql.SSLMode) {
+		add("sql.sslMode", "must be one of %s, got %q", strings.Join(SSLModes, ", "), sql.SSLMode)
+	}
+	if (sql.SSLCert == "") != (sql.SSLKey == "") {
+		add("sql.sslCert", "sslCert and sslKey must be set together")
+	}
+	if sql.StatementTimeout < 0 {
+		add("sql.statementTimeout", "cannot be negative")
+	}
+	if sql.MaxConns < 0 {
+		add("sql.maxConns", "cannot be negative")
+	}
+	if sql.MinConns < 0 {
+		add("sql.minConns", "cannot be negative")
+	}
+	if sql.MaxConns > 0 && sql.MinConns > sql.MaxConns {
+		add("sql.minConns", "%d exceeds maxConns %d", sql.MinConns, sql.MaxConns)
+	}
+	if sql.MaxConnIdleTime < 0 || sql.MaxConnLifetime < 0 {
+		add("sql.maxConnIdleTime", "pool durations cannot be negative")
+	}
+
+	return errors.Join(problems...)
+}
+
+// isLoopback allows plain http issuers on this machine, for testing
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useLoader installs a loader for the package level functions and restores
+// the previous state afterwards
+func useLoader(t *testing.T, l *Loader) {
+	t.Helper()
+	lock.Lock()
+	previousLoader, previousCurrent, previousListeners := loader, current, listeners
+	loader, listeners = l, nil
+	lock.Unlock()
+	t.Cleanup(func() {
+		lock.Lock()
+		loader, current, listeners = previousLoader, previousCurrent, previousListeners
+		lock.Unlock()
+	})
+}
+
+func TestLayerPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.json")
+	userPath := filepath.Join(dir, "user.json")
+	writeFile(t, systemPath, `{"ldap": {"server": "sys-dc", "domain": "example.com", "ou": "Staff"}, "sql": {"server": "sys-db", "port": 6000}}`)
+	writeFile(t, userPath, `{"ldap": {"server": "user-dc"}, "sql": {"database": "audit", "statementTimeout": "45s"}}`)
+
+	l, err := NewLoader(Options{
+		SystemPath: systemPath,
+		UserPath:   userPath,
+		Environ:    []string{"LDAP_OU=EnvOU", "SQL_SERVER=env-db", "SQL_USER="},
+		Args:       []string{"-sql-server", "flag-db"},
+	})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct{ name, got, want string }{
+		{"ldap.server from user file", cfg.LDAP.Server, "user-dc"},
+		{"ldap.domain from system file", cfg.LDAP.Domain, "example.com"},
+		{"ldap.ou from environment", cfg.LDAP.OU, "EnvOU"},
+		{"sql.server from flag", cfg.SQL.Server, "flag-db"},
+		{"sql.database from user file", cfg.SQL.Database, "audit"},
+		{"sql.applicationName from defaults", cfg.SQL.ApplicationName, "goAudit"},
+		{"empty environment variable ignored", cfg.SQL.User, ""},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+	if cfg.SQL.Port != 6000 {
+		t.Errorf("sql.port = %d, want 6000 from the system file", cfg.SQL.Port)
+	}
+	if time.Duration(cfg.SQL.StatementTimeout) != 45*time.Second {
+		t.Errorf("sql.statementTimeout = %v, want 45s", cfg.SQL.StatementTimeout)
+	}
+}
+
+func TestLoadReportsBadLayers(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.json")
+	userPath := filepath.Join(dir, "user.json")
+	writeFile(t, systemPath, `{"sql": {"server": "sys-db"}}`)
+	writeFile(t, userPath, "{\n  \"sql\": {\n    \"server\": \"user-db\",\n    \"sever\": \"typo\"\n  }\n}")
+
+	l, err := NewLoader(Options{SystemPath: systemPath, UserPath: userPath, Environ: []string{"SQL_POOL_MAX_CONNS=lots"}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	cfg, err := l.Load()
+	if err == nil {
+		t.Fatal("Load accepted an unknown key and a bad number")
+	}
+	message := err.Error()
+	for _, want := range []string{userPath, `unknown field "sever"`, "SQL_POOL_MAX_CONNS", "whole number"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("error %q does not mention %q", message, want)
+		}
+	}
+	if cfg.SQL.Server != "sys-db" {
+		t.Errorf("bad user file was partly applied: sql.server = %q", cfg.SQL.Server)
+	}
+}
+
+func TestDecodeErrorsNameTheLine(t *testing.T) {
+	cfg := Defaults()
+	err := decodeStrict([]byte("{\n  \"sql\": {\n    \"port\": \"5432\"\n  }\n}"), cfg)
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "sql.port") {
+		t.Errorf("type error = %v, want it to name sql.port on line 3", err)
+	}
+
+	err = decodeStrict([]byte("{\n  \"sql\": {\n    \"server\": \"db\",\n  }\n}"), cfg)
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("syntax error = %v, want line 4", err)
+	}
+
+	err = decodeStrict([]byte(`{"sql": {"statementTimeout": "soon"}}`), cfg)
+	if err == nil || !strings.Contains(err.Error(), `"soon"`) {
+		t.Errorf("duration error = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		field  string
+	}{
+		{"port out of range", func(c *Config) { c.SQL.Port = 70000 }, "sql.port"},
+		{"unknown sslmode", func(c *Config) { c.SQL.SSLMode = "always" }, "sql.sslMode"},
+		{"cert without key", func(c *Config) { c.SQL.SSLCert = "client.crt" }, "sql.sslCert"},
+		{"min above max", func(c *Config) { c.SQL.MaxConns, c.SQL.MinConns = 2, 3 }, "sql.minConns"},
+		{"url as ldap server", func(c *Config) { c.LDAP.Server = "ldaps://dc01" }, "ldap.server"},
+		{"single label domain", func(c *Config) { c.LDAP.Domain = "example" }, "ldap.domain"},
+		{"http ldap url", func(c *Config) { c.LDAP.URL = "http://ldap.example.com" }, "ldap.url"},
+		{"unknown ldap security", func(c *Config) { c.LDAP.Security = "tls" }, "ldap.security"},
+		{"starttls over ldaps", func(c *Config) { c.LDAP.URL, c.LDAP.Security = "ldaps://ldap", "starttls" }, "ldap.security"},
+		{"search base without a DN", func(c *Config) { c.LDAP.SearchBases = []string{"Users"} }, "ldap.searchBases"},
+		{"filter without placeholder", func(c *Config) { c.LDAP.UserFilter = "(uid=alice)" }, "ldap.userFilter"},
+		{"negative sync interval", func(c *Config) { c.LDAP.SyncInterval = -1 }, "ldap.syncInterval"},
+		{"role group without a DN", func(c *Config) { c.Roles.Admin = []string{"Domain Admins"} }, "roles.admin"},
+		{"negative role cache", func(c *Config) { c.Roles.CacheTTL = -1 }, "roles.cacheTTL"},
+		{"unknown auth source", func(c *Config) { c.Auth.Chain = []string{"ldap", "kerberos"} }, "auth.chain"},
+		{"repeated auth source", func(c *Config) { c.Auth.Chain = []string{"local", "local"} }, "auth.chain"},
+		{"two-factor for an unknown role", func(c *Config) { c.Auth.RequireTwoFactor, c.Auth.TOTPKey = []string{"owner"}, "k" }, "auth.requireTwoFactor"},
+		{"plain http issuer", func(c *Config) { c.OIDC.Issuer, c.OIDC.ClientID = "http://login.example.com", "goaudit" }, "oidc.issuer"},
+		{"issuer without a client", func(c *Config) { c.OIDC.Issuer = "https://login.example.com" }, "oidc.clientId"},
+		{"negative lockout", func(c *Config) { c.Auth.LockoutMinutes = -1 }, "auth.lockoutMinutes"},
+		{"idle timeout past the session", func(c *Config) { c.Auth.IdleMinutes, c.Auth.SessionHours = 120, 1 }, "auth.idleMinutes"},
+		{"two-factor without a key", func(c *Config) { c.Auth.RequireTwoFactor = []string{"admin"} }, "auth.totpKey"},
+		{"relative evidence directory", func(c *Config) { c.Evidence.Directory = "evidence" }, "evidence.directory"},
+		{"negative evidence size", func(c *Config) { c.Evidence.MaxSizeMB = -1 }, "evidence.maxSizeMB"},
+		{"negative schedule interval", func(c *Config) { c.Schedules.Interval = -1 }, "schedules.interval"},
+		{"repeated audit state", func(c *Config) { c.AuditWorkflow.States = []string{"Open", "Open"} }, "auditWorkflow.states"},
+		{"transition to an unlisted state", func(c *Config) {
+			c.AuditWorkflow = AuditWorkflow{States: []string{"Open", "Done"}, Transitions: []AuditTransition{{From: "Open", To: "Closed"}}}
+		}, "auditWorkflow.transitions"},
+		{"transition by an unknown party", func(c *Config) {
+			c.AuditWorkflow = AuditWorkflow{States: []string{"Open", "Done"}, Transitions: []AuditTransition{{From: "Open", To: "Done", By: []string{"partner"}}}}
+		}, "auditWorkflow.transitions"},
+	}
+
+	if err := Defaults().Validate(); err != nil {
+		t.Fatalf("defaults are invalid: %v", err)
+	}
+	for _, tc := range tests {
+		cfg := Defaults()
+		tc.modify(cfg)
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.field) {
+			t.Errorf("%s: got %v, want an error for %s", tc.name, err, tc.field)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	_, err := NewLoader(Options{Args: []string{"-sql-password", "secret"}, Environ: []string{}})
+	if err == nil {
+		t.Error("secrets must not be accepted on the command line")
+	}
+
+	_, err = NewLoader(Options{Args: []string{"-h"}, Environ: []string{}})
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h returned %v, want flag.ErrHelp", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "custom.json")
+	l, err := NewLoader(Options{Args: []string{"-config", path}, Environ: []string{EnvUserConfig + "=/ignored.json"}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	if l.UserPath != path {
+		t.Errorf("UserPath = %q, want the -config flag to win", l.UserPath)
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	cfg := &Config{}
+	for key, value := range map[string]string{
+		"SQL_PORT":                   "6432",
+		"SQL_POOL_MAX_CONN_LIFETIME": "1h0m0s",
+		"LDAP_READONLY_PASSWORD":     "secret",
+		"LDAP_SEARCH_BASES":          "OU=Staff,DC=example,DC=com;OU=Contractors,DC=example,DC=com",
+		"ROLES_NESTED_GROUPS":        "true",
+	} {
+		if err := Set(cfg, key, value); err != nil {
+			t.Fatalf("Set(%s): %v", key, err)
+		}
+		if got := Get(cfg, key); got != value {
+			t.Errorf("Get(%s) = %q, want %q", key, got, value)
+		}
+	}
+	if err := Set(cfg, "SQL_PORT", "five"); err == nil || !strings.Contains(err.Error(), "SQL_PORT") {
+		t.Errorf("Set with a bad port = %v", err)
+	}
+	if err := Set(cfg, "ROLES_NESTED_GROUPS", "sometimes"); err == nil {
+		t.Error("Set accepted a bad boolean")
+	}
+	if err := Set(cfg, "NOPE", "x"); err == nil {
+		t.Error("Set accepted an unknown key")
+	}
+}
+
+func TestSaveUserOnlyWritesUserLayer(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.json")
+	userPath := filepath.Join(dir, "user", "config.json")
+	writeFile(t, systemPath, `{"sql": {"server": "sys-db", "database": "audit"}}`)
+	writeFile(t, userPath, `{"iconPath": "/icons/goAudit.ico", "config": "/legacy/config.json"}`)
+
+	l, err := NewLoader(Options{SystemPath: systemPath, UserPath: userPath, Environ: []string{}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	useLoader(t, l)
+
+	var notified *Config
+	OnChange(func(cfg *Config) { notified = cfg })
+
+	err = SaveUser(func(cfg *Config) error {
+		cfg.LDAP = LDAP{Server: "dc01", Domain: "example.com", OU: "Staff", ReadOnlyPassword: "secret"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	data, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	if strings.Contains(saved, "sys-db") || strings.Contains(saved, "applicationName") {
+		t.Errorf("other layers leaked into the user file:\n%s", saved)
+	}
+	if !strings.Contains(saved, `"iconPath": "/icons/goAudit.ico"`) {
+		t.Errorf("existing user setting was lost:\n%s", saved)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(userPath); info.Mode().Perm() != 0600 {
+			t.Errorf("user file mode = %v, want 0600", info.Mode().Perm())
+		}
+	}
+
+	if notified == nil || !notified.LDAPConfigured() || notified.SQL.Server != "sys-db" {
+		t.Errorf("reload after save = %+v", notified)
+	}
+	if Current() != notified {
+		t.Error("Current does not return the reloaded config")
+	}
+}
+
+func TestImportEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+	userPath := filepath.Join(dir, "user", "config.json")
+	writeFile(t, envPath, "LDAP_SERVER=dc01\nLDAP_DOMAIN=example.com\nSQL_PORT=6432\nUNRELATED=1\n")
+
+	l, err := NewLoader(Options{SystemPath: filepath.Join(dir, "none.json"), UserPath: userPath, Environ: []string{}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	useLoader(t, l)
+
+	imported, err := ImportEnvFile(envPath)
+	if err != nil || !imported {
+		t.Fatalf("ImportEnvFile = %v, %v", imported, err)
+	}
+	cfg := Current()
+	if cfg.LDAP.Server != "dc01" || cfg.LDAP.Domain != "example.com" || cfg.SQL.Port != 6432 {
+		t.Errorf("imported config = %+v", cfg)
+	}
+
+	writeFile(t, envPath, "LDAP_SERVER=dc02\n")
+	imported, err = ImportEnvFile(envPath)
+	if err != nil || imported {
+		t.Errorf("second import = %v, %v; want it skipped once the user file exists", imported, err)
+	}
+}
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.json")
+	writeFile(t, userPath, `{"ldap": {"server": "dc01"}}`)
+
+	l, err := NewLoader(Options{SystemPath: filepath.Join(dir, "system.json"), UserPath: userPath, Environ: []string{}})
+	if err != nil {
+		t.Fatalf("NewLoader: %v", err)
+	}
+	useLoader(t, l)
+	if _, err := Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	reloaded := make(chan *Config, 1)
+	OnChange(func(cfg *Config) {
+		select {
+		case reloaded <- cfg:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	writeFile(t, userPath, `{"ldap": {"server": "dc02"}}`)
+	select {
+	case cfg := <-reloaded:
+		if cfg.LDAP.Server != "dc02" {
+			t.Errorf("reloaded ldap.server = %q, want dc02", cfg.LDAP.Server)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded after the file changed")
+	}
+}
+
+func TestLDAPConfigured(t *testing.T) {
+	tests := []struct {
+		name string
+		ldap LDAP
+		want bool
+	}{
+		{"active directory shorthand", LDAP{Server: "dc01", Domain: "example.com", ReadOnlyPassword: "x"}, true},
+		{"missing password", LDAP{Server: "dc01", Domain: "example.com"}, false},
+		{"openldap", LDAP{URL: "ldap://ldap", BindDN: "cn=reader,dc=example,dc=org", SearchBases: []string{"ou=people,dc=example,dc=org"}, ReadOnlyPassword: "x"}, true},
+		{"url without bind dn or domain", LDAP{URL: "ldap://ldap", SearchBases: []string{"ou=people,dc=example,dc=org"}, ReadOnlyPassword: "x"}, false},
+	}
+	for _, tc := range tests {
+		cfg := &Config{LDAP: tc.ldap}
+		if got := cfg.LDAPConfigured(); got != tc.want {
+			t.Errorf("%s: LDAPConfigured = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package config
+
+import (
+	// Standard Library
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that move the config files themselves
+const (
+	EnvUserConfig   = "GOAUDIT_CONFIG"
+	EnvSystemConfig = "GOAUDIT_SYSTEM_CONFIG"
+)
+
+// setting binds one value to its environment variable and, unless it is a
+// secret, a command-line flag derived from the same name.
+type setting struct {
+	Key    string
+	Usage  string
+	Secret bool
+	get    func(*Config) string
+	set    func(*Config, string) error
+}
+
+var settings = []setting{
+	stringSetting("GOAUDIT_ICON_PATH", "application icon", false, func(c *Config) *string { return &c.IconPath }),
+
+	stringSetting("GOAUDIT_SECRET_STORE", "where new passwords are stored: keyring or vault", false, func(c *Config) *string { return &c.Secrets.Store }),
+	stringSetting("GOAUDIT_VAULT_PATH", "encrypted secrets file", false, func(c *Config) *string { return &c.Secrets.VaultPath }),
+
+	stringSetting("LDAP_SERVER", "domain controller host name, without the domain", false, func(c *Config) *string { return &c.LDAP.Server }),
+	stringSetting("LDAP_DOMAIN", "Active Directory domain, e.g. example.com", false, func(c *Config) *string { return &c.LDAP.Domain }),
+	stringSetting("LDAP_OU", "OU containing user accounts", false, func(c *Config) *string { return &c.LDAP.OU }),
+	stringSetting("LDAP_READONLY_PASSWORD", "", true, func(c *Config) *string { return &c.LDAP.ReadOnlyPassword }),
+	stringSetting("LDAP_URL", "directory URL, e.g. ldaps://ldap.example.com (overrides server and domain)", false, func(c *Config) *string { return &c.LDAP.URL }),
+	intSetting("LDAP_PORT", "directory port (default 636 for LDAPS, 389 otherwise)", func(c *Config) *int { return &c.LDAP.Port }),
+	stringSetting("LDAP_SECURITY", "ldaps, starttls or none", false, func(c *Config) *string { return &c.LDAP.Security }),
+	stringSetting("LDAP_CA_CERT", "PEM bundle of CAs trusted for the directory", false, func(c *Config) *string { return &c.LDAP.CACert }),
+	stringSetting("LDAP_BIND_DN", "DN of the read-only bind account", false, func(c *Config) *string { return &c.LDAP.BindDN }),
+	listSetting("LDAP_SEARCH_BASES", "semicolon separated DNs searched for users", func(c *Config) *[]string { return &c.LDAP.SearchBases }),
+	stringSetting("LDAP_USER_FILTER", "user search filter, {username} is replaced by the escaped login name", false, func(c *Config) *string { return &c.LDAP.UserFilter }),
+	stringSetting("LDAP_ATTR_USERNAME", "login name attribute", false, func(c *Config) *string { return &c.LDAP.Attributes.Username }),
+	stringSetting("LDAP_ATTR_DISPLAY_NAME", "display name attribute", false, func(c *Config) *string { return &c.LDAP.Attributes.DisplayName }),
+	stringSetting("LDAP_ATTR_EMAIL", "email attribute", false, func(c *Config) *string { return &c.LDAP.Attributes.Email }),
+	stringSetting("LDAP_ATTR_MEMBER_OF", "group membership attribute", false, func(c *Config) *string { return &c.LDAP.Attributes.MemberOf }),
+	stringSetting("LDAP_ATTR_MANAGER", "attribute holding the DN of a user's manager", false, func(c *Config) *string { return &c.LDAP.Attributes.Manager }),
+	stringSetting("LDAP_ATTR_DEPARTMENT", "department attribute", false, func(c *Config) *string { return &c.LDAP.Attributes.Department }),
+	stringSetting("LDAP_ATTR_ID", "unique ID attribute (default objectGUID, then entryUUID)", false, func(c *Config) *string { return &c.LDAP.Attributes.ID }),
+	stringSetting("LDAP_ATTR_DIRECT_REPORTS", "attribute listing the DNs reporting to a manager", false, func(c *Config) *string { return &c.LDAP.Attributes.DirectReports }),
+	durationSetting("LDAP_SYNC_INTERVAL", "how often users are synced from the directory (default 1h)", func(c *Config) *Duration { return &c.LDAP.SyncInterval }),
+	stringSetting("LDAP_SYNC_FILTER", "filter selecting the users to sync (default: the user filter for any login name)", false, func(c *Config) *string { return &c.LDAP.SyncFilter }),
+	boolSetting("LDAP_SYNC_DISABLED", "do not sync users from the directory", func(c *Config) *bool { return &c.LDAP.SyncDisabled }),
+
+	listSetting("ROLES_ADMIN_GROUPS", "semicolon separated group DNs granted the admin role", func(c *Config) *[]string { return &c.Roles.Admin }),
+	listSetting("ROLES_MANAGER_GROUPS", "semicolon separated group DNs granted the manager role", func(c *Config) *[]string { return &c.Roles.Manager }),
+	listSetting("ROLES_AUDITOR_GROUPS", "semicolon separated group DNs granted the auditor role", func(c *Config) *[]string { return &c.Roles.Auditor }),
+	listSetting("ROLES_READONLY_GROUPS", "semicolon separated group DNs granted the read-only role", func(c *Config) *[]string { return &c.Roles.ReadOnly }),
+	boolSetting("ROLES_NESTED_GROUPS", "count membership through nested groups (Active Directory)", func(c *Config) *bool { return &c.Roles.NestedGroups }),
+	durationSetting("ROLES_CACHE_TTL", "look roles up again after this long, e.g. 15m", func(c *Config) *Duration { return &c.Roles.CacheTTL }),
+
+	listSetting("AUTH_CHAIN", "semicolon separated login sources tried in order: ldap, local, oidc", func(c *Config) *[]string { return &c.Auth.Chain }),
+	intSetting("AUTH_MIN_PASSWORD_LENGTH", "minimum length of local account passwords (default 12)", func(c *Config) *int { return &c.Auth.MinPasswordLength }),
+	stringSetting("AUTH_TOTP_KEY", "", true, func(c *Config) *string { return &c.Auth.TOTPKey }),
+	intSetting("AUTH_MAX_LOGIN_FAILURES", "failed logins before a username is locked (default 5)", func(c *Config) *int { return &c.Auth.MaxLoginFailures }),
+	intSetting("AUTH_LOCKOUT_MINUTES", "how long a locked username stays locked (default 15)", func(c *Config) *int { return &c.Auth.LockoutMinutes }),
+	intSetting("AUTH_IDLE_MINUTES", "minutes without input before the session ends (default 30)", func(c *Config) *int { return &c.Auth.IdleMinutes }),
+	intSetting("AUTH_SESSION_HOURS", "hours after login before the session ends (default 12)", func(c *Config) *int { return &c.Auth.SessionHours }),
+	intSetting("AUTH_REAUTH_MINUTES", "minutes a password entered again covers sensitive actions (default 5)", func(c *Config) *int { return &c.Auth.ReauthMinutes }),
+	listSetting("AUTH_REQUIRE_2FA", "semicolon separated roles that must log in with a second factor, e.g. admin;manager", func(c *Config) *[]string { return &c.Auth.RequireTwoFactor }),
+
+	stringSetting("OIDC_ISSUER", "OpenID Connect issuer URL, e.g. https://login.example.com", false, func(c *Config) *string { return &c.OIDC.Issuer }),
+	stringSetting("OIDC_CLIENT_ID", "client ID registered with the identity provider", false, func(c *Config) *string { return &c.OIDC.ClientID }),
+	stringSetting("OIDC_CLIENT_SECRET", "", true, func(c *Config) *string { return &c.OIDC.ClientSecret }),
+	listSetting("OIDC_SCOPES", "semicolon separated extra scopes; openid, profile and email are always asked for", func(c *Config) *[]string { return &c.OIDC.Scopes }),
+	intSetting("OIDC_REDIRECT_PORT", "loopback port of the redirect URI (default any free port)", func(c *Config) *int { return &c.OIDC.RedirectPort }),
+	stringSetting("OIDC_USERNAME_CLAIM", "ID token claim holding the username (default preferred_username)", false, func(c *Config) *string { return &c.OIDC.UsernameClaim }),
+	stringSetting("OIDC_GROUPS_CLAIM", "ID token claim holding the groups (default groups)", false, func(c *Config) *string { return &c.OIDC.GroupsClaim }),
+	listSetting("OIDC_ADMIN_GROUPS", "semicolon separated group names granted the admin role", func(c *Config) *[]string { return &c.OIDC.AdminGroups }),
+	listSetting("OIDC_MANAGER_GROUPS", "semicolon separated group names granted the manager role", func(c *Config) *[]string { return &c.OIDC.ManagerGroups }),
+	listSetting("OIDC_AUDITOR_GROUPS", "semicolon separated group names granted the auditor role", func(c *Config) *[]string { return &c.OIDC.AuditorGroups }),
+	listSetting("OIDC_READONLY_GROUPS", "semicolon separated group names granted the read-only role", func(c *Config) *[]string { return &c.OIDC.ReadOnlyGroups }),
+
+	stringSetting("EVIDENCE_DIRECTORY", "shared directory for evidence files (default: in the database)", false, func(c *Config) *string { return &c.Evidence.Directory }),
+	intSetting("EVIDENCE_MAX_SIZE_MB", "largest evidence upload in MB (default 50)", func(c *Config) *int { return &c.Evidence.MaxSizeMB }),
+
+	durationSetting("SCHEDULES_INTERVAL", "how often audits are created for recurring schedules (default 1h)", func(c *Config) *Duration { return &c.Schedules.Interval }),
+	boolSetting("SCHEDULES_DISABLED", "do not create the audits of recurring schedules from this installation", func(c *Config) *bool { return &c.Schedules.Disabled }),
+
+	stringSetting("SQL_DSN", "", true, func(c *Config) *string { return &c.SQL.DSN }),
+	stringSetting("SQL_SERVER", "Postgres host", false, func(c *Config) *string { return &c.SQL.Server }),
+	intSetting("SQL_PORT", "Postgres port", func(c *Config) *int { return &c.SQL.Port }),
+	stringSetting("SQL_USER", "Postgres user", false, func(c *Config) *string { return &c.SQL.User }),
+	stringSetting("SQL_PASSWORD", "", true, func(c *Config) *string { return &c.SQL.Password }),
+	stringSetting("SQL_DATABASE", "Postgres database name", false, func(c *Config) *string { return &c.SQL.Database }),
+	stringSetting("SQL_SSLMODE", "Postgres sslmode", false, func(c *Config) *string { return &c.SQL.SSLMode }),
+	stringSetting("SQL_SSLROOTCERT", "CA certificate used to verify the server", false, func(c *Config) *string { return &c.SQL.SSLRootCert }),
+	stringSetting("SQL_SSLCERT", "client certificate", false, func(c *Config) *string { return &c.SQL.SSLCert }),
+	stringSetting("SQL_SSLKEY", "client certificate key", false, func(c *Config) *string { return &c.SQL.SSLKey }),
+	stringSetting("SQL_APPLICATION_NAME", "application_name reported to Postgres", false, func(c *Config) *string { return &c.SQL.ApplicationName }),
+	durationSetting("SQL_STATEMENT_TIMEOUT", "statement timeout, e.g. 30s", func(c *Config) *Duration { return &c.SQL.StatementTimeout }),
+	int32Setting("SQL_POOL_MAX_CONNS", "maximum pooled connections", func(c *Config) *int32 { return &c.SQL.MaxConns }),
+	int32Setting("SQL_POOL_MIN_CONNS", "minimum pooled connections", func(c *Config) *int32 { return &c.SQL.MinConns }),
+	durationSetting("SQL_POOL_MAX_CONN_IDLE_TIME", "close pooled connections idle this long", func(c *Config) *Duration { return &c.SQL.MaxConnIdleTime }),
+	durationSetting("SQL_POOL_MAX_CONN_LIFETIME", "recycle pooled connections after this long", func(c *Config) *Duration { return &c.SQL.MaxConnLifetime }),
+}
+
+func stringSetting(key, usage string, secret bool, field func(*Config) *string) setting {
+	return setting{
+		Key:    key,
+		Usage:  usage,
+		Secret: secret,
+		get:    func(c *Config) string { return *field(c) },
+		set:    func(c *Config, value string) error { *field(c) = value; return nil },
+	}
+}
+
+func intSetting(key, usage string, field func(*Config) *int) setting {
+	return setting{
+		Key:   key,
+		Usage: usage,
+		get: func(c *Config) string {
+			if *field(c) == 0 {
+				return ""
+			}
+			return strconv.Itoa(*field(c))
+		},
+		set: func(c *Config, value string) error {
+			if strings.TrimSpace(value) == "" {
+				*field(c) = 0
+				return nil
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("expected a whole number, got %q", value)
+			}
+			*field(c) = n
+			return nil
+		},
+	}
+}
+
+func int32Setting(key, usage string, field func(*Config) *int32) setting {
+	return setting{
+		Key:   key,
+		Usage: usage,
+		get: func(c *Config) string {
+			if *field(c) == 0 {
+				return ""
+			}
+			return strconv.Itoa(int(*field(c)))
+		},
+		set: func(c *Config, value string) error {
+			if strings.TrimSpace(value) == "" {
+				*field(c) = 0
+				return nil
+			}
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return fmt.Errorf("expected a whole number, got %q", value)
+			}
+			*field(c) = int32(n)
+			return nil
+		},
+	}
+}
+
+// listSetting separates values with semicolons, since DNs contain commas
+func listSetting(key, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		Key:   key,
+		Usage: usage,
+		get:   func(c *Config) string { return strings.Join(*field(c), ";") },
+		set: func(c *Config, value string) error {
+			var values []string
+			for _, part := range strings.Split(value, ";") {
+				if part = strings.TrimSpace(part); part != "" {
+					values = append(values, part)
+				}
+			}
+			*field(c) = values
+			return nil
+		},
+	}
+}
+
+func boolSetting(key, usage string, field func(*Config) *bool) setting {
+	return setting{
+		Key:   key,
+		Usage: usage,
+		get: func(c *Config) string {
+			if !*field(c) {
+				return ""
+			}
+			return "true"
+		},
+		set: func(c *Config, value string) error {
+			if strings.TrimSpace(value) == "" {
+				*field(c) = false
+				return nil
+			}
+			b, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("expected true or false, got %q", value)
+			}
+			*field(c) = b
+			return nil
+		},
+	}
+}
+
+func durationSetting(key, usage string, field func(*Config) *Duration) setting {
+	return setting{
+		Key:   key,
+		Usage: usage,
+		get:   func(c *Config) string { return field(c).String() },
+		set: func(c *Config, value string) error {
+			d, err := ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = d
+			return nil
+		},
+	}
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Get returns a setting by its environment variable name, formatted as it
+// would be written there
+func Get(c *Config, key string) string {
+	s, ok := lookupSetting(key)
+	if !ok {
+		return ""
+	}
+	return s.get(c)
+}
+
+// Set parses value into the setting named by its environment variable
+func Set(c *Config, key, value string) error {
+	s, ok := lookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %s", key)
+	}
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+// SecretKeys lists the settings that hold passwords
+func SecretKeys() []string {
+	var keys []string
+	for _, s := range settings {
+		if s.Secret {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, "GOAUDIT_"), "_", "-"))
+}
+
+// Options choose where each layer comes from. Zero values use the real
+// environment and the platform's standard locations.
+type Options struct {
+	SystemPath string
+	UserPath   string
+	Environ    []string
+	Args       []string
+}
+
+// Loader merges the layers. Environment and flags are captured when the
+// loader is built; the files are re-read on every Load.
+type Loader struct {
+	SystemPath string
+	UserPath   string
+	env        map[string]string
+	flags      map[string]string
+}
+
+// NewLoader parses the command-line flags and resolves the file locations.
+// -h prints the usage and returns flag.ErrHelp.
+func NewLoader(opts Options) (*Loader, error) {
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	env := make(map[string]string)
+	for _, pair := range environ {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			env[key] = value
+		}
+	}
+
+	flags, userPath, err := parseFlags(opts.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := &Loader{SystemPath: opts.SystemPath, UserPath: opts.UserPath, env: env, flags: flags}
+	if userPath != "" {
+		loader.UserPath = userPath
+	}
+	if loader.UserPath == "" {
+		loader.UserPath = env[EnvUserConfig]
+	}
+	if loader.UserPath == "" {
+		loader.UserPath, err = DefaultUserPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if loader.SystemPath == "" {
+		loader.SystemPath = env[EnvSystemConfig]
+	}
+	if loader.SystemPath == "" {
+		loader.SystemPath = DefaultSystemPath()
+	}
+	return loader, nil
+}
+
+func parseFlags(args []string) (map[string]string, string, error) {
+	fs := flag.NewFlagSet("goAudit", flag.ContinueOnError)
+
+	userPath := fs.String("config", "", "per-user config file (default "+defaultUserPathHint()+")")
+	values := make(map[string]*string)
+	for _, s := range settings {
+		if s.Secret {
+			continue
+		}
+		values[s.Key] = fs.String(flagName(s.Key), "", s.Usage+" (env "+s.Key+")")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("invalid command line: %v", err)
+	}
+
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		for key, value := range values {
+			if flagName(key) == f.Name {
+				flags[key] = *value
+			}
+		}
+	})
+	return flags, *userPath, nil
+}
+
+// Load merges every layer. Layers that cannot be read are skipped, so the
+// returned config is always usable; the error describes everything skipped
+// or invalid.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Defaults()
+	var problems []error
+
+	for _, path := range []string{l.SystemPath, l.UserPath} {
+		if err := mergeFile(cfg, path); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value := l.env[s.Key]; value != "" {
+			if err := s.set(cfg, value); err != nil {
+				problems = append(problems, fmt.Errorf("environment variable %s: %v", s.Key, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := l.flags[s.Key]; ok {
+			if err := s.set(cfg, value); err != nil {
+				problems = append(problems, fmt.Errorf("flag -%s: %v", flagName(s.Key), err))
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+	return cfg, errors.Join(problems...)
+}
+
+// mergeFile overlays the keys present in path onto cfg. A missing file is
+// not an error. A file that fails to parse leaves cfg untouched.
+func mergeFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	merged := cfg.Clone()
+	if err := decodeStrict(data, merged); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	*cfg = *merged
+	return nil
+}
+
+// decodeStrict rejects unknown keys and reports syntax errors by line
+func decodeStrict(data []byte, cfg *Config) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(cfg)
+	if err == nil {
+		if _, extra := decoder.Token(); extra != io.EOF {
+			return fmt.Errorf("unexpected content after the closing brace")
+		}
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("line %d: %v", lineOf(data, syntaxErr.Offset), err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("line %d: %s must be a %s, not a %s", lineOf(data, typeErr.Offset), typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+}
+
+func lineOf(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// DefaultUserPath is os.UserConfigDir()/goAudit/config.json
+func DefaultUserPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %v", err)
+	}
+	return filepath.Join(dir, "goAudit", "config.json"), nil
+}
+
+func defaultUserPathHint() string {
+	if path, err := DefaultUserPath(); err == nil {
+		return path
+	}
+	return "the user config directory"
+}
+
+// DefaultSystemPath is the machine-wide config file for this platform
+func DefaultSystemPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "goAudit", "config.json")
+	case "darwin":
+		return "/Library/Application Support/goAudit/config.json"
+	default:
+		return "/etc/goAudit/config.json"
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	// Standard Library
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// External Imports
+	"github.com/fsnotify/fsnotify"
+	"github.com/joho/godotenv"
+)
+
+// Editors often write a file in several steps; wait for them to finish
+const reloadDelay = 250 * time.Millisecond
+
+var (
+	lock      sync.RWMutex
+	current   = Defaults()
+	loader    *Loader
+	listeners []func(*Config)
+)
+
+// Init builds the loader from the command line and loads the configuration.
+// The returned config is usable even when err describes skipped layers.
+func Init(args []string) (*Config, error) {
+	l, err := NewLoader(Options{Args: args})
+	if err != nil {
+		return Current(), err
+	}
+
+	lock.Lock()
+	loader = l
+	lock.Unlock()
+
+	return Reload()
+}
+
+// Current returns the active configuration. Callers must not modify it.
+func Current() *Config {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Use replaces the active configuration, for tests and tools that do not
+// load from disk
+func Use(cfg *Config) {
+	lock.Lock()
+	current = cfg
+	fns := append([]func(*Config){}, listeners...)
+	lock.Unlock()
+
+	for _, fn := range fns {
+		fn(cfg)
+	}
+}
+
+// OnChange registers fn to run after every reload
+func OnChange(fn func(*Config)) {
+	lock.Lock()
+	defer lock.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// UserPath is the per-user config file that SaveUser writes
+func UserPath() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	if loader != nil {
+		return loader.UserPath
+	}
+	path, _ := DefaultUserPath()
+	return path
+}
+
+// Reload re-reads every layer. Problems are returned but the merged config
+// is still applied, so one bad key does not take the whole app down.
+func Reload() (*Config, error) {
+	lock.RLock()
+	l := loader
+	lock.RUnlock()
+	if l == nil {
+		return Current(), fmt.Errorf("configuration has not been initialized")
+	}
+
+	cfg, err := l.Load()
+	Use(cfg)
+	return cfg, err
+}
+
+// SaveUser applies update to the per-user file only, leaving system, environment
+// and flag layers alone, then reloads. Keys update leaves empty are removed so
+// the lower layers show through again.
+func SaveUser(update func(*Config) error) error {
+	path := UserPath()
+
+	userCfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err == nil {
+		if err := decodeStrict(data, userCfg); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	if err := update(userCfg); err != nil {
+		return err
+	}
+	if err := userCfg.Validate(); err != nil {
+		return err
+	}
+
+	if err := writeConfigFile(path, userCfg); err != nil {
+		return err
+	}
+
+	lock.RLock()
+	initialized := loader != nil
+	lock.RUnlock()
+	if initialized {
+		_, err = Reload()
+		if err != nil {
+			log.Printf("Configuration reloaded with problems: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeConfigFile replaces path atomically. It may hold passwords, so it is
+// only readable by the owner.
+func writeConfigFile(path string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// Watch reloads whenever the system or user file changes until ctx is done.
+// The parent directories are watched because editors and SaveUser replace the
+// file rather than writing it in place.
+func Watch(ctx context.Context) error {
+	lock.RLock()
+	l := loader
+	lock.RUnlock()
+	if l == nil {
+		return fmt.Errorf("configuration has not been initialized")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch configuration: %v", err)
+	}
+
+	files := map[string]bool{}
+	for _, path := range []string{l.SystemPath, l.UserPath} {
+		files[filepath.Clean(path)] = true
+		dir := filepath.Dir(path)
+		if err := watcher.Add(dir); err != nil {
+			// Usually the directory does not exist yet; nothing to watch
+			log.Printf("Not watching %s: %v", dir, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] {
+					pending = time.After(reloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Configuration watcher error: %v", err)
+			case <-pending:
+				pending = nil
+				_, err := Reload()
+				if err != nil {
+					log.Printf("Configuration reloaded with problems: %v", err)
+				} else {
+					log.Println("Configuration reloaded")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// ImportEnvFile copies LDAP_* and SQL_* settings from a .env file written by
+// older versions into the per-user file. It does nothing once the per-user
+// file exists, and reports whether anything was imported.
+func ImportEnvFile(envPath string) (bool, error) {
+	if _, err := os.Stat(UserPath()); err == nil {
+		return false, nil
+	}
+	values, err := godotenv.Read(envPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", envPath, err)
+	}
+
+	imported := false
+	err = SaveUser(func(cfg *Config) error {
+		for _, s := range settings {
+			if value := values[s.Key]; value != "" {
+				if err := s.set(cfg, value); err != nil {
+					return fmt.Errorf("%s in %s: %v", s.Key, envPath, err)
+				}
+				imported = true
+			}
+		}
+		return nil
+	})
+	return imported, err
+}
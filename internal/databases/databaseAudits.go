@@ -3,10 +3,15 @@ package databases
 import (
 	// Standard Library
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	// External Imports
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
 )
@@ -25,39 +30,146 @@ func EnsureAuditTableExists() error {
         assigned_user TEXT,
         completed_at TIMESTAMP WITH TIME ZONE,
         completed BOOLEAN DEFAULT FALSE,
-        user_id INTEGER NOT NULL,
+        user_id UUID NOT NULL REFERENCES users (user_id),
         username TEXT,
         additional_users TEXT[],
-        firm TEXT
+        firm TEXT,
+        status TEXT,
+        reviewer TEXT,
+        version INTEGER NOT NULL DEFAULT 1
     );`
 
 	_, err := DBPool.Exec(context.Background(), createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create audits table: %v", err)
 	}
+	for _, column := range []struct{ name, definition string }{
+		{"version", "INTEGER NOT NULL DEFAULT 1"},
+		{"status", "TEXT"},
+		{"reviewer", "TEXT"},
+	} {
+		if err := ensureColumn("audits", column.name, column.definition); err != nil {
+			return err
+		}
+	}
 
+	_, err = DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS audit_status_changes (
+        id SERIAL PRIMARY KEY,
+        audit_id INTEGER NOT NULL REFERENCES audits (id) ON DELETE CASCADE,
+        from_status TEXT NOT NULL,
+        to_status TEXT NOT NULL,
+        actor TEXT NOT NULL,
+        comment TEXT,
+        changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS audit_status_changes_audit ON audit_status_changes (audit_id);`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit status changes table: %v", err)
+	}
 	return nil
 }
 
+// CreateAudit adds an audit. An audit created from a template takes its
+// type and area where they are blank, and a copy of its checklist. A
+// schedule's second audit for the same date is refused with
+// ErrAlreadyScheduled.
 func (dw *DatabaseWrapper) CreateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
-	query := `INSERT INTO audits (action, audit_id, audit_type, audit_area, notes, assigned_user, completed, user_id, username, additional_users, firm)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-              RETURNING id, created_at, updated_at`
+	userID, err := dw.ownerID(audit.Username)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	audit.UserID = userID
+	for _, username := range audit.NewAssignees(interfaces.Audits{}) {
+		if err := checkAssignable(username); err != nil {
+			return interfaces.Audits{}, err
+		}
+	}
 
-	err := DBPool.QueryRow(context.Background(), query,
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if audit.TemplateID != 0 {
+		var auditType, auditArea string
+		err := tx.QueryRow(ctx, `SELECT COALESCE(audit_type, ''), COALESCE(audit_area, '') FROM audit_templates WHERE id = $1`,
+			audit.TemplateID).Scan(&auditType, &auditArea)
+		if err != nil {
+			return interfaces.Audits{}, fmt.Errorf("template %d no longer exists: %w", audit.TemplateID, err)
+		}
+		if audit.AuditType == "" {
+			audit.AuditType = auditType
+		}
+		if audit.AuditArea == "" {
+			audit.AuditArea = auditArea
+		}
+	}
+
+	query := `INSERT INTO audits (action, audit_id, audit_type, audit_area, notes, assigned_user, completed, user_id, username,
+              additional_users, firm, status, reviewer, template_id, schedule_id, scheduled_for)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+              RETURNING id, created_at, updated_at, version`
+
+	err = tx.QueryRow(ctx, query,
 		audit.Action, audit.AuditID, audit.AuditType, audit.AuditArea, audit.Notes, audit.AssignedUser, audit.Completed,
-		audit.UserID, audit.Username, audit.AdditionalUsers, audit.Firm).
-		Scan(&audit.ID, &audit.CreatedAt, &audit.UpdatedAt)
+		audit.UserID, audit.Username, audit.AdditionalUsers, audit.Firm, audit.Status, nullIfEmpty(audit.Reviewer),
+		nullIfZero(audit.TemplateID), nullIfZero(audit.ScheduleID), nullTime(audit.ScheduledFor)).
+		Scan(&audit.ID, &audit.CreatedAt, &audit.UpdatedAt, &audit.Version)
 
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return interfaces.Audits{}, fmt.Errorf("schedule %d, %s: %w", audit.ScheduleID, audit.ScheduledFor.Format("2006-01-02"),
+			interfaces.ErrAlreadyScheduled)
+	}
 	if err != nil {
 		return interfaces.Audits{}, err
 	}
+	if audit.TemplateID != 0 {
+		if err := instantiateChecklist(ctx, tx, audit.ID, audit.TemplateID); err != nil {
+			return interfaces.Audits{}, err
+		}
+	}
+
+	return audit, tx.Commit(ctx)
+}
+
+// Columns read by scanAudit
+const auditColumns = `id, action, audit_id, audit_type, audit_area, created_at, updated_at, notes, assigned_user, completed_at,
+              completed, user_id::text, username, additional_users, firm, COALESCE(status, ''), COALESCE(reviewer, ''),
+              COALESCE(template_id, 0), COALESCE(schedule_id, 0), scheduled_for, version`
 
+func scanAudit(row pgx.Row) (interfaces.Audits, error) {
+	var audit interfaces.Audits
+	var completedAt, scheduledFor *time.Time
+	err := row.Scan(&audit.ID, &audit.Action, &audit.AuditID, &audit.AuditType, &audit.AuditArea, &audit.CreatedAt,
+		&audit.UpdatedAt, &audit.Notes, &audit.AssignedUser, &completedAt, &audit.Completed, &audit.UserID,
+		&audit.Username, &audit.AdditionalUsers, &audit.Firm, &audit.Status, &audit.Reviewer, &audit.TemplateID,
+		&audit.ScheduleID, &scheduledFor, &audit.Version)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	if completedAt != nil {
+		audit.CompletedAt = *completedAt
+	}
+	if scheduledFor != nil {
+		audit.ScheduledFor = *scheduledFor
+	}
 	return audit, nil
 }
 
+func (dw *DatabaseWrapper) GetAudit(id int) (interfaces.Audits, error) {
+	query := `SELECT ` + auditColumns + `
+              FROM audits
+              WHERE id = $1`
+
+	return scanAudit(DBPool.QueryRow(context.Background(), query, id))
+}
+
 func (dw *DatabaseWrapper) GetAudits(username string) ([]interfaces.Audits, string, error) {
-	query := `SELECT id, action, audit_id, audit_type, audit_area, created_at, updated_at, notes, assigned_user, completed_at, completed, user_id, username, additional_users, firm
+	query := `SELECT ` + auditColumns + `
               FROM audits
               WHERE username = $1 OR $1 = ANY(additional_users)
               ORDER BY created_at DESC`
@@ -70,10 +182,7 @@ func (dw *DatabaseWrapper) GetAudits(username string) ([]interfaces.Audits, stri
 
 	var audits []interfaces.Audits
 	for rows.Next() {
-		var audit interfaces.Audits
-		err := rows.Scan(&audit.ID, &audit.Action, &audit.AuditID, &audit.AuditType, &audit.AuditArea, &audit.CreatedAt,
-			&audit.UpdatedAt, &audit.Notes, &audit.AssignedUser, &audit.CompletedAt, &audit.Completed, &audit.UserID,
-			&audit.Username, &audit.AdditionalUsers, &audit.Firm)
+		audit, err := scanAudit(rows)
 		if err != nil {
 			log.Printf("Error scanning audit: %v", err)
 			continue
@@ -92,16 +201,34 @@ func (dw *DatabaseWrapper) GetAudits(username string) ([]interfaces.Audits, stri
 	return audits, "Audits fetched successfully", nil
 }
 
+// UpdateAudit only succeeds when audit.Version still matches the stored row.
+// The status is left alone; it changes through ChangeAuditStatus.
 func (dw *DatabaseWrapper) UpdateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
+	if stored, err := dw.GetAudit(audit.ID); err == nil {
+		for _, username := range audit.NewAssignees(stored) {
+			if err := checkAssignable(username); err != nil {
+				return interfaces.Audits{}, err
+			}
+		}
+	}
+
 	query := `UPDATE audits SET action=$1, audit_id=$2, audit_type=$3, audit_area=$4, notes=$5, assigned_user=$6,
-              completed_at=$7, completed=$8, additional_users=$9, firm=$10, updated_at=$11
-              WHERE id=$12 RETURNING id, created_at, updated_at`
+              completed_at=$7, completed=$8, additional_users=$9, firm=$10, updated_at=$11, reviewer=$14, version=version+1
+              WHERE id=$12 AND version=$13 RETURNING id, created_at, updated_at, version`
 
 	err := DBPool.QueryRow(context.Background(), query,
 		audit.Action, audit.AuditID, audit.AuditType, audit.AuditArea, audit.Notes, audit.AssignedUser,
-		audit.CompletedAt, audit.Completed, audit.AdditionalUsers, audit.Firm, time.Now(), audit.ID).
-		Scan(&audit.ID, &audit.CreatedAt, &audit.UpdatedAt)
+		nullTime(audit.CompletedAt), audit.Completed, audit.AdditionalUsers, audit.Firm, time.Now(), audit.ID, audit.Version,
+		nullIfEmpty(audit.Reviewer)).
+		Scan(&audit.ID, &audit.CreatedAt, &audit.UpdatedAt, &audit.Version)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetAudit(audit.ID)
+		if getErr != nil {
+			return interfaces.Audits{}, fmt.Errorf("audit %d no longer exists: %w", audit.ID, getErr)
+		}
+		return interfaces.Audits{}, &interfaces.ConflictError{Entity: "audit", ID: audit.ID, Current: current}
+	}
 	if err != nil {
 		return interfaces.Audits{}, err
 	}
@@ -114,3 +241,63 @@ func (dw *DatabaseWrapper) DeleteAudit(id int, username string) error {
 	_, err := DBPool.Exec(context.Background(), query, id, username)
 	return err
 }
+
+// ChangeAuditStatus moves an audit to change.To and records the change,
+// provided version still matches the stored row. completed marks the audit
+// complete, or open again when false.
+func (dw *DatabaseWrapper) ChangeAuditStatus(change interfaces.AuditStatusChange, version int, completed bool) (interfaces.Audits, error) {
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if change.At.IsZero() {
+		change.At = time.Now()
+	}
+	audit, err := scanAudit(tx.QueryRow(ctx, `
+    UPDATE audits SET status = $2, completed = $3,
+        completed_at = CASE WHEN $3 THEN COALESCE(completed_at, $4) END,
+        updated_at = $4, version = version + 1
+    WHERE id = $1 AND version = $5
+    RETURNING `+auditColumns, change.AuditID, change.To, completed, change.At, version))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetAudit(change.AuditID)
+		if getErr != nil {
+			return interfaces.Audits{}, fmt.Errorf("audit %d no longer exists: %w", change.AuditID, getErr)
+		}
+		return interfaces.Audits{}, &interfaces.ConflictError{Entity: "audit", ID: change.AuditID, Current: current}
+	}
+	if err != nil {
+		return interfaces.Audits{}, fmt.Errorf("failed to change the status of audit %d: %v", change.AuditID, err)
+	}
+	_, err = tx.Exec(ctx, `
+    INSERT INTO audit_status_changes (audit_id, from_status, to_status, actor, comment, changed_at)
+    VALUES ($1, $2, $3, $4, $5, $6)`, change.AuditID, change.From, change.To, change.Actor, nullIfEmpty(change.Comment), change.At)
+	if err != nil {
+		return interfaces.Audits{}, fmt.Errorf("failed to record the status change of audit %d: %v", change.AuditID, err)
+	}
+	return audit, tx.Commit(ctx)
+}
+
+// GetAuditStatusChanges lists the status changes of an audit, oldest first
+func (dw *DatabaseWrapper) GetAuditStatusChanges(auditID int) ([]interfaces.AuditStatusChange, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT id, audit_id, from_status, to_status, actor, COALESCE(comment, ''), changed_at
+    FROM audit_status_changes WHERE audit_id = $1 ORDER BY changed_at, id`, auditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the status changes of audit %d: %v", auditID, err)
+	}
+	defer rows.Close()
+
+	var changes []interfaces.AuditStatusChange
+	for rows.Next() {
+		var change interfaces.AuditStatusChange
+		if err := rows.Scan(&change.ID, &change.AuditID, &change.From, &change.To, &change.Actor, &change.Comment, &change.At); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
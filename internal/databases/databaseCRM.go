@@ -3,10 +3,14 @@ package databases
 import (
 	// Standard Library
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
 )
@@ -20,11 +24,12 @@ func EnsureCRMTableExists() error {
         phone TEXT,
         company TEXT,
         notes TEXT[],
-        user_id INTEGER NOT NULL,
+        user_id UUID NOT NULL REFERENCES users (user_id),
         username TEXT,
         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
         updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-        open BOOLEAN DEFAULT TRUE
+        open BOOLEAN DEFAULT TRUE,
+        version INTEGER NOT NULL DEFAULT 1
     );`
 
 	_, err := DBPool.Exec(context.Background(), createTableSQL)
@@ -32,17 +37,22 @@ func EnsureCRMTableExists() error {
 		return fmt.Errorf("failed to create CRM table: %v", err)
 	}
 
-	return nil
+	return ensureColumn("crm", "version", "INTEGER NOT NULL DEFAULT 1")
 }
 
 func (dw *DatabaseWrapper) CreateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
+	userID, err := dw.ownerID(crm.Username)
+	if err != nil {
+		return interfaces.CRM{}, err
+	}
+	crm.UserID = userID
 	query := `INSERT INTO crm (name, email, phone, company, notes, user_id, username, open)
               VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-              RETURNING id, created_at, updated_at`
+              RETURNING id, created_at, updated_at, version`
 
-	err := DBPool.QueryRow(context.Background(), query,
+	err = DBPool.QueryRow(context.Background(), query,
 		crm.Name, crm.Email, crm.Phone, crm.Company, crm.Notes, crm.UserID, crm.Username, crm.Open).
-		Scan(&crm.ID, &crm.CreatedAt, &crm.UpdatedAt)
+		Scan(&crm.ID, &crm.CreatedAt, &crm.UpdatedAt, &crm.Version)
 
 	if err != nil {
 		return interfaces.CRM{}, err
@@ -51,8 +61,22 @@ func (dw *DatabaseWrapper) CreateCRMEntry(crm interfaces.CRM) (interfaces.CRM, e
 	return crm, nil
 }
 
+func (dw *DatabaseWrapper) GetCRMEntry(id int) (interfaces.CRM, error) {
+	var crm interfaces.CRM
+	query := `SELECT id, name, email, phone, company, notes, user_id::text, username, created_at, updated_at, open, version
+              FROM crm
+              WHERE id = $1`
+
+	err := DBPool.QueryRow(context.Background(), query, id).Scan(&crm.ID, &crm.Name, &crm.Email, &crm.Phone, &crm.Company,
+		&crm.Notes, &crm.UserID, &crm.Username, &crm.CreatedAt, &crm.UpdatedAt, &crm.Open, &crm.Version)
+	if err != nil {
+		return interfaces.CRM{}, err
+	}
+	return crm, nil
+}
+
 func (dw *DatabaseWrapper) GetCRMEntries(username string) ([]interfaces.CRM, string, error) {
-	query := `SELECT id, name, email, phone, company, notes, user_id, username, created_at, updated_at, open
+	query := `SELECT id, name, email, phone, company, notes, user_id::text, username, created_at, updated_at, open, version
               FROM crm
               WHERE username = $1 OR open = true
               ORDER BY updated_at DESC`
@@ -67,7 +91,7 @@ func (dw *DatabaseWrapper) GetCRMEntries(username string) ([]interfaces.CRM, str
 	for rows.Next() {
 		var crm interfaces.CRM
 		err := rows.Scan(&crm.ID, &crm.Name, &crm.Email, &crm.Phone, &crm.Company, &crm.Notes,
-			&crm.UserID, &crm.Username, &crm.CreatedAt, &crm.UpdatedAt, &crm.Open)
+			&crm.UserID, &crm.Username, &crm.CreatedAt, &crm.UpdatedAt, &crm.Open, &crm.Version)
 		if err != nil {
 			log.Printf("Error scanning CRM entry: %v", err)
 			continue
@@ -86,14 +110,22 @@ func (dw *DatabaseWrapper) GetCRMEntries(username string) ([]interfaces.CRM, str
 	return crmEntries, "CRM entries fetched successfully", nil
 }
 
+// UpdateCRMEntry only succeeds when crm.Version still matches the stored row
 func (dw *DatabaseWrapper) UpdateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
-	query := `UPDATE crm SET name=$1, email=$2, phone=$3, company=$4, notes=$5, open=$6, updated_at=$7
-              WHERE id=$8 RETURNING id, created_at, updated_at`
+	query := `UPDATE crm SET name=$1, email=$2, phone=$3, company=$4, notes=$5, open=$6, updated_at=$7, version=version+1
+              WHERE id=$8 AND version=$9 RETURNING id, created_at, updated_at, version`
 
 	err := DBPool.QueryRow(context.Background(), query,
-		crm.Name, crm.Email, crm.Phone, crm.Company, crm.Notes, crm.Open, time.Now(), crm.ID).
-		Scan(&crm.ID, &crm.CreatedAt, &crm.UpdatedAt)
+		crm.Name, crm.Email, crm.Phone, crm.Company, crm.Notes, crm.Open, time.Now(), crm.ID, crm.Version).
+		Scan(&crm.ID, &crm.CreatedAt, &crm.UpdatedAt, &crm.Version)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetCRMEntry(crm.ID)
+		if getErr != nil {
+			return interfaces.CRM{}, fmt.Errorf("CRM entry %d no longer exists: %w", crm.ID, getErr)
+		}
+		return interfaces.CRM{}, &interfaces.ConflictError{Entity: "CRM entry", ID: crm.ID, Current: current}
+	}
 	if err != nil {
 		return interfaces.CRM{}, err
 	}
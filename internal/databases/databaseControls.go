@@ -0,0 +1,244 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureControlsTablesExist creates the controls catalog and the tables
+// mapping audits and checklist items to it. Mappings go with either side.
+func EnsureControlsTablesExist() error {
+	_, err := DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS controls (
+        id SERIAL PRIMARY KEY,
+        framework TEXT NOT NULL,
+        control_id TEXT NOT NULL,
+        title TEXT NOT NULL,
+        description TEXT,
+        family TEXT,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (framework, control_id)
+    );
+    CREATE TABLE IF NOT EXISTS audit_controls (
+        audit_id INTEGER NOT NULL REFERENCES audits (id) ON DELETE CASCADE,
+        control_id INTEGER NOT NULL REFERENCES controls (id) ON DELETE CASCADE,
+        PRIMARY KEY (audit_id, control_id)
+    );
+    CREATE INDEX IF NOT EXISTS audit_controls_control_idx ON audit_controls (control_id);
+    CREATE TABLE IF NOT EXISTS checklist_item_controls (
+        item_id INTEGER NOT NULL REFERENCES audit_checklist_items (id) ON DELETE CASCADE,
+        control_id INTEGER NOT NULL REFERENCES controls (id) ON DELETE CASCADE,
+        PRIMARY KEY (item_id, control_id)
+    );
+    CREATE INDEX IF NOT EXISTS checklist_item_controls_control_idx ON checklist_item_controls (control_id);`)
+	if err != nil {
+		return fmt.Errorf("failed to create controls tables: %v", err)
+	}
+	return nil
+}
+
+// Columns read by scanControl, from controls aliased as c
+const controlColumns = `c.id, c.framework, c.control_id, c.title, COALESCE(c.description, ''), COALESCE(c.family, ''), c.updated_at`
+
+func scanControl(row pgx.Row) (interfaces.Control, error) {
+	var control interfaces.Control
+	err := row.Scan(&control.ID, &control.Framework, &control.ControlID, &control.Title, &control.Description,
+		&control.Family, &control.UpdatedAt)
+	return control, err
+}
+
+func scanControls(rows pgx.Rows) ([]interfaces.Control, error) {
+	defer rows.Close()
+	var controls []interfaces.Control
+	for rows.Next() {
+		control, err := scanControl(rows)
+		if err != nil {
+			return nil, err
+		}
+		controls = append(controls, control)
+	}
+	return controls, rows.Err()
+}
+
+// ImportControls adds the controls of framework, and updates the title,
+// description and family of those it already has. Controls missing from
+// the import are kept, with their mappings.
+func (dw *DatabaseWrapper) ImportControls(framework string, controls []interfaces.Control) (interfaces.ControlImport, error) {
+	var result interfaces.ControlImport
+	seen := make(map[string]bool, len(controls))
+	for _, control := range controls {
+		control.Framework = framework
+		if err := control.Validate(); err != nil {
+			return result, err
+		}
+		if seen[control.ControlID] {
+			return result, fmt.Errorf("%w: control %s appears twice", interfaces.ErrInvalidControl, control.ControlID)
+		}
+		seen[control.ControlID] = true
+	}
+
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, control := range controls {
+		// xmax is 0 for a row this statement inserted; no row at all means it was unchanged
+		var inserted bool
+		err := tx.QueryRow(ctx, `
+    INSERT INTO controls AS c (framework, control_id, title, description, family)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (framework, control_id) DO UPDATE
+        SET title = EXCLUDED.title, description = EXCLUDED.description, family = EXCLUDED.family,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE (c.title, c.description, c.family) IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.description, EXCLUDED.family)
+    RETURNING c.xmax = 0`,
+			framework, control.ControlID, control.Title, nullIfEmpty(control.Description), nullIfEmpty(control.Family)).
+			Scan(&inserted)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			result.Unchanged++
+		case err != nil:
+			return interfaces.ControlImport{}, fmt.Errorf("failed to import control %s of %s: %v", control.ControlID, framework, err)
+		case inserted:
+			result.Added++
+		default:
+			result.Updated++
+		}
+	}
+	return result, tx.Commit(ctx)
+}
+
+// GetControls lists the controls of framework, or of every framework when it
+// is empty, by framework and control ID
+func (dw *DatabaseWrapper) GetControls(framework string) ([]interfaces.Control, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+controlColumns+`
+    FROM controls c
+    WHERE $1 = '' OR c.framework = $1
+    ORDER BY c.framework, c.control_id`, framework)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controls: %v", err)
+	}
+	return scanControls(rows)
+}
+
+// DeleteFramework deletes the controls of framework and every mapping to them
+func (dw *DatabaseWrapper) DeleteFramework(framework string) error {
+	_, err := DBPool.Exec(context.Background(), `DELETE FROM controls WHERE framework = $1`, framework)
+	if err != nil {
+		return fmt.Errorf("failed to delete framework %s: %v", framework, err)
+	}
+	return nil
+}
+
+func (dw *DatabaseWrapper) GetAuditControls(auditID int) ([]interfaces.Control, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+controlColumns+`
+    FROM controls c JOIN audit_controls m ON m.control_id = c.id
+    WHERE m.audit_id = $1
+    ORDER BY c.framework, c.control_id`, auditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the controls of audit %d: %v", auditID, err)
+	}
+	return scanControls(rows)
+}
+
+// setControls replaces the controls mapped in table to the row id of column
+func setControls(table, column string, id int, controlIDs []int) error {
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, table, column), id); err != nil {
+		return err
+	}
+	if len(controlIDs) > 0 {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (%s, control_id) SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING`,
+			table, column), id, controlIDs)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// SetAuditControls replaces the controls an audit is mapped to
+func (dw *DatabaseWrapper) SetAuditControls(auditID int, controlIDs []int) error {
+	if err := setControls("audit_controls", "audit_id", auditID, controlIDs); err != nil {
+		return fmt.Errorf("failed to map audit %d to controls: %v", auditID, err)
+	}
+	return nil
+}
+
+// SetChecklistItemControls replaces the controls a checklist item is mapped to
+func (dw *DatabaseWrapper) SetChecklistItemControls(itemID int, controlIDs []int) error {
+	if err := setControls("checklist_item_controls", "item_id", itemID, controlIDs); err != nil {
+		return fmt.Errorf("failed to map checklist item %d to controls: %v", itemID, err)
+	}
+	return nil
+}
+
+// SetFindingControls replaces the controls a finding is mapped to
+func (dw *DatabaseWrapper) SetFindingControls(findingID int, controlIDs []int) error {
+	if err := setControls("finding_controls", "finding_id", findingID, controlIDs); err != nil {
+		return fmt.Errorf("failed to map finding %d to controls: %v", findingID, err)
+	}
+	return nil
+}
+
+// GetControlLinks lists every audit and checklist item mapped to the
+// controls of framework, or of every framework when it is empty. An audit
+// whose findings are mapped to a control is linked to it once, like a
+// mapped audit, and only those findings count against the control.
+func (dw *DatabaseWrapper) GetControlLinks(framework string) ([]interfaces.ControlLink, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    WITH links AS (
+        SELECT m.control_id, m.audit_id, 0 AS item_id, '' AS result
+        FROM audit_controls m
+        UNION
+        SELECT m.control_id, f.audit_id, 0, ''
+        FROM finding_controls m JOIN findings f ON f.id = m.finding_id
+        UNION
+        SELECT m.control_id, i.audit_id, i.id, i.result
+        FROM checklist_item_controls m JOIN audit_checklist_items i ON i.id = m.item_id
+    )
+    SELECT l.control_id, l.audit_id, COALESCE(a.firm, ''), COALESCE(a.completed, FALSE), l.item_id, l.result,
+        (SELECT COUNT(*) FROM findings f JOIN finding_controls m ON m.finding_id = f.id
+         WHERE f.audit_id = a.id AND m.control_id = l.control_id AND f.status <> ALL($2::text[]))
+    FROM links l
+    JOIN controls c ON c.id = l.control_id
+    JOIN audits a ON a.id = l.audit_id
+    WHERE $1 = '' OR c.framework = $1
+    ORDER BY l.control_id, l.audit_id, l.item_id`,
+		framework, []string{interfaces.FindingResolved, interfaces.FindingRiskAccepted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read control mappings: %v", err)
+	}
+	defer rows.Close()
+
+	var links []interfaces.ControlLink
+	for rows.Next() {
+		var link interfaces.ControlLink
+		if err := rows.Scan(&link.ControlID, &link.AuditID, &link.Firm, &link.AuditCompleted, &link.ItemID, &link.Result,
+			&link.OpenFindings); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
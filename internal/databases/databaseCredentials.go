@@ -9,19 +9,44 @@ import (
 	"log"
 	"time"
 
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
-	"github.com/jackc/pgx"
-	"github.com/lib/pq"
 )
 
+// Columns read by scanCredential. Master password sign-up rows leave most of
+// them empty.
+const credentialColumns = `id, COALESCE(site, ''), COALESCE(program, ''), username, user_id::text, COALESCE(email, ''), master_password,
+              COALESCE(login_name, ''), COALESCE(login_pass, ''), created_at, updated_at, COALESCE(owner, ''),
+              COALESCE(password_history, '[]'::jsonb)`
+
+func scanCredential(row pgx.Row) (interfaces.Credentials, error) {
+	var credential interfaces.Credentials
+	var passwordHistoryJSON []byte
+	err := row.Scan(&credential.ID, &credential.Site, &credential.Program, &credential.Username, &credential.UserID,
+		&credential.Email, &credential.MasterPassword, &credential.LoginName, &credential.LoginPass, &credential.CreatedAt,
+		&credential.UpdatedAt, &credential.Owner, &passwordHistoryJSON)
+	if err != nil {
+		return interfaces.Credentials{}, err
+	}
+
+	err = json.Unmarshal(passwordHistoryJSON, &credential.PasswordHistory)
+	if err != nil {
+		log.Printf("Error unmarshaling password history: %v", err)
+		credential.PasswordHistory = []string{}
+	}
+	return credential, nil
+}
+
 func EnsureCredentialsTableExists() error {
 	createTableSQL := `
     CREATE TABLE IF NOT EXISTS credentials (
         id SERIAL PRIMARY KEY,
         site TEXT,
         program TEXT,
-		user_id INTEGER NOT NULL,
+		user_id UUID NOT NULL REFERENCES users (user_id),
         username TEXT NOT NULL,
 		email TEXT,
         master_password TEXT NOT NULL,
@@ -58,6 +83,16 @@ func CreateCredential(credential interfaces.Credentials) (interfaces.Credentials
 }
 
 func (dw *DatabaseWrapper) CreateCredential(credential interfaces.Credentials) (interfaces.Credentials, error) {
+	owner := credential.Owner
+	if owner == "" {
+		owner = credential.Username
+	}
+	userID, err := dw.ownerID(owner)
+	if err != nil {
+		return interfaces.Credentials{}, err
+	}
+	credential.UserID = userID
+
 	passwordHistoryJSON, err := json.Marshal(credential.PasswordHistory)
 	if err != nil {
 		return interfaces.Credentials{}, fmt.Errorf("failed to marshal password history: %v", err)
@@ -80,7 +115,7 @@ func (dw *DatabaseWrapper) CreateCredential(credential interfaces.Credentials) (
 }
 
 func (dw *DatabaseWrapper) GetCredentials(owner string) ([]interfaces.Credentials, string, error) {
-	query := `SELECT id, site, program, username, user_id, email, master_password, login_name, login_pass, created_at, updated_at, owner, password_history
+	query := `SELECT ` + credentialColumns + `
               FROM credentials
               WHERE owner = $1
               ORDER BY created_at DESC`
@@ -93,22 +128,11 @@ func (dw *DatabaseWrapper) GetCredentials(owner string) ([]interfaces.Credential
 
 	var credentials []interfaces.Credentials
 	for rows.Next() {
-		var credential interfaces.Credentials
-		var passwordHistoryJSON []byte
-		err := rows.Scan(&credential.ID, &credential.Site, &credential.Program, &credential.Username, &credential.UserID, &credential.Email,
-			&credential.MasterPassword, &credential.LoginName, &credential.LoginPass, &credential.CreatedAt,
-			&credential.UpdatedAt, &credential.Owner, &passwordHistoryJSON)
+		credential, err := scanCredential(rows)
 		if err != nil {
 			log.Printf("Error scanning credential: %v", err)
 			continue
 		}
-
-		err = json.Unmarshal(passwordHistoryJSON, &credential.PasswordHistory)
-		if err != nil {
-			log.Printf("Error unmarshaling password history: %v", err)
-			credential.PasswordHistory = []string{}
-		}
-
 		credentials = append(credentials, credential)
 	}
 
@@ -125,19 +149,17 @@ func (dw *DatabaseWrapper) GetCredentials(owner string) ([]interfaces.Credential
 
 func (dw *DatabaseWrapper) GetCredentialByLoginName(loginName string) ([]interfaces.Credentials, error) {
 	var creds []interfaces.Credentials
-	query := `SELECT id, site, program, username,user_id, email, master_password, login_name, login_pass, created_at, updated_at, owner, password_history
+	query := `SELECT ` + credentialColumns + `
               FROM credentials
               WHERE login_name = $1
+              ORDER BY id
               LIMIT 1`
 
-	var cred interfaces.Credentials
-	err := DBPool.QueryRow(context.Background(), query, loginName).Scan(
-		&cred.ID, &cred.Site, &cred.Program, &cred.Username, &cred.UserID, &cred.Email, &cred.MasterPassword,
-		&cred.LoginName, &cred.LoginPass, &cred.CreatedAt, &cred.UpdatedAt, &cred.Owner, pq.Array(&cred.PasswordHistory))
+	cred, err := scanCredential(DBPool.QueryRow(context.Background(), query, loginName))
 	if err != nil {
-		creds = append(creds, cred)
 		return creds, fmt.Errorf("error getting credential: %w", err)
 	}
+	creds = append(creds, cred)
 
 	return creds, nil
 }
@@ -174,7 +196,7 @@ func (dw *DatabaseWrapper) DeleteCredential(id int, owner string) error {
 func (dw *DatabaseWrapper) SearchCredentials(searchTerm, owner string) ([]interfaces.Credentials, string, error) {
 	// Using a more lenient search to match any part of the login name or site
 	query := `
-	SELECT id, site, program, username, user_id, email, master_password, login_name, login_pass, created_at, updated_at, owner, password_history
+	SELECT ` + credentialColumns + `
 	FROM credentials
 	WHERE (login_name ILIKE $1 OR site ILIKE $1) AND owner = $2
 	ORDER BY created_at DESC
@@ -191,11 +213,7 @@ func (dw *DatabaseWrapper) SearchCredentials(searchTerm, owner string) ([]interf
 
 	var credentials []interfaces.Credentials
 	for rows.Next() {
-		var cred interfaces.Credentials
-		err := rows.Scan(
-			&cred.ID, &cred.Site, &cred.Program, &cred.Username, &cred.UserID, &cred.Email, &cred.MasterPassword, &cred.LoginName,
-			&cred.LoginPass, &cred.CreatedAt, &cred.UpdatedAt, &cred.Owner, pq.Array(&cred.PasswordHistory),
-		)
+		cred, err := scanCredential(rows)
 		if err != nil {
 			return nil, "", fmt.Errorf("error scanning row: %v", err)
 		}
@@ -212,18 +230,18 @@ func (dw *DatabaseWrapper) SearchCredentials(searchTerm, owner string) ([]interf
 
 	return credentials, "Credentials fetched successfully", nil
 }
+
 func (dw *DatabaseWrapper) CreateCredUser(username, hashedPassword, email string) (*interfaces.Credentials, error) {
 	// Check if the db pool is initialized
 	if dw.Pool == nil {
 		return nil, errors.New("database pool is not initialized")
 	}
 	// First, we need to get the user_id from the users table
-	println(username, email)
-	var userID int
-	userQuery := `SELECT users.user_id FROM users WHERE username = $1`
+	var userID string
+	userQuery := `SELECT users.user_id::text FROM users WHERE username = $1`
 	err := dw.Pool.QueryRow(context.Background(), userQuery, username).Scan(&userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("no user found with username: %s", username)
 		}
 		return nil, fmt.Errorf("error fetching user: %v", err)
@@ -248,7 +266,7 @@ func (dw *DatabaseWrapper) CreateCredUser(username, hashedPassword, email string
 
 func (dw *DatabaseWrapper) GetUserPassword(username string) (string, error) {
 	var hashedPassword string
-	query := `SELECT password FROM credentials WHERE username = $1`
+	query := `SELECT master_password FROM credentials WHERE username = $1 ORDER BY id LIMIT 1`
 	err := DBPool.QueryRow(context.Background(), query, username).Scan(&hashedPassword)
 	return hashedPassword, err
 }
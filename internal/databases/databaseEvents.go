@@ -0,0 +1,158 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// ChangeChannel is the Postgres NOTIFY channel used for shared table changes
+const ChangeChannel = "goaudit_changes"
+
+// Tables that broadcast their changes to every connected client
+var notifyTables = []string{"notes", "tasks", "audits", "crm"}
+
+var (
+	subscribers     = make(map[chan interfaces.ChangeEvent]struct{})
+	subscribersLock sync.Mutex
+)
+
+func EnsureChangeTriggersExist() error {
+	createFunctionSQL := `
+    CREATE OR REPLACE FUNCTION goaudit_notify_change() RETURNS trigger AS $$
+    DECLARE
+        rec RECORD;
+    BEGIN
+        IF TG_OP = 'DELETE' THEN
+            rec := OLD;
+        ELSE
+            rec := NEW;
+        END IF;
+        PERFORM pg_notify('` + ChangeChannel + `', json_build_object(
+            'table', TG_TABLE_NAME,
+            'operation', TG_OP,
+            'id', rec.id,
+            'username', COALESCE(rec.username, ''))::text);
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;`
+
+	_, err := DBPool.Exec(context.Background(), createFunctionSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create change notification function: %v", err)
+	}
+
+	for _, table := range notifyTables {
+		createTriggerSQL := fmt.Sprintf(`
+        DROP TRIGGER IF EXISTS goaudit_notify_change ON %[1]s;
+        CREATE TRIGGER goaudit_notify_change
+            AFTER INSERT OR UPDATE OR DELETE ON %[1]s
+            FOR EACH ROW EXECUTE PROCEDURE goaudit_notify_change();`, table)
+		_, err = DBPool.Exec(context.Background(), createTriggerSQL)
+		if err != nil {
+			return fmt.Errorf("failed to create change trigger on %s table: %v", table, err)
+		}
+	}
+
+	return nil
+}
+
+// StartChangeListener opens a connection of its own, outside the pool, that
+// LISTENs for change notifications and fans them out to subscribers until
+// ctx is cancelled. Lost connections are re-established after a short delay.
+func StartChangeListener(ctx context.Context) {
+	if DBPool == nil {
+		log.Printf("Change listener not started: database connection not initialized")
+		return
+	}
+	connConfig := DBPool.Config().ConnConfig
+	go func() {
+		for {
+			err := listenForChanges(ctx, connConfig)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Change listener stopped: %v, reconnecting", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+}
+
+func listenForChanges(ctx context.Context, connConfig *pgx.ConnConfig) error {
+	conn, err := pgx.ConnectConfig(ctx, connConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("failed to open listener connection: %v", err)
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+ChangeChannel)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", ChangeChannel, err)
+	}
+	log.Printf("Listening for changes on channel: %s", ChangeChannel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event interfaces.ChangeEvent
+		err = json.Unmarshal([]byte(notification.Payload), &event)
+		if err != nil {
+			log.Printf("Error decoding change notification: %v", err)
+			continue
+		}
+		PublishChange(event)
+	}
+}
+
+// PublishChange delivers an event to every subscriber without blocking. A
+// subscriber that is not keeping up misses the event.
+func PublishChange(event interfaces.ChangeEvent) {
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+
+	for ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Dropping change event for slow subscriber: %+v", event)
+		}
+	}
+}
+
+// SubscribeChanges returns a channel of change events and a function that
+// stops the subscription and closes the channel.
+func (dw *DatabaseWrapper) SubscribeChanges() (<-chan interfaces.ChangeEvent, func()) {
+	ch := make(chan interfaces.ChangeEvent, 64)
+
+	subscribersLock.Lock()
+	subscribers[ch] = struct{}{}
+	subscribersLock.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			subscribersLock.Lock()
+			delete(subscribers, ch)
+			subscribersLock.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
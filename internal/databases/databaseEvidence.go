@@ -0,0 +1,220 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"fmt"
+	"io"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureEvidenceTableExists creates the evidence table. Rows cannot be
+// updated, except for the finding link that goes when the finding is
+// deleted, and their large objects are unlinked with them.
+func EnsureEvidenceTableExists() error {
+	_, err := DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS evidence (
+        id SERIAL PRIMARY KEY,
+        audit_id INTEGER NOT NULL REFERENCES audits (id) ON DELETE CASCADE,
+        finding_id INTEGER REFERENCES findings (id) ON DELETE SET NULL,
+        name TEXT NOT NULL,
+        content_type TEXT,
+        size BIGINT NOT NULL,
+        sha256 TEXT NOT NULL,
+        storage TEXT NOT NULL,
+        content_oid OID,
+        comment TEXT,
+        uploader TEXT NOT NULL,
+        uploaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1,
+        replaces INTEGER UNIQUE REFERENCES evidence (id)
+    );
+    CREATE INDEX IF NOT EXISTS evidence_audit_idx ON evidence (audit_id);
+
+    CREATE OR REPLACE FUNCTION goaudit_evidence_immutable() RETURNS trigger AS $$
+    BEGIN
+        IF to_jsonb(NEW) - 'finding_id' IS DISTINCT FROM to_jsonb(OLD) - 'finding_id' THEN
+            RAISE EXCEPTION 'evidence % cannot be modified; upload a new version', OLD.id;
+        END IF;
+        RETURN NEW;
+    END;
+    $$ LANGUAGE plpgsql;
+
+    CREATE OR REPLACE FUNCTION goaudit_evidence_unlink() RETURNS trigger AS $$
+    BEGIN
+        IF OLD.content_oid IS NOT NULL THEN
+            PERFORM lo_unlink(OLD.content_oid);
+        END IF;
+        RETURN OLD;
+    END;
+    $$ LANGUAGE plpgsql;
+
+    DROP TRIGGER IF EXISTS evidence_immutable ON evidence;
+    CREATE TRIGGER evidence_immutable BEFORE UPDATE ON evidence
+        FOR EACH ROW EXECUTE FUNCTION goaudit_evidence_immutable();
+    DROP TRIGGER IF EXISTS evidence_unlink ON evidence;
+    CREATE TRIGGER evidence_unlink AFTER DELETE ON evidence
+        FOR EACH ROW EXECUTE FUNCTION goaudit_evidence_unlink();`)
+	if err != nil {
+		return fmt.Errorf("failed to create evidence table: %v", err)
+	}
+	return nil
+}
+
+// Columns read by scanEvidence, from evidence aliased as e
+const evidenceColumns = `e.id, e.audit_id, COALESCE(e.finding_id, 0), e.name, COALESCE(e.content_type, ''), e.size, e.sha256,
+              e.storage, COALESCE(e.comment, ''), e.uploader, e.uploaded_at, e.version, COALESCE(e.replaces, 0),
+              COALESCE((SELECT n.id FROM evidence n WHERE n.replaces = e.id), 0)`
+
+func scanEvidence(row pgx.Row) (interfaces.Evidence, error) {
+	var evidence interfaces.Evidence
+	err := row.Scan(&evidence.ID, &evidence.AuditID, &evidence.FindingID, &evidence.Name, &evidence.ContentType,
+		&evidence.Size, &evidence.SHA256, &evidence.Storage, &evidence.Comment, &evidence.Uploader, &evidence.UploadedAt,
+		&evidence.Version, &evidence.Replaces, &evidence.ReplacedBy)
+	return evidence, err
+}
+
+// AddEvidence records an upload. content, which must match the hash and
+// size given, is stored as a large object unless the evidence is kept in a
+// directory. A replacement must be of the latest version of evidence in the
+// same audit.
+func (dw *DatabaseWrapper) AddEvidence(evidence interfaces.Evidence, content []byte) (interfaces.Evidence, error) {
+	switch {
+	case evidence.Storage == interfaces.EvidenceInDatabase && !evidence.Matches(content):
+		return interfaces.Evidence{}, fmt.Errorf("uploading %s: %w", evidence.Name, interfaces.ErrEvidenceTampered)
+	case evidence.Storage != interfaces.EvidenceInDatabase && evidence.Storage != interfaces.EvidenceInDirectory:
+		return interfaces.Evidence{}, fmt.Errorf("unknown evidence storage %q", evidence.Storage)
+	}
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return interfaces.Evidence{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if evidence.FindingID != 0 {
+		var auditID int
+		err := tx.QueryRow(ctx, `SELECT audit_id FROM findings WHERE id = $1`, evidence.FindingID).Scan(&auditID)
+		if err != nil || auditID != evidence.AuditID {
+			return interfaces.Evidence{}, fmt.Errorf("finding %d is not part of audit %d", evidence.FindingID, evidence.AuditID)
+		}
+	}
+	evidence.Version = 1
+	if evidence.Replaces != 0 {
+		previous, err := scanEvidence(tx.QueryRow(ctx, `SELECT `+evidenceColumns+` FROM evidence e WHERE e.id = $1 FOR UPDATE`,
+			evidence.Replaces))
+		if err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("evidence %d no longer exists: %w", evidence.Replaces, err)
+		}
+		if previous.AuditID != evidence.AuditID {
+			return interfaces.Evidence{}, fmt.Errorf("evidence %d is not part of audit %d", previous.ID, evidence.AuditID)
+		}
+		if previous.ReplacedBy != 0 {
+			current, err := dw.GetEvidence(previous.ReplacedBy)
+			if err != nil {
+				return interfaces.Evidence{}, err
+			}
+			return interfaces.Evidence{}, &interfaces.ConflictError{Entity: "evidence", ID: previous.ID, Current: current}
+		}
+		evidence.Version = previous.Version + 1
+	}
+
+	var oid *uint32
+	if evidence.Storage == interfaces.EvidenceInDatabase {
+		objects := tx.LargeObjects()
+		created, err := objects.Create(ctx, 0)
+		if err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("failed to store %s: %v", evidence.Name, err)
+		}
+		object, err := objects.Open(ctx, created, pgx.LargeObjectModeWrite)
+		if err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("failed to store %s: %v", evidence.Name, err)
+		}
+		if _, err := object.Write(content); err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("failed to store %s: %v", evidence.Name, err)
+		}
+		if err := object.Close(); err != nil {
+			return interfaces.Evidence{}, fmt.Errorf("failed to store %s: %v", evidence.Name, err)
+		}
+		oid = &created
+	}
+
+	err = tx.QueryRow(ctx, `
+    INSERT INTO evidence (audit_id, finding_id, name, content_type, size, sha256, storage, content_oid, comment,
+        uploader, uploaded_at, version, replaces)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, CURRENT_TIMESTAMP), $12, $13)
+    RETURNING id, uploaded_at`,
+		evidence.AuditID, nullIfZero(evidence.FindingID), evidence.Name, nullIfEmpty(evidence.ContentType), evidence.Size,
+		evidence.SHA256, evidence.Storage, oid, nullIfEmpty(evidence.Comment), evidence.Uploader, nullTime(evidence.UploadedAt),
+		evidence.Version, nullIfZero(evidence.Replaces)).
+		Scan(&evidence.ID, &evidence.UploadedAt)
+	if err != nil {
+		return interfaces.Evidence{}, fmt.Errorf("failed to record evidence %s for audit %d: %v", evidence.Name, evidence.AuditID, err)
+	}
+	evidence.ReplacedBy = 0
+	return evidence, tx.Commit(ctx)
+}
+
+func (dw *DatabaseWrapper) GetEvidence(id int) (interfaces.Evidence, error) {
+	return scanEvidence(DBPool.QueryRow(context.Background(), `SELECT `+evidenceColumns+` FROM evidence e WHERE e.id = $1`, id))
+}
+
+// ListEvidence lists every version of the evidence of an audit, oldest first
+func (dw *DatabaseWrapper) ListEvidence(auditID int) ([]interfaces.Evidence, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+evidenceColumns+`
+    FROM evidence e
+    WHERE e.audit_id = $1
+    ORDER BY e.uploaded_at, e.id`, auditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the evidence of audit %d: %v", auditID, err)
+	}
+	defer rows.Close()
+
+	var evidence []interfaces.Evidence
+	for rows.Next() {
+		item, err := scanEvidence(rows)
+		if err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, item)
+	}
+	return evidence, rows.Err()
+}
+
+// ReadEvidence returns the content of evidence stored in the database
+func (dw *DatabaseWrapper) ReadEvidence(id int) ([]byte, error) {
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var oid *uint32
+	err = tx.QueryRow(ctx, `SELECT content_oid FROM evidence WHERE id = $1`, id).Scan(&oid)
+	if err != nil {
+		return nil, fmt.Errorf("evidence %d: %w", id, err)
+	}
+	if oid == nil {
+		return nil, fmt.Errorf("evidence %d is not stored in the database", id)
+	}
+	objects := tx.LargeObjects()
+	object, err := objects.Open(ctx, *oid, pgx.LargeObjectModeRead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open evidence %d: %v", id, err)
+	}
+	content, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read evidence %d: %v", id, err)
+	}
+	if err := object.Close(); err != nil {
+		return nil, err
+	}
+	return content, tx.Commit(ctx)
+}
@@ -0,0 +1,166 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureFindingsTableExists creates the findings table and the table mapping
+// findings to controls. Findings go with their audit; remediation tasks
+// reference them from the tasks table.
+func EnsureFindingsTableExists() error {
+	_, err := DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS findings (
+        id SERIAL PRIMARY KEY,
+        audit_id INTEGER NOT NULL REFERENCES audits (id) ON DELETE CASCADE,
+        title TEXT NOT NULL,
+        description TEXT,
+        severity TEXT NOT NULL,
+        area TEXT,
+        recommendation TEXT,
+        owner TEXT,
+        due_date TIMESTAMP WITH TIME ZONE,
+        status TEXT NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1
+    );
+    CREATE INDEX IF NOT EXISTS findings_audit_idx ON findings (audit_id);
+    CREATE INDEX IF NOT EXISTS findings_owner_idx ON findings (owner);
+    CREATE TABLE IF NOT EXISTS finding_controls (
+        finding_id INTEGER NOT NULL REFERENCES findings (id) ON DELETE CASCADE,
+        control_id INTEGER NOT NULL REFERENCES controls (id) ON DELETE CASCADE,
+        PRIMARY KEY (finding_id, control_id)
+    );
+    CREATE INDEX IF NOT EXISTS finding_controls_control_idx ON finding_controls (control_id);`)
+	if err != nil {
+		return fmt.Errorf("failed to create findings table: %v", err)
+	}
+	return nil
+}
+
+// Columns read by scanFinding, from findings aliased as f
+const findingColumns = `f.id, f.audit_id, f.title, COALESCE(f.description, ''), f.severity, COALESCE(f.area, ''),
+              COALESCE(f.recommendation, ''), COALESCE(f.owner, ''), f.due_date, f.status,
+              ARRAY(SELECT t.id FROM tasks t WHERE t.finding_id = f.id ORDER BY t.id),
+              ARRAY(SELECT m.control_id FROM finding_controls m WHERE m.finding_id = f.id ORDER BY m.control_id),
+              f.created_at, f.updated_at, f.version`
+
+func scanFinding(row pgx.Row) (interfaces.Finding, error) {
+	var finding interfaces.Finding
+	var dueDate *time.Time
+	err := row.Scan(&finding.ID, &finding.AuditID, &finding.Title, &finding.Description, &finding.Severity, &finding.Area,
+		&finding.Recommendation, &finding.Owner, &dueDate, &finding.Status, &finding.Tasks, &finding.Controls,
+		&finding.CreatedAt, &finding.UpdatedAt, &finding.Version)
+	if err != nil {
+		return interfaces.Finding{}, err
+	}
+	if dueDate != nil {
+		finding.DueDate = *dueDate
+	}
+	if len(finding.Tasks) == 0 {
+		finding.Tasks = nil
+	}
+	if len(finding.Controls) == 0 {
+		finding.Controls = nil
+	}
+	return finding, nil
+}
+
+func (dw *DatabaseWrapper) GetFinding(id int) (interfaces.Finding, error) {
+	return scanFinding(DBPool.QueryRow(context.Background(), `SELECT `+findingColumns+` FROM findings f WHERE f.id = $1`, id))
+}
+
+// GetFindings lists the findings of an audit, most severe first, then by due date
+func (dw *DatabaseWrapper) GetFindings(auditID int) ([]interfaces.Finding, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+findingColumns+`
+    FROM findings f
+    WHERE f.audit_id = $1
+    ORDER BY array_position($2::text[], f.severity), f.due_date NULLS LAST, f.id`, auditID, interfaces.Severities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the findings of audit %d: %v", auditID, err)
+	}
+	defer rows.Close()
+
+	var findings []interfaces.Finding
+	for rows.Next() {
+		finding, err := scanFinding(rows)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, finding)
+	}
+	return findings, rows.Err()
+}
+
+func (dw *DatabaseWrapper) CreateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	if err := finding.Validate(); err != nil {
+		return interfaces.Finding{}, err
+	}
+	if err := checkAssignable(finding.Owner); err != nil {
+		return interfaces.Finding{}, err
+	}
+	created, err := scanFinding(DBPool.QueryRow(context.Background(), `
+    INSERT INTO findings AS f (audit_id, title, description, severity, area, recommendation, owner, due_date, status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING `+findingColumns,
+		finding.AuditID, finding.Title, nullIfEmpty(finding.Description), finding.Severity, nullIfEmpty(finding.Area),
+		nullIfEmpty(finding.Recommendation), nullIfEmpty(finding.Owner), nullTime(finding.DueDate), finding.Status))
+	if err != nil {
+		return interfaces.Finding{}, fmt.Errorf("failed to add a finding to audit %d: %v", finding.AuditID, err)
+	}
+	return created, nil
+}
+
+// UpdateFinding only succeeds when finding.Version still matches the stored row
+func (dw *DatabaseWrapper) UpdateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	if err := finding.Validate(); err != nil {
+		return interfaces.Finding{}, err
+	}
+	var owner string
+	err := DBPool.QueryRow(context.Background(), `SELECT COALESCE(owner, '') FROM findings WHERE id = $1`, finding.ID).Scan(&owner)
+	if err == nil && owner != finding.Owner {
+		if err := checkAssignable(finding.Owner); err != nil {
+			return interfaces.Finding{}, err
+		}
+	}
+
+	updated, err := scanFinding(DBPool.QueryRow(context.Background(), `
+    UPDATE findings f SET title = $3, description = $4, severity = $5, area = $6, recommendation = $7, owner = $8,
+        due_date = $9, status = $10, updated_at = CURRENT_TIMESTAMP, version = version + 1
+    WHERE f.id = $1 AND f.version = $2
+    RETURNING `+findingColumns,
+		finding.ID, finding.Version, finding.Title, nullIfEmpty(finding.Description), finding.Severity,
+		nullIfEmpty(finding.Area), nullIfEmpty(finding.Recommendation), nullIfEmpty(finding.Owner),
+		nullTime(finding.DueDate), finding.Status))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetFinding(finding.ID)
+		if getErr != nil {
+			return interfaces.Finding{}, fmt.Errorf("finding %d no longer exists: %w", finding.ID, getErr)
+		}
+		return interfaces.Finding{}, &interfaces.ConflictError{Entity: "finding", ID: finding.ID, Current: current}
+	}
+	if err != nil {
+		return interfaces.Finding{}, fmt.Errorf("failed to update finding %d: %v", finding.ID, err)
+	}
+	return updated, nil
+}
+
+// DeleteFinding deletes a finding. Its remediation tasks stay, unlinked.
+func (dw *DatabaseWrapper) DeleteFinding(id int) error {
+	_, err := DBPool.Exec(context.Background(), `DELETE FROM findings WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete finding %d: %v", id, err)
+	}
+	return nil
+}
@@ -5,9 +5,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	// External Imports
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	// Internal Imports
@@ -16,6 +17,11 @@ import (
 
 var DBPool *pgxpool.Pool
 
+// How long Connect waits for the server before giving up
+const connectTimeout = 10 * time.Second
+
+var _ interfaces.DatabaseOperations = (*DatabaseWrapper)(nil)
+
 type LDAPWrapper struct{}
 
 type DatabaseWrapper struct {
@@ -33,46 +39,109 @@ func NewAuth(db interfaces.DatabaseOperations, ldap interfaces.LDAPOperations) *
 	}
 }
 
-type SQLSettings struct {
-	Server   string
-	User     string
-	Password string
-	Database string
-	Port     string
-}
-
-func LoadSQLSettings() SQLSettings {
-	return SQLSettings{
-		Server:   os.Getenv("SQL_SERVER"),
-		User:     os.Getenv("SQL_USER"),
-		Password: os.Getenv("SQL_PASSWORD"),
-		Database: os.Getenv("SQL_DATABASE"),
-		Port:     os.Getenv("SQL_PORT"),
+// InitDB connects using the configured SQL settings
+func InitDB() (*DatabaseWrapper, error) {
+	settings, err := LoadSQLSettings()
+	if err != nil {
+		return nil, err
 	}
+	return Connect(settings)
 }
 
-func InitDB() (*DatabaseWrapper, error) {
-	SQLSettings := LoadSQLSettings()
-
-	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-		SQLSettings.User, SQLSettings.Password, SQLSettings.Server, SQLSettings.Port, SQLSettings.Database)
+// Connect opens the shared pool and checks the server answers
+func Connect(settings SQLSettings) (*DatabaseWrapper, error) {
+	config, err := settings.PoolConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	var err error
-	DBPool, err = pgxpool.New(context.Background(), connString)
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	err = DBPool.Ping(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	err = pool.Ping(ctx)
 	if err != nil {
-		DBPool.Close()
-		return nil, fmt.Errorf("failed to ping database: %v", err)
+		pool.Close()
+		return nil, fmt.Errorf("failed to reach database %s on %s: %v", config.ConnConfig.Database, config.ConnConfig.Host, err)
 	}
 	log.Println("Successfully connected to the database.")
 
-	dbWrapper := &DatabaseWrapper{Pool: DBPool}
+	DBPool = pool
+	return &DatabaseWrapper{Pool: DBPool}, nil
+}
+
+// CheckConnection opens a single connection with settings and pings it,
+// leaving the shared pool alone
+func CheckConnection(settings SQLSettings) error {
+	config, err := settings.PoolConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	conn, err := pgx.ConnectConfig(ctx, config.ConnConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %v", err)
+	}
+	defer conn.Close(context.Background())
+
+	err = conn.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+	return nil
+}
 
-	// Now you can use auth for both database and LDAP operations
+// EnsureSchema creates every table and trigger the application needs
+func EnsureSchema() error {
+	ensureFuncs := []struct {
+		name   string
+		ensure func() error
+	}{
+		// Every other table references users
+		{"user table", EnsureUserTableExists},
+		{"audit table", EnsureAuditTableExists},
+		{"audit templates", EnsureTemplatesTablesExist},
+		{"controls", EnsureControlsTablesExist},
+		{"audit schedules", EnsureSchedulesTableExists},
+		{"findings table", EnsureFindingsTableExists},
+		{"evidence table", EnsureEvidenceTableExists},
+		{"credentials table", EnsureCredentialsTableExists},
+		{"CRM table", EnsureCRMTableExists},
+		{"notes table", EnsureNotesTableExists},
+		{"task table", EnsureTaskTableExists},
+		{"security tables", EnsureSecurityTablesExist},
+		{"migrations", RunMigrations},
+		{"change triggers", EnsureChangeTriggersExist},
+	}
 
-	return dbWrapper, nil
+	for _, item := range ensureFuncs {
+		if err := item.ensure(); err != nil {
+			log.Printf("Error ensuring %s exists: %v", item.name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ensureColumn adds a column to an existing table when it is missing
+func ensureColumn(table, column, definition string) error {
+	alterTableSQL := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s`, table, column, definition)
+	_, err := DBPool.Exec(context.Background(), alterTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to add '%s' column to %s table: %v", column, table, err)
+	}
+	return nil
 }
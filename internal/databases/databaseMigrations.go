@@ -0,0 +1,265 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"fmt"
+	"log"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Postgres error codes
+const (
+	// A row still referenced by a foreign key
+	foreignKeyViolation = "23503"
+	// A duplicate value in a unique column
+	uniqueViolation = "23505"
+)
+
+// Held while migrating so two clients starting together do not both migrate
+const migrationLockID = 7_202_411
+
+// migration is one schema change. Each runs once, in its own transaction,
+// and is recorded in schema_migrations.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx pgx.Tx) error
+}
+
+// Migrations in the order they apply. Never renumber or remove one that has
+// shipped; add a new one instead.
+var migrations = []migration{
+	{1, "stable user ids and owner foreign keys", migrateStableUserIDs},
+}
+
+// ownedTables reference users.user_id. owner names the user, for re-linking
+// rows whose old user_id cannot be trusted; the table is aliased t.
+var ownedTables = []struct {
+	table string
+	owner string
+}{
+	{"notes", "t.username"},
+	{"tasks", "t.username"},
+	{"audits", "t.username"},
+	{"crm", "t.username"},
+	{"credentials", "COALESCE(NULLIF(t.owner, ''), t.username)"},
+}
+
+// Legacy rows whose owner cannot be found are given to this user
+const unownedUsername = "~unowned"
+
+// RunMigrations applies every migration the database has not seen yet
+func RunMigrations() error {
+	ctx := context.Background()
+	_, err := DBPool.Exec(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    );`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	for _, m := range migrations {
+		if err := applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, m migration) error {
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	var applied bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+
+	if err := m.up(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+		return err
+	}
+	log.Printf("Applied database migration %d: %s", m.version, m.name)
+	return tx.Commit(ctx)
+}
+
+// migrateStableUserIDs replaces the integer user_id, which was random and
+// could collide, with a UUID: the directory ID for synced users, UUIDv7 for
+// the rest. Rows in the owned tables are re-linked by owner name, or through
+// the old ID where they have no owner name.
+func migrateStableUserIDs(ctx context.Context, tx pgx.Tx) error {
+	var dataType string
+	err := tx.QueryRow(ctx, `SELECT data_type FROM information_schema.columns
+              WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'user_id'`).Scan(&dataType)
+	if err != nil {
+		return fmt.Errorf("failed to read the users.user_id type: %v", err)
+	}
+	if dataType != "uuid" {
+		if err := relinkUsers(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	for _, owned := range ownedTables {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_user_id_idx ON %[1]s (user_id)`, owned.table))
+		if err != nil {
+			return fmt.Errorf("failed to index %s by user: %v", owned.table, err)
+		}
+	}
+	return nil
+}
+
+func relinkUsers(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `
+        ALTER TABLE users DROP CONSTRAINT IF EXISTS users_user_id_key;
+        ALTER TABLE users RENAME COLUMN user_id TO legacy_user_id;
+        ALTER TABLE users ALTER COLUMN legacy_user_id DROP NOT NULL;
+        ALTER TABLE users ADD COLUMN user_id UUID;
+        ALTER TABLE users ADD CONSTRAINT users_user_id_key UNIQUE (user_id);`)
+	if err != nil {
+		return fmt.Errorf("failed to add the new users.user_id: %v", err)
+	}
+	if err := assignUserIDs(ctx, tx); err != nil {
+		return err
+	}
+
+	for _, owned := range ownedTables {
+		if err := relinkTable(ctx, tx, owned.table, owned.owner); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+        ALTER TABLE users DROP COLUMN legacy_user_id;
+        ALTER TABLE users ALTER COLUMN user_id SET NOT NULL;`)
+	if err != nil {
+		return fmt.Errorf("failed to drop the old users.user_id: %v", err)
+	}
+	return nil
+}
+
+// assignUserIDs gives every user row a UUID, including rows added for
+// owners that had gone missing
+func assignUserIDs(ctx context.Context, tx pgx.Tx) error {
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(directory_id, '') FROM users WHERE user_id IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to read users: %v", err)
+	}
+	type pending struct {
+		id          int
+		directoryID string
+	}
+	var users []pending
+	for rows.Next() {
+		var user pending
+		if err := rows.Scan(&user.id, &user.directoryID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read users: %v", err)
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read users: %v", err)
+	}
+
+	for _, user := range users {
+		userID := interfaces.NewUserID()
+		if user.directoryID != "" {
+			userID = interfaces.DirectoryUserID(user.directoryID)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET user_id = $1 WHERE id = $2`, userID, user.id); err != nil {
+			return fmt.Errorf("failed to assign a user_id to user %d: %v", user.id, err)
+		}
+	}
+	return nil
+}
+
+// relinkTable points table's rows at the new user IDs and adds the foreign
+// key. The old IDs changed whenever a user was re-created and could collide,
+// so a row's owner name is trusted over its old ID.
+func relinkTable(ctx context.Context, tx pgx.Tx, table, owner string) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+        ALTER TABLE %[1]s RENAME COLUMN user_id TO legacy_user_id;
+        ALTER TABLE %[1]s ALTER COLUMN legacy_user_id DROP NOT NULL;
+        ALTER TABLE %[1]s ADD COLUMN user_id UUID;`, table))
+	if err != nil {
+		return fmt.Errorf("failed to add the new %s.user_id: %v", table, err)
+	}
+
+	// Owners that were deleted or never stored get a user row again
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+        INSERT INTO users (username, status)
+        SELECT DISTINCT ON (lower(%[2]s)) %[2]s, $1::text FROM %[1]s t
+        WHERE COALESCE(%[2]s, '') <> ''
+          AND NOT EXISTS (SELECT 1 FROM users u WHERE lower(u.username) = lower(%[2]s))
+        ON CONFLICT (username) DO NOTHING`, table, owner), interfaces.UserActive)
+	if err != nil {
+		return fmt.Errorf("failed to restore the missing owners of %s: %v", table, err)
+	}
+	if err := assignUserIDs(ctx, tx); err != nil {
+		return err
+	}
+
+	links := []struct {
+		by    string
+		query string
+	}{
+		{"owner", `UPDATE %[1]s t SET user_id = u.user_id FROM users u
+              WHERE t.user_id IS NULL AND lower(u.username) = lower(%[2]s)`},
+		{"old user_id", `UPDATE %[1]s t SET user_id = u.user_id FROM users u
+              WHERE t.user_id IS NULL AND t.legacy_user_id = u.legacy_user_id`},
+	}
+	for _, link := range links {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(link.query, table, owner)); err != nil {
+			return fmt.Errorf("failed to re-link %s by %s: %v", table, link.by, err)
+		}
+	}
+
+	var unowned int
+	err = tx.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE user_id IS NULL`, table)).Scan(&unowned)
+	if err != nil {
+		return fmt.Errorf("failed to count the unowned rows of %s: %v", table, err)
+	}
+	if unowned > 0 {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+            WITH unowned AS (
+                INSERT INTO users (username, user_id, status) VALUES ($1, $2, $3)
+                ON CONFLICT (username) DO UPDATE SET username = EXCLUDED.username
+                RETURNING user_id
+            )
+            UPDATE %s SET user_id = (SELECT user_id FROM unowned) WHERE user_id IS NULL`, table),
+			unownedUsername, interfaces.NewUserID(), interfaces.UserInactive)
+		if err != nil {
+			return fmt.Errorf("failed to keep the unowned rows of %s: %v", table, err)
+		}
+		log.Printf("%d rows in %s had no owner and now belong to %s", unowned, table, unownedUsername)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+        ALTER TABLE %[1]s DROP COLUMN legacy_user_id;
+        ALTER TABLE %[1]s ALTER COLUMN user_id SET NOT NULL;
+        ALTER TABLE %[1]s ADD CONSTRAINT %[1]s_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id);`, table))
+	if err != nil {
+		return fmt.Errorf("failed to add the %s foreign key: %v", table, err)
+	}
+	return nil
+}
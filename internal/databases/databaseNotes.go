@@ -3,10 +3,14 @@ package databases
 import (
 	// Standard Library
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
 )
@@ -20,11 +24,12 @@ func EnsureNotesTableExists() error {
         content TEXT,
         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
         updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-        user_id INTEGER NOT NULL,
+        user_id UUID NOT NULL REFERENCES users (user_id),
         username TEXT,
         updated_by TEXT,
         author TEXT,
-        open BOOLEAN DEFAULT FALSE
+        open BOOLEAN DEFAULT FALSE,
+        version INTEGER NOT NULL DEFAULT 1
     );`
 
 	_, err := DBPool.Exec(context.Background(), createTableSQL)
@@ -61,7 +66,7 @@ func EnsureNotesTableExists() error {
 		return fmt.Errorf("failed to add 'author' column to notes table: %v", err)
 	}
 
-	return nil
+	return ensureColumn("notes", "version", "INTEGER NOT NULL DEFAULT 1")
 }
 
 func (dw *DatabaseWrapper) CreateNote(title, content string, username string, open bool) (interfaces.Note, error) {
@@ -78,9 +83,10 @@ func (dw *DatabaseWrapper) CreateNote(title, content string, username string, op
 		Open:     open,
 		Author:   User.Username,
 	}
-	query := `INSERT INTO notes (title, content, user_id, open) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
-	err = DBPool.QueryRow(context.Background(), query, note.Title, note.Content, User.UserID, note.Open).
-		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+	query := `INSERT INTO notes (title, content, user_id, username, author, open) VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id, created_at, updated_at, version`
+	err = DBPool.QueryRow(context.Background(), query, note.Title, note.Content, User.UserID, note.Username, note.Author, note.Open).
+		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version)
 	if err != nil {
 		return interfaces.Note{}, err
 	}
@@ -93,14 +99,15 @@ func (dw *DatabaseWrapper) GetNotes(username string) ([]interfaces.Note, string,
 	}
 
 	query := `
-    SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at, notes.user_id, users.username, notes.open, notes.author
+    SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at, notes.user_id::text, users.username, notes.open,
+           COALESCE(notes.author, users.username), notes.version
     FROM notes
     JOIN users ON notes.user_id = users.user_id
-    WHERE notes.user_id = (SELECT users.user_id FROM users WHERE users.username ILIKE $1) OR notes.open = true
+    WHERE notes.user_id = (SELECT users.user_id FROM users WHERE lower(users.username) = lower($1)) OR notes.open = true
     ORDER BY notes.created_at DESC
     `
 
-	rows, err := DBPool.Query(context.Background(), query, "%"+username+"%")
+	rows, err := DBPool.Query(context.Background(), query, username)
 	if err != nil {
 		log.Printf("Error querying notes: %v", err)
 		return nil, fmt.Sprintf("Error querying notes: %v", err), err
@@ -111,7 +118,7 @@ func (dw *DatabaseWrapper) GetNotes(username string) ([]interfaces.Note, string,
 	for rows.Next() {
 		var note interfaces.Note
 		err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt,
-			&note.UserID, &note.Username, &note.Open, &note.Author)
+			&note.UserID, &note.Username, &note.Open, &note.Author, &note.Version)
 		if err != nil {
 			log.Printf("Error scanning note: %v", err)
 			continue
@@ -134,12 +141,12 @@ func (dw *DatabaseWrapper) GetNotes(username string) ([]interfaces.Note, string,
 
 func (dw *DatabaseWrapper) GetNote(id int) (interfaces.Note, error) {
 	var note interfaces.Note
-	query := `SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at, 
-              notes.user_id, users.username, users.email
+	query := `SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at,
+              notes.user_id::text, users.username, notes.open, COALESCE(notes.author, users.username), notes.version
               FROM notes JOIN users ON notes.user_id = users.user_id WHERE notes.id = $1`
 	err := DBPool.QueryRow(context.Background(), query, id).Scan(
 		&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt,
-		&note.UserID, &note.Username, &note.Username)
+		&note.UserID, &note.Username, &note.Open, &note.Author, &note.Version)
 	if err != nil {
 		log.Printf("Error getting note. %s", err)
 		return interfaces.Note{}, err
@@ -147,10 +154,20 @@ func (dw *DatabaseWrapper) GetNote(id int) (interfaces.Note, error) {
 	return note, nil
 }
 
+// UpdateNote only succeeds when note.Version still matches the stored row
 func (dw *DatabaseWrapper) UpdateNote(note interfaces.Note) (interfaces.Note, error) {
-	query := `UPDATE notes SET title=$1, content=$2, updated_at=$3, open=$4 WHERE id=$5 RETURNING id, created_at, updated_at`
-	err := DBPool.QueryRow(context.Background(), query, note.Title, note.Content, time.Now(), note.Open, note.ID).
-		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+	query := `UPDATE notes SET title=$1, content=$2, updated_at=$3, open=$4, version=version+1
+              WHERE id=$5 AND version=$6 RETURNING id, created_at, updated_at, version`
+	err := DBPool.QueryRow(context.Background(), query, note.Title, note.Content, time.Now(), note.Open, note.ID, note.Version).
+		Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt, &note.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetNote(note.ID)
+		if getErr != nil {
+			return interfaces.Note{}, fmt.Errorf("note %d no longer exists: %w", note.ID, getErr)
+		}
+		log.Printf("Conflict updating note %d: have version %d, server has %d", note.ID, note.Version, current.Version)
+		return interfaces.Note{}, &interfaces.ConflictError{Entity: "note", ID: note.ID, Current: current}
+	}
 	if err != nil {
 		log.Printf("Error updating note. %s", err)
 		return interfaces.Note{}, err
@@ -172,17 +189,18 @@ func (dw *DatabaseWrapper) DeleteNote(id int) error {
 func (dw *DatabaseWrapper) SearchNotes(searchTerm string, username string) ([]interfaces.Note, string, error) {
 	var notes []interfaces.Note
 	query := `
-    SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at, notes.user_id, users.username, notes.open, notes.author
+    SELECT notes.id, notes.title, notes.content, notes.created_at, notes.updated_at, notes.user_id::text, users.username, notes.open,
+           COALESCE(notes.author, users.username), notes.version
     FROM notes
     JOIN users ON notes.user_id = users.user_id
     WHERE (title ILIKE $1 OR content ILIKE $1)
-    AND (notes.user_id = (SELECT users.user_id FROM users WHERE users.username ILIKE $2) OR notes.open = true)
+    AND (notes.user_id = (SELECT users.user_id FROM users WHERE lower(users.username) = lower($2)) OR notes.open = true)
     ORDER BY notes.created_at DESC
     `
 	log.Printf("Executing SQL search query: %s\nWith parameters: searchTerm='%s', username='%s'",
-		query, "%"+searchTerm+"%", "%"+username+"%")
+		query, "%"+searchTerm+"%", username)
 
-	rows, err := DBPool.Query(context.Background(), query, "%"+searchTerm+"%", "%"+username+"%")
+	rows, err := DBPool.Query(context.Background(), query, "%"+searchTerm+"%", username)
 	if err != nil {
 		log.Printf("Error searching notes: %v", err)
 		return nil, fmt.Sprintf("Error searching notes: %v", err), err
@@ -192,7 +210,7 @@ func (dw *DatabaseWrapper) SearchNotes(searchTerm string, username string) ([]in
 	for rows.Next() {
 		var note interfaces.Note
 		err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt,
-			&note.UserID, &note.Username, &note.Open, &note.Author)
+			&note.UserID, &note.Username, &note.Open, &note.Author, &note.Version)
 		if err != nil {
 			log.Printf("Error scanning note: %v", err)
 			continue
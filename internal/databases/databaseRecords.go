@@ -0,0 +1,124 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+
+	//External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// recordQuery is what the record browser reads from a table. The
+// expressions are written against the table aliased as r.
+type recordQuery struct{ title, assignee, status, completed string }
+
+var recordQueries = map[string]recordQuery{
+	interfaces.RecordNotes:  {"r.title", "''", "''", "FALSE"},
+	interfaces.RecordTasks:  {"r.title", "COALESCE(r.assignee, '')", "''", "COALESCE(r.completed, FALSE)"},
+	interfaces.RecordAudits: {"concat_ws(' - ', r.action, NULLIF(r.firm, ''))", "COALESCE(r.assigned_user, '')", "COALESCE(r.status, '')", "COALESCE(r.completed, FALSE)"},
+	interfaces.RecordCRM:    {"r.name", "''", "''", "FALSE"},
+}
+
+func queryFor(kind string) (recordQuery, error) {
+	query, ok := recordQueries[kind]
+	if !ok {
+		return query, fmt.Errorf("unknown record kind %q", kind)
+	}
+	return query, nil
+}
+
+// ListRecords lists every record of kind, whoever owns it, newest first
+func (dw *DatabaseWrapper) ListRecords(kind string) ([]interfaces.RecordSummary, error) {
+	query, err := queryFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DBPool.Query(context.Background(), fmt.Sprintf(`
+    SELECT r.id, COALESCE(%s, ''), COALESCE(u.username, r.username, ''), %s, %s, %s, r.created_at, r.updated_at
+    FROM %s r LEFT JOIN users u ON u.user_id = r.user_id
+    ORDER BY r.updated_at DESC, r.id DESC`, query.title, query.assignee, query.status, query.completed, kind))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", kind, err)
+	}
+	defer rows.Close()
+
+	var records []interfaces.RecordSummary
+	for rows.Next() {
+		record := interfaces.RecordSummary{Kind: kind}
+		if err := rows.Scan(&record.ID, &record.Title, &record.Owner, &record.Assignee, &record.Status, &record.Completed,
+			&record.CreatedAt, &record.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", kind, err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// DeleteRecords deletes the records of kind with the given IDs and returns
+// how many there were
+func (dw *DatabaseWrapper) DeleteRecords(kind string, ids []int) (int, error) {
+	if _, err := queryFor(kind); err != nil {
+		return 0, err
+	}
+	tag, err := DBPool.Exec(context.Background(), fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1)`, kind), ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %s: %v", kind, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ReassignRecords gives the records of kind with the given IDs to another
+// user, who must be active. Tasks and audits get a new assignee; notes and
+// CRM entries, which have none, a new owner. It returns how many changed.
+func (dw *DatabaseWrapper) ReassignRecords(kind string, ids []int, to string) (int, error) {
+	if _, err := queryFor(kind); err != nil {
+		return 0, err
+	}
+	var toID, toStatus string
+	err := DBPool.QueryRow(context.Background(), `SELECT user_id::text, COALESCE(status, '') FROM users WHERE username = $1`, to).
+		Scan(&toID, &toStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("user not found: %s", to)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if toStatus == interfaces.UserInactive {
+		return 0, fmt.Errorf("cannot reassign %s to %s: %w", kind, to, interfaces.ErrInactiveUser)
+	}
+
+	set, args := `user_id = $2, username = $3`, []interface{}{ids, toID, to}
+	switch kind {
+	case interfaces.RecordTasks:
+		set, args = `assignee = $2`, []interface{}{ids, to}
+	case interfaces.RecordAudits:
+		set, args = `assigned_user = $2, additional_users = array_remove(additional_users, $2)`, []interface{}{ids, to}
+	}
+	tag, err := DBPool.Exec(context.Background(), fmt.Sprintf(`
+    UPDATE %s SET %s, updated_at = CURRENT_TIMESTAMP, version = version + 1
+    WHERE id = ANY($1)`, kind, set), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign %s to %s: %v", kind, to, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// CompleteRecords marks the tasks with the given IDs complete and returns
+// how many were still open
+func (dw *DatabaseWrapper) CompleteRecords(kind string, ids []int) (int, error) {
+	if !interfaces.Completable(kind) {
+		return 0, fmt.Errorf("%s cannot be marked complete", kind)
+	}
+	tag, err := DBPool.Exec(context.Background(), fmt.Sprintf(`
+    UPDATE %s SET completed = TRUE, updated_at = CURRENT_TIMESTAMP, version = version + 1
+    WHERE id = ANY($1) AND NOT COALESCE(completed, FALSE)`, kind), ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete %s: %v", kind, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
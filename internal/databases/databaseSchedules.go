@@ -0,0 +1,199 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureSchedulesTableExists creates the recurring audit schedules and links
+// audits to the schedule and date they were created for. A schedule has at
+// most one audit per date, so two schedulers running at once cannot both
+// create it. Audits are kept when their schedule is deleted.
+func EnsureSchedulesTableExists() error {
+	_, err := DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS audit_schedules (
+        id SERIAL PRIMARY KEY,
+        name TEXT NOT NULL,
+        recurrence TEXT NOT NULL,
+        start_date DATE NOT NULL,
+        lead_days INTEGER NOT NULL DEFAULT 30,
+        firm TEXT,
+        audit_type TEXT,
+        audit_area TEXT,
+        template_id INTEGER REFERENCES audit_templates (id) ON DELETE SET NULL,
+        assignee TEXT,
+        paused BOOLEAN NOT NULL DEFAULT FALSE,
+        created_by TEXT NOT NULL,
+        created_through DATE,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1
+    );`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit schedules table: %v", err)
+	}
+	for _, column := range []struct{ name, definition string }{
+		{"schedule_id", "INTEGER REFERENCES audit_schedules (id) ON DELETE SET NULL"},
+		{"scheduled_for", "DATE"},
+	} {
+		if err := ensureColumn("audits", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	_, err = DBPool.Exec(context.Background(), `
+    CREATE UNIQUE INDEX IF NOT EXISTS audits_schedule_date ON audits (schedule_id, scheduled_for);
+    CREATE INDEX IF NOT EXISTS audits_scheduled_for ON audits (scheduled_for);`)
+	if err != nil {
+		return fmt.Errorf("failed to index scheduled audits: %v", err)
+	}
+	return nil
+}
+
+// Columns read by scanSchedule
+const scheduleColumns = `id, name, recurrence, start_date, lead_days, COALESCE(firm, ''), COALESCE(audit_type, ''),
+              COALESCE(audit_area, ''), COALESCE(template_id, 0), COALESCE(assignee, ''), paused, created_by,
+              created_through, created_at, updated_at, version`
+
+func scanSchedule(row pgx.Row) (interfaces.AuditSchedule, error) {
+	var schedule interfaces.AuditSchedule
+	var createdThrough *time.Time
+	err := row.Scan(&schedule.ID, &schedule.Name, &schedule.Recurrence, &schedule.StartDate, &schedule.LeadDays,
+		&schedule.Firm, &schedule.AuditType, &schedule.AuditArea, &schedule.TemplateID, &schedule.Assignee,
+		&schedule.Paused, &schedule.CreatedBy, &createdThrough, &schedule.CreatedAt, &schedule.UpdatedAt, &schedule.Version)
+	if err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	if createdThrough != nil {
+		schedule.CreatedThrough = *createdThrough
+	}
+	return schedule, nil
+}
+
+func (dw *DatabaseWrapper) GetSchedule(id int) (interfaces.AuditSchedule, error) {
+	return scanSchedule(DBPool.QueryRow(context.Background(), `SELECT `+scheduleColumns+` FROM audit_schedules WHERE id = $1`, id))
+}
+
+// GetSchedules lists every schedule by name
+func (dw *DatabaseWrapper) GetSchedules() ([]interfaces.AuditSchedule, error) {
+	rows, err := DBPool.Query(context.Background(), `SELECT `+scheduleColumns+` FROM audit_schedules ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit schedules: %v", err)
+	}
+	defer rows.Close()
+
+	var schedules []interfaces.AuditSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (dw *DatabaseWrapper) CreateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	if err := checkAssignable(schedule.Assignee); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	created, err := scanSchedule(DBPool.QueryRow(context.Background(), `
+    INSERT INTO audit_schedules (name, recurrence, start_date, lead_days, firm, audit_type, audit_area, template_id,
+        assignee, paused, created_by)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    RETURNING `+scheduleColumns,
+		schedule.Name, schedule.Recurrence, schedule.StartDate, schedule.LeadDays, nullIfEmpty(schedule.Firm),
+		nullIfEmpty(schedule.AuditType), nullIfEmpty(schedule.AuditArea), nullIfZero(schedule.TemplateID),
+		nullIfEmpty(schedule.Assignee), schedule.Paused, schedule.CreatedBy))
+	if err != nil {
+		return interfaces.AuditSchedule{}, fmt.Errorf("failed to save schedule %q: %v", schedule.Name, err)
+	}
+	return created, nil
+}
+
+// UpdateSchedule only succeeds when schedule.Version still matches the
+// stored row. Who created it and how far it has got are left alone.
+func (dw *DatabaseWrapper) UpdateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	if err := checkAssignable(schedule.Assignee); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	updated, err := scanSchedule(DBPool.QueryRow(context.Background(), `
+    UPDATE audit_schedules SET name = $3, recurrence = $4, start_date = $5, lead_days = $6, firm = $7, audit_type = $8,
+        audit_area = $9, template_id = $10, assignee = $11, paused = $12, updated_at = CURRENT_TIMESTAMP,
+        version = version + 1
+    WHERE id = $1 AND version = $2
+    RETURNING `+scheduleColumns,
+		schedule.ID, schedule.Version, schedule.Name, schedule.Recurrence, schedule.StartDate, schedule.LeadDays,
+		nullIfEmpty(schedule.Firm), nullIfEmpty(schedule.AuditType), nullIfEmpty(schedule.AuditArea),
+		nullIfZero(schedule.TemplateID), nullIfEmpty(schedule.Assignee), schedule.Paused))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetSchedule(schedule.ID)
+		if getErr != nil {
+			return interfaces.AuditSchedule{}, fmt.Errorf("schedule %d no longer exists: %w", schedule.ID, getErr)
+		}
+		return interfaces.AuditSchedule{}, &interfaces.ConflictError{Entity: "schedule", ID: schedule.ID, Current: current}
+	}
+	if err != nil {
+		return interfaces.AuditSchedule{}, fmt.Errorf("failed to save schedule %q: %v", schedule.Name, err)
+	}
+	return updated, nil
+}
+
+// DeleteSchedule deletes a schedule. The audits it created are kept.
+func (dw *DatabaseWrapper) DeleteSchedule(id int) error {
+	_, err := DBPool.Exec(context.Background(), `DELETE FROM audit_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule %d: %v", id, err)
+	}
+	return nil
+}
+
+// SetScheduleCreatedThrough records that a schedule's audits have been
+// created up to through. It never moves back and does not count as an edit.
+func (dw *DatabaseWrapper) SetScheduleCreatedThrough(id int, through time.Time) error {
+	_, err := DBPool.Exec(context.Background(), `
+    UPDATE audit_schedules SET created_through = GREATEST(created_through, $2::date)
+    WHERE id = $1`, id, through)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule %d: %v", id, err)
+	}
+	return nil
+}
+
+// GetScheduledAudits lists the audits of every schedule dated from from to
+// to, by date
+func (dw *DatabaseWrapper) GetScheduledAudits(from, to time.Time) ([]interfaces.Audits, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+auditColumns+`
+    FROM audits
+    WHERE schedule_id IS NOT NULL AND scheduled_for BETWEEN $1::date AND $2::date
+    ORDER BY scheduled_for, id`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduled audits: %v", err)
+	}
+	defer rows.Close()
+
+	var audits []interfaces.Audits
+	for rows.Next() {
+		audit, err := scanAudit(rows)
+		if err != nil {
+			return nil, err
+		}
+		audits = append(audits, audit)
+	}
+	return audits, rows.Err()
+}
@@ -0,0 +1,137 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureSecurityTablesExist creates the login attempt counters and the change
+// history. Neither references users: failed logins name whatever was typed.
+func EnsureSecurityTablesExist() error {
+	createTablesSQL := `
+    CREATE TABLE IF NOT EXISTS login_attempts (
+        scope TEXT NOT NULL,
+        name TEXT NOT NULL,
+        failures INTEGER NOT NULL DEFAULT 0,
+        last_failure TIMESTAMP WITH TIME ZONE NOT NULL,
+        PRIMARY KEY (scope, name)
+    );
+    CREATE TABLE IF NOT EXISTS change_history (
+        id SERIAL PRIMARY KEY,
+        occurred_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        actor TEXT NOT NULL DEFAULT '',
+        host TEXT NOT NULL DEFAULT '',
+        action TEXT NOT NULL,
+        subject TEXT NOT NULL DEFAULT '',
+        detail TEXT NOT NULL DEFAULT ''
+    );
+    CREATE INDEX IF NOT EXISTS change_history_occurred_at ON change_history (occurred_at DESC);`
+
+	_, err := DBPool.Exec(context.Background(), createTablesSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create login attempt and change history tables: %v", err)
+	}
+	return nil
+}
+
+// GetLoginAttempts returns the failures counted for name, or none
+func (dw *DatabaseWrapper) GetLoginAttempts(scope, name string) (interfaces.LoginAttempts, error) {
+	attempts := interfaces.LoginAttempts{Scope: scope, Name: name}
+	err := DBPool.QueryRow(context.Background(), `SELECT failures, last_failure FROM login_attempts
+              WHERE scope = $1 AND name = $2`, scope, name).Scan(&attempts.Failures, &attempts.LastFailure)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return attempts, nil
+	}
+	return attempts, err
+}
+
+// ListLoginAttempts returns every username and host with failures, most
+// recent first
+func (dw *DatabaseWrapper) ListLoginAttempts() ([]interfaces.LoginAttempts, error) {
+	rows, err := DBPool.Query(context.Background(), `SELECT scope, name, failures, last_failure FROM login_attempts
+              WHERE failures > 0 ORDER BY last_failure DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %v", err)
+	}
+	defer rows.Close()
+
+	var list []interfaces.LoginAttempts
+	for rows.Next() {
+		var attempts interfaces.LoginAttempts
+		if err := rows.Scan(&attempts.Scope, &attempts.Name, &attempts.Failures, &attempts.LastFailure); err != nil {
+			return nil, err
+		}
+		list = append(list, attempts)
+	}
+	return list, rows.Err()
+}
+
+// RecordLoginFailure counts one more failure for name at the given time. A
+// count whose last failure is before since starts again at one.
+func (dw *DatabaseWrapper) RecordLoginFailure(scope, name string, at, since time.Time) (interfaces.LoginAttempts, error) {
+	attempts := interfaces.LoginAttempts{Scope: scope, Name: name}
+	err := DBPool.QueryRow(context.Background(), `
+    INSERT INTO login_attempts (scope, name, failures, last_failure) VALUES ($1, $2, 1, $3)
+    ON CONFLICT (scope, name) DO UPDATE SET
+        failures = CASE WHEN login_attempts.last_failure < $4 THEN 1 ELSE login_attempts.failures + 1 END,
+        last_failure = GREATEST(login_attempts.last_failure, EXCLUDED.last_failure)
+    RETURNING failures, last_failure`, scope, name, at, since).Scan(&attempts.Failures, &attempts.LastFailure)
+	if err != nil {
+		return interfaces.LoginAttempts{}, fmt.Errorf("failed to record a failed login for %s: %v", name, err)
+	}
+	return attempts, nil
+}
+
+// ClearLoginFailures forgets the failures of name, after a good login or
+// when an administrator unlocks it
+func (dw *DatabaseWrapper) ClearLoginFailures(scope, name string) error {
+	_, err := DBPool.Exec(context.Background(), `DELETE FROM login_attempts WHERE scope = $1 AND name = $2`, scope, name)
+	if err != nil {
+		return fmt.Errorf("failed to clear the failed logins of %s: %v", name, err)
+	}
+	return nil
+}
+
+// AddChangeHistory appends an entry to the change history. A zero At means
+// now.
+func (dw *DatabaseWrapper) AddChangeHistory(entry interfaces.ChangeHistory) (interfaces.ChangeHistory, error) {
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	err := DBPool.QueryRow(context.Background(), `INSERT INTO change_history (occurred_at, actor, host, action, subject, detail)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		entry.At, entry.Actor, entry.Host, entry.Action, entry.Subject, entry.Detail).Scan(&entry.ID)
+	if err != nil {
+		return interfaces.ChangeHistory{}, fmt.Errorf("failed to add to the change history: %v", err)
+	}
+	return entry, nil
+}
+
+// GetChangeHistory returns the latest limit entries, newest first
+func (dw *DatabaseWrapper) GetChangeHistory(limit int) ([]interfaces.ChangeHistory, error) {
+	rows, err := DBPool.Query(context.Background(), `SELECT id, occurred_at, actor, host, action, subject, detail
+              FROM change_history ORDER BY occurred_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the change history: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []interfaces.ChangeHistory
+	for rows.Next() {
+		var entry interfaces.ChangeHistory
+		if err := rows.Scan(&entry.ID, &entry.At, &entry.Actor, &entry.Host, &entry.Action, &entry.Subject, &entry.Detail); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,206 @@
+package databases
+
+import (
+	// Standard Library
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
+)
+
+const (
+	defaultSQLPort         = "5432"
+	defaultApplicationName = "goAudit"
+)
+
+// SQLSettings describes how to reach the Postgres server. When DSN is set it is
+// used as is and the discrete connection fields are ignored; the pool and
+// timeout settings apply either way.
+type SQLSettings struct {
+	DSN      string
+	Server   string
+	User     string
+	Password string
+	Database string
+	Port     string
+
+	// TLS
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	ApplicationName  string
+	StatementTimeout time.Duration
+
+	// Pool tuning, zero keeps the pgxpool default
+	MaxConns        int32
+	MinConns        int32
+	MaxConnIdleTime time.Duration
+	MaxConnLifetime time.Duration
+}
+
+// LoadSQLSettings returns the SQL settings from the active configuration
+func LoadSQLSettings() (SQLSettings, error) {
+	return SQLSettingsFromConfig(config.Current().SQL)
+}
+
+// SQLSettingsFromConfig converts c, fetching the password and DSN from the
+// secret store when they hold references
+func SQLSettingsFromConfig(c config.SQL) (SQLSettings, error) {
+	settings := SQLSettings{
+		Server:           c.Server,
+		User:             c.User,
+		Database:         c.Database,
+		SSLMode:          c.SSLMode,
+		SSLRootCert:      c.SSLRootCert,
+		SSLCert:          c.SSLCert,
+		SSLKey:           c.SSLKey,
+		ApplicationName:  c.ApplicationName,
+		StatementTimeout: time.Duration(c.StatementTimeout),
+		MaxConns:         c.MaxConns,
+		MinConns:         c.MinConns,
+		MaxConnIdleTime:  time.Duration(c.MaxConnIdleTime),
+		MaxConnLifetime:  time.Duration(c.MaxConnLifetime),
+	}
+	if c.Port != 0 {
+		settings.Port = strconv.Itoa(c.Port)
+	}
+
+	var err error
+	settings.DSN, err = secrets.Resolve(c.DSN)
+	if err != nil {
+		return settings, fmt.Errorf("SQL DSN: %v", err)
+	}
+	settings.Password, err = secrets.Resolve(c.Password)
+	if err != nil {
+		return settings, fmt.Errorf("SQL password: %v", err)
+	}
+	return settings, nil
+}
+
+// Validate reports settings that can never produce a working connection
+func (s SQLSettings) Validate() error {
+	if s.DSN == "" {
+		if s.Server == "" {
+			return fmt.Errorf("SQL server is not configured")
+		}
+		if s.Database == "" {
+			return fmt.Errorf("SQL database name is not configured")
+		}
+		if s.Port != "" {
+			if port, err := strconv.Atoi(s.Port); err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("invalid SQL port: %q", s.Port)
+			}
+		}
+		if s.SSLMode != "" && !containsString(config.SSLModes, s.SSLMode) {
+			return fmt.Errorf("invalid SQL sslmode %q, expected one of %v", s.SSLMode, config.SSLModes)
+		}
+		if (s.SSLCert == "") != (s.SSLKey == "") {
+			return fmt.Errorf("SQL client certificate and key must be set together")
+		}
+	}
+	if s.StatementTimeout < 0 {
+		return fmt.Errorf("SQL statement timeout cannot be negative")
+	}
+	if s.MaxConns < 0 || s.MinConns < 0 {
+		return fmt.Errorf("SQL pool sizes cannot be negative")
+	}
+	if s.MaxConns > 0 && s.MinConns > s.MaxConns {
+		return fmt.Errorf("SQL pool min connections (%d) exceeds max connections (%d)", s.MinConns, s.MaxConns)
+	}
+	return nil
+}
+
+// ConnString returns the DSN, or a postgres:// URL built from the discrete
+// fields with the credentials escaped.
+func (s SQLSettings) ConnString() string {
+	if s.DSN != "" {
+		return s.DSN
+	}
+
+	port := s.Port
+	if port == "" {
+		port = defaultSQLPort
+	}
+
+	connURL := url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(s.Server, port),
+		Path:   "/" + s.Database,
+	}
+	if s.User != "" {
+		connURL.User = url.UserPassword(s.User, s.Password)
+	}
+
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"sslmode":     s.SSLMode,
+		"sslrootcert": s.SSLRootCert,
+		"sslcert":     s.SSLCert,
+		"sslkey":      s.SSLKey,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	connURL.RawQuery = query.Encode()
+
+	return connURL.String()
+}
+
+// PoolConfig validates the settings and turns them into a pgxpool config
+func (s SQLSettings) PoolConfig() (*pgxpool.Config, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(s.ConnString())
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQL connection settings: %v", err)
+	}
+
+	runtimeParams := poolConfig.ConnConfig.RuntimeParams
+	if _, ok := runtimeParams["application_name"]; !ok {
+		name := s.ApplicationName
+		if name == "" {
+			name = defaultApplicationName
+		}
+		runtimeParams["application_name"] = name
+	}
+	if s.StatementTimeout > 0 {
+		runtimeParams["statement_timeout"] = strconv.FormatInt(s.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if s.MaxConns > 0 {
+		poolConfig.MaxConns = s.MaxConns
+	}
+	if s.MinConns > 0 {
+		poolConfig.MinConns = s.MinConns
+	}
+	if s.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = s.MaxConnIdleTime
+	}
+	if s.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = s.MaxConnLifetime
+	}
+
+	return poolConfig, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
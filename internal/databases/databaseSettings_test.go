@@ -0,0 +1,142 @@
+package databases
+
+import (
+	// Standard Library
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+)
+
+func TestConnStringEscapesCredentials(t *testing.T) {
+	settings := SQLSettings{
+		Server:   "db.example.com",
+		User:     "audit user",
+		Password: "p@ss:w/rd?#%",
+		Database: "goaudit",
+		SSLMode:  "verify-full",
+	}
+
+	config, err := settings.PoolConfig()
+	if err != nil {
+		t.Fatalf("PoolConfig: %v", err)
+	}
+	conn := config.ConnConfig
+	if conn.User != "audit user" || conn.Password != "p@ss:w/rd?#%" {
+		t.Errorf("credentials did not round trip: user %q password %q", conn.User, conn.Password)
+	}
+	if conn.Host != "db.example.com" || conn.Port != 5432 || conn.Database != "goaudit" {
+		t.Errorf("host %q port %d database %q", conn.Host, conn.Port, conn.Database)
+	}
+	if conn.TLSConfig == nil || conn.TLSConfig.InsecureSkipVerify || conn.TLSConfig.ServerName != "db.example.com" {
+		t.Errorf("verify-full did not produce a verifying TLS config: %+v", conn.TLSConfig)
+	}
+	if got := conn.RuntimeParams["application_name"]; got != "goAudit" {
+		t.Errorf("application_name = %q, want goAudit", got)
+	}
+}
+
+func TestConnStringIPv6Host(t *testing.T) {
+	settings := SQLSettings{Server: "::1", Port: "6543", Database: "goaudit"}
+	if got := settings.ConnString(); !strings.HasPrefix(got, "postgres://[::1]:6543/goaudit") {
+		t.Errorf("ConnString = %q", got)
+	}
+}
+
+func TestPoolConfigTuning(t *testing.T) {
+	settings := SQLSettings{
+		DSN:              "postgres://u@localhost/db?sslmode=disable&application_name=custom",
+		ApplicationName:  "ignored",
+		StatementTimeout: 30 * time.Second,
+		MaxConns:         8,
+		MinConns:         2,
+		MaxConnIdleTime:  time.Minute,
+		MaxConnLifetime:  time.Hour,
+	}
+
+	config, err := settings.PoolConfig()
+	if err != nil {
+		t.Fatalf("PoolConfig: %v", err)
+	}
+	if config.MaxConns != 8 || config.MinConns != 2 || config.MaxConnIdleTime != time.Minute || config.MaxConnLifetime != time.Hour {
+		t.Errorf("pool settings not applied: max %d min %d idle %v lifetime %v",
+			config.MaxConns, config.MinConns, config.MaxConnIdleTime, config.MaxConnLifetime)
+	}
+	params := config.ConnConfig.RuntimeParams
+	if params["statement_timeout"] != "30000" {
+		t.Errorf("statement_timeout = %q, want 30000", params["statement_timeout"])
+	}
+	if params["application_name"] != "custom" {
+		t.Errorf("application_name from the DSN was overridden: %q", params["application_name"])
+	}
+	if config.ConnConfig.TLSConfig != nil {
+		t.Error("sslmode=disable from the DSN was not honoured")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	base := SQLSettings{Server: "localhost", Database: "goaudit"}
+
+	tests := []struct {
+		name   string
+		modify func(*SQLSettings)
+	}{
+		{"missing server", func(s *SQLSettings) { s.Server = "" }},
+		{"missing database", func(s *SQLSettings) { s.Database = "" }},
+		{"bad port", func(s *SQLSettings) { s.Port = "54x2" }},
+		{"bad sslmode", func(s *SQLSettings) { s.SSLMode = "always" }},
+		{"cert without key", func(s *SQLSettings) { s.SSLCert = "/etc/goaudit/client.crt" }},
+		{"min above max", func(s *SQLSettings) { s.MaxConns, s.MinConns = 2, 4 }},
+		{"negative timeout", func(s *SQLSettings) { s.StatementTimeout = -time.Second }},
+	}
+
+	if err := base.Validate(); err != nil {
+		t.Fatalf("valid settings rejected: %v", err)
+	}
+	for _, tc := range tests {
+		settings := base
+		tc.modify(&settings)
+		if err := settings.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestSQLSettingsFromConfig(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := SQLSettingsFromConfig(config.SQL{
+		Server:           "db.example.com",
+		Port:             6543,
+		Password:         "file:" + passwordFile,
+		Database:         "goaudit",
+		StatementTimeout: config.Duration(15 * time.Second),
+		MaxConns:         12,
+	})
+	if err != nil {
+		t.Fatalf("SQLSettingsFromConfig: %v", err)
+	}
+	if settings.Port != "6543" || settings.StatementTimeout != 15*time.Second || settings.MaxConns != 12 {
+		t.Errorf("SQLSettingsFromConfig = %+v", settings)
+	}
+	if settings.Password != "s3cret" {
+		t.Errorf("password = %q, want it read from the secret file", settings.Password)
+	}
+
+	settings, err = SQLSettingsFromConfig(config.SQL{Server: "db"})
+	if err != nil || settings.Port != "" {
+		t.Errorf("unset port = %q, %v; want empty so the default applies", settings.Port, err)
+	}
+
+	_, err = SQLSettingsFromConfig(config.SQL{Password: "file:" + passwordFile + ".missing"})
+	if err == nil || !strings.Contains(err.Error(), "SQL password") {
+		t.Errorf("missing secret file = %v", err)
+	}
+}
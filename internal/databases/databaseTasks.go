@@ -3,10 +3,14 @@ package databases
 import (
 	// Standard Library
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	// External Imports
+	"github.com/jackc/pgx/v5"
+
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
 )
@@ -22,10 +26,12 @@ func EnsureTaskTableExists() error {
         notes TEXT,
         due_date TIMESTAMP WITH TIME ZONE,
         completed BOOLEAN DEFAULT FALSE,
-        user_id INTEGER NOT NULL,
+        user_id UUID NOT NULL REFERENCES users (user_id),
         username TEXT,
+        assignee TEXT,
         created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1
     );`
 
 	_, err := DBPool.Exec(context.Background(), createTableSQL)
@@ -33,17 +39,69 @@ func EnsureTaskTableExists() error {
 		return fmt.Errorf("failed to create tasks table: %v", err)
 	}
 
+	err = ensureColumn("tasks", "version", "INTEGER NOT NULL DEFAULT 1")
+	if err != nil {
+		return err
+	}
+	err = ensureColumn("tasks", "assignee", "TEXT")
+	if err != nil {
+		return err
+	}
+	err = ensureColumn("tasks", "finding_id", "INTEGER REFERENCES findings (id) ON DELETE SET NULL")
+	if err != nil {
+		return err
+	}
+	_, err = DBPool.Exec(context.Background(), `CREATE INDEX IF NOT EXISTS tasks_assignee_idx ON tasks (assignee)`)
+	if err != nil {
+		return fmt.Errorf("failed to index tasks by assignee: %v", err)
+	}
 	return nil
 }
 
+const taskColumns = `id, title, description, status, priority, notes, due_date, completed, user_id::text, username,
+              COALESCE(assignee, ''), COALESCE(finding_id, 0), created_at, updated_at, version`
+
+func scanTask(row pgx.Row) (interfaces.Tasks, error) {
+	var task interfaces.Tasks
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.Notes,
+		&task.DueDate, &task.Completed, &task.UserID, &task.Username, &task.Assignee, &task.FindingID, &task.CreatedAt,
+		&task.UpdatedAt, &task.Version)
+	return task, err
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// nullIfZero stores an unset ID as NULL
+func nullIfZero(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 func (dw *DatabaseWrapper) CreateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
-	query := `INSERT INTO tasks (title, description, status, priority, notes, due_date, completed, user_id, username)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-              RETURNING id, created_at, updated_at`
+	if err := checkAssignable(task.Assignee); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	userID, err := dw.ownerID(task.Username)
+	if err != nil {
+		return interfaces.Tasks{}, err
+	}
+	task.UserID = userID
+	query := `INSERT INTO tasks (title, description, status, priority, notes, due_date, completed, user_id, username, assignee, finding_id)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+              RETURNING id, created_at, updated_at, version`
 
-	err := DBPool.QueryRow(context.Background(), query,
-		task.Title, task.Description, task.Status, task.Priority, task.Notes, task.DueDate, task.Completed, task.UserID, task.Username).
-		Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+	err = DBPool.QueryRow(context.Background(), query,
+		task.Title, task.Description, task.Status, task.Priority, task.Notes, task.DueDate, task.Completed, task.UserID, task.Username,
+		nullIfEmpty(task.Assignee), nullIfZero(task.FindingID)).
+		Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt, &task.Version)
 
 	if err != nil {
 		return interfaces.Tasks{}, err
@@ -52,13 +110,40 @@ func (dw *DatabaseWrapper) CreateTask(task interfaces.Tasks) (interfaces.Tasks,
 	return task, nil
 }
 
+func (dw *DatabaseWrapper) GetTask(id int) (interfaces.Tasks, error) {
+	query := `SELECT ` + taskColumns + `
+              FROM tasks
+              WHERE id = $1`
+
+	task, err := scanTask(DBPool.QueryRow(context.Background(), query, id))
+	if err != nil {
+		return interfaces.Tasks{}, err
+	}
+	return task, nil
+}
+
+// GetTasks returns the tasks username created or was assigned
 func (dw *DatabaseWrapper) GetTasks(username string) ([]interfaces.Tasks, string, error) {
-	query := `SELECT id, title, description, status, priority, notes, due_date, completed, user_id, username, created_at, updated_at
+	query := `SELECT ` + taskColumns + `
               FROM tasks
-              WHERE username = $1
+              WHERE username = $1 OR assignee = $1
               ORDER BY due_date ASC`
 
-	rows, err := DBPool.Query(context.Background(), query, username)
+	return queryTasks(query, username)
+}
+
+// GetAssignedTasks returns the tasks assigned to any of assignees
+func (dw *DatabaseWrapper) GetAssignedTasks(assignees []string) ([]interfaces.Tasks, string, error) {
+	query := `SELECT ` + taskColumns + `
+              FROM tasks
+              WHERE assignee = ANY($1)
+              ORDER BY assignee, due_date ASC`
+
+	return queryTasks(query, assignees)
+}
+
+func queryTasks(query string, args ...interface{}) ([]interfaces.Tasks, string, error) {
+	rows, err := DBPool.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, fmt.Sprintf("Error querying tasks: %v", err), err
 	}
@@ -66,9 +151,7 @@ func (dw *DatabaseWrapper) GetTasks(username string) ([]interfaces.Tasks, string
 
 	var tasks []interfaces.Tasks
 	for rows.Next() {
-		var task interfaces.Tasks
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.Notes,
-			&task.DueDate, &task.Completed, &task.UserID, &task.Username, &task.CreatedAt, &task.UpdatedAt)
+		task, err := scanTask(rows)
 		if err != nil {
 			log.Printf("Error scanning task: %v", err)
 			continue
@@ -87,14 +170,32 @@ func (dw *DatabaseWrapper) GetTasks(username string) ([]interfaces.Tasks, string
 	return tasks, "Tasks fetched successfully", nil
 }
 
+// UpdateTask only succeeds when task.Version still matches the stored row
 func (dw *DatabaseWrapper) UpdateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
-	query := `UPDATE tasks SET title=$1, description=$2, status=$3, priority=$4, notes=$5, due_date=$6, completed=$7, updated_at=$8
-              WHERE id=$9 RETURNING id, created_at, updated_at`
+	var assignee string
+	err := DBPool.QueryRow(context.Background(), `SELECT COALESCE(assignee, '') FROM tasks WHERE id = $1`, task.ID).Scan(&assignee)
+	if err == nil && assignee != task.Assignee {
+		if err := checkAssignable(task.Assignee); err != nil {
+			return interfaces.Tasks{}, err
+		}
+	}
+
+	query := `UPDATE tasks SET title=$1, description=$2, status=$3, priority=$4, notes=$5, due_date=$6, completed=$7, updated_at=$8,
+              assignee=$11, version=version+1
+              WHERE id=$9 AND version=$10 RETURNING id, created_at, updated_at, version`
 
-	err := DBPool.QueryRow(context.Background(), query,
-		task.Title, task.Description, task.Status, task.Priority, task.Notes, task.DueDate, task.Completed, time.Now(), task.ID).
-		Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+	err = DBPool.QueryRow(context.Background(), query,
+		task.Title, task.Description, task.Status, task.Priority, task.Notes, task.DueDate, task.Completed, time.Now(), task.ID,
+		task.Version, nullIfEmpty(task.Assignee)).
+		Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt, &task.Version)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetTask(task.ID)
+		if getErr != nil {
+			return interfaces.Tasks{}, fmt.Errorf("task %d no longer exists: %w", task.ID, getErr)
+		}
+		return interfaces.Tasks{}, &interfaces.ConflictError{Entity: "task", ID: task.ID, Current: current}
+	}
 	if err != nil {
 		return interfaces.Tasks{}, err
 	}
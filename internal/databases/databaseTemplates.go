@@ -0,0 +1,262 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// External Imports
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// EnsureTemplatesTablesExist creates the audit templates and the checklists
+// copied from them into audits. Audits remember their template, and keep
+// their checklist if it is deleted.
+func EnsureTemplatesTablesExist() error {
+	_, err := DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS audit_templates (
+        id SERIAL PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        audit_type TEXT,
+        audit_area TEXT,
+        items JSONB NOT NULL DEFAULT '[]',
+        finding_categories TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        version INTEGER NOT NULL DEFAULT 1
+    );`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit templates table: %v", err)
+	}
+	if err := ensureColumn("audits", "template_id", "INTEGER REFERENCES audit_templates (id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	_, err = DBPool.Exec(context.Background(), `
+    CREATE TABLE IF NOT EXISTS audit_checklist_items (
+        id SERIAL PRIMARY KEY,
+        audit_id INTEGER NOT NULL REFERENCES audits (id) ON DELETE CASCADE,
+        position INTEGER NOT NULL,
+        title TEXT NOT NULL,
+        expected_evidence TEXT,
+        guidance TEXT,
+        result TEXT NOT NULL DEFAULT '',
+        comment TEXT,
+        updated_by TEXT,
+        updated_at TIMESTAMP WITH TIME ZONE,
+        version INTEGER NOT NULL DEFAULT 1
+    );
+    CREATE INDEX IF NOT EXISTS audit_checklist_items_audit_idx ON audit_checklist_items (audit_id);`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit checklist table: %v", err)
+	}
+	return nil
+}
+
+// Columns read by scanTemplate
+const templateColumns = `id, name, COALESCE(audit_type, ''), COALESCE(audit_area, ''), items, finding_categories,
+              created_at, updated_at, version`
+
+func scanTemplate(row pgx.Row) (interfaces.AuditTemplate, error) {
+	var template interfaces.AuditTemplate
+	err := row.Scan(&template.ID, &template.Name, &template.AuditType, &template.AuditArea, &template.Items,
+		&template.FindingCategories, &template.CreatedAt, &template.UpdatedAt, &template.Version)
+	if err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	if len(template.Items) == 0 {
+		template.Items = nil
+	}
+	if len(template.FindingCategories) == 0 {
+		template.FindingCategories = nil
+	}
+	return template, nil
+}
+
+// templateArgs are the items and categories of template as stored, never NULL
+func templateArgs(template interfaces.AuditTemplate) ([]interfaces.TemplateItem, []string) {
+	items, categories := template.Items, template.FindingCategories
+	if items == nil {
+		items = []interfaces.TemplateItem{}
+	}
+	if categories == nil {
+		categories = []string{}
+	}
+	return items, categories
+}
+
+// templateSaveError explains a duplicate template name
+func templateSaveError(template interfaces.AuditTemplate, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: a template named %q already exists", interfaces.ErrInvalidTemplate, template.Name)
+	}
+	return fmt.Errorf("failed to save template %q: %v", template.Name, err)
+}
+
+func (dw *DatabaseWrapper) GetTemplate(id int) (interfaces.AuditTemplate, error) {
+	return scanTemplate(DBPool.QueryRow(context.Background(), `SELECT `+templateColumns+` FROM audit_templates WHERE id = $1`, id))
+}
+
+// GetTemplates lists every template by name
+func (dw *DatabaseWrapper) GetTemplates() ([]interfaces.AuditTemplate, error) {
+	rows, err := DBPool.Query(context.Background(), `SELECT `+templateColumns+` FROM audit_templates ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit templates: %v", err)
+	}
+	defer rows.Close()
+
+	var templates []interfaces.AuditTemplate
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (dw *DatabaseWrapper) CreateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	if err := template.Validate(); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	items, categories := templateArgs(template)
+	created, err := scanTemplate(DBPool.QueryRow(context.Background(), `
+    INSERT INTO audit_templates (name, audit_type, audit_area, items, finding_categories)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING `+templateColumns,
+		template.Name, nullIfEmpty(template.AuditType), nullIfEmpty(template.AuditArea), items, categories))
+	if err != nil {
+		return interfaces.AuditTemplate{}, templateSaveError(template, err)
+	}
+	return created, nil
+}
+
+// UpdateTemplate only succeeds when template.Version still matches the
+// stored row. Audits already created from it keep their checklists.
+func (dw *DatabaseWrapper) UpdateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	if err := template.Validate(); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	items, categories := templateArgs(template)
+	updated, err := scanTemplate(DBPool.QueryRow(context.Background(), `
+    UPDATE audit_templates SET name = $3, audit_type = $4, audit_area = $5, items = $6, finding_categories = $7,
+        updated_at = CURRENT_TIMESTAMP, version = version + 1
+    WHERE id = $1 AND version = $2
+    RETURNING `+templateColumns,
+		template.ID, template.Version, template.Name, nullIfEmpty(template.AuditType), nullIfEmpty(template.AuditArea),
+		items, categories))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := dw.GetTemplate(template.ID)
+		if getErr != nil {
+			return interfaces.AuditTemplate{}, fmt.Errorf("template %d no longer exists: %w", template.ID, getErr)
+		}
+		return interfaces.AuditTemplate{}, &interfaces.ConflictError{Entity: "template", ID: template.ID, Current: current}
+	}
+	if err != nil {
+		return interfaces.AuditTemplate{}, templateSaveError(template, err)
+	}
+	return updated, nil
+}
+
+// DeleteTemplate deletes a template. Audits created from it keep their checklists.
+func (dw *DatabaseWrapper) DeleteTemplate(id int) error {
+	_, err := DBPool.Exec(context.Background(), `DELETE FROM audit_templates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template %d: %v", id, err)
+	}
+	return nil
+}
+
+// instantiateChecklist copies the checklist of a template into a new audit
+func instantiateChecklist(ctx context.Context, tx pgx.Tx, auditID, templateID int) error {
+	_, err := tx.Exec(ctx, `
+    INSERT INTO audit_checklist_items (audit_id, position, title, expected_evidence, guidance)
+    SELECT $1, i.position, i.item->>'title', NULLIF(i.item->>'expected_evidence', ''), NULLIF(i.item->>'guidance', '')
+    FROM audit_templates t, jsonb_array_elements(t.items) WITH ORDINALITY AS i (item, position)
+    WHERE t.id = $2`, auditID, templateID)
+	if err != nil {
+		return fmt.Errorf("failed to copy the checklist of template %d: %v", templateID, err)
+	}
+	return nil
+}
+
+// Columns read by scanChecklistItem
+const checklistColumns = `id, audit_id, position, title, COALESCE(expected_evidence, ''), COALESCE(guidance, ''), result,
+              COALESCE(comment, ''), COALESCE(updated_by, ''), updated_at,
+              ARRAY(SELECT m.control_id FROM checklist_item_controls m
+                    WHERE m.item_id = audit_checklist_items.id ORDER BY m.control_id), version`
+
+func scanChecklistItem(row pgx.Row) (interfaces.ChecklistItem, error) {
+	var item interfaces.ChecklistItem
+	var updatedAt *time.Time
+	err := row.Scan(&item.ID, &item.AuditID, &item.Position, &item.Title, &item.ExpectedEvidence, &item.Guidance,
+		&item.Result, &item.Comment, &item.UpdatedBy, &updatedAt, &item.Controls, &item.Version)
+	if err != nil {
+		return interfaces.ChecklistItem{}, err
+	}
+	if updatedAt != nil {
+		item.UpdatedAt = *updatedAt
+	}
+	if len(item.Controls) == 0 {
+		item.Controls = nil
+	}
+	return item, nil
+}
+
+// GetChecklist lists the checklist of an audit in template order
+func (dw *DatabaseWrapper) GetChecklist(auditID int) ([]interfaces.ChecklistItem, error) {
+	rows, err := DBPool.Query(context.Background(), `
+    SELECT `+checklistColumns+`
+    FROM audit_checklist_items
+    WHERE audit_id = $1
+    ORDER BY position, id`, auditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the checklist of audit %d: %v", auditID, err)
+	}
+	defer rows.Close()
+
+	var items []interfaces.ChecklistItem
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// UpdateChecklistItem records the result and comment of an item, provided
+// item.Version still matches the stored row
+func (dw *DatabaseWrapper) UpdateChecklistItem(item interfaces.ChecklistItem) (interfaces.ChecklistItem, error) {
+	if err := item.Validate(); err != nil {
+		return interfaces.ChecklistItem{}, err
+	}
+	if item.UpdatedAt.IsZero() {
+		item.UpdatedAt = time.Now()
+	}
+	updated, err := scanChecklistItem(DBPool.QueryRow(context.Background(), `
+    UPDATE audit_checklist_items SET result = $3, comment = $4, updated_by = $5, updated_at = $6, version = version + 1
+    WHERE id = $1 AND version = $2
+    RETURNING `+checklistColumns,
+		item.ID, item.Version, item.Result, nullIfEmpty(item.Comment), nullIfEmpty(item.UpdatedBy), item.UpdatedAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, getErr := scanChecklistItem(DBPool.QueryRow(context.Background(),
+			`SELECT `+checklistColumns+` FROM audit_checklist_items WHERE id = $1`, item.ID))
+		if getErr != nil {
+			return interfaces.ChecklistItem{}, fmt.Errorf("checklist item %d no longer exists: %w", item.ID, getErr)
+		}
+		return interfaces.ChecklistItem{}, &interfaces.ConflictError{Entity: "checklist item", ID: item.ID, Current: current}
+	}
+	if err != nil {
+		return interfaces.ChecklistItem{}, fmt.Errorf("failed to update checklist item %d: %v", item.ID, err)
+	}
+	return updated, nil
+}
@@ -3,40 +3,60 @@ package databases
 import (
 	// Standard Library
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
 	//External Imports
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	// Internal Imports
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
 )
 
-func GetUserByAnyID(identifier interface{}) (interfaces.Users, error) {
+// Columns read by scanUser. Everything after username and user_id is optional.
+const userColumns = `id, username, user_id::text, COALESCE(email, ''), COALESCE(status, ''), created_at, updated_at, last_login,
+              COALESCE(directory_id, ''), COALESCE(display_name, ''), COALESCE(department, ''), COALESCE(manager, ''),
+              COALESCE(role, ''), password_hash IS NOT NULL, must_change_password, totp_secret IS NOT NULL`
+
+func scanUser(row pgx.Row) (interfaces.Users, error) {
 	var user interfaces.Users
+	var lastLogin *time.Time
+	err := row.Scan(&user.ID, &user.Username, &user.UserID, &user.Email, &user.Status,
+		&user.CreatedAt, &user.UpdatedAt, &lastLogin,
+		&user.DirectoryID, &user.DisplayName, &user.Department, &user.Manager,
+		&user.Role, &user.Local, &user.MustChangePassword, &user.TwoFactor)
+	if err != nil {
+		return interfaces.Users{}, err
+	}
+	if lastLogin != nil {
+		user.LastLogin = *lastLogin
+	}
+	return user, nil
+}
+
+func GetUserByAnyID(identifier interface{}) (interfaces.Users, error) {
 	var query string
 	var args []interface{}
 
 	switch v := identifier.(type) {
 	case int:
-		query = `SELECT id, username, user_id, email, status, created_at, updated_at, last_login
-                 FROM users WHERE id = $1 OR user_id = $1`
+		query = `SELECT ` + userColumns + `
+                 FROM users WHERE id = $1`
 		args = []interface{}{v}
 	case string:
-		query = `SELECT id, username, user_id, email, status, created_at, updated_at, last_login
+		query = `SELECT ` + userColumns + `
                  FROM users WHERE username = $1`
 		args = []interface{}{v}
 	default:
 		return interfaces.Users{}, fmt.Errorf("invalid identifier type")
 	}
 
-	err := DBPool.QueryRow(context.Background(), query, args...).Scan(
-		&user.ID, &user.Username, &user.UserID, &user.Email, &user.Status,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLogin)
+	user, err := scanUser(DBPool.QueryRow(context.Background(), query, args...))
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return interfaces.Users{}, fmt.Errorf("user not found")
 		}
 		return interfaces.Users{}, err
@@ -49,22 +69,50 @@ func EnsureUserTableExists() error {
     CREATE TABLE IF NOT EXISTS users (
 		id SERIAL PRIMARY KEY,
 		username TEXT UNIQUE NOT NULL,
-		user_id INTEGER UNIQUE NOT NULL,
+		user_id UUID UNIQUE NOT NULL,
 		email TEXT,
 		status TEXT,
 		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		last_login TIMESTAMP WITH TIME ZONE
+		last_login TIMESTAMP WITH TIME ZONE,
+		directory_id TEXT UNIQUE,
+		display_name TEXT,
+		department TEXT,
+		manager TEXT,
+		role TEXT,
+		password_hash TEXT,
+		must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+		totp_secret TEXT,
+		totp_last_step BIGINT NOT NULL DEFAULT 0,
+		backup_codes TEXT[],
+		oidc_subject TEXT UNIQUE
 	);`
 
 	_, err := DBPool.Exec(context.Background(), createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
+	for _, column := range []struct{ name, definition string }{
+		{"directory_id", "TEXT UNIQUE"},
+		{"display_name", "TEXT"},
+		{"department", "TEXT"},
+		{"manager", "TEXT"},
+		{"role", "TEXT"},
+		{"password_hash", "TEXT"},
+		{"must_change_password", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"totp_secret", "TEXT"},
+		{"totp_last_step", "BIGINT NOT NULL DEFAULT 0"},
+		{"backup_codes", "TEXT[]"},
+		{"oidc_subject", "TEXT UNIQUE"},
+	} {
+		if err := ensureColumn("users", column.name, column.definition); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func Create(username, email, status string, userID int, createdAt, updatedAt, lastLogin time.Time) (interfaces.Users, error) {
+func Create(username, email, status, userID string, createdAt, updatedAt, lastLogin time.Time) (interfaces.Users, error) {
 	userItem := interfaces.Users{
 		Username:  username,
 		Email:     email,
@@ -82,13 +130,11 @@ func Create(username, email, status string, userID int, createdAt, updatedAt, la
               status = EXCLUDED.status,
               updated_at = EXCLUDED.updated_at,
               last_login = EXCLUDED.last_login
-              RETURNING id, username, user_id, email, status, created_at, updated_at, last_login`
+              RETURNING ` + userColumns
 
-	err := DBPool.QueryRow(context.Background(), query,
+	userItem, err := scanUser(DBPool.QueryRow(context.Background(), query,
 		userItem.Username, userItem.UserID, userItem.Email, userItem.Status,
-		userItem.CreatedAt, userItem.UpdatedAt, userItem.LastLogin).
-		Scan(&userItem.ID, &userItem.Username, &userItem.UserID, &userItem.Email,
-			&userItem.Status, &userItem.CreatedAt, &userItem.UpdatedAt, &userItem.LastLogin)
+		userItem.CreatedAt, userItem.UpdatedAt, nullTime(userItem.LastLogin)))
 
 	if err != nil {
 		return interfaces.Users{}, err
@@ -97,24 +143,15 @@ func Create(username, email, status string, userID int, createdAt, updatedAt, la
 	return userItem, nil
 }
 
+// Create adds the user, or touches updated_at when the username already exists
 func (dw *DatabaseWrapper) Create(username string) (interfaces.Users, error) {
-	userItem := interfaces.Users{
-		Username: username,
-		Status:   "Active",
-		UserID:   generateUserID(),
-	}
-	query := `INSERT INTO users (username, user_id, status) 
-              VALUES ($1, $2, $3) 
-              ON CONFLICT (user_id) DO UPDATE SET
-              username = EXCLUDED.username,
-              status = EXCLUDED.status,
-              RETURNING id, username, user_id, email, status, created_at, updated_at, last_login`
+	query := `INSERT INTO users (username, user_id, status)
+              VALUES ($1, $2, $3)
+              ON CONFLICT (username) DO UPDATE SET
+              updated_at = CURRENT_TIMESTAMP
+              RETURNING ` + userColumns
 
-	err := DBPool.QueryRow(context.Background(), query,
-		userItem.Username, userItem.UserID, userItem.Email, userItem.Status,
-		userItem.CreatedAt, userItem.UpdatedAt, userItem.LastLogin).
-		Scan(&userItem.ID, &userItem.Username, &userItem.UserID, &userItem.Email,
-			&userItem.Status, &userItem.CreatedAt, &userItem.UpdatedAt, &userItem.LastLogin)
+	userItem, err := scanUser(DBPool.QueryRow(context.Background(), query, username, interfaces.NewUserID(), interfaces.UserActive))
 
 	if err != nil {
 		return interfaces.Users{}, err
@@ -129,8 +166,8 @@ func (dw *DatabaseWrapper) GetOrCreateUser(username string) (interfaces.Users, e
 		// User not found, create a new one
 		newUser := interfaces.Users{
 			Username:  username,
-			UserID:    generateUserID(),
-			Status:    "Active",
+			UserID:    interfaces.NewUserID(),
+			Status:    interfaces.UserActive,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -144,31 +181,31 @@ func (dw *DatabaseWrapper) GetOrCreateUser(username string) (interfaces.Users, e
 }
 
 func (dw *DatabaseWrapper) GetUsers(username string) ([]interfaces.Users, string, error) {
-	var users []interfaces.Users
 	user, err := GetUserByAnyID(username)
 	if err != nil {
-		if err != nil {
-			return []interfaces.Users{}, fmt.Sprintf("user not found: %s", username), err
-		}
-		users = append(users, user)
-		return users, fmt.Sprintf("User %s", username), nil
+		return []interfaces.Users{}, fmt.Sprintf("user not found: %s", username), err
 	}
-	users = append(users, user)
-	return users, fmt.Sprintf("User %s", username), nil
+	return []interfaces.Users{user}, fmt.Sprintf("User %s", username), nil
 }
 
-func generateUserID() int {
-	timestamp := time.Now().Unix()
-	randomPart := rand.Intn(10000)
-	userID := (int(timestamp)%100000)*10000 + randomPart
-	return userID
+// ownerID returns the user_id of username, adding the user if needed, for
+// rows that reference their owner
+func (dw *DatabaseWrapper) ownerID(username string) (string, error) {
+	if username == "" {
+		return "", fmt.Errorf("record has no owner")
+	}
+	user, err := dw.GetOrCreateUser(username)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up owner %s: %v", username, err)
+	}
+	return user.UserID, nil
 }
 
 func (dw *DatabaseWrapper) GetAll() ([]interfaces.Users, error) {
 	if DBPool == nil {
 		return nil, fmt.Errorf("database connection not initialized")
 	}
-	query := `SELECT id, username, user_id, email, status, created_at, updated_at, last_login FROM users`
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY username`
 
 	rows, err := DBPool.Query(context.Background(), query)
 	if err != nil {
@@ -178,8 +215,7 @@ func (dw *DatabaseWrapper) GetAll() ([]interfaces.Users, error) {
 
 	var users []interfaces.Users
 	for rows.Next() {
-		var user interfaces.Users
-		err := rows.Scan(&user.ID, &user.Username, &user.UserID, &user.Email, &user.Status, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -190,31 +226,375 @@ func (dw *DatabaseWrapper) GetAll() ([]interfaces.Users, error) {
 }
 
 func Get(id int) (interfaces.Users, error) {
-	var user interfaces.Users
-	query := `SELECT id, username, user_id, email, status, created_at, updated_at, last_login
+	query := `SELECT ` + userColumns + `
               FROM users WHERE id = $1`
-	err := DBPool.QueryRow(context.Background(), query, id).Scan(
-		&user.ID, &user.Username, &user.UserID, &user.Email, &user.Status, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin)
+	user, err := scanUser(DBPool.QueryRow(context.Background(), query, id))
 	if err != nil {
 		return interfaces.Users{}, err
 	}
 	return user, nil
 }
 
+// Update saves everything but user_id, which never changes once assigned,
+// and the password, which only SetPassword changes
 func (dw *DatabaseWrapper) Update(user interfaces.Users) (interfaces.Users, error) {
-	query := `UPDATE users SET username=$1, user_id=$2, email=$3, status=$4, updated_at=$5, last_login=$6 
-              WHERE id=$7 RETURNING id, created_at, updated_at`
+	query := `UPDATE users SET username=$1, email=$2, status=$3, updated_at=$4, last_login=$5,
+              directory_id=$7, display_name=$8, department=$9, manager=$10, role=$11
+              WHERE id=$6 RETURNING id, user_id::text, created_at, updated_at, password_hash IS NOT NULL, must_change_password,
+              totp_secret IS NOT NULL`
 	err := DBPool.QueryRow(context.Background(), query,
-		user.Username, user.UserID, user.Email, user.Status, time.Now(), user.LastLogin, user.ID).
-		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		user.Username, user.Email, user.Status, time.Now(), nullTime(user.LastLogin), user.ID,
+		nullIfEmpty(user.DirectoryID), user.DisplayName, user.Department, user.Manager, nullIfEmpty(user.Role)).
+		Scan(&user.ID, &user.UserID, &user.CreatedAt, &user.UpdatedAt, &user.Local, &user.MustChangePassword, &user.TwoFactor)
 	if err != nil {
 		return interfaces.Users{}, err
 	}
 	return user, nil
 }
 
+// Delete removes a user who owns and is assigned nothing. Assignments are
+// only names, so they are checked here rather than by foreign keys.
 func (dw *DatabaseWrapper) Delete(user interfaces.Users) error {
+	records, err := dw.GetUserRecords(user.Username)
+	if err != nil {
+		return err
+	}
+	if records.Total() > 0 {
+		return fmt.Errorf("cannot delete %s, who still has %s: %w", user.Username, records, interfaces.ErrUserInUse)
+	}
 	query := `DELETE FROM users WHERE id=$1 AND user_id=$2`
-	_, err := DBPool.Exec(context.Background(), query, user.ID, user.UserID)
+	_, err = DBPool.Exec(context.Background(), query, user.ID, user.UserID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return fmt.Errorf("cannot delete %s: %w", user.Username, interfaces.ErrUserInUse)
+	}
 	return err
 }
+
+// GetPasswordHash returns the local password hash of username, or "" when
+// they have no local password
+func (dw *DatabaseWrapper) GetPasswordHash(username string) (string, error) {
+	var hash *string
+	err := DBPool.QueryRow(context.Background(), `SELECT password_hash FROM users WHERE username = $1`, username).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	if hash == nil {
+		return "", nil
+	}
+	return *hash, nil
+}
+
+// SetPassword stores a local password hash for an existing user. An empty
+// hash removes the local password.
+func (dw *DatabaseWrapper) SetPassword(username, passwordHash string, mustChange bool) error {
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET password_hash = $2, must_change_password = $3,
+              updated_at = CURRENT_TIMESTAMP WHERE username = $1`,
+		username, nullIfEmpty(passwordHash), mustChange && passwordHash != "")
+	if err != nil {
+		return fmt.Errorf("failed to set the password of %s: %v", username, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// GetTwoFactor returns the TOTP enrolment of username
+func (dw *DatabaseWrapper) GetTwoFactor(username string) (interfaces.TwoFactor, error) {
+	var twoFactor interfaces.TwoFactor
+	err := DBPool.QueryRow(context.Background(), `SELECT COALESCE(totp_secret, ''), COALESCE(backup_codes, '{}')
+              FROM users WHERE username = $1`, username).Scan(&twoFactor.Secret, &twoFactor.BackupCodes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return interfaces.TwoFactor{}, fmt.Errorf("user not found")
+	}
+	return twoFactor, err
+}
+
+// SetTwoFactor replaces the TOTP enrolment of username. An empty secret
+// removes it along with the backup codes.
+func (dw *DatabaseWrapper) SetTwoFactor(username string, twoFactor interfaces.TwoFactor) error {
+	codes := twoFactor.BackupCodes
+	if twoFactor.Secret == "" || codes == nil {
+		codes = []string{}
+	}
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET totp_secret = $2, backup_codes = $3,
+              updated_at = CURRENT_TIMESTAMP WHERE username = $1`,
+		username, nullIfEmpty(twoFactor.Secret), codes)
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor settings for %s: %v", username, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// UseTOTPStep records that a code from time step was accepted. It returns
+// false when that step or a later one was already used, so a code cannot be
+// replayed.
+func (dw *DatabaseWrapper) UseTOTPStep(username string, step int64) (bool, error) {
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET totp_last_step = $2
+              WHERE username = $1 AND totp_last_step < $2`, username, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseBackupCode removes codeHash from the user's backup codes. It returns
+// false when the code is not one of them.
+func (dw *DatabaseWrapper) UseBackupCode(username, codeHash string) (bool, error) {
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET backup_codes = array_remove(backup_codes, $2)
+              WHERE username = $1 AND $2 = ANY(backup_codes)`, username, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetUserByOIDCSubject returns the user signed in as subject, the issuer and
+// sub claim of an ID token, or none
+func (dw *DatabaseWrapper) GetUserByOIDCSubject(subject string) ([]interfaces.Users, error) {
+	user, err := scanUser(DBPool.QueryRow(context.Background(),
+		`SELECT `+userColumns+` FROM users WHERE oidc_subject = $1`, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []interfaces.Users{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []interfaces.Users{user}, nil
+}
+
+// SetOIDCSubject links username to an OpenID Connect subject, which no other
+// user may have. A user linked to another subject has to be unlinked first,
+// by setting an empty subject.
+func (dw *DatabaseWrapper) SetOIDCSubject(username, subject string) error {
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET oidc_subject = $2, updated_at = CURRENT_TIMESTAMP
+              WHERE username = $1 AND ($2::text IS NULL OR oidc_subject IS NULL OR oidc_subject = $2)`,
+		username, nullIfEmpty(subject))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("another user is already linked to %s", subject)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link %s to %s: %v", username, subject, err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := GetUserByAnyID(username); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s is already linked to another identity", username)
+	}
+	return nil
+}
+
+// RecordLogin sets when username last logged in
+func (dw *DatabaseWrapper) RecordLogin(username string, at time.Time) error {
+	tag, err := DBPool.Exec(context.Background(), `UPDATE users SET last_login = $2 WHERE username = $1`, username, at)
+	if err != nil {
+		return fmt.Errorf("failed to record the login of %s: %v", username, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// GetUserRecords counts what username owns or is assigned
+func (dw *DatabaseWrapper) GetUserRecords(username string) (interfaces.UserRecords, error) {
+	var records interfaces.UserRecords
+	err := DBPool.QueryRow(context.Background(), `
+    WITH owner AS (SELECT user_id FROM users WHERE username = $1)
+    SELECT
+        (SELECT count(*) FROM notes WHERE user_id IN (SELECT user_id FROM owner)),
+        (SELECT count(*) FROM tasks WHERE user_id IN (SELECT user_id FROM owner)),
+        (SELECT count(*) FROM tasks WHERE assignee = $1),
+        (SELECT count(*) FROM audits WHERE user_id IN (SELECT user_id FROM owner)),
+        (SELECT count(*) FROM audits WHERE assigned_user = $1 OR $1 = ANY(additional_users)),
+        (SELECT count(*) FROM findings WHERE owner = $1),
+        (SELECT count(*) FROM crm WHERE user_id IN (SELECT user_id FROM owner)),
+        (SELECT count(*) FROM credentials WHERE user_id IN (SELECT user_id FROM owner))`, username).
+		Scan(&records.Notes, &records.Tasks, &records.AssignedTasks, &records.Audits, &records.AssignedAudits,
+			&records.Findings, &records.CRMEntries, &records.Credentials)
+	if err != nil {
+		return interfaces.UserRecords{}, fmt.Errorf("failed to count the records of %s: %v", username, err)
+	}
+	return records, nil
+}
+
+// TransferUserRecords gives the notes, tasks, audits, findings and CRM entries owned
+// by or assigned to from to another user, who must be active. Credentials
+// stay with from. It returns what was moved.
+func (dw *DatabaseWrapper) TransferUserRecords(from, to string) (interfaces.UserRecords, error) {
+	var moved interfaces.UserRecords
+	if from == to {
+		return moved, fmt.Errorf("cannot transfer records from %s to themselves", from)
+	}
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return moved, err
+	}
+	defer tx.Rollback(ctx)
+
+	var fromID, toID, toStatus string
+	err = tx.QueryRow(ctx, `SELECT user_id::text FROM users WHERE username = $1`, from).Scan(&fromID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return moved, fmt.Errorf("user not found: %s", from)
+	}
+	if err != nil {
+		return moved, err
+	}
+	err = tx.QueryRow(ctx, `SELECT user_id::text, COALESCE(status, '') FROM users WHERE username = $1`, to).Scan(&toID, &toStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return moved, fmt.Errorf("user not found: %s", to)
+	}
+	if err != nil {
+		return moved, err
+	}
+	if toStatus == interfaces.UserInactive {
+		return moved, fmt.Errorf("cannot transfer records to %s: %w", to, interfaces.ErrInactiveUser)
+	}
+
+	for _, step := range []struct {
+		count *int
+		query string
+		args  []interface{}
+	}{
+		{&moved.Notes, `UPDATE notes SET user_id = $2, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE user_id = $1`, []interface{}{fromID, toID}},
+		{&moved.Tasks, `UPDATE tasks SET user_id = $2, username = $3, updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE user_id = $1`, []interface{}{fromID, toID, to}},
+		{&moved.AssignedTasks, `UPDATE tasks SET assignee = $2, updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE assignee = $1`, []interface{}{from, to}},
+		{&moved.Audits, `UPDATE audits SET user_id = $2, username = $3, updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE user_id = $1`, []interface{}{fromID, toID, to}},
+		{&moved.AssignedAudits, `UPDATE audits SET
+              assigned_user = CASE WHEN assigned_user = $1 THEN $2 ELSE assigned_user END,
+              additional_users = CASE WHEN $2 = ANY(additional_users) THEN array_remove(additional_users, $1)
+                  ELSE array_replace(additional_users, $1, $2) END,
+              updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE assigned_user = $1 OR $1 = ANY(additional_users)`, []interface{}{from, to}},
+		{&moved.Findings, `UPDATE findings SET owner = $2, updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE owner = $1`, []interface{}{from, to}},
+		{&moved.CRMEntries, `UPDATE crm SET user_id = $2, username = $3, updated_at = CURRENT_TIMESTAMP, version = version + 1
+              WHERE user_id = $1`, []interface{}{fromID, toID, to}},
+	} {
+		tag, err := tx.Exec(ctx, step.query, step.args...)
+		if err != nil {
+			return interfaces.UserRecords{}, fmt.Errorf("failed to transfer records from %s to %s: %v", from, to, err)
+		}
+		*step.count = int(tag.RowsAffected())
+	}
+	return moved, tx.Commit(ctx)
+}
+
+// SyncDirectoryUsers upserts users read from the directory. Rows are matched
+// by directory ID, or by username for users who logged in before their first
+// sync. Disabled accounts become inactive; with complete, so do synced users
+// who are no longer in the directory.
+func (dw *DatabaseWrapper) SyncDirectoryUsers(users []interfaces.LDAPUser, complete bool) (interfaces.SyncResult, error) {
+	var result interfaces.SyncResult
+	ctx := context.Background()
+	tx, err := DBPool.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback(ctx)
+
+	managers := managerUsernames(users)
+	seen := make([]string, 0, len(users))
+	for _, user := range users {
+		if user.DirectoryID == "" || user.Username == "" {
+			continue
+		}
+		seen = append(seen, user.DirectoryID)
+		status := interfaces.UserActive
+		if user.Disabled {
+			status = interfaces.UserInactive
+		}
+		manager := managers[strings.ToLower(user.ManagerDN)]
+
+		// An account deleted and recreated with the same name gets a new
+		// directory ID; move the old row out of the way
+		_, err = tx.Exec(ctx, `UPDATE users SET username = username || '~' || id, status = $3, updated_at = CURRENT_TIMESTAMP
+              WHERE username = $1 AND directory_id IS NOT NULL AND directory_id <> $2`,
+			user.Username, user.DirectoryID, interfaces.UserInactive)
+		if err != nil {
+			return result, fmt.Errorf("failed to retire the previous %s: %v", user.Username, err)
+		}
+
+		var id int
+		err = tx.QueryRow(ctx, `SELECT id FROM users WHERE directory_id = $1 OR (directory_id IS NULL AND username = $2)
+              ORDER BY directory_id NULLS LAST LIMIT 1`, user.DirectoryID, user.Username).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			_, err = tx.Exec(ctx, `INSERT INTO users (username, user_id, email, status, directory_id, display_name, department, manager)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+				user.Username, interfaces.DirectoryUserID(user.DirectoryID), user.Email, status, user.DirectoryID, user.DisplayName, user.Department, manager)
+			if err != nil {
+				return result, fmt.Errorf("failed to add %s: %v", user.Username, err)
+			}
+			result.Created++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to look up %s: %v", user.Username, err)
+		}
+
+		tag, err := tx.Exec(ctx, `UPDATE users SET username = $2, email = $3, status = $4, directory_id = $5, display_name = $6,
+              department = $7, manager = $8, updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1 AND (username, COALESCE(email, ''), COALESCE(status, ''), COALESCE(directory_id, ''),
+                  COALESCE(display_name, ''), COALESCE(department, ''), COALESCE(manager, ''))
+                  IS DISTINCT FROM ($2, $3, $4, $5, $6, $7, $8)`,
+			id, user.Username, user.Email, status, user.DirectoryID, user.DisplayName, user.Department, manager)
+		if err != nil {
+			return result, fmt.Errorf("failed to update %s: %v", user.Username, err)
+		}
+		result.Updated += int(tag.RowsAffected())
+	}
+
+	// An empty result is far more likely a bad filter than an empty directory
+	if complete && len(seen) > 0 {
+		tag, err := tx.Exec(ctx, `UPDATE users SET status = $2, updated_at = CURRENT_TIMESTAMP
+              WHERE directory_id IS NOT NULL AND NOT (directory_id = ANY($1)) AND status IS DISTINCT FROM $2`,
+			seen, interfaces.UserInactive)
+		if err != nil {
+			return result, fmt.Errorf("failed to deactivate users missing from the directory: %v", err)
+		}
+		result.Deactivated = int(tag.RowsAffected())
+	}
+
+	return result, tx.Commit(ctx)
+}
+
+// managerUsernames maps each synced user's DN, lower case, to their username
+func managerUsernames(users []interfaces.LDAPUser) map[string]string {
+	usernames := make(map[string]string, len(users))
+	for _, user := range users {
+		usernames[strings.ToLower(user.DN)] = user.Username
+	}
+	return usernames
+}
+
+// checkAssignable refuses to give work to a user marked inactive
+func checkAssignable(username string) error {
+	if username == "" {
+		return nil
+	}
+	var status string
+	err := DBPool.QueryRow(context.Background(), `SELECT COALESCE(status, '') FROM users WHERE username = $1`, username).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if status == interfaces.UserInactive {
+		return fmt.Errorf("cannot assign work to %s: %w", username, interfaces.ErrInactiveUser)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package databases
+
+import (
+	// Standard Library
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	// External Imports
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/databases/dbtest"
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// TestPostgresConformance runs the shared store suite against a real server.
+// Set GOAUDIT_TEST_DATABASE_URL to use an existing (disposable!) database;
+// otherwise a throwaway cluster is started when initdb and pg_ctl are found.
+func TestPostgresConformance(t *testing.T) {
+	dsn := startPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", dsn, err)
+	}
+	t.Cleanup(pool.Close)
+
+	previous := DBPool
+	DBPool = pool
+	t.Cleanup(func() { DBPool = previous })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	StartChangeListener(ctx)
+
+	dbtest.RunDatabaseConformance(t, func(t *testing.T) interfaces.DatabaseOperations {
+		_, err := pool.Exec(context.Background(), `DROP SCHEMA public CASCADE; CREATE SCHEMA public;`)
+		if err != nil {
+			t.Fatalf("failed to reset schema: %v", err)
+		}
+		if err := EnsureSchema(); err != nil {
+			t.Fatalf("EnsureSchema: %v", err)
+		}
+		return &DatabaseWrapper{Pool: pool}
+	})
+}
+
+// TestMigrateLegacyUserIDs upgrades tables that still use the old random
+// integer user_id and checks every row ends up with the right owner
+func TestMigrateLegacyUserIDs(t *testing.T) {
+	dsn := startPostgres(t)
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", dsn, err)
+	}
+	t.Cleanup(pool.Close)
+
+	previous := DBPool
+	DBPool = pool
+	t.Cleanup(func() { DBPool = previous })
+
+	exec := func(sql string, args ...interface{}) {
+		t.Helper()
+		if _, err := pool.Exec(context.Background(), sql, args...); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+	exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public;`)
+
+	// Build today's tables, then turn user_id back into the old integer
+	for _, ensure := range []func() error{EnsureUserTableExists, EnsureAuditTableExists, EnsureCredentialsTableExists,
+		EnsureCRMTableExists, EnsureNotesTableExists, EnsureTaskTableExists} {
+		if err := ensure(); err != nil {
+			t.Fatalf("creating tables: %v", err)
+		}
+	}
+	for _, owned := range ownedTables {
+		exec(fmt.Sprintf(`ALTER TABLE %[1]s DROP CONSTRAINT %[1]s_user_id_fkey;
+            ALTER TABLE %[1]s ALTER COLUMN user_id TYPE INTEGER USING 0`, owned.table))
+	}
+	exec(`ALTER TABLE users ALTER COLUMN user_id TYPE INTEGER USING 0`)
+
+	exec(`INSERT INTO users (username, user_id, status) VALUES ('alice', 111, 'Active')`)
+	exec(`INSERT INTO users (username, user_id, status, directory_id) VALUES ('bob', 222, 'Active', 'E12345')`)
+	exec(`INSERT INTO notes (title, user_id, username) VALUES ('current', 111, 'alice'), ('stale', 999, 'bob'),
+        ('deleted owner', 333, 'carol'), ('by id', 222, NULL), ('lost', 444, NULL)`)
+	exec(`INSERT INTO tasks (title, user_id, username) VALUES ('task', 111, 'ALICE')`)
+	exec(`INSERT INTO credentials (user_id, username, master_password, owner) VALUES (5, 'site-login', 'hash', 'bob')`)
+
+	if err := EnsureSchema(); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	if err := EnsureSchema(); err != nil {
+		t.Fatalf("EnsureSchema again: %v", err)
+	}
+
+	owners := func(query string) map[string]string {
+		t.Helper()
+		rows, err := pool.Query(context.Background(), query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		defer rows.Close()
+		found := make(map[string]string)
+		for rows.Next() {
+			var key, owner string
+			if err := rows.Scan(&key, &owner); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			found[key] = owner
+		}
+		return found
+	}
+
+	notes := owners(`SELECT n.title, u.username FROM notes n JOIN users u ON u.user_id = n.user_id`)
+	want := map[string]string{"current": "alice", "stale": "bob", "deleted owner": "carol", "by id": "bob", "lost": unownedUsername}
+	for title, owner := range want {
+		if notes[title] != owner {
+			t.Errorf("note %q belongs to %q, want %q", title, notes[title], owner)
+		}
+	}
+	if tasks := owners(`SELECT t.title, u.username FROM tasks t JOIN users u ON u.user_id = t.user_id`); tasks["task"] != "alice" {
+		t.Errorf("task belongs to %q, want alice", tasks["task"])
+	}
+	if credentials := owners(`SELECT c.username, u.username FROM credentials c JOIN users u ON u.user_id = c.user_id`); credentials["site-login"] != "bob" {
+		t.Errorf("credential belongs to %q, want its owner bob", credentials["site-login"])
+	}
+
+	bob, err := GetUserByAnyID("bob")
+	if err != nil || bob.UserID != interfaces.DirectoryUserID("E12345") {
+		t.Errorf("bob = %+v, %v, want the user_id derived from his directory ID", bob, err)
+	}
+	if _, err := pool.Exec(context.Background(), `INSERT INTO notes (title, user_id) VALUES ('x', $1)`, interfaces.NewUserID()); err == nil {
+		t.Error("a note for an unknown user_id was accepted; the foreign key is missing")
+	}
+}
+
+func startPostgres(t *testing.T) string {
+	t.Helper()
+
+	if dsn := os.Getenv("GOAUDIT_TEST_DATABASE_URL"); dsn != "" {
+		return dsn
+	}
+
+	initdb, pgCtl := findPostgresBinaries()
+	if initdb == "" || pgCtl == "" {
+		t.Skip("initdb and pg_ctl not found; set GOAUDIT_TEST_DATABASE_URL to run against an existing server")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("postgres refuses to run as root; set GOAUDIT_TEST_DATABASE_URL to run against an existing server")
+	}
+
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		t.Fatalf("initdb failed: %v\n%s", err, out)
+	}
+
+	// Unix socket paths are limited to ~100 bytes, too short for most TempDirs
+	socketDir, err := os.MkdirTemp("", "pg")
+	if err != nil {
+		t.Fatalf("failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(socketDir) })
+
+	port := freePort(t)
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, socketDir)
+	out, err = exec.Command(pgCtl, "-D", dataDir, "-l", filepath.Join(dir, "postgres.log"), "-w", "-o", options, "start").CombinedOutput()
+	if err != nil {
+		t.Fatalf("pg_ctl start failed: %v\n%s", err, out)
+	}
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+	})
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+}
+
+// findPostgresBinaries looks on PATH, then in the Debian/Ubuntu versioned directories
+func findPostgresBinaries() (string, string) {
+	initdb, err1 := exec.LookPath("initdb")
+	pgCtl, err2 := exec.LookPath("pg_ctl")
+	if err1 == nil && err2 == nil {
+		return initdb, pgCtl
+	}
+
+	dirs, _ := filepath.Glob("/usr/lib/postgresql/*/bin")
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		initdb = filepath.Join(dir, "initdb")
+		pgCtl = filepath.Join(dir, "pg_ctl")
+		if _, err := os.Stat(initdb); err != nil {
+			continue
+		}
+		if _, err := os.Stat(pgCtl); err != nil {
+			continue
+		}
+		return initdb, pgCtl
+	}
+	return "", ""
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
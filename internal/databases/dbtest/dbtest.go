@@ -0,0 +1,1715 @@
+// Package dbtest is the conformance suite every interfaces.DatabaseOperations
+// implementation must pass. The Postgres store and the in-memory fake both run
+// it so the two cannot drift apart.
+package dbtest
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// NewStore returns an empty store for a single test
+type NewStore func(t *testing.T) interfaces.DatabaseOperations
+
+// RunDatabaseConformance runs every check against fresh stores from newStore.
+// Subtests run sequentially so implementations may share one database.
+func RunDatabaseConformance(t *testing.T, newStore NewStore) {
+	tests := []struct {
+		name string
+		run  func(*testing.T, interfaces.DatabaseOperations)
+	}{
+		{"Users", testUsers},
+		{"UserIdentity", testUserIdentity},
+		{"DirectorySync", testDirectorySync},
+		{"LocalPasswords", testLocalPasswords},
+		{"TwoFactor", testTwoFactor},
+		{"OIDCSubjects", testOIDCSubjects},
+		{"UserRecords", testUserRecords},
+		{"RecordBrowser", testRecordBrowser},
+		{"RecordReassignment", testRecordReassignment},
+		{"LoginAttempts", testLoginAttempts},
+		{"ChangeHistory", testChangeHistory},
+		{"Notes", testNotes},
+		{"NoteConflicts", testNoteConflicts},
+		{"Tasks", testTasks},
+		{"TaskConflicts", testTaskConflicts},
+		{"TaskAssignment", testTaskAssignment},
+		{"Audits", testAudits},
+		{"AuditConflicts", testAuditConflicts},
+		{"AuditStatus", testAuditStatus},
+		{"AuditAssignment", testAuditAssignment},
+		{"Findings", testFindings},
+		{"Evidence", testEvidence},
+		{"Templates", testTemplates},
+		{"Controls", testControls},
+		{"Schedules", testSchedules},
+		{"CRM", testCRM},
+		{"CRMConflicts", testCRMConflicts},
+		{"Credentials", testCredentials},
+		{"ChangeEvents", testChangeEvents},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newStore(t))
+		})
+	}
+}
+
+func testUsers(t *testing.T, db interfaces.DatabaseOperations) {
+	alice, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	if alice.Username != "alice" || alice.UserID == "" {
+		t.Fatalf("GetOrCreateUser returned %+v", alice)
+	}
+
+	again, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser again: %v", err)
+	}
+	if again.ID != alice.ID || again.UserID != alice.UserID {
+		t.Errorf("GetOrCreateUser is not stable: first %+v, then %+v", alice, again)
+	}
+
+	created, err := db.Create("alice")
+	if err != nil {
+		t.Fatalf("Create existing user: %v", err)
+	}
+	if created.UserID != alice.UserID {
+		t.Errorf("Create changed the user_id of an existing user: %s != %s", created.UserID, alice.UserID)
+	}
+
+	bob, err := db.Create("bob")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if bob.Status != "Active" {
+		t.Errorf("new user status = %q, want Active", bob.Status)
+	}
+
+	if _, _, err := db.GetUsers("nobody"); err == nil {
+		t.Error("GetUsers for an unknown user returned no error")
+	}
+	users, _, err := db.GetUsers("bob")
+	if err != nil || len(users) != 1 || users[0].UserID != bob.UserID {
+		t.Errorf("GetUsers(bob) = %+v, %v", users, err)
+	}
+
+	all, err := db.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(all) != 2 || all[0].Username != "alice" || all[1].Username != "bob" {
+		t.Errorf("GetAll = %+v, want alice and bob", all)
+	}
+
+	bob.Email = "bob@example.com"
+	if _, err := db.Update(bob); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	users, _, _ = db.GetUsers("bob")
+	if len(users) != 1 || users[0].Email != "bob@example.com" {
+		t.Errorf("Update did not persist email: %+v", users)
+	}
+
+	if err := db.Delete(bob); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := db.GetUsers("bob"); err == nil {
+		t.Error("deleted user is still returned")
+	}
+}
+
+func testUserIdentity(t *testing.T, db interfaces.DatabaseOperations) {
+	alice, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	bob, err := db.GetOrCreateUser("bob")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	if alice.UserID == bob.UserID {
+		t.Errorf("alice and bob share user_id %s", alice.UserID)
+	}
+
+	// Owners are linked by user_id, and added if they are new
+	note := mustCreateNote(t, db, "Plan", "", "alice", false)
+	task := mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "carol"})
+	carol, err := db.GetOrCreateUser("carol")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser(carol): %v", err)
+	}
+	if note.UserID != alice.UserID || task.UserID != carol.UserID {
+		t.Errorf("note owner %s, task owner %s; want %s and %s", note.UserID, task.UserID, alice.UserID, carol.UserID)
+	}
+	if _, err := db.CreateTask(interfaces.Tasks{Title: "Orphan"}); err == nil {
+		t.Error("CreateTask without an owner returned no error")
+	}
+
+	alice.Email = "alice@example.com"
+	updated, err := db.Update(alice)
+	if err != nil || updated.UserID != alice.UserID {
+		t.Errorf("Update = %+v, %v, want user_id %s kept", updated, err, alice.UserID)
+	}
+
+	if err := db.Delete(alice); !errors.Is(err, interfaces.ErrUserInUse) {
+		t.Errorf("Delete of a note owner: err = %v, want ErrUserInUse", err)
+	}
+	if err := db.Delete(bob); err != nil {
+		t.Errorf("Delete of a user without records: %v", err)
+	}
+
+	// Directory users take their user_id from the directory
+	result, err := db.SyncDirectoryUsers([]interfaces.LDAPUser{
+		{DN: "CN=Dave,DC=example,DC=com", Username: "dave", DirectoryID: "6F1B3C1E-0000-4000-8000-000000000001"},
+		{DN: "CN=Erin,DC=example,DC=com", Username: "erin", DirectoryID: "E12345"},
+	}, false)
+	if err != nil || result.Created != 2 {
+		t.Fatalf("SyncDirectoryUsers = %+v, %v", result, err)
+	}
+	for username, directoryID := range map[string]string{"dave": "6F1B3C1E-0000-4000-8000-000000000001", "erin": "E12345"} {
+		user, err := db.GetOrCreateUser(username)
+		if err != nil || user.UserID != interfaces.DirectoryUserID(directoryID) {
+			t.Errorf("synced %s = %+v, %v, want user_id %s", username, user, err, interfaces.DirectoryUserID(directoryID))
+		}
+	}
+}
+
+func testDirectorySync(t *testing.T, db interfaces.DatabaseOperations) {
+	existing, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	users := []interfaces.LDAPUser{
+		{DN: "CN=Alice,OU=Staff,DC=example,DC=com", Username: "alice", DisplayName: "Alice Smith", Email: "alice@example.com", Department: "Audit", DirectoryID: "id-alice"},
+		{DN: "CN=Bob,OU=Staff,DC=example,DC=com", Username: "bob", DisplayName: "Bob Jones", ManagerDN: "cn=alice,ou=staff,dc=example,dc=com", DirectoryID: "id-bob"},
+		{DN: "CN=Carol,OU=Staff,DC=example,DC=com", Username: "carol", DirectoryID: "id-carol", Disabled: true},
+	}
+	result, err := db.SyncDirectoryUsers(users, true)
+	if err != nil {
+		t.Fatalf("SyncDirectoryUsers: %v", err)
+	}
+	if result != (interfaces.SyncResult{Created: 2, Updated: 1}) {
+		t.Errorf("first sync = %+v, want 2 created and 1 updated", result)
+	}
+
+	alice, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser(alice): %v", err)
+	}
+	if alice.ID != existing.ID || alice.DirectoryID != "id-alice" || alice.DisplayName != "Alice Smith" || alice.Department != "Audit" {
+		t.Errorf("synced alice = %+v, want the existing row linked to the directory", alice)
+	}
+	bob, err := db.GetOrCreateUser("bob")
+	if err != nil || bob.Manager != "alice" || bob.Status != interfaces.UserActive {
+		t.Errorf("synced bob = %+v, %v, want active and managed by alice", bob, err)
+	}
+	carol, err := db.GetOrCreateUser("carol")
+	if err != nil || carol.Status != interfaces.UserInactive {
+		t.Errorf("synced carol = %+v, %v, want inactive", carol, err)
+	}
+
+	if result, err := db.SyncDirectoryUsers(users, true); err != nil || result != (interfaces.SyncResult{}) {
+		t.Errorf("unchanged sync = %+v, %v, want no changes", result, err)
+	}
+
+	// Bob is renamed and Alice has left
+	users = []interfaces.LDAPUser{
+		{DN: "CN=Robert,OU=Staff,DC=example,DC=com", Username: "robert", DirectoryID: "id-bob"},
+		users[2],
+	}
+	result, err = db.SyncDirectoryUsers(users, true)
+	if err != nil {
+		t.Fatalf("SyncDirectoryUsers: %v", err)
+	}
+	if result != (interfaces.SyncResult{Updated: 1, Deactivated: 1}) {
+		t.Errorf("second sync = %+v, want 1 updated and 1 deactivated", result)
+	}
+	robert, err := db.GetOrCreateUser("robert")
+	if err != nil || robert.ID != bob.ID {
+		t.Errorf("renamed user = %+v, %v, want bob's row %d", robert, err, bob.ID)
+	}
+
+	_, err = db.CreateTask(interfaces.Tasks{Title: "Review", Username: "robert", Assignee: "carol"})
+	if !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("CreateTask for inactive user: err = %v, want ErrInactiveUser", err)
+	}
+	task := mustCreateTask(t, db, interfaces.Tasks{Title: "Plan", Username: "robert", Assignee: "robert"})
+	task.Assignee = "alice"
+	if _, err := db.UpdateTask(task); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("UpdateTask to inactive user: err = %v, want ErrInactiveUser", err)
+	}
+}
+
+func testLocalPasswords(t *testing.T, db interfaces.DatabaseOperations) {
+	if err := db.SetPassword("nobody", "hash", false); err == nil {
+		t.Error("SetPassword for a missing user returned no error")
+	}
+	if _, err := db.GetPasswordHash("nobody"); err == nil {
+		t.Error("GetPasswordHash for a missing user returned no error")
+	}
+
+	alice, err := db.GetOrCreateUser("alice")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	if alice.Local {
+		t.Error("new user is local before a password is set")
+	}
+	if hash, err := db.GetPasswordHash("alice"); err != nil || hash != "" {
+		t.Errorf("GetPasswordHash without a password = %q, %v", hash, err)
+	}
+
+	if err := db.SetPassword("alice", "hash-1", true); err != nil {
+		t.Fatalf("SetPassword: %v", err)
+	}
+	if hash, err := db.GetPasswordHash("alice"); err != nil || hash != "hash-1" {
+		t.Errorf("GetPasswordHash = %q, %v, want hash-1", hash, err)
+	}
+
+	// Role is saved by Update, which leaves the password alone
+	alice.Role = "auditor"
+	updated, err := db.Update(alice)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !updated.Local || !updated.MustChangePassword || updated.Role != "auditor" {
+		t.Errorf("Update = %+v, want a local auditor who must change their password", updated)
+	}
+	if hash, _ := db.GetPasswordHash("alice"); hash != "hash-1" {
+		t.Errorf("Update changed the password hash to %q", hash)
+	}
+
+	if err := db.SetPassword("alice", "hash-2", false); err != nil {
+		t.Fatalf("SetPassword: %v", err)
+	}
+	users, _, err := db.GetUsers("alice")
+	if err != nil || len(users) != 1 || !users[0].Local || users[0].MustChangePassword || users[0].Role != "auditor" {
+		t.Errorf("GetUsers after a password change = %+v, %v", users, err)
+	}
+
+	if err := db.SetPassword("alice", "", true); err != nil {
+		t.Fatalf("SetPassword to remove: %v", err)
+	}
+	users, _, _ = db.GetUsers("alice")
+	if len(users) != 1 || users[0].Local || users[0].MustChangePassword {
+		t.Errorf("GetUsers after removing the password = %+v", users)
+	}
+}
+
+func testTwoFactor(t *testing.T, db interfaces.DatabaseOperations) {
+	if err := db.SetTwoFactor("nobody", interfaces.TwoFactor{Secret: "s"}); err == nil {
+		t.Error("SetTwoFactor for a missing user returned no error")
+	}
+	if _, err := db.GetOrCreateUser("alice"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	if twoFactor, err := db.GetTwoFactor("alice"); err != nil || twoFactor.Secret != "" || len(twoFactor.BackupCodes) != 0 {
+		t.Errorf("GetTwoFactor before enrolling = %+v, %v", twoFactor, err)
+	}
+
+	err := db.SetTwoFactor("alice", interfaces.TwoFactor{Secret: "encrypted", BackupCodes: []string{"code-1", "code-2"}})
+	if err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+	users, _, _ := db.GetUsers("alice")
+	if len(users) != 1 || !users[0].TwoFactor {
+		t.Errorf("GetUsers after enrolling = %+v, want TwoFactor set", users)
+	}
+
+	// Each time step and backup code is accepted once
+	for _, step := range []struct {
+		step int64
+		want bool
+	}{{100, true}, {100, false}, {99, false}, {101, true}} {
+		if ok, err := db.UseTOTPStep("alice", step.step); err != nil || ok != step.want {
+			t.Errorf("UseTOTPStep(%d) = %v, %v, want %v", step.step, ok, err, step.want)
+		}
+	}
+	if ok, err := db.UseBackupCode("alice", "code-1"); err != nil || !ok {
+		t.Errorf("UseBackupCode = %v, %v, want accepted", ok, err)
+	}
+	if ok, _ := db.UseBackupCode("alice", "code-1"); ok {
+		t.Error("a backup code was accepted twice")
+	}
+	if ok, _ := db.UseBackupCode("alice", "code-3"); ok {
+		t.Error("an unknown backup code was accepted")
+	}
+	twoFactor, err := db.GetTwoFactor("alice")
+	if err != nil || twoFactor.Secret != "encrypted" || len(twoFactor.BackupCodes) != 1 || twoFactor.BackupCodes[0] != "code-2" {
+		t.Errorf("GetTwoFactor = %+v, %v, want the secret and code-2", twoFactor, err)
+	}
+
+	if err := db.SetTwoFactor("alice", interfaces.TwoFactor{}); err != nil {
+		t.Fatalf("SetTwoFactor to remove: %v", err)
+	}
+	if twoFactor, _ := db.GetTwoFactor("alice"); twoFactor.Secret != "" || len(twoFactor.BackupCodes) != 0 {
+		t.Errorf("GetTwoFactor after removing = %+v", twoFactor)
+	}
+}
+
+func testOIDCSubjects(t *testing.T, db interfaces.DatabaseOperations) {
+	const subject = "https://login.example.com|248289761001"
+	if users, err := db.GetUserByOIDCSubject(subject); err != nil || len(users) != 0 {
+		t.Errorf("GetUserByOIDCSubject before linking = %+v, %v", users, err)
+	}
+	if err := db.SetOIDCSubject("nobody", subject); err == nil {
+		t.Error("SetOIDCSubject for a missing user returned no error")
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser: %v", err)
+		}
+	}
+	if err := db.SetOIDCSubject("alice", subject); err != nil {
+		t.Fatalf("SetOIDCSubject: %v", err)
+	}
+	users, err := db.GetUserByOIDCSubject(subject)
+	if err != nil || len(users) != 1 || users[0].Username != "alice" {
+		t.Errorf("GetUserByOIDCSubject = %+v, %v, want alice", users, err)
+	}
+	if err := db.SetOIDCSubject("bob", subject); err == nil {
+		t.Error("two users were linked to the same subject")
+	}
+	if err := db.SetOIDCSubject("alice", "https://login.example.com|other"); err == nil {
+		t.Error("a linked user was linked to a second subject")
+	}
+	if err := db.SetOIDCSubject("alice", subject); err != nil {
+		t.Errorf("linking the same subject again: %v", err)
+	}
+	if err := db.SetOIDCSubject("alice", ""); err != nil {
+		t.Fatalf("SetOIDCSubject to unlink: %v", err)
+	}
+	if users, _ := db.GetUserByOIDCSubject(subject); len(users) != 0 {
+		t.Errorf("GetUserByOIDCSubject after unlinking = %+v", users)
+	}
+	if err := db.SetOIDCSubject("bob", subject); err != nil {
+		t.Errorf("linking the subject after it was freed: %v", err)
+	}
+}
+
+func testUserRecords(t *testing.T, db interfaces.DatabaseOperations) {
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser: %v", err)
+		}
+	}
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	if err := db.RecordLogin("alice", at); err != nil {
+		t.Fatalf("RecordLogin: %v", err)
+	}
+	if users, _, err := db.GetUsers("alice"); err != nil || !users[0].LastLogin.Equal(at) {
+		t.Errorf("last login = %+v, %v, want %s", users, err, at)
+	}
+
+	mustCreateNote(t, db, "Handover", "", "alice", false)
+	mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "alice", Assignee: "alice"})
+	mustCreateTask(t, db, interfaces.Tasks{Title: "Follow up", Username: "bob", Assignee: "alice"})
+	audit, err := db.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "bob", AssignedUser: "alice",
+		AdditionalUsers: []string{"alice", "carol"}})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	if _, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "Stale accounts", Severity: interfaces.SeverityMedium,
+		Owner: "alice", Status: interfaces.FindingOpen}); err != nil {
+		t.Fatalf("CreateFinding: %v", err)
+	}
+	if _, err := db.CreateCRMEntry(interfaces.CRM{Name: "Acme", Username: "alice"}); err != nil {
+		t.Fatalf("CreateCRMEntry: %v", err)
+	}
+
+	want := interfaces.UserRecords{Notes: 1, Tasks: 1, AssignedTasks: 2, AssignedAudits: 1, Findings: 1, CRMEntries: 1}
+	if records, err := db.GetUserRecords("alice"); err != nil || records != want {
+		t.Errorf("GetUserRecords(alice) = %+v, %v, want %+v", records, err, want)
+	}
+	alice, _, _ := db.GetUsers("alice")
+	if err := db.Delete(alice[0]); !errors.Is(err, interfaces.ErrUserInUse) {
+		t.Errorf("Delete of a user with assigned work = %v, want ErrUserInUse", err)
+	}
+
+	carol, _, _ := db.GetUsers("carol")
+	carol[0].Status = interfaces.UserInactive
+	if _, err := db.Update(carol[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := db.TransferUserRecords("alice", "carol"); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("transfer to an inactive user = %v, want ErrInactiveUser", err)
+	}
+	if _, err := db.TransferUserRecords("alice", "alice"); err == nil {
+		t.Error("TransferUserRecords to the same user returned no error")
+	}
+
+	moved, err := db.TransferUserRecords("alice", "bob")
+	if err != nil || moved != want {
+		t.Fatalf("TransferUserRecords = %+v, %v, want %+v", moved, err, want)
+	}
+	if records, _ := db.GetUserRecords("alice"); records.Total() != 0 {
+		t.Errorf("alice still has %s", records)
+	}
+	audits, _, err := db.GetAudits("bob")
+	if err != nil || len(audits) != 1 || audits[0].AssignedUser != "bob" || len(audits[0].AdditionalUsers) != 2 {
+		t.Errorf("transferred audit = %+v, %v, want assigned to bob with bob and carol", audits, err)
+	}
+	if err := db.Delete(alice[0]); err != nil {
+		t.Errorf("Delete after the transfer: %v", err)
+	}
+}
+
+func testRecordBrowser(t *testing.T, db interfaces.DatabaseOperations) {
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser: %v", err)
+		}
+	}
+	note := mustCreateNote(t, db, "Handover", "", "alice", false)
+	first := mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "alice", Assignee: "alice"})
+	second := mustCreateTask(t, db, interfaces.Tasks{Title: "Follow up", Username: "bob"})
+	audit, err := db.CreateAudit(interfaces.Audits{Action: "Inventory", Firm: "Acme", Username: "bob", AdditionalUsers: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	entry, err := db.CreateCRMEntry(interfaces.CRM{Name: "Acme", Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateCRMEntry: %v", err)
+	}
+
+	tasks, err := db.ListRecords(interfaces.RecordTasks)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("ListRecords(tasks) = %+v, %v", tasks, err)
+	}
+	for _, task := range tasks {
+		if task.Kind != interfaces.RecordTasks || task.CreatedAt.IsZero() ||
+			(task.ID == first.ID && (task.Owner != "alice" || task.Assignee != "alice" || task.Title != "Review")) {
+			t.Errorf("listed task = %+v", task)
+		}
+	}
+	audits, err := db.ListRecords(interfaces.RecordAudits)
+	if err != nil || len(audits) != 1 || audits[0].Title != "Inventory - Acme" || audits[0].Owner != "bob" {
+		t.Errorf("ListRecords(audits) = %+v, %v", audits, err)
+	}
+	if _, err := db.ListRecords("users"); err == nil {
+		t.Error("ListRecords of an unknown kind returned no error")
+	}
+
+	if n, err := db.CompleteRecords(interfaces.RecordTasks, []int{first.ID, second.ID}); err != nil || n != 2 {
+		t.Errorf("CompleteRecords(tasks) = %d, %v, want 2", n, err)
+	}
+	if n, err := db.CompleteRecords(interfaces.RecordTasks, []int{first.ID}); err != nil || n != 0 {
+		t.Errorf("CompleteRecords of a completed task = %d, %v, want 0", n, err)
+	}
+	if _, err := db.CompleteRecords(interfaces.RecordAudits, []int{audit.ID}); err == nil {
+		t.Error("CompleteRecords of audits, which close through their workflow, returned no error")
+	}
+	if _, err := db.CompleteRecords(interfaces.RecordNotes, []int{note.ID}); err == nil {
+		t.Error("CompleteRecords of notes returned no error")
+	}
+
+	carol, _, _ := db.GetUsers("carol")
+	carol[0].Status = interfaces.UserInactive
+	if _, err := db.Update(carol[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := db.ReassignRecords(interfaces.RecordTasks, []int{first.ID}, "carol"); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("reassign to an inactive user = %v, want ErrInactiveUser", err)
+	}
+	if n, err := db.ReassignRecords(interfaces.RecordTasks, []int{first.ID}, "bob"); err != nil || n != 1 {
+		t.Errorf("ReassignRecords(tasks) = %d, %v, want 1", n, err)
+	}
+	if got, err := db.GetTask(first.ID); err != nil || got.Assignee != "bob" || got.Username != "alice" {
+		t.Errorf("reassigned task = %+v, %v, want owned by alice and assigned to bob", got, err)
+	}
+	if n, err := db.ReassignRecords(interfaces.RecordAudits, []int{audit.ID}, "alice"); err != nil || n != 1 {
+		t.Errorf("ReassignRecords(audits) = %d, %v, want 1", n, err)
+	}
+	if got, err := db.GetAudit(audit.ID); err != nil || got.AssignedUser != "alice" || len(got.AdditionalUsers) != 0 {
+		t.Errorf("reassigned audit = %+v, %v, want assigned to alice alone", got, err)
+	}
+	if n, err := db.ReassignRecords(interfaces.RecordCRM, []int{entry.ID}, "bob"); err != nil || n != 1 {
+		t.Errorf("ReassignRecords(crm) = %d, %v, want 1", n, err)
+	}
+	if records, _ := db.GetUserRecords("bob"); records.CRMEntries != 1 {
+		t.Errorf("bob has %s after the CRM reassignment", records)
+	}
+
+	if n, err := db.DeleteRecords(interfaces.RecordTasks, []int{first.ID, second.ID, 0}); err != nil || n != 2 {
+		t.Errorf("DeleteRecords(tasks) = %d, %v, want 2", n, err)
+	}
+	if tasks, _ := db.ListRecords(interfaces.RecordTasks); len(tasks) != 0 {
+		t.Errorf("tasks left after DeleteRecords: %+v", tasks)
+	}
+	if n, err := db.DeleteRecords(interfaces.RecordNotes, []int{note.ID}); err != nil || n != 1 {
+		t.Errorf("DeleteRecords(notes) = %d, %v, want 1", n, err)
+	}
+}
+
+// testRecordReassignment moves several tasks and audits to a new assignee at
+// once, leaving their owners alone
+func testRecordReassignment(t *testing.T, db interfaces.DatabaseOperations) {
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser: %v", err)
+		}
+	}
+	tasks := []interfaces.Tasks{
+		mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "alice", Assignee: "carol"}),
+		mustCreateTask(t, db, interfaces.Tasks{Title: "Follow up", Username: "alice"}),
+	}
+	if n, err := db.ReassignRecords(interfaces.RecordTasks, []int{tasks[0].ID, tasks[1].ID}, "bob"); err != nil || n != 2 {
+		t.Fatalf("ReassignRecords(tasks) = %d, %v, want 2", n, err)
+	}
+	for _, task := range tasks {
+		got, err := db.GetTask(task.ID)
+		if err != nil || got.Assignee != "bob" || got.Username != "alice" || got.Version != task.Version+1 {
+			t.Errorf("reassigned task = %+v, %v, want owned by alice, assigned to bob, version %d", got, err, task.Version+1)
+		}
+	}
+
+	audits := []interfaces.Audits{
+		mustCreateAudit(t, db, interfaces.Audits{Action: "Inventory", Username: "alice", AssignedUser: "carol",
+			AdditionalUsers: []string{"bob", "alice"}}),
+		mustCreateAudit(t, db, interfaces.Audits{Action: "Payroll", Username: "alice"}),
+	}
+	if n, err := db.ReassignRecords(interfaces.RecordAudits, []int{audits[0].ID, audits[1].ID}, "bob"); err != nil || n != 2 {
+		t.Fatalf("ReassignRecords(audits) = %d, %v, want 2", n, err)
+	}
+	for _, audit := range audits {
+		got, err := db.GetAudit(audit.ID)
+		if err != nil || got.AssignedUser != "bob" || got.Username != "alice" || got.Version != audit.Version+1 {
+			t.Errorf("reassigned audit = %+v, %v, want owned by alice, assigned to bob, version %d", got, err, audit.Version+1)
+		}
+		if audit.ID == audits[0].ID && (len(got.AdditionalUsers) != 1 || got.AdditionalUsers[0] != "alice") {
+			t.Errorf("additional users after reassignment = %v, want [alice]", got.AdditionalUsers)
+		}
+	}
+
+	if _, err := db.ReassignRecords(interfaces.RecordAudits, []int{audits[0].ID}, "nobody"); err == nil {
+		t.Error("reassigning to an unknown user returned no error")
+	}
+}
+
+func testLoginAttempts(t *testing.T, db interfaces.DatabaseOperations) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if attempts, err := db.GetLoginAttempts(interfaces.AttemptsUser, "alice"); err != nil || attempts.Failures != 0 {
+		t.Errorf("GetLoginAttempts before any failure = %+v, %v", attempts, err)
+	}
+	for i := 1; i <= 3; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		attempts, err := db.RecordLoginFailure(interfaces.AttemptsUser, "alice", at, start)
+		if err != nil || attempts.Failures != i || !attempts.LastFailure.Equal(at) {
+			t.Fatalf("RecordLoginFailure %d = %+v, %v", i, attempts, err)
+		}
+	}
+	if _, err := db.RecordLoginFailure(interfaces.AttemptsHost, "alice", start, start); err != nil {
+		t.Fatalf("RecordLoginFailure for a host: %v", err)
+	}
+	if attempts, _ := db.GetLoginAttempts(interfaces.AttemptsUser, "alice"); attempts.Failures != 3 {
+		t.Errorf("failures = %d, want 3 counted apart from the host of the same name", attempts.Failures)
+	}
+
+	// Failures before since are forgotten
+	later := start.Add(time.Hour)
+	attempts, err := db.RecordLoginFailure(interfaces.AttemptsUser, "alice", later, later.Add(-15*time.Minute))
+	if err != nil || attempts.Failures != 1 {
+		t.Errorf("RecordLoginFailure after the window = %+v, %v, want one failure", attempts, err)
+	}
+
+	list, err := db.ListLoginAttempts()
+	if err != nil || len(list) != 2 || list[0].Scope != interfaces.AttemptsUser || list[0].Name != "alice" {
+		t.Errorf("ListLoginAttempts = %+v, %v, want alice's count first", list, err)
+	}
+	if err := db.ClearLoginFailures(interfaces.AttemptsUser, "alice"); err != nil {
+		t.Fatalf("ClearLoginFailures: %v", err)
+	}
+	if attempts, _ := db.GetLoginAttempts(interfaces.AttemptsUser, "alice"); attempts.Failures != 0 {
+		t.Errorf("failures after clearing = %d", attempts.Failures)
+	}
+	if list, _ := db.ListLoginAttempts(); len(list) != 1 {
+		t.Errorf("ListLoginAttempts after clearing = %+v, want only the host", list)
+	}
+}
+
+func testChangeHistory(t *testing.T, db interfaces.DatabaseOperations) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, action := range []string{"login_failed", "login_locked", "login_unlocked"} {
+		entry, err := db.AddChangeHistory(interfaces.ChangeHistory{
+			At: start.Add(time.Duration(i) * time.Minute), Actor: "admin", Host: "pc-1", Action: action, Subject: "alice", Detail: "detail",
+		})
+		if err != nil || entry.ID == 0 {
+			t.Fatalf("AddChangeHistory = %+v, %v", entry, err)
+		}
+	}
+	entries, err := db.GetChangeHistory(2)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("GetChangeHistory = %+v, %v, want two entries", entries, err)
+	}
+	first := entries[0]
+	if first.Action != "login_unlocked" || entries[1].Action != "login_locked" {
+		t.Errorf("GetChangeHistory = %+v, want the newest first", entries)
+	}
+	if first.Actor != "admin" || first.Host != "pc-1" || first.Subject != "alice" || first.Detail != "detail" || !first.At.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("entry = %+v, fields were not kept", first)
+	}
+}
+
+func testNotes(t *testing.T, db interfaces.DatabaseOperations) {
+	private := mustCreateNote(t, db, "Alice budget", "numbers", "alice", false)
+	shared := mustCreateNote(t, db, "Team plan", "the BUDGET for Q3", "bob", true)
+	mustCreateNote(t, db, "Bob diary", "secret", "bob", false)
+
+	if private.Author != "alice" || private.Username != "alice" || private.Version != 1 {
+		t.Errorf("CreateNote returned %+v", private)
+	}
+
+	notes, _, err := db.GetNotes("alice")
+	if err != nil {
+		t.Fatalf("GetNotes: %v", err)
+	}
+	if !sameIDs(noteIDs(notes), private.ID, shared.ID) {
+		t.Errorf("GetNotes(alice) = %v, want own and open notes %d, %d", noteIDs(notes), private.ID, shared.ID)
+	}
+	for _, note := range notes {
+		if note.ID == shared.ID && (note.Username != "bob" || note.Author != "bob") {
+			t.Errorf("open note owner = %q author = %q, want bob", note.Username, note.Author)
+		}
+	}
+
+	notes, _, _ = db.GetNotes("ALICE")
+	if !sameIDs(noteIDs(notes), private.ID, shared.ID) {
+		t.Errorf("GetNotes is case sensitive: %v", noteIDs(notes))
+	}
+
+	notes, _, _ = db.GetNotes("carol")
+	if !sameIDs(noteIDs(notes), shared.ID) {
+		t.Errorf("GetNotes(carol) = %v, want only the open note", noteIDs(notes))
+	}
+
+	found, _, err := db.SearchNotes("budget", "alice")
+	if err != nil {
+		t.Fatalf("SearchNotes: %v", err)
+	}
+	if !sameIDs(noteIDs(found), private.ID, shared.ID) {
+		t.Errorf("SearchNotes(budget) = %v", noteIDs(found))
+	}
+	found, _, _ = db.SearchNotes("secret", "alice")
+	if len(found) != 0 {
+		t.Errorf("SearchNotes returned another user's private note: %v", noteIDs(found))
+	}
+
+	note, err := db.GetNote(private.ID)
+	if err != nil {
+		t.Fatalf("GetNote: %v", err)
+	}
+	if note.Title != "Alice budget" || note.Content != "numbers" || note.Username != "alice" {
+		t.Errorf("GetNote = %+v", note)
+	}
+
+	if err := db.DeleteNote(private.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if _, err := db.GetNote(private.ID); err == nil {
+		t.Error("deleted note is still returned")
+	}
+}
+
+func testNoteConflicts(t *testing.T, db interfaces.DatabaseOperations) {
+	note := mustCreateNote(t, db, "Draft", "v1", "alice", true)
+
+	mine := note
+	theirs := note
+
+	theirs.Content = "edited by bob"
+	theirs, err := db.UpdateNote(theirs)
+	if err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if theirs.Version != note.Version+1 {
+		t.Errorf("version after update = %d, want %d", theirs.Version, note.Version+1)
+	}
+
+	mine.Content = "edited by alice"
+	_, err = db.UpdateNote(mine)
+	current := expectConflict(t, err)
+	if serverCopy, ok := current.(interfaces.Note); !ok || serverCopy.Content != "edited by bob" || serverCopy.Version != theirs.Version {
+		t.Errorf("conflict current = %+v, want bob's edit", current)
+	}
+
+	mine.Version = theirs.Version
+	saved, err := db.UpdateNote(mine)
+	if err != nil {
+		t.Fatalf("UpdateNote after merge: %v", err)
+	}
+	stored, _ := db.GetNote(note.ID)
+	if stored.Content != "edited by alice" || stored.Version != saved.Version {
+		t.Errorf("stored note = %+v, want alice's edit at version %d", stored, saved.Version)
+	}
+
+	missing := note
+	missing.ID = note.ID + 1000
+	_, err = db.UpdateNote(missing)
+	if err == nil || errors.Is(err, interfaces.ErrConflict) {
+		t.Errorf("updating a missing note returned %v, want a not found error", err)
+	}
+}
+
+func testTasks(t *testing.T, db interfaces.DatabaseOperations) {
+	now := time.Now().Truncate(time.Second)
+	later := mustCreateTask(t, db, interfaces.Tasks{Title: "Later", Username: "alice", DueDate: now.Add(48 * time.Hour), Priority: 2})
+	sooner := mustCreateTask(t, db, interfaces.Tasks{Title: "Sooner", Username: "alice", DueDate: now.Add(24 * time.Hour), Priority: 1})
+	mustCreateTask(t, db, interfaces.Tasks{Title: "Bob's", Username: "bob", DueDate: now})
+
+	tasks, _, err := db.GetTasks("alice")
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].ID != sooner.ID || tasks[1].ID != later.ID {
+		t.Errorf("GetTasks(alice) = %+v, want sooner then later", tasks)
+	}
+
+	task, err := db.GetTask(later.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if task.Title != "Later" || task.Priority != 2 || !task.DueDate.Equal(later.DueDate) {
+		t.Errorf("GetTask = %+v", task)
+	}
+
+	if err := db.DeleteTask(later.ID, "bob"); err != nil {
+		t.Fatalf("DeleteTask by another user: %v", err)
+	}
+	if _, err := db.GetTask(later.ID); err != nil {
+		t.Error("DeleteTask removed a task owned by someone else")
+	}
+	if err := db.DeleteTask(later.ID, "alice"); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := db.GetTask(later.ID); err == nil {
+		t.Error("deleted task is still returned")
+	}
+}
+
+func testTaskConflicts(t *testing.T, db interfaces.DatabaseOperations) {
+	task := mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "alice", DueDate: time.Now()})
+
+	theirs := task
+	theirs.Status = "In Progress"
+	theirs, err := db.UpdateTask(theirs)
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+
+	mine := task
+	mine.Completed = true
+	_, err = db.UpdateTask(mine)
+	current := expectConflict(t, err)
+	if serverCopy, ok := current.(interfaces.Tasks); !ok || serverCopy.Status != "In Progress" {
+		t.Errorf("conflict current = %+v, want the in progress task", current)
+	}
+
+	theirs.Completed = true
+	if _, err := db.UpdateTask(theirs); err != nil {
+		t.Errorf("UpdateTask with the current version: %v", err)
+	}
+}
+
+func testTaskAssignment(t *testing.T, db interfaces.DatabaseOperations) {
+	now := time.Now().Truncate(time.Second)
+	review := mustCreateTask(t, db, interfaces.Tasks{Title: "Review", Username: "manager", Assignee: "bob", DueDate: now})
+	mustCreateTask(t, db, interfaces.Tasks{Title: "Plan", Username: "manager", DueDate: now})
+	mustCreateTask(t, db, interfaces.Tasks{Title: "Own", Username: "carol", Assignee: "carol", DueDate: now.Add(time.Hour)})
+
+	tasks, _, err := db.GetTasks("bob")
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != review.ID || tasks[0].Assignee != "bob" {
+		t.Errorf("GetTasks(bob) = %+v, want the task assigned to him", tasks)
+	}
+
+	tasks, _, err = db.GetAssignedTasks([]string{"bob", "carol", "dave"})
+	if err != nil {
+		t.Fatalf("GetAssignedTasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Assignee != "bob" || tasks[1].Assignee != "carol" {
+		t.Errorf("GetAssignedTasks = %+v, want bob's then carol's", tasks)
+	}
+
+	review.Assignee = "carol"
+	if _, err := db.UpdateTask(review); err != nil {
+		t.Fatalf("UpdateTask reassigning: %v", err)
+	}
+	stored, err := db.GetTask(review.ID)
+	if err != nil || stored.Assignee != "carol" {
+		t.Errorf("reassigned task = %+v, %v", stored, err)
+	}
+	if tasks, _, _ := db.GetAssignedTasks([]string{"bob"}); len(tasks) != 0 {
+		t.Errorf("bob still has %+v after reassignment", tasks)
+	}
+}
+
+func testAudits(t *testing.T, db interfaces.DatabaseOperations) {
+	owned := mustCreateAudit(t, db, interfaces.Audits{Action: "Review", Username: "alice", Firm: "Acme"})
+	shared := mustCreateAudit(t, db, interfaces.Audits{Action: "Fieldwork", Username: "bob", AdditionalUsers: []string{"alice"}})
+	mustCreateAudit(t, db, interfaces.Audits{Action: "Other", Username: "bob"})
+
+	audits, _, err := db.GetAudits("alice")
+	if err != nil {
+		t.Fatalf("GetAudits: %v", err)
+	}
+	var ids []int
+	for _, audit := range audits {
+		ids = append(ids, audit.ID)
+	}
+	if !sameIDs(ids, owned.ID, shared.ID) {
+		t.Errorf("GetAudits(alice) = %v, want owned and additional-user audits", ids)
+	}
+
+	audit, err := db.GetAudit(owned.ID)
+	if err != nil {
+		t.Fatalf("GetAudit: %v", err)
+	}
+	if !audit.CompletedAt.IsZero() {
+		t.Errorf("new audit CompletedAt = %v, want zero", audit.CompletedAt)
+	}
+
+	completedAt := time.Now().Truncate(time.Second)
+	audit.Completed = true
+	audit.CompletedAt = completedAt
+	if _, err := db.UpdateAudit(audit); err != nil {
+		t.Fatalf("UpdateAudit: %v", err)
+	}
+	audit, _ = db.GetAudit(owned.ID)
+	if !audit.Completed || !audit.CompletedAt.Equal(completedAt) {
+		t.Errorf("completion not stored: %+v", audit)
+	}
+
+	if err := db.DeleteAudit(owned.ID, "bob"); err != nil {
+		t.Fatalf("DeleteAudit by another user: %v", err)
+	}
+	if _, err := db.GetAudit(owned.ID); err != nil {
+		t.Error("DeleteAudit removed an audit owned by someone else")
+	}
+	if err := db.DeleteAudit(owned.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if _, err := db.GetAudit(owned.ID); err == nil {
+		t.Error("deleted audit is still returned")
+	}
+}
+
+func testAuditAssignment(t *testing.T, db interfaces.DatabaseOperations) {
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser(%s): %v", username, err)
+		}
+	}
+	audit, err := db.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice", AssignedUser: "carol",
+		AdditionalUsers: []string{"bob"}, Reviewer: "bob"})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	carol, _, _ := db.GetUsers("carol")
+	carol[0].Status = interfaces.UserInactive
+	if _, err := db.Update(carol[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	for name, audit := range map[string]interfaces.Audits{
+		"assignee":        {Action: "Access", Username: "alice", AssignedUser: "carol"},
+		"additional user": {Action: "Access", Username: "alice", AdditionalUsers: []string{"bob", "carol"}},
+		"reviewer":        {Action: "Access", Username: "alice", Reviewer: "carol"},
+	} {
+		if _, err := db.CreateAudit(audit); !errors.Is(err, interfaces.ErrInactiveUser) {
+			t.Errorf("CreateAudit with an inactive %s = %v, want ErrInactiveUser", name, err)
+		}
+	}
+
+	// Work already given to carol stays hers until it is reassigned
+	audit.Notes = "Counted the stock room"
+	audit, err = db.UpdateAudit(audit)
+	if err != nil {
+		t.Fatalf("UpdateAudit keeping the inactive assignee: %v", err)
+	}
+	audit.AssignedUser = "bob"
+	audit.Reviewer = "alice"
+	audit, err = db.UpdateAudit(audit)
+	if err != nil {
+		t.Fatalf("UpdateAudit away from the inactive assignee: %v", err)
+	}
+
+	changed := audit
+	changed.AssignedUser = "carol"
+	if _, err := db.UpdateAudit(changed); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("UpdateAudit back to the inactive assignee = %v, want ErrInactiveUser", err)
+	}
+	changed = audit
+	changed.AdditionalUsers = []string{"bob", "carol"}
+	if _, err := db.UpdateAudit(changed); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("UpdateAudit adding an inactive additional user = %v, want ErrInactiveUser", err)
+	}
+	changed = audit
+	changed.Reviewer = "carol"
+	if _, err := db.UpdateAudit(changed); !errors.Is(err, interfaces.ErrInactiveUser) {
+		t.Errorf("UpdateAudit making the inactive user reviewer = %v, want ErrInactiveUser", err)
+	}
+	if stored, err := db.GetAudit(audit.ID); err != nil || stored.Version != audit.Version || stored.Reviewer != "alice" {
+		t.Errorf("audit after the refused updates = %+v, %v, want it unchanged", stored, err)
+	}
+}
+
+func testAuditStatus(t *testing.T, db interfaces.DatabaseOperations) {
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Inventory", Username: "alice", Status: "Planned", Reviewer: "bob"})
+	if got, _ := db.GetAudit(audit.ID); got.Status != "Planned" || got.Reviewer != "bob" {
+		t.Errorf("new audit = %+v, want Planned and reviewed by bob", got)
+	}
+
+	at := time.Now().Truncate(time.Second)
+	closed, err := db.ChangeAuditStatus(interfaces.AuditStatusChange{AuditID: audit.ID, From: "Planned", To: "Closed",
+		Actor: "bob", Comment: "signed off", At: at}, audit.Version, true)
+	if err != nil {
+		t.Fatalf("ChangeAuditStatus: %v", err)
+	}
+	if closed.Status != "Closed" || !closed.Completed || !closed.CompletedAt.Equal(at) || closed.Version != audit.Version+1 {
+		t.Errorf("closed audit = %+v", closed)
+	}
+	if _, err := db.ChangeAuditStatus(interfaces.AuditStatusChange{AuditID: audit.ID, From: "Planned", To: "Fieldwork", Actor: "alice"},
+		audit.Version, false); !errors.Is(err, interfaces.ErrConflict) {
+		t.Errorf("status change of a stale version = %v, want ErrConflict", err)
+	}
+	reopened, err := db.ChangeAuditStatus(interfaces.AuditStatusChange{AuditID: audit.ID, From: "Closed", To: "Fieldwork",
+		Actor: "bob", Comment: "missing evidence"}, closed.Version, false)
+	if err != nil || reopened.Completed || !reopened.CompletedAt.IsZero() {
+		t.Errorf("reopened audit = %+v, %v, want open again", reopened, err)
+	}
+
+	reopened.Notes = "more work"
+	if updated, err := db.UpdateAudit(reopened); err != nil {
+		t.Errorf("UpdateAudit: %v", err)
+	} else if got, _ := db.GetAudit(updated.ID); got.Status != "Fieldwork" {
+		t.Errorf("status after UpdateAudit = %q, want Fieldwork", got.Status)
+	}
+
+	changes, err := db.GetAuditStatusChanges(audit.ID)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("GetAuditStatusChanges = %+v, %v, want 2 changes", changes, err)
+	}
+	if first := changes[0]; first.From != "Planned" || first.To != "Closed" || first.Actor != "bob" ||
+		first.Comment != "signed off" || !first.At.Equal(at) {
+		t.Errorf("first status change = %+v", first)
+	}
+	if changes[1].To != "Fieldwork" || changes[1].Comment != "missing evidence" {
+		t.Errorf("second status change = %+v", changes[1])
+	}
+	if other, _ := db.GetAuditStatusChanges(audit.ID + 1000); len(other) != 0 {
+		t.Errorf("status changes of another audit = %+v", other)
+	}
+}
+
+func testFindings(t *testing.T, db interfaces.DatabaseOperations) {
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Access review", Username: "alice"})
+	if _, err := db.GetOrCreateUser("bob"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	due := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	low, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "Old tickets", Severity: interfaces.SeverityLow,
+		Status: interfaces.FindingOpen})
+	if err != nil {
+		t.Fatalf("CreateFinding: %v", err)
+	}
+	high, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "Shared admin account", Description: "Three people",
+		Severity: interfaces.SeverityHigh, Area: "IAM", Recommendation: "Personal accounts", Owner: "bob", DueDate: due,
+		Status: interfaces.FindingOpen})
+	if err != nil {
+		t.Fatalf("CreateFinding: %v", err)
+	}
+	if high.ID == 0 || high.Version != 1 || high.Area != "IAM" || !high.DueDate.Equal(due) {
+		t.Errorf("created finding = %+v", high)
+	}
+	if _, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "x", Severity: "urgent",
+		Status: interfaces.FindingOpen}); !errors.Is(err, interfaces.ErrInvalidFinding) {
+		t.Errorf("finding with an unknown severity = %v, want ErrInvalidFinding", err)
+	}
+	if _, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID + 1000, Title: "x", Severity: interfaces.SeverityLow,
+		Status: interfaces.FindingOpen}); err == nil {
+		t.Error("finding for a missing audit returned no error")
+	}
+
+	findings, err := db.GetFindings(audit.ID)
+	if err != nil || len(findings) != 2 || findings[0].ID != high.ID || findings[1].ID != low.ID {
+		t.Fatalf("GetFindings = %+v, %v, want the high finding first", findings, err)
+	}
+
+	task := mustCreateTask(t, db, interfaces.Tasks{Title: "Remove shared account", Username: "alice", Assignee: "bob",
+		FindingID: high.ID})
+	if got, err := db.GetTask(task.ID); err != nil || got.FindingID != high.ID {
+		t.Errorf("remediation task = %+v, %v, want it linked to finding %d", got, err, high.ID)
+	}
+	if _, err := db.CreateTask(interfaces.Tasks{Title: "Orphan", Username: "alice", FindingID: high.ID + 1000}); err == nil {
+		t.Error("task for a missing finding returned no error")
+	}
+
+	high.Status = interfaces.FindingInRemediation
+	updated, err := db.UpdateFinding(high)
+	if err != nil {
+		t.Fatalf("UpdateFinding: %v", err)
+	}
+	if updated.Status != interfaces.FindingInRemediation || updated.Version != high.Version+1 ||
+		len(updated.Tasks) != 1 || updated.Tasks[0] != task.ID {
+		t.Errorf("updated finding = %+v, want in remediation with task %d", updated, task.ID)
+	}
+	high.Status = interfaces.FindingResolved
+	_, err = db.UpdateFinding(high)
+	if current, ok := expectConflict(t, err).(interfaces.Finding); !ok || current.Status != interfaces.FindingInRemediation {
+		t.Errorf("conflict current = %+v, want the finding in remediation", current)
+	}
+
+	if err := db.DeleteFinding(high.ID); err != nil {
+		t.Fatalf("DeleteFinding: %v", err)
+	}
+	if got, err := db.GetTask(task.ID); err != nil || got.FindingID != 0 {
+		t.Errorf("task of a deleted finding = %+v, %v, want it kept without the link", got, err)
+	}
+	if err := db.DeleteAudit(audit.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if _, err := db.GetFinding(low.ID); err == nil {
+		t.Error("finding of a deleted audit still exists")
+	}
+}
+
+func testEvidence(t *testing.T, db interfaces.DatabaseOperations) {
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Backups", Username: "alice"})
+	other := mustCreateAudit(t, db, interfaces.Audits{Action: "Payroll", Username: "alice"})
+	finding, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "No restore test",
+		Severity: interfaces.SeverityHigh, Status: interfaces.FindingOpen})
+	if err != nil {
+		t.Fatalf("CreateFinding: %v", err)
+	}
+
+	content := []byte("restore log")
+	upload := interfaces.Evidence{AuditID: audit.ID, FindingID: finding.ID, Name: "restore.log", ContentType: "text/plain",
+		Size: int64(len(content)), SHA256: interfaces.EvidenceHash(content), Storage: interfaces.EvidenceInDatabase,
+		Uploader: "alice", UploadedAt: time.Now().Add(-time.Hour).Truncate(time.Second)}
+	first, err := db.AddEvidence(upload, content)
+	if err != nil {
+		t.Fatalf("AddEvidence: %v", err)
+	}
+	if first.ID == 0 || first.Version != 1 || first.Replaces != 0 || !first.UploadedAt.Equal(upload.UploadedAt) {
+		t.Errorf("added evidence = %+v", first)
+	}
+	if got, err := db.ReadEvidence(first.ID); err != nil || string(got) != "restore log" {
+		t.Errorf("ReadEvidence = %q, %v", got, err)
+	}
+
+	wrong := upload
+	wrong.SHA256 = interfaces.EvidenceHash([]byte("something else"))
+	if _, err := db.AddEvidence(wrong, content); !errors.Is(err, interfaces.ErrEvidenceTampered) {
+		t.Errorf("content that does not match its hash = %v, want ErrEvidenceTampered", err)
+	}
+	misplaced := upload
+	misplaced.AuditID = other.ID
+	if _, err := db.AddEvidence(misplaced, content); err == nil {
+		t.Error("evidence linked to another audit's finding returned no error")
+	}
+
+	file := interfaces.Evidence{AuditID: audit.ID, Name: "restore.log", Size: 20, SHA256: interfaces.EvidenceHash([]byte("x")),
+		Storage: interfaces.EvidenceInDirectory, Uploader: "bob", Replaces: first.ID}
+	second, err := db.AddEvidence(file, nil)
+	if err != nil || second.Version != 2 || second.Replaces != first.ID {
+		t.Fatalf("replacement = %+v, %v, want version 2 of %d", second, err, first.ID)
+	}
+	if _, err := db.ReadEvidence(second.ID); err == nil {
+		t.Error("ReadEvidence of evidence kept in a directory returned no error")
+	}
+	_, err = db.AddEvidence(file, nil)
+	if current, ok := expectConflict(t, err).(interfaces.Evidence); !ok || current.ID != second.ID {
+		t.Errorf("conflict current = %+v, want version 2", current)
+	}
+
+	evidence, err := db.ListEvidence(audit.ID)
+	if err != nil || len(evidence) != 2 || evidence[0].ID != first.ID || evidence[0].ReplacedBy != second.ID {
+		t.Fatalf("ListEvidence = %+v, %v, want both versions with the first replaced", evidence, err)
+	}
+	if err := db.DeleteFinding(finding.ID); err != nil {
+		t.Fatalf("DeleteFinding: %v", err)
+	}
+	if got, err := db.GetEvidence(first.ID); err != nil || got.FindingID != 0 || got.SHA256 != first.SHA256 {
+		t.Errorf("evidence of a deleted finding = %+v, %v, want it kept without the link", got, err)
+	}
+	if err := db.DeleteAudit(audit.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if left, _ := db.ListEvidence(audit.ID); len(left) != 0 {
+		t.Errorf("evidence of a deleted audit = %+v", left)
+	}
+}
+
+func testTemplates(t *testing.T, db interfaces.DatabaseOperations) {
+	template, err := db.CreateTemplate(interfaces.AuditTemplate{Name: "Backup review", AuditType: "Backups", AuditArea: "IT",
+		Items: []interfaces.TemplateItem{
+			{Title: "Nightly jobs succeed", ExpectedEvidence: "Job report", Guidance: "Last 30 days"},
+			{Title: "Restore tested"},
+		},
+		FindingCategories: []string{"Retention", "Restores"}})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	if template.ID == 0 || template.Version != 1 || len(template.Items) != 2 || template.Items[0].Guidance != "Last 30 days" {
+		t.Errorf("created template = %+v", template)
+	}
+	if _, err := db.CreateTemplate(interfaces.AuditTemplate{Name: "Backup review"}); !errors.Is(err, interfaces.ErrInvalidTemplate) {
+		t.Errorf("template with a duplicate name = %v, want ErrInvalidTemplate", err)
+	}
+	if _, err := db.CreateTemplate(interfaces.AuditTemplate{Name: "Empty"}); err != nil {
+		t.Errorf("template without items: %v", err)
+	}
+	templates, err := db.GetTemplates()
+	if err != nil || len(templates) != 2 || templates[0].Name != "Backup review" || templates[1].Items != nil {
+		t.Fatalf("GetTemplates = %+v, %v, want both by name", templates, err)
+	}
+
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Q1 backups", AuditArea: "Datacentre", Username: "alice",
+		TemplateID: template.ID})
+	if audit.AuditType != "Backups" || audit.AuditArea != "Datacentre" || audit.TemplateID != template.ID {
+		t.Errorf("audit from template = %+v, want the template's type and its own area", audit)
+	}
+	if _, err := db.CreateAudit(interfaces.Audits{Action: "x", Username: "alice", TemplateID: template.ID + 1000}); err == nil {
+		t.Error("audit from a missing template returned no error")
+	}
+
+	template.Items = template.Items[:1]
+	template.FindingCategories = nil
+	updated, err := db.UpdateTemplate(template)
+	if err != nil || updated.Version != 2 || len(updated.Items) != 1 || updated.FindingCategories != nil {
+		t.Fatalf("UpdateTemplate = %+v, %v", updated, err)
+	}
+	_, err = db.UpdateTemplate(template)
+	if current, ok := expectConflict(t, err).(interfaces.AuditTemplate); !ok || current.Version != 2 {
+		t.Errorf("conflict current = %+v, want version 2", current)
+	}
+
+	items, err := db.GetChecklist(audit.ID)
+	if err != nil || len(items) != 2 || items[0].Title != "Nightly jobs succeed" || items[0].ExpectedEvidence != "Job report" ||
+		items[1].Title != "Restore tested" || items[0].Result != interfaces.ChecklistPending {
+		t.Fatalf("GetChecklist = %+v, %v, want the items of the template when the audit was created", items, err)
+	}
+	item := items[1]
+	item.Result = interfaces.ChecklistFail
+	item.Comment = "Never tested"
+	item.UpdatedBy = "alice"
+	marked, err := db.UpdateChecklistItem(item)
+	if err != nil || marked.Result != interfaces.ChecklistFail || marked.Comment != "Never tested" || marked.UpdatedBy != "alice" ||
+		marked.UpdatedAt.IsZero() || marked.Version != item.Version+1 {
+		t.Errorf("UpdateChecklistItem = %+v, %v", marked, err)
+	}
+	item.Result = interfaces.ChecklistPass
+	_, err = db.UpdateChecklistItem(item)
+	if current, ok := expectConflict(t, err).(interfaces.ChecklistItem); !ok || current.Result != interfaces.ChecklistFail {
+		t.Errorf("conflict current = %+v, want the failed item", current)
+	}
+	marked.Result = "Maybe"
+	if _, err := db.UpdateChecklistItem(marked); !errors.Is(err, interfaces.ErrInvalidTemplate) {
+		t.Errorf("unknown result = %v, want ErrInvalidTemplate", err)
+	}
+
+	if err := db.DeleteTemplate(template.ID); err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	if got, err := db.GetAudit(audit.ID); err != nil || got.TemplateID != 0 {
+		t.Errorf("audit of a deleted template = %+v, %v, want it unlinked", got, err)
+	}
+	if left, _ := db.GetChecklist(audit.ID); len(left) != 2 {
+		t.Errorf("checklist after the template was deleted = %+v, want it kept", left)
+	}
+	if err := db.DeleteAudit(audit.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if left, _ := db.GetChecklist(audit.ID); len(left) != 0 {
+		t.Errorf("checklist of a deleted audit = %+v", left)
+	}
+}
+
+func testControls(t *testing.T, db interfaces.DatabaseOperations) {
+	result, err := db.ImportControls("SOC 2", []interfaces.Control{
+		{ControlID: "CC7.2", Title: "Monitoring"},
+		{ControlID: "CC6.1", Title: "Logical access", Family: "Access"},
+	})
+	if err != nil || result.Added != 2 {
+		t.Fatalf("ImportControls = %+v, %v, want 2 added", result, err)
+	}
+	if _, err := db.ImportControls("ISO 27001", []interfaces.Control{{ControlID: "A.8.13", Title: "Backup"}}); err != nil {
+		t.Fatalf("ImportControls: %v", err)
+	}
+	result, err = db.ImportControls("SOC 2", []interfaces.Control{
+		{ControlID: "CC6.1", Title: "Logical access security", Family: "Access"},
+		{ControlID: "CC7.2", Title: "Monitoring"},
+	})
+	if err != nil || result != (interfaces.ControlImport{Updated: 1, Unchanged: 1}) {
+		t.Errorf("reimport = %+v, %v, want one updated and one unchanged", result, err)
+	}
+	if _, err := db.ImportControls("SOC 2", []interfaces.Control{{ControlID: "X", Title: "a"}, {ControlID: "X", Title: "b"}}); !errors.Is(err, interfaces.ErrInvalidControl) {
+		t.Errorf("import with a repeated control = %v, want ErrInvalidControl", err)
+	}
+
+	soc2, err := db.GetControls("SOC 2")
+	if err != nil || len(soc2) != 2 || soc2[0].ControlID != "CC6.1" || soc2[0].Title != "Logical access security" ||
+		soc2[0].Framework != "SOC 2" || soc2[0].Family != "Access" {
+		t.Fatalf("GetControls = %+v, %v, want both SOC 2 controls in order", soc2, err)
+	}
+	all, err := db.GetControls("")
+	if err != nil || len(all) != 3 || all[0].Framework != "ISO 27001" {
+		t.Fatalf("GetControls of every framework = %+v, %v", all, err)
+	}
+	iso := all[0]
+	access, monitoring := soc2[0], soc2[1]
+
+	template, err := db.CreateTemplate(interfaces.AuditTemplate{Name: "Access controls",
+		Items: []interfaces.TemplateItem{{Title: "Leavers removed"}, {Title: "Alerts reviewed"}}})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	acme := mustCreateAudit(t, db, interfaces.Audits{Action: "Acme access", Firm: "Acme", Username: "alice", TemplateID: template.ID})
+	globex := mustCreateAudit(t, db, interfaces.Audits{Action: "Globex access", Firm: "Globex", Username: "alice"})
+	if err := db.SetAuditControls(acme.ID, []int{access.ID, iso.ID, access.ID}); err != nil {
+		t.Fatalf("SetAuditControls: %v", err)
+	}
+	if err := db.SetAuditControls(acme.ID, []int{access.ID + 1000}); err == nil {
+		t.Error("mapping to a missing control returned no error")
+	}
+	if err := db.SetAuditControls(globex.ID, []int{access.ID}); err != nil {
+		t.Fatalf("SetAuditControls: %v", err)
+	}
+	mapped, err := db.GetAuditControls(acme.ID)
+	if err != nil || len(mapped) != 2 || mapped[0].ID != iso.ID || mapped[1].ID != access.ID {
+		t.Errorf("GetAuditControls = %+v, %v, want the ISO and SOC 2 controls", mapped, err)
+	}
+
+	items, _ := db.GetChecklist(acme.ID)
+	if len(items) != 2 {
+		t.Fatalf("checklist = %+v", items)
+	}
+	if err := db.SetChecklistItemControls(items[1].ID, []int{monitoring.ID, access.ID}); err != nil {
+		t.Fatalf("SetChecklistItemControls: %v", err)
+	}
+	items[1].Result = interfaces.ChecklistFail
+	marked, err := db.UpdateChecklistItem(items[1])
+	if err != nil || len(marked.Controls) != 2 || marked.Controls[0] != min(access.ID, monitoring.ID) {
+		t.Errorf("UpdateChecklistItem = %+v, %v, want its two controls", marked, err)
+	}
+	// Only open findings mapped to a control count against it
+	initech := mustCreateAudit(t, db, interfaces.Audits{Action: "Initech monitoring", Firm: "Initech", Username: "alice"})
+	finding := func(audit interfaces.Audits, title, status string, controls ...int) interfaces.Finding {
+		t.Helper()
+		created, err := db.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: title,
+			Severity: interfaces.SeverityHigh, Status: status})
+		if err != nil {
+			t.Fatalf("CreateFinding: %v", err)
+		}
+		if len(controls) > 0 {
+			if err := db.SetFindingControls(created.ID, controls); err != nil {
+				t.Fatalf("SetFindingControls: %v", err)
+			}
+		}
+		return created
+	}
+	shared := finding(globex, "Shared accounts", interfaces.FindingOpen, access.ID, access.ID)
+	finding(globex, "Stale leavers", interfaces.FindingResolved, access.ID)
+	finding(globex, "Slow patching", interfaces.FindingOpen)
+	finding(acme, "Alerts ignored", interfaces.FindingOpen, monitoring.ID)
+	finding(initech, "No alerting", interfaces.FindingInRemediation, monitoring.ID)
+	if err := db.SetFindingControls(shared.ID, []int{access.ID + 1000}); err == nil {
+		t.Error("mapping a finding to a missing control returned no error")
+	}
+	if got, err := db.GetFinding(shared.ID); err != nil || len(got.Controls) != 1 || got.Controls[0] != access.ID {
+		t.Errorf("GetFinding = %+v, %v, want it mapped to the access control", got, err)
+	}
+
+	links, err := db.GetControlLinks("SOC 2")
+	if err != nil {
+		t.Fatalf("GetControlLinks: %v", err)
+	}
+	// Links come by control row ID; monitoring was imported first
+	want := []interfaces.ControlLink{
+		{ControlID: monitoring.ID, AuditID: acme.ID, Firm: "Acme", OpenFindings: 1},
+		{ControlID: monitoring.ID, AuditID: acme.ID, Firm: "Acme", ItemID: items[1].ID, Result: interfaces.ChecklistFail,
+			OpenFindings: 1},
+		{ControlID: monitoring.ID, AuditID: initech.ID, Firm: "Initech", OpenFindings: 1},
+		{ControlID: access.ID, AuditID: acme.ID, Firm: "Acme"},
+		{ControlID: access.ID, AuditID: acme.ID, Firm: "Acme", ItemID: items[1].ID, Result: interfaces.ChecklistFail},
+		{ControlID: access.ID, AuditID: globex.ID, Firm: "Globex", OpenFindings: 1},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("GetControlLinks = %+v, want %+v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, links[i], want[i])
+		}
+	}
+
+	if err := db.DeleteFramework("SOC 2"); err != nil {
+		t.Fatalf("DeleteFramework: %v", err)
+	}
+	if mapped, _ := db.GetAuditControls(acme.ID); len(mapped) != 1 || mapped[0].ID != iso.ID {
+		t.Errorf("audit controls after deleting SOC 2 = %+v, want only ISO", mapped)
+	}
+	if items, _ := db.GetChecklist(acme.ID); items[1].Controls != nil {
+		t.Errorf("item controls after deleting SOC 2 = %v", items[1].Controls)
+	}
+	if got, _ := db.GetFinding(shared.ID); got.Controls != nil {
+		t.Errorf("finding controls after deleting SOC 2 = %v", got.Controls)
+	}
+	if err := db.DeleteAudit(acme.ID, "alice"); err != nil {
+		t.Fatalf("DeleteAudit: %v", err)
+	}
+	if links, _ := db.GetControlLinks(""); len(links) != 0 {
+		t.Errorf("links after deleting the audit = %+v", links)
+	}
+}
+
+func testSchedules(t *testing.T, db interfaces.DatabaseOperations) {
+	day := func(text string) time.Time {
+		d, _ := time.Parse("2006-01-02", text)
+		return d
+	}
+	template, err := db.CreateTemplate(interfaces.AuditTemplate{Name: "Quarterly access", AuditType: "Access",
+		Items: []interfaces.TemplateItem{{Title: "Leavers removed"}}})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	schedule, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "Acme access review", Recurrence: "FREQ=MONTHLY;INTERVAL=3",
+		StartDate: day("2026-01-15"), LeadDays: 30, Firm: "Acme", TemplateID: template.ID, Assignee: "bob", CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	if schedule.ID == 0 || schedule.Version != 1 || !schedule.StartDate.Equal(day("2026-01-15")) || schedule.TemplateID != template.ID ||
+		schedule.Assignee != "bob" || !schedule.CreatedThrough.IsZero() {
+		t.Errorf("created schedule = %+v", schedule)
+	}
+	if _, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "Broken", Recurrence: "FREQ=HOURLY", StartDate: day("2026-01-01"),
+		CreatedBy: "alice"}); !errors.Is(err, interfaces.ErrInvalidSchedule) {
+		t.Errorf("schedule with a bad rule = %v, want ErrInvalidSchedule", err)
+	}
+	if _, err := db.CreateSchedule(interfaces.AuditSchedule{Name: "Annual", Recurrence: "FREQ=YEARLY", StartDate: day("2026-06-30"),
+		CreatedBy: "alice", Paused: true}); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	schedules, err := db.GetSchedules()
+	if err != nil || len(schedules) != 2 || schedules[0].Name != "Acme access review" || !schedules[1].Paused {
+		t.Fatalf("GetSchedules = %+v, %v, want both by name", schedules, err)
+	}
+
+	schedule.LeadDays = 45
+	updated, err := db.UpdateSchedule(schedule)
+	if err != nil || updated.Version != 2 || updated.LeadDays != 45 || updated.CreatedBy != "alice" {
+		t.Fatalf("UpdateSchedule = %+v, %v", updated, err)
+	}
+	current := expectConflict(t, func() error { _, err := db.UpdateSchedule(schedule); return err }())
+	if current.(interfaces.AuditSchedule).Version != 2 {
+		t.Errorf("conflict current = %+v, want version 2", current)
+	}
+
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Acme access review 2026-04-15", Username: "alice",
+		TemplateID: template.ID, ScheduleID: schedule.ID, ScheduledFor: day("2026-04-15")})
+	if audit.ScheduleID != schedule.ID || !audit.ScheduledFor.Equal(day("2026-04-15")) {
+		t.Errorf("scheduled audit = %+v", audit)
+	}
+	if _, err := db.CreateAudit(interfaces.Audits{Action: "again", Username: "alice", ScheduleID: schedule.ID,
+		ScheduledFor: day("2026-04-15")}); !errors.Is(err, interfaces.ErrAlreadyScheduled) {
+		t.Errorf("second audit for the date = %v, want ErrAlreadyScheduled", err)
+	}
+	mustCreateAudit(t, db, interfaces.Audits{Action: "Unscheduled", Username: "alice"})
+	mustCreateAudit(t, db, interfaces.Audits{Action: "Unscheduled too", Username: "alice"})
+
+	scheduled, err := db.GetScheduledAudits(day("2026-04-01"), day("2026-04-15"))
+	if err != nil || len(scheduled) != 1 || scheduled[0].ID != audit.ID || !scheduled[0].ScheduledFor.Equal(day("2026-04-15")) {
+		t.Errorf("GetScheduledAudits = %+v, %v, want the April audit", scheduled, err)
+	}
+	if got, _ := db.GetAudit(audit.ID); got.ScheduleID != schedule.ID || !got.ScheduledFor.Equal(day("2026-04-15")) {
+		t.Errorf("GetAudit = %+v, want its schedule and date", got)
+	}
+	if scheduled, _ := db.GetScheduledAudits(day("2026-04-16"), day("2026-12-31")); len(scheduled) != 0 {
+		t.Errorf("GetScheduledAudits after the audit = %+v", scheduled)
+	}
+
+	if err := db.SetScheduleCreatedThrough(schedule.ID, day("2026-05-01")); err != nil {
+		t.Fatalf("SetScheduleCreatedThrough: %v", err)
+	}
+	if err := db.SetScheduleCreatedThrough(schedule.ID, day("2026-04-01")); err != nil {
+		t.Fatalf("SetScheduleCreatedThrough: %v", err)
+	}
+	stored, err := db.GetSchedule(schedule.ID)
+	if err != nil || !stored.CreatedThrough.Equal(day("2026-05-01")) || stored.Version != 2 {
+		t.Errorf("schedule = %+v, %v, want it created through May 1st without a new version", stored, err)
+	}
+
+	if err := db.DeleteTemplate(template.ID); err != nil {
+		t.Fatalf("DeleteTemplate: %v", err)
+	}
+	if stored, _ := db.GetSchedule(schedule.ID); stored.TemplateID != 0 {
+		t.Errorf("schedule template after deleting it = %d", stored.TemplateID)
+	}
+	if err := db.DeleteSchedule(schedule.ID); err != nil {
+		t.Fatalf("DeleteSchedule: %v", err)
+	}
+	if _, err := db.GetSchedule(schedule.ID); err == nil {
+		t.Error("deleted schedule is still there")
+	}
+	if got, err := db.GetAudit(audit.ID); err != nil || got.ScheduleID != 0 {
+		t.Errorf("audit after deleting its schedule = %+v, %v, want it kept", got, err)
+	}
+}
+
+func testAuditConflicts(t *testing.T, db interfaces.DatabaseOperations) {
+	audit := mustCreateAudit(t, db, interfaces.Audits{Action: "Review", Username: "alice"})
+
+	theirs := audit
+	theirs.Notes = "bob's findings"
+	if _, err := db.UpdateAudit(theirs); err != nil {
+		t.Fatalf("UpdateAudit: %v", err)
+	}
+
+	mine := audit
+	mine.Notes = "alice's findings"
+	_, err := db.UpdateAudit(mine)
+	current := expectConflict(t, err)
+	if serverCopy, ok := current.(interfaces.Audits); !ok || serverCopy.Notes != "bob's findings" {
+		t.Errorf("conflict current = %+v, want bob's notes", current)
+	}
+}
+
+func testCRM(t *testing.T, db interfaces.DatabaseOperations) {
+	own := mustCreateCRM(t, db, interfaces.CRM{Name: "Ann", Username: "alice", Notes: []string{"called"}})
+	open := mustCreateCRM(t, db, interfaces.CRM{Name: "Ben", Username: "bob", Open: true})
+	mustCreateCRM(t, db, interfaces.CRM{Name: "Cat", Username: "bob"})
+
+	entries, _, err := db.GetCRMEntries("alice")
+	if err != nil {
+		t.Fatalf("GetCRMEntries: %v", err)
+	}
+	var ids []int
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	if !sameIDs(ids, own.ID, open.ID) {
+		t.Errorf("GetCRMEntries(alice) = %v, want own and open entries", ids)
+	}
+
+	entry, err := db.GetCRMEntry(own.ID)
+	if err != nil {
+		t.Fatalf("GetCRMEntry: %v", err)
+	}
+	if len(entry.Notes) != 1 || entry.Notes[0] != "called" {
+		t.Errorf("GetCRMEntry notes = %v", entry.Notes)
+	}
+
+	if err := db.DeleteCRMEntry(own.ID, "bob"); err != nil {
+		t.Fatalf("DeleteCRMEntry by another user: %v", err)
+	}
+	if _, err := db.GetCRMEntry(own.ID); err != nil {
+		t.Error("DeleteCRMEntry removed an entry owned by someone else")
+	}
+	if err := db.DeleteCRMEntry(own.ID, "alice"); err != nil {
+		t.Fatalf("DeleteCRMEntry: %v", err)
+	}
+	if _, err := db.GetCRMEntry(own.ID); err == nil {
+		t.Error("deleted CRM entry is still returned")
+	}
+}
+
+func testCRMConflicts(t *testing.T, db interfaces.DatabaseOperations) {
+	entry := mustCreateCRM(t, db, interfaces.CRM{Name: "Ann", Username: "alice", Open: true})
+
+	theirs := entry
+	theirs.Phone = "555-0100"
+	if _, err := db.UpdateCRMEntry(theirs); err != nil {
+		t.Fatalf("UpdateCRMEntry: %v", err)
+	}
+
+	mine := entry
+	mine.Phone = "555-0199"
+	_, err := db.UpdateCRMEntry(mine)
+	current := expectConflict(t, err)
+	if serverCopy, ok := current.(interfaces.CRM); !ok || serverCopy.Phone != "555-0100" {
+		t.Errorf("conflict current = %+v, want bob's phone number", current)
+	}
+}
+
+func testCredentials(t *testing.T, db interfaces.DatabaseOperations) {
+	if _, err := db.GetOrCreateUser("alice"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+
+	mail, err := db.CreateCredential(interfaces.Credentials{Site: "mail.example.com", LoginName: "alice.mail", LoginPass: "hash",
+		Username: "alice", Owner: "alice", MasterPassword: "master"})
+	if err != nil {
+		t.Fatalf("CreateCredential: %v", err)
+	}
+	if _, err := db.CreateCredential(interfaces.Credentials{Site: "bank.example.com", LoginName: "alice.bank", Username: "alice",
+		Owner: "alice", MasterPassword: "master"}); err != nil {
+		t.Fatalf("CreateCredential: %v", err)
+	}
+	if _, err := db.CreateCredential(interfaces.Credentials{Site: "mail.example.com", LoginName: "bob.mail", Username: "bob",
+		Owner: "bob", MasterPassword: "master"}); err != nil {
+		t.Fatalf("CreateCredential: %v", err)
+	}
+
+	credentials, _, err := db.GetCredentials("alice")
+	if err != nil || len(credentials) != 2 {
+		t.Fatalf("GetCredentials(alice) = %d credentials, %v; want 2", len(credentials), err)
+	}
+
+	found, _, err := db.SearchCredentials("MAIL", "alice")
+	if err != nil {
+		t.Fatalf("SearchCredentials: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != mail.ID {
+		t.Errorf("SearchCredentials(MAIL) = %+v, want only alice's mail login", found)
+	}
+
+	byLogin, err := db.GetCredentialByLoginName("alice.mail")
+	if err != nil || len(byLogin) != 1 || byLogin[0].LoginPass != "hash" {
+		t.Errorf("GetCredentialByLoginName = %+v, %v", byLogin, err)
+	}
+	if _, err := db.GetCredentialByLoginName("nobody"); err == nil {
+		t.Error("GetCredentialByLoginName for an unknown login returned no error")
+	}
+
+	if err := db.DeleteCredential(mail.ID, "bob"); err != nil {
+		t.Fatalf("DeleteCredential by another owner: %v", err)
+	}
+	credentials, _, _ = db.GetCredentials("alice")
+	if len(credentials) != 2 {
+		t.Error("DeleteCredential removed a credential owned by someone else")
+	}
+	if err := db.DeleteCredential(mail.ID, "alice"); err != nil {
+		t.Fatalf("DeleteCredential: %v", err)
+	}
+	credentials, _, _ = db.GetCredentials("alice")
+	if len(credentials) != 1 {
+		t.Errorf("GetCredentials after delete = %d credentials, want 1", len(credentials))
+	}
+
+	if _, err := db.CreateCredUser("nobody", "hash", ""); err == nil {
+		t.Error("CreateCredUser for an unknown user returned no error")
+	}
+	if _, err := db.GetOrCreateUser("carol"); err != nil {
+		t.Fatalf("GetOrCreateUser: %v", err)
+	}
+	credUser, err := db.CreateCredUser("carol", "carol-hash", "carol@example.com")
+	if err != nil {
+		t.Fatalf("CreateCredUser: %v", err)
+	}
+	if credUser.Username != "carol" || credUser.UserID == "" {
+		t.Errorf("CreateCredUser = %+v", credUser)
+	}
+	hash, err := db.GetUserPassword("carol")
+	if err != nil || hash != "carol-hash" {
+		t.Errorf("GetUserPassword = %q, %v", hash, err)
+	}
+}
+
+func testChangeEvents(t *testing.T, db interfaces.DatabaseOperations) {
+	events, unsubscribe := db.SubscribeChanges()
+	defer unsubscribe()
+
+	task := mustCreateTask(t, db, interfaces.Tasks{Title: "Notify", Username: "alice", DueDate: time.Now()})
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Table == "tasks" && event.Operation == "INSERT" && event.ID == task.ID {
+				if event.Username != "alice" {
+					t.Errorf("event username = %q, want alice", event.Username)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("no change event received for the new task")
+		}
+	}
+}
+
+// Helpers
+
+func expectConflict(t *testing.T, err error) interface{} {
+	t.Helper()
+	if !errors.Is(err, interfaces.ErrConflict) {
+		t.Fatalf("stale update returned %v, want a conflict", err)
+	}
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("conflict error has type %T, want *interfaces.ConflictError", err)
+	}
+	return conflict.Current
+}
+
+func mustCreateNote(t *testing.T, db interfaces.DatabaseOperations, title, content, username string, open bool) interfaces.Note {
+	t.Helper()
+	note, err := db.CreateNote(title, content, username, open)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	return note
+}
+
+func mustCreateTask(t *testing.T, db interfaces.DatabaseOperations, task interfaces.Tasks) interfaces.Tasks {
+	t.Helper()
+	task, err := db.CreateTask(task)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	return task
+}
+
+func mustCreateAudit(t *testing.T, db interfaces.DatabaseOperations, audit interfaces.Audits) interfaces.Audits {
+	t.Helper()
+	audit, err := db.CreateAudit(audit)
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	return audit
+}
+
+func mustCreateCRM(t *testing.T, db interfaces.DatabaseOperations, crm interfaces.CRM) interfaces.CRM {
+	t.Helper()
+	crm, err := db.CreateCRMEntry(crm)
+	if err != nil {
+		t.Fatalf("CreateCRMEntry: %v", err)
+	}
+	return crm
+}
+
+func noteIDs(notes []interfaces.Note) []int {
+	var ids []int
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	return ids
+}
+
+// sameIDs reports whether got holds exactly want, in any order
+func sameIDs(got []int, want ...int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[int]int)
+	for _, id := range got {
+		seen[id]++
+	}
+	for _, id := range want {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
@@ -0,0 +1,2460 @@
+// Package fakes provides in-memory implementations of the store and directory
+// interfaces. They follow the same rules as the Postgres and LDAP versions and
+// are checked against them by the conformance suite in databases/dbtest.
+package fakes
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+var _ interfaces.DatabaseOperations = (*Database)(nil)
+
+// ErrNotFound mirrors the "no rows" error returned by the Postgres store
+var ErrNotFound = errors.New("no rows in result set")
+
+// Database is an in-memory interfaces.DatabaseOperations
+type Database struct {
+	mu          sync.Mutex
+	nextID      int
+	users       []interfaces.Users
+	notes       []interfaces.Note
+	tasks       []interfaces.Tasks
+	audits      []interfaces.Audits
+	crm         []interfaces.CRM
+	credentials []interfaces.Credentials
+	passwords   map[int]string
+	twoFactor   map[int]interfaces.TwoFactor
+	totpSteps   map[int]int64
+	attempts    map[[2]string]interfaces.LoginAttempts
+	subjects    map[int]string
+	history     []interfaces.ChangeHistory
+	auditSteps  []interfaces.AuditStatusChange
+	findings    []interfaces.Finding
+	evidence    []interfaces.Evidence
+	content     map[int][]byte
+	templates   []interfaces.AuditTemplate
+	checklist   []interfaces.ChecklistItem
+	controls    []interfaces.Control
+	schedules   []interfaces.AuditSchedule
+	// Control IDs mapped to each audit, checklist item and finding
+	auditControls   map[int][]int
+	itemControls    map[int][]int
+	findingControls map[int][]int
+	subscribers     map[chan interfaces.ChangeEvent]struct{}
+}
+
+func NewDatabase() *Database {
+	return &Database{
+		passwords:       make(map[int]string),
+		twoFactor:       make(map[int]interfaces.TwoFactor),
+		totpSteps:       make(map[int]int64),
+		attempts:        make(map[[2]string]interfaces.LoginAttempts),
+		subjects:        make(map[int]string),
+		content:         make(map[int][]byte),
+		auditControls:   make(map[int][]int),
+		itemControls:    make(map[int][]int),
+		findingControls: make(map[int][]int),
+		subscribers:     make(map[chan interfaces.ChangeEvent]struct{}),
+	}
+}
+
+func (db *Database) newID() int {
+	db.nextID++
+	return db.nextID
+}
+
+// Users
+
+func (db *Database) findUser(username string) int {
+	for i, user := range db.users {
+		if user.Username == username {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *Database) createUser(username, userID string) interfaces.Users {
+	now := time.Now()
+	user := interfaces.Users{
+		ID:        db.newID(),
+		UserID:    userID,
+		Username:  username,
+		Status:    "Active",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	db.users = append(db.users, user)
+	return user
+}
+
+func (db *Database) Create(username string) (interfaces.Users, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findUser(username); i >= 0 {
+		db.users[i].UpdatedAt = time.Now()
+		return db.users[i], nil
+	}
+	return db.createUser(username, interfaces.NewUserID()), nil
+}
+
+func (db *Database) GetUsers(username string) ([]interfaces.Users, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return []interfaces.Users{}, fmt.Sprintf("user not found: %s", username), fmt.Errorf("user not found")
+	}
+	return []interfaces.Users{db.users[i]}, fmt.Sprintf("User %s", username), nil
+}
+
+func (db *Database) GetOrCreateUser(username string) (interfaces.Users, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findUser(username); i >= 0 {
+		return db.users[i], nil
+	}
+	return db.createUser(username, interfaces.NewUserID()), nil
+}
+
+func (db *Database) GetAll() ([]interfaces.Users, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	users := append([]interfaces.Users(nil), db.users...)
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (db *Database) Update(user interfaces.Users) (interfaces.Users, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.users {
+		if db.users[i].ID == user.ID {
+			user.UserID = db.users[i].UserID
+			user.Local = db.users[i].Local
+			user.MustChangePassword = db.users[i].MustChangePassword
+			user.TwoFactor = db.users[i].TwoFactor
+			user.CreatedAt = db.users[i].CreatedAt
+			user.UpdatedAt = time.Now()
+			db.users[i] = user
+			return user, nil
+		}
+	}
+	return interfaces.Users{}, ErrNotFound
+}
+
+func (db *Database) Delete(user interfaces.Users) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if records := db.userRecords(user.Username, user.UserID); records.Total() > 0 {
+		return fmt.Errorf("cannot delete %s, who still has %s: %w", user.Username, records, interfaces.ErrUserInUse)
+	}
+	for i := range db.users {
+		if db.users[i].ID == user.ID && db.users[i].UserID == user.UserID {
+			db.users = append(db.users[:i], db.users[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (db *Database) GetPasswordHash(username string) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return "", fmt.Errorf("user not found")
+	}
+	return db.passwords[db.users[i].ID], nil
+}
+
+func (db *Database) SetPassword(username, passwordHash string, mustChange bool) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("user not found")
+	}
+	if passwordHash == "" {
+		delete(db.passwords, db.users[i].ID)
+	} else {
+		db.passwords[db.users[i].ID] = passwordHash
+	}
+	db.users[i].Local = passwordHash != ""
+	db.users[i].MustChangePassword = mustChange && passwordHash != ""
+	db.users[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func (db *Database) GetTwoFactor(username string) (interfaces.TwoFactor, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return interfaces.TwoFactor{}, fmt.Errorf("user not found")
+	}
+	twoFactor := db.twoFactor[db.users[i].ID]
+	twoFactor.BackupCodes = append([]string{}, twoFactor.BackupCodes...)
+	return twoFactor, nil
+}
+
+func (db *Database) SetTwoFactor(username string, twoFactor interfaces.TwoFactor) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("user not found")
+	}
+	if twoFactor.Secret == "" {
+		delete(db.twoFactor, db.users[i].ID)
+	} else {
+		twoFactor.BackupCodes = append([]string(nil), twoFactor.BackupCodes...)
+		db.twoFactor[db.users[i].ID] = twoFactor
+	}
+	db.users[i].TwoFactor = twoFactor.Secret != ""
+	db.users[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func (db *Database) UseTOTPStep(username string, step int64) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 || db.totpSteps[db.users[i].ID] >= step {
+		return false, nil
+	}
+	db.totpSteps[db.users[i].ID] = step
+	return true, nil
+}
+
+func (db *Database) UseBackupCode(username, codeHash string) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return false, nil
+	}
+	twoFactor := db.twoFactor[db.users[i].ID]
+	for j, code := range twoFactor.BackupCodes {
+		if code == codeHash {
+			twoFactor.BackupCodes = append(twoFactor.BackupCodes[:j:j], twoFactor.BackupCodes[j+1:]...)
+			db.twoFactor[db.users[i].ID] = twoFactor
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (db *Database) GetUserByOIDCSubject(subject string) ([]interfaces.Users, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, user := range db.users {
+		if subject != "" && db.subjects[user.ID] == subject {
+			return []interfaces.Users{user}, nil
+		}
+	}
+	return []interfaces.Users{}, nil
+}
+
+func (db *Database) SetOIDCSubject(username, subject string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("user not found")
+	}
+	if subject == "" {
+		delete(db.subjects, db.users[i].ID)
+		return nil
+	}
+	if linked := db.subjects[db.users[i].ID]; linked != "" && linked != subject {
+		return fmt.Errorf("%s is already linked to another identity", username)
+	}
+	for id, linked := range db.subjects {
+		if linked == subject && id != db.users[i].ID {
+			return fmt.Errorf("another user is already linked to %s", subject)
+		}
+	}
+	db.subjects[db.users[i].ID] = subject
+	db.users[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func (db *Database) RecordLogin(username string, at time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("user not found")
+	}
+	db.users[i].LastLogin = at
+	return nil
+}
+
+func (db *Database) GetUserRecords(username string) (interfaces.UserRecords, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	userID := ""
+	if i := db.findUser(username); i >= 0 {
+		userID = db.users[i].UserID
+	}
+	return db.userRecords(username, userID), nil
+}
+
+func (db *Database) TransferUserRecords(from, to string) (interfaces.UserRecords, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var moved interfaces.UserRecords
+	if from == to {
+		return moved, fmt.Errorf("cannot transfer records from %s to themselves", from)
+	}
+	f, t := db.findUser(from), db.findUser(to)
+	if f < 0 {
+		return moved, fmt.Errorf("user not found: %s", from)
+	}
+	if t < 0 {
+		return moved, fmt.Errorf("user not found: %s", to)
+	}
+	if db.users[t].Status == interfaces.UserInactive {
+		return moved, fmt.Errorf("cannot transfer records to %s: %w", to, interfaces.ErrInactiveUser)
+	}
+	fromID, toID := db.users[f].UserID, db.users[t].UserID
+	now := time.Now()
+
+	for i := range db.notes {
+		if db.notes[i].UserID == fromID {
+			db.notes[i].UserID, db.notes[i].Username = toID, to
+			db.notes[i].UpdatedAt, db.notes[i].Version = now, db.notes[i].Version+1
+			moved.Notes++
+			db.publish("notes", "UPDATE", db.notes[i].ID, to)
+		}
+	}
+	for i := range db.tasks {
+		changed := false
+		if db.tasks[i].UserID == fromID {
+			db.tasks[i].UserID, db.tasks[i].Username = toID, to
+			moved.Tasks++
+			changed = true
+		}
+		if db.tasks[i].Assignee == from {
+			db.tasks[i].Assignee = to
+			moved.AssignedTasks++
+			changed = true
+		}
+		if changed {
+			db.tasks[i].UpdatedAt, db.tasks[i].Version = now, db.tasks[i].Version+1
+			db.publish("tasks", "UPDATE", db.tasks[i].ID, db.tasks[i].Username)
+		}
+	}
+	for i := range db.audits {
+		audit := &db.audits[i]
+		changed := false
+		if audit.UserID == fromID {
+			audit.UserID, audit.Username = toID, to
+			moved.Audits++
+			changed = true
+		}
+		if audit.AssignedUser == from || contains(audit.AdditionalUsers, from) {
+			if audit.AssignedUser == from {
+				audit.AssignedUser = to
+			}
+			var additional []string
+			for _, username := range audit.AdditionalUsers {
+				if username == from {
+					username = to
+				}
+				if !contains(additional, username) {
+					additional = append(additional, username)
+				}
+			}
+			audit.AdditionalUsers = additional
+			moved.AssignedAudits++
+			changed = true
+		}
+		if changed {
+			audit.UpdatedAt, audit.Version = now, audit.Version+1
+			db.publish("audits", "UPDATE", audit.ID, audit.Username)
+		}
+	}
+	for i := range db.findings {
+		if db.findings[i].Owner == from {
+			db.findings[i].Owner = to
+			db.findings[i].UpdatedAt, db.findings[i].Version = now, db.findings[i].Version+1
+			moved.Findings++
+		}
+	}
+	for i := range db.crm {
+		if db.crm[i].UserID == fromID {
+			db.crm[i].UserID, db.crm[i].Username = toID, to
+			db.crm[i].UpdatedAt, db.crm[i].Version = now, db.crm[i].Version+1
+			moved.CRMEntries++
+			db.publish("crm", "UPDATE", db.crm[i].ID, to)
+		}
+	}
+	return moved, nil
+}
+
+func (db *Database) GetLoginAttempts(scope, name string) (interfaces.LoginAttempts, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if attempts, ok := db.attempts[[2]string{scope, name}]; ok {
+		return attempts, nil
+	}
+	return interfaces.LoginAttempts{Scope: scope, Name: name}, nil
+}
+
+func (db *Database) ListLoginAttempts() ([]interfaces.LoginAttempts, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var list []interfaces.LoginAttempts
+	for _, attempts := range db.attempts {
+		list = append(list, attempts)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastFailure.After(list[j].LastFailure) })
+	return list, nil
+}
+
+func (db *Database) RecordLoginFailure(scope, name string, at, since time.Time) (interfaces.LoginAttempts, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := [2]string{scope, name}
+	attempts, ok := db.attempts[key]
+	if !ok || attempts.LastFailure.Before(since) {
+		attempts = interfaces.LoginAttempts{Scope: scope, Name: name}
+	}
+	attempts.Failures++
+	if at.After(attempts.LastFailure) {
+		attempts.LastFailure = at
+	}
+	db.attempts[key] = attempts
+	return attempts, nil
+}
+
+func (db *Database) ClearLoginFailures(scope, name string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.attempts, [2]string{scope, name})
+	return nil
+}
+
+func (db *Database) AddChangeHistory(entry interfaces.ChangeHistory) (interfaces.ChangeHistory, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	db.nextID++
+	entry.ID = db.nextID
+	db.history = append(db.history, entry)
+	return entry, nil
+}
+
+func (db *Database) GetChangeHistory(limit int) ([]interfaces.ChangeHistory, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	entries := append([]interfaces.ChangeHistory(nil), db.history...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].At.Equal(entries[j].At) {
+			return entries[i].ID > entries[j].ID
+		}
+		return entries[i].At.After(entries[j].At)
+	})
+	if limit >= 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func (db *Database) SyncDirectoryUsers(users []interfaces.LDAPUser, complete bool) (interfaces.SyncResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var result interfaces.SyncResult
+	managers := make(map[string]string, len(users))
+	for _, user := range users {
+		managers[strings.ToLower(user.DN)] = user.Username
+	}
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		if user.DirectoryID == "" || user.Username == "" {
+			continue
+		}
+		seen[user.DirectoryID] = true
+		status := interfaces.UserActive
+		if user.Disabled {
+			status = interfaces.UserInactive
+		}
+
+		for i := range db.users {
+			if db.users[i].Username == user.Username && db.users[i].DirectoryID != "" && db.users[i].DirectoryID != user.DirectoryID {
+				db.users[i].Username = fmt.Sprintf("%s~%d", db.users[i].Username, db.users[i].ID)
+				db.users[i].Status = interfaces.UserInactive
+			}
+		}
+
+		match := -1
+		for i := range db.users {
+			if db.users[i].DirectoryID == user.DirectoryID {
+				match = i
+				break
+			}
+			if match < 0 && db.users[i].DirectoryID == "" && db.users[i].Username == user.Username {
+				match = i
+			}
+		}
+		created := match < 0
+		if created {
+			db.createUser(user.Username, interfaces.DirectoryUserID(user.DirectoryID))
+			match = len(db.users) - 1
+		}
+
+		stored := db.users[match]
+		updated := stored
+		updated.Username = user.Username
+		updated.Email = user.Email
+		updated.Status = status
+		updated.DirectoryID = user.DirectoryID
+		updated.DisplayName = user.DisplayName
+		updated.Department = user.Department
+		updated.Manager = managers[strings.ToLower(user.ManagerDN)]
+		switch {
+		case created:
+			result.Created++
+		case updated != stored:
+			result.Updated++
+		default:
+			continue
+		}
+		updated.UpdatedAt = time.Now()
+		db.users[match] = updated
+	}
+
+	if complete && len(seen) > 0 {
+		for i := range db.users {
+			if db.users[i].DirectoryID != "" && !seen[db.users[i].DirectoryID] && db.users[i].Status != interfaces.UserInactive {
+				db.users[i].Status = interfaces.UserInactive
+				result.Deactivated++
+			}
+		}
+	}
+	return result, nil
+}
+
+// ownerID returns the user_id of username, adding the user if needed, like
+// the foreign keys in the Postgres store
+func (db *Database) ownerID(username string) (string, error) {
+	if username == "" {
+		return "", fmt.Errorf("record has no owner")
+	}
+	if i := db.findUser(username); i >= 0 {
+		return db.users[i].UserID, nil
+	}
+	return db.createUser(username, interfaces.NewUserID()).UserID, nil
+}
+
+// userRecords counts the rows owned by userID or assigned to username
+func (db *Database) userRecords(username, userID string) interfaces.UserRecords {
+	var records interfaces.UserRecords
+	for _, note := range db.notes {
+		if note.UserID == userID {
+			records.Notes++
+		}
+	}
+	for _, task := range db.tasks {
+		if task.UserID == userID {
+			records.Tasks++
+		}
+		if task.Assignee == username {
+			records.AssignedTasks++
+		}
+	}
+	for _, audit := range db.audits {
+		if audit.UserID == userID {
+			records.Audits++
+		}
+		if audit.AssignedUser == username || contains(audit.AdditionalUsers, username) {
+			records.AssignedAudits++
+		}
+	}
+	for _, finding := range db.findings {
+		if finding.Owner != "" && finding.Owner == username {
+			records.Findings++
+		}
+	}
+	for _, crm := range db.crm {
+		if crm.UserID == userID {
+			records.CRMEntries++
+		}
+	}
+	for _, credential := range db.credentials {
+		if credential.UserID == userID {
+			records.Credentials++
+		}
+	}
+	return records
+}
+
+// checkAssignable refuses to give work to a user marked inactive
+func (db *Database) checkAssignable(username string) error {
+	if i := db.findUser(username); i >= 0 && db.users[i].Status == interfaces.UserInactive {
+		return fmt.Errorf("cannot assign work to %s: %w", username, interfaces.ErrInactiveUser)
+	}
+	return nil
+}
+
+// Notes
+
+func (db *Database) usernameFor(userID string) (string, bool) {
+	for _, user := range db.users {
+		if user.UserID == userID {
+			return user.Username, true
+		}
+	}
+	return "", false
+}
+
+// visibleNotes returns the notes owned by username or shared with everyone,
+// newest first. Notes whose owner no longer exists are hidden like the join
+// in the Postgres query.
+func (db *Database) visibleNotes(username string, match func(interfaces.Note) bool) []interfaces.Note {
+	ownerID := ""
+	for _, user := range db.users {
+		if strings.EqualFold(user.Username, username) {
+			ownerID = user.UserID
+			break
+		}
+	}
+
+	var notes []interfaces.Note
+	for _, note := range db.notes {
+		owner, ok := db.usernameFor(note.UserID)
+		if !ok {
+			continue
+		}
+		if note.UserID != ownerID && !note.Open {
+			continue
+		}
+		if !match(note) {
+			continue
+		}
+		note.Username = owner
+		notes = append(notes, note)
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.After(notes[j].CreatedAt) })
+	return notes
+}
+
+func (db *Database) GetNote(id int) (interfaces.Note, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, note := range db.notes {
+		if note.ID == id {
+			owner, ok := db.usernameFor(note.UserID)
+			if !ok {
+				break
+			}
+			note.Username = owner
+			return note, nil
+		}
+	}
+	return interfaces.Note{}, ErrNotFound
+}
+
+func (db *Database) GetNotes(username string) ([]interfaces.Note, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	notes := db.visibleNotes(username, func(interfaces.Note) bool { return true })
+	if len(notes) == 0 {
+		return notes, "No notes found", nil
+	}
+	return notes, "Notes fetched successfully", nil
+}
+
+func (db *Database) UpdateNote(note interfaces.Note) (interfaces.Note, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.notes {
+		if db.notes[i].ID != note.ID {
+			continue
+		}
+		if db.notes[i].Version != note.Version {
+			current := db.notes[i]
+			current.Username, _ = db.usernameFor(current.UserID)
+			return interfaces.Note{}, &interfaces.ConflictError{Entity: "note", ID: note.ID, Current: current}
+		}
+		stored := db.notes[i]
+		stored.Title = note.Title
+		stored.Content = note.Content
+		stored.Open = note.Open
+		stored.UpdatedAt = time.Now()
+		stored.Version++
+		db.notes[i] = stored
+
+		note.CreatedAt = stored.CreatedAt
+		note.UpdatedAt = stored.UpdatedAt
+		note.Version = stored.Version
+		db.publish("notes", "UPDATE", stored.ID, stored.Username)
+		return note, nil
+	}
+	return interfaces.Note{}, fmt.Errorf("note %d no longer exists: %w", note.ID, ErrNotFound)
+}
+
+func (db *Database) DeleteNote(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.notes {
+		if db.notes[i].ID == id {
+			username := db.notes[i].Username
+			db.notes = append(db.notes[:i], db.notes[i+1:]...)
+			db.publish("notes", "DELETE", id, username)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (db *Database) CreateNote(title, content string, username string, open bool) (interfaces.Note, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	user := interfaces.Users{}
+	if i := db.findUser(username); i >= 0 {
+		user = db.users[i]
+	} else {
+		user = db.createUser(username, interfaces.NewUserID())
+	}
+
+	now := time.Now()
+	note := interfaces.Note{
+		ID:        db.newID(),
+		Title:     title,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.UserID,
+		Username:  user.Username,
+		Open:      open,
+		Author:    user.Username,
+		Version:   1,
+	}
+	db.notes = append(db.notes, note)
+	db.publish("notes", "INSERT", note.ID, note.Username)
+	return note, nil
+}
+
+func (db *Database) SearchNotes(searchTerm string, username string) ([]interfaces.Note, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	term := strings.ToLower(searchTerm)
+	notes := db.visibleNotes(username, func(note interfaces.Note) bool {
+		return strings.Contains(strings.ToLower(note.Title), term) || strings.Contains(strings.ToLower(note.Content), term)
+	})
+	if len(notes) == 0 {
+		return notes, "No notes found", nil
+	}
+	return notes, "Notes fetched successfully", nil
+}
+
+// Tasks
+
+func (db *Database) GetTask(id int) (interfaces.Tasks, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, task := range db.tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+	return interfaces.Tasks{}, ErrNotFound
+}
+
+func (db *Database) GetTasks(username string) ([]interfaces.Tasks, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var tasks []interfaces.Tasks
+	for _, task := range db.tasks {
+		if task.Username == username || task.Assignee == username {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].DueDate.Before(tasks[j].DueDate) })
+
+	if len(tasks) == 0 {
+		return tasks, "No tasks found", nil
+	}
+	return tasks, "Tasks fetched successfully", nil
+}
+
+func (db *Database) GetAssignedTasks(assignees []string) ([]interfaces.Tasks, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	wanted := make(map[string]bool, len(assignees))
+	for _, assignee := range assignees {
+		wanted[assignee] = true
+	}
+	var tasks []interfaces.Tasks
+	for _, task := range db.tasks {
+		if task.Assignee != "" && wanted[task.Assignee] {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Assignee != tasks[j].Assignee {
+			return tasks[i].Assignee < tasks[j].Assignee
+		}
+		return tasks[i].DueDate.Before(tasks[j].DueDate)
+	})
+
+	if len(tasks) == 0 {
+		return tasks, "No tasks found", nil
+	}
+	return tasks, "Tasks fetched successfully", nil
+}
+
+func (db *Database) CreateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.checkAssignable(task.Assignee); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	if task.FindingID != 0 && db.findFinding(task.FindingID) < 0 {
+		return interfaces.Tasks{}, fmt.Errorf("finding %d no longer exists: %w", task.FindingID, ErrNotFound)
+	}
+	userID, err := db.ownerID(task.Username)
+	if err != nil {
+		return interfaces.Tasks{}, err
+	}
+	now := time.Now()
+	task.ID = db.newID()
+	task.UserID = userID
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.Version = 1
+	db.tasks = append(db.tasks, task)
+	db.publish("tasks", "INSERT", task.ID, task.Username)
+	return task, nil
+}
+
+func (db *Database) UpdateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.tasks {
+		if db.tasks[i].ID != task.ID {
+			continue
+		}
+		if db.tasks[i].Assignee != task.Assignee {
+			if err := db.checkAssignable(task.Assignee); err != nil {
+				return interfaces.Tasks{}, err
+			}
+		}
+		if db.tasks[i].Version != task.Version {
+			return interfaces.Tasks{}, &interfaces.ConflictError{Entity: "task", ID: task.ID, Current: db.tasks[i]}
+		}
+		stored := db.tasks[i]
+		stored.Title = task.Title
+		stored.Description = task.Description
+		stored.Status = task.Status
+		stored.Priority = task.Priority
+		stored.Notes = task.Notes
+		stored.DueDate = task.DueDate
+		stored.Completed = task.Completed
+		stored.Assignee = task.Assignee
+		stored.UpdatedAt = time.Now()
+		stored.Version++
+		db.tasks[i] = stored
+
+		task.CreatedAt = stored.CreatedAt
+		task.UpdatedAt = stored.UpdatedAt
+		task.Version = stored.Version
+		db.publish("tasks", "UPDATE", stored.ID, stored.Username)
+		return task, nil
+	}
+	return interfaces.Tasks{}, fmt.Errorf("task %d no longer exists: %w", task.ID, ErrNotFound)
+}
+
+func (db *Database) DeleteTask(id int, username string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.tasks {
+		if db.tasks[i].ID == id && db.tasks[i].Username == username {
+			db.tasks = append(db.tasks[:i], db.tasks[i+1:]...)
+			db.publish("tasks", "DELETE", id, username)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Audits
+
+func (db *Database) GetAudit(id int) (interfaces.Audits, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, audit := range db.audits {
+		if audit.ID == id {
+			return copyAudit(audit), nil
+		}
+	}
+	return interfaces.Audits{}, ErrNotFound
+}
+
+func (db *Database) GetAudits(username string) ([]interfaces.Audits, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var audits []interfaces.Audits
+	for _, audit := range db.audits {
+		if audit.Username == username || contains(audit.AdditionalUsers, username) {
+			audits = append(audits, copyAudit(audit))
+		}
+	}
+	sort.SliceStable(audits, func(i, j int) bool { return audits[i].CreatedAt.After(audits[j].CreatedAt) })
+
+	if len(audits) == 0 {
+		return audits, "No audits found", nil
+	}
+	return audits, "Audits fetched successfully", nil
+}
+
+func (db *Database) CreateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	userID, err := db.ownerID(audit.Username)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	for _, username := range audit.NewAssignees(interfaces.Audits{}) {
+		if err := db.checkAssignable(username); err != nil {
+			return interfaces.Audits{}, err
+		}
+	}
+	audit.ScheduledFor = dateOf(audit.ScheduledFor)
+	if audit.ScheduleID != 0 {
+		for _, other := range db.audits {
+			if other.ScheduleID == audit.ScheduleID && other.ScheduledFor.Equal(audit.ScheduledFor) {
+				return interfaces.Audits{}, fmt.Errorf("schedule %d, %s: %w", audit.ScheduleID,
+					audit.ScheduledFor.Format("2006-01-02"), interfaces.ErrAlreadyScheduled)
+			}
+		}
+	}
+	var items []interfaces.TemplateItem
+	if audit.TemplateID != 0 {
+		i := db.findTemplate(audit.TemplateID)
+		if i < 0 {
+			return interfaces.Audits{}, fmt.Errorf("template %d no longer exists: %w", audit.TemplateID, ErrNotFound)
+		}
+		template := db.templates[i]
+		if audit.AuditType == "" {
+			audit.AuditType = template.AuditType
+		}
+		if audit.AuditArea == "" {
+			audit.AuditArea = template.AuditArea
+		}
+		items = template.Items
+	}
+	now := time.Now()
+	audit.ID = db.newID()
+	audit.UserID = userID
+	audit.CreatedAt = now
+	audit.UpdatedAt = now
+	audit.Version = 1
+	// completed_at is only written by updates
+	stored := copyAudit(audit)
+	stored.CompletedAt = time.Time{}
+	db.audits = append(db.audits, stored)
+	for position, item := range items {
+		db.checklist = append(db.checklist, interfaces.ChecklistItem{ID: db.newID(), AuditID: audit.ID, Position: position + 1,
+			Title: item.Title, ExpectedEvidence: item.ExpectedEvidence, Guidance: item.Guidance, Version: 1})
+	}
+	db.publish("audits", "INSERT", audit.ID, audit.Username)
+	return audit, nil
+}
+
+func (db *Database) UpdateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.audits {
+		if db.audits[i].ID != audit.ID {
+			continue
+		}
+		for _, username := range audit.NewAssignees(db.audits[i]) {
+			if err := db.checkAssignable(username); err != nil {
+				return interfaces.Audits{}, err
+			}
+		}
+		if db.audits[i].Version != audit.Version {
+			return interfaces.Audits{}, &interfaces.ConflictError{Entity: "audit", ID: audit.ID, Current: copyAudit(db.audits[i])}
+		}
+		stored := db.audits[i]
+		stored.Action = audit.Action
+		stored.AuditID = audit.AuditID
+		stored.AuditType = audit.AuditType
+		stored.AuditArea = audit.AuditArea
+		stored.Notes = audit.Notes
+		stored.AssignedUser = audit.AssignedUser
+		stored.CompletedAt = audit.CompletedAt
+		stored.Completed = audit.Completed
+		stored.AdditionalUsers = append([]string(nil), audit.AdditionalUsers...)
+		stored.Firm = audit.Firm
+		stored.Reviewer = audit.Reviewer
+		stored.UpdatedAt = time.Now()
+		stored.Version++
+		db.audits[i] = stored
+
+		audit.CreatedAt = stored.CreatedAt
+		audit.UpdatedAt = stored.UpdatedAt
+		audit.Version = stored.Version
+		db.publish("audits", "UPDATE", stored.ID, stored.Username)
+		return audit, nil
+	}
+	return interfaces.Audits{}, fmt.Errorf("audit %d no longer exists: %w", audit.ID, ErrNotFound)
+}
+
+func (db *Database) ChangeAuditStatus(change interfaces.AuditStatusChange, version int, completed bool) (interfaces.Audits, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.audits {
+		audit := &db.audits[i]
+		if audit.ID != change.AuditID {
+			continue
+		}
+		if audit.Version != version {
+			return interfaces.Audits{}, &interfaces.ConflictError{Entity: "audit", ID: audit.ID, Current: copyAudit(*audit)}
+		}
+		if change.At.IsZero() {
+			change.At = time.Now()
+		}
+		audit.Status, audit.Completed = change.To, completed
+		switch {
+		case !completed:
+			audit.CompletedAt = time.Time{}
+		case audit.CompletedAt.IsZero():
+			audit.CompletedAt = change.At
+		}
+		audit.UpdatedAt, audit.Version = change.At, audit.Version+1
+		change.ID = db.newID()
+		db.auditSteps = append(db.auditSteps, change)
+		db.publish("audits", "UPDATE", audit.ID, audit.Username)
+		return copyAudit(*audit), nil
+	}
+	return interfaces.Audits{}, fmt.Errorf("audit %d no longer exists: %w", change.AuditID, ErrNotFound)
+}
+
+func (db *Database) GetAuditStatusChanges(auditID int) ([]interfaces.AuditStatusChange, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var changes []interfaces.AuditStatusChange
+	for _, change := range db.auditSteps {
+		if change.AuditID == auditID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (db *Database) DeleteAudit(id int, username string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.audits {
+		if db.audits[i].ID == id && db.audits[i].Username == username {
+			db.audits = append(db.audits[:i], db.audits[i+1:]...)
+			db.cascadeAudit(id)
+			db.publish("audits", "DELETE", id, username)
+			return nil
+		}
+	}
+	return nil
+}
+
+func copyAudit(audit interfaces.Audits) interfaces.Audits {
+	audit.AdditionalUsers = append([]string(nil), audit.AdditionalUsers...)
+	return audit
+}
+
+// Findings
+
+func (db *Database) findFinding(id int) int {
+	for i, finding := range db.findings {
+		if finding.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// finding returns a copy of a stored finding with the tasks that link to it
+// and the controls it is mapped to
+func (db *Database) finding(i int) interfaces.Finding {
+	finding := db.findings[i]
+	finding.Controls = append([]int(nil), db.findingControls[finding.ID]...)
+	finding.Tasks = nil
+	for _, task := range db.tasks {
+		if task.FindingID == finding.ID {
+			finding.Tasks = append(finding.Tasks, task.ID)
+		}
+	}
+	sort.Ints(finding.Tasks)
+	return finding
+}
+
+// cascadeAudit deletes the findings, evidence, checklist and control
+// mappings of a deleted audit, like the cascade in Postgres
+func (db *Database) cascadeAudit(auditID int) {
+	for i := len(db.checklist) - 1; i >= 0; i-- {
+		if db.checklist[i].AuditID == auditID {
+			delete(db.itemControls, db.checklist[i].ID)
+			db.checklist = append(db.checklist[:i], db.checklist[i+1:]...)
+		}
+	}
+	delete(db.auditControls, auditID)
+	for i := len(db.findings) - 1; i >= 0; i-- {
+		if db.findings[i].AuditID == auditID {
+			db.unlinkFinding(db.findings[i].ID)
+			db.findings = append(db.findings[:i], db.findings[i+1:]...)
+		}
+	}
+	for i := len(db.evidence) - 1; i >= 0; i-- {
+		if db.evidence[i].AuditID == auditID {
+			delete(db.content, db.evidence[i].ID)
+			db.evidence = append(db.evidence[:i], db.evidence[i+1:]...)
+		}
+	}
+}
+
+// unlinkFinding keeps the tasks and evidence of a deleted finding, and drops
+// its control mappings
+func (db *Database) unlinkFinding(findingID int) {
+	delete(db.findingControls, findingID)
+	for i := range db.tasks {
+		if db.tasks[i].FindingID == findingID {
+			db.tasks[i].FindingID = 0
+		}
+	}
+	for i := range db.evidence {
+		if db.evidence[i].FindingID == findingID {
+			db.evidence[i].FindingID = 0
+		}
+	}
+}
+
+func (db *Database) GetFinding(id int) (interfaces.Finding, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findFinding(id); i >= 0 {
+		return db.finding(i), nil
+	}
+	return interfaces.Finding{}, ErrNotFound
+}
+
+func (db *Database) GetFindings(auditID int) ([]interfaces.Finding, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var findings []interfaces.Finding
+	for i := range db.findings {
+		if db.findings[i].AuditID == auditID {
+			findings = append(findings, db.finding(i))
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if rank := interfaces.SeverityRank(a.Severity) - interfaces.SeverityRank(b.Severity); rank != 0 {
+			return rank < 0
+		}
+		if !a.DueDate.Equal(b.DueDate) {
+			// Findings without a due date go last
+			return !a.DueDate.IsZero() && (b.DueDate.IsZero() || a.DueDate.Before(b.DueDate))
+		}
+		return a.ID < b.ID
+	})
+	return findings, nil
+}
+
+func (db *Database) CreateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := finding.Validate(); err != nil {
+		return interfaces.Finding{}, err
+	}
+	if err := db.checkAssignable(finding.Owner); err != nil {
+		return interfaces.Finding{}, err
+	}
+	exists := false
+	for _, audit := range db.audits {
+		exists = exists || audit.ID == finding.AuditID
+	}
+	if !exists {
+		return interfaces.Finding{}, fmt.Errorf("audit %d no longer exists: %w", finding.AuditID, ErrNotFound)
+	}
+	now := time.Now()
+	finding.ID = db.newID()
+	finding.Tasks = nil
+	finding.CreatedAt = now
+	finding.UpdatedAt = now
+	finding.Version = 1
+	db.findings = append(db.findings, finding)
+	return finding, nil
+}
+
+func (db *Database) UpdateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := finding.Validate(); err != nil {
+		return interfaces.Finding{}, err
+	}
+	i := db.findFinding(finding.ID)
+	if i < 0 {
+		return interfaces.Finding{}, fmt.Errorf("finding %d no longer exists: %w", finding.ID, ErrNotFound)
+	}
+	stored := db.findings[i]
+	if stored.Owner != finding.Owner {
+		if err := db.checkAssignable(finding.Owner); err != nil {
+			return interfaces.Finding{}, err
+		}
+	}
+	if stored.Version != finding.Version {
+		return interfaces.Finding{}, &interfaces.ConflictError{Entity: "finding", ID: finding.ID, Current: db.finding(i)}
+	}
+	stored.Title = finding.Title
+	stored.Description = finding.Description
+	stored.Severity = finding.Severity
+	stored.Area = finding.Area
+	stored.Recommendation = finding.Recommendation
+	stored.Owner = finding.Owner
+	stored.DueDate = finding.DueDate
+	stored.Status = finding.Status
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	db.findings[i] = stored
+	return db.finding(i), nil
+}
+
+func (db *Database) DeleteFinding(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findFinding(id); i >= 0 {
+		db.unlinkFinding(id)
+		db.findings = append(db.findings[:i], db.findings[i+1:]...)
+	}
+	return nil
+}
+
+// Evidence
+
+func (db *Database) findEvidence(id int) int {
+	for i, evidence := range db.evidence {
+		if evidence.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// storedEvidence returns a copy of stored evidence with the version that
+// replaced it
+func (db *Database) storedEvidence(i int) interfaces.Evidence {
+	evidence := db.evidence[i]
+	evidence.ReplacedBy = 0
+	for _, newer := range db.evidence {
+		if newer.Replaces == evidence.ID {
+			evidence.ReplacedBy = newer.ID
+		}
+	}
+	return evidence
+}
+
+func (db *Database) AddEvidence(evidence interfaces.Evidence, content []byte) (interfaces.Evidence, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case evidence.Storage == interfaces.EvidenceInDatabase && !evidence.Matches(content):
+		return interfaces.Evidence{}, fmt.Errorf("uploading %s: %w", evidence.Name, interfaces.ErrEvidenceTampered)
+	case evidence.Storage != interfaces.EvidenceInDatabase && evidence.Storage != interfaces.EvidenceInDirectory:
+		return interfaces.Evidence{}, fmt.Errorf("unknown evidence storage %q", evidence.Storage)
+	}
+	exists := false
+	for _, audit := range db.audits {
+		exists = exists || audit.ID == evidence.AuditID
+	}
+	if !exists {
+		return interfaces.Evidence{}, fmt.Errorf("audit %d no longer exists: %w", evidence.AuditID, ErrNotFound)
+	}
+	if evidence.FindingID != 0 {
+		if i := db.findFinding(evidence.FindingID); i < 0 || db.findings[i].AuditID != evidence.AuditID {
+			return interfaces.Evidence{}, fmt.Errorf("finding %d is not part of audit %d", evidence.FindingID, evidence.AuditID)
+		}
+	}
+	evidence.Version = 1
+	if evidence.Replaces != 0 {
+		i := db.findEvidence(evidence.Replaces)
+		if i < 0 {
+			return interfaces.Evidence{}, fmt.Errorf("evidence %d no longer exists: %w", evidence.Replaces, ErrNotFound)
+		}
+		previous := db.storedEvidence(i)
+		if previous.AuditID != evidence.AuditID {
+			return interfaces.Evidence{}, fmt.Errorf("evidence %d is not part of audit %d", previous.ID, evidence.AuditID)
+		}
+		if previous.ReplacedBy != 0 {
+			current := db.storedEvidence(db.findEvidence(previous.ReplacedBy))
+			return interfaces.Evidence{}, &interfaces.ConflictError{Entity: "evidence", ID: previous.ID, Current: current}
+		}
+		evidence.Version = previous.Version + 1
+	}
+	evidence.ID = db.newID()
+	evidence.ReplacedBy = 0
+	if evidence.UploadedAt.IsZero() {
+		evidence.UploadedAt = time.Now()
+	}
+	if evidence.Storage == interfaces.EvidenceInDatabase {
+		db.content[evidence.ID] = append([]byte(nil), content...)
+	}
+	db.evidence = append(db.evidence, evidence)
+	return evidence, nil
+}
+
+func (db *Database) GetEvidence(id int) (interfaces.Evidence, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findEvidence(id); i >= 0 {
+		return db.storedEvidence(i), nil
+	}
+	return interfaces.Evidence{}, ErrNotFound
+}
+
+func (db *Database) ListEvidence(auditID int) ([]interfaces.Evidence, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var evidence []interfaces.Evidence
+	for i := range db.evidence {
+		if db.evidence[i].AuditID == auditID {
+			evidence = append(evidence, db.storedEvidence(i))
+		}
+	}
+	sort.SliceStable(evidence, func(i, j int) bool { return evidence[i].UploadedAt.Before(evidence[j].UploadedAt) })
+	return evidence, nil
+}
+
+func (db *Database) ReadEvidence(id int) ([]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.findEvidence(id) < 0 {
+		return nil, fmt.Errorf("evidence %d: %w", id, ErrNotFound)
+	}
+	content, ok := db.content[id]
+	if !ok {
+		return nil, fmt.Errorf("evidence %d is not stored in the database", id)
+	}
+	return append([]byte(nil), content...), nil
+}
+
+// Corrupt changes the stored content of evidence, as a disk fault or
+// someone with access to the database could, so tests can check downloads
+// are verified
+func (db *Database) Corrupt(id int, content []byte) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.content[id] = append([]byte(nil), content...)
+}
+
+// Templates
+
+func (db *Database) findTemplate(id int) int {
+	for i, template := range db.templates {
+		if template.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyTemplate copies a template's slices, leaving empty ones nil like Postgres
+func copyTemplate(template interfaces.AuditTemplate) interfaces.AuditTemplate {
+	template.Items = append([]interfaces.TemplateItem(nil), template.Items...)
+	template.FindingCategories = append([]string(nil), template.FindingCategories...)
+	return template
+}
+
+// checkTemplateName mirrors the unique name of templates in Postgres
+func (db *Database) checkTemplateName(template interfaces.AuditTemplate) error {
+	for _, other := range db.templates {
+		if other.Name == template.Name && other.ID != template.ID {
+			return fmt.Errorf("%w: a template named %q already exists", interfaces.ErrInvalidTemplate, template.Name)
+		}
+	}
+	return nil
+}
+
+func (db *Database) GetTemplate(id int) (interfaces.AuditTemplate, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findTemplate(id); i >= 0 {
+		return copyTemplate(db.templates[i]), nil
+	}
+	return interfaces.AuditTemplate{}, ErrNotFound
+}
+
+func (db *Database) GetTemplates() ([]interfaces.AuditTemplate, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var templates []interfaces.AuditTemplate
+	for _, template := range db.templates {
+		templates = append(templates, copyTemplate(template))
+	}
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+func (db *Database) CreateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := template.Validate(); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	template.ID = 0
+	if err := db.checkTemplateName(template); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	now := time.Now()
+	template.ID = db.newID()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	template.Version = 1
+	template = copyTemplate(template)
+	db.templates = append(db.templates, template)
+	return copyTemplate(template), nil
+}
+
+func (db *Database) UpdateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := template.Validate(); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	i := db.findTemplate(template.ID)
+	if i < 0 {
+		return interfaces.AuditTemplate{}, fmt.Errorf("template %d no longer exists: %w", template.ID, ErrNotFound)
+	}
+	stored := db.templates[i]
+	if stored.Version != template.Version {
+		return interfaces.AuditTemplate{}, &interfaces.ConflictError{Entity: "template", ID: template.ID, Current: copyTemplate(stored)}
+	}
+	if err := db.checkTemplateName(template); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	template.CreatedAt = stored.CreatedAt
+	template.UpdatedAt = time.Now()
+	template.Version = stored.Version + 1
+	db.templates[i] = copyTemplate(template)
+	return copyTemplate(template), nil
+}
+
+// DeleteTemplate leaves the checklists of audits created from the template
+func (db *Database) DeleteTemplate(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findTemplate(id); i >= 0 {
+		db.templates = append(db.templates[:i], db.templates[i+1:]...)
+		for j := range db.audits {
+			if db.audits[j].TemplateID == id {
+				db.audits[j].TemplateID = 0
+			}
+		}
+		for j := range db.schedules {
+			if db.schedules[j].TemplateID == id {
+				db.schedules[j].TemplateID = 0
+			}
+		}
+	}
+	return nil
+}
+
+// checklistItem returns a copy of a stored checklist item with its controls
+func (db *Database) checklistItem(i int) interfaces.ChecklistItem {
+	item := db.checklist[i]
+	item.Controls = append([]int(nil), db.itemControls[item.ID]...)
+	return item
+}
+
+func (db *Database) GetChecklist(auditID int) ([]interfaces.ChecklistItem, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var items []interfaces.ChecklistItem
+	for i, item := range db.checklist {
+		if item.AuditID == auditID {
+			items = append(items, db.checklistItem(i))
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Position < items[j].Position })
+	return items, nil
+}
+
+func (db *Database) UpdateChecklistItem(item interfaces.ChecklistItem) (interfaces.ChecklistItem, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := item.Validate(); err != nil {
+		return interfaces.ChecklistItem{}, err
+	}
+	for i := range db.checklist {
+		stored := &db.checklist[i]
+		if stored.ID != item.ID {
+			continue
+		}
+		if stored.Version != item.Version {
+			return interfaces.ChecklistItem{}, &interfaces.ConflictError{Entity: "checklist item", ID: item.ID, Current: db.checklistItem(i)}
+		}
+		if item.UpdatedAt.IsZero() {
+			item.UpdatedAt = time.Now()
+		}
+		stored.Result = item.Result
+		stored.Comment = item.Comment
+		stored.UpdatedBy = item.UpdatedBy
+		stored.UpdatedAt = item.UpdatedAt
+		stored.Version++
+		return db.checklistItem(i), nil
+	}
+	return interfaces.ChecklistItem{}, fmt.Errorf("checklist item %d no longer exists: %w", item.ID, ErrNotFound)
+}
+
+// Controls
+
+func (db *Database) findControl(framework, controlID string) int {
+	for i, control := range db.controls {
+		if control.Framework == framework && control.ControlID == controlID {
+			return i
+		}
+	}
+	return -1
+}
+
+// controlsByID returns the stored controls with ids, or an error naming the
+// first that does not exist
+func (db *Database) controlsByID(ids []int) ([]interfaces.Control, error) {
+	var controls []interfaces.Control
+	for _, id := range ids {
+		found := false
+		for _, control := range db.controls {
+			if control.ID == id {
+				controls, found = append(controls, control), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("control %d no longer exists: %w", id, ErrNotFound)
+		}
+	}
+	return controls, nil
+}
+
+func sortControls(controls []interfaces.Control) {
+	sort.SliceStable(controls, func(i, j int) bool {
+		if controls[i].Framework != controls[j].Framework {
+			return controls[i].Framework < controls[j].Framework
+		}
+		return controls[i].ControlID < controls[j].ControlID
+	})
+}
+
+// uniqueIDs sorts ids and drops repeats, as the mapping tables' keys do
+func uniqueIDs(ids []int) []int {
+	unique := append([]int(nil), ids...)
+	sort.Ints(unique)
+	for i := len(unique) - 1; i > 0; i-- {
+		if unique[i] == unique[i-1] {
+			unique = append(unique[:i], unique[i+1:]...)
+		}
+	}
+	return unique
+}
+
+func (db *Database) ImportControls(framework string, controls []interfaces.Control) (interfaces.ControlImport, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var result interfaces.ControlImport
+	seen := make(map[string]bool, len(controls))
+	for _, control := range controls {
+		control.Framework = framework
+		if err := control.Validate(); err != nil {
+			return result, err
+		}
+		if seen[control.ControlID] {
+			return result, fmt.Errorf("%w: control %s appears twice", interfaces.ErrInvalidControl, control.ControlID)
+		}
+		seen[control.ControlID] = true
+	}
+	now := time.Now()
+	for _, control := range controls {
+		control.Framework = framework
+		control.UpdatedAt = now
+		i := db.findControl(framework, control.ControlID)
+		switch {
+		case i < 0:
+			control.ID = db.newID()
+			db.controls = append(db.controls, control)
+			result.Added++
+		case db.controls[i].Title == control.Title && db.controls[i].Description == control.Description &&
+			db.controls[i].Family == control.Family:
+			result.Unchanged++
+		default:
+			control.ID = db.controls[i].ID
+			db.controls[i] = control
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+func (db *Database) GetControls(framework string) ([]interfaces.Control, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var controls []interfaces.Control
+	for _, control := range db.controls {
+		if framework == "" || control.Framework == framework {
+			controls = append(controls, control)
+		}
+	}
+	sortControls(controls)
+	return controls, nil
+}
+
+// dropControl removes a deleted control from every mapping
+func dropControl(mappings map[int][]int, controlID int) {
+	for id, controls := range mappings {
+		for i := len(controls) - 1; i >= 0; i-- {
+			if controls[i] == controlID {
+				controls = append(controls[:i], controls[i+1:]...)
+			}
+		}
+		if len(controls) == 0 {
+			delete(mappings, id)
+		} else {
+			mappings[id] = controls
+		}
+	}
+}
+
+func (db *Database) DeleteFramework(framework string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := len(db.controls) - 1; i >= 0; i-- {
+		if db.controls[i].Framework == framework {
+			dropControl(db.auditControls, db.controls[i].ID)
+			dropControl(db.itemControls, db.controls[i].ID)
+			dropControl(db.findingControls, db.controls[i].ID)
+			db.controls = append(db.controls[:i], db.controls[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (db *Database) GetAuditControls(auditID int) ([]interfaces.Control, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	controls, err := db.controlsByID(db.auditControls[auditID])
+	sortControls(controls)
+	return controls, err
+}
+
+func (db *Database) SetAuditControls(auditID int, controlIDs []int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	exists := false
+	for _, audit := range db.audits {
+		exists = exists || audit.ID == auditID
+	}
+	if !exists {
+		return fmt.Errorf("audit %d no longer exists: %w", auditID, ErrNotFound)
+	}
+	if _, err := db.controlsByID(controlIDs); err != nil {
+		return err
+	}
+	if ids := uniqueIDs(controlIDs); len(ids) > 0 {
+		db.auditControls[auditID] = ids
+	} else {
+		delete(db.auditControls, auditID)
+	}
+	return nil
+}
+
+func (db *Database) SetChecklistItemControls(itemID int, controlIDs []int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	exists := false
+	for _, item := range db.checklist {
+		exists = exists || item.ID == itemID
+	}
+	if !exists {
+		return fmt.Errorf("checklist item %d no longer exists: %w", itemID, ErrNotFound)
+	}
+	if _, err := db.controlsByID(controlIDs); err != nil {
+		return err
+	}
+	if ids := uniqueIDs(controlIDs); len(ids) > 0 {
+		db.itemControls[itemID] = ids
+	} else {
+		delete(db.itemControls, itemID)
+	}
+	return nil
+}
+
+func (db *Database) SetFindingControls(findingID int, controlIDs []int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.findFinding(findingID) < 0 {
+		return fmt.Errorf("finding %d no longer exists: %w", findingID, ErrNotFound)
+	}
+	if _, err := db.controlsByID(controlIDs); err != nil {
+		return err
+	}
+	if ids := uniqueIDs(controlIDs); len(ids) > 0 {
+		db.findingControls[findingID] = ids
+	} else {
+		delete(db.findingControls, findingID)
+	}
+	return nil
+}
+
+func (db *Database) GetControlLinks(framework string) ([]interfaces.ControlLink, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	inFramework := make(map[int]bool)
+	for _, control := range db.controls {
+		inFramework[control.ID] = framework == "" || control.Framework == framework
+	}
+	audits := make(map[int]interfaces.Audits, len(db.audits))
+	for _, audit := range db.audits {
+		audits[audit.ID] = audit
+	}
+	// Open findings by the control and audit they are mapped to
+	type key struct{ control, audit int }
+	openFindings := make(map[key]int)
+	findingAudits := make(map[key]bool)
+	for _, finding := range db.findings {
+		for _, controlID := range db.findingControls[finding.ID] {
+			k := key{controlID, finding.AuditID}
+			findingAudits[k] = true
+			if !finding.Closed() {
+				openFindings[k]++
+			}
+		}
+	}
+	link := func(controlID, auditID, itemID int, result string) interfaces.ControlLink {
+		audit := audits[auditID]
+		return interfaces.ControlLink{ControlID: controlID, AuditID: auditID, Firm: audit.Firm, AuditCompleted: audit.Completed,
+			ItemID: itemID, Result: result, OpenFindings: openFindings[key{controlID, auditID}]}
+	}
+
+	var links []interfaces.ControlLink
+	for auditID, controls := range db.auditControls {
+		for _, controlID := range controls {
+			if inFramework[controlID] {
+				delete(findingAudits, key{controlID, auditID})
+				links = append(links, link(controlID, auditID, 0, interfaces.ChecklistPending))
+			}
+		}
+	}
+	// A mapped finding brings its audit in, once, when the audit itself is not mapped
+	for k := range findingAudits {
+		if inFramework[k.control] {
+			links = append(links, link(k.control, k.audit, 0, interfaces.ChecklistPending))
+		}
+	}
+	for _, item := range db.checklist {
+		for _, controlID := range db.itemControls[item.ID] {
+			if inFramework[controlID] {
+				links = append(links, link(controlID, item.AuditID, item.ID, item.Result))
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.ControlID != b.ControlID {
+			return a.ControlID < b.ControlID
+		}
+		if a.AuditID != b.AuditID {
+			return a.AuditID < b.AuditID
+		}
+		return a.ItemID < b.ItemID
+	})
+	return links, nil
+}
+
+// Schedules
+
+// dateOf truncates t to its calendar day at midnight UTC, as Postgres reads
+// back a DATE
+func dateOf(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (db *Database) findSchedule(id int) int {
+	for i, schedule := range db.schedules {
+		if schedule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *Database) GetSchedule(id int) (interfaces.AuditSchedule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findSchedule(id); i >= 0 {
+		return db.schedules[i], nil
+	}
+	return interfaces.AuditSchedule{}, ErrNotFound
+}
+
+func (db *Database) GetSchedules() ([]interfaces.AuditSchedule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	schedules := append([]interfaces.AuditSchedule(nil), db.schedules...)
+	sort.SliceStable(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	return schedules, nil
+}
+
+func (db *Database) CreateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := schedule.Validate(); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	if err := db.checkAssignable(schedule.Assignee); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	now := time.Now()
+	schedule.ID = db.newID()
+	schedule.StartDate = dateOf(schedule.StartDate)
+	schedule.CreatedThrough = time.Time{}
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	schedule.Version = 1
+	db.schedules = append(db.schedules, schedule)
+	return schedule, nil
+}
+
+func (db *Database) UpdateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := schedule.Validate(); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	if err := db.checkAssignable(schedule.Assignee); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	i := db.findSchedule(schedule.ID)
+	if i < 0 {
+		return interfaces.AuditSchedule{}, fmt.Errorf("schedule %d no longer exists: %w", schedule.ID, ErrNotFound)
+	}
+	stored := db.schedules[i]
+	if stored.Version != schedule.Version {
+		return interfaces.AuditSchedule{}, &interfaces.ConflictError{Entity: "schedule", ID: schedule.ID, Current: stored}
+	}
+	schedule.StartDate = dateOf(schedule.StartDate)
+	schedule.CreatedBy = stored.CreatedBy
+	schedule.CreatedThrough = stored.CreatedThrough
+	schedule.CreatedAt = stored.CreatedAt
+	schedule.UpdatedAt = time.Now()
+	schedule.Version = stored.Version + 1
+	db.schedules[i] = schedule
+	return schedule, nil
+}
+
+// DeleteSchedule keeps the audits the schedule created
+func (db *Database) DeleteSchedule(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findSchedule(id); i >= 0 {
+		db.schedules = append(db.schedules[:i], db.schedules[i+1:]...)
+		for j := range db.audits {
+			if db.audits[j].ScheduleID == id {
+				db.audits[j].ScheduleID = 0
+			}
+		}
+	}
+	return nil
+}
+
+func (db *Database) SetScheduleCreatedThrough(id int, through time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if i := db.findSchedule(id); i >= 0 && dateOf(through).After(db.schedules[i].CreatedThrough) {
+		db.schedules[i].CreatedThrough = dateOf(through)
+	}
+	return nil
+}
+
+func (db *Database) GetScheduledAudits(from, to time.Time) ([]interfaces.Audits, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	from, to = dateOf(from), dateOf(to)
+	var audits []interfaces.Audits
+	for _, audit := range db.audits {
+		if audit.ScheduleID != 0 && !audit.ScheduledFor.Before(from) && !audit.ScheduledFor.After(to) {
+			audits = append(audits, copyAudit(audit))
+		}
+	}
+	sort.SliceStable(audits, func(i, j int) bool { return audits[i].ScheduledFor.Before(audits[j].ScheduledFor) })
+	return audits, nil
+}
+
+// CRM
+
+func (db *Database) GetCRMEntry(id int) (interfaces.CRM, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, crm := range db.crm {
+		if crm.ID == id {
+			return copyCRM(crm), nil
+		}
+	}
+	return interfaces.CRM{}, ErrNotFound
+}
+
+func (db *Database) GetCRMEntries(username string) ([]interfaces.CRM, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var entries []interfaces.CRM
+	for _, crm := range db.crm {
+		if crm.Username == username || crm.Open {
+			entries = append(entries, copyCRM(crm))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].UpdatedAt.After(entries[j].UpdatedAt) })
+
+	if len(entries) == 0 {
+		return entries, "No CRM entries found", nil
+	}
+	return entries, "CRM entries fetched successfully", nil
+}
+
+func (db *Database) CreateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	userID, err := db.ownerID(crm.Username)
+	if err != nil {
+		return interfaces.CRM{}, err
+	}
+	now := time.Now()
+	crm.ID = db.newID()
+	crm.UserID = userID
+	crm.CreatedAt = now
+	crm.UpdatedAt = now
+	crm.Version = 1
+	db.crm = append(db.crm, copyCRM(crm))
+	db.publish("crm", "INSERT", crm.ID, crm.Username)
+	return crm, nil
+}
+
+func (db *Database) UpdateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.crm {
+		if db.crm[i].ID != crm.ID {
+			continue
+		}
+		if db.crm[i].Version != crm.Version {
+			return interfaces.CRM{}, &interfaces.ConflictError{Entity: "CRM entry", ID: crm.ID, Current: copyCRM(db.crm[i])}
+		}
+		stored := db.crm[i]
+		stored.Name = crm.Name
+		stored.Email = crm.Email
+		stored.Phone = crm.Phone
+		stored.Company = crm.Company
+		stored.Notes = append([]string(nil), crm.Notes...)
+		stored.Open = crm.Open
+		stored.UpdatedAt = time.Now()
+		stored.Version++
+		db.crm[i] = stored
+
+		crm.CreatedAt = stored.CreatedAt
+		crm.UpdatedAt = stored.UpdatedAt
+		crm.Version = stored.Version
+		db.publish("crm", "UPDATE", stored.ID, stored.Username)
+		return crm, nil
+	}
+	return interfaces.CRM{}, fmt.Errorf("CRM entry %d no longer exists: %w", crm.ID, ErrNotFound)
+}
+
+func (db *Database) DeleteCRMEntry(id int, username string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.crm {
+		if db.crm[i].ID == id && db.crm[i].Username == username {
+			db.crm = append(db.crm[:i], db.crm[i+1:]...)
+			db.publish("crm", "DELETE", id, username)
+			return nil
+		}
+	}
+	return nil
+}
+
+func copyCRM(crm interfaces.CRM) interfaces.CRM {
+	crm.Notes = append([]string(nil), crm.Notes...)
+	return crm
+}
+
+// Credentials
+
+func (db *Database) ownedCredentials(owner string, match func(interfaces.Credentials) bool) []interfaces.Credentials {
+	var credentials []interfaces.Credentials
+	for _, credential := range db.credentials {
+		if credential.Owner == owner && match(credential) {
+			credentials = append(credentials, copyCredential(credential))
+		}
+	}
+	sort.SliceStable(credentials, func(i, j int) bool { return credentials[i].CreatedAt.After(credentials[j].CreatedAt) })
+	return credentials
+}
+
+func (db *Database) GetCredentials(owner string) ([]interfaces.Credentials, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	credentials := db.ownedCredentials(owner, func(interfaces.Credentials) bool { return true })
+	if len(credentials) == 0 {
+		return credentials, "No credentials were found.", nil
+	}
+	return credentials, "", nil
+}
+
+func (db *Database) GetCredentialByLoginName(loginName string) ([]interfaces.Credentials, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, credential := range db.credentials {
+		if credential.LoginName == loginName {
+			return []interfaces.Credentials{copyCredential(credential)}, nil
+		}
+	}
+	return nil, fmt.Errorf("error getting credential: %w", ErrNotFound)
+}
+
+func (db *Database) CreateCredential(credential interfaces.Credentials) (interfaces.Credentials, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	owner := credential.Owner
+	if owner == "" {
+		owner = credential.Username
+	}
+	userID, err := db.ownerID(owner)
+	if err != nil {
+		return interfaces.Credentials{}, err
+	}
+	now := time.Now()
+	credential.ID = db.newID()
+	credential.UserID = userID
+	credential.CreatedAt = now
+	credential.UpdatedAt = now
+	db.credentials = append(db.credentials, copyCredential(credential))
+	return credential, nil
+}
+
+func (db *Database) UpdateCredential(credential interfaces.Credentials) (interfaces.Credentials, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.credentials {
+		if db.credentials[i].ID != credential.ID {
+			continue
+		}
+		stored := db.credentials[i]
+		stored.Site = credential.Site
+		stored.Program = credential.Program
+		stored.Username = credential.Username
+		stored.MasterPassword = credential.MasterPassword
+		stored.LoginName = credential.LoginName
+		stored.LoginPass = credential.LoginPass
+		stored.Owner = credential.Owner
+		stored.PasswordHistory = append([]string(nil), credential.PasswordHistory...)
+		stored.UpdatedAt = time.Now()
+		db.credentials[i] = stored
+
+		credential.CreatedAt = stored.CreatedAt
+		credential.UpdatedAt = stored.UpdatedAt
+		return credential, nil
+	}
+	return interfaces.Credentials{}, ErrNotFound
+}
+
+func (db *Database) DeleteCredential(id int, owner string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.credentials {
+		if db.credentials[i].ID == id && db.credentials[i].Owner == owner {
+			db.credentials = append(db.credentials[:i], db.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (db *Database) SearchCredentials(searchTerm, owner string) ([]interfaces.Credentials, string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	term := strings.ToLower(searchTerm)
+	credentials := db.ownedCredentials(owner, func(credential interfaces.Credentials) bool {
+		return strings.Contains(strings.ToLower(credential.LoginName), term) ||
+			strings.Contains(strings.ToLower(credential.Site), term)
+	})
+	if len(credentials) == 0 {
+		return credentials, "No credentials found", nil
+	}
+	return credentials, "Credentials fetched successfully", nil
+}
+
+func (db *Database) CreateCredUser(username string, hashedPassword string, email string) (*interfaces.Credentials, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.findUser(username)
+	if i < 0 {
+		return nil, fmt.Errorf("no user found with username: %s", username)
+	}
+
+	now := time.Now()
+	credential := interfaces.Credentials{
+		ID:             db.newID(),
+		UserID:         db.users[i].UserID,
+		Username:       username,
+		MasterPassword: hashedPassword,
+		Email:          email,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	db.credentials = append(db.credentials, credential)
+
+	created := credential
+	created.MasterPassword = ""
+	created.Email = ""
+	return &created, nil
+}
+
+func (db *Database) GetUserPassword(username string) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, credential := range db.credentials {
+		if credential.Username == username {
+			return credential.MasterPassword, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func copyCredential(credential interfaces.Credentials) interfaces.Credentials {
+	if credential.PasswordHistory == nil {
+		credential.PasswordHistory = []string{}
+	} else {
+		credential.PasswordHistory = append([]string(nil), credential.PasswordHistory...)
+	}
+	return credential
+}
+
+// Records
+
+// summaries must be called with db.mu held
+func (db *Database) summaries(kind string) ([]interfaces.RecordSummary, error) {
+	var records []interfaces.RecordSummary
+	switch kind {
+	case interfaces.RecordNotes:
+		for _, note := range db.notes {
+			records = append(records, interfaces.RecordSummary{Kind: kind, ID: note.ID, Title: note.Title, Owner: note.Username,
+				CreatedAt: note.CreatedAt, UpdatedAt: note.UpdatedAt})
+		}
+	case interfaces.RecordTasks:
+		for _, task := range db.tasks {
+			records = append(records, interfaces.RecordSummary{Kind: kind, ID: task.ID, Title: task.Title, Owner: task.Username,
+				Assignee: task.Assignee, Completed: task.Completed, CreatedAt: task.CreatedAt, UpdatedAt: task.UpdatedAt})
+		}
+	case interfaces.RecordAudits:
+		for _, audit := range db.audits {
+			title := audit.Action
+			if audit.Firm != "" {
+				title += " - " + audit.Firm
+			}
+			records = append(records, interfaces.RecordSummary{Kind: kind, ID: audit.ID, Title: title, Owner: audit.Username,
+				Assignee: audit.AssignedUser, Status: audit.Status, Completed: audit.Completed, CreatedAt: audit.CreatedAt, UpdatedAt: audit.UpdatedAt})
+		}
+	case interfaces.RecordCRM:
+		for _, entry := range db.crm {
+			records = append(records, interfaces.RecordSummary{Kind: kind, ID: entry.ID, Title: entry.Name, Owner: entry.Username,
+				CreatedAt: entry.CreatedAt, UpdatedAt: entry.UpdatedAt})
+		}
+	default:
+		return nil, fmt.Errorf("unknown record kind %q", kind)
+	}
+	return records, nil
+}
+
+func (db *Database) ListRecords(kind string) ([]interfaces.RecordSummary, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	records, err := db.summaries(kind)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].UpdatedAt.Equal(records[j].UpdatedAt) {
+			return records[i].UpdatedAt.After(records[j].UpdatedAt)
+		}
+		return records[i].ID > records[j].ID
+	})
+	return records, nil
+}
+
+func (db *Database) DeleteRecords(kind string, ids []int) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	records, err := db.summaries(kind)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		if !containsID(ids, records[i].ID) {
+			continue
+		}
+		switch kind {
+		case interfaces.RecordNotes:
+			db.notes = append(db.notes[:i], db.notes[i+1:]...)
+		case interfaces.RecordTasks:
+			db.tasks = append(db.tasks[:i], db.tasks[i+1:]...)
+		case interfaces.RecordAudits:
+			db.audits = append(db.audits[:i], db.audits[i+1:]...)
+			db.cascadeAudit(records[i].ID)
+		case interfaces.RecordCRM:
+			db.crm = append(db.crm[:i], db.crm[i+1:]...)
+		}
+		deleted++
+		db.publish(kind, "DELETE", records[i].ID, records[i].Owner)
+	}
+	return deleted, nil
+}
+
+func (db *Database) ReassignRecords(kind string, ids []int, to string) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.summaries(kind); err != nil {
+		return 0, err
+	}
+	t := db.findUser(to)
+	if t < 0 {
+		return 0, fmt.Errorf("user not found: %s", to)
+	}
+	if db.users[t].Status == interfaces.UserInactive {
+		return 0, fmt.Errorf("cannot reassign %s to %s: %w", kind, to, interfaces.ErrInactiveUser)
+	}
+	toID, now := db.users[t].UserID, time.Now()
+	changed := 0
+	switch kind {
+	case interfaces.RecordNotes:
+		for i := range db.notes {
+			if containsID(ids, db.notes[i].ID) {
+				db.notes[i].UserID, db.notes[i].Username = toID, to
+				db.notes[i].UpdatedAt, db.notes[i].Version = now, db.notes[i].Version+1
+				changed++
+				db.publish(kind, "UPDATE", db.notes[i].ID, to)
+			}
+		}
+	case interfaces.RecordTasks:
+		for i := range db.tasks {
+			if containsID(ids, db.tasks[i].ID) {
+				db.tasks[i].Assignee = to
+				db.tasks[i].UpdatedAt, db.tasks[i].Version = now, db.tasks[i].Version+1
+				changed++
+				db.publish(kind, "UPDATE", db.tasks[i].ID, db.tasks[i].Username)
+			}
+		}
+	case interfaces.RecordAudits:
+		for i := range db.audits {
+			audit := &db.audits[i]
+			if containsID(ids, audit.ID) {
+				audit.AssignedUser = to
+				var additional []string
+				for _, username := range audit.AdditionalUsers {
+					if username != to {
+						additional = append(additional, username)
+					}
+				}
+				audit.AdditionalUsers = additional
+				audit.UpdatedAt, audit.Version = now, audit.Version+1
+				changed++
+				db.publish(kind, "UPDATE", audit.ID, audit.Username)
+			}
+		}
+	case interfaces.RecordCRM:
+		for i := range db.crm {
+			if containsID(ids, db.crm[i].ID) {
+				db.crm[i].UserID, db.crm[i].Username = toID, to
+				db.crm[i].UpdatedAt, db.crm[i].Version = now, db.crm[i].Version+1
+				changed++
+				db.publish(kind, "UPDATE", db.crm[i].ID, to)
+			}
+		}
+	}
+	return changed, nil
+}
+
+func (db *Database) CompleteRecords(kind string, ids []int) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !interfaces.Completable(kind) {
+		return 0, fmt.Errorf("%s cannot be marked complete", kind)
+	}
+	now := time.Now()
+	completed := 0
+	for i := range db.tasks {
+		task := &db.tasks[i]
+		if containsID(ids, task.ID) && !task.Completed {
+			task.Completed = true
+			task.UpdatedAt, task.Version = now, task.Version+1
+			completed++
+			db.publish(kind, "UPDATE", task.ID, task.Username)
+		}
+	}
+	return completed, nil
+}
+
+// Change notifications
+
+// publish must be called with db.mu held
+func (db *Database) publish(table, operation string, id int, username string) {
+	event := interfaces.ChangeEvent{Table: table, Operation: operation, ID: id, Username: username}
+	for ch := range db.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (db *Database) SubscribeChanges() (<-chan interfaces.ChangeEvent, func()) {
+	ch := make(chan interfaces.ChangeEvent, 64)
+
+	db.mu.Lock()
+	db.subscribers[ch] = struct{}{}
+	db.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			db.mu.Lock()
+			delete(db.subscribers, ch)
+			db.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package fakes
+
+import (
+	// Standard Library
+	"testing"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/databases/dbtest"
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func TestDatabaseConformance(t *testing.T) {
+	dbtest.RunDatabaseConformance(t, func(t *testing.T) interfaces.DatabaseOperations {
+		return NewDatabase()
+	})
+}
@@ -0,0 +1,156 @@
+package fakes
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"sync"
+
+	// External Imports
+	"golang.org/x/crypto/bcrypt"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+var (
+	_ interfaces.LDAPOperations = (*LDAP)(nil)
+	_ interfaces.Authenticator  = (*LDAP)(nil)
+)
+
+// LDAP is an in-memory directory implementing interfaces.LDAPOperations.
+// Connections it returns carry no *ldap.Conn.
+type LDAP struct {
+	mu       sync.Mutex
+	Server   string
+	Domain   string
+	DB       interfaces.DatabaseOperations
+	users    map[string]string
+	groups   map[string][]string
+	loggedIn map[string]bool
+	down     bool
+}
+
+// NewLDAP returns a directory for domain. db is used by VerifyCredentialAccess
+// and may be nil when credentials are not exercised.
+func NewLDAP(server, domain string, db interfaces.DatabaseOperations) *LDAP {
+	return &LDAP{
+		Server:   server,
+		Domain:   domain,
+		DB:       db,
+		users:    make(map[string]string),
+		groups:   make(map[string][]string),
+		loggedIn: make(map[string]bool),
+	}
+}
+
+// AddUser creates or replaces a directory account
+func (l *LDAP) AddUser(username, password string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.users[username] = password
+}
+
+// SetGroups replaces the group DNs a user is a member of
+func (l *LDAP) SetGroups(username string, groupDNs ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.groups[username] = groupDNs
+}
+
+// SetUnavailable makes logins fail as if the directory could not be reached
+func (l *LDAP) SetUnavailable(down bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.down = down
+}
+
+// LoggedIn reports whether username holds an open connection
+func (l *LDAP) LoggedIn(username string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loggedIn[username]
+}
+
+func (l *LDAP) ConnectToAdServer(username, password string) (*interfaces.LDAPConnection, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.down {
+		return nil, fmt.Errorf("%w: failed to connect to %s", interfaces.ErrAuthUnavailable, l.Server)
+	}
+	stored, ok := l.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not in the directory", interfaces.ErrUnknownUser, username)
+	}
+	if password == "" || stored != password {
+		return nil, fmt.Errorf("failed to bind as user: %w", interfaces.ErrInvalidCredentials)
+	}
+
+	l.loggedIn[username] = true
+	return &interfaces.LDAPConnection{
+		Username: username,
+		Server:   l.Server,
+		Domain:   l.Domain,
+		DN:       fmt.Sprintf("CN=%s,%s", username, l.DomaintoOU(l.Domain)),
+		Groups:   append([]string(nil), l.groups[username]...),
+	}, nil
+}
+
+func (l *LDAP) Name() string {
+	return "ldap"
+}
+
+func (l *LDAP) Authenticate(username, password string) (*interfaces.Login, error) {
+	conn, err := l.ConnectToAdServer(username, password)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Login{Username: conn.Username, Source: "ldap", LDAP: conn}, nil
+}
+
+func (l *LDAP) LogoutUser(c *interfaces.LDAPConnection) error {
+	if c == nil {
+		return fmt.Errorf("no connection to close")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.loggedIn, c.Username)
+	return nil
+}
+
+func (l *LDAP) DomaintoOU(domain string) string {
+	parts := strings.Split(domain, ".")
+	var ous []string
+	for _, part := range parts {
+		ous = append(ous, "DC="+strings.ToLower(part))
+	}
+	return strings.Join(ous, ",")
+}
+
+func (l *LDAP) IsProperDomain(domain string) bool {
+	parts := strings.Split(domain, ".")
+	return len(parts) >= 2 && len(parts) <= 3
+}
+
+func (l *LDAP) OUwithDomain(ou string, domain string) string {
+	return fmt.Sprintf("OU=%s,%s", ou, l.DomaintoOU(domain))
+}
+
+func (l *LDAP) VerifyCredentialAccess(loginName, loginPass string) error {
+	if l.DB == nil {
+		return fmt.Errorf("credential not found: database is not initialized")
+	}
+	creds, err := l.DB.GetCredentialByLoginName(loginName)
+	if err != nil {
+		return fmt.Errorf("credential not found: %w", err)
+	}
+	if len(creds) == 0 {
+		return fmt.Errorf("no credentials found")
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(creds[0].LoginPass), []byte(loginPass))
+	if err != nil {
+		return fmt.Errorf("invalid password")
+	}
+	return nil
+}
@@ -2,38 +2,31 @@ package functions
 
 import (
 	//Standard Library Imports//
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
+	"time"
 
 	// Fyne Imports//
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
-	// External Imports
-	"github.com/go-ldap/ldap/v3"
-	"github.com/joho/godotenv"
-
 	// Internal Imports
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
 	crud "github.com/j4m1n-t/goAudit/internal/databases"
 	"github.com/j4m1n-t/goAudit/internal/interfaces"
 	layouts "github.com/j4m1n-t/goAudit/internal/layouts"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
 	state "github.com/j4m1n-t/goAudit/internal/status"
 )
 
-type AppConfig struct {
-	IconPath   string `json:"iconPath"`
-	ConfigPath string `json:"config"`
-}
-
-var configPath string
-
 func SetLog() {
 	AppDataDir, err := os.UserConfigDir()
 	if err != nil {
@@ -56,218 +49,18 @@ func SetLog() {
 	log.Println("Logging initialized")
 }
 
-func SaveConfig(config AppConfig) error {
-	err := os.MkdirAll(filepath.Dir(configPath), os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	file, err := os.Create(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(&config)
-	if err != nil {
-		return fmt.Errorf("failed to encode config file: %w", err)
-	}
-
-	return nil
-}
-
-func LoadConfig() AppConfig {
-	config := AppConfig{}
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		fyne.LogError("Failed to get user config dir.", err)
-	}
-	configPath = filepath.Join(configDir, "goAudit", "config.json")
-	iconPath := filepath.Join(configDir, "goAudit", "goAudit.ico")
-	file, err := os.Open(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Create the directory if it doesn't exist
-			err = os.MkdirAll(filepath.Dir(configPath), os.ModePerm)
-			if err != nil {
-				log.Printf("Failed to create config directory: %v", err)
-			}
-			// Return default config as the file doesn't exist yet
-			return config
-		}
-		log.Printf("Failed to open config file: %v", err)
-		return config
-	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&config)
-	if err != nil {
-		log.Printf("Failed to decode config file: %v", err)
-		// Return default config if decoding fails
-		return AppConfig{ConfigPath: configPath, IconPath: iconPath}
-	}
-	return config
-}
-
 func LDAPConfigured() bool {
-	_, err := os.Stat(".env")
-	if os.IsNotExist(err) {
-		return false
-	}
-
-	// Check if all necessary LDAP settings are present
-	envMap, err := godotenv.Read(".env")
-	if err != nil {
-		return false
-	}
-
-	requiredKeys := []string{"LDAP_SERVER", "LDAP_DOMAIN", "LDAP_OU"}
-	for _, key := range requiredKeys {
-		if _, exists := envMap[key]; !exists {
-			return false
-		}
-	}
-
-	return true
+	return config.Current().LDAPConfigured()
 }
 
 func ShowLDAPDialog(window fyne.Window) {
-
-	serverEntry := widget.NewEntry()
-	serverEntry.SetPlaceHolder("LDAP Server")
-
-	domainEntry := widget.NewEntry()
-	domainEntry.SetPlaceHolder("LDAP Domain")
-
-	ouEntry := widget.NewEntry()
-	ouEntry.SetPlaceHolder("LDAP OU")
-
-	readonlyEntry := widget.NewEntry()
-	readonlyEntry.SetPlaceHolder("Read Only Password")
-
-	// Load the current LDAP settings
-	ldapSettings := LoadLDAPSettings()
-
-	// Set the current LDAP settings in the dialog fields
-	serverEntry.SetText(ldapSettings.Server)
-	domainEntry.SetText(ldapSettings.Domain)
-	ouEntry.SetText(ldapSettings.OU)
-	maskedPassword := strings.Repeat("*", len(ldapSettings.ReadOnlyPassword))
-	readonlyEntry.SetText(maskedPassword)
-
-	// Show Password Toggle button
-	showPasswordButton := widget.NewButton("Show/Hide Password", func() {
-		if readonlyEntry.Password {
-			readonlyEntry.Password = false
-			readonlyEntry.SetText(ldapSettings.ReadOnlyPassword)
-		} else {
-			readonlyEntry.Password = true
-			readonlyEntry.SetText(maskedPassword)
-		}
-		readonlyEntry.Refresh()
-	})
-
-	content := container.NewVBox(
-		widget.NewForm(
-			widget.NewFormItem("LDAP Server", serverEntry),
-			widget.NewFormItem("LDAP Domain", domainEntry),
-			widget.NewFormItem("LDAP OU", ouEntry),
-			widget.NewFormItem("Read Only Password", readonlyEntry),
-		),
-	)
-
-	// Create a custom dialog
-	customDialog := dialog.NewCustom("LDAP Settings", "Save", content, window)
-
-	// Add a cancel button
-	cancelButton := widget.NewButton("Cancel", func() {
-		customDialog.Hide()
-	})
-
-	// Create a save button
-	saveButton := widget.NewButton("Save", func() {
-		err := SaveLDAPSettings(serverEntry.Text, domainEntry.Text, ouEntry.Text, readonlyEntry.Text)
-		if err != nil {
-			dialog.ShowError(err, window)
-			return
-		}
-		dialog.ShowInformation("Success", "LDAP settings saved successfully", window)
-		customDialog.Hide()
-	})
-
-	// Create a container for buttons
-	buttons := container.NewHBox(cancelButton, saveButton, showPasswordButton)
-
-	// Set the buttons to the dialog
-	customDialog.SetButtons([]fyne.CanvasObject{buttons})
-
-	// Set a larger size for the dialog
-	customDialog.Resize(fyne.NewSize(400, 300))
-
-	// Show the dialog
-	customDialog.Show()
+	layouts.ShowLDAPSetupDialog(window)
 }
 
 func ShowSQLDialog(window fyne.Window) {
-	serverEntry := widget.NewEntry()
-	serverEntry.SetPlaceHolder("SQL Server")
-
-	userEntry := widget.NewEntry()
-	userEntry.SetPlaceHolder("SQL User")
-
-	passEntry := widget.NewEntry()
-	passEntry.SetPlaceHolder("SQL Password")
-
-	dbEntry := widget.NewEntry()
-	dbEntry.SetPlaceHolder("Database Name")
-
-	portEntry := widget.NewEntry()
-	portEntry.SetPlaceHolder("Port Number")
-	portEntry.SetPlaceHolder("5432")
-
-	content := container.NewVBox(
-		widget.NewForm(
-			widget.NewFormItem("SQL Server", serverEntry),
-			widget.NewFormItem("SQL User", userEntry),
-			widget.NewFormItem("SQL Password", passEntry),
-			widget.NewFormItem("Database Name", dbEntry),
-			widget.NewFormItem("Port Number", portEntry),
-		),
-	)
-
-	// Create a custom dialog
-	customDialog := dialog.NewCustom("SQL Settings", "Save", content, window)
-
-	// Add a cancel button
-	cancelButton := widget.NewButton("Cancel", func() {
-		customDialog.Hide()
-	})
-
-	// Create a save button
-	saveButton := widget.NewButton("Save", func() {
-		err := SaveSQLSettings(serverEntry.Text, userEntry.Text, passEntry.Text, dbEntry.Text, portEntry.Text)
-		if err != nil {
-			dialog.ShowError(err, window)
-			return
-		}
-		dialog.ShowInformation("Success", "SQL settings saved successfully", window)
-		customDialog.Hide()
-	})
-
-	// Create a container for buttons
-	buttons := container.NewHBox(cancelButton, saveButton)
-
-	// Set the buttons to the dialog
-	customDialog.SetButtons([]fyne.CanvasObject{buttons})
-
-	// Set a larger size for the dialog
-	customDialog.Resize(fyne.NewSize(400, 300))
-
-	// Show the dialog
-	customDialog.Show()
+	layouts.ShowPostgresSetupDialog(window)
 }
+
 func UpdateMenuForUser(isAdmin bool, window fyne.Window) {
 	mainMenu := window.MainMenu()
 	settingsMenu := mainMenu.Items[1] // Assuming Settings is the first menu
@@ -282,64 +75,225 @@ func UpdateMenuForUser(isAdmin bool, window fyne.Window) {
 	} else {
 		// Remove Config item if it's there
 		if len(settingsMenu.Items) > 1 {
-			settingsMenu.Items = settingsMenu.Items[1:]
+			settingsMenu.Items = settingsMenu.Items[:1]
 		}
 	}
 
 	window.SetMainMenu(mainMenu)
 }
 
-func CheckIfAdmin(conn *interfaces.LDAPConnection, username string) bool {
-	if conn == nil {
-		log.Println("LDAP connection is nil")
-		return false
+// accounts is the database without permission checks. Logins and password
+// changes use it because nobody is logged in yet.
+var accounts interfaces.DatabaseOperations
+
+func localAccounts() interfaces.Authenticator {
+	if accounts == nil {
+		return nil
 	}
+	return &auth.LocalAccounts{DB: accounts}
+}
 
-	searchBase := fmt.Sprintf("DC=%s", strings.Replace(conn.Domain, ".", ",DC=", -1))
-	searchFilter := fmt.Sprintf("(&(objectClass=user)(sAMAccountName=%s))", ldap.EscapeFilter(username))
+// loginThrottle counts failed application logins. Without a database it is
+// nil, which refuses every login.
+func loginThrottle() *auth.Throttle {
+	if accounts == nil {
+		return nil
+	}
+	return &auth.Throttle{DB: accounts, Scope: interfaces.AttemptsUser}
+}
 
-	searchRequest := ldap.NewSearchRequest(
-		searchBase,
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		0,
-		0,
-		false,
-		searchFilter,
-		[]string{"memberOf"},
-		nil,
-	)
+// Login checks a username and password against each source in the
+// configured auth chain, slowing down and then locking out repeated failures
+func Login(username, password string) (*interfaces.Login, error) {
+	chain := auth.NewChain(config.Current().Auth.Sources(), &auth.LDAPWrapper{}, localAccounts())
+	var login *interfaces.Login
+	err := loginThrottle().Attempt(username, func() error {
+		var err error
+		login, err = chain.Authenticate(username, password)
+		return err
+	})
+	return login, err
+}
 
-	log.Printf("Searching for user with filter: %s in base: %s", searchFilter, searchBase)
+// OIDCLoginAvailable reports whether the login form should offer signing in
+// through the OpenID Connect provider
+func OIDCLoginAvailable() bool {
+	cfg := config.Current()
+	return accounts != nil && cfg.OIDC.Configured() && cfg.Auth.Allows(auth.SourceOIDC)
+}
 
-	sr, err := conn.Conn.Search(searchRequest)
+// LoginWithOIDC signs in through the identity provider in the system browser
+// and links the identity to a goAudit user
+func LoginWithOIDC(ctx context.Context) (*interfaces.Login, error) {
+	if accounts == nil {
+		return nil, fmt.Errorf("the database is not connected")
+	}
+	provider := &auth.OIDCProvider{
+		Settings: config.Current().OIDC,
+		OpenURL:  fyne.CurrentApp().OpenURL,
+	}
+	identity, err := provider.Login(ctx)
 	if err != nil {
-		log.Printf("Error searching for user: %v", err)
-		fyne.LogError("Error searching for user", err)
-		return false
+		return nil, err
+	}
+	user, err := auth.LinkOIDCUser(accounts, identity)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("%s signed in through %s", user.Username, config.Current().OIDC.Issuer)
+	return &interfaces.Login{Username: user.Username, Source: auth.SourceOIDC, Groups: identity.Groups}, nil
+}
+
+// LinkOIDC signs in through the identity provider and links that identity to
+// the user who is logged in, so they can sign in with it from then on
+func LinkOIDC(ctx context.Context) error {
+	if accounts == nil {
+		return fmt.Errorf("the database is not connected")
 	}
+	if state.GlobalState.Session == nil {
+		return fmt.Errorf("log in before linking an identity provider account")
+	}
+	provider := &auth.OIDCProvider{
+		Settings: config.Current().OIDC,
+		OpenURL:  fyne.CurrentApp().OpenURL,
+	}
+	identity, err := provider.Login(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = auth.LinkOIDCAccount(accounts, state.GlobalState.Username, identity)
+	return err
+}
+
+// LocalLoginAvailable reports whether local accounts can be used to log in
+func LocalLoginAvailable() bool {
+	return accounts != nil && config.Current().Auth.Allows(auth.SourceLocal)
+}
+
+// ChangePassword replaces a local account's password. The current password
+// is checked again, so this also works before the session starts.
+func ChangePassword(username, current, replacement string) error {
+	if accounts == nil {
+		return fmt.Errorf("the database is not connected")
+	}
+	return (&auth.LocalAccounts{DB: accounts}).ChangePassword(username, current, replacement)
+}
 
-	if len(sr.Entries) == 0 {
-		log.Printf("No entries found for user: %s", username)
+// NeedsFirstAccount reports whether the database has no users at all and
+// LDAP is not set up, so the first administrator has to be a local account
+func NeedsFirstAccount() bool {
+	if !LocalLoginAvailable() || LDAPConfigured() {
 		return false
 	}
+	users, err := accounts.GetAll()
+	if err != nil {
+		log.Printf("Failed to check for existing users: %v", err)
+		return false
+	}
+	return len(users) == 0
+}
 
-	for _, entry := range sr.Entries {
-		for _, attr := range entry.Attributes {
-			if attr.Name == "memberOf" {
-				for _, member := range attr.Values {
-					log.Printf("User %s is member of: %s", username, member)
-					if strings.Contains(strings.ToLower(member), "admin") {
-						log.Printf("User %s is an admin (member of %s)", username, member)
-						return true
-					}
-				}
-			}
+// CreateFirstAccount adds the first local administrator to an empty database
+func CreateFirstAccount(username, password string) error {
+	if !NeedsFirstAccount() {
+		return fmt.Errorf("goAudit already has users; ask an administrator for an account")
+	}
+	_, err := auth.SetLocalAccount(accounts, interfaces.Users{Username: username, Role: string(rbac.RoleAdmin)}, password, false)
+	return err
+}
+
+// TwoFactor checks and sets up the second factor of the user logging in
+func TwoFactor() *auth.TwoFactor {
+	return &auth.TwoFactor{DB: accounts, Throttle: loginThrottle()}
+}
+
+// NewSession looks up the roles of the user who just logged in. Directory
+// users in none of the configured role groups, and local accounts without a
+// role, are refused. The session is not used until StartSession.
+func NewSession(login *interfaces.Login) (*rbac.Session, error) {
+	if login == nil {
+		return nil, fmt.Errorf("not logged in")
+	}
+	if err := checkActive(login.Username); err != nil {
+		return nil, err
+	}
+	var resolve rbac.Resolver
+	if login.LDAP != nil {
+		resolve = auth.RoleResolver(login.LDAP)
+	} else if login.Source == auth.SourceOIDC {
+		resolve = auth.OIDCRoleResolver(login.Groups)
+	} else if accounts != nil {
+		resolve = (&auth.LocalAccounts{DB: accounts}).RoleResolver(login.Username)
+	} else {
+		return nil, fmt.Errorf("the database is not connected")
+	}
+	ttl := time.Duration(config.Current().Roles.CacheTTL)
+	session, err := rbac.NewSession(login.Username, ttl, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if login.LDAP != nil {
+		session.SetReportsResolver(auth.ReportsResolver(login.LDAP))
+	}
+	session.SetTimeouts(func() (time.Duration, time.Duration) {
+		settings := config.Current().Auth
+		return settings.IdleTimeout(), settings.SessionLifetime()
+	})
+	session.SetReauthWindow(func() time.Duration { return config.Current().Auth.ReauthWindow() })
+	roles := session.Roles()
+	if len(roles) == 0 {
+		if login.Source == auth.SourceOIDC {
+			return nil, fmt.Errorf("%s is not in any identity provider group allowed to use goAudit", login.Username)
 		}
+		if login.LDAP == nil {
+			return nil, fmt.Errorf("%s has no role; ask an administrator to assign one", login.Username)
+		}
+		return nil, fmt.Errorf("%s is not a member of any group allowed to use goAudit", login.Username)
 	}
+	log.Printf("User %s has roles %v", login.Username, roles)
+	return session, nil
+}
 
-	log.Printf("User %s is not an admin", username)
-	return false
+// StartSession makes session the logged in user's, once any second factor
+// has been checked. Sensitive actions check login's user again the same way
+// they logged in.
+func StartSession(session *rbac.Session, login *interfaces.Login) {
+	session.Reauthenticated()
+	state.GlobalState.Session = session
+	layouts.SetReauthenticator(reauthenticator(login))
+	if accounts == nil {
+		return
+	}
+	if _, err := accounts.GetOrCreateUser(login.Username); err != nil {
+		log.Printf("Failed to add %s to the users: %v", login.Username, err)
+		return
+	}
+	if err := accounts.RecordLogin(login.Username, time.Now()); err != nil {
+		log.Printf("Failed to record the login of %s: %v", login.Username, err)
+	}
+}
+
+// checkActive refuses users an administrator has deactivated, whichever
+// source they logged in with
+func checkActive(username string) error {
+	if accounts == nil {
+		return nil
+	}
+	users, _, err := accounts.GetUsers(username)
+	if err != nil || len(users) == 0 {
+		// Not in the database yet, so not deactivated either
+		return nil
+	}
+	if users[0].Status == interfaces.UserInactive {
+		return fmt.Errorf("the account %s is disabled; ask an administrator to activate it", username)
+	}
+	return nil
+}
+
+// EndSession forgets the logged in user and their data
+func EndSession() {
+	state.GlobalState.ClearUser()
+	layouts.SetReauthenticator(layouts.Reauthenticator{})
 }
 
 func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppState) {
@@ -363,9 +317,12 @@ func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppStat
 
 	// Create common tab items
 	auditsTab := createTabItem("Audits", layouts.CreateAuditsTabContent)
-	credsTab := createTabItem("Credentials", func(w fyne.Window) fyne.CanvasObject {
-		return layouts.CreateCredentialsTabContent(w)
-	})
+	var credsTab *container.TabItem
+	if state.GlobalState.Can(rbac.CredentialsUse) {
+		credsTab = createTabItem("Credentials", func(w fyne.Window) fyne.CanvasObject {
+			return layouts.CreateCredentialsTabContent(w)
+		})
+	}
 	crmTab := createTabItem("CRM", layouts.CreateCRMTabContent)
 	notesTab := createTabItem("Notes", func(w fyne.Window) fyne.CanvasObject {
 		return layouts.CreateNotesTabContent(w)
@@ -379,6 +336,14 @@ func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppStat
 		}
 	}
 
+	// Managers see their reporting tree's tasks
+	if state.GlobalState.Can(rbac.TasksAssign) {
+		teamTab := createTabItem("Team", layouts.CreateTeamTabContent)
+		if teamTab != nil {
+			tabItems = append(tabItems, teamTab)
+		}
+	}
+
 	// Add admin tab if user is admin
 	if isAdmin {
 		adminTab := createTabItem("Admin", layouts.CreateAdminTabContent)
@@ -391,7 +356,8 @@ func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppStat
 	// Create AppTabs with valid items
 	if len(tabItems) > 0 {
 		tabs = container.NewAppTabs(tabItems...)
-		window.SetContent(tabs)
+		tabs.OnSelected = func(*container.TabItem) { state.GlobalState.CurrentSession().Touch() }
+		window.SetContent(layouts.TrackActivity(window, tabs))
 	} else {
 		log.Println("Error: No valid tabs to display")
 		errorLabel := widget.NewLabel("Error: Unable to load application tabs")
@@ -399,7 +365,7 @@ func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppStat
 	}
 
 	// Add credentials tab if master password is authenticated
-	if state.GlobalState.CredentialAuthStatus {
+	if state.GlobalState.CredentialAuthStatus && state.GlobalState.Can(rbac.CredentialsUse) {
 		credentialsTab := createTabItem("Credentials", func(w fyne.Window) fyne.CanvasObject {
 			return layouts.CreateCredentialsTabContent(w)
 		})
@@ -409,49 +375,57 @@ func UpdateTabsForUser(isAdmin bool, window fyne.Window, appState *state.AppStat
 	}
 }
 
+var (
+	stopBackground     context.CancelFunc
+	stopBackgroundLock sync.Mutex
+)
+
+// StopBackground stops the change listener, directory sync and scheduler
+// started by the last InitDBs, if they are running
+func StopBackground() {
+	stopBackgroundLock.Lock()
+	defer stopBackgroundLock.Unlock()
+	if stopBackground != nil {
+		stopBackground()
+		stopBackground = nil
+	}
+}
+
+// startBackground stops any loops already running and starts them again
+// against db
+func startBackground(db interfaces.DatabaseOperations) {
+	stopBackgroundLock.Lock()
+	defer stopBackgroundLock.Unlock()
+	if stopBackground != nil {
+		stopBackground()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopBackground = cancel
+	crud.StartChangeListener(ctx)
+	// The sync runs as the application, not as whoever is logged in
+	auth.StartDirectorySync(ctx, db)
+	// So is the scheduler, which creates audits owned by each schedule's creator
+	audits.StartScheduler(ctx, db)
+}
+
 func InitDBs() error {
 	dbWrapper, err := crud.InitDB()
 	if err != nil {
 		log.Printf("Error initializing database: %v", err)
 		return err
 	}
-	state.GlobalState.SetDB(dbWrapper)
-	EnsureTablesExists()
+	store := rbac.NewStore(dbWrapper, state.GlobalState.CurrentSession)
+	store.SetWorkflow(audits.Workflow)
+	state.GlobalState.SetDB(store)
+	accounts = dbWrapper
+	err = EnsureTablesExists()
+	if err != nil {
+		return fmt.Errorf("failed to prepare database schema: %v", err)
+	}
+	startBackground(dbWrapper)
 	return nil
 }
 
 func EnsureTablesExists() error {
-	var err error
-
-	if err = crud.EnsureAuditTableExists(); err != nil {
-		log.Printf("Error ensuring audit table exists: %v", err)
-		return err
-	}
-
-	if err = crud.EnsureCredentialsTableExists(); err != nil {
-		log.Printf("Error ensuring credentials table exists: %v", err)
-		return err
-	}
-
-	if err = crud.EnsureCRMTableExists(); err != nil {
-		log.Printf("Error ensuring CRM table exists: %v", err)
-		return err
-	}
-
-	if err = crud.EnsureNotesTableExists(); err != nil {
-		log.Printf("Error ensuring notes table exists: %v", err)
-		return err
-	}
-
-	if err = crud.EnsureTaskTableExists(); err != nil {
-		log.Printf("Error ensuring task table exists: %v", err)
-		return err
-	}
-
-	if err = crud.EnsureUserTableExists(); err != nil {
-		log.Printf("Error ensuring user table exists: %v", err)
-		return err
-	}
-
-	return nil
+	return crud.EnsureSchema()
 }
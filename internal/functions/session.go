@@ -0,0 +1,106 @@
+package functions
+
+import (
+	// Standard Library
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	layouts "github.com/j4m1n-t/goAudit/internal/layouts"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// How often the session watcher checks for typing and the timeouts
+const sessionCheckInterval = 15 * time.Second
+
+// reauthenticator checks the user of login again through the source they
+// logged in with. Password checks go through the login throttle, so they
+// cannot be used to guess the password either.
+func reauthenticator(login *interfaces.Login) layouts.Reauthenticator {
+	username := login.Username
+	if login.Source == auth.SourceOIDC {
+		return layouts.Reauthenticator{SSO: func(ctx context.Context) error {
+			again, err := LoginWithOIDC(ctx)
+			if err != nil {
+				return err
+			}
+			if again.Username != username {
+				return fmt.Errorf("you signed in as %s, not %s", again.Username, username)
+			}
+			return nil
+		}}
+	}
+	return layouts.Reauthenticator{Password: func(password string) error {
+		ldapWrapper := &auth.LDAPWrapper{}
+		chain := auth.NewChain(config.Current().Auth.Sources(), ldapWrapper, localAccounts())
+		return loginThrottle().Attempt(username, func() error {
+			again, err := chain.Authenticate(username, password)
+			if err != nil {
+				return err
+			}
+			// Only the answer matters; the new directory connection is not kept
+			ldapWrapper.LogoutUser(again.LDAP)
+			return nil
+		})
+	}}
+}
+
+// WatchSession ends the session through expired once it has been idle or
+// open for longer than the auth config allows. Typing in a field counts as
+// activity; the mouse is noticed by layouts.TrackActivity. stop ends the
+// watch without expiring the session.
+func WatchSession(window fyne.Window, expired func(error)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(sessionCheckInterval)
+		defer ticker.Stop()
+		var typing typingWatch
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			session := state.GlobalState.Session
+			if session == nil {
+				continue
+			}
+			if typing.changed(window.Canvas().Focused()) {
+				session.Touch()
+			}
+			if err := session.Active(); err != nil {
+				log.Printf("Ending the session of %s: %v", session.Username, err)
+				expired(err)
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+// typingWatch remembers the focused field and its text between checks
+type typingWatch struct {
+	focused fyne.Focusable
+	text    string
+}
+
+// changed reports whether the focus moved or the focused field's text
+// changed since the last check
+func (w *typingWatch) changed(focused fyne.Focusable) bool {
+	text := ""
+	if entry, ok := focused.(*widget.Entry); ok {
+		text = entry.Text
+	}
+	changed := focused != w.focused || text != w.text
+	w.focused, w.text = focused, text
+	return changed && focused != nil
+}
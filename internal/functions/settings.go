@@ -1,54 +1,46 @@
 package functions
 
 import (
-	// Standard Library
-	"fmt"
-	"os"
-	// Fyne Imports
-	// External Imports
 	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	crud "github.com/j4m1n-t/goAudit/internal/databases"
+	"github.com/j4m1n-t/goAudit/internal/secrets"
 )
 
-type LDAPSettings struct {
-	Server           string
-	Domain           string
-	OU               string
-	ReadOnlyPassword string
-}
-
-type SQLSettings struct {
-	Server   string
-	User     string
-	Password string
-	Database string
-	Port     string
-}
-
+// SaveLDAPSettings stores the LDAP settings in the per-user config file
 func SaveLDAPSettings(server, domain, ou, readOnlyPassword string) error {
-	envContent := fmt.Sprintf("LDAP_SERVER=%s\nLDAP_DOMAIN=%s\nLDAP_OU=%s\nLDAP_READONLY_PASSWORD=%s\n", server, domain, ou, readOnlyPassword)
-	return os.WriteFile(".env", []byte(envContent), 0644)
+	ldapSettings := auth.LDAPSettings{Server: server, Domain: domain, OU: ou, ReadOnlyPassword: readOnlyPassword}
+	ldapConfig, err := ldapSettings.Config(config.Current().LDAP)
+	if err != nil {
+		return err
+	}
+	return config.SaveUser(func(cfg *config.Config) error {
+		cfg.LDAP = ldapConfig
+		return nil
+	})
 }
 
-func LoadLDAPSettings() LDAPSettings {
-	return LDAPSettings{
-		Server:           os.Getenv("LDAP_SERVER"),
-		Domain:           os.Getenv("LDAP_DOMAIN"),
-		OU:               os.Getenv("LDAP_OU"),
-		ReadOnlyPassword: os.Getenv("LDAP_READONLY_PASSWORD"),
+// SaveSQLSettings stores the basic SQL settings in the per-user config file,
+// leaving TLS and pool settings as they are
+func SaveSQLSettings(server, user, password, database string, port string) error {
+	passwordRef, err := secrets.Update(config.Current().SQL.Password, secrets.Name("SQL_PASSWORD"), password)
+	if err != nil {
+		return err
 	}
+	return config.SaveUser(func(cfg *config.Config) error {
+		cfg.SQL.Server = server
+		cfg.SQL.User = user
+		cfg.SQL.Password = passwordRef
+		cfg.SQL.Database = database
+		return config.Set(cfg, "SQL_PORT", port)
+	})
 }
 
-func SaveSQLSettings(server, user, password, database string, port string) error {
-	envContent := fmt.Sprintf("SQL_SERVER=%s\nSQL_USER=%s\nSQL_PASSWORD=%s\nSQL_DATABASE=%s\nSQL_PORT=%s", server, user, password, database, port)
-	return os.WriteFile(".env", []byte(envContent), 0644)
+func LoadLDAPSettings() (auth.LDAPSettings, error) {
+	return auth.LoadLDAPSettings()
 }
 
-func LoadSQLSettings() SQLSettings {
-	return SQLSettings{
-		Server:   os.Getenv("SQL_SERVER"),
-		User:     os.Getenv("SQL_USER"),
-		Password: os.Getenv("SQL_PASSWORD"),
-		Database: os.Getenv("SQL_DATABASE"),
-		Port:     os.Getenv("SQL_PORT"),
-	}
+func LoadSQLSettings() (crud.SQLSettings, error) {
+	return crud.LoadSQLSettings()
 }
@@ -0,0 +1,54 @@
+package interfaces
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Control is one control of a compliance framework, such as CC6.1 of SOC 2.
+// ControlID is the framework's own reference and is unique within it.
+type Control struct {
+	ID          int       `json:"id"`
+	Framework   string    `json:"framework"`
+	ControlID   string    `json:"control_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Family      string    `json:"family"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks that the control has a framework, a reference and a title
+func (c Control) Validate() error {
+	switch {
+	case strings.TrimSpace(c.Framework) == "":
+		return fmt.Errorf("%w: a framework is required", ErrInvalidControl)
+	case strings.TrimSpace(c.ControlID) == "":
+		return fmt.Errorf("%w: a control ID is required", ErrInvalidControl)
+	case strings.TrimSpace(c.Title) == "":
+		return fmt.Errorf("%w: control %s has no title", ErrInvalidControl, c.ControlID)
+	}
+	return nil
+}
+
+// ControlImport counts what importing a framework changed
+type ControlImport struct {
+	Added     int
+	Updated   int
+	Unchanged int
+}
+
+// ControlLink is an audit, or a checklist item of one, mapped to a control.
+// ItemID is 0 when the audit itself, or only its findings, are mapped.
+// OpenFindings counts the findings of the audit mapped to the control that
+// are not closed.
+type ControlLink struct {
+	ControlID      int    `json:"control_id"`
+	AuditID        int    `json:"audit_id"`
+	Firm           string `json:"firm"`
+	AuditCompleted bool   `json:"audit_completed"`
+	ItemID         int    `json:"item_id"`
+	Result         string `json:"result"`
+	OpenFindings   int    `json:"open_findings"`
+}
@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is matched by every ConflictError so callers can use errors.Is
+var ErrConflict = errors.New("record was modified by another user")
+
+// ErrInactiveUser is returned when work is assigned to an inactive user
+var ErrInactiveUser = errors.New("user is inactive")
+
+// ErrUserInUse is returned when deleting a user who still owns records.
+// Mark them inactive instead.
+var ErrUserInUse = errors.New("user still owns records")
+
+// ErrInvalidFinding is returned for a finding without a title or with an
+// unknown severity or status
+var ErrInvalidFinding = errors.New("invalid finding")
+
+// ErrInvalidTemplate is returned for a template without a name, a checklist
+// item without a title or an unknown checklist result
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// ErrInvalidControl is returned for a control without a framework, control
+// ID or title, and for a catalog that cannot be read
+var ErrInvalidControl = errors.New("invalid control")
+
+// ErrInvalidSchedule is returned for a schedule without a name or start date,
+// or with a recurrence rule that cannot be read
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ErrAlreadyScheduled is returned when a schedule already has an audit for a date
+var ErrAlreadyScheduled = errors.New("the schedule already has an audit for that date")
+
+// ErrEvidenceTampered is returned when evidence no longer matches the hash
+// recorded when it was uploaded
+var ErrEvidenceTampered = errors.New("evidence does not match its recorded SHA-256")
+
+// Returned by an Authenticator. Unavailable and unknown user let the auth
+// chain try its next source; invalid credentials end the login.
+var (
+	ErrAuthUnavailable    = errors.New("authentication source unavailable")
+	ErrUnknownUser        = errors.New("unknown user")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// ErrLockedOut is returned while too many failed logins lock a username or host
+var ErrLockedOut = errors.New("too many failed logins")
+
+// ConflictError is returned by an update whose version no longer matches the
+// stored row. Current holds the copy that is on the server right now.
+type ConflictError struct {
+	Entity  string
+	ID      int
+	Current interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %d was modified by another user", e.Entity, e.ID)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
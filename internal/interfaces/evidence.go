@@ -0,0 +1,46 @@
+package interfaces
+
+import (
+	// Standard Library
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Where the content of evidence is kept
+const (
+	EvidenceInDatabase  = "database"
+	EvidenceInDirectory = "directory"
+)
+
+// Evidence is a file attached to an audit, and optionally to one of its
+// findings. It is never changed once uploaded: a replacement is a new row
+// that Replaces the old one, with the next Version. SHA256 and Size are
+// recorded at upload and checked on every download. ReplacedBy is read only.
+type Evidence struct {
+	ID          int       `json:"id"`
+	AuditID     int       `json:"audit_id"`
+	FindingID   int       `json:"finding_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Storage     string    `json:"storage"`
+	Comment     string    `json:"comment"`
+	Uploader    string    `json:"uploader"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	Version     int       `json:"version"`
+	Replaces    int       `json:"replaces"`
+	ReplacedBy  int       `json:"replaced_by"`
+}
+
+// EvidenceHash is the hex SHA-256 recorded for content
+func EvidenceHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether content is what was uploaded
+func (e Evidence) Matches(content []byte) bool {
+	return int64(len(content)) == e.Size && EvidenceHash(content) == e.SHA256
+}
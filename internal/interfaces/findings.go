@@ -0,0 +1,83 @@
+package interfaces
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Finding severities, most serious first
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Severities lists the finding severities, most serious first
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
+
+// Finding statuses. New findings are open until someone starts remediating
+// them, and end up resolved or with the risk accepted.
+const (
+	FindingOpen          = "Open"
+	FindingInRemediation = "In Remediation"
+	FindingResolved      = "Resolved"
+	FindingRiskAccepted  = "Risk Accepted"
+)
+
+// FindingStatuses lists the finding statuses in the order they are worked through
+var FindingStatuses = []string{FindingOpen, FindingInRemediation, FindingResolved, FindingRiskAccepted}
+
+// Finding is an issue raised by an audit. Owner is responsible for fixing it
+// by DueDate. Tasks lists the remediation tasks that link back to it, and
+// Controls the controls it is mapped to; both are read only.
+type Finding struct {
+	ID             int       `json:"id"`
+	AuditID        int       `json:"audit_id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	Severity       string    `json:"severity"`
+	Area           string    `json:"area"`
+	Recommendation string    `json:"recommendation"`
+	Owner          string    `json:"owner"`
+	DueDate        time.Time `json:"due_date"`
+	Status         string    `json:"status"`
+	Tasks          []int     `json:"tasks"`
+	Controls       []int     `json:"controls"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Version        int       `json:"version"`
+}
+
+// Validate checks that the finding has a title and a known severity and status
+func (f Finding) Validate() error {
+	switch {
+	case strings.TrimSpace(f.Title) == "":
+		return fmt.Errorf("%w: a title is required", ErrInvalidFinding)
+	case SeverityRank(f.Severity) < 0:
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidFinding, f.Severity)
+	}
+	for _, status := range FindingStatuses {
+		if f.Status == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown status %q", ErrInvalidFinding, f.Status)
+}
+
+// Closed reports whether nothing is left to do about the finding
+func (f Finding) Closed() bool {
+	return f.Status == FindingResolved || f.Status == FindingRiskAccepted
+}
+
+// SeverityRank orders severities from critical, 0, to low. Unknown ones are -1.
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
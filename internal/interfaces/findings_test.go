@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+)
+
+func TestFindingValidate(t *testing.T) {
+	valid := Finding{Title: "Shared admin account", Severity: SeverityHigh, Status: FindingOpen}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	for name, finding := range map[string]Finding{
+		"no title":       {Title: "  ", Severity: SeverityHigh, Status: FindingOpen},
+		"bad severity":   {Title: "x", Severity: "urgent", Status: FindingOpen},
+		"bad status":     {Title: "x", Severity: SeverityLow, Status: "Done"},
+		"missing status": {Title: "x", Severity: SeverityLow},
+	} {
+		if err := finding.Validate(); !errors.Is(err, ErrInvalidFinding) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidFinding", name, err)
+		}
+	}
+	if SeverityRank(SeverityCritical) >= SeverityRank(SeverityLow) {
+		t.Errorf("critical should rank before low")
+	}
+}
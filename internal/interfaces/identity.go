@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	// External Imports
+	"github.com/google/uuid"
+)
+
+// directoryNamespace turns directory IDs that are not UUIDs, such as an
+// employee number, into name-based UUIDs
+var directoryNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/j4m1n-t/goAudit/directory-users"))
+
+// NewUserID returns a user_id for an account the directory does not know
+// about. UUIDv7 sorts by creation time, which keeps the index compact.
+func NewUserID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// DirectoryUserID derives a user_id from a directory ID. objectGUID and
+// entryUUID are used as they are; anything else is hashed, so the same
+// directory entry always gets the same user_id.
+func DirectoryUserID(directoryID string) string {
+	if id, err := uuid.Parse(directoryID); err == nil {
+		return id.String()
+	}
+	return uuid.NewSHA1(directoryNamespace, []byte(directoryID)).String()
+}
@@ -0,0 +1,49 @@
+package interfaces
+
+import (
+	// Standard Library
+	"testing"
+
+	// External Imports
+	"github.com/google/uuid"
+)
+
+func TestNewUserID(t *testing.T) {
+	first, second := NewUserID(), NewUserID()
+	if first == second {
+		t.Fatalf("NewUserID returned %s twice", first)
+	}
+	id, err := uuid.Parse(first)
+	if err != nil || id.Version() != 7 {
+		t.Errorf("NewUserID() = %s, %v, want a version 7 UUID", first, err)
+	}
+	if second < first {
+		t.Errorf("NewUserID() = %s after %s, want time ordered IDs", second, first)
+	}
+}
+
+func TestDirectoryUserID(t *testing.T) {
+	if got := DirectoryUserID("6F1B3C1E-0000-4000-8000-000000000001"); got != "6f1b3c1e-0000-4000-8000-000000000001" {
+		t.Errorf("DirectoryUserID(UUID) = %s, want the UUID itself", got)
+	}
+	hashed := DirectoryUserID("E12345")
+	if _, err := uuid.Parse(hashed); err != nil {
+		t.Errorf("DirectoryUserID(E12345) = %s, want a UUID", hashed)
+	}
+	if again := DirectoryUserID("E12345"); again != hashed {
+		t.Errorf("DirectoryUserID is not stable: %s, then %s", hashed, again)
+	}
+	if other := DirectoryUserID("E12346"); other == hashed {
+		t.Errorf("DirectoryUserID gave E12345 and E12346 the same ID %s", hashed)
+	}
+}
+
+func TestUserRecordsString(t *testing.T) {
+	if got := (UserRecords{}).String(); got != "no records" {
+		t.Errorf("UserRecords{}.String() = %q", got)
+	}
+	records := UserRecords{Tasks: 1, AssignedAudits: 2, CRMEntries: 3}
+	if got := records.String(); got != "1 task, 2 assigned audits, 3 CRM entries" || records.Total() != 6 {
+		t.Errorf("String() = %q, Total() = %d", got, records.Total())
+	}
+}
@@ -2,6 +2,8 @@ package interfaces
 
 import (
 	// Standard Library
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +21,26 @@ type DatabaseOperations interface {
 	GetAll() ([]Users, error)
 	Update(user Users) (Users, error)
 	Delete(user Users) error
+	SyncDirectoryUsers(users []LDAPUser, complete bool) (SyncResult, error)
+	GetPasswordHash(username string) (string, error)
+	SetPassword(username, passwordHash string, mustChange bool) error
+	GetTwoFactor(username string) (TwoFactor, error)
+	SetTwoFactor(username string, twoFactor TwoFactor) error
+	UseTOTPStep(username string, step int64) (bool, error)
+	UseBackupCode(username, codeHash string) (bool, error)
+	GetUserByOIDCSubject(subject string) ([]Users, error)
+	SetOIDCSubject(username, subject string) error
+	RecordLogin(username string, at time.Time) error
+	GetUserRecords(username string) (UserRecords, error)
+	TransferUserRecords(from, to string) (UserRecords, error)
+	// Login throttling
+	GetLoginAttempts(scope, name string) (LoginAttempts, error)
+	ListLoginAttempts() ([]LoginAttempts, error)
+	RecordLoginFailure(scope, name string, at, since time.Time) (LoginAttempts, error)
+	ClearLoginFailures(scope, name string) error
+	// Change history
+	AddChangeHistory(entry ChangeHistory) (ChangeHistory, error)
+	GetChangeHistory(limit int) ([]ChangeHistory, error)
 	// Notes
 	GetNote(id int) (Note, error)
 	GetNotes(username string) ([]Note, string, error)
@@ -27,16 +49,61 @@ type DatabaseOperations interface {
 	CreateNote(title, content string, username string, open bool) (Note, error)
 	SearchNotes(searchTerm string, username string) ([]Note, string, error)
 	// Tasks
+	GetTask(id int) (Tasks, error)
 	GetTasks(username string) ([]Tasks, string, error)
+	GetAssignedTasks(assignees []string) ([]Tasks, string, error)
 	CreateTask(task Tasks) (Tasks, error)
 	UpdateTask(task Tasks) (Tasks, error)
 	DeleteTask(id int, username string) error
 	// Audits
+	GetAudit(id int) (Audits, error)
 	GetAudits(username string) ([]Audits, string, error)
 	DeleteAudit(id int, username string) error
 	UpdateAudit(audit Audits) (Audits, error)
 	CreateAudit(audit Audits) (Audits, error)
+	ChangeAuditStatus(change AuditStatusChange, version int, completed bool) (Audits, error)
+	GetAuditStatusChanges(auditID int) ([]AuditStatusChange, error)
+	// Audit findings
+	GetFinding(id int) (Finding, error)
+	GetFindings(auditID int) ([]Finding, error)
+	CreateFinding(finding Finding) (Finding, error)
+	UpdateFinding(finding Finding) (Finding, error)
+	DeleteFinding(id int) error
+	// Evidence. content is nil for evidence kept in a directory.
+	AddEvidence(evidence Evidence, content []byte) (Evidence, error)
+	GetEvidence(id int) (Evidence, error)
+	ListEvidence(auditID int) ([]Evidence, error)
+	ReadEvidence(id int) ([]byte, error)
+	// Audit templates, and the checklists copied from them
+	GetTemplate(id int) (AuditTemplate, error)
+	GetTemplates() ([]AuditTemplate, error)
+	CreateTemplate(template AuditTemplate) (AuditTemplate, error)
+	UpdateTemplate(template AuditTemplate) (AuditTemplate, error)
+	DeleteTemplate(id int) error
+	GetChecklist(auditID int) ([]ChecklistItem, error)
+	UpdateChecklistItem(item ChecklistItem) (ChecklistItem, error)
+	// Compliance controls, and the audits, checklist items and findings mapped
+	// to them.
+	// An empty framework means every framework.
+	ImportControls(framework string, controls []Control) (ControlImport, error)
+	GetControls(framework string) ([]Control, error)
+	DeleteFramework(framework string) error
+	GetAuditControls(auditID int) ([]Control, error)
+	SetAuditControls(auditID int, controlIDs []int) error
+	SetChecklistItemControls(itemID int, controlIDs []int) error
+	SetFindingControls(findingID int, controlIDs []int) error
+	GetControlLinks(framework string) ([]ControlLink, error)
+	// Recurring audit schedules. GetScheduledAudits lists the audits created
+	// by any schedule for dates from from to to, inclusive.
+	GetSchedule(id int) (AuditSchedule, error)
+	GetSchedules() ([]AuditSchedule, error)
+	CreateSchedule(schedule AuditSchedule) (AuditSchedule, error)
+	UpdateSchedule(schedule AuditSchedule) (AuditSchedule, error)
+	DeleteSchedule(id int) error
+	SetScheduleCreatedThrough(id int, through time.Time) error
+	GetScheduledAudits(from, to time.Time) ([]Audits, error)
 	// CRM
+	GetCRMEntry(id int) (CRM, error)
 	GetCRMEntries(username string) ([]CRM, string, error)
 	DeleteCRMEntry(id int, username string) error
 	UpdateCRMEntry(crm CRM) (CRM, error)
@@ -50,6 +117,61 @@ type DatabaseOperations interface {
 	SearchCredentials(searchTerm, owner string) ([]Credentials, string, error)
 	CreateCredUser(username string, hashedPassword string, email string) (*Credentials, error)
 	GetUserPassword(username string) (string, error)
+	// Administration of everyone's records
+	ListRecords(kind string) ([]RecordSummary, error)
+	DeleteRecords(kind string, ids []int) (int, error)
+	ReassignRecords(kind string, ids []int, to string) (int, error)
+	CompleteRecords(kind string, ids []int) (int, error)
+	// Change notifications
+	SubscribeChanges() (<-chan ChangeEvent, func())
+}
+
+// ChangeEvent describes a row in a shared table that was inserted, updated or
+// deleted by any client connected to the same database.
+type ChangeEvent struct {
+	Table     string `json:"table"`
+	Operation string `json:"operation"`
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+}
+
+// Kinds of record an administrator can browse, named like their tables
+const (
+	RecordNotes  = "notes"
+	RecordTasks  = "tasks"
+	RecordAudits = "audits"
+	RecordCRM    = "crm"
+)
+
+// RecordKinds lists the record kinds in the order the browser shows them
+var RecordKinds = []string{RecordNotes, RecordTasks, RecordAudits, RecordCRM}
+
+// RecordSummary is one note, task, audit or CRM entry as the record browser
+// lists it. Assignee is the task assignee or the audit's assigned user;
+// reassigning changes it, or the owner for notes and CRM entries, which have
+// no assignee. Status is the audit's workflow status.
+type RecordSummary struct {
+	Kind      string
+	ID        int
+	Title     string
+	Owner     string
+	Assignee  string
+	Status    string
+	Completed bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Completable reports whether records of kind can be marked complete. Audits
+// are closed through their workflow instead.
+func Completable(kind string) bool {
+	return kind == RecordTasks
+}
+
+// ReassignsOwner reports whether reassigning records of kind changes their
+// owner rather than their assignee
+func ReassignsOwner(kind string) bool {
+	return kind == RecordNotes || kind == RecordCRM
 }
 
 type Note struct {
@@ -58,10 +180,11 @@ type Note struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	UserID    int       `json:"-"`
+	UserID    string    `json:"-"`
 	Username  string    `json:"username"`
 	Open      bool      `json:"open"`
 	Author    string    `json:"author"`
+	Version   int       `json:"version"`
 }
 
 type Tasks struct {
@@ -75,8 +198,11 @@ type Tasks struct {
 	Completed   bool      `json:"completed"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
-	UserID      int       `json:"-"`
+	UserID      string    `json:"-"`
 	Username    string    `json:"username"`
+	Assignee    string    `json:"assignee"`
+	FindingID   int       `json:"finding_id"`
+	Version     int       `json:"version"`
 }
 
 type Audits struct {
@@ -91,10 +217,48 @@ type Audits struct {
 	AssignedUser    string    `json:"assigned_user"`
 	CompletedAt     time.Time `json:"completed_at"`
 	Completed       bool      `json:"completed"`
-	UserID          int       `json:"-"`
+	UserID          string    `json:"-"`
 	Username        string    `json:"username"`
 	AdditionalUsers []string  `json:"additional_users"`
 	Firm            string    `json:"firm"`
+	Status          string    `json:"status"`
+	Reviewer        string    `json:"reviewer"`
+	TemplateID      int       `json:"template_id"`
+	ScheduleID      int       `json:"schedule_id"`
+	ScheduledFor    time.Time `json:"scheduled_for"`
+	Version         int       `json:"version"`
+}
+
+// NewAssignees lists the users a gives work to, as assignee, additional user
+// or reviewer, who before did not have it. before is empty for a new audit.
+func (a Audits) NewAssignees(before Audits) []string {
+	had := func(username string) bool {
+		for _, existing := range append([]string{before.AssignedUser, before.Reviewer}, before.AdditionalUsers...) {
+			if strings.EqualFold(existing, username) {
+				return true
+			}
+		}
+		return false
+	}
+	var added []string
+	for _, username := range append([]string{a.AssignedUser, a.Reviewer}, a.AdditionalUsers...) {
+		if username != "" && !had(username) {
+			added = append(added, username)
+		}
+	}
+	return added
+}
+
+// AuditStatusChange is one step of an audit through its workflow. Comment
+// says why, and is required when work is sent back.
+type AuditStatusChange struct {
+	ID      int       `json:"id"`
+	AuditID int       `json:"audit_id"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Actor   string    `json:"actor"`
+	Comment string    `json:"comment"`
+	At      time.Time `json:"at"`
 }
 
 type CRM struct {
@@ -107,15 +271,16 @@ type CRM struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Open      bool      `json:"open"`
-	UserID    int       `json:"-"`
+	UserID    string    `json:"-"`
 	Username  string    `json:"username"`
+	Version   int       `json:"version"`
 }
 
 type Credentials struct {
 	ID              int       `json:"id"`
 	Site            string    `json:"site"`
 	Program         string    `json:"program"`
-	UserID          int       `json:"-"`
+	UserID          string    `json:"-"`
 	Username        string    `json:"username"`
 	Email           string    `json:"email"`
 	MasterPassword  string    `json:"master_password"`
@@ -127,9 +292,13 @@ type Credentials struct {
 	PasswordHistory []string  `json:"password_history"`
 }
 
+// Users are the people goAudit knows about. UserID never changes once
+// assigned: users added by the directory sync get one derived from their
+// directory ID, everyone else a UUIDv7. Local users have a password stored in
+// goAudit and log in with Role instead of directory groups.
 type Users struct {
 	ID        int       `json:"id"`
-	UserID    int       `json:"-"`
+	UserID    string    `json:"-"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -137,21 +306,143 @@ type Users struct {
 	LastLogin time.Time `json:"last_login"`
 	Email     string    `json:"email"`
 	Status    string    `json:"status"`
+	// Filled in by the directory sync
+	DirectoryID string `json:"directory_id"`
+	DisplayName string `json:"display_name"`
+	Department  string `json:"department"`
+	Manager     string `json:"manager"`
+	// Local accounts
+	Role               string `json:"role"`
+	Local              bool   `json:"local"`
+	MustChangePassword bool   `json:"must_change_password"`
+	TwoFactor          bool   `json:"two_factor"`
+}
+
+// TwoFactor is a user's TOTP enrolment. Secret is encrypted and BackupCodes
+// holds hashes of the backup codes not used yet. An empty Secret means the
+// user has not enrolled.
+type TwoFactor struct {
+	Secret      string
+	BackupCodes []string
+}
+
+// LoginAttempts counts the failed logins for a username or a host.
+// RecordLoginFailure starts counting again when the last failure is older
+// than its since argument.
+type LoginAttempts struct {
+	Scope       string    `json:"scope"`
+	Name        string    `json:"name"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// Login attempt scopes. Credential logins are the master password of the
+// credentials store and are counted apart from application logins.
+const (
+	AttemptsUser        = "user"
+	AttemptsHost        = "host"
+	AttemptsCredentials = "credentials"
+)
+
+// ChangeHistory is one entry in the log of security relevant events, such as
+// failed logins and lockouts. Actor is empty when nobody was logged in.
+type ChangeHistory struct {
+	ID      int       `json:"id"`
+	At      time.Time `json:"at"`
+	Actor   string    `json:"actor"`
+	Host    string    `json:"host"`
+	Action  string    `json:"action"`
+	Subject string    `json:"subject"`
+	Detail  string    `json:"detail"`
+}
+
+// User statuses. Inactive users cannot be assigned work.
+const (
+	UserActive   = "Active"
+	UserInactive = "Inactive"
+)
+
+// UserRecords counts the records that belong to a user or are assigned to
+// them. Credentials are encrypted for their owner and cannot be transferred.
+type UserRecords struct {
+	Notes          int
+	Tasks          int
+	AssignedTasks  int
+	Audits         int
+	AssignedAudits int
+	Findings       int
+	CRMEntries     int
+	Credentials    int
+}
+
+// Total is the number of records of every kind
+func (r UserRecords) Total() int {
+	return r.Notes + r.Tasks + r.AssignedTasks + r.Audits + r.AssignedAudits + r.Findings + r.CRMEntries + r.Credentials
+}
+
+// String lists the kinds of record there are any of, e.g. "2 tasks, 1 audit"
+func (r UserRecords) String() string {
+	var parts []string
+	for _, count := range []struct {
+		n                int
+		singular, plural string
+	}{
+		{r.Notes, "note", "notes"},
+		{r.Tasks, "task", "tasks"},
+		{r.AssignedTasks, "assigned task", "assigned tasks"},
+		{r.Audits, "audit", "audits"},
+		{r.AssignedAudits, "assigned audit", "assigned audits"},
+		{r.Findings, "finding", "findings"},
+		{r.CRMEntries, "CRM entry", "CRM entries"},
+		{r.Credentials, "credential", "credentials"},
+	} {
+		switch {
+		case count.n == 1:
+			parts = append(parts, "1 "+count.singular)
+		case count.n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.plural))
+		}
+	}
+	if len(parts) == 0 {
+		return "no records"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SyncResult counts what a directory sync changed in the users table
+type SyncResult struct {
+	Created     int
+	Updated     int
+	Deactivated int
 }
 
 // LDAP Interface
 
+// LDAPConnection is the directory connection bound as the logged in user.
+// The password is only needed for the bind and is not kept.
 type LDAPConnection struct {
 	Conn     *ldap.Conn
 	Username string
-	Password string
 	Server   string
 	Domain   string
+	// Read from the directory when the user logged in
+	DN          string
+	DisplayName string
+	Email       string
+	Groups      []string
 }
 
 type LDAPUser struct {
-	DN       string
-	Username string
+	DN          string
+	Username    string
+	DisplayName string
+	Email       string
+	// DN of the user's manager, when the directory records one
+	ManagerDN string
+	// objectGUID or entryUUID, which survive renames and moves
+	DirectoryID string
+	Department  string
+	Disabled    bool
 }
 type CredentialAuth struct {
 	IsAuthenticated bool
@@ -163,6 +454,25 @@ var (
 	credentialAuthLock sync.RWMutex
 )
 
+// Login is a successful authentication by one of the sources in the auth
+// chain, or through OpenID Connect. LDAP is set for directory logins and nil
+// otherwise; Groups holds the groups an OpenID Connect provider sent.
+type Login struct {
+	Username           string
+	Source             string
+	LDAP               *LDAPConnection
+	Groups             []string
+	MustChangePassword bool
+}
+
+// Authenticator is one source of accounts in the auth chain. Authenticate
+// returns ErrAuthUnavailable or ErrUnknownUser to let the next source try,
+// and ErrInvalidCredentials to stop the chain.
+type Authenticator interface {
+	Name() string
+	Authenticate(username, password string) (*Login, error)
+}
+
 type LDAPOperations interface {
 	// LDAPOperations
 	ConnectToAdServer(username, password string) (*LDAPConnection, error)
@@ -0,0 +1,68 @@
+package interfaces
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/recurrence"
+)
+
+// How many days ahead of its date a scheduled audit is created, unless the
+// schedule says otherwise, and the furthest it may say
+const (
+	DefaultLeadDays = 30
+	MaxLeadDays     = 366
+)
+
+// AuditSchedule creates an audit for Firm on each date its Recurrence, an
+// RRULE such as "FREQ=MONTHLY;INTERVAL=3", falls on from StartDate. Each
+// audit is created LeadDays ahead of its date, owned by CreatedBy, assigned
+// to Assignee and started from the template, if any. Paused schedules
+// create nothing.
+//
+// CreatedThrough is the last date audits have been created up to; it only
+// moves forward, so an audit that was deleted is not created again.
+type AuditSchedule struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Recurrence     string    `json:"recurrence"`
+	StartDate      time.Time `json:"start_date"`
+	LeadDays       int       `json:"lead_days"`
+	Firm           string    `json:"firm"`
+	AuditType      string    `json:"audit_type"`
+	AuditArea      string    `json:"audit_area"`
+	TemplateID     int       `json:"template_id"`
+	Assignee       string    `json:"assignee"`
+	Paused         bool      `json:"paused"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedThrough time.Time `json:"created_through"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Version        int       `json:"version"`
+}
+
+// Validate checks that the schedule has a name, a start date, a lead time in
+// range and a recurrence rule goAudit can read
+func (s AuditSchedule) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("%w: a name is required", ErrInvalidSchedule)
+	}
+	if s.StartDate.IsZero() {
+		return fmt.Errorf("%w: a start date is required", ErrInvalidSchedule)
+	}
+	if s.LeadDays < 0 || s.LeadDays > MaxLeadDays {
+		return fmt.Errorf("%w: audits can be created 0 to %d days ahead, not %d", ErrInvalidSchedule, MaxLeadDays, s.LeadDays)
+	}
+	if _, err := s.Rule(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return nil
+}
+
+// Rule parses the schedule's recurrence
+func (s AuditSchedule) Rule() (recurrence.Rule, error) {
+	return recurrence.Parse(s.Recurrence)
+}
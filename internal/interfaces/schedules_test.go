@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	valid := AuditSchedule{Name: "Quarterly access review", Recurrence: "FREQ=MONTHLY;INTERVAL=3", StartDate: start,
+		LeadDays: DefaultLeadDays}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	for name, schedule := range map[string]AuditSchedule{
+		"no name":        {Recurrence: "FREQ=YEARLY", StartDate: start},
+		"no start date":  {Name: "x", Recurrence: "FREQ=YEARLY"},
+		"no recurrence":  {Name: "x", StartDate: start},
+		"bad recurrence": {Name: "x", Recurrence: "FREQ=HOURLY", StartDate: start},
+		"negative lead":  {Name: "x", Recurrence: "FREQ=YEARLY", StartDate: start, LeadDays: -1},
+		"lead too long":  {Name: "x", Recurrence: "FREQ=YEARLY", StartDate: start, LeadDays: MaxLeadDays + 1},
+	} {
+		if err := schedule.Validate(); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidSchedule", name, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package interfaces
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Checklist results. Items start without one.
+const (
+	ChecklistPending       = ""
+	ChecklistPass          = "Pass"
+	ChecklistFail          = "Fail"
+	ChecklistNotApplicable = "N/A"
+)
+
+// ChecklistResults lists the results an item can be marked with
+var ChecklistResults = []string{ChecklistPass, ChecklistFail, ChecklistNotApplicable}
+
+// TemplateItem is a checklist item as written in a template
+type TemplateItem struct {
+	Title            string `json:"title"`
+	ExpectedEvidence string `json:"expected_evidence,omitempty"`
+	Guidance         string `json:"guidance,omitempty"`
+}
+
+// AuditTemplate is the starting point for audits of one AuditType. Audits
+// created from it take its AuditArea and a copy of its checklist; the
+// FindingCategories are offered as areas for their findings.
+type AuditTemplate struct {
+	ID                int            `json:"id"`
+	Name              string         `json:"name"`
+	AuditType         string         `json:"audit_type"`
+	AuditArea         string         `json:"audit_area"`
+	Items             []TemplateItem `json:"items"`
+	FindingCategories []string       `json:"finding_categories"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Version           int            `json:"version"`
+}
+
+// Validate checks that the template has a name and every item a title
+func (t AuditTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("%w: a name is required", ErrInvalidTemplate)
+	}
+	for i, item := range t.Items {
+		if strings.TrimSpace(item.Title) == "" {
+			return fmt.Errorf("%w: checklist item %d has no title", ErrInvalidTemplate, i+1)
+		}
+	}
+	return nil
+}
+
+// ChecklistItem is one item of an audit's checklist, copied from its
+// template when the audit was created. Result and Comment are the only
+// fields that change; UpdatedBy and UpdatedAt say who last set them.
+// Controls lists the controls the item is mapped to and is read only.
+type ChecklistItem struct {
+	ID               int       `json:"id"`
+	AuditID          int       `json:"audit_id"`
+	Position         int       `json:"position"`
+	Title            string    `json:"title"`
+	ExpectedEvidence string    `json:"expected_evidence"`
+	Guidance         string    `json:"guidance"`
+	Result           string    `json:"result"`
+	Comment          string    `json:"comment"`
+	UpdatedBy        string    `json:"updated_by"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Controls         []int     `json:"controls"`
+	Version          int       `json:"version"`
+}
+
+// Validate checks that the item's result is known
+func (c ChecklistItem) Validate() error {
+	if c.Result == ChecklistPending {
+		return nil
+	}
+	for _, result := range ChecklistResults {
+		if c.Result == result {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown checklist result %q", ErrInvalidTemplate, c.Result)
+}
+
+// ChecklistProgress counts the items of a checklist by result
+func ChecklistProgress(items []ChecklistItem) map[string]int {
+	counts := make(map[string]int, len(ChecklistResults)+1)
+	for _, item := range items {
+		counts[item.Result]++
+	}
+	return counts
+}
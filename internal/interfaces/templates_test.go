@@ -0,0 +1,36 @@
+package interfaces
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+)
+
+func TestTemplateValidate(t *testing.T) {
+	valid := AuditTemplate{Name: "Access review", Items: []TemplateItem{{Title: "Leavers removed"}}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	for name, template := range map[string]AuditTemplate{
+		"no name":       {Name: " "},
+		"untitled item": {Name: "x", Items: []TemplateItem{{Title: "a"}, {Guidance: "b"}}},
+	} {
+		if err := template.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidTemplate", name, err)
+		}
+	}
+
+	items := []ChecklistItem{{Result: ChecklistPass}, {Result: ChecklistFail}, {Result: ChecklistPass}, {}}
+	for _, item := range items {
+		if err := item.Validate(); err != nil {
+			t.Errorf("Validate(%q) = %v", item.Result, err)
+		}
+	}
+	if err := (ChecklistItem{Result: "Done"}).Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("unknown result = %v, want ErrInvalidTemplate", err)
+	}
+	progress := ChecklistProgress(items)
+	if progress[ChecklistPass] != 2 || progress[ChecklistFail] != 1 || progress[ChecklistPending] != 1 {
+		t.Errorf("ChecklistProgress = %v", progress)
+	}
+}
@@ -0,0 +1,145 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// ShowChangePasswordDialog asks for a new password twice and passes it to
+// change. done reports whether the password was changed; cancelling a
+// forced change should abandon the login.
+func ShowChangePasswordDialog(window fyne.Window, message string, change func(replacement string) error, done func(changed bool)) {
+	passwordEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+
+	label := widget.NewLabel(fmt.Sprintf("%s Passwords must be at least %d characters.", message, config.Current().Auth.PasswordLength()))
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("New Password", passwordEntry),
+		widget.NewFormItem("Confirm", confirmEntry),
+	}
+
+	changeDialog := dialog.NewForm("Change Password", "Change", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			done(false)
+			return
+		}
+		var err error
+		if passwordEntry.Text != confirmEntry.Text {
+			err = errors.New("the passwords do not match")
+		} else {
+			err = change(passwordEntry.Text)
+		}
+		if err != nil {
+			// Ask again once the error has been read
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { ShowChangePasswordDialog(window, message, change, done) })
+			errorDialog.Show()
+			return
+		}
+		done(true)
+	}, window)
+	changeDialog.Resize(fyne.NewSize(450, 250))
+	changeDialog.Show()
+}
+
+// ShowFirstAccountDialog asks for the first administrator's username and
+// password when there is neither a directory nor any user to log in with
+func ShowFirstAccountDialog(window fyne.Window, create func(username, password string) error, done func(created bool)) {
+	usernameEntry := widget.NewEntry()
+	passwordEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+
+	label := widget.NewLabel("LDAP is not configured and goAudit has no users yet. Create a local administrator account, or cancel and configure LDAP from the Settings menu after logging in with it.")
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Username", usernameEntry),
+		widget.NewFormItem("Password", passwordEntry),
+		widget.NewFormItem("Confirm", confirmEntry),
+	}
+
+	firstDialog := dialog.NewForm("Create Administrator", "Create", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			done(false)
+			return
+		}
+		var err error
+		if passwordEntry.Text != confirmEntry.Text {
+			err = errors.New("the passwords do not match")
+		} else {
+			err = create(usernameEntry.Text, passwordEntry.Text)
+		}
+		if err != nil {
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { ShowFirstAccountDialog(window, create, done) })
+			errorDialog.Show()
+			return
+		}
+		done(true)
+	}, window)
+	firstDialog.Resize(fyne.NewSize(450, 300))
+	firstDialog.Show()
+}
+
+// showLocalAccountDialog creates a local account, or resets the password
+// and role of an existing one. The password is temporary: the user has to
+// change it at their next login.
+func showLocalAccountDialog(window fyne.Window) {
+	usernameEntry := widget.NewEntry()
+	displayNameEntry := widget.NewEntry()
+	emailEntry := widget.NewEntry()
+	roles := make([]string, len(rbac.Roles))
+	for i, role := range rbac.Roles {
+		roles[i] = string(role)
+	}
+	roleSelect := widget.NewSelect(roles, nil)
+	roleSelect.SetSelected(string(rbac.RoleAuditor))
+	passwordEntry := widget.NewPasswordEntry()
+
+	label := widget.NewLabel("Local accounts can log in when the directory is unavailable, or without one. An existing username keeps its records and gets the new role and password.")
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Username", usernameEntry),
+		widget.NewFormItem("Display Name", displayNameEntry),
+		widget.NewFormItem("Email", emailEntry),
+		widget.NewFormItem("Role", roleSelect),
+		widget.NewFormItem("Temporary Password", passwordEntry),
+	}
+
+	accountDialog := dialog.NewForm("Local Account", "Save", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		account := interfaces.Users{
+			Username:    usernameEntry.Text,
+			DisplayName: displayNameEntry.Text,
+			Email:       emailEntry.Text,
+			Role:        roleSelect.Selected,
+		}
+		user, err := auth.SetLocalAccount(state.GlobalState.DB, account, passwordEntry.Text, true)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dialog.ShowInformation("Local Account", fmt.Sprintf("%s can now log in as %s and will be asked to choose a new password.",
+			user.Username, user.Role), window)
+	}, window)
+	accountDialog.Resize(fyne.NewSize(450, 400))
+	accountDialog.Show()
+}
@@ -11,7 +11,8 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	// Internal Imports
-	crud "github.com/j4m1n-t/goAudit/internal/databases"
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
 	state "github.com/j4m1n-t/goAudit/internal/status"
 )
 
@@ -24,102 +25,60 @@ func CreatePlaceholderAdminTab() fyne.CanvasObject {
 func CreateAdminTabContent(window fyne.Window) fyne.CanvasObject {
 	// LDAP Setup
 	ldapSetupButton := widget.NewButton("LDAP Setup", func() {
-		showLDAPSetupDialog(window)
+		ShowLDAPSetupDialog(window)
 	})
 
 	// Postgres Setup
 	postgresSetupButton := widget.NewButton("Postgres Setup", func() {
-		showPostgresSetupDialog(window)
+		ShowPostgresSetupDialog(window)
 	})
 
-	// Delete functions
-	deleteNoteButton := widget.NewButton("Delete Note", func() {
-		showDeleteDialog(window, "Note", deleteNote)
+	// Directory sync
+	syncDirectoryButton := widget.NewButton("Sync Directory", func() {
+		result, err := auth.SyncDirectory(state.GlobalState.DB)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dialog.ShowInformation("Directory Sync", fmt.Sprintf("%d users created, %d updated, %d marked inactive",
+			result.Created, result.Updated, result.Deactivated), window)
 	})
 
-	deleteTaskButton := widget.NewButton("Delete Task", func() {
-		showDeleteDialog(window, "Task", deleteTask)
+	// Everyone's records and users, to browse and change in bulk
+	recordsButton := widget.NewButton("Records", func() {
+		showRecordBrowser(window, recordKindName(interfaces.RecordNotes))
+	})
+	usersButton := widget.NewButton("Users", func() {
+		showRecordBrowser(window, "Users")
 	})
 
-	deleteUserButton := widget.NewButton("Delete User", func() {
-		showDeleteDialog(window, "User", deleteUser)
+	// Local accounts
+	localAccountButton := widget.NewButton("Local Account", func() {
+		showLocalAccountDialog(window)
 	})
 
-	deleteCRMButton := widget.NewButton("Delete CRM Entry", func() {
-		showDeleteDialog(window, "CRM Entry", deleteCRM)
+	resetTwoFactorButton := widget.NewButton("Reset Two-Factor Login", func() {
+		showResetTwoFactorDialog(window)
 	})
 
-	deleteAuditButton := widget.NewButton("Delete Audit", func() {
-		showDeleteDialog(window, "Audit", deleteAudit)
+	// Failed logins and the change history
+	loginAttemptsButton := widget.NewButton("Failed Logins", func() {
+		showLoginAttemptsDialog(window)
+	})
+	changeHistoryButton := widget.NewButton("Change History", func() {
+		showChangeHistoryDialog(window)
 	})
 
 	return container.NewVBox(
 		widget.NewLabel("Administrative Functions"),
 		ldapSetupButton,
 		postgresSetupButton,
-		deleteNoteButton,
-		deleteTaskButton,
-		deleteUserButton,
-		deleteCRMButton,
-		deleteAuditButton,
-	)
-}
-
-func showLDAPSetupDialog(window fyne.Window) {
-	// Implement LDAP setup dialog
-	// This should include fields for LDAP server, port, base DN, etc.
-}
-
-func showPostgresSetupDialog(window fyne.Window) {
-	// Implement Postgres setup dialog
-	// This should include fields for host, port, database name, user, password, etc.
-}
-
-func showDeleteDialog(window fyne.Window, itemType string, deleteFunc func(int) error) {
-	idEntry := widget.NewEntry()
-	idEntry.SetPlaceHolder("Enter ID to delete")
-
-	content := container.NewVBox(
-		widget.NewLabel("Delete "+itemType),
-		idEntry,
+		syncDirectoryButton,
+		usersButton,
+		localAccountButton,
+		resetTwoFactorButton,
+		loginAttemptsButton,
+		changeHistoryButton,
+		recordsButton,
 	)
-
-	dialog.ShowCustomConfirm("Confirm Delete", "Delete", "Cancel", content, func(confirm bool) {
-		if confirm {
-			id := parseInt(idEntry.Text)
-			if id == 0 {
-				dialog.ShowError(fmt.Errorf("Invalid ID"), window)
-				return
-			}
-			err := deleteFunc(id)
-			if err != nil {
-				dialog.ShowError(err, window)
-			} else {
-				dialog.ShowInformation("Success", itemType+" deleted successfully", window)
-			}
-		}
-	}, window)
-}
-
-var dw *crud.DatabaseWrapper
-
-func deleteNote(id int) error {
-	return dw.DeleteNote(id)
-}
-
-func deleteTask(id int) error {
-	return dw.DeleteTask(id, state.GlobalState.Username)
-}
-
-func deleteUser(id int) error {
-	// Implement user deletion logic
-	return fmt.Errorf("user deletion not implemented")
-}
-
-func deleteCRM(id int) error {
-	return dw.DeleteCRMEntry(id, state.GlobalState.Username)
-}
-
-func deleteAudit(id int) error {
-	return dw.DeleteAudit(id, state.GlobalState.Username)
 }
@@ -2,8 +2,9 @@ package layouts
 
 import (
 	// Standard Library
+	"errors"
 	"fmt"
-	"time"
+	"strings"
 
 	// Fyne Imports
 	"fyne.io/fyne/v2"
@@ -13,12 +14,18 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	// Internal Imports
-
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
 	state "github.com/j4m1n-t/goAudit/internal/status"
 )
 
-var auditsList *widget.List
+var (
+	auditsList        *widget.List
+	auditStatusFilter *widget.Select
+	// Indexes into state.GlobalState.Audits of the audits the filter shows
+	shownAudits []int
+)
 
 func CreatePlaceholderAuditsTab() fyne.CanvasObject {
 	return container.NewVBox(
@@ -33,43 +40,87 @@ func CreateAuditsTabContent(window fyne.Window) fyne.CanvasObject {
 
 	auditsList = widget.NewList(
 		func() int {
-			return len(state.GlobalState.Audits)
+			return len(shownAudits)
 		},
 		func() fyne.CanvasObject {
 			return container.NewHBox(
 				widget.NewIcon(theme.DocumentIcon()),
+				widget.NewLabelWithStyle("Status", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 				widget.NewLabel("Audit Action"),
 				widget.NewLabel("Audit Type"),
 			)
 		},
 		func(id widget.ListItemID, item fyne.CanvasObject) {
-			if id < len(state.GlobalState.Audits) {
-				audit := state.GlobalState.Audits[id]
-				item.(*fyne.Container).Objects[1].(*widget.Label).SetText(audit.Action)
-				item.(*fyne.Container).Objects[2].(*widget.Label).SetText(audit.AuditType)
+			if audit := shownAudit(id); audit != nil {
+				item.(*fyne.Container).Objects[1].(*widget.Label).SetText(audits.Workflow().State(*audit))
+				item.(*fyne.Container).Objects[2].(*widget.Label).SetText(audit.Action)
+				item.(*fyne.Container).Objects[3].(*widget.Label).SetText(audit.AuditType)
 			}
 		},
 	)
 
 	auditsList.OnSelected = func(id widget.ListItemID) {
-		if id < len(state.GlobalState.Audits) {
-			showAuditDialog(window, &state.GlobalState.Audits[id])
+		auditsList.Unselect(id)
+		if audit := shownAudit(id); audit != nil {
+			showAuditDialog(window, audit)
 		}
 	}
 
+	auditStatusFilter = widget.NewSelect(append([]string{filterAll}, audits.Workflow().States...), func(string) {
+		showAudits()
+	})
+	auditStatusFilter.SetSelected(filterAll)
+
+	auditButtons := container.NewHBox(newAuditButton, widget.NewButton("Controls", func() {
+		showControlsDialog(window)
+	}), widget.NewButton("Calendar", func() {
+		showCalendarDialog(window)
+	}))
+	if state.GlobalState.CurrentSession().Can(rbac.TemplatesManage) {
+		auditButtons.Add(widget.NewButton("Templates", func() {
+			showTemplatesDialog(window)
+		}))
+	}
+	if state.GlobalState.CurrentSession().Can(rbac.SchedulesManage) {
+		auditButtons.Add(widget.NewButton("Schedules", func() {
+			showSchedulesDialog(window)
+		}))
+	}
+
 	return container.NewBorder(
 		container.NewVBox(
 			widget.NewLabel("Audits"),
-			newAuditButton,
+			container.NewBorder(nil, nil, auditButtons, nil, auditStatusFilter),
 		),
 		nil, nil, nil,
 		auditsList,
 	)
 }
 
+// showAudits lists the audits whose status matches the filter
+func showAudits() {
+	if auditsList == nil {
+		return
+	}
+	workflow := audits.Workflow()
+	shownAudits = shownAudits[:0]
+	for i, audit := range state.GlobalState.Audits {
+		if auditStatusFilter == nil || auditStatusFilter.Selected == filterAll || workflow.State(audit) == auditStatusFilter.Selected {
+			shownAudits = append(shownAudits, i)
+		}
+	}
+	auditsList.Refresh()
+}
+
+func shownAudit(id widget.ListItemID) *interfaces.Audits {
+	if id < 0 || id >= len(shownAudits) || shownAudits[id] >= len(state.GlobalState.Audits) {
+		return nil
+	}
+	return &state.GlobalState.Audits[shownAudits[id]]
+}
+
 func showAuditDialog(window fyne.Window, audit *interfaces.Audits) {
-	var actionEntry, auditTypeEntry, auditAreaEntry, notesEntry, assignedUserEntry, firmEntry *widget.Entry
-	var completedCheck *widget.Check
+	var actionEntry, auditTypeEntry, auditAreaEntry, notesEntry, assignedUserEntry, reviewerEntry, firmEntry *widget.Entry
 
 	actionEntry = widget.NewEntry()
 	actionEntry.SetPlaceHolder("Enter action")
@@ -87,19 +138,50 @@ func showAuditDialog(window fyne.Window, audit *interfaces.Audits) {
 	assignedUserEntry = widget.NewEntry()
 	assignedUserEntry.SetPlaceHolder("Enter assigned user")
 
+	reviewerEntry = widget.NewEntry()
+	reviewerEntry.SetPlaceHolder("Enter reviewer")
+	if !state.GlobalState.CurrentSession().Can(rbac.AuditsAssignReviewer) {
+		reviewerEntry.Disable()
+	}
+
 	firmEntry = widget.NewEntry()
 	firmEntry.SetPlaceHolder("Enter firm")
 
-	completedCheck = widget.NewCheck("Completed", nil)
+	workflow := audits.Workflow()
+	statusLabel := widget.NewLabelWithStyle(workflow.Initial(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+
+	fillForm := func(a interfaces.Audits) {
+		actionEntry.SetText(a.Action)
+		auditTypeEntry.SetText(a.AuditType)
+		auditAreaEntry.SetText(a.AuditArea)
+		notesEntry.SetText(a.Notes)
+		assignedUserEntry.SetText(a.AssignedUser)
+		reviewerEntry.SetText(a.Reviewer)
+		firmEntry.SetText(a.Firm)
+		statusLabel.SetText(workflow.State(a))
+	}
 
 	if audit != nil {
-		actionEntry.SetText(audit.Action)
-		auditTypeEntry.SetText(audit.AuditType)
-		auditAreaEntry.SetText(audit.AuditArea)
-		notesEntry.SetText(audit.Notes)
-		assignedUserEntry.SetText(audit.AssignedUser)
-		firmEntry.SetText(audit.Firm)
-		completedCheck.SetChecked(audit.Completed)
+		fillForm(*audit)
+	}
+
+	// New audits can start from a template, which fills in the type and area
+	var templates []interfaces.AuditTemplate
+	templateSelect := widget.NewSelect(nil, nil)
+	if audit == nil {
+		templates, _ = state.GlobalState.DB.GetTemplates()
+		choices := []string{"None"}
+		for _, template := range templates {
+			choices = append(choices, template.Name)
+		}
+		templateSelect.Options = choices
+		templateSelect.OnChanged = func(string) {
+			if index := templateSelect.SelectedIndex(); index > 0 {
+				auditTypeEntry.SetText(templates[index-1].AuditType)
+				auditAreaEntry.SetText(templates[index-1].AuditArea)
+			}
+		}
+		templateSelect.SetSelected(choices[0])
 	}
 
 	saveButton := widget.NewButton("Save", func() {
@@ -110,11 +192,14 @@ func showAuditDialog(window fyne.Window, audit *interfaces.Audits) {
 				AuditArea:    auditAreaEntry.Text,
 				Notes:        notesEntry.Text,
 				AssignedUser: assignedUserEntry.Text,
+				Reviewer:     strings.TrimSpace(reviewerEntry.Text),
 				Firm:         firmEntry.Text,
-				Completed:    completedCheck.Checked,
 				Username:     state.GlobalState.Username,
 			}
-			_, err := dw.CreateAudit(newAudit)
+			if index := templateSelect.SelectedIndex(); index > 0 {
+				newAudit.TemplateID = templates[index-1].ID
+			}
+			_, err := state.GlobalState.DB.CreateAudit(newAudit)
 			if err != nil {
 				dialog.ShowError(err, window)
 				return
@@ -125,35 +210,55 @@ func showAuditDialog(window fyne.Window, audit *interfaces.Audits) {
 			audit.AuditArea = auditAreaEntry.Text
 			audit.Notes = notesEntry.Text
 			audit.AssignedUser = assignedUserEntry.Text
+			audit.Reviewer = strings.TrimSpace(reviewerEntry.Text)
 			audit.Firm = firmEntry.Text
-			audit.Completed = completedCheck.Checked
-			if audit.Completed {
-				audit.CompletedAt = time.Now()
-			}
-			_, err := dw.UpdateAudit(*audit)
+			updatedAudit, err := state.GlobalState.DB.UpdateAudit(*audit)
 			if err != nil {
-				dialog.ShowError(err, window)
+				handleAuditUpdateError(window, audit, err, fillForm)
 				return
 			}
+			*audit = updatedAudit
 		}
 
 		refreshAudits(window)
 		dialog.ShowInformation("Success", "Audit saved successfully", window)
 	})
 
+	var auditDialog dialog.Dialog
+	statusRow := container.NewHBox(widget.NewLabel("Status"), statusLabel)
+	if audit != nil && audit.ScheduleID != 0 {
+		statusRow.Add(widget.NewLabel("Scheduled for " + audit.ScheduledFor.Format("2006-01-02")))
+	}
+	var checklist, controls, history, findings, evidence fyne.CanvasObject = widget.NewLabel(""), widget.NewLabel(""),
+		widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel("")
 	var buttons fyne.CanvasObject
 	if audit != nil {
+		for _, transition := range workflow.Available(state.GlobalState.CurrentSession(), *audit) {
+			transition := transition
+			statusRow.Add(widget.NewButton(transitionLabel(workflow, transition), func() {
+				showStatusChangeDialog(window, audit, transition, func() { auditDialog.Hide() })
+			}))
+		}
+		history = auditHistory(audit.ID)
+		controls = controlsSection(window, audit)
+		checklist = checklistSection(window, audit)
+		findings = findingsSection(window, audit)
+		evidence = evidenceSection(window, audit)
+
 		deleteButton := widget.NewButton("Delete", func() {
 			dialog.ShowConfirm("Confirm Delete", "Are you sure you want to delete this audit?", func(confirm bool) {
-				if confirm {
-					err := dw.DeleteAudit(audit.ID, state.GlobalState.Username)
+				if !confirm {
+					return
+				}
+				ConfirmIdentity(window, "delete this audit", func() {
+					err := state.GlobalState.DB.DeleteAudit(audit.ID, state.GlobalState.Username)
 					if err != nil {
 						dialog.ShowError(err, window)
 						return
 					}
 					refreshAudits(window)
 					dialog.ShowInformation("Success", "Audit deleted successfully", window)
-				}
+				})
 			}, window)
 		})
 		buttons = container.NewHBox(saveButton, deleteButton)
@@ -172,13 +277,142 @@ func showAuditDialog(window fyne.Window, audit *interfaces.Audits) {
 		notesEntry,
 		widget.NewLabel("Assigned User"),
 		assignedUserEntry,
+		widget.NewLabel("Reviewer"),
+		reviewerEntry,
 		widget.NewLabel("Firm"),
 		firmEntry,
-		completedCheck,
+		statusRow,
 		buttons,
+		controls,
+		checklist,
+		findings,
+		evidence,
+		history,
 	)
+	if audit == nil {
+		content.Objects = append([]fyne.CanvasObject{widget.NewLabel("Template"), templateSelect}, content.Objects...)
+	}
 
-	dialog.ShowCustom("Audit Details", "Close", content, window)
+	auditDialog = dialog.NewCustom("Audit Details", "Close", container.NewVScroll(content), window)
+	auditDialog.Resize(fyne.NewSize(760, 760))
+	auditDialog.Show()
+}
+
+// transitionLabel names a transition for its button. Going back to an
+// earlier state sends the work back.
+func transitionLabel(workflow rbac.Workflow, transition rbac.Transition) string {
+	from, to := -1, -1
+	for i, state := range workflow.States {
+		switch state {
+		case transition.From:
+			from = i
+		case transition.To:
+			to = i
+		}
+	}
+	if to < from {
+		return "Send Back to " + transition.To
+	}
+	return "Move to " + transition.To
+}
+
+// showStatusChangeDialog asks for a comment, which some transitions require,
+// and moves the audit. moved is called once it has.
+func showStatusChangeDialog(window fyne.Window, audit *interfaces.Audits, transition rbac.Transition, moved func()) {
+	commentEntry := widget.NewMultiLineEntry()
+	commentEntry.Wrapping = fyne.TextWrapWord
+	commentLabel := "Comment"
+	if transition.Comment {
+		commentLabel = "Comment (required)"
+		commentEntry.Validator = func(text string) error {
+			if strings.TrimSpace(text) == "" {
+				return fmt.Errorf("say why")
+			}
+			return nil
+		}
+	}
+	items := []*widget.FormItem{widget.NewFormItem(commentLabel, commentEntry)}
+
+	title := fmt.Sprintf("%s: %s", transitionLabel(audits.Workflow(), transition), audit.Action)
+	changeDialog := dialog.NewForm(title, "Confirm", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		change := interfaces.AuditStatusChange{AuditID: audit.ID, To: transition.To, Comment: commentEntry.Text}
+		updated, err := state.GlobalState.DB.ChangeAuditStatus(change, audit.Version, false)
+		if errors.Is(err, interfaces.ErrConflict) {
+			refreshAudits(window)
+			dialog.ShowError(fmt.Errorf("%v; open it again to see the changes", err), window)
+			moved()
+			return
+		}
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		*audit = updated
+		refreshAudits(window)
+		moved()
+		dialog.ShowInformation("Audit Status", fmt.Sprintf("%s is now %s.", audit.Action, audit.Status), window)
+	}, window)
+	changeDialog.Resize(fyne.NewSize(450, 250))
+	changeDialog.Show()
+}
+
+// auditHistory lists the status changes of an audit, oldest first
+func auditHistory(auditID int) fyne.CanvasObject {
+	changes, err := state.GlobalState.DB.GetAuditStatusChanges(auditID)
+	text := "No status changes yet."
+	if err != nil {
+		text = err.Error()
+	} else if len(changes) > 0 {
+		lines := make([]string, 0, len(changes))
+		for _, change := range changes {
+			line := fmt.Sprintf("%s  %s: %s -> %s", change.At.Local().Format("2006-01-02 15:04"), change.Actor, change.From, change.To)
+			if change.Comment != "" {
+				line += "\n    " + strings.ReplaceAll(change.Comment, "\n", "\n    ")
+			}
+			lines = append(lines, line)
+		}
+		text = strings.Join(lines, "\n")
+	}
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	return widget.NewAccordion(widget.NewAccordionItem("Status History", label))
+}
+
+// handleAuditUpdateError offers a merge when the audit was changed by someone else
+func handleAuditUpdateError(window fyne.Window, audit *interfaces.Audits, err error, fillForm func(interfaces.Audits)) {
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		dialog.ShowError(err, window)
+		return
+	}
+	current, ok := conflict.Current.(interfaces.Audits)
+	if !ok {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	showConflictDialog(window, conflict, auditConflictFields(*audit), auditConflictFields(current),
+		func() {
+			// The status only changes through the workflow, so keep theirs
+			audit.Version = current.Version
+			audit.Status, audit.Completed, audit.CompletedAt = current.Status, current.Completed, current.CompletedAt
+			updatedAudit, err := state.GlobalState.DB.UpdateAudit(*audit)
+			if err != nil {
+				handleAuditUpdateError(window, audit, err, fillForm)
+				return
+			}
+			*audit = updatedAudit
+			refreshAudits(window)
+			dialog.ShowInformation("Success", "Audit saved successfully", window)
+		},
+		func() {
+			*audit = current
+			fillForm(current)
+			refreshAudits(window)
+		})
 }
 
 func refreshAudits(window fyne.Window) {
@@ -193,5 +427,5 @@ func refreshAudits(window fyne.Window) {
 		return
 	}
 	state.GlobalState.Audits = audits
-	auditsList.Refresh()
+	showAudits()
 }
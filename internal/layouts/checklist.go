@@ -0,0 +1,150 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+func checklistResultLabel(result string) string {
+	if result == interfaces.ChecklistPending {
+		return "Not checked"
+	}
+	return result
+}
+
+func checklistSummary(items []interfaces.ChecklistItem) string {
+	progress := interfaces.ChecklistProgress(items)
+	return fmt.Sprintf("Checklist: %d of %d checked (%d pass, %d fail, %d N/A)",
+		len(items)-progress[interfaces.ChecklistPending], len(items), progress[interfaces.ChecklistPass],
+		progress[interfaces.ChecklistFail], progress[interfaces.ChecklistNotApplicable])
+}
+
+// checklistSection lists the checklist an audit was given by its template,
+// with each item marked and commented on in place. Audits created without a
+// template have no checklist and show nothing.
+func checklistSection(window fyne.Window, audit *interfaces.Audits) fyne.CanvasObject {
+	items, err := state.GlobalState.DB.GetChecklist(audit.ID)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+	if len(items) == 0 {
+		return widget.NewLabel("")
+	}
+
+	summary := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	changed := func() { summary.SetText(checklistSummary(items)) }
+	changed()
+
+	rows := container.NewVBox()
+	for i := range items {
+		rows.Add(checklistRow(window, &items[i], changed))
+		rows.Add(widget.NewSeparator())
+	}
+	return container.NewVBox(summary, rows)
+}
+
+// checklistRow shows one item with its guidance and the expected evidence.
+// item is kept on the saved copy and changed is called after each save.
+func checklistRow(window fyne.Window, item *interfaces.ChecklistItem, changed func()) fyne.CanvasObject {
+	title := widget.NewLabelWithStyle(fmt.Sprintf("%d. %s", item.Position, item.Title), fyne.TextAlignLeading,
+		fyne.TextStyle{Bold: true})
+	title.Wrapping = fyne.TextWrapWord
+	row := container.NewVBox(title)
+	if item.Guidance != "" {
+		guidance := widget.NewLabel(item.Guidance)
+		guidance.Wrapping = fyne.TextWrapWord
+		row.Add(guidance)
+	}
+	if item.ExpectedEvidence != "" {
+		evidence := widget.NewLabelWithStyle("Expected evidence: "+item.ExpectedEvidence, fyne.TextAlignLeading,
+			fyne.TextStyle{Italic: true})
+		evidence.Wrapping = fyne.TextWrapWord
+		row.Add(evidence)
+	}
+
+	resultGroup := widget.NewRadioGroup(interfaces.ChecklistResults, nil)
+	resultGroup.Horizontal = true
+	commentEntry := widget.NewEntry()
+	commentEntry.SetPlaceHolder("Comment")
+	markedLabel := widget.NewLabel("")
+
+	fill := func(i interfaces.ChecklistItem) {
+		resultGroup.SetSelected(i.Result)
+		commentEntry.SetText(i.Comment)
+		if i.UpdatedBy == "" {
+			markedLabel.SetText(checklistResultLabel(i.Result))
+		} else {
+			markedLabel.SetText(fmt.Sprintf("%s by %s, %s", checklistResultLabel(i.Result), i.UpdatedBy,
+				i.UpdatedAt.Local().Format("2006-01-02 15:04")))
+		}
+	}
+	fill(*item)
+
+	stored := func(i interfaces.ChecklistItem) {
+		*item = i
+		fill(i)
+		changed()
+	}
+
+	var save func(interfaces.ChecklistItem)
+	save = func(edited interfaces.ChecklistItem) {
+		saved, err := state.GlobalState.DB.UpdateChecklistItem(edited)
+		var conflict *interfaces.ConflictError
+		if errors.As(err, &conflict) {
+			current, ok := conflict.Current.(interfaces.ChecklistItem)
+			if !ok {
+				dialog.ShowError(err, window)
+				return
+			}
+			showConflictDialog(window, conflict, checklistConflictFields(edited), checklistConflictFields(current),
+				func() {
+					edited.Version = current.Version
+					save(edited)
+				},
+				func() { stored(current) })
+			return
+		}
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		stored(saved)
+	}
+
+	controlsButton := widget.NewButton("", nil)
+	showControls := func() { controlsButton.SetText(fmt.Sprintf("Controls (%d)", len(item.Controls))) }
+	showControls()
+	controlsButton.OnTapped = func() {
+		showControlPicker(window, "Checklist Item Controls", item.Controls, func(ids []int) {
+			if err := state.GlobalState.DB.SetChecklistItemControls(item.ID, ids); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			item.Controls = ids
+			showControls()
+		})
+	}
+
+	saveButton := widget.NewButton("Save", func() {
+		edited := *item
+		edited.Result = resultGroup.Selected
+		edited.Comment = strings.TrimSpace(commentEntry.Text)
+		save(edited)
+	})
+
+	row.Add(container.NewBorder(nil, nil, resultGroup, markedLabel))
+	row.Add(container.NewBorder(nil, nil, nil, container.NewHBox(controlsButton, saveButton), commentEntry))
+	return row
+}
@@ -0,0 +1,184 @@
+package layouts
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+type conflictField struct {
+	Name  string
+	Value string
+}
+
+// showConflictDialog shows the user's edit next to the copy currently stored on
+// the server. Fields that differ are marked with "*". keepMine should retry the
+// save on top of the server version, useServer should discard the local edit.
+func showConflictDialog(window fyne.Window, conflict *interfaces.ConflictError, mine, theirs []conflictField,
+	keepMine func(), useServer func()) {
+	mineText, theirsText := formatConflictFields(mine, theirs)
+
+	mineEntry := widget.NewMultiLineEntry()
+	mineEntry.SetText(mineText)
+	mineEntry.Wrapping = fyne.TextWrapWord
+	mineEntry.Disable()
+
+	theirsEntry := widget.NewMultiLineEntry()
+	theirsEntry.SetText(theirsText)
+	theirsEntry.Wrapping = fyne.TextWrapWord
+	theirsEntry.Disable()
+
+	message := widget.NewLabel(fmt.Sprintf("This %s was changed by someone else while you were editing it. "+
+		"Compare the two versions and choose which one to keep.", conflict.Entity))
+	message.Wrapping = fyne.TextWrapWord
+
+	var conflictDialog dialog.Dialog
+	keepMineButton := widget.NewButton("Keep My Changes", func() {
+		conflictDialog.Hide()
+		keepMine()
+	})
+	useServerButton := widget.NewButton("Use Server Copy", func() {
+		conflictDialog.Hide()
+		useServer()
+	})
+
+	content := container.NewBorder(
+		message,
+		container.NewHBox(keepMineButton, useServerButton),
+		nil, nil,
+		container.NewGridWithColumns(2,
+			container.NewBorder(widget.NewLabel("Your changes"), nil, nil, nil, mineEntry),
+			container.NewBorder(widget.NewLabel("Current server copy"), nil, nil, nil, theirsEntry),
+		),
+	)
+
+	conflictDialog = dialog.NewCustom("Edit Conflict", "Keep Editing", content, window)
+	conflictDialog.Resize(fyne.NewSize(700, 500))
+	conflictDialog.Show()
+}
+
+func formatConflictFields(mine, theirs []conflictField) (string, string) {
+	var mineText, theirsText strings.Builder
+	for i := range mine {
+		marker := "  "
+		if i < len(theirs) && mine[i].Value != theirs[i].Value {
+			marker = "* "
+		}
+		fmt.Fprintf(&mineText, "%s%s: %s\n", marker, mine[i].Name, mine[i].Value)
+		if i < len(theirs) {
+			fmt.Fprintf(&theirsText, "%s%s: %s\n", marker, theirs[i].Name, theirs[i].Value)
+		}
+	}
+	return mineText.String(), theirsText.String()
+}
+
+func noteConflictFields(note interfaces.Note) []conflictField {
+	return []conflictField{
+		{"Title", note.Title},
+		{"Open", fmt.Sprintf("%t", note.Open)},
+		{"Updated", note.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Content", note.Content},
+	}
+}
+
+func taskConflictFields(task interfaces.Tasks) []conflictField {
+	return []conflictField{
+		{"Title", task.Title},
+		{"Description", task.Description},
+		{"Status", task.Status},
+		{"Priority", fmt.Sprintf("%d", task.Priority)},
+		{"Due Date", task.DueDate.Format("2006-01-02")},
+		{"Completed", fmt.Sprintf("%t", task.Completed)},
+		{"Assignee", task.Assignee},
+		{"Updated", task.UpdatedAt.Format("2006-01-02 15:04:05")},
+	}
+}
+
+func auditConflictFields(audit interfaces.Audits) []conflictField {
+	return []conflictField{
+		{"Action", audit.Action},
+		{"Audit Type", audit.AuditType},
+		{"Audit Area", audit.AuditArea},
+		{"Assigned User", audit.AssignedUser},
+		{"Reviewer", audit.Reviewer},
+		{"Firm", audit.Firm},
+		{"Status", audit.Status},
+		{"Updated", audit.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Notes", audit.Notes},
+	}
+}
+
+func findingConflictFields(finding interfaces.Finding) []conflictField {
+	return []conflictField{
+		{"Title", finding.Title},
+		{"Severity", finding.Severity},
+		{"Area", finding.Area},
+		{"Owner", finding.Owner},
+		{"Due Date", findingCell(finding, "Due")},
+		{"Status", finding.Status},
+		{"Updated", finding.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Description", finding.Description},
+		{"Recommendation", finding.Recommendation},
+	}
+}
+
+func templateConflictFields(template interfaces.AuditTemplate) []conflictField {
+	items := make([]string, len(template.Items))
+	for i, item := range template.Items {
+		items[i] = fmt.Sprintf("%d. %s", i+1, item.Title)
+	}
+	return []conflictField{
+		{"Name", template.Name},
+		{"Audit Type", template.AuditType},
+		{"Audit Area", template.AuditArea},
+		{"Finding Categories", strings.Join(template.FindingCategories, ", ")},
+		{"Updated", template.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Checklist", strings.Join(items, "\n")},
+	}
+}
+
+func scheduleConflictFields(schedule interfaces.AuditSchedule) []conflictField {
+	return []conflictField{
+		{"Name", schedule.Name},
+		{"Recurrence", schedule.Recurrence},
+		{"Start Date", schedule.StartDate.Format("2006-01-02")},
+		{"Lead Days", fmt.Sprintf("%d", schedule.LeadDays)},
+		{"Firm", schedule.Firm},
+		{"Audit Type", schedule.AuditType},
+		{"Audit Area", schedule.AuditArea},
+		{"Assignee", schedule.Assignee},
+		{"Paused", fmt.Sprintf("%t", schedule.Paused)},
+		{"Updated", schedule.UpdatedAt.Format("2006-01-02 15:04:05")},
+	}
+}
+
+func checklistConflictFields(item interfaces.ChecklistItem) []conflictField {
+	return []conflictField{
+		{"Item", item.Title},
+		{"Result", checklistResultLabel(item.Result)},
+		{"Marked By", item.UpdatedBy},
+		{"Updated", item.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Comment", item.Comment},
+	}
+}
+
+func crmConflictFields(crm interfaces.CRM) []conflictField {
+	return []conflictField{
+		{"Name", crm.Name},
+		{"Email", crm.Email},
+		{"Phone", crm.Phone},
+		{"Company", crm.Company},
+		{"Open", fmt.Sprintf("%t", crm.Open)},
+		{"Updated", crm.UpdatedAt.Format("2006-01-02 15:04:05")},
+		{"Notes", strings.Join(crm.Notes, "\n")},
+	}
+}
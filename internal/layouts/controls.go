@@ -0,0 +1,331 @@
+package layouts
+
+import (
+	// Standard Library
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
+	"github.com/j4m1n-t/goAudit/internal/catalog"
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// Largest catalog file read by the import
+const maxCatalogSize = 10 << 20
+
+// frameworkNames lists the frameworks of controls, which come sorted by framework
+func frameworkNames(controls []interfaces.Control) []string {
+	var names []string
+	for _, control := range controls {
+		if len(names) == 0 || names[len(names)-1] != control.Framework {
+			names = append(names, control.Framework)
+		}
+	}
+	return names
+}
+
+func controlLabel(control interfaces.Control) string {
+	return fmt.Sprintf("%s %s: %s", control.Framework, control.ControlID, control.Title)
+}
+
+func firmLabel(firm string) string {
+	if firm == "" {
+		return "(no firm)"
+	}
+	return firm
+}
+
+// showControlsDialog shows the coverage of a framework's controls by the
+// audits of each firm, and lets managers import and delete frameworks
+func showControlsDialog(window fyne.Window) {
+	message := widget.NewLabel("")
+	message.Wrapping = fyne.TextWrapWord
+	var matrix catalog.Matrix
+
+	table := widget.NewTable(
+		func() (int, int) { return len(matrix.Controls) + 1, len(matrix.Firms) + 2 },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			label.TextStyle = fyne.TextStyle{Bold: id.Row == 0}
+			label.SetText(coverageCellText(matrix, id))
+		},
+	)
+	table.SetColumnWidth(0, 100)
+	table.SetColumnWidth(1, 260)
+
+	frameworkSelect := widget.NewSelect(nil, nil)
+	frameworkSelect.PlaceHolder = "Select a framework"
+	showFramework := func(framework string) {
+		controls, err := state.GlobalState.DB.GetControls(framework)
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		links, err := state.GlobalState.DB.GetControlLinks(framework)
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		matrix = catalog.Coverage(controls, links)
+		for i := range matrix.Firms {
+			table.SetColumnWidth(i+2, 130)
+		}
+		message.SetText(coverageSummary(matrix))
+		table.Refresh()
+	}
+	frameworkSelect.OnChanged = showFramework
+
+	// reload shows selected, or else the framework shown before, or the first
+	reload := func(selected string) {
+		controls, err := state.GlobalState.DB.GetControls("")
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		frameworkSelect.Options = frameworkNames(controls)
+		if len(frameworkSelect.Options) == 0 {
+			frameworkSelect.ClearSelected()
+			matrix = catalog.Matrix{}
+			message.SetText("No frameworks have been imported yet")
+			table.Refresh()
+			return
+		}
+		if selected == "" {
+			selected = frameworkSelect.Selected
+		}
+		if !slices.Contains(frameworkSelect.Options, selected) {
+			selected = frameworkSelect.Options[0]
+		}
+		frameworkSelect.Selected = selected
+		frameworkSelect.Refresh()
+		showFramework(selected)
+	}
+
+	buttons := container.NewHBox()
+	if state.GlobalState.CurrentSession().Can(rbac.ControlsManage) {
+		buttons.Add(widget.NewButton("Import", func() {
+			showControlsImport(window, reload)
+		}))
+		buttons.Add(widget.NewButton("Delete Framework", func() {
+			framework := frameworkSelect.Selected
+			if framework == "" {
+				return
+			}
+			dialog.ShowConfirm("Delete Framework",
+				fmt.Sprintf("Delete %s and every audit and checklist mapping to its controls?", framework),
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					ConfirmIdentity(window, "delete "+framework, func() {
+						if err := audits.DeleteFramework(state.GlobalState.DB, framework); err != nil {
+							dialog.ShowError(err, window)
+							return
+						}
+						reload("")
+					})
+				}, window)
+		}))
+	}
+	reload("")
+
+	controlsDialog := dialog.NewCustom("Control Coverage", "Close",
+		container.NewBorder(container.NewVBox(container.NewBorder(nil, nil, nil, buttons, frameworkSelect), message),
+			nil, nil, nil, table), window)
+	controlsDialog.Resize(fyne.NewSize(900, 600))
+	controlsDialog.Show()
+}
+
+// coverageCellText is the text of a coverage table cell: a header row, then
+// the control ID, title and the status for each firm
+func coverageCellText(matrix catalog.Matrix, id widget.TableCellID) string {
+	if id.Row == 0 {
+		switch id.Col {
+		case 0:
+			return "Control"
+		case 1:
+			return "Title"
+		}
+		return firmLabel(matrix.Firms[id.Col-2])
+	}
+	control := matrix.Controls[id.Row-1]
+	switch id.Col {
+	case 0:
+		return control.ControlID
+	case 1:
+		return control.Title
+	}
+	cell := matrix.Cell(control.ID, matrix.Firms[id.Col-2])
+	if cell.OpenFindings > 0 {
+		return fmt.Sprintf("%s (%d)", cell.Status(), cell.OpenFindings)
+	}
+	return cell.Status()
+}
+
+// coverageSummary counts the statuses of the controls for each firm
+func coverageSummary(matrix catalog.Matrix) string {
+	if len(matrix.Firms) == 0 {
+		return fmt.Sprintf("%d controls, none mapped to an audit", len(matrix.Controls))
+	}
+	lines := make([]string, 0, len(matrix.Firms))
+	for _, firm := range matrix.Firms {
+		counts := matrix.Counts(firm)
+		var parts []string
+		for _, status := range catalog.Statuses {
+			if counts[status] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[status], strings.ToLower(status)))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", firmLabel(firm), strings.Join(parts, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showControlsImport reads a catalog file and imports its controls.
+// imported is called with the framework afterwards.
+func showControlsImport(window fyne.Window, imported func(framework string)) {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		content, err := io.ReadAll(io.LimitReader(reader, maxCatalogSize+1))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to read %s: %v", reader.URI().Name(), err), window)
+			return
+		}
+		if len(content) > maxCatalogSize {
+			dialog.ShowError(fmt.Errorf("%s is larger than %s", reader.URI().Name(), formatSize(maxCatalogSize)), window)
+			return
+		}
+		framework, result, err := audits.ImportControls(state.GlobalState.DB, reader.URI().Name(), content)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		imported(framework)
+		dialog.ShowInformation("Controls Imported", fmt.Sprintf("%s: %d added, %d updated, %d unchanged",
+			framework, result.Added, result.Updated, result.Unchanged), window)
+	}, window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".yaml", ".yml", ".json", ".csv"}))
+	fileDialog.Show()
+}
+
+// showControlPicker lets the user choose controls from the catalog. save is
+// called with the row IDs of the chosen controls.
+func showControlPicker(window fyne.Window, title string, selected []int, save func([]int)) {
+	controls, err := state.GlobalState.DB.GetControls("")
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	if len(controls) == 0 {
+		dialog.ShowInformation(title, "No frameworks have been imported yet", window)
+		return
+	}
+
+	chosen := make(map[int]bool, len(selected))
+	for _, id := range selected {
+		chosen[id] = true
+	}
+	checks := make([]*widget.Check, len(controls))
+	checksBox := container.NewVBox()
+	for i, control := range controls {
+		control := control
+		checks[i] = widget.NewCheck(controlLabel(control), func(on bool) { chosen[control.ID] = on })
+		checks[i].SetChecked(chosen[control.ID])
+		checksBox.Add(checks[i])
+	}
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by framework, ID, title or family")
+	filterEntry.OnChanged = func(text string) {
+		text = strings.ToLower(strings.TrimSpace(text))
+		for i, control := range controls {
+			if text == "" || strings.Contains(strings.ToLower(controlLabel(control)+" "+control.Family), text) {
+				checks[i].Show()
+			} else {
+				checks[i].Hide()
+			}
+		}
+	}
+
+	pickerDialog := dialog.NewCustomConfirm(title, "Save", "Cancel",
+		container.NewBorder(filterEntry, nil, nil, nil, container.NewVScroll(checksBox)),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			var ids []int
+			for _, control := range controls {
+				if chosen[control.ID] {
+					ids = append(ids, control.ID)
+				}
+			}
+			save(ids)
+		}, window)
+	pickerDialog.Resize(fyne.NewSize(600, 500))
+	pickerDialog.Show()
+}
+
+// controlsSection lists the controls an audit is mapped to
+func controlsSection(window fyne.Window, audit *interfaces.Audits) fyne.CanvasObject {
+	list := widget.NewLabel("")
+	list.Wrapping = fyne.TextWrapWord
+	var mapped []interfaces.Control
+	reload := func() {
+		controls, err := state.GlobalState.DB.GetAuditControls(audit.ID)
+		if err != nil {
+			list.SetText(err.Error())
+			return
+		}
+		mapped = controls
+		if len(mapped) == 0 {
+			list.SetText("Not mapped to any controls")
+			return
+		}
+		labels := make([]string, len(mapped))
+		for i, control := range mapped {
+			labels[i] = controlLabel(control)
+		}
+		list.SetText(strings.Join(labels, "\n"))
+	}
+	reload()
+
+	mapButton := widget.NewButton("Map Controls", func() {
+		selected := make([]int, len(mapped))
+		for i, control := range mapped {
+			selected[i] = control.ID
+		}
+		showControlPicker(window, "Audit Controls", selected, func(ids []int) {
+			if err := state.GlobalState.DB.SetAuditControls(audit.ID, ids); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			reload()
+		})
+	})
+	return container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabelWithStyle("Controls", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			mapButton),
+		list,
+	)
+}
@@ -30,7 +30,7 @@ func CreateCredentialsTabContent(window fyne.Window) fyne.CanvasObject {
 	var appState *state.AppState
 
 	// Check if user is not logged in
-	if state.GlobalState.UserID == 0 {
+	if state.GlobalState.UserID == "" {
 		// User not logged in, show appropriate dialog
 		if state.GlobalState.MPPresent {
 			ShowLoginDialog(window, appState)
@@ -38,13 +38,13 @@ func CreateCredentialsTabContent(window fyne.Window) fyne.CanvasObject {
 			showSignUpDialog(window)
 		}
 		// After dialog, recheck if user is now logged in
-		if state.GlobalState.UserID == 0 {
+		if state.GlobalState.UserID == "" {
 			return widget.NewLabel("Please log in to view credentials")
 		}
 	}
 
 	// User is logged in, create credentials content
-	if state.GlobalState.UserID != 0 {
+	if state.GlobalState.UserID != "" {
 		newCredentialButton := widget.NewButton("New Credential", func() {
 			showCredentialDialog(window, nil)
 		})
@@ -185,7 +185,10 @@ func showCredentialDialog(window fyne.Window, credential *interfaces.Credentials
 	if credential != nil {
 		deleteButton := widget.NewButton("Delete", func() {
 			dialog.ShowConfirm("Confirm Delete", "Are you sure you want to delete this credential?", func(confirm bool) {
-				if confirm {
+				if !confirm {
+					return
+				}
+				ConfirmIdentity(window, "delete this credential", func() {
 					err := state.GlobalState.DB.DeleteCredential(credential.ID, state.GlobalState.Username)
 					if err != nil {
 						dialog.ShowError(err, window)
@@ -193,7 +196,7 @@ func showCredentialDialog(window fyne.Window, credential *interfaces.Credentials
 					}
 					refreshCredentials(window)
 					dialog.ShowInformation("Success", "Credential deleted successfully", window)
-				}
+				})
 			}, window)
 		})
 		buttons = container.NewHBox(saveButton, deleteButton)
@@ -253,7 +256,17 @@ func ShowLoginDialog(window fyne.Window, appState *state.AppState) {
 		widget.NewFormItem("Password", passwordEntry),
 	}, func(res bool) {
 		if res {
-			user, err := auth.AuthenticateUser(state.GlobalState.DB, usernameEntry.Text, passwordEntry.Text)
+			var user *interfaces.Users
+			throttle := &auth.Throttle{DB: state.GlobalState.DB, Scope: interfaces.AttemptsCredentials}
+			err := throttle.Attempt(usernameEntry.Text, func() error {
+				var err error
+				user, err = auth.AuthenticateUser(state.GlobalState.DB, usernameEntry.Text, passwordEntry.Text)
+				return err
+			})
+			if errors.Is(err, interfaces.ErrLockedOut) {
+				dialog.ShowError(err, window)
+				return
+			}
 			if err != nil {
 				showSignUpDialog(window)
 				dialog.ShowError(err, window)
@@ -308,7 +321,7 @@ func showSignUpDialog(window fyne.Window) {
 				dialog.ShowError(err, window)
 				return
 			}
-			state.GlobalState.UserID = newUser.ID
+			state.GlobalState.UserID = newUser.UserID
 
 			state.GlobalState.Username = usernameEntry.Text
 			state.GlobalState.SetMasterPassword(state.GlobalState.Username, passwordEntry.Text)
@@ -2,7 +2,9 @@ package layouts
 
 import (
 	// Standard Library
+	"errors"
 	"fmt"
+	"strings"
 
 	// Fyne Imports
 	"fyne.io/fyne/v2"
@@ -89,13 +91,17 @@ func showCRMDialog(window fyne.Window, crm *interfaces.CRM) {
 
 	openCheck = widget.NewCheck("Open to All", nil)
 
+	fillForm := func(c interfaces.CRM) {
+		nameEntry.SetText(c.Name)
+		emailEntry.SetText(c.Email)
+		phoneEntry.SetText(c.Phone)
+		companyEntry.SetText(c.Company)
+		notesEntry.SetText(strings.Join(c.Notes, "\n"))
+		openCheck.SetChecked(c.Open)
+	}
+
 	if crm != nil {
-		nameEntry.SetText(crm.Name)
-		emailEntry.SetText(crm.Email)
-		phoneEntry.SetText(crm.Phone)
-		companyEntry.SetText(crm.Company)
-		notesEntry.SetText(crm.Notes[0]) // Assuming we're using only the first note for simplicity
-		openCheck.SetChecked(crm.Open)
+		fillForm(*crm)
 	}
 
 	saveButton := widget.NewButton("Save", func() {
@@ -109,7 +115,7 @@ func showCRMDialog(window fyne.Window, crm *interfaces.CRM) {
 				Open:     openCheck.Checked,
 				Username: state.GlobalState.Username,
 			}
-			_, err := dw.CreateCRMEntry(newCRM)
+			_, err := state.GlobalState.DB.CreateCRMEntry(newCRM)
 			if err != nil {
 				dialog.ShowError(err, window)
 				return
@@ -121,11 +127,12 @@ func showCRMDialog(window fyne.Window, crm *interfaces.CRM) {
 			crm.Company = companyEntry.Text
 			crm.Notes = []string{notesEntry.Text}
 			crm.Open = openCheck.Checked
-			_, err := dw.UpdateCRMEntry(*crm)
+			updatedCRM, err := state.GlobalState.DB.UpdateCRMEntry(*crm)
 			if err != nil {
-				dialog.ShowError(err, window)
+				handleCRMUpdateError(window, crm, err, fillForm)
 				return
 			}
+			*crm = updatedCRM
 		}
 
 		refreshCRM(window)
@@ -136,15 +143,18 @@ func showCRMDialog(window fyne.Window, crm *interfaces.CRM) {
 	if crm != nil {
 		deleteButton := widget.NewButton("Delete", func() {
 			dialog.ShowConfirm("Confirm Delete", "Are you sure you want to delete this CRM entry?", func(confirm bool) {
-				if confirm {
-					err := dw.DeleteCRMEntry(crm.ID, state.GlobalState.Username)
+				if !confirm {
+					return
+				}
+				ConfirmIdentity(window, "delete this CRM entry", func() {
+					err := state.GlobalState.DB.DeleteCRMEntry(crm.ID, state.GlobalState.Username)
 					if err != nil {
 						dialog.ShowError(err, window)
 						return
 					}
 					refreshCRM(window)
 					dialog.ShowInformation("Success", "CRM entry deleted successfully", window)
-				}
+				})
 			}, window)
 		})
 		buttons = container.NewHBox(saveButton, deleteButton)
@@ -170,6 +180,38 @@ func showCRMDialog(window fyne.Window, crm *interfaces.CRM) {
 	dialog.ShowCustom("CRM Entry Details", "Close", content, window)
 }
 
+// handleCRMUpdateError offers a merge when the entry was changed by someone else
+func handleCRMUpdateError(window fyne.Window, crm *interfaces.CRM, err error, fillForm func(interfaces.CRM)) {
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		dialog.ShowError(err, window)
+		return
+	}
+	current, ok := conflict.Current.(interfaces.CRM)
+	if !ok {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	showConflictDialog(window, conflict, crmConflictFields(*crm), crmConflictFields(current),
+		func() {
+			crm.Version = current.Version
+			updatedCRM, err := state.GlobalState.DB.UpdateCRMEntry(*crm)
+			if err != nil {
+				handleCRMUpdateError(window, crm, err, fillForm)
+				return
+			}
+			*crm = updatedCRM
+			refreshCRM(window)
+			dialog.ShowInformation("Success", "CRM entry saved successfully", window)
+		},
+		func() {
+			*crm = current
+			fillForm(current)
+			refreshCRM(window)
+		})
+}
+
 func refreshCRM(window fyne.Window) {
 	if state.GlobalState.DB == nil {
 		dialog.ShowError(fmt.Errorf("database is not initialized"), window)
@@ -0,0 +1,281 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+var evidenceColumns = []string{"Name", "Version", "Size", "Finding", "Uploaded By", "Uploaded", "SHA-256"}
+
+func evidenceCell(evidence interfaces.Evidence, findings map[int]string, column string) string {
+	switch column {
+	case "Name":
+		return evidence.Name
+	case "Version":
+		return fmt.Sprintf("v%d", evidence.Version)
+	case "Size":
+		return formatSize(evidence.Size)
+	case "Finding":
+		return findings[evidence.FindingID]
+	case "Uploaded By":
+		return evidence.Uploader
+	case "Uploaded":
+		return evidence.UploadedAt.Local().Format("2006-01-02 15:04")
+	case "SHA-256":
+		return evidence.SHA256[:min(12, len(evidence.SHA256))]
+	}
+	return ""
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// evidenceSection is the grid of the latest version of each piece of an
+// audit's evidence in the audit dialog. Tapping a row opens its versions.
+func evidenceSection(window fyne.Window, audit *interfaces.Audits) fyne.CanvasObject {
+	var all, latest []interfaces.Evidence
+	findingTitles := map[int]string{}
+	summary := widget.NewLabel("")
+
+	table := widget.NewTable(
+		func() (int, int) { return len(latest), len(evidenceColumns) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			if id.Row < len(latest) {
+				cell.(*widget.Label).SetText(evidenceCell(latest[id.Row], findingTitles, evidenceColumns[id.Col]))
+			}
+		},
+	)
+	table.ShowHeaderRow = true
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	table.UpdateHeader = func(id widget.TableCellID, header fyne.CanvasObject) {
+		header.(*widget.Label).SetText(evidenceColumns[id.Col])
+	}
+	for col, width := range []float32{150, 60, 80, 120, 90, 120, 110} {
+		table.SetColumnWidth(col, width)
+	}
+
+	reload := func() {
+		loaded, err := state.GlobalState.DB.ListEvidence(audit.ID)
+		if err != nil {
+			summary.SetText(err.Error())
+			return
+		}
+		if findings, err := state.GlobalState.DB.GetFindings(audit.ID); err == nil {
+			for _, finding := range findings {
+				findingTitles[finding.ID] = finding.Title
+			}
+		}
+		all, latest = loaded, nil
+		for _, evidence := range all {
+			if evidence.ReplacedBy == 0 {
+				latest = append(latest, evidence)
+			}
+		}
+		summary.SetText(fmt.Sprintf("Evidence: %d files, %d versions", len(latest), len(all)))
+		table.Refresh()
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		if id.Row >= 0 && id.Row < len(latest) {
+			showEvidenceDialog(window, audit, latest[id.Row], evidenceVersions(all, latest[id.Row]), reload)
+		}
+	}
+	reload()
+
+	uploadButton := widget.NewButton("Upload Evidence", func() {
+		showEvidenceUpload(window, audit, interfaces.Evidence{AuditID: audit.ID}, reload)
+	})
+	return container.NewVBox(
+		container.NewBorder(nil, nil, nil, uploadButton, summary),
+		container.NewGridWrap(fyne.NewSize(700, 160), table),
+	)
+}
+
+// evidenceVersions is the chain of versions ending in latest, newest first
+func evidenceVersions(all []interfaces.Evidence, latest interfaces.Evidence) []interfaces.Evidence {
+	byID := make(map[int]interfaces.Evidence, len(all))
+	for _, evidence := range all {
+		byID[evidence.ID] = evidence
+	}
+	versions := []interfaces.Evidence{latest}
+	for current := latest; current.Replaces != 0; {
+		previous, ok := byID[current.Replaces]
+		if !ok {
+			break
+		}
+		versions = append(versions, previous)
+		current = previous
+	}
+	return versions
+}
+
+// showEvidenceDialog shows the details and history of a piece of evidence,
+// with actions to save a verified copy of any version or upload a new one
+func showEvidenceDialog(window fyne.Window, audit *interfaces.Audits, latest interfaces.Evidence,
+	versions []interfaces.Evidence, changed func()) {
+	details := widget.NewForm(
+		widget.NewFormItem("Name", widget.NewLabel(latest.Name)),
+		widget.NewFormItem("Type", widget.NewLabel(latest.ContentType)),
+		widget.NewFormItem("Size", widget.NewLabel(fmt.Sprintf("%s (%d bytes)", formatSize(latest.Size), latest.Size))),
+		widget.NewFormItem("SHA-256", widget.NewLabelWithStyle(latest.SHA256, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})),
+		widget.NewFormItem("Stored In", widget.NewLabel(capitalize(latest.Storage))),
+	)
+
+	var evidenceDialog dialog.Dialog
+	history := container.NewVBox()
+	for _, version := range versions {
+		version := version
+		line := fmt.Sprintf("v%d  %s  %s, %s", version.Version, version.UploadedAt.Local().Format("2006-01-02 15:04"),
+			version.Uploader, formatSize(version.Size))
+		if version.Comment != "" {
+			line += "\n    " + strings.ReplaceAll(version.Comment, "\n", "\n    ")
+		}
+		label := widget.NewLabel(line)
+		label.Wrapping = fyne.TextWrapWord
+		history.Add(container.NewBorder(nil, nil, nil, widget.NewButton("Save a Copy", func() {
+			saveEvidenceCopy(window, version)
+		}), label))
+	}
+
+	replaceButton := widget.NewButton("Upload New Version", func() {
+		evidenceDialog.Hide()
+		showEvidenceUpload(window, audit, interfaces.Evidence{AuditID: audit.ID, FindingID: latest.FindingID,
+			Replaces: latest.ID}, changed)
+	})
+
+	content := container.NewVBox(details, widget.NewLabelWithStyle("Versions", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		history, container.NewHBox(replaceButton))
+	evidenceDialog = dialog.NewCustom("Evidence: "+latest.Name, "Close", container.NewVScroll(content), window)
+	evidenceDialog.Resize(fyne.NewSize(650, 500))
+	evidenceDialog.Show()
+}
+
+// showEvidenceUpload picks a file and uploads it as evidence, which may
+// replace earlier evidence. The finding and comment are asked for first.
+func showEvidenceUpload(window fyne.Window, audit *interfaces.Audits, evidence interfaces.Evidence, changed func()) {
+	findings, _ := state.GlobalState.DB.GetFindings(audit.ID)
+	choices := []string{"None"}
+	for _, finding := range findings {
+		choices = append(choices, fmt.Sprintf("#%d %s", finding.ID, finding.Title))
+	}
+	findingSelect := widget.NewSelect(choices, nil)
+	findingSelect.SetSelected(choices[0])
+	for i, finding := range findings {
+		if finding.ID == evidence.FindingID {
+			findingSelect.SetSelected(choices[i+1])
+		}
+	}
+	commentEntry := widget.NewMultiLineEntry()
+	commentEntry.SetPlaceHolder("What this shows")
+
+	title := "Upload Evidence"
+	if evidence.Replaces != 0 {
+		title = "Upload New Version"
+	}
+	dialog.ShowForm(title, "Choose File", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Finding", findingSelect),
+		widget.NewFormItem("Comment", commentEntry),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		evidence.FindingID = 0
+		if index := findingSelect.SelectedIndex(); index > 0 {
+			evidence.FindingID = findings[index-1].ID
+		}
+		evidence.Comment = strings.TrimSpace(commentEntry.Text)
+
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			limit := config.Current().Evidence.MaxSize()
+			content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to read %s: %v", reader.URI().Name(), err), window)
+				return
+			}
+			evidence.Name = reader.URI().Name()
+			evidence.ContentType = reader.URI().MimeType()
+			evidence.Uploader = state.GlobalState.Username
+			uploaded, err := audits.UploadEvidence(state.GlobalState.DB, evidence, content)
+			if err != nil {
+				if errors.Is(err, interfaces.ErrConflict) {
+					err = fmt.Errorf("someone else has already uploaded a new version; reopen the evidence to see it")
+				}
+				dialog.ShowError(err, window)
+				return
+			}
+			changed()
+			dialog.ShowInformation("Evidence Uploaded", fmt.Sprintf("%s version %d was stored with SHA-256\n%s",
+				uploaded.Name, uploaded.Version, uploaded.SHA256), window)
+		}, window)
+	}, window)
+}
+
+// saveEvidenceCopy checks evidence against its recorded hash and, if it
+// matches, saves it where the user chooses
+func saveEvidenceCopy(window fyne.Window, evidence interfaces.Evidence) {
+	_, content, err := audits.DownloadEvidence(state.GlobalState.DB, evidence.ID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrEvidenceTampered) {
+			err = fmt.Errorf("%s version %d no longer matches the SHA-256 recorded when it was uploaded and was not saved. "+
+				"This has been recorded in the change history.", evidence.Name, evidence.Version)
+		}
+		dialog.ShowError(err, window)
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		if _, err := writer.Write(content); err != nil {
+			writer.Close()
+			dialog.ShowError(fmt.Errorf("failed to save %s: %v", evidence.Name, err), window)
+			return
+		}
+		if err := writer.Close(); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save %s: %v", evidence.Name, err), window)
+			return
+		}
+		dialog.ShowInformation("Evidence Saved", fmt.Sprintf("%s version %d matched its SHA-256 and was saved.",
+			evidence.Name, evidence.Version), window)
+	}, window)
+	save.SetFileName(evidence.Name)
+	save.Show()
+}
@@ -0,0 +1,335 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+var findingColumns = []string{"Severity", "Title", "Area", "Owner", "Due", "Status", "Tasks"}
+
+func findingCell(finding interfaces.Finding, column string) string {
+	switch column {
+	case "Severity":
+		return capitalize(finding.Severity)
+	case "Title":
+		return finding.Title
+	case "Area":
+		return finding.Area
+	case "Owner":
+		return finding.Owner
+	case "Due":
+		if finding.DueDate.IsZero() {
+			return ""
+		}
+		return finding.DueDate.Format("2006-01-02")
+	case "Status":
+		return finding.Status
+	case "Tasks":
+		ids := make([]string, len(finding.Tasks))
+		for i, id := range finding.Tasks {
+			ids[i] = fmt.Sprintf("#%d", id)
+		}
+		return strings.Join(ids, ", ")
+	}
+	return ""
+}
+
+// findingsSection is the grid of an audit's findings in the audit dialog.
+// Tapping a finding opens it; findings are added from here too.
+func findingsSection(window fyne.Window, audit *interfaces.Audits) fyne.CanvasObject {
+	var findings []interfaces.Finding
+	summary := widget.NewLabel("")
+
+	table := widget.NewTable(
+		func() (int, int) { return len(findings), len(findingColumns) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			if id.Row < len(findings) {
+				cell.(*widget.Label).SetText(findingCell(findings[id.Row], findingColumns[id.Col]))
+			}
+		},
+	)
+	table.ShowHeaderRow = true
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	table.UpdateHeader = func(id widget.TableCellID, header fyne.CanvasObject) {
+		header.(*widget.Label).SetText(findingColumns[id.Col])
+	}
+	for col, width := range []float32{80, 160, 90, 90, 90, 110, 70} {
+		table.SetColumnWidth(col, width)
+	}
+
+	reload := func() {
+		loaded, err := state.GlobalState.DB.GetFindings(audit.ID)
+		if err != nil {
+			summary.SetText(err.Error())
+			return
+		}
+		findings = loaded
+		open := 0
+		for _, finding := range findings {
+			if !finding.Closed() {
+				open++
+			}
+		}
+		summary.SetText(fmt.Sprintf("Findings: %d, %d open", len(findings), open))
+		table.Refresh()
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		if id.Row >= 0 && id.Row < len(findings) {
+			finding := findings[id.Row]
+			showFindingDialog(window, audit, &finding, reload)
+		}
+	}
+	reload()
+
+	addButton := widget.NewButton("Add Finding", func() {
+		showFindingDialog(window, audit, nil, reload)
+	})
+	return container.NewVBox(
+		container.NewBorder(nil, nil, nil, addButton, summary),
+		container.NewGridWrap(fyne.NewSize(700, 200), table),
+	)
+}
+
+// showFindingDialog adds a finding to audit, or edits finding. changed is
+// called after every save so the grid can reload.
+func showFindingDialog(window fyne.Window, audit *interfaces.Audits, finding *interfaces.Finding, changed func()) {
+	titleEntry := widget.NewEntry()
+	titleEntry.SetPlaceHolder("Enter title")
+
+	descriptionEntry := widget.NewMultiLineEntry()
+	descriptionEntry.SetPlaceHolder("What was found")
+	descriptionEntry.Wrapping = fyne.TextWrapWord
+
+	severitySelect := widget.NewSelect(capitalizeAll(interfaces.Severities), nil)
+	severitySelect.SetSelected(capitalize(interfaces.SeverityMedium))
+
+	areaEntry := widget.NewSelectEntry(findingCategories(audit))
+	areaEntry.SetPlaceHolder("Affected area")
+
+	recommendationEntry := widget.NewMultiLineEntry()
+	recommendationEntry.SetPlaceHolder("How to fix it")
+	recommendationEntry.Wrapping = fyne.TextWrapWord
+
+	ownerEntry := widget.NewSelectEntry(assigneeChoices())
+	ownerEntry.SetPlaceHolder("Nobody")
+
+	dueDateEntry := widget.NewEntry()
+	dueDateEntry.SetPlaceHolder("Enter due date (YYYY-MM-DD)")
+
+	statusSelect := widget.NewSelect(interfaces.FindingStatuses, nil)
+	statusSelect.SetSelected(interfaces.FindingOpen)
+
+	fillForm := func(f interfaces.Finding) {
+		titleEntry.SetText(f.Title)
+		descriptionEntry.SetText(f.Description)
+		severitySelect.SetSelected(capitalize(f.Severity))
+		areaEntry.SetText(f.Area)
+		recommendationEntry.SetText(f.Recommendation)
+		ownerEntry.SetText(f.Owner)
+		dueDateEntry.SetText(findingCell(f, "Due"))
+		statusSelect.SetSelected(f.Status)
+	}
+	if finding != nil {
+		fillForm(*finding)
+	}
+
+	// stored keeps the dialog on the saved copy so the next save is not a conflict
+	stored := func(f interfaces.Finding) {
+		if finding != nil {
+			*finding = f
+		}
+		changed()
+	}
+
+	var findingDialog dialog.Dialog
+	saveButton := widget.NewButton("Save", func() {
+		var dueDate time.Time
+		if text := strings.TrimSpace(dueDateEntry.Text); text != "" {
+			var err error
+			if dueDate, err = time.ParseInLocation("2006-01-02", text, time.Local); err != nil {
+				dialog.ShowError(fmt.Errorf("due date %q is not a date like 2024-06-30", text), window)
+				return
+			}
+		}
+		edited := interfaces.Finding{AuditID: audit.ID}
+		if finding != nil {
+			edited = *finding
+		}
+		edited.Title = strings.TrimSpace(titleEntry.Text)
+		edited.Description = descriptionEntry.Text
+		edited.Severity = strings.ToLower(severitySelect.Selected)
+		edited.Area = strings.TrimSpace(areaEntry.Text)
+		edited.Recommendation = recommendationEntry.Text
+		edited.Owner = strings.TrimSpace(ownerEntry.Text)
+		edited.DueDate = dueDate
+		edited.Status = statusSelect.Selected
+
+		var saved interfaces.Finding
+		var err error
+		if finding == nil {
+			saved, err = state.GlobalState.DB.CreateFinding(edited)
+		} else {
+			saved, err = state.GlobalState.DB.UpdateFinding(edited)
+		}
+		if err != nil {
+			handleFindingSaveError(window, edited, err, fillForm, stored)
+			return
+		}
+		if finding == nil {
+			findingDialog.Hide()
+		}
+		stored(saved)
+		dialog.ShowInformation("Success", "Finding saved successfully", window)
+	})
+
+	buttons := container.NewHBox(saveButton)
+	if finding != nil {
+		controlsButton := widget.NewButton("", nil)
+		showControls := func() { controlsButton.SetText(fmt.Sprintf("Controls (%d)", len(finding.Controls))) }
+		showControls()
+		controlsButton.OnTapped = func() {
+			showControlPicker(window, "Finding Controls", finding.Controls, func(ids []int) {
+				if err := state.GlobalState.DB.SetFindingControls(finding.ID, ids); err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				finding.Controls = ids
+				showControls()
+			})
+		}
+		buttons.Add(controlsButton)
+		buttons.Add(widget.NewButton("Create Remediation Task", func() {
+			createRemediationTask(window, *finding, func(f interfaces.Finding) {
+				fillForm(f)
+				stored(f)
+			}, changed)
+		}))
+		buttons.Add(widget.NewButton("Delete", func() {
+			dialog.ShowConfirm("Confirm Delete", fmt.Sprintf("Delete the finding %q? Its remediation tasks are kept.", finding.Title),
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					ConfirmIdentity(window, "delete this finding", func() {
+						if err := state.GlobalState.DB.DeleteFinding(finding.ID); err != nil {
+							dialog.ShowError(err, window)
+							return
+						}
+						findingDialog.Hide()
+						changed()
+					})
+				}, window)
+		}))
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Title", titleEntry),
+		widget.NewFormItem("Severity", severitySelect),
+		widget.NewFormItem("Area", areaEntry),
+		widget.NewFormItem("Description", descriptionEntry),
+		widget.NewFormItem("Recommendation", recommendationEntry),
+		widget.NewFormItem("Owner", ownerEntry),
+		widget.NewFormItem("Due Date", dueDateEntry),
+		widget.NewFormItem("Status", statusSelect),
+	)
+	title := "New Finding"
+	if finding != nil {
+		title = "Finding: " + finding.Title
+	}
+	findingDialog = dialog.NewCustom(title, "Close", container.NewVScroll(container.NewVBox(form, buttons)), window)
+	findingDialog.Resize(fyne.NewSize(550, 600))
+	findingDialog.Show()
+}
+
+// createRemediationTask adds a task to fix finding, assigned to its owner and
+// linked back to it. An open finding moves into remediation and is passed to
+// moved; otherwise changed is called.
+func createRemediationTask(window fyne.Window, finding interfaces.Finding, moved func(interfaces.Finding), changed func()) {
+	description := finding.Description
+	if finding.Recommendation != "" {
+		description = strings.TrimSpace(description + "\n\nRecommendation: " + finding.Recommendation)
+	}
+	task, err := state.GlobalState.DB.CreateTask(interfaces.Tasks{
+		Title:       "Remediate: " + finding.Title,
+		Description: description,
+		Priority:    interfaces.SeverityRank(finding.Severity) + 1,
+		DueDate:     finding.DueDate,
+		Username:    state.GlobalState.Username,
+		Assignee:    finding.Owner,
+		FindingID:   finding.ID,
+	})
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	refreshTasks(window)
+	if finding.Status == interfaces.FindingOpen {
+		finding.Status = interfaces.FindingInRemediation
+		updated, err := state.GlobalState.DB.UpdateFinding(finding)
+		if err != nil {
+			changed()
+			dialog.ShowError(fmt.Errorf("task %d was created, but the finding stays open: %v", task.ID, err), window)
+			return
+		}
+		moved(updated)
+	} else {
+		changed()
+	}
+	dialog.ShowInformation("Remediation Task", fmt.Sprintf("Task #%d was created for %q.", task.ID, finding.Title), window)
+}
+
+// handleFindingSaveError offers a merge when the finding was changed by someone else
+func handleFindingSaveError(window fyne.Window, finding interfaces.Finding, err error, fillForm func(interfaces.Finding),
+	stored func(interfaces.Finding)) {
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		dialog.ShowError(err, window)
+		return
+	}
+	current, ok := conflict.Current.(interfaces.Finding)
+	if !ok {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	showConflictDialog(window, conflict, findingConflictFields(finding), findingConflictFields(current),
+		func() {
+			finding.Version = current.Version
+			updated, err := state.GlobalState.DB.UpdateFinding(finding)
+			if err != nil {
+				handleFindingSaveError(window, finding, err, fillForm, stored)
+				return
+			}
+			stored(updated)
+			dialog.ShowInformation("Success", "Finding saved successfully", window)
+		},
+		func() {
+			fillForm(current)
+			stored(current)
+		})
+}
+
+func capitalizeAll(values []string) []string {
+	capitalized := make([]string, len(values))
+	for i, value := range values {
+		capitalized[i] = capitalize(value)
+	}
+	return capitalized
+}
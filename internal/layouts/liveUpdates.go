@@ -0,0 +1,82 @@
+package layouts
+
+import (
+	// Standard Library
+	"log"
+	"time"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+
+	// Internal Imports
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// Bursts of changes (bulk edits, imports) are folded into one refresh per table
+const liveUpdateDelay = 500 * time.Millisecond
+
+// StartLiveUpdates refreshes the lists whenever any client changes a shared
+// table. The returned function stops listening.
+func StartLiveUpdates(window fyne.Window) func() {
+	if state.GlobalState.DB == nil {
+		log.Println("StartLiveUpdates: database is not initialized")
+		return func() {}
+	}
+
+	events, unsubscribe := state.GlobalState.DB.SubscribeChanges()
+	go func() {
+		pending := make(map[string]bool)
+		var flush <-chan time.Time
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				log.Printf("Change received: %s %s %d by %s", event.Operation, event.Table, event.ID, event.Username)
+				pending[event.Table] = true
+				if flush == nil {
+					flush = time.After(liveUpdateDelay)
+				}
+			case <-flush:
+				for table := range pending {
+					refreshTable(table)
+				}
+				pending = make(map[string]bool)
+				flush = nil
+			}
+		}
+	}()
+
+	return unsubscribe
+}
+
+func refreshTable(table string) {
+	var err error
+	switch table {
+	case "notes":
+		err = state.GlobalState.FetchNotes()
+		if notesList != nil {
+			notesList.Refresh()
+		}
+	case "tasks":
+		err = state.GlobalState.FetchTasks()
+		if tasksList != nil {
+			tasksList.Refresh()
+		}
+		if teamErr := refreshTeam(); teamErr != nil {
+			log.Printf("Error refreshing team after change: %v", teamErr)
+		}
+	case "audits":
+		err = state.GlobalState.FetchAudits()
+		showAudits()
+	case "crm":
+		err = state.GlobalState.FetchCRMEntries()
+		if crmList != nil {
+			crmList.Refresh()
+		}
+	}
+	if err != nil {
+		log.Printf("Error refreshing %s after change: %v", table, err)
+	}
+}
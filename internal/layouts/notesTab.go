@@ -28,7 +28,7 @@ func CreatePlaceholderNotesTab() fyne.CanvasObject {
 }
 
 func CreateNotesTabContent(window fyne.Window) fyne.CanvasObject {
-	var appState *state.AppState
+	appState := state.GlobalState
 	searchEntry := widget.NewEntry()
 	searchEntry.SetPlaceHolder("Search notes...")
 	searchButton := widget.NewButton("Search", func() {
@@ -107,10 +107,14 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 		log.Printf("Open status changed to: %v", checked)
 	})
 
+	fillForm := func(n interfaces.Note) {
+		titleEntry.SetText(n.Title)
+		contentEntry.SetText(n.Content)
+		openCheck.SetChecked(n.Open)
+	}
+
 	if note != nil {
-		titleEntry.SetText(note.Title)
-		contentEntry.SetText(note.Content)
-		openCheck.SetChecked(note.Open)
+		fillForm(*note)
 	}
 
 	saveButton := widget.NewButton("Save", func() {
@@ -122,7 +126,7 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 		log.Printf("Note Title changed to: %v", titleEntry.Text)
 		log.Printf("Note Content changed to: %v", contentEntry.Text)
 		if note == nil {
-			newNote, err := dw.CreateNote(titleEntry.Text, contentEntry.Text, appState.Username, openCheck.Checked)
+			newNote, err := state.GlobalState.DB.CreateNote(titleEntry.Text, contentEntry.Text, appState.Username, openCheck.Checked)
 			if err != nil {
 				dialog.ShowError(err, window)
 				return
@@ -132,11 +136,12 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 			note.Title = titleEntry.Text
 			note.Content = contentEntry.Text
 			note.Open = openCheck.Checked
-			updatedNote, err := dw.UpdateNote(*note)
+			updatedNote, err := state.GlobalState.DB.UpdateNote(*note)
 			if err != nil {
-				dialog.ShowError(err, window)
+				handleNoteUpdateError(window, note, err, fillForm, appState)
 				return
 			}
+			*note = updatedNote
 			log.Printf("Updated note with ID: %d", updatedNote.ID)
 		}
 		appState.FetchNotes()
@@ -147,8 +152,11 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 	deleteButton := widget.NewButton("Delete", func() {
 		if note != nil {
 			confirmDialog := dialog.NewConfirm("Confirm Delete", "Are you sure you want to delete this note?", func(confirm bool) {
-				if confirm {
-					err := dw.DeleteNote(note.ID)
+				if !confirm {
+					return
+				}
+				ConfirmIdentity(window, "delete this note", func() {
+					err := state.GlobalState.DB.DeleteNote(note.ID)
 					if err != nil {
 						dialog.ShowError(err, window)
 						return
@@ -157,7 +165,7 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 					appState.FetchNotes()
 					notesList.Refresh()
 					customDialog.Hide()
-				}
+				})
 			}, window)
 			confirmDialog.Show()
 		}
@@ -201,13 +209,48 @@ func showNoteDialog(window fyne.Window, note *interfaces.Note, appState *state.A
 	customDialog.Show()
 }
 
+// handleNoteUpdateError offers a merge when the note was changed by someone else
+func handleNoteUpdateError(window fyne.Window, note *interfaces.Note, err error, fillForm func(interfaces.Note),
+	appState *state.AppState) {
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		dialog.ShowError(err, window)
+		return
+	}
+	current, ok := conflict.Current.(interfaces.Note)
+	if !ok {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	showConflictDialog(window, conflict, noteConflictFields(*note), noteConflictFields(current),
+		func() {
+			note.Version = current.Version
+			updatedNote, err := state.GlobalState.DB.UpdateNote(*note)
+			if err != nil {
+				handleNoteUpdateError(window, note, err, fillForm, appState)
+				return
+			}
+			*note = updatedNote
+			log.Printf("Overwrote note with ID: %d after conflict", updatedNote.ID)
+			appState.FetchNotes()
+			notesList.Refresh()
+		},
+		func() {
+			*note = current
+			fillForm(current)
+			appState.FetchNotes()
+			notesList.Refresh()
+		})
+}
+
 func performSearch(searchTerm string, window fyne.Window, appState *state.AppState) {
 	if appState == nil || appState.Username == "" {
 		dialog.ShowError(errors.New("user is not logged in"), window)
 		return
 	}
 
-	searchResults, message, err := dw.SearchNotes(searchTerm, appState.Username)
+	searchResults, message, err := state.GlobalState.DB.SearchNotes(searchTerm, appState.Username)
 	if err != nil {
 		dialog.ShowError(err, window)
 		return
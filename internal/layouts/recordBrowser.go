@@ -0,0 +1,753 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	admin "github.com/j4m1n-t/goAudit/internal/admin"
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// Filter choices shared by the browser tabs
+const (
+	filterAll       = "All"
+	filterOpen      = "Open"
+	filterCompleted = "Completed"
+	allOwners       = "All owners"
+)
+
+// showRecordBrowser lists everyone's notes, tasks, audits, CRM entries and
+// users, one tab each, starting on the tab named first. Rows are selected
+// by tapping them and changed together.
+func showRecordBrowser(window fyne.Window, first string) {
+	tabs := container.NewAppTabs()
+	for _, kind := range interfaces.RecordKinds {
+		tabs.Append(container.NewTabItem(recordKindName(kind), recordBrowserTab(window, kind)))
+	}
+	tabs.Append(container.NewTabItem("Users", usersBrowserTab(window)))
+	for _, tab := range tabs.Items {
+		if tab.Text == first {
+			tabs.Select(tab)
+		}
+	}
+
+	browser := dialog.NewCustom("Records", "Close", tabs, window)
+	browser.Resize(fyne.NewSize(1000, 600))
+	browser.Show()
+}
+
+// selectTable is a table with a check mark in front of each row. Tapping a
+// row selects or unselects it; tapping a header sorts by that column.
+type selectTable struct {
+	columns    []string
+	rows       func() int
+	cell       func(row int, column string) string
+	selected   func(row int) bool
+	toggle     func(row int)
+	sorted     func()
+	sortColumn int
+	descending bool
+}
+
+func (s *selectTable) build(widths []float32) *widget.Table {
+	table := widget.NewTable(
+		func() (int, int) { return s.rows(), len(s.columns) + 1 },
+		func() fyne.CanvasObject { return container.NewStack(widget.NewIcon(nil), widget.NewLabel("")) },
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			icon := cell.(*fyne.Container).Objects[0].(*widget.Icon)
+			label := cell.(*fyne.Container).Objects[1].(*widget.Label)
+			if id.Col == 0 {
+				icon.SetResource(theme.CheckButtonIcon())
+				if s.selected(id.Row) {
+					icon.SetResource(theme.CheckButtonCheckedIcon())
+				}
+				icon.Show()
+				label.Hide()
+				return
+			}
+			icon.Hide()
+			label.SetText(s.cell(id.Row, s.columns[id.Col-1]))
+			label.Show()
+		},
+	)
+	table.ShowHeaderRow = true
+	table.CreateHeader = func() fyne.CanvasObject { return widget.NewButton("", nil) }
+	table.UpdateHeader = func(id widget.TableCellID, header fyne.CanvasObject) {
+		button := header.(*widget.Button)
+		if id.Col == 0 {
+			button.SetText("")
+			button.OnTapped = nil
+			return
+		}
+		text := s.columns[id.Col-1]
+		if id.Col-1 == s.sortColumn && s.descending {
+			text += " ▼"
+		} else if id.Col-1 == s.sortColumn {
+			text += " ▲"
+		}
+		button.SetText(text)
+		button.OnTapped = func() {
+			if s.sortColumn == id.Col-1 {
+				s.descending = !s.descending
+			} else {
+				s.sortColumn, s.descending = id.Col-1, false
+			}
+			s.sorted()
+		}
+	}
+	table.SetColumnWidth(0, 40)
+	for col, width := range widths {
+		table.SetColumnWidth(col+1, width)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		if id.Row >= 0 && id.Row < s.rows() {
+			s.toggle(id.Row)
+			table.Refresh()
+		}
+	}
+	return table
+}
+
+// hasStatus reports whether records of kind have an assignee and a status.
+// Notes and CRM entries have neither.
+func hasStatus(kind string) bool {
+	return !interfaces.ReassignsOwner(kind)
+}
+
+func recordColumns(kind string) []string {
+	if hasStatus(kind) {
+		return []string{"ID", "Title", "Owner", "Assignee", "Status", "Created", "Updated"}
+	}
+	return []string{"ID", "Title", "Owner", "Created", "Updated"}
+}
+
+func recordCell(record interfaces.RecordSummary, column string) string {
+	switch column {
+	case "ID":
+		return strconv.Itoa(record.ID)
+	case "Title":
+		return record.Title
+	case "Owner":
+		return record.Owner
+	case "Assignee":
+		if record.Assignee == "" {
+			return "-"
+		}
+		return record.Assignee
+	case "Status":
+		switch {
+		case record.Kind == interfaces.RecordAudits:
+			return audits.Workflow().State(interfaces.Audits{Status: record.Status, Completed: record.Completed})
+		case record.Completed:
+			return filterCompleted
+		}
+		return filterOpen
+	case "Created":
+		return record.CreatedAt.Local().Format("2006-01-02 15:04")
+	case "Updated":
+		return record.UpdatedAt.Local().Format("2006-01-02 15:04")
+	}
+	return ""
+}
+
+// sortRecords orders records by a column, keeping IDs in order among equal
+// values
+func sortRecords(records []interfaces.RecordSummary, column string, descending bool) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if descending {
+			a, b = b, a
+		}
+		switch column {
+		case "Created":
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case "Updated":
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		case "ID":
+		default:
+			if x, y := strings.ToLower(recordCell(a, column)), strings.ToLower(recordCell(b, column)); x != y {
+				return x < y
+			}
+		}
+		return a.ID < b.ID
+	})
+}
+
+// matchesSearch reports whether search, in lower case, is part of the
+// record's ID, title, owner or assignee
+func matchesSearch(record interfaces.RecordSummary, search string) bool {
+	if search == "" {
+		return true
+	}
+	for _, value := range []string{strconv.Itoa(record.ID), record.Title, record.Owner, record.Assignee} {
+		if strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordBrowserTab lists every record of kind with a search, owner and
+// status filter, and the bulk actions for the selected records
+func recordBrowserTab(window fyne.Window, kind string) fyne.CanvasObject {
+	var records, shown []interfaces.RecordSummary
+	selected := make(map[int]bool)
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search by ID, title, owner or assignee")
+	ownerSelect := widget.NewSelect([]string{allOwners}, nil)
+	ownerSelect.SetSelected(allOwners)
+	statuses := []string{filterOpen, filterCompleted}
+	if kind == interfaces.RecordAudits {
+		statuses = audits.Workflow().States
+	}
+	statusSelect := widget.NewSelect(append([]string{filterAll}, statuses...), nil)
+	statusSelect.SetSelected(filterAll)
+	countLabel := widget.NewLabel("")
+
+	columns := recordColumns(kind)
+	list := &selectTable{
+		columns:    columns,
+		rows:       func() int { return len(shown) },
+		cell:       func(row int, column string) string { return recordCell(shown[row], column) },
+		selected:   func(row int) bool { return selected[shown[row].ID] },
+		sortColumn: len(columns) - 1,
+		descending: true,
+	}
+	var table *widget.Table
+	apply := func() {
+		search := strings.ToLower(strings.TrimSpace(searchEntry.Text))
+		shown = shown[:0]
+		for _, record := range records {
+			if !matchesSearch(record, search) ||
+				(ownerSelect.Selected != allOwners && record.Owner != ownerSelect.Selected) ||
+				(statusSelect.Selected != filterAll && recordCell(record, "Status") != statusSelect.Selected) {
+				continue
+			}
+			shown = append(shown, record)
+		}
+		sortRecords(shown, columns[list.sortColumn], list.descending)
+		countLabel.SetText(fmt.Sprintf("%d of %d shown, %d selected", len(shown), len(records), len(selected)))
+		table.Refresh()
+	}
+	reload := func() {
+		all, err := state.GlobalState.DB.ListRecords(kind)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		records = all
+		owners := []string{allOwners}
+		exists := make(map[int]bool, len(all))
+		for _, record := range all {
+			exists[record.ID] = true
+			if !containsText(owners, record.Owner) {
+				owners = append(owners, record.Owner)
+			}
+		}
+		for id := range selected {
+			if !exists[id] {
+				delete(selected, id)
+			}
+		}
+		sort.Strings(owners[1:])
+		ownerSelect.Options = owners
+		if !containsText(owners, ownerSelect.Selected) {
+			ownerSelect.SetSelected(allOwners)
+		}
+		apply()
+	}
+	list.toggle = func(row int) {
+		id := shown[row].ID
+		if selected[id] {
+			delete(selected, id)
+		} else {
+			selected[id] = true
+		}
+		apply()
+	}
+	list.sorted = apply
+	widths := []float32{60, 260, 110, 110, 90, 130, 130}
+	if !hasStatus(kind) {
+		widths = []float32{60, 380, 130, 140, 140}
+	}
+	table = list.build(widths)
+
+	// chosen returns the selected records in the order they are shown, with
+	// any hidden by the filters after them
+	chosen := func() []interfaces.RecordSummary {
+		var picked []interfaces.RecordSummary
+		for _, record := range shown {
+			if selected[record.ID] {
+				picked = append(picked, record)
+			}
+		}
+		for _, record := range records {
+			if selected[record.ID] && !containsRecord(picked, record.ID) {
+				picked = append(picked, record)
+			}
+		}
+		return picked
+	}
+
+	selectShownButton := widget.NewButton("Select Shown", func() {
+		for _, record := range shown {
+			selected[record.ID] = true
+		}
+		apply()
+	})
+	clearButton := widget.NewButton("Clear Selection", func() {
+		for id := range selected {
+			delete(selected, id)
+		}
+		apply()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		picked := chosen()
+		if len(picked) == 0 {
+			dialog.ShowError(fmt.Errorf("select the %s to delete", recordNoun(kind, 2)), window)
+			return
+		}
+		lines := make([]string, len(picked))
+		for i, record := range picked {
+			lines[i] = fmt.Sprintf("%s: deleted", recordLine(record))
+		}
+		action := fmt.Sprintf("delete %d %s", len(picked), recordNoun(kind, len(picked)))
+		confirmRecordChanges(window, "Delete", "These will be deleted. This cannot be undone.", lines, func() {
+			ConfirmIdentity(window, action, func() {
+				deleted, err := admin.DeleteRecords(state.GlobalState.DB, kind, recordIDList(picked))
+				finishRecordChanges(window, err, fmt.Sprintf("Deleted %d %s.", deleted, recordNoun(kind, deleted)), reload)
+			})
+		})
+	})
+	reassignButton := widget.NewButton("Reassign", func() {
+		picked := chosen()
+		if len(picked) == 0 {
+			dialog.ShowError(fmt.Errorf("select the %s to reassign", recordNoun(kind, 2)), window)
+			return
+		}
+		chooseActiveUser(window, "Reassign To", func(to string) {
+			field := "assignee"
+			if interfaces.ReassignsOwner(kind) {
+				field = "owner"
+			}
+			lines := make([]string, len(picked))
+			for i, record := range picked {
+				from := record.Assignee
+				if interfaces.ReassignsOwner(kind) {
+					from = record.Owner
+				}
+				if from == "" {
+					from = "nobody"
+				}
+				lines[i] = fmt.Sprintf("%s: %s %s -> %s", recordLine(record), field, from, to)
+			}
+			action := fmt.Sprintf("reassign %d %s to %s", len(picked), recordNoun(kind, len(picked)), to)
+			confirmRecordChanges(window, "Reassign", fmt.Sprintf("The %s of these will change.", field), lines, func() {
+				ConfirmIdentity(window, action, func() {
+					changed, err := admin.ReassignRecords(state.GlobalState.DB, kind, recordIDList(picked), to)
+					finishRecordChanges(window, err, fmt.Sprintf("Reassigned %d %s to %s.", changed, recordNoun(kind, changed), to), reload)
+				})
+			})
+		})
+	})
+	completeButton := widget.NewButton("Mark Complete", func() {
+		picked := chosen()
+		if len(picked) == 0 {
+			dialog.ShowError(fmt.Errorf("select the %s to mark complete", recordNoun(kind, 2)), window)
+			return
+		}
+		lines := make([]string, len(picked))
+		for i, record := range picked {
+			lines[i] = fmt.Sprintf("%s: open -> completed", recordLine(record))
+			if record.Completed {
+				lines[i] = fmt.Sprintf("%s: already completed, unchanged", recordLine(record))
+			}
+		}
+		confirmRecordChanges(window, "Mark Complete", "These will be marked complete.", lines, func() {
+			completed, err := admin.CompleteRecords(state.GlobalState.DB, kind, recordIDList(picked))
+			finishRecordChanges(window, err, fmt.Sprintf("Marked %d %s complete.", completed, recordNoun(kind, completed)), reload)
+		})
+	})
+
+	searchEntry.OnChanged = func(string) { apply() }
+	ownerSelect.OnChanged = func(string) { apply() }
+	statusSelect.OnChanged = func(string) { apply() }
+	reload()
+
+	filters := container.NewBorder(nil, nil, nil, ownerSelect, searchEntry)
+	actions := container.NewHBox(selectShownButton, clearButton, deleteButton, reassignButton)
+	if hasStatus(kind) {
+		filters = container.NewBorder(nil, nil, nil, container.NewHBox(ownerSelect, statusSelect), searchEntry)
+	}
+	if interfaces.Completable(kind) {
+		actions.Add(completeButton)
+	}
+	actions.Add(countLabel)
+	return container.NewBorder(filters, actions, nil, nil, table)
+}
+
+// usersBrowserTab lists every user with the same selection as the record
+// tabs. Tapping Details shows the one selected user.
+func usersBrowserTab(window fyne.Window) fyne.CanvasObject {
+	var users, shown []interfaces.Users
+	selected := make(map[string]bool)
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by username or name")
+	statusSelect := widget.NewSelect([]string{filterAll, interfaces.UserActive, interfaces.UserInactive}, nil)
+	statusSelect.SetSelected(filterAll)
+	countLabel := widget.NewLabel("")
+
+	list := &selectTable{
+		columns:  userColumns,
+		rows:     func() int { return len(shown) },
+		cell:     func(row int, column string) string { return userCell(shown[row], columnIndex(userColumns, column)) },
+		selected: func(row int) bool { return selected[shown[row].Username] },
+	}
+	var table *widget.Table
+	apply := func() {
+		filter := strings.ToLower(strings.TrimSpace(filterEntry.Text))
+		shown = shown[:0]
+		for _, user := range users {
+			if filter != "" && !strings.Contains(strings.ToLower(user.Username), filter) &&
+				!strings.Contains(strings.ToLower(user.DisplayName), filter) {
+				continue
+			}
+			if statusSelect.Selected != filterAll && (user.Status == interfaces.UserInactive) != (statusSelect.Selected == interfaces.UserInactive) {
+				continue
+			}
+			shown = append(shown, user)
+		}
+		sortUsers(shown, list.sortColumn, list.descending)
+		countLabel.SetText(fmt.Sprintf("%d of %d shown, %d selected", len(shown), len(users), len(selected)))
+		table.Refresh()
+	}
+	reload := func() {
+		all, err := state.GlobalState.DB.GetAll()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		users = all
+		for username := range selected {
+			if !containsUser(all, username) {
+				delete(selected, username)
+			}
+		}
+		apply()
+	}
+	list.toggle = func(row int) {
+		username := shown[row].Username
+		if selected[username] {
+			delete(selected, username)
+		} else {
+			selected[username] = true
+		}
+		apply()
+	}
+	list.sorted = apply
+	table = list.build([]float32{140, 180, 90, 110, 80, 150})
+
+	chosen := func() []interfaces.Users {
+		var picked []interfaces.Users
+		for _, user := range users {
+			if selected[user.Username] {
+				picked = append(picked, user)
+			}
+		}
+		return picked
+	}
+	// each runs change on every chosen user and reports what failed
+	each := func(verb string, change func(interfaces.Users) error) func() {
+		return func() {
+			picked := chosen()
+			if len(picked) == 0 {
+				dialog.ShowError(fmt.Errorf("select the users to %s", verb), window)
+				return
+			}
+			lines := make([]string, len(picked))
+			for i, user := range picked {
+				lines[i] = userChangeLine(user, verb)
+			}
+			confirmRecordChanges(window, capitalize(verb), fmt.Sprintf("These users will be %s.", pastTense(verb)), lines, func() {
+				ConfirmIdentity(window, fmt.Sprintf("%s %d users", verb, len(picked)), func() {
+					var failed []string
+					for _, user := range picked {
+						if err := change(user); err != nil {
+							failed = append(failed, err.Error())
+						}
+					}
+					var err error
+					if len(failed) > 0 {
+						err = errors.New(strings.Join(failed, "\n"))
+					}
+					finishRecordChanges(window, err, fmt.Sprintf("%s %d users.", capitalize(pastTense(verb)), len(picked)), reload)
+				})
+			})
+		}
+	}
+
+	detailsButton := widget.NewButton("Details", func() {
+		picked := chosen()
+		if len(picked) != 1 {
+			dialog.ShowError(fmt.Errorf("select one user to see their details"), window)
+			return
+		}
+		showUserDialog(window, picked[0], reload)
+	})
+	selectShownButton := widget.NewButton("Select Shown", func() {
+		for _, user := range shown {
+			selected[user.Username] = true
+		}
+		apply()
+	})
+	clearButton := widget.NewButton("Clear Selection", func() {
+		for username := range selected {
+			delete(selected, username)
+		}
+		apply()
+	})
+	activateButton := widget.NewButton("Activate", each("activate", func(user interfaces.Users) error {
+		_, err := admin.SetUserStatus(state.GlobalState.DB, user, interfaces.UserActive)
+		return err
+	}))
+	deactivateButton := widget.NewButton("Deactivate", each("deactivate", func(user interfaces.Users) error {
+		_, err := admin.SetUserStatus(state.GlobalState.DB, user, interfaces.UserInactive)
+		return err
+	}))
+	deleteButton := widget.NewButton("Delete", each("delete", func(user interfaces.Users) error {
+		return admin.DeleteUser(state.GlobalState.DB, user)
+	}))
+	transferButton := widget.NewButton("Transfer Records", func() {
+		picked := chosen()
+		if len(picked) == 0 {
+			dialog.ShowError(fmt.Errorf("select the users whose records to transfer"), window)
+			return
+		}
+		chooseActiveUser(window, "Transfer To", func(to string) {
+			lines := make([]string, len(picked))
+			for i, user := range picked {
+				records, err := state.GlobalState.DB.GetUserRecords(user.Username)
+				lines[i] = fmt.Sprintf("%s: %s -> %s", user.Username, records, to)
+				if err != nil {
+					lines[i] = fmt.Sprintf("%s: %v", user.Username, err)
+				} else if user.Username == to {
+					lines[i] = fmt.Sprintf("%s: unchanged, already the recipient", user.Username)
+				}
+			}
+			confirmRecordChanges(window, "Transfer Records", "Everything these users own or are assigned, apart from credentials, will move.", lines, func() {
+				ConfirmIdentity(window, fmt.Sprintf("transfer the records of %d users to %s", len(picked), to), func() {
+					var failed []string
+					for _, user := range picked {
+						if user.Username == to {
+							continue
+						}
+						if _, err := admin.TransferUserRecords(state.GlobalState.DB, user.Username, to); err != nil {
+							failed = append(failed, err.Error())
+						}
+					}
+					var err error
+					if len(failed) > 0 {
+						err = errors.New(strings.Join(failed, "\n"))
+					}
+					finishRecordChanges(window, err, fmt.Sprintf("Transferred the records of %d users to %s.", len(picked), to), reload)
+				})
+			})
+		})
+	})
+
+	filterEntry.OnChanged = func(string) { apply() }
+	statusSelect.OnChanged = func(string) { apply() }
+	reload()
+
+	filters := container.NewBorder(nil, nil, nil, statusSelect, filterEntry)
+	actions := container.NewHBox(selectShownButton, clearButton, detailsButton, activateButton, deactivateButton,
+		transferButton, deleteButton, countLabel)
+	return container.NewBorder(filters, actions, nil, nil, table)
+}
+
+// confirmRecordChanges lists exactly what a bulk action will change and
+// runs proceed once the administrator agrees
+func confirmRecordChanges(window fyne.Window, title, summary string, lines []string, proceed func()) {
+	summaryLabel := widget.NewLabel(summary)
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	changes := widget.NewLabel(strings.Join(lines, "\n"))
+	content := container.NewBorder(summaryLabel, nil, nil, nil, container.NewVScroll(changes))
+	confirm := dialog.NewCustomConfirm(title, title, "Cancel", content, func(ok bool) {
+		if ok {
+			proceed()
+		}
+	}, window)
+	confirm.Resize(fyne.NewSize(600, 400))
+	confirm.Show()
+}
+
+// finishRecordChanges reports the outcome of a bulk action and reloads the
+// tab, which also shows any part that did succeed
+func finishRecordChanges(window fyne.Window, err error, done string, reload func()) {
+	reload()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	dialog.ShowInformation("Records", done, window)
+}
+
+// chooseActiveUser asks for an active user to give records to
+func chooseActiveUser(window fyne.Window, label string, chosen func(username string)) {
+	users, err := state.GlobalState.DB.GetAll()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	var targets []string
+	for _, user := range users {
+		if user.Status != interfaces.UserInactive {
+			targets = append(targets, user.Username)
+		}
+	}
+	sort.Strings(targets)
+	targetSelect := widget.NewSelect(targets, nil)
+	items := []*widget.FormItem{widget.NewFormItem(label, targetSelect)}
+	chooseDialog := dialog.NewForm(label, "Next", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if targetSelect.Selected == "" {
+			dialog.ShowError(fmt.Errorf("choose a user"), window)
+			return
+		}
+		chosen(targetSelect.Selected)
+	}, window)
+	chooseDialog.Resize(fyne.NewSize(400, 180))
+	chooseDialog.Show()
+}
+
+// recordLine names a record in a confirmation summary
+func recordLine(record interfaces.RecordSummary) string {
+	return fmt.Sprintf("#%d %s (%s)", record.ID, record.Title, record.Owner)
+}
+
+// userChangeLine names a user in a confirmation summary, with what they
+// still own
+func userChangeLine(user interfaces.Users, verb string) string {
+	line := fmt.Sprintf("%s (%s): %s", user.Username, user.Status, verb)
+	switch {
+	case user.Username == state.GlobalState.Username && verb != "activate":
+		line += ", refused for your own account"
+	case verb == "delete":
+		if records, err := state.GlobalState.DB.GetUserRecords(user.Username); err == nil {
+			line += fmt.Sprintf(", has %s", records)
+			if records.Total() > 0 {
+				line += ", refused until they are transferred"
+			}
+		}
+	}
+	return line
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+func pastTense(verb string) string {
+	if strings.HasSuffix(verb, "e") {
+		return verb + "d"
+	}
+	return verb + "ed"
+}
+
+func recordKindName(kind string) string {
+	switch kind {
+	case interfaces.RecordCRM:
+		return "CRM"
+	case interfaces.RecordNotes:
+		return "Notes"
+	case interfaces.RecordTasks:
+		return "Tasks"
+	case interfaces.RecordAudits:
+		return "Audits"
+	}
+	return kind
+}
+
+// recordNoun is kind in the singular or plural, to go after n
+func recordNoun(kind string, n int) string {
+	noun := strings.TrimSuffix(kind, "s")
+	if kind == interfaces.RecordCRM {
+		noun = "CRM entry"
+		if n != 1 {
+			return "CRM entries"
+		}
+	}
+	if n != 1 && kind != interfaces.RecordCRM {
+		noun += "s"
+	}
+	return noun
+}
+
+func recordIDList(records []interfaces.RecordSummary) []int {
+	ids := make([]int, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	return ids
+}
+
+func containsRecord(records []interfaces.RecordSummary, id int) bool {
+	for _, record := range records {
+		if record.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUser(users []interfaces.Users, username string) bool {
+	for _, user := range users {
+		if user.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+func containsText(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func columnIndex(columns []string, column string) int {
+	for i, c := range columns {
+		if c == column {
+			return i
+		}
+	}
+	return -1
+}
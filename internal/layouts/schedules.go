@@ -0,0 +1,484 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	audits "github.com/j4m1n-t/goAudit/internal/audits"
+	"github.com/j4m1n-t/goAudit/internal/config"
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/recurrence"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// recurrencePresets are the rules offered by the schedule editor. Any other
+// RRULE can be entered as a custom rule.
+var recurrencePresets = []struct{ label, rule string }{
+	{"Monthly", "FREQ=MONTHLY"},
+	{"Quarterly", "FREQ=MONTHLY;INTERVAL=3"},
+	{"Half-yearly", "FREQ=MONTHLY;INTERVAL=6"},
+	{"Annually", "FREQ=YEARLY"},
+}
+
+const customRecurrence = "Custom"
+
+// How many of a schedule's next dates the editor previews
+const schedulePreviewDates = 5
+
+// recurrenceLabel names rule after its preset, if it has one
+func recurrenceLabel(rule string) string {
+	for _, preset := range recurrencePresets {
+		if preset.rule == rule {
+			return preset.label
+		}
+	}
+	return rule
+}
+
+// upcomingDates lists up to count dates rule falls on from start, beginning today
+func upcomingDates(rule recurrence.Rule, start time.Time, count int) []time.Time {
+	var dates []time.Time
+	after := time.Now().AddDate(0, 0, -1)
+	for len(dates) < count {
+		next, ok := rule.Next(start, after)
+		if !ok {
+			break
+		}
+		dates = append(dates, next)
+		after = next
+	}
+	return dates
+}
+
+// nextScheduled describes the next date schedule falls on
+func nextScheduled(schedule interfaces.AuditSchedule) string {
+	if schedule.Paused {
+		return "Paused"
+	}
+	rule, err := schedule.Rule()
+	if err != nil {
+		return "Invalid recurrence"
+	}
+	dates := upcomingDates(rule, schedule.StartDate, 1)
+	if len(dates) == 0 {
+		return "Finished"
+	}
+	return "Next " + dates[0].Format("2006-01-02")
+}
+
+// showSchedulesDialog lists the recurring audit schedules for editing
+func showSchedulesDialog(window fyne.Window) {
+	var schedules []interfaces.AuditSchedule
+	message := widget.NewLabel("")
+
+	list := widget.NewList(
+		func() int { return len(schedules) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabelWithStyle("Name", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabel("Recurrence"),
+				widget.NewLabel("Firm"),
+				widget.NewLabel("Next"),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id < len(schedules) {
+				row := item.(*fyne.Container).Objects
+				row[0].(*widget.Label).SetText(schedules[id].Name)
+				row[1].(*widget.Label).SetText(recurrenceLabel(schedules[id].Recurrence))
+				row[2].(*widget.Label).SetText(firmLabel(schedules[id].Firm))
+				row[3].(*widget.Label).SetText(nextScheduled(schedules[id]))
+			}
+		},
+	)
+	reload := func() {
+		loaded, err := state.GlobalState.DB.GetSchedules()
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		schedules = loaded
+		message.SetText(fmt.Sprintf("%d schedules", len(schedules)))
+		list.Refresh()
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+		if id < len(schedules) {
+			schedule := schedules[id]
+			showScheduleDialog(window, &schedule, reload)
+		}
+	}
+	reload()
+
+	newButton := widget.NewButton("New Schedule", func() {
+		showScheduleDialog(window, nil, reload)
+	})
+	schedulesDialog := dialog.NewCustom("Audit Schedules", "Close",
+		container.NewBorder(container.NewBorder(nil, nil, nil, newButton, message), nil, nil, nil, list), window)
+	schedulesDialog.Resize(fyne.NewSize(650, 450))
+	schedulesDialog.Show()
+}
+
+// createDueAudits creates the audits that are now due, so a saved schedule
+// shows up straight away rather than on the scheduler's next run
+func createDueAudits(window fyne.Window) {
+	if config.Current().Schedules.Disabled {
+		return
+	}
+	created, err := audits.CreateScheduledAudits(state.GlobalState.DB, time.Now())
+	if err != nil {
+		log.Printf("Error creating scheduled audits: %v", err)
+	}
+	if len(created) > 0 {
+		refreshAudits(window)
+	}
+}
+
+// showScheduleDialog adds a schedule, or edits schedule. changed is called
+// after every save so the list can reload.
+func showScheduleDialog(window fyne.Window, schedule *interfaces.AuditSchedule, changed func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Enter schedule name")
+
+	recurrenceEntry := widget.NewEntry()
+	recurrenceEntry.SetPlaceHolder("RRULE, e.g. FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1")
+
+	presets := make([]string, 0, len(recurrencePresets)+1)
+	for _, preset := range recurrencePresets {
+		presets = append(presets, preset.label)
+	}
+	presetSelect := widget.NewSelect(append(presets, customRecurrence), func(label string) {
+		for _, preset := range recurrencePresets {
+			if preset.label == label {
+				recurrenceEntry.SetText(preset.rule)
+			}
+		}
+	})
+
+	startEntry := widget.NewEntry()
+	startEntry.SetPlaceHolder("Enter first date (YYYY-MM-DD)")
+
+	leadDaysEntry := widget.NewEntry()
+	leadDaysEntry.SetPlaceHolder(fmt.Sprintf("Days ahead to create each audit (0-%d)", interfaces.MaxLeadDays))
+
+	firmEntry := widget.NewEntry()
+	firmEntry.SetPlaceHolder("Enter firm")
+
+	auditTypeEntry := widget.NewEntry()
+	auditTypeEntry.SetPlaceHolder("Enter audit type")
+
+	auditAreaEntry := widget.NewEntry()
+	auditAreaEntry.SetPlaceHolder("Enter audit area")
+
+	// Choosing a template fills in the type and area, as for a new audit
+	templates, _ := state.GlobalState.DB.GetTemplates()
+	templateChoices := []string{"None"}
+	for _, template := range templates {
+		templateChoices = append(templateChoices, template.Name)
+	}
+	templateSelect := widget.NewSelect(templateChoices, nil)
+	selectTemplate := func(id int) {
+		templateSelect.SetSelectedIndex(0)
+		for i, template := range templates {
+			if template.ID == id {
+				templateSelect.SetSelectedIndex(i + 1)
+			}
+		}
+	}
+
+	assigneeEntry := widget.NewSelectEntry(assigneeChoices())
+	assigneeEntry.SetPlaceHolder("Unassigned")
+
+	pausedCheck := widget.NewCheck("Paused", nil)
+
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+	showPreview := func(string) {
+		rule, err := recurrence.Parse(recurrenceEntry.Text)
+		if err != nil {
+			preview.SetText(err.Error())
+			return
+		}
+		start, err := time.Parse("2006-01-02", strings.TrimSpace(startEntry.Text))
+		if err != nil {
+			preview.SetText("Enter the first date as YYYY-MM-DD")
+			return
+		}
+		dates := upcomingDates(rule, start, schedulePreviewDates)
+		if len(dates) == 0 {
+			preview.SetText("No upcoming dates")
+			return
+		}
+		labels := make([]string, len(dates))
+		for i, date := range dates {
+			labels[i] = date.Format("Mon 2 Jan 2006")
+		}
+		preview.SetText(strings.Join(labels, "\n"))
+	}
+	recurrenceEntry.OnChanged = showPreview
+	startEntry.OnChanged = showPreview
+
+	fillForm := func(s interfaces.AuditSchedule) {
+		nameEntry.SetText(s.Name)
+		if label := recurrenceLabel(s.Recurrence); label != s.Recurrence {
+			presetSelect.SetSelected(label)
+		} else {
+			presetSelect.SetSelected(customRecurrence)
+		}
+		recurrenceEntry.SetText(s.Recurrence)
+		startEntry.SetText(s.StartDate.Format("2006-01-02"))
+		leadDaysEntry.SetText(strconv.Itoa(s.LeadDays))
+		firmEntry.SetText(s.Firm)
+		auditTypeEntry.SetText(s.AuditType)
+		auditAreaEntry.SetText(s.AuditArea)
+		selectTemplate(s.TemplateID)
+		assigneeEntry.SetText(s.Assignee)
+		pausedCheck.SetChecked(s.Paused)
+	}
+	if schedule != nil {
+		fillForm(*schedule)
+	} else {
+		presetSelect.SetSelected(recurrencePresets[0].label)
+		startEntry.SetText(time.Now().Format("2006-01-02"))
+		leadDaysEntry.SetText(strconv.Itoa(interfaces.DefaultLeadDays))
+		templateSelect.SetSelectedIndex(0)
+	}
+	templateSelect.OnChanged = func(string) {
+		if index := templateSelect.SelectedIndex(); index > 0 {
+			auditTypeEntry.SetText(templates[index-1].AuditType)
+			auditAreaEntry.SetText(templates[index-1].AuditArea)
+		}
+	}
+
+	stored := func(s interfaces.AuditSchedule) {
+		if schedule != nil {
+			*schedule = s
+		}
+		changed()
+		createDueAudits(window)
+	}
+
+	var scheduleDialog dialog.Dialog
+	var save func(interfaces.AuditSchedule)
+	save = func(edited interfaces.AuditSchedule) {
+		var saved interfaces.AuditSchedule
+		var err error
+		if schedule == nil {
+			saved, err = state.GlobalState.DB.CreateSchedule(edited)
+		} else {
+			saved, err = state.GlobalState.DB.UpdateSchedule(edited)
+		}
+		var conflict *interfaces.ConflictError
+		if errors.As(err, &conflict) {
+			current, ok := conflict.Current.(interfaces.AuditSchedule)
+			if !ok {
+				dialog.ShowError(err, window)
+				return
+			}
+			showConflictDialog(window, conflict, scheduleConflictFields(edited), scheduleConflictFields(current),
+				func() {
+					edited.Version = current.Version
+					save(edited)
+				},
+				func() {
+					fillForm(current)
+					stored(current)
+				})
+			return
+		}
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if schedule == nil {
+			scheduleDialog.Hide()
+		}
+		stored(saved)
+		dialog.ShowInformation("Success", "Schedule saved successfully", window)
+	}
+
+	saveButton := widget.NewButton("Save", func() {
+		edited := interfaces.AuditSchedule{}
+		if schedule != nil {
+			edited = *schedule
+		}
+		rule, err := recurrence.Parse(recurrenceEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		start, err := time.Parse("2006-01-02", strings.TrimSpace(startEntry.Text))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid start date format. Please use YYYY-MM-DD"), window)
+			return
+		}
+		leadDays, err := strconv.Atoi(strings.TrimSpace(leadDaysEntry.Text))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("lead days must be a whole number"), window)
+			return
+		}
+		edited.Name = strings.TrimSpace(nameEntry.Text)
+		edited.Recurrence = rule.String()
+		edited.StartDate = start
+		edited.LeadDays = leadDays
+		edited.Firm = strings.TrimSpace(firmEntry.Text)
+		edited.AuditType = strings.TrimSpace(auditTypeEntry.Text)
+		edited.AuditArea = strings.TrimSpace(auditAreaEntry.Text)
+		edited.TemplateID = 0
+		if index := templateSelect.SelectedIndex(); index > 0 {
+			edited.TemplateID = templates[index-1].ID
+		}
+		edited.Assignee = strings.TrimSpace(assigneeEntry.Text)
+		edited.Paused = pausedCheck.Checked
+		save(edited)
+	})
+
+	buttons := container.NewHBox(saveButton)
+	if schedule != nil {
+		buttons.Add(widget.NewButton("Delete", func() {
+			dialog.ShowConfirm("Confirm Delete",
+				fmt.Sprintf("Delete the schedule %q? Audits it has already created are kept.", schedule.Name),
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					ConfirmIdentity(window, "delete this schedule", func() {
+						if err := state.GlobalState.DB.DeleteSchedule(schedule.ID); err != nil {
+							dialog.ShowError(err, window)
+							return
+						}
+						scheduleDialog.Hide()
+						changed()
+					})
+				}, window)
+		}))
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Repeats", presetSelect),
+		widget.NewFormItem("Rule", recurrenceEntry),
+		widget.NewFormItem("Start Date", startEntry),
+		widget.NewFormItem("Lead Days", leadDaysEntry),
+		widget.NewFormItem("Firm", firmEntry),
+		widget.NewFormItem("Template", templateSelect),
+		widget.NewFormItem("Audit Type", auditTypeEntry),
+		widget.NewFormItem("Audit Area", auditAreaEntry),
+		widget.NewFormItem("Assignee", assigneeEntry),
+		widget.NewFormItem("", pausedCheck),
+	)
+	content := container.NewVBox(
+		form,
+		widget.NewLabelWithStyle("Next dates", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		preview,
+		buttons,
+	)
+	title := "New Schedule"
+	if schedule != nil {
+		title = "Schedule: " + schedule.Name
+	}
+	scheduleDialog = dialog.NewCustom(title, "Close", container.NewVScroll(content), window)
+	scheduleDialog.Resize(fyne.NewSize(600, 650))
+	scheduleDialog.Show()
+}
+
+// Ranges the calendar can show, in months
+var calendarRanges = []struct {
+	label  string
+	months int
+}{
+	{"3 months", 3},
+	{"6 months", 6},
+	{"12 months", 12},
+}
+
+// showCalendarDialog lists the audits schedules have created or will
+// create, by month. Created audits can be opened.
+func showCalendarDialog(window fyne.Window) {
+	message := widget.NewLabel("")
+	message.Wrapping = fyne.TextWrapWord
+	entriesBox := container.NewVBox()
+
+	var calendarDialog dialog.Dialog
+	showRange := func(months int) {
+		now := time.Now()
+		entries, err := audits.AuditCalendar(state.GlobalState.DB, now, now.AddDate(0, months, 0), now)
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		entriesBox.RemoveAll()
+		if len(entries) == 0 {
+			message.SetText("No scheduled audits in this period")
+			entriesBox.Refresh()
+			return
+		}
+		message.SetText(fmt.Sprintf("%d scheduled audits", len(entries)))
+		month := ""
+		for _, entry := range entries {
+			if heading := entry.Date.Format("January 2006"); heading != month {
+				month = heading
+				entriesBox.Add(widget.NewLabelWithStyle(month, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			}
+			entriesBox.Add(calendarRow(window, entry, func() { calendarDialog.Hide() }))
+		}
+		entriesBox.Refresh()
+	}
+
+	labels := make([]string, len(calendarRanges))
+	for i, option := range calendarRanges {
+		labels[i] = option.label
+	}
+	rangeSelect := widget.NewSelect(labels, nil)
+	rangeSelect.OnChanged = func(string) {
+		if index := rangeSelect.SelectedIndex(); index >= 0 {
+			showRange(calendarRanges[index].months)
+		}
+	}
+	rangeSelect.SetSelectedIndex(0)
+
+	calendarDialog = dialog.NewCustom("Audit Calendar", "Close",
+		container.NewBorder(container.NewVBox(rangeSelect, message), nil, nil, nil, container.NewVScroll(entriesBox)), window)
+	calendarDialog.Resize(fyne.NewSize(750, 550))
+	calendarDialog.Show()
+}
+
+// calendarRow shows one calendar entry. opened is called before a created
+// audit is opened.
+func calendarRow(window fyne.Window, entry audits.CalendarEntry, opened func()) fyne.CanvasObject {
+	name, firm, assignee, status := entry.Schedule.Name, entry.Schedule.Firm, entry.Schedule.Assignee, "To be created"
+	if entry.Audit != nil {
+		name, firm, assignee = entry.Audit.Action, entry.Audit.Firm, entry.Audit.AssignedUser
+		status = audits.Workflow().State(*entry.Audit)
+	}
+	if assignee == "" {
+		assignee = "Unassigned"
+	}
+	row := container.NewHBox(
+		widget.NewLabel(entry.Date.Format("Mon 2 Jan")),
+		widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel(firmLabel(firm)),
+		widget.NewLabel(assignee),
+		widget.NewLabel(status),
+	)
+	if entry.Audit != nil {
+		audit := *entry.Audit
+		row.Add(widget.NewButton("Open", func() {
+			opened()
+			showAuditDialog(window, &audit)
+		}))
+	}
+	return row
+}
@@ -0,0 +1,87 @@
+package layouts
+
+import (
+	// Standard Library
+	"fmt"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// How many change history entries the Admin tab shows
+const changeHistoryLimit = 200
+
+// showLoginAttemptsDialog lists the usernames and hosts with failed logins
+// and lets an administrator unlock them
+func showLoginAttemptsDialog(window fyne.Window) {
+	list, err := state.GlobalState.DB.ListLoginAttempts()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	var attemptsDialog dialog.Dialog
+	rows := container.NewVBox()
+	if len(list) == 0 {
+		rows.Add(widget.NewLabel("No failed logins."))
+	}
+	for _, attempts := range list {
+		status := ""
+		if auth.LockedOut(attempts) {
+			status = ", locked"
+		}
+		label := widget.NewLabel(fmt.Sprintf("%s %s: %d failed, last %s%s", attempts.Scope, attempts.Name,
+			attempts.Failures, attempts.LastFailure.Local().Format("2006-01-02 15:04:05"), status))
+		unlockButton := widget.NewButton("Unlock", func() {
+			if err := auth.UnlockLogin(state.GlobalState.DB, attempts); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			attemptsDialog.Hide()
+			showLoginAttemptsDialog(window)
+		})
+		rows.Add(container.NewBorder(nil, nil, nil, unlockButton, label))
+	}
+
+	attemptsDialog = dialog.NewCustom("Failed Logins", "Close", container.NewVScroll(rows), window)
+	attemptsDialog.Resize(fyne.NewSize(600, 400))
+	attemptsDialog.Show()
+}
+
+// showChangeHistoryDialog shows the latest change history entries, newest
+// first
+func showChangeHistoryDialog(window fyne.Window) {
+	entries, err := state.GlobalState.DB.GetChangeHistory(changeHistoryLimit)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	historyList := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(formatChangeHistory(entries[i]))
+		},
+	)
+	historyDialog := dialog.NewCustom("Change History", "Close", historyList, window)
+	historyDialog.Resize(fyne.NewSize(800, 500))
+	historyDialog.Show()
+}
+
+func formatChangeHistory(entry interfaces.ChangeHistory) string {
+	actor := entry.Actor
+	if actor == "" {
+		actor = "-"
+	}
+	return fmt.Sprintf("%s  %s@%s  %s %s: %s", entry.At.Local().Format("2006-01-02 15:04:05"),
+		actor, entry.Host, entry.Action, entry.Subject, entry.Detail)
+}
@@ -0,0 +1,145 @@
+package layouts
+
+import (
+	// Standard Library
+	"context"
+	"errors"
+	"fmt"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// Reauthenticator checks again who the logged in user is. Password is set
+// for users who log in with a password, SSO for those who sign in through
+// the identity provider.
+type Reauthenticator struct {
+	Password func(password string) error
+	SSO      func(ctx context.Context) error
+}
+
+var reauthenticator Reauthenticator
+
+// SetReauthenticator sets how ConfirmIdentity checks the user of the session
+// that has just started
+func SetReauthenticator(r Reauthenticator) {
+	reauthenticator = r
+}
+
+// ConfirmIdentity runs proceed once the user has proved who they are. A
+// login or re-authentication within the session's re-authentication window
+// is enough; otherwise they are asked for their password, or to sign in
+// again. Every action that deletes, deactivates or transfers stored records
+// goes through it, as the store refuses them outside the window; removing
+// rows from a form that has not been saved yet does not.
+func ConfirmIdentity(window fyne.Window, action string, proceed func()) {
+	session := state.GlobalState.Session
+	if session == nil {
+		dialog.ShowError(fmt.Errorf("log in to %s", action), window)
+		return
+	}
+	if session.Confirmed() == nil {
+		proceed()
+		return
+	}
+	verified := func() {
+		session.Reauthenticated()
+		proceed()
+	}
+	switch {
+	case reauthenticator.Password != nil:
+		showPasswordReauthentication(window, action, verified)
+	case reauthenticator.SSO != nil:
+		showSSOReauthentication(window, action, verified)
+	default:
+		dialog.ShowError(fmt.Errorf("cannot check who you are; log in again to %s", action), window)
+	}
+}
+
+func showPasswordReauthentication(window fyne.Window, action string, verified func()) {
+	passwordEntry := widget.NewPasswordEntry()
+	label := widget.NewLabel(fmt.Sprintf("Enter your password again to %s.", action))
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Password", passwordEntry),
+	}
+
+	passwordDialog := dialog.NewForm("Confirm It's You", "Continue", "Cancel", items, func(confirm bool) {
+		password := passwordEntry.Text
+		passwordEntry.SetText("")
+		if !confirm {
+			return
+		}
+		if err := reauthenticator.Password(password); err != nil {
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { showPasswordReauthentication(window, action, verified) })
+			errorDialog.Show()
+			return
+		}
+		verified()
+	}, window)
+	passwordDialog.Resize(fyne.NewSize(400, 200))
+	passwordDialog.Show()
+	window.Canvas().Focus(passwordEntry)
+}
+
+func showSSOReauthentication(window fyne.Window, action string, verified func()) {
+	dialog.ShowConfirm("Confirm It's You", fmt.Sprintf("Sign in again in your browser to %s.", action), func(confirm bool) {
+		if !confirm {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		waiting := dialog.NewCustom("Confirm It's You", "Cancel",
+			widget.NewLabel("Finish signing in in your browser."), window)
+		waiting.SetOnClosed(cancel)
+		waiting.Show()
+		go func() {
+			err := reauthenticator.SSO(ctx)
+			waiting.Hide()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					dialog.ShowError(err, window)
+				}
+				return
+			}
+			verified()
+		}()
+	}, window)
+}
+
+// TrackActivity lays content over an area that notes mouse movement as
+// activity for the idle timeout, along with keys typed outside any field.
+// Typing in fields is noticed by the session watcher.
+func TrackActivity(window fyne.Window, content fyne.CanvasObject) fyne.CanvasObject {
+	touch := func() { state.GlobalState.CurrentSession().Touch() }
+	window.Canvas().SetOnTypedKey(func(*fyne.KeyEvent) { touch() })
+	window.Canvas().SetOnTypedRune(func(rune) { touch() })
+	area := &activityArea{touch: touch}
+	area.ExtendBaseWidget(area)
+	return container.NewStack(area, content)
+}
+
+// activityArea sits behind the workspace and sees the mouse wherever no
+// other widget handles it
+type activityArea struct {
+	widget.BaseWidget
+	touch func()
+}
+
+var _ desktop.Hoverable = (*activityArea)(nil)
+
+func (a *activityArea) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack())
+}
+
+func (a *activityArea) MouseIn(*desktop.MouseEvent)    { a.touch() }
+func (a *activityArea) MouseMoved(*desktop.MouseEvent) { a.touch() }
+func (a *activityArea) MouseOut()                      {}
@@ -2,8 +2,11 @@ package layouts
 
 import (
 	// Standard Library
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	// Fyne Imports
@@ -16,6 +19,7 @@ import (
 	// Internal Imports
 
 	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
 	state "github.com/j4m1n-t/goAudit/internal/status"
 )
 
@@ -41,6 +45,7 @@ func CreateTasksTabContent(window fyne.Window) fyne.CanvasObject {
 				widget.NewIcon(theme.DocumentIcon()),
 				widget.NewLabel("Task Title"),
 				widget.NewLabel("Due Date"),
+				widget.NewLabel("Assignee"),
 			)
 		},
 		func(id widget.ListItemID, item fyne.CanvasObject) {
@@ -48,6 +53,7 @@ func CreateTasksTabContent(window fyne.Window) fyne.CanvasObject {
 				task := state.GlobalState.Tasks[id]
 				item.(*fyne.Container).Objects[1].(*widget.Label).SetText(task.Title)
 				item.(*fyne.Container).Objects[2].(*widget.Label).SetText(task.DueDate.Format("2006-01-02"))
+				item.(*fyne.Container).Objects[3].(*widget.Label).SetText(task.Assignee)
 			}
 		},
 	)
@@ -69,6 +75,36 @@ func CreateTasksTabContent(window fyne.Window) fyne.CanvasObject {
 }
 
 func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
+	showTaskForm(window, task, "")
+}
+
+// showNewTaskDialog opens an empty task already assigned to assignee
+func showNewTaskDialog(window fyne.Window, assignee string) {
+	showTaskForm(window, nil, assignee)
+}
+
+// assigneeChoices are the people the user may assign tasks to: themself and
+// the active members of their reporting tree
+func assigneeChoices() []string {
+	choices := []string{state.GlobalState.Username}
+	session := state.GlobalState.CurrentSession()
+	if !session.Can(rbac.TasksAssign) {
+		return choices
+	}
+	reports, err := session.Reports()
+	if err != nil {
+		log.Printf("Error loading reporting tree: %v", err)
+		return choices
+	}
+	for _, report := range reports {
+		if !report.Disabled {
+			choices = append(choices, report.Username)
+		}
+	}
+	return choices
+}
+
+func showTaskForm(window fyne.Window, task *interfaces.Tasks, assignee string) {
 	var titleEntry *widget.Entry
 	var descriptionEntry *widget.Entry
 	var statusEntry *widget.Entry
@@ -94,13 +130,25 @@ func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
 
 	completedCheck = widget.NewCheck("Completed", nil)
 
+	assigneeEntry := widget.NewSelectEntry(assigneeChoices())
+	assigneeEntry.SetPlaceHolder("Unassigned")
+	assigneeEntry.SetText(assignee)
+	if !state.GlobalState.CurrentSession().Can(rbac.TasksAssign) {
+		assigneeEntry.Disable()
+	}
+
+	fillForm := func(t interfaces.Tasks) {
+		titleEntry.SetText(t.Title)
+		descriptionEntry.SetText(t.Description)
+		statusEntry.SetText(t.Status)
+		priorityEntry.SetText(fmt.Sprintf("%d", t.Priority))
+		dueDateEntry.SetText(t.DueDate.Format("2006-01-02"))
+		completedCheck.SetChecked(t.Completed)
+		assigneeEntry.SetText(t.Assignee)
+	}
+
 	if task != nil {
-		titleEntry.SetText(task.Title)
-		descriptionEntry.SetText(task.Description)
-		statusEntry.SetText(task.Status)
-		priorityEntry.SetText(fmt.Sprintf("%d", task.Priority))
-		dueDateEntry.SetText(task.DueDate.Format("2006-01-02"))
-		completedCheck.SetChecked(task.Completed)
+		fillForm(*task)
 	}
 
 	saveButton := widget.NewButton("Save", func() {
@@ -116,8 +164,9 @@ func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
 				DueDate:     dueDate,
 				Completed:   completedCheck.Checked,
 				Username:    state.GlobalState.Username,
+				Assignee:    strings.TrimSpace(assigneeEntry.Text),
 			}
-			_, err := dw.CreateTask(newTask)
+			_, err := state.GlobalState.DB.CreateTask(newTask)
 			if err != nil {
 				dialog.ShowError(err, window)
 				return
@@ -129,11 +178,13 @@ func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
 			task.Priority = priority
 			task.DueDate = dueDate
 			task.Completed = completedCheck.Checked
-			_, err := dw.UpdateTask(*task)
+			task.Assignee = strings.TrimSpace(assigneeEntry.Text)
+			updatedTask, err := state.GlobalState.DB.UpdateTask(*task)
 			if err != nil {
-				dialog.ShowError(err, window)
+				handleTaskUpdateError(window, task, err, fillForm)
 				return
 			}
+			*task = updatedTask
 		}
 
 		refreshTasks(window)
@@ -144,15 +195,18 @@ func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
 	if task != nil {
 		deleteButton := widget.NewButton("Delete", func() {
 			dialog.ShowConfirm("Confirm Delete", "Are you sure you want to delete this task?", func(confirm bool) {
-				if confirm {
-					err := dw.DeleteTask(task.ID, state.GlobalState.Username)
+				if !confirm {
+					return
+				}
+				ConfirmIdentity(window, "delete this task", func() {
+					err := state.GlobalState.DB.DeleteTask(task.ID, state.GlobalState.Username)
 					if err != nil {
 						dialog.ShowError(err, window)
 						return
 					}
 					refreshTasks(window)
 					dialog.ShowInformation("Success", "Task deleted successfully", window)
-				}
+				})
 			}, window)
 		})
 		buttons = container.NewHBox(saveButton, deleteButton)
@@ -171,13 +225,54 @@ func showTaskDialog(window fyne.Window, task *interfaces.Tasks) {
 		priorityEntry,
 		widget.NewLabel("Due Date"),
 		dueDateEntry,
+		widget.NewLabel("Assignee"),
+		assigneeEntry,
 		completedCheck,
 		buttons,
 	)
+	if task != nil && task.FindingID != 0 {
+		link := fmt.Sprintf("Remediates finding #%d", task.FindingID)
+		if finding, err := state.GlobalState.DB.GetFinding(task.FindingID); err == nil {
+			link = fmt.Sprintf("Remediates the %s finding %q", finding.Severity, finding.Title)
+		}
+		content.Add(widget.NewLabelWithStyle(link, fyne.TextAlignLeading, fyne.TextStyle{Italic: true}))
+	}
 
 	dialog.ShowCustom("Task Details", "Close", content, window)
 }
 
+// handleTaskUpdateError offers a merge when the task was changed by someone else
+func handleTaskUpdateError(window fyne.Window, task *interfaces.Tasks, err error, fillForm func(interfaces.Tasks)) {
+	var conflict *interfaces.ConflictError
+	if !errors.As(err, &conflict) {
+		dialog.ShowError(err, window)
+		return
+	}
+	current, ok := conflict.Current.(interfaces.Tasks)
+	if !ok {
+		dialog.ShowError(err, window)
+		return
+	}
+
+	showConflictDialog(window, conflict, taskConflictFields(*task), taskConflictFields(current),
+		func() {
+			task.Version = current.Version
+			updatedTask, err := state.GlobalState.DB.UpdateTask(*task)
+			if err != nil {
+				handleTaskUpdateError(window, task, err, fillForm)
+				return
+			}
+			*task = updatedTask
+			refreshTasks(window)
+			dialog.ShowInformation("Success", "Task saved successfully", window)
+		},
+		func() {
+			*task = current
+			fillForm(current)
+			refreshTasks(window)
+		})
+}
+
 func refreshTasks(window fyne.Window) {
 	if state.GlobalState.DB == nil {
 		dialog.ShowError(fmt.Errorf("database is not initialized"), window)
@@ -191,6 +286,9 @@ func refreshTasks(window fyne.Window) {
 	}
 	state.GlobalState.Tasks = tasks
 	tasksList.Refresh()
+	if err := refreshTeam(); err != nil {
+		log.Printf("Error refreshing team: %v", err)
+	}
 }
 
 func parseInt(s string) int {
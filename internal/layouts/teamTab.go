@@ -0,0 +1,217 @@
+package layouts
+
+import (
+	// Standard Library
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// teamMember is one person in the manager's reporting tree with their tasks
+type teamMember struct {
+	Report    interfaces.LDAPUser
+	Open      []interfaces.Tasks
+	Overdue   []interfaces.Tasks
+	Completed []interfaces.Tasks
+}
+
+var (
+	teamList     *widget.List
+	teamTaskList *widget.List
+	team         []teamMember
+	teamSelected = -1
+	teamMessage  *widget.Label
+)
+
+// CreateTeamTabContent lists each report's open, overdue and completed tasks
+func CreateTeamTabContent(window fyne.Window) fyne.CanvasObject {
+	teamMessage = widget.NewLabel("")
+	teamSelected = -1
+
+	teamList = widget.NewList(
+		func() int {
+			return len(team)
+		},
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewIcon(theme.AccountIcon()),
+				widget.NewLabel("Name"),
+				widget.NewLabel("Counts"),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id < len(team) {
+				member := team[id]
+				name := member.Report.DisplayName
+				if name == "" {
+					name = member.Report.Username
+				}
+				item.(*fyne.Container).Objects[1].(*widget.Label).SetText(name)
+				item.(*fyne.Container).Objects[2].(*widget.Label).SetText(fmt.Sprintf("Open: %d  Overdue: %d  Completed: %d",
+					len(member.Open), len(member.Overdue), len(member.Completed)))
+			}
+		},
+	)
+
+	teamTaskList = widget.NewList(
+		func() int {
+			return len(selectedTeamTasks())
+		},
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewIcon(theme.DocumentIcon()),
+				widget.NewLabel("Task Title"),
+				widget.NewLabel("Due Date"),
+				widget.NewLabel("State"),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			tasks := selectedTeamTasks()
+			if id < len(tasks) {
+				task := tasks[id]
+				item.(*fyne.Container).Objects[1].(*widget.Label).SetText(task.Title)
+				item.(*fyne.Container).Objects[2].(*widget.Label).SetText(task.DueDate.Format("2006-01-02"))
+				item.(*fyne.Container).Objects[3].(*widget.Label).SetText(taskState(task, time.Now()))
+			}
+		},
+	)
+
+	teamList.OnSelected = func(id widget.ListItemID) {
+		teamSelected = id
+		teamTaskList.UnselectAll()
+		teamTaskList.Refresh()
+	}
+	teamTaskList.OnSelected = func(id widget.ListItemID) {
+		tasks := selectedTeamTasks()
+		if id < len(tasks) {
+			task := tasks[id]
+			showTaskDialog(window, &task)
+		}
+		teamTaskList.UnselectAll()
+	}
+
+	refreshButton := widget.NewButton("Refresh", func() {
+		if err := refreshTeam(); err != nil {
+			dialog.ShowError(err, window)
+		}
+	})
+	assignButton := widget.NewButton("Assign Task", func() {
+		assignee := ""
+		if teamSelected >= 0 && teamSelected < len(team) {
+			assignee = team[teamSelected].Report.Username
+		}
+		showNewTaskDialog(window, assignee)
+	})
+
+	if err := refreshTeam(); err != nil {
+		log.Printf("Error loading team: %v", err)
+		teamMessage.SetText(err.Error())
+	}
+
+	return container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Team"),
+			container.NewHBox(assignButton, refreshButton),
+			teamMessage,
+		),
+		nil, nil, nil,
+		container.NewVSplit(teamList, teamTaskList),
+	)
+}
+
+// selectedTeamTasks are the tasks of the selected report, overdue first
+func selectedTeamTasks() []interfaces.Tasks {
+	if teamSelected < 0 || teamSelected >= len(team) {
+		return nil
+	}
+	member := team[teamSelected]
+	tasks := append([]interfaces.Tasks(nil), member.Overdue...)
+	tasks = append(tasks, member.Open...)
+	return append(tasks, member.Completed...)
+}
+
+func taskState(task interfaces.Tasks, now time.Time) string {
+	switch {
+	case task.Completed:
+		return "Completed"
+	case !task.DueDate.IsZero() && task.DueDate.Before(now):
+		return "Overdue"
+	default:
+		return "Open"
+	}
+}
+
+// refreshTeam reloads the reporting tree and their assigned tasks
+func refreshTeam() error {
+	if teamList == nil {
+		return nil
+	}
+	session := state.GlobalState.CurrentSession()
+	if state.GlobalState.DB == nil || session == nil {
+		return fmt.Errorf("not logged in")
+	}
+	reports, err := session.Reports()
+	if err != nil {
+		return err
+	}
+
+	usernames := make([]string, 0, len(reports))
+	for _, report := range reports {
+		usernames = append(usernames, report.Username)
+	}
+	var tasks []interfaces.Tasks
+	if len(usernames) > 0 {
+		tasks, _, err = state.GlobalState.DB.GetAssignedTasks(usernames)
+		if err != nil {
+			return err
+		}
+	}
+
+	team = groupTeamTasks(reports, tasks, time.Now())
+	if teamMessage != nil {
+		if len(team) == 0 {
+			teamMessage.SetText("Nobody reports to you in the directory.")
+		} else {
+			teamMessage.SetText(fmt.Sprintf("%d people report to you", len(team)))
+		}
+	}
+	teamList.Refresh()
+	teamTaskList.Refresh()
+	return nil
+}
+
+func groupTeamTasks(reports []interfaces.LDAPUser, tasks []interfaces.Tasks, now time.Time) []teamMember {
+	members := make([]teamMember, len(reports))
+	index := make(map[string]int, len(reports))
+	for i, report := range reports {
+		members[i].Report = report
+		index[strings.ToLower(report.Username)] = i
+	}
+	for _, task := range tasks {
+		i, ok := index[strings.ToLower(task.Assignee)]
+		if !ok {
+			continue
+		}
+		switch taskState(task, now) {
+		case "Completed":
+			members[i].Completed = append(members[i].Completed, task)
+		case "Overdue":
+			members[i].Overdue = append(members[i].Overdue, task)
+		default:
+			members[i].Open = append(members[i].Open, task)
+		}
+	}
+	return members
+}
@@ -0,0 +1,278 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	interfaces "github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// findingCategories are the areas offered for the findings of audit: the
+// categories of its template, if it still has one
+func findingCategories(audit *interfaces.Audits) []string {
+	if audit == nil || audit.TemplateID == 0 {
+		return nil
+	}
+	template, err := state.GlobalState.DB.GetTemplate(audit.TemplateID)
+	if err != nil {
+		return nil
+	}
+	return template.FindingCategories
+}
+
+// showTemplatesDialog lists the audit templates for editing
+func showTemplatesDialog(window fyne.Window) {
+	var templates []interfaces.AuditTemplate
+	message := widget.NewLabel("")
+
+	list := widget.NewList(
+		func() int { return len(templates) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabelWithStyle("Name", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabel("Audit Type"),
+				widget.NewLabel("Items"),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id < len(templates) {
+				row := item.(*fyne.Container).Objects
+				row[0].(*widget.Label).SetText(templates[id].Name)
+				row[1].(*widget.Label).SetText(templates[id].AuditType)
+				row[2].(*widget.Label).SetText(fmt.Sprintf("%d checklist items", len(templates[id].Items)))
+			}
+		},
+	)
+	reload := func() {
+		loaded, err := state.GlobalState.DB.GetTemplates()
+		if err != nil {
+			message.SetText(err.Error())
+			return
+		}
+		templates = loaded
+		message.SetText(fmt.Sprintf("%d templates", len(templates)))
+		list.Refresh()
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+		if id < len(templates) {
+			template := templates[id]
+			showTemplateDialog(window, &template, reload)
+		}
+	}
+	reload()
+
+	newButton := widget.NewButton("New Template", func() {
+		showTemplateDialog(window, nil, reload)
+	})
+	templatesDialog := dialog.NewCustom("Audit Templates", "Close",
+		container.NewBorder(container.NewBorder(nil, nil, nil, newButton, message), nil, nil, nil, list), window)
+	templatesDialog.Resize(fyne.NewSize(550, 450))
+	templatesDialog.Show()
+}
+
+// templateItemEditor holds the entries of one checklist item being edited
+type templateItemEditor struct {
+	title, evidence, guidance *widget.Entry
+}
+
+func newTemplateItemEditor(item interfaces.TemplateItem) *templateItemEditor {
+	editor := &templateItemEditor{title: widget.NewEntry(), evidence: widget.NewEntry(), guidance: widget.NewMultiLineEntry()}
+	editor.title.SetPlaceHolder("What to check")
+	editor.title.SetText(item.Title)
+	editor.evidence.SetPlaceHolder("Expected evidence")
+	editor.evidence.SetText(item.ExpectedEvidence)
+	editor.guidance.SetPlaceHolder("Guidance for the auditor")
+	editor.guidance.Wrapping = fyne.TextWrapWord
+	editor.guidance.SetText(item.Guidance)
+	return editor
+}
+
+func (e *templateItemEditor) item() interfaces.TemplateItem {
+	return interfaces.TemplateItem{
+		Title:            strings.TrimSpace(e.title.Text),
+		ExpectedEvidence: strings.TrimSpace(e.evidence.Text),
+		Guidance:         strings.TrimSpace(e.guidance.Text),
+	}
+}
+
+// showTemplateDialog adds a template, or edits template. changed is called
+// after every save so the list can reload.
+func showTemplateDialog(window fyne.Window, template *interfaces.AuditTemplate, changed func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Enter template name")
+
+	auditTypeEntry := widget.NewEntry()
+	auditTypeEntry.SetPlaceHolder("Audit type this template is for")
+
+	auditAreaEntry := widget.NewEntry()
+	auditAreaEntry.SetPlaceHolder("Default audit area")
+
+	categoriesEntry := widget.NewMultiLineEntry()
+	categoriesEntry.SetPlaceHolder("Finding categories, one per line")
+
+	var editors []*templateItemEditor
+	itemsBox := container.NewVBox()
+	var showItems func()
+	showItems = func() {
+		itemsBox.RemoveAll()
+		for i, editor := range editors {
+			i, editor := i, editor
+			upButton := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+				editors[i-1], editors[i] = editors[i], editors[i-1]
+				showItems()
+			})
+			if i == 0 {
+				upButton.Disable()
+			}
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				editors = append(editors[:i], editors[i+1:]...)
+				showItems()
+			})
+			itemsBox.Add(container.NewBorder(nil, nil, widget.NewLabel(fmt.Sprintf("%d.", i+1)),
+				container.NewHBox(upButton, removeButton), editor.title))
+			itemsBox.Add(editor.evidence)
+			itemsBox.Add(editor.guidance)
+			itemsBox.Add(widget.NewSeparator())
+		}
+	}
+
+	fillForm := func(t interfaces.AuditTemplate) {
+		nameEntry.SetText(t.Name)
+		auditTypeEntry.SetText(t.AuditType)
+		auditAreaEntry.SetText(t.AuditArea)
+		categoriesEntry.SetText(strings.Join(t.FindingCategories, "\n"))
+		editors = editors[:0]
+		for _, item := range t.Items {
+			editors = append(editors, newTemplateItemEditor(item))
+		}
+		showItems()
+	}
+	if template != nil {
+		fillForm(*template)
+	}
+
+	stored := func(t interfaces.AuditTemplate) {
+		if template != nil {
+			*template = t
+		}
+		changed()
+	}
+
+	var templateDialog dialog.Dialog
+	var save func(interfaces.AuditTemplate)
+	save = func(edited interfaces.AuditTemplate) {
+		var saved interfaces.AuditTemplate
+		var err error
+		if template == nil {
+			saved, err = state.GlobalState.DB.CreateTemplate(edited)
+		} else {
+			saved, err = state.GlobalState.DB.UpdateTemplate(edited)
+		}
+		var conflict *interfaces.ConflictError
+		if errors.As(err, &conflict) {
+			current, ok := conflict.Current.(interfaces.AuditTemplate)
+			if !ok {
+				dialog.ShowError(err, window)
+				return
+			}
+			showConflictDialog(window, conflict, templateConflictFields(edited), templateConflictFields(current),
+				func() {
+					edited.Version = current.Version
+					save(edited)
+				},
+				func() {
+					fillForm(current)
+					stored(current)
+				})
+			return
+		}
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if template == nil {
+			templateDialog.Hide()
+		}
+		stored(saved)
+		dialog.ShowInformation("Success", "Template saved successfully", window)
+	}
+
+	saveButton := widget.NewButton("Save", func() {
+		edited := interfaces.AuditTemplate{}
+		if template != nil {
+			edited = *template
+		}
+		edited.Name = strings.TrimSpace(nameEntry.Text)
+		edited.AuditType = strings.TrimSpace(auditTypeEntry.Text)
+		edited.AuditArea = strings.TrimSpace(auditAreaEntry.Text)
+		edited.FindingCategories = nil
+		for _, category := range strings.Split(categoriesEntry.Text, "\n") {
+			if category = strings.TrimSpace(category); category != "" {
+				edited.FindingCategories = append(edited.FindingCategories, category)
+			}
+		}
+		edited.Items = nil
+		for _, editor := range editors {
+			edited.Items = append(edited.Items, editor.item())
+		}
+		save(edited)
+	})
+	addItemButton := widget.NewButton("Add Checklist Item", func() {
+		editors = append(editors, newTemplateItemEditor(interfaces.TemplateItem{}))
+		showItems()
+	})
+
+	buttons := container.NewHBox(saveButton)
+	if template != nil {
+		buttons.Add(widget.NewButton("Delete", func() {
+			dialog.ShowConfirm("Confirm Delete",
+				fmt.Sprintf("Delete the template %q? Audits created from it keep their checklists.", template.Name),
+				func(confirm bool) {
+					if !confirm {
+						return
+					}
+					ConfirmIdentity(window, "delete this template", func() {
+						if err := state.GlobalState.DB.DeleteTemplate(template.ID); err != nil {
+							dialog.ShowError(err, window)
+							return
+						}
+						templateDialog.Hide()
+						changed()
+					})
+				}, window)
+		}))
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Audit Type", auditTypeEntry),
+		widget.NewFormItem("Audit Area", auditAreaEntry),
+		widget.NewFormItem("Finding Categories", categoriesEntry),
+	)
+	content := container.NewVBox(
+		form,
+		widget.NewLabelWithStyle("Checklist", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		itemsBox,
+		container.NewHBox(addItemButton),
+		buttons,
+	)
+	title := "New Template"
+	if template != nil {
+		title = "Template: " + template.Name
+	}
+	templateDialog = dialog.NewCustom(title, "Close", container.NewVScroll(content), window)
+	templateDialog.Resize(fyne.NewSize(600, 650))
+	templateDialog.Show()
+}
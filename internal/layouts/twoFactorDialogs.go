@@ -0,0 +1,231 @@
+package layouts
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	auth "github.com/j4m1n-t/goAudit/internal/authentication"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// ShowTwoFactorPrompt asks for a code from the user's authenticator app or a
+// backup code. done reports whether a valid code was entered.
+func ShowTwoFactorPrompt(window fyne.Window, username string, twoFactor *auth.TwoFactor, done func(verified bool)) {
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("123456")
+
+	label := widget.NewLabel("Enter the code from your authenticator app, or one of your backup codes.")
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Code", codeEntry),
+	}
+
+	promptDialog := dialog.NewForm("Two-Factor Login", "Verify", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			done(false)
+			return
+		}
+		if err := twoFactor.Verify(username, codeEntry.Text); err != nil {
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { ShowTwoFactorPrompt(window, username, twoFactor, done) })
+			errorDialog.Show()
+			return
+		}
+		done(true)
+	}, window)
+	promptDialog.Resize(fyne.NewSize(400, 200))
+	promptDialog.Show()
+	window.Canvas().Focus(codeEntry)
+}
+
+// ShowTwoFactorEnrolment shows a new secret as a QR code and saves it once
+// the user enters a code from their app. done reports whether enrolment
+// finished; the backup codes have been shown by then.
+func ShowTwoFactorEnrolment(window fyne.Window, username string, twoFactor *auth.TwoFactor, message string, done func(enrolled bool)) {
+	enrolment, err := twoFactor.Begin(username)
+	if err != nil {
+		errorDialog := dialog.NewError(err, window)
+		errorDialog.SetOnClosed(func() { done(false) })
+		errorDialog.Show()
+		return
+	}
+	showEnrolment(window, enrolment, twoFactor, message, done)
+}
+
+func showEnrolment(window fyne.Window, enrolment *auth.Enrolment, twoFactor *auth.TwoFactor, message string, done func(enrolled bool)) {
+	label := widget.NewLabel(strings.TrimSpace(message + " Scan the code with an authenticator app, then enter the six digit code it shows."))
+	label.Wrapping = fyne.TextWrapWord
+
+	var qrCode fyne.CanvasObject
+	image, err := enrolment.QRCode(200)
+	if err != nil {
+		qrCode = widget.NewLabel(fmt.Sprintf("The QR code could not be drawn: %v", err))
+	} else {
+		qrImage := canvas.NewImageFromImage(image)
+		qrImage.FillMode = canvas.ImageFillContain
+		qrImage.SetMinSize(fyne.NewSize(200, 200))
+		qrCode = qrImage
+	}
+	secretEntry := widget.NewEntry()
+	secretEntry.SetText(enrolment.Secret())
+	secretEntry.Disable()
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("123456")
+
+	content := container.NewVBox(
+		label,
+		container.NewCenter(qrCode),
+		widget.NewForm(
+			widget.NewFormItem("Secret", secretEntry),
+			widget.NewFormItem("Code", codeEntry),
+		),
+	)
+
+	enrolDialog := dialog.NewCustomConfirm("Set Up Two-Factor Login", "Confirm", "Cancel", content, func(confirm bool) {
+		if !confirm {
+			done(false)
+			return
+		}
+		codes, err := twoFactor.Confirm(enrolment, codeEntry.Text)
+		if err != nil {
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { showEnrolment(window, enrolment, twoFactor, message, done) })
+			errorDialog.Show()
+			return
+		}
+		showBackupCodes(window, codes, func() { done(true) })
+	}, window)
+	enrolDialog.Resize(fyne.NewSize(450, 500))
+	enrolDialog.Show()
+}
+
+// showBackupCodes shows the backup codes once; only their hashes are kept
+func showBackupCodes(window fyne.Window, codes []string, closed func()) {
+	list := strings.Join(codes, "\n")
+	codesLabel := widget.NewLabel(list)
+	codesLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	label := widget.NewLabel("Keep these backup codes somewhere safe. Each one logs you in once without your authenticator app. They will not be shown again.")
+	label.Wrapping = fyne.TextWrapWord
+	copyButton := widget.NewButton("Copy", func() {
+		window.Clipboard().SetContent(list)
+	})
+
+	codesDialog := dialog.NewCustom("Backup Codes", "Done", container.NewVBox(label, codesLabel, copyButton), window)
+	codesDialog.SetOnClosed(closed)
+	codesDialog.Resize(fyne.NewSize(400, 450))
+	codesDialog.Show()
+}
+
+// ShowTwoFactorSettings lets the logged in user set up two-factor login. Once
+// it is on, a current code is needed to set it up again, after confirming
+// who they are, or to turn it off when their role does not require it.
+func ShowTwoFactorSettings(window fyne.Window, username string, twoFactor *auth.TwoFactor) {
+	enrolled, err := twoFactor.Enrolled(username)
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	if !enrolled {
+		ShowTwoFactorEnrolment(window, username, twoFactor, "", func(enrolled bool) {
+			if enrolled {
+				dialog.ShowInformation("Two-Factor Login", "Two-factor login is on. You will be asked for a code each time you log in.", window)
+			}
+		})
+		return
+	}
+
+	required := false
+	if session := state.GlobalState.CurrentSession(); session != nil {
+		required = auth.TwoFactorRequired(session.Roles())
+	}
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("123456")
+	label := widget.NewLabel("Two-factor login is on. Enter a code from your authenticator app or a backup code to set it up again on a new device with new backup codes, or to turn it off.")
+	label.Wrapping = fyne.TextWrapWord
+	disableButton := widget.NewButton("Turn Off", nil)
+	if required {
+		label.SetText("Two-factor login is on and your role requires it. Enter a code from your authenticator app or a backup code to set it up again on a new device with new backup codes.")
+		disableButton.Disable()
+	}
+
+	var settingsDialog dialog.Dialog
+	resetButton := widget.NewButton("Set Up Again", func() {
+		ConfirmIdentity(window, "set up two-factor login again", func() {
+			if err := twoFactor.Verify(username, codeEntry.Text); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			settingsDialog.Hide()
+			ShowTwoFactorEnrolment(window, username, twoFactor, "", func(enrolled bool) {
+				if enrolled {
+					dialog.ShowInformation("Two-Factor Login", "Your authenticator app and backup codes have been replaced.", window)
+				}
+			})
+		})
+	})
+	disableButton.OnTapped = func() {
+		if err := twoFactor.Verify(username, codeEntry.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if err := twoFactor.Disable(username); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		settingsDialog.Hide()
+		dialog.ShowInformation("Two-Factor Login", "Two-factor login is off.", window)
+	}
+
+	content := container.NewVBox(
+		label,
+		widget.NewForm(widget.NewFormItem("Code", codeEntry)),
+		resetButton,
+		disableButton,
+	)
+	settingsDialog = dialog.NewCustom("Two-Factor Login", "Close", content, window)
+	settingsDialog.Resize(fyne.NewSize(450, 300))
+	settingsDialog.Show()
+}
+
+// showResetTwoFactorDialog removes a user's enrolment, for someone who lost
+// both their device and their backup codes
+func showResetTwoFactorDialog(window fyne.Window) {
+	usernameEntry := widget.NewEntry()
+	label := widget.NewLabel("The user will log in with their password alone, unless their role requires two-factor login; then they set it up again at their next login.")
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Username", usernameEntry),
+	}
+
+	resetDialog := dialog.NewForm("Reset Two-Factor Login", "Reset", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		username := strings.TrimSpace(usernameEntry.Text)
+		if username == "" {
+			dialog.ShowError(fmt.Errorf("a username is required"), window)
+			return
+		}
+		ConfirmIdentity(window, "reset two-factor login for "+username, func() {
+			if err := state.GlobalState.DB.SetTwoFactor(username, interfaces.TwoFactor{}); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			dialog.ShowInformation("Reset Two-Factor Login", fmt.Sprintf("Two-factor login for %s has been reset.", username), window)
+		})
+	}, window)
+	resetDialog.Resize(fyne.NewSize(450, 250))
+	resetDialog.Show()
+}
@@ -0,0 +1,208 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	admin "github.com/j4m1n-t/goAudit/internal/admin"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	state "github.com/j4m1n-t/goAudit/internal/status"
+)
+
+// Columns of the user table, in order
+var userColumns = []string{"Username", "Name", "Role", "Source", "Status", "Last Login"}
+
+func userCell(user interfaces.Users, col int) string {
+	switch col {
+	case 0:
+		return user.Username
+	case 1:
+		return user.DisplayName
+	case 2:
+		if user.Role == "" {
+			return "-"
+		}
+		return user.Role
+	case 3:
+		return userSource(user)
+	case 4:
+		return user.Status
+	case 5:
+		if user.LastLogin.IsZero() {
+			return "Never"
+		}
+		return user.LastLogin.Local().Format("2006-01-02 15:04")
+	}
+	return ""
+}
+
+func userSource(user interfaces.Users) string {
+	switch {
+	case user.Local:
+		return "Local"
+	case user.DirectoryID != "":
+		return "Directory"
+	}
+	return "-"
+}
+
+// sortUsers orders users by a column of the user table, keeping usernames in
+// order among equal values
+func sortUsers(users []interfaces.Users, col int, descending bool) {
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if descending {
+			a, b = b, a
+		}
+		if col == 5 && !a.LastLogin.Equal(b.LastLogin) {
+			return a.LastLogin.Before(b.LastLogin)
+		}
+		if x, y := strings.ToLower(userCell(a, col)), strings.ToLower(userCell(b, col)); col != 5 && x != y {
+			return x < y
+		}
+		return a.Username < b.Username
+	})
+}
+
+// showUserDialog shows one user with what they still own, and the actions an
+// administrator can take on them. changed is called after any of them.
+func showUserDialog(window fyne.Window, user interfaces.Users, changed func()) {
+	records, err := state.GlobalState.DB.GetUserRecords(user.Username)
+	recordsText := records.String()
+	if err != nil {
+		recordsText = err.Error()
+	}
+	details := widget.NewForm(
+		widget.NewFormItem("Username", widget.NewLabel(user.Username)),
+		widget.NewFormItem("Name", widget.NewLabel(user.DisplayName)),
+		widget.NewFormItem("Email", widget.NewLabel(user.Email)),
+		widget.NewFormItem("Department", widget.NewLabel(user.Department)),
+		widget.NewFormItem("Manager", widget.NewLabel(user.Manager)),
+		widget.NewFormItem("Role", widget.NewLabel(userCell(user, 2))),
+		widget.NewFormItem("Source", widget.NewLabel(userSource(user))),
+		widget.NewFormItem("Status", widget.NewLabel(user.Status)),
+		widget.NewFormItem("Created", widget.NewLabel(user.CreatedAt.Local().Format("2006-01-02 15:04"))),
+		widget.NewFormItem("Last Login", widget.NewLabel(userCell(user, 5))),
+		widget.NewFormItem("Records", widget.NewLabel(recordsText)),
+	)
+
+	var userDialog dialog.Dialog
+	done := func() {
+		userDialog.Hide()
+		changed()
+	}
+
+	statusButton := widget.NewButton("Deactivate", func() {
+		ConfirmIdentity(window, "deactivate "+user.Username, func() {
+			if _, err := admin.SetUserStatus(state.GlobalState.DB, user, interfaces.UserInactive); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			done()
+		})
+	})
+	if user.Status == interfaces.UserInactive {
+		statusButton.SetText("Activate")
+		statusButton.OnTapped = func() {
+			if _, err := admin.SetUserStatus(state.GlobalState.DB, user, interfaces.UserActive); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			done()
+		}
+	}
+	transferButton := widget.NewButton("Transfer Records", func() {
+		showTransferDialog(window, user, done)
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		dialog.ShowConfirm("Delete User", fmt.Sprintf("Delete %s? This cannot be undone.", user.Username), func(confirm bool) {
+			if !confirm {
+				return
+			}
+			ConfirmIdentity(window, "delete "+user.Username, func() {
+				err := admin.DeleteUser(state.GlobalState.DB, user)
+				if errors.Is(err, interfaces.ErrUserInUse) {
+					dialog.ShowError(fmt.Errorf("%v\n\nTransfer their records to someone else first, or deactivate them instead.", err), window)
+					return
+				}
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				done()
+			})
+		}, window)
+	})
+	if user.Username == state.GlobalState.Username {
+		statusButton.Disable()
+		deleteButton.Disable()
+	}
+	if records.Total() == records.Credentials {
+		transferButton.Disable()
+	}
+
+	note := widget.NewLabel("")
+	note.Wrapping = fyne.TextWrapWord
+	if user.DirectoryID != "" {
+		note.SetText("The directory sync sets this user's status from their directory account.")
+	}
+	content := container.NewVBox(details, note, container.NewHBox(statusButton, transferButton, deleteButton))
+	userDialog = dialog.NewCustom(user.Username, "Close", content, window)
+	userDialog.Resize(fyne.NewSize(500, 500))
+	userDialog.Show()
+}
+
+// showTransferDialog hands a user's notes, tasks, audits and CRM entries to
+// an active user chosen from a list
+func showTransferDialog(window fyne.Window, from interfaces.Users, done func()) {
+	users, err := state.GlobalState.DB.GetAll()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	var targets []string
+	for _, user := range users {
+		if user.Username != from.Username && user.Status != interfaces.UserInactive {
+			targets = append(targets, user.Username)
+		}
+	}
+	targetSelect := widget.NewSelect(targets, nil)
+	label := widget.NewLabel(fmt.Sprintf("Give everything %s owns or is assigned to another user. Credentials stay with %s.", from.Username, from.Username))
+	label.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", label),
+		widget.NewFormItem("Transfer To", targetSelect),
+	}
+
+	transferDialog := dialog.NewForm("Transfer Records", "Transfer", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		to := targetSelect.Selected
+		if to == "" {
+			dialog.ShowError(fmt.Errorf("choose who to transfer the records to"), window)
+			return
+		}
+		ConfirmIdentity(window, fmt.Sprintf("transfer the records of %s to %s", from.Username, to), func() {
+			moved, err := admin.TransferUserRecords(state.GlobalState.DB, from.Username, to)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			dialog.ShowInformation("Transfer Records", fmt.Sprintf("Transferred %s to %s.", moved, to), window)
+			done()
+		})
+	}, window)
+	transferDialog.Resize(fyne.NewSize(450, 250))
+	transferDialog.Show()
+}
@@ -0,0 +1,59 @@
+package layouts
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+
+	// Fyne Imports
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/secrets"
+)
+
+// ShowUnlockVaultDialog asks for the secrets vault passphrase, or for a new
+// one if the vault has not been created yet. done reports whether the vault
+// was unlocked; skipping leaves settings stored there unavailable.
+func ShowUnlockVaultDialog(window fyne.Window, done func(unlocked bool)) {
+	vault := secrets.DefaultVault()
+	creating := !vault.Exists()
+
+	passphraseEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+
+	message := "Enter the passphrase for the goAudit secrets vault."
+	items := []*widget.FormItem{widget.NewFormItem("Passphrase", passphraseEntry)}
+	if creating {
+		message = fmt.Sprintf("Choose a passphrase for a new secrets vault at %s. It is needed every time goAudit starts.", vault.Path)
+		items = append(items, widget.NewFormItem("Confirm", confirmEntry))
+	}
+	label := widget.NewLabel(message)
+	label.Wrapping = fyne.TextWrapWord
+	items = append([]*widget.FormItem{widget.NewFormItem("", label)}, items...)
+
+	unlockDialog := dialog.NewForm("Unlock Secrets", "Unlock", "Skip", items, func(confirm bool) {
+		if !confirm {
+			done(false)
+			return
+		}
+		var err error
+		if creating && passphraseEntry.Text != confirmEntry.Text {
+			err = errors.New("the passphrases do not match")
+		} else {
+			err = vault.Unlock(passphraseEntry.Text)
+		}
+		if err != nil {
+			// Ask again once the error has been read
+			errorDialog := dialog.NewError(err, window)
+			errorDialog.SetOnClosed(func() { ShowUnlockVaultDialog(window, done) })
+			errorDialog.Show()
+			return
+		}
+		done(true)
+	}, window)
+	unlockDialog.Resize(fyne.NewSize(450, 250))
+	unlockDialog.Show()
+}
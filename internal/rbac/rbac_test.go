@@ -0,0 +1,701 @@
+package rbac
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/fakes"
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+func fixedSession(username string, roles ...Role) *Session {
+	s, _ := NewSession(username, 0, func() ([]Role, error) { return roles, nil })
+	return s
+}
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role Role
+		p    Permission
+		want bool
+	}{
+		{RoleAdmin, SettingsManage, true},
+		{RoleAdmin, UsersDelete, true},
+		{RoleManager, SettingsManage, false},
+		{RoleManager, AuditsDelete, true},
+		{RoleManager, UsersRead, true},
+		{RoleAuditor, AuditsWrite, true},
+		{RoleAuditor, AuditsDelete, false},
+		{RoleManager, TemplatesManage, true},
+		{RoleAuditor, TemplatesManage, false},
+		{RoleManager, ControlsManage, true},
+		{RoleAuditor, ControlsManage, false},
+		{RoleManager, SchedulesManage, true},
+		{RoleAuditor, SchedulesManage, false},
+		{RoleManager, AuditsAssignReviewer, true},
+		{RoleAuditor, AuditsAssignReviewer, false},
+		{RoleAuditor, UsersRead, false},
+		{RoleReadOnly, NotesRead, true},
+		{RoleReadOnly, NotesWrite, false},
+		{RoleReadOnly, CredentialsUse, false},
+		{Role("owner"), NotesRead, false},
+	}
+	for _, tc := range tests {
+		if got := tc.role.Has(tc.p); got != tc.want {
+			t.Errorf("%s has %s = %v, want %v", tc.role, tc.p, got, tc.want)
+		}
+	}
+	for _, role := range Roles {
+		if _, err := ParseRole(string(role)); err != nil {
+			t.Errorf("ParseRole(%q): %v", role, err)
+		}
+	}
+}
+
+func TestSessionCachesRoles(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	lookups := 0
+	current := []Role{RoleAdmin}
+	var failure error
+	s, err := NewSession("alice", 15*time.Minute, func() ([]Role, error) {
+		lookups++
+		return current, failure
+	})
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	s.now = func() time.Time { return clock }
+	s.resolvedAt = clock
+
+	current = []Role{RoleReadOnly}
+	if !s.Can(SettingsManage) || lookups != 1 {
+		t.Fatalf("roles were looked up again before the TTL (%d lookups)", lookups)
+	}
+
+	clock = clock.Add(20 * time.Minute)
+	failure = errors.New("directory unavailable")
+	if !s.Can(SettingsManage) {
+		t.Error("a failed refresh dropped the cached roles")
+	}
+
+	failure = nil
+	if s.Can(SettingsManage) || !s.Can(NotesRead) || lookups != 3 {
+		t.Errorf("roles were not refreshed after the TTL: %v after %d lookups", s.Roles(), lookups)
+	}
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	s, err := NewSession("alice", 0, func() ([]Role, error) { return []Role{RoleAuditor}, nil })
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	s.now = func() time.Time { return clock }
+	s.started, s.lastActivity, s.authenticatedAt = clock, clock, clock
+
+	if err := s.Expired(30*time.Minute, 8*time.Hour); err != nil {
+		t.Errorf("a new session expired: %v", err)
+	}
+	clock = clock.Add(20 * time.Minute)
+	s.Touch()
+	clock = clock.Add(20 * time.Minute)
+	if err := s.Expired(30*time.Minute, 8*time.Hour); err != nil {
+		t.Errorf("activity did not keep the session open: %v", err)
+	}
+	if s.AuthenticatedWithin(5 * time.Minute) {
+		t.Error("the login still counts as recent after 40 minutes")
+	}
+	s.Reauthenticated()
+	if !s.AuthenticatedWithin(5 * time.Minute) {
+		t.Error("re-authenticating was not noted")
+	}
+
+	clock = clock.Add(31 * time.Minute)
+	if err := s.Expired(30*time.Minute, 8*time.Hour); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("idle session = %v, want ErrSessionExpired", err)
+	}
+	if err := s.Expired(0, 0); err != nil {
+		t.Errorf("zero limits should not expire: %v", err)
+	}
+
+	for i := 0; i < 16; i++ {
+		clock = clock.Add(29 * time.Minute)
+		s.Touch()
+	}
+	if err := s.Expired(30*time.Minute, 8*time.Hour); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("session open for over 8 hours = %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestStoreRefusesExpiredSessions(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	session := fixedSession("alice", RoleAuditor)
+	session.now = func() time.Time { return clock }
+	session.started, session.lastActivity, session.authenticatedAt = clock, clock, clock
+	session.SetTimeouts(func() (time.Duration, time.Duration) { return 30 * time.Minute, 8 * time.Hour })
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+
+	if _, err := store.CreateNote("Plan", "", "alice", false); err != nil {
+		t.Fatalf("CreateNote in an active session: %v", err)
+	}
+	clock = clock.Add(31 * time.Minute)
+	if err := session.Require(NotesRead); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Require after the idle timeout = %v, want ErrSessionExpired", err)
+	}
+	if _, err := store.CreateNote("Plan", "", "alice", false); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("CreateNote after the idle timeout = %v, want ErrSessionExpired", err)
+	}
+	if _, _, err := store.GetUsers("alice"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("reading their own account after the idle timeout = %v, want ErrSessionExpired", err)
+	}
+	if _, err := store.AddChangeHistory(interfaces.ChangeHistory{Action: "test"}); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("AddChangeHistory after the idle timeout = %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestStoreWantsRecentAuthenticationForDeletes(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	db := fakes.NewDatabase()
+	session := fixedSession("dave", RoleAdmin)
+	session.now = func() time.Time { return clock }
+	session.started, session.lastActivity, session.authenticatedAt = clock, clock, clock
+	session.SetReauthWindow(func() time.Duration { return 5 * time.Minute })
+	store := NewStore(db, func() *Session { return session })
+
+	bob, _ := db.GetOrCreateUser("bob")
+	db.GetOrCreateUser("carol")
+	note, _ := db.CreateNote("Plan", "", "bob", false)
+	audit, _ := db.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "bob"})
+	credential, _ := db.CreateCredential(interfaces.Credentials{Site: "wiki", Username: "dave", Owner: "dave"})
+
+	clock = clock.Add(10 * time.Minute)
+	refused := map[string]error{
+		"DeleteNote":          store.DeleteNote(note.ID),
+		"DeleteAudit":         store.DeleteAudit(audit.ID, "bob"),
+		"DeleteCredential":    store.DeleteCredential(credential.ID, "dave"),
+		"Delete":              store.Delete(bob),
+		"SetTwoFactor(bob)":   store.SetTwoFactor("bob", interfaces.TwoFactor{}),
+		"TransferUserRecords": func() error { _, err := store.TransferUserRecords("bob", "carol"); return err }(),
+		"DeleteRecords":       func() error { _, err := store.DeleteRecords(interfaces.RecordNotes, []int{note.ID}); return err }(),
+		"ReassignRecords": func() error {
+			_, err := store.ReassignRecords(interfaces.RecordNotes, []int{note.ID}, "carol")
+			return err
+		}(),
+		"deactivating bob": func() error {
+			inactive := bob
+			inactive.Status = interfaces.UserInactive
+			_, err := store.Update(inactive)
+			return err
+		}(),
+	}
+	for call, err := range refused {
+		if !errors.Is(err, ErrReauthRequired) {
+			t.Errorf("%s 10 minutes after the login = %v, want ErrReauthRequired", call, err)
+		}
+	}
+	if _, err := store.CreateNote("Next", "", "dave", false); err != nil {
+		t.Errorf("CreateNote outside the window: %v", err)
+	}
+	if err := store.SetTwoFactor("dave", interfaces.TwoFactor{}); err != nil {
+		t.Errorf("changing their own two-factor login outside the window: %v", err)
+	}
+	if _, err := store.GetNote(note.ID); err != nil {
+		t.Errorf("the refused delete removed the note: %v", err)
+	}
+
+	session.Reauthenticated()
+	if err := store.DeleteNote(note.ID); err != nil {
+		t.Errorf("DeleteNote after re-authenticating: %v", err)
+	}
+	bob.Status = interfaces.UserInactive
+	if _, err := store.Update(bob); err != nil {
+		t.Errorf("deactivating bob after re-authenticating: %v", err)
+	}
+	// Editing someone already inactive is not a deactivation
+	clock = clock.Add(10 * time.Minute)
+	bob.Email = "bob@example.com"
+	if _, err := store.Update(bob); err != nil {
+		t.Errorf("updating inactive bob outside the window: %v", err)
+	}
+}
+
+func TestStoreEnforcesPermissions(t *testing.T) {
+	var session *Session
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+
+	_, err := store.CreateNote("Plan", "", "alice", true)
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateNote with nobody logged in = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("alice", RoleAuditor)
+	if _, err := store.Create("alice"); err != nil {
+		t.Errorf("users must be able to create their own record: %v", err)
+	}
+	if _, err := store.Create("bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor created another user: %v", err)
+	}
+	note, err := store.CreateNote("Plan", "", "alice", true)
+	if err != nil {
+		t.Fatalf("CreateNote as auditor: %v", err)
+	}
+	audit, err := store.CreateAudit(interfaces.Audits{AuditType: "Access review", Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateAudit as auditor: %v", err)
+	}
+	err = store.DeleteAudit(audit.ID, "alice")
+	var forbidden *ForbiddenError
+	if !errors.As(err, &forbidden) || forbidden.Permission != AuditsDelete {
+		t.Errorf("DeleteAudit as auditor = %v, want audits.delete refused", err)
+	}
+
+	session = fixedSession("carol", RoleReadOnly)
+	if _, _, err := store.GetNotes("alice"); err != nil {
+		t.Errorf("GetNotes as read-only: %v", err)
+	}
+	if err := store.DeleteNote(note.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteNote as read-only = %v, want ErrForbidden", err)
+	}
+	if _, _, err := store.GetCredentials("carol"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetCredentials as read-only = %v, want ErrForbidden", err)
+	}
+	// Changing your own password goes through the auth package, which asks
+	// for the current one
+	if err := store.SetPassword("carol", "hash", false); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetPassword of your own account = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("dave", RoleReadOnly, RoleManager)
+	if err := store.DeleteAudit(audit.ID, "alice"); err != nil {
+		t.Errorf("roles should combine; DeleteAudit as manager: %v", err)
+	}
+}
+
+func TestStoreKeepsVaultsApart(t *testing.T) {
+	db := fakes.NewDatabase()
+	for _, username := range []string{"alice", "mallory"} {
+		if _, err := db.GetOrCreateUser(username); err != nil {
+			t.Fatalf("GetOrCreateUser: %v", err)
+		}
+	}
+	session := fixedSession("alice", RoleAuditor)
+	store := NewStore(db, func() *Session { return session })
+	if _, err := store.CreateCredUser("alice", "alice-hash", ""); err != nil {
+		t.Fatalf("CreateCredUser as alice: %v", err)
+	}
+	stored, err := store.CreateCredential(interfaces.Credentials{Site: "bank", LoginName: "alice-bank", LoginPass: "secret", Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateCredential as alice: %v", err)
+	}
+
+	// Not even an administrator can open someone else's vault
+	for _, role := range []Role{RoleAuditor, RoleAdmin} {
+		session = fixedSession("mallory", role)
+		if credentials, _, err := store.GetCredentials("alice"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory read alice's credentials: %+v, %v", role, credentials, err)
+		}
+		if hash, err := store.GetUserPassword("alice"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory read alice's master password: %q, %v", role, hash, err)
+		}
+		if found, _, err := store.SearchCredentials("bank", "alice"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory searched alice's credentials: %+v, %v", role, found, err)
+		}
+		if found, err := store.GetCredentialByLoginName("alice-bank"); err != nil || len(found) != 0 {
+			t.Errorf("%s mallory looked up alice's login: %+v, %v", role, found, err)
+		}
+		if _, err := store.CreateCredUser("alice", "mallory-hash", ""); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory set alice's master password: %v", role, err)
+		}
+		changed := stored
+		changed.LoginPass = "stolen"
+		if _, err := store.UpdateCredential(changed); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory changed alice's credential: %v", role, err)
+		}
+		changed.Owner = "mallory"
+		if _, err := store.UpdateCredential(changed); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory took alice's credential into her vault: %v", role, err)
+		}
+		if err := store.DeleteCredential(stored.ID, "alice"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s mallory deleted alice's credential: %v", role, err)
+		}
+	}
+
+	// Usernames match without regard to case, as everywhere else
+	session = fixedSession("Alice", RoleAuditor)
+	credentials, _, err := store.GetCredentials("alice")
+	if err != nil || len(credentials) != 1 || credentials[0].LoginPass != "secret" {
+		t.Errorf("alice's credentials = %+v, %v, want hers untouched", credentials, err)
+	}
+	if hash, err := store.GetUserPassword("alice"); err != nil || hash != "alice-hash" {
+		t.Errorf("alice's master password = %q, %v", hash, err)
+	}
+}
+
+func TestStoreLoginAttempts(t *testing.T) {
+	db := fakes.NewDatabase()
+	session := fixedSession("carol", RoleAuditor)
+	store := NewStore(db, func() *Session { return session })
+	now := time.Now()
+
+	if _, err := store.RecordLoginFailure(interfaces.AttemptsCredentials, "carol", now, now); err != nil {
+		t.Errorf("counting your own credential logins: %v", err)
+	}
+	if _, err := store.RecordLoginFailure(interfaces.AttemptsHost, "pc-1", now, now); err != nil {
+		t.Errorf("counting failures from a host: %v", err)
+	}
+	if _, err := store.RecordLoginFailure(interfaces.AttemptsUser, "alice", now, now); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor counted a failure against alice: %v", err)
+	}
+	if err := store.ClearLoginFailures(interfaces.AttemptsCredentials, "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor cleared alice's failures: %v", err)
+	}
+	if _, err := store.ListLoginAttempts(); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor listed failed logins: %v", err)
+	}
+
+	entry, err := store.AddChangeHistory(interfaces.ChangeHistory{Actor: "admin", Action: "login_failed"})
+	if err != nil || entry.Actor != "carol" {
+		t.Errorf("AddChangeHistory = %+v, %v, want it recorded as carol's", entry, err)
+	}
+	if _, err := store.GetChangeHistory(10); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor read the change history: %v", err)
+	}
+
+	session = fixedSession("dave", RoleAdmin)
+	if list, err := store.ListLoginAttempts(); err != nil || len(list) != 2 {
+		t.Errorf("ListLoginAttempts as admin = %+v, %v", list, err)
+	}
+	if err := store.ClearLoginFailures(interfaces.AttemptsCredentials, "carol"); err != nil {
+		t.Errorf("admin unlocking carol: %v", err)
+	}
+}
+
+func TestStoreUserAdministration(t *testing.T) {
+	db := fakes.NewDatabase()
+	var session *Session
+	store := NewStore(db, func() *Session { return session })
+	admin, _ := db.GetOrCreateUser("dave")
+	bob, _ := db.GetOrCreateUser("bob")
+	db.GetOrCreateUser("carol")
+
+	session = fixedSession("bob", RoleManager)
+	if _, err := store.TransferUserRecords("carol", "bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager transferred records: %v", err)
+	}
+	if _, err := store.ListRecords(interfaces.RecordTasks); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager listed everyone's tasks: %v", err)
+	}
+	if _, err := store.DeleteRecords(interfaces.RecordNotes, []int{1}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager bulk deleted notes: %v", err)
+	}
+	if err := store.RecordLogin("bob", time.Now()); err != nil {
+		t.Errorf("recording your own login: %v", err)
+	}
+	if err := store.RecordLogin("carol", time.Now()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager recorded carol's login: %v", err)
+	}
+
+	session = fixedSession("dave", RoleAdmin)
+	admin.Status = interfaces.UserInactive
+	if _, err := store.Update(admin); err == nil {
+		t.Error("an administrator deactivated their own account")
+	}
+	if err := store.Delete(admin); err == nil {
+		t.Error("an administrator deleted their own account")
+	}
+	bob.Status = interfaces.UserInactive
+	if _, err := store.Update(bob); err != nil {
+		t.Errorf("deactivating bob: %v", err)
+	}
+	if _, err := store.TransferUserRecords("bob", "carol"); err != nil {
+		t.Errorf("admin transferring bob's records: %v", err)
+	}
+	if _, err := store.ListRecords(interfaces.RecordAudits); err != nil {
+		t.Errorf("admin listing audits: %v", err)
+	}
+	if _, err := store.CompleteRecords(interfaces.RecordTasks, nil); err != nil {
+		t.Errorf("admin completing tasks: %v", err)
+	}
+	if _, err := store.ListRecords("credentials"); err == nil {
+		t.Error("ListRecords of credentials returned no error")
+	}
+}
+
+func TestStoreTaskAssignment(t *testing.T) {
+	var session *Session
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+
+	manager := fixedSession("mia", RoleManager)
+	manager.SetReportsResolver(func() ([]interfaces.LDAPUser, error) {
+		return []interfaces.LDAPUser{{Username: "bob"}, {Username: "carol"}}, nil
+	})
+	session = manager
+
+	task, err := store.CreateTask(interfaces.Tasks{Title: "Walkthrough", Username: "mia", Assignee: "bob"})
+	if err != nil {
+		t.Fatalf("manager assigning to a report: %v", err)
+	}
+	if _, err := store.CreateTask(interfaces.Tasks{Title: "Outside", Username: "mia", Assignee: "eve"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager assigned outside the reporting tree: %v", err)
+	}
+	task.Assignee = "carol"
+	if task, err = store.UpdateTask(task); err != nil {
+		t.Fatalf("manager reassigning within the tree: %v", err)
+	}
+	if _, _, err := store.GetAssignedTasks([]string{"bob", "carol"}); err != nil {
+		t.Errorf("manager viewing the team: %v", err)
+	}
+	if _, _, err := store.GetAssignedTasks([]string{"eve"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("manager viewed tasks outside the tree: %v", err)
+	}
+
+	session = fixedSession("carol", RoleAuditor)
+	task.Completed = true
+	if task, err = store.UpdateTask(task); err != nil {
+		t.Fatalf("assignee completing their task: %v", err)
+	}
+	task.Assignee = "bob"
+	if _, err := store.UpdateTask(task); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor handed a task to someone else: %v", err)
+	}
+	if _, err := store.CreateTask(interfaces.Tasks{Title: "Mine", Username: "carol", Assignee: "carol"}); err != nil {
+		t.Errorf("self assignment: %v", err)
+	}
+
+	session = fixedSession("ada", RoleAdmin)
+	task.Assignee = "eve"
+	if _, err := store.UpdateTask(task); err != nil {
+		t.Errorf("admin assigning to anyone: %v", err)
+	}
+}
+
+func TestStoreAuditWorkflow(t *testing.T) {
+	var session *Session
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+	alice, bob, carol := fixedSession("alice", RoleAuditor), fixedSession("bob", RoleAuditor), fixedSession("carol", RoleAdmin)
+	move := func(audit interfaces.Audits, to, comment string) (interfaces.Audits, error) {
+		return store.ChangeAuditStatus(interfaces.AuditStatusChange{AuditID: audit.ID, To: to, Comment: comment, Actor: "mallory"}, audit.Version, false)
+	}
+
+	session = alice
+	if _, err := store.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice", Reviewer: "alice"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor naming a reviewer = %v, want ErrForbidden", err)
+	}
+	audit, err := store.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice", Status: "Closed", Completed: true})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	if audit.Status != "Planned" || audit.Completed {
+		t.Errorf("new audit = %+v, want Planned and open", audit)
+	}
+	selfReviewed := audit
+	selfReviewed.Reviewer = "alice"
+	if _, err := store.UpdateAudit(selfReviewed); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor making themself reviewer = %v, want ErrForbidden", err)
+	}
+
+	session = carol
+	reviewed := audit
+	reviewed.Reviewer = "bob"
+	if audit, err = store.UpdateAudit(reviewed); err != nil {
+		t.Fatalf("admin assigning a reviewer: %v", err)
+	}
+
+	session = alice
+	if _, err := move(audit, "Review", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("skipping fieldwork = %v, want ErrInvalidTransition", err)
+	}
+	if audit, err = move(audit, "Fieldwork", ""); err != nil {
+		t.Fatalf("owner starting fieldwork: %v", err)
+	}
+	closing := audit
+	closing.Status, closing.Completed = "Closed", true
+	if _, err := store.UpdateAudit(closing); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("closing through UpdateAudit = %v, want ErrInvalidTransition", err)
+	}
+	if audit, err = move(audit, "Review", ""); err != nil {
+		t.Fatalf("owner sending for review: %v", err)
+	}
+	if _, err := move(audit, "Closed", ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("owner closing their own audit = %v, want ErrForbidden", err)
+	}
+
+	session = bob
+	if _, err := move(audit, "Fieldwork", " "); !errors.Is(err, ErrCommentRequired) {
+		t.Errorf("rejection without a comment = %v, want ErrCommentRequired", err)
+	}
+	if audit, err = move(audit, "Closed", ""); err != nil || !audit.Completed {
+		t.Fatalf("reviewer closing = %+v, %v", audit, err)
+	}
+
+	session = carol
+	if got := DefaultWorkflow.Available(carol, audit); len(got) != 1 || got[0].To != "Fieldwork" {
+		t.Errorf("transitions open to an admin = %+v, want reopening", got)
+	}
+	if audit, err = move(audit, "Fieldwork", "evidence missing"); err != nil || audit.Completed {
+		t.Fatalf("admin reopening = %+v, %v", audit, err)
+	}
+	changes, _ := store.GetAuditStatusChanges(audit.ID)
+	if len(changes) != 4 || changes[2].Actor != "bob" || changes[2].From != "Review" || changes[3].Comment != "evidence missing" {
+		t.Errorf("status changes = %+v", changes)
+	}
+
+	store.SetWorkflow(func() Workflow {
+		return Workflow{States: []string{"Open", "Done"}, Transitions: []Transition{{From: "Open", To: "Done"}}}
+	})
+	other, _ := store.CreateAudit(interfaces.Audits{Action: "Walkthrough", Username: "carol"})
+	if other.Status != "Open" {
+		t.Errorf("audit under a configured workflow starts in %q, want Open", other.Status)
+	}
+	if other, err = move(other, "Done", ""); err != nil || !other.Completed {
+		t.Errorf("closing under a configured workflow = %+v, %v", other, err)
+	}
+}
+
+func TestWorkflowRefusesSelfReview(t *testing.T) {
+	alice := fixedSession("alice", RoleAuditor)
+	tests := []struct {
+		name  string
+		audit interfaces.Audits
+		want  bool
+	}{
+		{"independent reviewer", interfaces.Audits{Username: "bob", AssignedUser: "carol", Reviewer: "alice"}, true},
+		{"reviewer owns the audit", interfaces.Audits{Username: "alice", Reviewer: "alice"}, false},
+		{"reviewer is assigned", interfaces.Audits{Username: "bob", AssignedUser: "alice", Reviewer: "alice"}, false},
+		{"reviewer also works on it", interfaces.Audits{Username: "bob", AdditionalUsers: []string{"alice"}, Reviewer: "alice"}, false},
+	}
+	for _, tc := range tests {
+		tc.audit.Status = "Review"
+		err := DefaultWorkflow.Check(alice, tc.audit, "Closed", "")
+		if got := err == nil; got != tc.want {
+			t.Errorf("%s: closing = %v, want allowed %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestStoreFindings(t *testing.T) {
+	session := fixedSession("alice", RoleAuditor)
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+
+	audit, err := store.CreateAudit(interfaces.Audits{Action: "Inventory", Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+	finding, err := store.CreateFinding(interfaces.Finding{AuditID: audit.ID, Title: "Unlabelled assets", Severity: interfaces.SeverityLow})
+	if err != nil || finding.Status != interfaces.FindingOpen {
+		t.Fatalf("CreateFinding = %+v, %v, want an open finding", finding, err)
+	}
+	if err := store.DeleteFinding(finding.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor deleting a finding = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("carol", RoleReadOnly)
+	if findings, err := store.GetFindings(audit.ID); err != nil || len(findings) != 1 {
+		t.Errorf("read only GetFindings = %+v, %v", findings, err)
+	}
+	finding.Status = interfaces.FindingResolved
+	if _, err := store.UpdateFinding(finding); !errors.Is(err, ErrForbidden) {
+		t.Errorf("read only UpdateFinding = %v, want ErrForbidden", err)
+	}
+}
+
+func TestStoreTemplates(t *testing.T) {
+	session := fixedSession("alice", RoleManager)
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+
+	template, err := store.CreateTemplate(interfaces.AuditTemplate{Name: "Access review",
+		Items: []interfaces.TemplateItem{{Title: "Leavers removed"}}})
+	if err != nil {
+		t.Fatalf("manager CreateTemplate: %v", err)
+	}
+	audit, err := store.CreateAudit(interfaces.Audits{Action: "Q3 access review", Username: "alice", TemplateID: template.ID})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+
+	session = fixedSession("bob", RoleAuditor)
+	if _, err := store.UpdateTemplate(template); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor UpdateTemplate = %v, want ErrForbidden", err)
+	}
+	items, err := store.GetChecklist(audit.ID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("GetChecklist = %+v, %v, want one item", items, err)
+	}
+	items[0].Result = interfaces.ChecklistPass
+	items[0].UpdatedBy = "mallory"
+	marked, err := store.UpdateChecklistItem(items[0])
+	if err != nil || marked.UpdatedBy != "bob" || marked.UpdatedAt.IsZero() {
+		t.Errorf("UpdateChecklistItem = %+v, %v, want it marked by bob", marked, err)
+	}
+
+	session = fixedSession("carol", RoleReadOnly)
+	marked.Result = interfaces.ChecklistFail
+	if _, err := store.UpdateChecklistItem(marked); !errors.Is(err, ErrForbidden) {
+		t.Errorf("read only UpdateChecklistItem = %v, want ErrForbidden", err)
+	}
+}
+
+func TestStoreControls(t *testing.T) {
+	session := fixedSession("bob", RoleAuditor)
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+	controls := []interfaces.Control{{ControlID: "CC6.1", Title: "Logical access"}}
+	if _, err := store.ImportControls("SOC 2", controls); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor ImportControls = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("alice", RoleManager)
+	if _, err := store.ImportControls("SOC 2", controls); err != nil {
+		t.Fatalf("manager ImportControls: %v", err)
+	}
+	audit, err := store.CreateAudit(interfaces.Audits{Action: "Q3 access review", Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateAudit: %v", err)
+	}
+
+	session = fixedSession("bob", RoleAuditor)
+	imported, err := store.GetControls("SOC 2")
+	if err != nil || len(imported) != 1 {
+		t.Fatalf("GetControls = %+v, %v", imported, err)
+	}
+	if err := store.SetAuditControls(audit.ID, []int{imported[0].ID}); err != nil {
+		t.Errorf("auditor SetAuditControls: %v", err)
+	}
+	if err := store.DeleteFramework("SOC 2"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor DeleteFramework = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("carol", RoleReadOnly)
+	if err := store.SetAuditControls(audit.ID, nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("read only SetAuditControls = %v, want ErrForbidden", err)
+	}
+	if links, err := store.GetControlLinks("SOC 2"); err != nil || len(links) != 1 {
+		t.Errorf("read only GetControlLinks = %+v, %v, want the audit mapping", links, err)
+	}
+}
+
+func TestStoreSchedules(t *testing.T) {
+	session := fixedSession("bob", RoleAuditor)
+	store := NewStore(fakes.NewDatabase(), func() *Session { return session })
+	schedule := interfaces.AuditSchedule{Name: "Quarterly access review", Recurrence: "FREQ=MONTHLY;INTERVAL=3",
+		StartDate: time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC), CreatedBy: "mallory"}
+	if _, err := store.CreateSchedule(schedule); !errors.Is(err, ErrForbidden) {
+		t.Errorf("auditor CreateSchedule = %v, want ErrForbidden", err)
+	}
+
+	session = fixedSession("alice", RoleManager)
+	created, err := store.CreateSchedule(schedule)
+	if err != nil || created.CreatedBy != "alice" {
+		t.Fatalf("manager CreateSchedule = %+v, %v, want it created by alice", created, err)
+	}
+
+	session = fixedSession("carol", RoleReadOnly)
+	if schedules, err := store.GetSchedules(); err != nil || len(schedules) != 1 {
+		t.Errorf("read only GetSchedules = %+v, %v", schedules, err)
+	}
+	if err := store.DeleteSchedule(created.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("read only DeleteSchedule = %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,404 @@
+// Package rbac maps directory groups to goAudit roles and enforces what each
+// role may do at the store layer, so hiding a tab is never the only check.
+package rbac
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleManager  Role = "manager"
+	RoleAuditor  Role = "auditor"
+	RoleReadOnly Role = "read-only"
+)
+
+// Roles lists every role, most privileged first
+var Roles = []Role{RoleAdmin, RoleManager, RoleAuditor, RoleReadOnly}
+
+type Permission string
+
+const (
+	NotesRead   Permission = "notes.read"
+	NotesWrite  Permission = "notes.write"
+	NotesDelete Permission = "notes.delete"
+
+	TasksRead   Permission = "tasks.read"
+	TasksWrite  Permission = "tasks.write"
+	TasksDelete Permission = "tasks.delete"
+	// Assign tasks to people in the user's reporting tree
+	TasksAssign Permission = "tasks.assign"
+	// Assign tasks to anyone
+	TasksAssignAny Permission = "tasks.assign.any"
+
+	AuditsRead   Permission = "audits.read"
+	AuditsWrite  Permission = "audits.write"
+	AuditsDelete Permission = "audits.delete"
+	// Choose who signs an audit off
+	AuditsAssignReviewer Permission = "audits.assign.reviewer"
+
+	// Create and change the templates audits start from
+	TemplatesManage Permission = "templates.manage"
+	// Import and delete compliance frameworks
+	ControlsManage Permission = "controls.manage"
+	// Create and change recurring audit schedules
+	SchedulesManage Permission = "schedules.manage"
+
+	CRMRead   Permission = "crm.read"
+	CRMWrite  Permission = "crm.write"
+	CRMDelete Permission = "crm.delete"
+
+	// Credentials are each user's own password store
+	CredentialsUse Permission = "credentials.use"
+
+	UsersRead   Permission = "users.read"
+	UsersWrite  Permission = "users.write"
+	UsersDelete Permission = "users.delete"
+
+	// Directory, database and role settings, and the Admin tab
+	SettingsManage Permission = "settings.manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		NotesRead, NotesWrite, NotesDelete,
+		TasksRead, TasksWrite, TasksDelete, TasksAssign, TasksAssignAny,
+		AuditsRead, AuditsWrite, AuditsDelete, AuditsAssignReviewer, TemplatesManage, ControlsManage, SchedulesManage,
+		CRMRead, CRMWrite, CRMDelete,
+		CredentialsUse,
+		UsersRead, UsersWrite, UsersDelete,
+		SettingsManage,
+	},
+	RoleManager: {
+		NotesRead, NotesWrite, NotesDelete,
+		TasksRead, TasksWrite, TasksDelete, TasksAssign,
+		AuditsRead, AuditsWrite, AuditsDelete, AuditsAssignReviewer, TemplatesManage, ControlsManage, SchedulesManage,
+		CRMRead, CRMWrite, CRMDelete,
+		CredentialsUse,
+		UsersRead,
+	},
+	RoleAuditor: {
+		NotesRead, NotesWrite, NotesDelete,
+		TasksRead, TasksWrite, TasksDelete,
+		AuditsRead, AuditsWrite,
+		CRMRead, CRMWrite,
+		CredentialsUse,
+	},
+	RoleReadOnly: {
+		NotesRead, TasksRead, AuditsRead, CRMRead,
+	},
+}
+
+// Permissions returns what role grants
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Has reports whether role grants p
+func (r Role) Has(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRole accepts a role name as written in the configuration
+func ParseRole(name string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == name {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q", name)
+}
+
+// ErrForbidden is wrapped by every permission failure
+var ErrForbidden = errors.New("permission denied")
+
+// ErrSessionExpired is returned once a session has been idle or open too long
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrReauthRequired is returned for changes that cannot be undone once the
+// user last proved who they are longer ago than the re-authentication window
+var ErrReauthRequired = errors.New("confirm who you are first")
+
+// ForbiddenError says who was refused what
+type ForbiddenError struct {
+	Username   string
+	Permission Permission
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Username == "" {
+		return fmt.Sprintf("%v: log in to %s", ErrForbidden, e.Permission)
+	}
+	return fmt.Sprintf("%v: %s does not have %s", ErrForbidden, e.Username, e.Permission)
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Resolver looks up a user's roles, normally from directory groups
+type Resolver func() ([]Role, error)
+
+// ReportsResolver looks up everyone who reports to the user, directly or
+// through other managers
+type ReportsResolver func() ([]interfaces.LDAPUser, error)
+
+// Session holds a logged in user's roles. They are resolved at login and
+// cached for the TTL so permission checks do not query the directory; after
+// that the next check resolves them again. The reporting tree is cached the
+// same way but only looked up when first needed. The session also notes when
+// the user logged in, last did something and last proved who they are, for
+// the timeouts and re-authentication.
+type Session struct {
+	Username string
+
+	mu              sync.Mutex
+	started         time.Time
+	lastActivity    time.Time
+	authenticatedAt time.Time
+	timeouts        func() (idle, lifetime time.Duration)
+	reauthWindow    func() time.Duration
+
+	roles      []Role
+	resolvedAt time.Time
+	ttl        time.Duration
+	resolve    Resolver
+	now        func() time.Time
+
+	reports           []interfaces.LDAPUser
+	reportsResolvedAt time.Time
+	resolveReports    ReportsResolver
+}
+
+// NewSession resolves the user's roles. A ttl of zero caches them for the
+// whole session.
+func NewSession(username string, ttl time.Duration, resolve Resolver) (*Session, error) {
+	s := &Session{Username: username, ttl: ttl, resolve: resolve, now: time.Now}
+	roles, err := resolve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up roles for %s: %v", username, err)
+	}
+	s.roles, s.resolvedAt = roles, s.now()
+	s.started, s.lastActivity, s.authenticatedAt = s.resolvedAt, s.resolvedAt, s.resolvedAt
+	return s, nil
+}
+
+// Touch notes that the user did something
+func (s *Session) Touch() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActivity = s.now()
+}
+
+// Expired returns ErrSessionExpired once the session has gone idle longer
+// than idle, or lasted longer than lifetime since the login. Zero disables
+// either limit.
+func (s *Session) Expired(idle, lifetime time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if lifetime > 0 && now.Sub(s.started) >= lifetime {
+		return fmt.Errorf("%w: %s logged in more than %s ago", ErrSessionExpired, s.Username, lifetime)
+	}
+	if idle > 0 && now.Sub(s.lastActivity) >= idle {
+		return fmt.Errorf("%w: no activity for %s", ErrSessionExpired, idle)
+	}
+	return nil
+}
+
+// SetTimeouts sets where the idle and absolute limits checked by Active come
+// from, so changes to them apply to sessions already open
+func (s *Session) SetTimeouts(timeouts func() (idle, lifetime time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeouts = timeouts
+}
+
+// Active returns ErrSessionExpired once the session has gone past the limits
+// from SetTimeouts. A session without them never expires.
+func (s *Session) Active() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	timeouts := s.timeouts
+	s.mu.Unlock()
+	if timeouts == nil {
+		return nil
+	}
+	return s.Expired(timeouts())
+}
+
+// SetReauthWindow sets where the window checked by Confirmed comes from, so
+// changes to it apply to sessions already open
+func (s *Session) SetReauthWindow(window func() time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reauthWindow = window
+}
+
+// Confirmed returns ErrReauthRequired unless the user logged in or
+// re-authenticated within the window from SetReauthWindow. A session without
+// one never asks.
+func (s *Session) Confirmed() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	window := s.reauthWindow
+	s.mu.Unlock()
+	if window == nil {
+		return nil
+	}
+	if limit := window(); !s.AuthenticatedWithin(limit) {
+		return fmt.Errorf("%w: %s has not entered their password in the last %s", ErrReauthRequired, s.Username, limit)
+	}
+	return nil
+}
+
+// Reauthenticated notes that the user just proved who they are again
+func (s *Session) Reauthenticated() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authenticatedAt = s.now()
+	s.lastActivity = s.authenticatedAt
+}
+
+// AuthenticatedWithin reports whether the user logged in or re-authenticated
+// in the last window
+func (s *Session) AuthenticatedWithin(window time.Duration) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now().Sub(s.authenticatedAt) < window
+}
+
+// Roles returns the cached roles, refreshing them once the TTL has passed.
+// If the refresh fails the previous roles are kept and retried next time.
+func (s *Session) Roles() []Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ttl > 0 && s.now().Sub(s.resolvedAt) >= s.ttl {
+		roles, err := s.resolve()
+		if err != nil {
+			log.Printf("Keeping cached roles for %s: %v", s.Username, err)
+		} else {
+			s.roles = roles
+			s.resolvedAt = s.now()
+		}
+	}
+	return append([]Role(nil), s.roles...)
+}
+
+// SetReportsResolver sets how the user's reporting tree is looked up
+func (s *Session) SetReportsResolver(resolve ReportsResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolveReports = resolve
+	s.reports, s.reportsResolvedAt = nil, time.Time{}
+}
+
+// Reports returns the user's reporting tree, looking it up on first use and
+// again once the TTL has passed. A failed refresh keeps the previous tree.
+func (s *Session) Reports() ([]interfaces.LDAPUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolveReports == nil {
+		return nil, nil
+	}
+	stale := s.reportsResolvedAt.IsZero() || (s.ttl > 0 && s.now().Sub(s.reportsResolvedAt) >= s.ttl)
+	if stale {
+		reports, err := s.resolveReports()
+		if err != nil {
+			if s.reportsResolvedAt.IsZero() {
+				return nil, fmt.Errorf("failed to look up the reporting tree for %s: %v", s.Username, err)
+			}
+			log.Printf("Keeping cached reporting tree for %s: %v", s.Username, err)
+		} else {
+			s.reports = reports
+			s.reportsResolvedAt = s.now()
+		}
+	}
+	return append([]interfaces.LDAPUser(nil), s.reports...), nil
+}
+
+// Manages reports whether username is in the user's reporting tree
+func (s *Session) Manages(username string) bool {
+	if s == nil || username == "" {
+		return false
+	}
+	reports, err := s.Reports()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	for _, report := range reports {
+		if strings.EqualFold(report.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAssignTo reports whether the user may give username tasks, or take
+// tasks away from them: anyone with TasksAssignAny, and people in the
+// reporting tree with TasksAssign.
+func (s *Session) CanAssignTo(username string) bool {
+	if s.Can(TasksAssignAny) {
+		return true
+	}
+	return s.Can(TasksAssign) && s.Manages(username)
+}
+
+// Can reports whether any of the user's roles grants p
+func (s *Session) Can(p Permission) bool {
+	if s == nil {
+		return false
+	}
+	for _, role := range s.Roles() {
+		if role.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns a ForbiddenError unless the session grants p, and
+// ErrSessionExpired once it has timed out whatever it grants
+func (s *Session) Require(p Permission) error {
+	if err := s.Active(); err != nil {
+		return err
+	}
+	if s.Can(p) {
+		return nil
+	}
+	username := ""
+	if s != nil {
+		username = s.Username
+	}
+	return &ForbiddenError{Username: username, Permission: p}
+}
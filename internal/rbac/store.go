@@ -0,0 +1,970 @@
+package rbac
+
+import (
+	// Standard Library
+	"fmt"
+	"strings"
+	"time"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Store checks the session's permissions before passing each call on to the
+// database. The UI hides what a role cannot use, but this is what enforces it.
+type Store struct {
+	next     interfaces.DatabaseOperations
+	session  func() *Session
+	workflow func() Workflow
+}
+
+var _ interfaces.DatabaseOperations = (*Store)(nil)
+
+// NewStore wraps next. session returns the logged in user's session, or nil
+// when nobody is logged in, in which case every call is refused.
+func NewStore(next interfaces.DatabaseOperations, session func() *Session) *Store {
+	return &Store{next: next, session: session}
+}
+
+// SetWorkflow sets where the store reads the audit workflow from. Without
+// one it uses DefaultWorkflow.
+func (s *Store) SetWorkflow(workflow func() Workflow) {
+	s.workflow = workflow
+}
+
+func (s *Store) auditWorkflow() Workflow {
+	if s.workflow == nil {
+		return DefaultWorkflow
+	}
+	return s.workflow()
+}
+
+func (s *Store) require(p Permission) error {
+	return s.session().Require(p)
+}
+
+// requireConfirmed is require for changes that cannot be undone, which also
+// need the user to have proved who they are within the re-authentication
+// window
+func (s *Store) requireConfirmed(p Permission) error {
+	if err := s.require(p); err != nil {
+		return err
+	}
+	return s.session().Confirmed()
+}
+
+// requireSelfOr lets users work with their own account without p
+func (s *Store) requireSelfOr(username string, p Permission) error {
+	if s.isSelf(username) {
+		return s.session().Active()
+	}
+	return s.session().Require(p)
+}
+
+// requireAssignment checks that moving a task from one assignee to another
+// only touches the user themself and people they may assign to
+func (s *Store) requireAssignment(from, to string) error {
+	if strings.EqualFold(from, to) {
+		return nil
+	}
+	session := s.session()
+	if session == nil {
+		return session.Require(TasksAssign)
+	}
+	for _, username := range []string{from, to} {
+		if username == "" || strings.EqualFold(username, session.Username) {
+			continue
+		}
+		if !session.CanAssignTo(username) {
+			if !session.Can(TasksAssign) {
+				return session.Require(TasksAssign)
+			}
+			return fmt.Errorf("%w: %s is not in %s's reporting tree", ErrForbidden, username, session.Username)
+		}
+	}
+	return nil
+}
+
+// Users
+
+func (s *Store) Create(username string) (interfaces.Users, error) {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return interfaces.Users{}, err
+	}
+	return s.next.Create(username)
+}
+
+func (s *Store) GetUsers(username string) ([]interfaces.Users, string, error) {
+	if err := s.requireSelfOr(username, UsersRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetUsers(username)
+}
+
+func (s *Store) GetOrCreateUser(username string) (interfaces.Users, error) {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return interfaces.Users{}, err
+	}
+	return s.next.GetOrCreateUser(username)
+}
+
+func (s *Store) GetAll() ([]interfaces.Users, error) {
+	if err := s.require(UsersRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetAll()
+}
+
+// Update refuses to deactivate the user's own account, which would lock
+// them out the next time they log in. Deactivating someone else needs a
+// recent re-authentication, like a delete.
+func (s *Store) Update(user interfaces.Users) (interfaces.Users, error) {
+	if err := s.require(UsersWrite); err != nil {
+		return interfaces.Users{}, err
+	}
+	if user.Status == interfaces.UserInactive {
+		if s.isSelf(user.Username) {
+			return interfaces.Users{}, fmt.Errorf("you cannot deactivate your own account")
+		}
+		stored, _, err := s.next.GetUsers(user.Username)
+		if err != nil || len(stored) == 0 || stored[0].Status != interfaces.UserInactive {
+			if err := s.session().Confirmed(); err != nil {
+				return interfaces.Users{}, err
+			}
+		}
+	}
+	return s.next.Update(user)
+}
+
+func (s *Store) Delete(user interfaces.Users) error {
+	if err := s.requireConfirmed(UsersDelete); err != nil {
+		return err
+	}
+	if s.isSelf(user.Username) {
+		return fmt.Errorf("you cannot delete your own account")
+	}
+	return s.next.Delete(user)
+}
+
+func (s *Store) isSelf(username string) bool {
+	session := s.session()
+	return session != nil && strings.EqualFold(session.Username, username)
+}
+
+// SyncDirectoryUsers writes every directory user, so it needs UsersWrite
+func (s *Store) SyncDirectoryUsers(users []interfaces.LDAPUser, complete bool) (interfaces.SyncResult, error) {
+	if err := s.require(UsersWrite); err != nil {
+		return interfaces.SyncResult{}, err
+	}
+	return s.next.SyncDirectoryUsers(users, complete)
+}
+
+// GetPasswordHash and SetPassword manage local accounts. Users changing their
+// own password go through the auth package, which checks the old one.
+func (s *Store) GetPasswordHash(username string) (string, error) {
+	if err := s.require(UsersWrite); err != nil {
+		return "", err
+	}
+	return s.next.GetPasswordHash(username)
+}
+
+func (s *Store) SetPassword(username, passwordHash string, mustChange bool) error {
+	if err := s.require(UsersWrite); err != nil {
+		return err
+	}
+	return s.next.SetPassword(username, passwordHash, mustChange)
+}
+
+// Two-factor settings belong to the user, who may enrol or use codes, or to
+// an administrator resetting a lost device
+func (s *Store) GetTwoFactor(username string) (interfaces.TwoFactor, error) {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return interfaces.TwoFactor{}, err
+	}
+	return s.next.GetTwoFactor(username)
+}
+
+// SetTwoFactor for another user resets their enrolment, so it needs a recent
+// re-authentication
+func (s *Store) SetTwoFactor(username string, twoFactor interfaces.TwoFactor) error {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return err
+	}
+	if !s.isSelf(username) {
+		if err := s.session().Confirmed(); err != nil {
+			return err
+		}
+	}
+	return s.next.SetTwoFactor(username, twoFactor)
+}
+
+func (s *Store) UseTOTPStep(username string, step int64) (bool, error) {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return false, err
+	}
+	return s.next.UseTOTPStep(username, step)
+}
+
+func (s *Store) UseBackupCode(username, codeHash string) (bool, error) {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return false, err
+	}
+	return s.next.UseBackupCode(username, codeHash)
+}
+
+// OIDC subjects are linked at sign-in, before the session starts, or by user
+// management
+func (s *Store) GetUserByOIDCSubject(subject string) ([]interfaces.Users, error) {
+	if err := s.require(UsersWrite); err != nil {
+		return nil, err
+	}
+	return s.next.GetUserByOIDCSubject(subject)
+}
+
+func (s *Store) SetOIDCSubject(username, subject string) error {
+	if err := s.require(UsersWrite); err != nil {
+		return err
+	}
+	return s.next.SetOIDCSubject(username, subject)
+}
+
+func (s *Store) RecordLogin(username string, at time.Time) error {
+	if err := s.requireSelfOr(username, UsersWrite); err != nil {
+		return err
+	}
+	return s.next.RecordLogin(username, at)
+}
+
+func (s *Store) GetUserRecords(username string) (interfaces.UserRecords, error) {
+	if err := s.requireSelfOr(username, UsersRead); err != nil {
+		return interfaces.UserRecords{}, err
+	}
+	return s.next.GetUserRecords(username)
+}
+
+// TransferUserRecords moves other users' work, so it needs UsersWrite even
+// for records the user could reassign one at a time
+func (s *Store) TransferUserRecords(from, to string) (interfaces.UserRecords, error) {
+	if err := s.requireConfirmed(UsersWrite); err != nil {
+		return interfaces.UserRecords{}, err
+	}
+	return s.next.TransferUserRecords(from, to)
+}
+
+// Permissions on each kind of record, for reading, changing and deleting
+var recordPermissions = map[string][3]Permission{
+	interfaces.RecordNotes:  {NotesRead, NotesWrite, NotesDelete},
+	interfaces.RecordTasks:  {TasksRead, TasksWrite, TasksDelete},
+	interfaces.RecordAudits: {AuditsRead, AuditsWrite, AuditsDelete},
+	interfaces.RecordCRM:    {CRMRead, CRMWrite, CRMDelete},
+}
+
+// requireRecords checks the permission on kind at index action of
+// recordPermissions. The record browser works on everyone's records, so it
+// is also limited to those who manage users.
+func (s *Store) requireRecords(kind string, action int) error {
+	permissions, ok := recordPermissions[kind]
+	if !ok {
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+	if err := s.require(permissions[action]); err != nil {
+		return err
+	}
+	return s.require(UsersWrite)
+}
+
+// requireConfirmedRecords is requireRecords for bulk changes that cannot be
+// undone
+func (s *Store) requireConfirmedRecords(kind string, action int) error {
+	if err := s.requireRecords(kind, action); err != nil {
+		return err
+	}
+	return s.session().Confirmed()
+}
+
+func (s *Store) ListRecords(kind string) ([]interfaces.RecordSummary, error) {
+	if err := s.requireRecords(kind, 0); err != nil {
+		return nil, err
+	}
+	return s.next.ListRecords(kind)
+}
+
+func (s *Store) DeleteRecords(kind string, ids []int) (int, error) {
+	if err := s.requireConfirmedRecords(kind, 2); err != nil {
+		return 0, err
+	}
+	return s.next.DeleteRecords(kind, ids)
+}
+
+func (s *Store) ReassignRecords(kind string, ids []int, to string) (int, error) {
+	if err := s.requireConfirmedRecords(kind, 1); err != nil {
+		return 0, err
+	}
+	return s.next.ReassignRecords(kind, ids, to)
+}
+
+func (s *Store) CompleteRecords(kind string, ids []int) (int, error) {
+	if err := s.requireRecords(kind, 1); err != nil {
+		return 0, err
+	}
+	return s.next.CompleteRecords(kind, ids)
+}
+
+// requireOwnAttempts lets a logged in user count and clear the failed logins
+// of their own credential store and of the host they are on. Other counters
+// belong to user management.
+func (s *Store) requireOwnAttempts(scope, name string) error {
+	session := s.session()
+	if session != nil {
+		switch {
+		case scope == interfaces.AttemptsHost:
+			return session.Active()
+		case scope == interfaces.AttemptsCredentials && strings.EqualFold(session.Username, name):
+			return session.Active()
+		}
+	}
+	return session.Require(UsersWrite)
+}
+
+func (s *Store) GetLoginAttempts(scope, name string) (interfaces.LoginAttempts, error) {
+	if err := s.requireOwnAttempts(scope, name); err != nil {
+		return interfaces.LoginAttempts{}, err
+	}
+	return s.next.GetLoginAttempts(scope, name)
+}
+
+func (s *Store) ListLoginAttempts() ([]interfaces.LoginAttempts, error) {
+	if err := s.require(UsersWrite); err != nil {
+		return nil, err
+	}
+	return s.next.ListLoginAttempts()
+}
+
+func (s *Store) RecordLoginFailure(scope, name string, at, since time.Time) (interfaces.LoginAttempts, error) {
+	if err := s.requireOwnAttempts(scope, name); err != nil {
+		return interfaces.LoginAttempts{}, err
+	}
+	return s.next.RecordLoginFailure(scope, name, at, since)
+}
+
+func (s *Store) ClearLoginFailures(scope, name string) error {
+	if err := s.requireOwnAttempts(scope, name); err != nil {
+		return err
+	}
+	return s.next.ClearLoginFailures(scope, name)
+}
+
+// Change history
+
+// AddChangeHistory records the entry as the logged in user's, whatever
+// Actor it names
+func (s *Store) AddChangeHistory(entry interfaces.ChangeHistory) (interfaces.ChangeHistory, error) {
+	session := s.session()
+	if session == nil {
+		return interfaces.ChangeHistory{}, session.Require(SettingsManage)
+	}
+	if err := session.Active(); err != nil {
+		return interfaces.ChangeHistory{}, err
+	}
+	entry.Actor = session.Username
+	return s.next.AddChangeHistory(entry)
+}
+
+func (s *Store) GetChangeHistory(limit int) ([]interfaces.ChangeHistory, error) {
+	if err := s.require(SettingsManage); err != nil {
+		return nil, err
+	}
+	return s.next.GetChangeHistory(limit)
+}
+
+// Notes
+
+func (s *Store) GetNote(id int) (interfaces.Note, error) {
+	if err := s.require(NotesRead); err != nil {
+		return interfaces.Note{}, err
+	}
+	return s.next.GetNote(id)
+}
+
+func (s *Store) GetNotes(username string) ([]interfaces.Note, string, error) {
+	if err := s.require(NotesRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetNotes(username)
+}
+
+func (s *Store) UpdateNote(note interfaces.Note) (interfaces.Note, error) {
+	if err := s.require(NotesWrite); err != nil {
+		return interfaces.Note{}, err
+	}
+	return s.next.UpdateNote(note)
+}
+
+func (s *Store) DeleteNote(id int) error {
+	if err := s.requireConfirmed(NotesDelete); err != nil {
+		return err
+	}
+	return s.next.DeleteNote(id)
+}
+
+func (s *Store) CreateNote(title, content string, username string, open bool) (interfaces.Note, error) {
+	if err := s.require(NotesWrite); err != nil {
+		return interfaces.Note{}, err
+	}
+	return s.next.CreateNote(title, content, username, open)
+}
+
+func (s *Store) SearchNotes(searchTerm string, username string) ([]interfaces.Note, string, error) {
+	if err := s.require(NotesRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.SearchNotes(searchTerm, username)
+}
+
+// Tasks
+
+func (s *Store) GetTask(id int) (interfaces.Tasks, error) {
+	if err := s.require(TasksRead); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	return s.next.GetTask(id)
+}
+
+func (s *Store) GetTasks(username string) ([]interfaces.Tasks, string, error) {
+	if err := s.require(TasksRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetTasks(username)
+}
+
+// GetAssignedTasks is limited to the user's own tasks and those of people
+// they may assign to
+func (s *Store) GetAssignedTasks(assignees []string) ([]interfaces.Tasks, string, error) {
+	if err := s.require(TasksRead); err != nil {
+		return nil, err.Error(), err
+	}
+	for _, assignee := range assignees {
+		if err := s.requireAssignment("", assignee); err != nil {
+			return nil, err.Error(), err
+		}
+	}
+	return s.next.GetAssignedTasks(assignees)
+}
+
+func (s *Store) CreateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
+	if err := s.require(TasksWrite); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	if err := s.requireAssignment("", task.Assignee); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	return s.next.CreateTask(task)
+}
+
+func (s *Store) UpdateTask(task interfaces.Tasks) (interfaces.Tasks, error) {
+	if err := s.require(TasksWrite); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	stored, err := s.next.GetTask(task.ID)
+	if err != nil {
+		return interfaces.Tasks{}, err
+	}
+	if err := s.requireAssignment(stored.Assignee, task.Assignee); err != nil {
+		return interfaces.Tasks{}, err
+	}
+	return s.next.UpdateTask(task)
+}
+
+func (s *Store) DeleteTask(id int, username string) error {
+	if err := s.requireConfirmed(TasksDelete); err != nil {
+		return err
+	}
+	return s.next.DeleteTask(id, username)
+}
+
+// Audits
+
+func (s *Store) GetAudit(id int) (interfaces.Audits, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return interfaces.Audits{}, err
+	}
+	return s.next.GetAudit(id)
+}
+
+func (s *Store) GetAudits(username string) ([]interfaces.Audits, string, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetAudits(username)
+}
+
+func (s *Store) DeleteAudit(id int, username string) error {
+	if err := s.requireConfirmed(AuditsDelete); err != nil {
+		return err
+	}
+	return s.next.DeleteAudit(id, username)
+}
+
+// UpdateAudit leaves the status alone; it only changes through
+// ChangeAuditStatus, so the workflow cannot be skipped. Only those who may
+// assign reviewers can change who signs the audit off.
+func (s *Store) UpdateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Audits{}, err
+	}
+	current, err := s.next.GetAudit(audit.ID)
+	if err == nil && current.Version == audit.Version {
+		if audit.Status != current.Status || audit.Completed != current.Completed || !audit.CompletedAt.Equal(current.CompletedAt) {
+			return interfaces.Audits{}, fmt.Errorf("%w: change the status of audit %d through its workflow", ErrInvalidTransition, audit.ID)
+		}
+		if audit.Reviewer != current.Reviewer {
+			if err := s.require(AuditsAssignReviewer); err != nil {
+				return interfaces.Audits{}, err
+			}
+		}
+	}
+	return s.next.UpdateAudit(audit)
+}
+
+// CreateAudit starts every audit in the workflow's initial state. Naming a
+// reviewer needs the same permission as changing one.
+func (s *Store) CreateAudit(audit interfaces.Audits) (interfaces.Audits, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Audits{}, err
+	}
+	if audit.Reviewer != "" {
+		if err := s.require(AuditsAssignReviewer); err != nil {
+			return interfaces.Audits{}, err
+		}
+	}
+	audit.Status = s.auditWorkflow().Initial()
+	audit.Completed, audit.CompletedAt = false, time.Time{}
+	return s.next.CreateAudit(audit)
+}
+
+// ChangeAuditStatus moves an audit along its workflow if the session may take
+// that transition. The store works out where the audit comes from, who moved
+// it and whether it is now complete; only the audit, the new status and the
+// comment are read from change.
+func (s *Store) ChangeAuditStatus(change interfaces.AuditStatusChange, version int, completed bool) (interfaces.Audits, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Audits{}, err
+	}
+	audit, err := s.next.GetAudit(change.AuditID)
+	if err != nil {
+		return interfaces.Audits{}, err
+	}
+	if audit.Version != version {
+		return interfaces.Audits{}, &interfaces.ConflictError{Entity: "audit", ID: audit.ID, Current: audit}
+	}
+	workflow := s.auditWorkflow()
+	if err := workflow.Check(s.session(), audit, change.To, change.Comment); err != nil {
+		return interfaces.Audits{}, err
+	}
+	change.From = workflow.State(audit)
+	change.Actor = s.session().Username
+	change.Comment = strings.TrimSpace(change.Comment)
+	change.At = time.Now()
+	return s.next.ChangeAuditStatus(change, version, change.To == workflow.Final())
+}
+
+func (s *Store) GetAuditStatusChanges(auditID int) ([]interfaces.AuditStatusChange, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetAuditStatusChanges(auditID)
+}
+
+// Findings belong to their audit and take its permissions
+
+func (s *Store) GetFinding(id int) (interfaces.Finding, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return interfaces.Finding{}, err
+	}
+	return s.next.GetFinding(id)
+}
+
+func (s *Store) GetFindings(auditID int) ([]interfaces.Finding, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetFindings(auditID)
+}
+
+// CreateFinding opens a finding unless it is given a status
+func (s *Store) CreateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Finding{}, err
+	}
+	if finding.Status == "" {
+		finding.Status = interfaces.FindingOpen
+	}
+	return s.next.CreateFinding(finding)
+}
+
+func (s *Store) UpdateFinding(finding interfaces.Finding) (interfaces.Finding, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Finding{}, err
+	}
+	return s.next.UpdateFinding(finding)
+}
+
+func (s *Store) DeleteFinding(id int) error {
+	if err := s.requireConfirmed(AuditsDelete); err != nil {
+		return err
+	}
+	return s.next.DeleteFinding(id)
+}
+
+// Evidence also takes the permissions of its audit. It cannot be changed or
+// deleted, only replaced by a new version.
+
+// AddEvidence records the session user as the uploader
+func (s *Store) AddEvidence(evidence interfaces.Evidence, content []byte) (interfaces.Evidence, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.Evidence{}, err
+	}
+	evidence.Uploader = s.session().Username
+	evidence.UploadedAt = time.Now()
+	return s.next.AddEvidence(evidence, content)
+}
+
+func (s *Store) GetEvidence(id int) (interfaces.Evidence, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return interfaces.Evidence{}, err
+	}
+	return s.next.GetEvidence(id)
+}
+
+func (s *Store) ListEvidence(auditID int) ([]interfaces.Evidence, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.ListEvidence(auditID)
+}
+
+func (s *Store) ReadEvidence(id int) ([]byte, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.ReadEvidence(id)
+}
+
+// Templates can be read by anyone who can read audits, and are managed by
+// TemplatesManage. Checklists belong to their audit.
+
+func (s *Store) GetTemplate(id int) (interfaces.AuditTemplate, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	return s.next.GetTemplate(id)
+}
+
+func (s *Store) GetTemplates() ([]interfaces.AuditTemplate, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetTemplates()
+}
+
+func (s *Store) CreateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	if err := s.require(TemplatesManage); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	return s.next.CreateTemplate(template)
+}
+
+func (s *Store) UpdateTemplate(template interfaces.AuditTemplate) (interfaces.AuditTemplate, error) {
+	if err := s.require(TemplatesManage); err != nil {
+		return interfaces.AuditTemplate{}, err
+	}
+	return s.next.UpdateTemplate(template)
+}
+
+func (s *Store) DeleteTemplate(id int) error {
+	if err := s.requireConfirmed(TemplatesManage); err != nil {
+		return err
+	}
+	return s.next.DeleteTemplate(id)
+}
+
+func (s *Store) GetChecklist(auditID int) ([]interfaces.ChecklistItem, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetChecklist(auditID)
+}
+
+// UpdateChecklistItem records the session user as the one who set the result
+func (s *Store) UpdateChecklistItem(item interfaces.ChecklistItem) (interfaces.ChecklistItem, error) {
+	if err := s.require(AuditsWrite); err != nil {
+		return interfaces.ChecklistItem{}, err
+	}
+	item.UpdatedBy = s.session().Username
+	item.UpdatedAt = time.Now()
+	return s.next.UpdateChecklistItem(item)
+}
+
+// The controls catalog can be read by anyone who can read audits and is
+// changed by ControlsManage. Mapping audits and checklist items to controls
+// is part of working on the audit.
+
+func (s *Store) ImportControls(framework string, controls []interfaces.Control) (interfaces.ControlImport, error) {
+	if err := s.require(ControlsManage); err != nil {
+		return interfaces.ControlImport{}, err
+	}
+	return s.next.ImportControls(framework, controls)
+}
+
+func (s *Store) GetControls(framework string) ([]interfaces.Control, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetControls(framework)
+}
+
+func (s *Store) DeleteFramework(framework string) error {
+	if err := s.requireConfirmed(ControlsManage); err != nil {
+		return err
+	}
+	return s.next.DeleteFramework(framework)
+}
+
+func (s *Store) GetAuditControls(auditID int) ([]interfaces.Control, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetAuditControls(auditID)
+}
+
+func (s *Store) SetAuditControls(auditID int, controlIDs []int) error {
+	if err := s.require(AuditsWrite); err != nil {
+		return err
+	}
+	return s.next.SetAuditControls(auditID, controlIDs)
+}
+
+func (s *Store) SetChecklistItemControls(itemID int, controlIDs []int) error {
+	if err := s.require(AuditsWrite); err != nil {
+		return err
+	}
+	return s.next.SetChecklistItemControls(itemID, controlIDs)
+}
+
+func (s *Store) SetFindingControls(findingID int, controlIDs []int) error {
+	if err := s.require(AuditsWrite); err != nil {
+		return err
+	}
+	return s.next.SetFindingControls(findingID, controlIDs)
+}
+
+func (s *Store) GetControlLinks(framework string) ([]interfaces.ControlLink, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetControlLinks(framework)
+}
+
+// Schedules and the calendar of their audits can be read by anyone who can
+// read audits and are changed by SchedulesManage. The scheduler itself runs
+// as the application, not through the store.
+
+func (s *Store) GetSchedule(id int) (interfaces.AuditSchedule, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	return s.next.GetSchedule(id)
+}
+
+func (s *Store) GetSchedules() ([]interfaces.AuditSchedule, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetSchedules()
+}
+
+// CreateSchedule makes the session's user the owner of the schedule's audits
+func (s *Store) CreateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	if err := s.require(SchedulesManage); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	schedule.CreatedBy = s.session().Username
+	return s.next.CreateSchedule(schedule)
+}
+
+func (s *Store) UpdateSchedule(schedule interfaces.AuditSchedule) (interfaces.AuditSchedule, error) {
+	if err := s.require(SchedulesManage); err != nil {
+		return interfaces.AuditSchedule{}, err
+	}
+	return s.next.UpdateSchedule(schedule)
+}
+
+func (s *Store) DeleteSchedule(id int) error {
+	if err := s.requireConfirmed(SchedulesManage); err != nil {
+		return err
+	}
+	return s.next.DeleteSchedule(id)
+}
+
+func (s *Store) SetScheduleCreatedThrough(id int, through time.Time) error {
+	if err := s.require(SchedulesManage); err != nil {
+		return err
+	}
+	return s.next.SetScheduleCreatedThrough(id, through)
+}
+
+func (s *Store) GetScheduledAudits(from, to time.Time) ([]interfaces.Audits, error) {
+	if err := s.require(AuditsRead); err != nil {
+		return nil, err
+	}
+	return s.next.GetScheduledAudits(from, to)
+}
+
+// CRM
+
+func (s *Store) GetCRMEntry(id int) (interfaces.CRM, error) {
+	if err := s.require(CRMRead); err != nil {
+		return interfaces.CRM{}, err
+	}
+	return s.next.GetCRMEntry(id)
+}
+
+func (s *Store) GetCRMEntries(username string) ([]interfaces.CRM, string, error) {
+	if err := s.require(CRMRead); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetCRMEntries(username)
+}
+
+func (s *Store) DeleteCRMEntry(id int, username string) error {
+	if err := s.requireConfirmed(CRMDelete); err != nil {
+		return err
+	}
+	return s.next.DeleteCRMEntry(id, username)
+}
+
+func (s *Store) UpdateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
+	if err := s.require(CRMWrite); err != nil {
+		return interfaces.CRM{}, err
+	}
+	return s.next.UpdateCRMEntry(crm)
+}
+
+func (s *Store) CreateCRMEntry(crm interfaces.CRM) (interfaces.CRM, error) {
+	if err := s.require(CRMWrite); err != nil {
+		return interfaces.CRM{}, err
+	}
+	return s.next.CreateCRMEntry(crm)
+}
+
+// Credentials
+
+// requireOwnVault lets users into their own credentials only. No role opens
+// another user's vault or master password, so there is no permission that
+// stands in for being owner.
+func (s *Store) requireOwnVault(owner string) error {
+	if err := s.require(CredentialsUse); err != nil {
+		return err
+	}
+	if !s.isSelf(owner) {
+		return fmt.Errorf("%w: the credentials of %q belong to them alone", ErrForbidden, owner)
+	}
+	return nil
+}
+
+// credentialOwner is whose vault credential is in. Entries saved without an
+// owner belong to their username, as when they were created.
+func credentialOwner(credential interfaces.Credentials) string {
+	if credential.Owner != "" {
+		return credential.Owner
+	}
+	return credential.Username
+}
+
+func (s *Store) GetCredentials(username string) ([]interfaces.Credentials, string, error) {
+	if err := s.requireOwnVault(username); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.GetCredentials(username)
+}
+
+// GetCredentialByLoginName only finds credentials in the user's own vault
+func (s *Store) GetCredentialByLoginName(loginName string) ([]interfaces.Credentials, error) {
+	if err := s.require(CredentialsUse); err != nil {
+		return nil, err
+	}
+	credentials, err := s.next.GetCredentialByLoginName(loginName)
+	if err != nil {
+		return nil, err
+	}
+	var own []interfaces.Credentials
+	for _, credential := range credentials {
+		if s.isSelf(credentialOwner(credential)) {
+			own = append(own, credential)
+		}
+	}
+	return own, nil
+}
+
+func (s *Store) CreateCredential(credential interfaces.Credentials) (interfaces.Credentials, error) {
+	if err := s.requireOwnVault(credentialOwner(credential)); err != nil {
+		return interfaces.Credentials{}, err
+	}
+	return s.next.CreateCredential(credential)
+}
+
+// UpdateCredential checks the stored credential is the user's as well as the
+// one they send, so neither can be swapped for someone else's
+func (s *Store) UpdateCredential(credential interfaces.Credentials) (interfaces.Credentials, error) {
+	owner := credentialOwner(credential)
+	if err := s.requireOwnVault(owner); err != nil {
+		return interfaces.Credentials{}, err
+	}
+	own, _, err := s.next.GetCredentials(owner)
+	if err != nil {
+		return interfaces.Credentials{}, err
+	}
+	for _, stored := range own {
+		if stored.ID == credential.ID {
+			return s.next.UpdateCredential(credential)
+		}
+	}
+	return interfaces.Credentials{}, fmt.Errorf("%w: credential %d is not in the vault of %s", ErrForbidden, credential.ID, owner)
+}
+
+func (s *Store) DeleteCredential(id int, owner string) error {
+	if err := s.requireOwnVault(owner); err != nil {
+		return err
+	}
+	if err := s.session().Confirmed(); err != nil {
+		return err
+	}
+	return s.next.DeleteCredential(id, owner)
+}
+
+func (s *Store) SearchCredentials(searchTerm, owner string) ([]interfaces.Credentials, string, error) {
+	if err := s.requireOwnVault(owner); err != nil {
+		return nil, err.Error(), err
+	}
+	return s.next.SearchCredentials(searchTerm, owner)
+}
+
+func (s *Store) CreateCredUser(username string, hashedPassword string, email string) (*interfaces.Credentials, error) {
+	if err := s.requireOwnVault(username); err != nil {
+		return nil, err
+	}
+	return s.next.CreateCredUser(username, hashedPassword, email)
+}
+
+func (s *Store) GetUserPassword(username string) (string, error) {
+	if err := s.requireOwnVault(username); err != nil {
+		return "", err
+	}
+	return s.next.GetUserPassword(username)
+}
+
+// Change notifications carry only table, operation and row ID, and the
+// subscriber still reads through the checks above, so they are not filtered.
+func (s *Store) SubscribeChanges() (<-chan interfaces.ChangeEvent, func()) {
+	return s.next.SubscribeChanges()
+}
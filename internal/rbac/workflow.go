@@ -0,0 +1,169 @@
+package rbac
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"strings"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/interfaces"
+)
+
+// Parties to an audit that a workflow transition can name besides roles
+const (
+	PartyOwner    = "owner"
+	PartyAssignee = "assignee"
+	PartyReviewer = "reviewer"
+)
+
+// ErrInvalidTransition is returned for a status change the workflow does
+// not have
+var ErrInvalidTransition = errors.New("status change not allowed")
+
+// ErrCommentRequired is returned when a transition that needs a reason is
+// taken without one
+var ErrCommentRequired = errors.New("a comment is required")
+
+// Workflow is the life cycle of an audit. New audits start in the first
+// state and reaching the last one completes them.
+type Workflow struct {
+	States      []string
+	Transitions []Transition
+}
+
+// Transition moves an audit From one state To another. By lists who may take
+// it: the audit's owner, assignee or reviewer (who must be neither), or
+// anyone with one of the roles named; empty lets anyone who may edit audits.
+// With Comment, a reason must be given, as when work is sent back.
+type Transition struct {
+	From    string
+	To      string
+	By      []string
+	Comment bool
+}
+
+// DefaultWorkflow takes an engagement from planning through fieldwork to a
+// review that only the reviewer can close or send back
+var DefaultWorkflow = Workflow{
+	States: []string{"Planned", "Fieldwork", "Review", "Closed"},
+	Transitions: []Transition{
+		{From: "Planned", To: "Fieldwork", By: []string{PartyOwner, PartyAssignee}},
+		{From: "Fieldwork", To: "Review", By: []string{PartyOwner, PartyAssignee}},
+		{From: "Review", To: "Closed", By: []string{PartyReviewer}},
+		{From: "Review", To: "Fieldwork", By: []string{PartyReviewer}, Comment: true},
+		{From: "Closed", To: "Fieldwork", By: []string{PartyReviewer, string(RoleAdmin)}, Comment: true},
+	},
+}
+
+// Initial is the state new audits start in
+func (w Workflow) Initial() string {
+	if len(w.States) == 0 {
+		return ""
+	}
+	return w.States[0]
+}
+
+// Final is the state that completes an audit
+func (w Workflow) Final() string {
+	if len(w.States) == 0 {
+		return ""
+	}
+	return w.States[len(w.States)-1]
+}
+
+// State is the status of audit, counting audits from before the workflow as
+// in the initial state, or the final one once completed
+func (w Workflow) State(audit interfaces.Audits) string {
+	switch {
+	case audit.Status != "":
+		return audit.Status
+	case audit.Completed:
+		return w.Final()
+	}
+	return w.Initial()
+}
+
+// Available lists the transitions out of audit's state that session may take
+func (w Workflow) Available(session *Session, audit interfaces.Audits) []Transition {
+	var available []Transition
+	for _, transition := range w.Transitions {
+		if transition.From == w.State(audit) && transition.allows(session, audit) {
+			available = append(available, transition)
+		}
+	}
+	return available
+}
+
+// Check returns why session cannot move audit to the state to, if it cannot
+func (w Workflow) Check(session *Session, audit interfaces.Audits, to, comment string) error {
+	from := w.State(audit)
+	for _, transition := range w.Transitions {
+		if transition.From != from || transition.To != to {
+			continue
+		}
+		if !transition.allows(session, audit) {
+			username := ""
+			if session != nil {
+				username = session.Username
+			}
+			return fmt.Errorf("%w: only the %s can move audit %d from %s to %s, not %s",
+				ErrForbidden, strings.Join(transition.By, " or "), audit.ID, from, to, username)
+		}
+		if transition.Comment && strings.TrimSpace(comment) == "" {
+			return fmt.Errorf("moving audit %d from %s to %s: %w", audit.ID, from, to, ErrCommentRequired)
+		}
+		return nil
+	}
+	return fmt.Errorf("audit %d cannot go from %s to %s: %w", audit.ID, from, to, ErrInvalidTransition)
+}
+
+func (t Transition) allows(session *Session, audit interfaces.Audits) bool {
+	if session == nil {
+		return false
+	}
+	if len(t.By) == 0 {
+		return session.Can(AuditsWrite)
+	}
+	for _, party := range t.By {
+		switch party {
+		case PartyOwner:
+			if strings.EqualFold(audit.Username, session.Username) {
+				return true
+			}
+		case PartyAssignee:
+			if strings.EqualFold(audit.AssignedUser, session.Username) || containsString(audit.AdditionalUsers, session.Username) {
+				return true
+			}
+		case PartyReviewer:
+			if audit.Reviewer != "" && strings.EqualFold(audit.Reviewer, session.Username) && !selfReview(audit) {
+				return true
+			}
+		default:
+			for _, role := range session.Roles() {
+				if string(role) == party {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// selfReview reports whether audit's reviewer also owns or works on it, and
+// so cannot sign it off
+func selfReview(audit interfaces.Audits) bool {
+	return strings.EqualFold(audit.Reviewer, audit.Username) || strings.EqualFold(audit.Reviewer, audit.AssignedUser) ||
+		containsString(audit.AdditionalUsers, audit.Reviewer)
+}
+
+// containsString reports whether values holds value, ignoring case like
+// every other username comparison
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,469 @@
+// Package recurrence reads the part of iCalendar recurrence rules (RRULE,
+// RFC 5545) that audit schedules need, and lists the dates they fall on.
+// Rules recur by the day: times of day are ignored and dates are in UTC.
+package recurrence
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned for a rule that cannot be read or that uses a
+// part goAudit does not support
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Frequencies a rule can repeat at
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// How far Next looks for an occurrence before giving up
+const maxSearchYears = 100
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Weekday is a BYDAY entry. N picks the Nth such day of the month, counting
+// from the end when negative; zero means every one.
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w Weekday) String() string {
+	name := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return name
+	}
+	return strconv.Itoa(w.N) + name
+}
+
+// Rule is a parsed RRULE. Interval is at least 1. Count and Until are
+// optional and never both set.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByMonth    []time.Month
+	ByMonthDay []int
+	ByDay      []Weekday
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;INTERVAL=3" or
+// "RRULE:FREQ=MONTHLY;BYDAY=-1FR". FREQ, INTERVAL, COUNT, UNTIL, BYMONTH,
+// BYMONTHDAY and BYDAY are supported.
+func Parse(text string) (Rule, error) {
+	text = strings.TrimSpace(text)
+	if len(text) >= 6 && strings.EqualFold(text[:6], "RRULE:") {
+		text = text[6:]
+	}
+	rule := Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(text, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s is given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = value
+			default:
+				err = fmt.Errorf("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY, not %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positive(value)
+		case "COUNT":
+			rule.Count, err = positive(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYMONTH":
+			err = eachValue(value, func(v string) error {
+				month, err := inRange(v, 1, 12, false)
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+				return err
+			})
+		case "BYMONTHDAY":
+			err = eachValue(value, func(v string) error {
+				day, err := inRange(v, -31, 31, true)
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+				return err
+			})
+		case "BYDAY":
+			err = eachValue(value, func(v string) error {
+				day, err := parseWeekday(v)
+				rule.ByDay = append(rule.ByDay, day)
+				return err
+			})
+		case "WKST":
+			if value != "MO" {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("%s is not supported", name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	if err := rule.check(); err != nil {
+		return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	return rule, nil
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive number", value)
+	}
+	return n, nil
+}
+
+func inRange(value string, min, max int, noZero bool) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max || (noZero && n == 0) {
+		return 0, fmt.Errorf("%q is not between %d and %d", value, min, max)
+	}
+	return n, nil
+}
+
+func eachValue(value string, parse func(string) error) error {
+	for _, v := range strings.Split(value, ",") {
+		if err := parse(strings.TrimSpace(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return date(until), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q is not a date such as 20261231", value)
+}
+
+func parseWeekday(value string) (Weekday, error) {
+	if len(value) < 2 {
+		return Weekday{}, fmt.Errorf("%q is not a day such as MO or -1FR", value)
+	}
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("%q is not a day such as MO or -1FR", value)
+	}
+	w := Weekday{Day: day}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := inRange(strings.TrimPrefix(prefix, "+"), -5, 5, true)
+		if err != nil {
+			return Weekday{}, fmt.Errorf("BYDAY %s: %v", value, err)
+		}
+		w.N = n
+	}
+	return w, nil
+}
+
+// check rejects combinations the rule cannot be expanded with
+func (r Rule) check() error {
+	switch {
+	case r.Freq == "":
+		return errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return errors.New("COUNT and UNTIL cannot both be given")
+	case r.Freq == Weekly && len(r.ByMonthDay) > 0:
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, day := range r.ByDay {
+		if day.N == 0 {
+			continue
+		}
+		if r.Freq == Daily || r.Freq == Weekly {
+			return fmt.Errorf("BYDAY %s needs FREQ=MONTHLY or YEARLY", day)
+		}
+		if r.Freq == Yearly && len(r.ByMonth) == 0 {
+			return fmt.Errorf("BYDAY %s with FREQ=YEARLY needs BYMONTH", day)
+		}
+	}
+	return nil
+}
+
+// String writes the rule back out in a fixed order
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between lists the dates from start on that fall within from and to,
+// inclusive
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	from, to = date(from), date(to)
+	var dates []time.Time
+	r.each(start, to, func(d time.Time) bool {
+		if !d.Before(from) {
+			dates = append(dates, d)
+		}
+		return true
+	})
+	return dates
+}
+
+// Next returns the first date from start on that falls after after
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	after = date(after)
+	var next time.Time
+	r.each(start, after.AddDate(maxSearchYears, 0, 0), func(d time.Time) bool {
+		if d.After(after) {
+			next = d
+			return false
+		}
+		return true
+	})
+	return next, !next.IsZero()
+}
+
+// each calls yield with every date from start to limit, in order, until it
+// returns false. Start itself only counts if the rule falls on it.
+func (r Rule) each(start, limit time.Time, yield func(time.Time) bool) {
+	start, limit = date(start), date(limit)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	count := 0
+	for k := 0; ; k++ {
+		first, candidates := r.period(start, k*interval)
+		if first.After(limit) {
+			return
+		}
+		for _, d := range candidates {
+			if d.Before(start) {
+				continue
+			}
+			if d.After(limit) || (!r.Until.IsZero() && d.After(r.Until)) {
+				return
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return
+			}
+			if !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the first day of the nth period after the one holding
+// start, and the dates the rule falls on in it, sorted
+func (r Rule) period(start time.Time, n int) (time.Time, []time.Time) {
+	var first time.Time
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		first = start.AddDate(0, 0, n)
+		dates = []time.Time{first}
+	case Weekly:
+		monday := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		first = monday.AddDate(0, 0, 7*n)
+		for i := 0; i < 7; i++ {
+			d := first.AddDate(0, 0, i)
+			if r.onWeekday(d, start) {
+				dates = append(dates, d)
+			}
+		}
+	case Monthly:
+		first = time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		dates = r.monthDays(first, start)
+	case Yearly:
+		first = time.Date(start.Year()+n, time.January, 1, 0, 0, 0, 0, time.UTC)
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			dates = append(dates, r.monthDays(time.Date(first.Year(), month, 1, 0, 0, 0, 0, time.UTC), start)...)
+		}
+	}
+
+	kept := dates[:0]
+	for _, d := range dates {
+		if r.keep(d) {
+			kept = append(kept, d)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Before(kept[j]) })
+	return first, uniqueDates(kept)
+}
+
+// onWeekday reports whether a weekly rule falls on the weekday of d
+func (r Rule) onWeekday(d, start time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return d.Weekday() == start.Weekday()
+	}
+	for _, day := range r.ByDay {
+		if day.Day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays lists the days of the month starting at first that BYMONTHDAY
+// and BYDAY pick, or the day of the month of start when neither is given
+func (r Rule) monthDays(first, start time.Time) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if start.Day() > last {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, start.Day()-1)}
+	}
+
+	byMonthDay := make(map[int]bool)
+	for _, day := range r.ByMonthDay {
+		if day < 0 {
+			day = last + day + 1
+		}
+		if day >= 1 && day <= last {
+			byMonthDay[day] = true
+		}
+	}
+	byDay := make(map[int]bool)
+	for _, w := range r.ByDay {
+		var days []int
+		for day := 1; day <= last; day++ {
+			if first.AddDate(0, 0, day-1).Weekday() == w.Day {
+				days = append(days, day)
+			}
+		}
+		switch {
+		case w.N == 0:
+			for _, day := range days {
+				byDay[day] = true
+			}
+		case w.N > 0 && w.N <= len(days):
+			byDay[days[w.N-1]] = true
+		case w.N < 0 && -w.N <= len(days):
+			byDay[days[len(days)+w.N]] = true
+		}
+	}
+
+	var dates []time.Time
+	for day := 1; day <= last; day++ {
+		switch {
+		case len(r.ByMonthDay) > 0 && !byMonthDay[day]:
+		case len(r.ByDay) > 0 && !byDay[day]:
+		default:
+			dates = append(dates, first.AddDate(0, 0, day-1))
+		}
+	}
+	return dates
+}
+
+// keep applies the parts that narrow down the dates of a period
+func (r Rule) keep(d time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, d.Month()) {
+		return false
+	}
+	if r.Freq != Daily {
+		return true
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := d.AddDate(0, 1, -d.Day()).Day()
+		found := false
+		for _, day := range r.ByMonthDay {
+			found = found || day == d.Day() || last+day+1 == d.Day()
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, day := range r.ByDay {
+			found = found || day.Day == d.Weekday()
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueDates(dates []time.Time) []time.Time {
+	unique := dates[:0]
+	for _, d := range dates {
+		if len(unique) == 0 || !d.Equal(unique[len(unique)-1]) {
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// date is the calendar day of t, at midnight UTC
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
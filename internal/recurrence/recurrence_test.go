@@ -0,0 +1,119 @@
+package recurrence
+
+import (
+	// Standard Library
+	"errors"
+	"testing"
+	"time"
+)
+
+func day(text string) time.Time {
+	d, err := time.Parse("2006-01-02", text)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func formatDates(dates []time.Time) []string {
+	formatted := make([]string, len(dates))
+	for i, d := range dates {
+		formatted[i] = d.Format("2006-01-02")
+	}
+	return formatted
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		rule, start, from, to string
+		want                  []string
+	}{
+		{"FREQ=MONTHLY;INTERVAL=3", "2026-01-15", "2026-01-01", "2026-12-31",
+			[]string{"2026-01-15", "2026-04-15", "2026-07-15", "2026-10-15"}},
+		{"RRULE:FREQ=YEARLY", "2024-02-29", "2024-01-01", "2029-01-01", []string{"2024-02-29", "2028-02-29"}},
+		{"FREQ=MONTHLY", "2026-01-31", "2026-01-01", "2026-05-31", []string{"2026-01-31", "2026-03-31", "2026-05-31"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-31", "2026-01-01", "2026-04-30",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "2026-01-01", "2026-01-01", "2026-03-31",
+			[]string{"2026-01-30", "2026-02-27", "2026-03-27"}},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYMONTHDAY=1,2,3", "2026-08-01", "2026-08-01", "2026-08-31",
+			[]string{"2026-08-03"}},
+		{"FREQ=YEARLY;BYMONTH=3,9;BYDAY=1MO", "2026-01-01", "2026-01-01", "2027-03-31",
+			[]string{"2026-03-02", "2026-09-07", "2027-03-01"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2026-10-19", "2026-10-01", "2026-11-05",
+			[]string{"2026-10-19", "2026-10-22", "2026-11-02", "2026-11-05"}},
+		{"FREQ=DAILY;BYDAY=SA", "2026-10-01", "2026-10-01", "2026-10-20", []string{"2026-10-03", "2026-10-10", "2026-10-17"}},
+		{"FREQ=MONTHLY;COUNT=3", "2026-01-10", "2026-03-01", "2027-01-01", []string{"2026-03-10"}},
+		{"FREQ=MONTHLY;UNTIL=20260310", "2026-01-10", "2026-01-01", "2027-01-01",
+			[]string{"2026-01-10", "2026-02-10", "2026-03-10"}},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2026-01-01", "2026-01-01", "2030-01-01", nil},
+	}
+	for _, tc := range tests {
+		rule, err := Parse(tc.rule)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tc.rule, err)
+			continue
+		}
+		got := formatDates(rule.Between(day(tc.start), day(tc.from), day(tc.to)))
+		if len(got) != len(tc.want) {
+			t.Errorf("%s from %s = %v, want %v", tc.rule, tc.start, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s from %s = %v, want %v", tc.rule, tc.start, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	rule, _ := Parse("FREQ=MONTHLY;INTERVAL=3")
+	if next, ok := rule.Next(day("2026-01-15"), day("2026-04-15")); !ok || !next.Equal(day("2026-07-15")) {
+		t.Errorf("Next = %v, %v, want 2026-07-15", next, ok)
+	}
+	rule, _ = Parse("FREQ=MONTHLY;COUNT=2")
+	if next, ok := rule.Next(day("2026-01-15"), day("2026-02-15")); ok {
+		t.Errorf("Next after the last occurrence = %v", next)
+	}
+	// Local times count by their calendar day
+	at := time.Date(2026, time.October, 19, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	rule, _ = Parse("FREQ=DAILY")
+	if next, ok := rule.Next(at, at); !ok || !next.Equal(day("2026-10-20")) {
+		t.Errorf("Next = %v, %v, want 2026-10-20", next, ok)
+	}
+}
+
+func TestParse(t *testing.T) {
+	rule, err := Parse(" rrule:freq=monthly;interval=3;bymonthday=1,-1;byday=+1MO,FR;count=4 ")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, want := rule.String(), "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1,-1;BYDAY=1MO,FR;COUNT=4"; got != want {
+		t.Errorf("String = %s, want %s", got, want)
+	}
+
+	for _, text := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=MONTHLY;INTERVAL=0",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20261231",
+		"FREQ=MONTHLY;FREQ=YEARLY",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYSETPOS=-1",
+		"FREQ=MONTHLY;UNTIL=tomorrow",
+		"FREQ",
+	} {
+		if _, err := Parse(text); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidRule", text, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	// Standard Library
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrReadOnly is returned when writing to a provider that cannot be changed
+// from goAudit
+var ErrReadOnly = errors.New("secret is read-only")
+
+// Files reads secrets mounted as files, such as Docker or Kubernetes secrets.
+// The name is the file path; a trailing newline is ignored.
+type Files struct{}
+
+func (Files) Get(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (Files) Set(path, value string) error {
+	return ErrReadOnly
+}
+
+func (Files) Delete(path string) error {
+	return ErrReadOnly
+}
@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	// Standard Library
+	"errors"
+
+	// External Imports
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring keeps secrets in the OS keyring: the Secret Service on Linux, the
+// Keychain on macOS and the Credential Manager on Windows
+type Keyring struct {
+	Service string
+}
+
+func (k Keyring) Get(name string) (string, error) {
+	value, err := keyring.Get(k.Service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (k Keyring) Set(name, value string) error {
+	return keyring.Set(k.Service, name, value)
+}
+
+func (k Keyring) Delete(name string) error {
+	err := keyring.Delete(k.Service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
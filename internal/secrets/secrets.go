@@ -0,0 +1,184 @@
+// Package secrets keeps passwords out of the config files. A password setting
+// may hold the password itself or a reference to where it is kept:
+//
+//	keyring:NAME  the OS keyring (Secret Service, Keychain or Credential Manager)
+//	vault:NAME    the encrypted vault file, unlocked with a passphrase at startup
+//	file:PATH     a file holding only the password, such as a Docker secret
+package secrets
+
+import (
+	// Standard Library
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+)
+
+// ErrNotFound is returned when a provider has nothing stored under a name
+var ErrNotFound = errors.New("secret not found")
+
+// Provider stores secrets by name
+type Provider interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+var (
+	lock      sync.RWMutex
+	providers = map[string]Provider{
+		"keyring": Keyring{Service: "goAudit"},
+		"file":    Files{},
+	}
+)
+
+// Register installs p for references starting with scheme + ":"
+func Register(scheme string, p Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers[scheme] = p
+}
+
+func provider(scheme string) (Provider, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if scheme == "vault" {
+		return DefaultVault(), true
+	}
+	p, ok := providers[scheme]
+	return p, ok
+}
+
+// Ref builds the reference stored in the config for a secret
+func Ref(scheme, name string) string {
+	return scheme + ":" + name
+}
+
+// ParseRef splits a reference. ok is false for plain values.
+func ParseRef(value string) (scheme, name string, ok bool) {
+	scheme, name, found := strings.Cut(value, ":")
+	if !found || name == "" {
+		return "", "", false
+	}
+	switch scheme {
+	case "keyring", "vault", "file":
+		return scheme, name, true
+	}
+	if _, registered := provider(scheme); registered {
+		return scheme, name, true
+	}
+	return "", "", false
+}
+
+// IsRef reports whether value points at a secret rather than holding one
+func IsRef(value string) bool {
+	_, _, ok := ParseRef(value)
+	return ok
+}
+
+// Resolve returns the secret value points at, or value itself when it is not
+// a reference
+func Resolve(value string) (string, error) {
+	scheme, name, ok := ParseRef(value)
+	if !ok {
+		return value, nil
+	}
+	p, _ := provider(scheme)
+	secret, err := p.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", value, err)
+	}
+	return secret, nil
+}
+
+// Store saves value under name in the configured secret store and returns the
+// reference to save in the config instead. An empty value stores nothing.
+func Store(name, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	stores := config.SecretStores
+	if store := config.Current().Secrets.Store; store != "" {
+		stores = []string{store}
+	}
+
+	var problems []error
+	for _, scheme := range stores {
+		p, _ := provider(scheme)
+		err := p.Set(name, value)
+		if err == nil {
+			return Ref(scheme, name), nil
+		}
+		problems = append(problems, fmt.Errorf("%s: %w", scheme, err))
+	}
+	return "", fmt.Errorf("could not store %s securely (%w); unlock the vault or set up the OS keyring", name, errors.Join(problems...))
+}
+
+// Update returns what to save for a password setting that held existing and
+// should now hold value. References, including an unchanged existing one, are
+// saved as is. A new password is written back where the old one was kept, or
+// stored with Store when that is not possible.
+func Update(existing, name, value string) (string, error) {
+	if value == "" || IsRef(value) {
+		return value, nil
+	}
+	scheme, existingName, ok := ParseRef(existing)
+	if !ok {
+		return Store(name, value)
+	}
+
+	p, _ := provider(scheme)
+	if err := p.Set(existingName, value); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return Store(name, value)
+		}
+		return "", fmt.Errorf("failed to update %s: %w", existing, err)
+	}
+	return existing, nil
+}
+
+// Name is the name a config setting's password is stored under, e.g.
+// SQL_PASSWORD is kept as "sql-password"
+func Name(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// errUnchanged stops SaveUser from rewriting a file that needs no migration
+var errUnchanged = errors.New("unchanged")
+
+// MigrateUserConfig moves plaintext passwords in the per-user config file
+// into the secret store. Passwords that cannot be stored stay where they are.
+// It returns how many were moved.
+func MigrateUserConfig() (int, error) {
+	moved := 0
+	var problems []error
+	err := config.SaveUser(func(cfg *config.Config) error {
+		for _, key := range config.SecretKeys() {
+			value := config.Get(cfg, key)
+			if value == "" || IsRef(value) {
+				continue
+			}
+			ref, err := Store(Name(key), value)
+			if err != nil {
+				problems = append(problems, err)
+				continue
+			}
+			if err := config.Set(cfg, key, ref); err != nil {
+				return err
+			}
+			moved++
+		}
+		if moved == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errUnchanged) {
+		problems = append(problems, err)
+	}
+	return moved, errors.Join(problems...)
+}
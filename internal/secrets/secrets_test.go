@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	// Standard Library
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// External Imports
+	"github.com/zalando/go-keyring"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+)
+
+// brokenKeyring stands in for a machine without a Secret Service
+type brokenKeyring struct{}
+
+func (brokenKeyring) Get(name string) (string, error) { return "", errors.New("no keyring") }
+func (brokenKeyring) Set(name, value string) error    { return errors.New("no keyring") }
+func (brokenKeyring) Delete(name string) error        { return errors.New("no keyring") }
+
+// useVault points "vault:" references at a fresh vault for the test
+func useVault(t *testing.T) *Vault {
+	t.Helper()
+	vault := NewVault(filepath.Join(t.TempDir(), "secrets.vault"))
+	vaultLock.Lock()
+	previous := defaultVault
+	defaultVault = vault
+	vaultLock.Unlock()
+	t.Cleanup(func() {
+		vaultLock.Lock()
+		defaultVault = previous
+		vaultLock.Unlock()
+	})
+	return vault
+}
+
+func useKeyring(t *testing.T, p Provider) {
+	t.Helper()
+	Register("keyring", p)
+	t.Cleanup(func() { Register("keyring", Keyring{Service: "goAudit"}) })
+}
+
+func TestResolve(t *testing.T) {
+	keyring.MockInit()
+	if err := (Keyring{Service: "goAudit"}).Set("sql-password", "from-keyring"); err != nil {
+		t.Fatal(err)
+	}
+	secretFile := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"plain password":         "plain password",
+		"pa:ss":                  "pa:ss",
+		"keyring:sql-password":   "from-keyring",
+		"file:" + secretFile:     "from-file",
+		"https://not-a-ref.test": "https://not-a-ref.test",
+	}
+	for value, want := range tests {
+		got, err := Resolve(value)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+
+	_, err := Resolve("keyring:missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing keyring entry = %v, want ErrNotFound", err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	vault := NewVault(path)
+	if _, err := vault.Get("sql-password"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Get on a locked vault = %v, want ErrLocked", err)
+	}
+	if err := vault.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock new vault: %v", err)
+	}
+	if err := vault.Set("sql-password", "hunter2"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "sql-password") {
+		t.Error("vault file contains the secret in plain text")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("vault mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened := NewVault(path)
+	if err := reopened.Unlock("battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase = %v", err)
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if got, err := reopened.Get("sql-password"); err != nil || got != "hunter2" {
+		t.Errorf("Get after reopening = %q, %v", got, err)
+	}
+	if err := reopened.Delete("sql-password"); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Lock()
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("sql-password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted secret = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreFallsBackToVault(t *testing.T) {
+	useKeyring(t, brokenKeyring{})
+	vault := useVault(t)
+
+	_, err := Store("sql-password", "hunter2")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Store with no keyring and a locked vault = %v, want ErrLocked", err)
+	}
+
+	if err := vault.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := Store("sql-password", "hunter2")
+	if err != nil || ref != "vault:sql-password" {
+		t.Fatalf("Store = %q, %v", ref, err)
+	}
+	if got, err := Resolve(ref); err != nil || got != "hunter2" {
+		t.Errorf("Resolve(%q) = %q, %v", ref, got, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	keyring.MockInit()
+	useVault(t).Unlock("passphrase")
+
+	ref, err := Update("plaintext", "ldap-readonly-password", "s3cret")
+	if err != nil || ref != "keyring:ldap-readonly-password" {
+		t.Fatalf("Update from plain text = %q, %v", ref, err)
+	}
+	if got, _ := Resolve(ref); got != "s3cret" {
+		t.Errorf("stored %q", got)
+	}
+
+	if got, err := Update(ref, "ldap-readonly-password", ref); err != nil || got != ref {
+		t.Errorf("unchanged reference = %q, %v", got, err)
+	}
+
+	vaultRef := Ref("vault", "older-name")
+	got, err := Update(vaultRef, "ldap-readonly-password", "changed")
+	if err != nil || got != vaultRef {
+		t.Errorf("changed password = %q, %v; want it written back to %s", got, err, vaultRef)
+	}
+	if value, _ := Resolve(vaultRef); value != "changed" {
+		t.Errorf("vault holds %q", value)
+	}
+
+	got, err = Update("file:/run/secrets/ldap", "ldap-readonly-password", "replaced")
+	if err != nil || got != "keyring:ldap-readonly-password" {
+		t.Errorf("replacing a read-only file secret = %q, %v", got, err)
+	}
+}
+
+func TestMigrateUserConfig(t *testing.T) {
+	keyring.MockInit()
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.json")
+	t.Setenv(config.EnvSystemConfig, filepath.Join(dir, "system.json"))
+	if err := os.WriteFile(userPath, []byte(`{"ldap": {"readOnlyPassword": "plain"}, "sql": {"password": "keyring:sql-password"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Init([]string{"-config", userPath}); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	t.Cleanup(func() { config.Use(config.Defaults()) })
+
+	moved, err := MigrateUserConfig()
+	if err != nil || moved != 1 {
+		t.Fatalf("MigrateUserConfig = %d, %v; want 1 moved", moved, err)
+	}
+	data, _ := os.ReadFile(userPath)
+	if strings.Contains(string(data), `"plain"`) {
+		t.Errorf("plain text password left in the config:\n%s", data)
+	}
+	cfg := config.Current()
+	if cfg.LDAP.ReadOnlyPassword != "keyring:ldap-readonly-password" || cfg.SQL.Password != "keyring:sql-password" {
+		t.Errorf("migrated config = %+v", cfg)
+	}
+	if got, _ := Resolve(cfg.LDAP.ReadOnlyPassword); got != "plain" {
+		t.Errorf("keyring holds %q", got)
+	}
+
+	if moved, err := MigrateUserConfig(); err != nil || moved != 0 {
+		t.Errorf("second migration = %d, %v", moved, err)
+	}
+}
@@ -0,0 +1,289 @@
+package secrets
+
+import (
+	// Standard Library
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	// External Imports
+	"golang.org/x/crypto/argon2"
+
+	// Internal Imports
+	"github.com/j4m1n-t/goAudit/internal/config"
+)
+
+// EnvVaultPassphrase unlocks the vault without prompting, for unattended use
+const EnvVaultPassphrase = "GOAUDIT_VAULT_PASSPHRASE"
+
+var (
+	ErrLocked          = errors.New("the secrets vault is locked")
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+)
+
+// Argon2id parameters used to turn the passphrase into the vault key
+const (
+	vaultKeyTime    = 3
+	vaultKeyMemory  = 64 * 1024
+	vaultKeyThreads = 4
+	vaultKeyLength  = 32
+	vaultSaltLength = 16
+)
+
+// vaultFile is the on-disk format. Data is the AES-256-GCM encrypted JSON
+// object of name to secret.
+type vaultFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault is a passphrase protected file of secrets, for machines without a
+// usable OS keyring
+type Vault struct {
+	Path string
+
+	mu     sync.Mutex
+	salt   []byte
+	key    []byte
+	values map[string]string
+}
+
+var (
+	vaultLock    sync.Mutex
+	defaultVault *Vault
+)
+
+// DefaultVault is the vault "vault:" references read from. It lives next to
+// the per-user config file unless secrets.vaultPath says otherwise.
+func DefaultVault() *Vault {
+	vaultLock.Lock()
+	defer vaultLock.Unlock()
+	if defaultVault == nil {
+		path := config.Current().Secrets.VaultPath
+		if path == "" {
+			path = filepath.Join(filepath.Dir(config.UserPath()), "secrets.vault")
+		}
+		defaultVault = NewVault(path)
+	}
+	return defaultVault
+}
+
+func NewVault(path string) *Vault {
+	return &Vault{Path: path}
+}
+
+// Exists reports whether the vault file has been created
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.Path)
+	return err == nil
+}
+
+func (v *Vault) Locked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// Unlock decrypts the vault. If the file does not exist yet, passphrase
+// becomes the passphrase for the new vault, written on the first Set.
+func (v *Vault) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("the vault passphrase cannot be empty")
+	}
+
+	data, err := os.ReadFile(v.Path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, vaultSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to create vault: %v", err)
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.salt, v.key, v.values = salt, deriveKey(passphrase, salt), make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %v", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s is not a goAudit vault: %v", v.Path, err)
+	}
+	if file.Version != 1 {
+		return fmt.Errorf("%s has unsupported vault version %d", v.Path, file.Version)
+	}
+
+	key := deriveKey(passphrase, file.Salt)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	values := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return fmt.Errorf("vault contents are corrupt: %v", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.salt, v.key, v.values = file.Salt, key, values
+	return nil
+}
+
+// Lock forgets the key and the decrypted secrets
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.salt, v.key, v.values = nil, nil, nil
+}
+
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	value, ok := v.values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (v *Vault) Set(name, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	previous, existed := v.values[name]
+	v.values[name] = value
+	if err := v.save(); err != nil {
+		if existed {
+			v.values[name] = previous
+		} else {
+			delete(v.values, name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	if _, ok := v.values[name]; !ok {
+		return nil
+	}
+	previous := v.values[name]
+	delete(v.values, name)
+	if err := v.save(); err != nil {
+		v.values[name] = previous
+		return err
+	}
+	return nil
+}
+
+// save re-encrypts every value with a fresh nonce and replaces the file.
+// The caller holds v.mu.
+func (v *Vault) save() error {
+	plaintext, err := json.Marshal(v.values)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %v", err)
+	}
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to encrypt vault: %v", err)
+	}
+
+	data, err := json.MarshalIndent(vaultFile{
+		Version: 1,
+		Salt:    v.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create vault directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.Path), filepath.Base(v.Path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), v.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %v", err)
+	}
+	return nil
+}
+
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, vaultKeyTime, vaultKeyMemory, vaultKeyThreads, vaultKeyLength)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up vault encryption: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// VaultNeeded reports whether cfg keeps any password in the vault or stores
+// new ones there, so the vault should be unlocked at startup
+func VaultNeeded(cfg *config.Config) bool {
+	if cfg.Secrets.Store == "vault" {
+		return true
+	}
+	for _, key := range config.SecretKeys() {
+		if scheme, _, ok := ParseRef(config.Get(cfg, key)); ok && scheme == "vault" {
+			return true
+		}
+	}
+	return false
+}
+
+// UnlockFromEnvironment unlocks the default vault with the passphrase in
+// GOAUDIT_VAULT_PASSPHRASE, if set, and then clears the variable so it is not
+// passed on to child processes
+func UnlockFromEnvironment() error {
+	passphrase := os.Getenv(EnvVaultPassphrase)
+	if passphrase == "" {
+		return nil
+	}
+	os.Unsetenv(EnvVaultPassphrase)
+	return DefaultVault().Unlock(passphrase)
+}
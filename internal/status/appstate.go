@@ -17,12 +17,13 @@ import (
 	// Internal Imports
 	crud "github.com/j4m1n-t/goAudit/internal/databases"
 	"github.com/j4m1n-t/goAudit/internal/interfaces"
+	"github.com/j4m1n-t/goAudit/internal/rbac"
 )
 
 type AppState struct {
 	LDAPConn             *interfaces.LDAPConnection
 	Username             string
-	UserID               int
+	UserID               string
 	CredentialAuthStatus bool
 	CredentialUsername   string
 	MPPresent            bool
@@ -32,22 +33,48 @@ type AppState struct {
 	CRMEntries           []interfaces.CRM
 	Credentials          []interfaces.Credentials
 	Message              string
-	DB                   *crud.DatabaseWrapper
-	//db 					 interfaces.DatabaseOperations
-	lw     interfaces.LDAPOperations
-	window fyne.Window
+	DB                   interfaces.DatabaseOperations
+	Session              *rbac.Session
+	lw                   interfaces.LDAPOperations
+	window               fyne.Window
 }
 
 var GlobalState = &AppState{}
 
 // Global State
-func (appState *AppState) SetDB(db *crud.DatabaseWrapper) {
+func (appState *AppState) SetDB(db interfaces.DatabaseOperations) {
 	if db == nil {
 		log.Fatal("SetDB: Database instance cannot be nil")
 	}
 	appState.DB = db
 }
 
+// CurrentSession returns the logged in user's session, or nil
+func (appState *AppState) CurrentSession() *rbac.Session {
+	return appState.Session
+}
+
+// ClearUser forgets the logged in user and everything fetched for them
+func (appState *AppState) ClearUser() {
+	appState.Session = nil
+	appState.LDAPConn = nil
+	appState.Username = ""
+	appState.UserID = ""
+	appState.CredentialAuthStatus = false
+	appState.CredentialUsername = ""
+	appState.Notes = nil
+	appState.Tasks = nil
+	appState.Audits = nil
+	appState.CRMEntries = nil
+	appState.Credentials = nil
+	appState.Message = ""
+}
+
+// Can reports whether the logged in user has permission p
+func (appState *AppState) Can(p rbac.Permission) bool {
+	return appState.Session.Can(p)
+}
+
 func (appState *AppState) SetLDAP(lw interfaces.LDAPOperations) {
 	if lw == nil {
 		log.Fatal("SetDB: Database instance cannot be nil")
@@ -63,7 +90,7 @@ func (appState *AppState) SetMPPresent() {
 	appState.MPPresent = false
 }
 func (appState *AppState) SetMasterPassword(Username string, password string) error {
-	if appState.DB == nil || appState.DB.Pool == nil {
+	if appState.DB == nil || crud.DBPool == nil {
 		return errors.New("database is not initialized")
 	}
 
@@ -78,7 +105,7 @@ func (appState *AppState) SetMasterPassword(Username string, password string) er
 
 	// Update the database
 
-	_, err = appState.DB.Pool.Exec(context.Background(),
+	_, err = crud.DBPool.Exec(context.Background(),
 		"UPDATE credentials SET master_password = $1 WHERE username = $2",
 		hashedPassword, Username)
 	if err != nil {
@@ -187,21 +214,18 @@ func (appState *AppState) FetchCredentials() error {
 	return err
 }
 
+// FetchAll loads everything the user's roles allow them to read
 func (appState *AppState) FetchAll() error {
-	if err := appState.FetchNotes(); err != nil {
-		return err
-	}
-	if err := appState.FetchTasks(); err != nil {
-		return err
-	}
-	if err := appState.FetchCredentials(); err != nil {
-		return err
-	}
-	if err := appState.FetchCRMEntries(); err != nil {
-		return err
-	}
-	if err := appState.FetchAudits(); err != nil {
-		return err
+	for _, fetch := range []func() error{
+		appState.FetchNotes,
+		appState.FetchTasks,
+		appState.FetchCredentials,
+		appState.FetchCRMEntries,
+		appState.FetchAudits,
+	} {
+		if err := fetch(); err != nil && !errors.Is(err, rbac.ErrForbidden) {
+			return err
+		}
 	}
 	return nil
 }